@@ -1,13 +1,18 @@
 package apimanagement
 
 import (
+	"bufio"
 	"context"
+	"encoding/csv"
 	"encoding/json"
+	"strconv"
+	"strings"
 
 	"github.com/cloudtrust/common-service/configuration"
 	errorhandler "github.com/cloudtrust/common-service/errors"
 	"github.com/cloudtrust/common-service/validation"
 	"github.com/cloudtrust/keycloak-bridge/internal/constants"
+	"github.com/cloudtrust/keycloak-bridge/internal/dto"
 	"github.com/cloudtrust/keycloak-bridge/internal/keycloakb"
 	kc "github.com/cloudtrust/keycloak-client"
 	"github.com/spf13/cast"
@@ -54,6 +59,167 @@ type UsersPageRepresentation struct {
 	Count *int                 `json:"count"`
 }
 
+// UsersSearchRepresentation holds the criteria of a users search. Users must match all the given criteria
+type UsersSearchRepresentation struct {
+	PhoneNumber      *string
+	TrustIDGroup     *string
+	Accreditation    *string
+	Locale           *string
+	IDDocumentNumber *string
+	First            *int
+	Max              *int
+}
+
+// UserImportEntry is a user read from a users import file, with the line where it was found
+type UserImportEntry struct {
+	Line int
+	User UserRepresentation
+}
+
+// Status of the rows of a users import
+const (
+	UserImportStatusValid   = "valid"
+	UserImportStatusInvalid = "invalid"
+	UserImportStatusCreated = "created"
+	UserImportStatusFailed  = "failed"
+)
+
+// Status of a users import job
+const (
+	UserImportJobStatusRunning = "running"
+	UserImportJobStatusDone    = "done"
+)
+
+// UserImportRowRepresentation is the result of the check or of the import of a row of a users import file
+type UserImportRowRepresentation struct {
+	Line     int     `json:"line"`
+	Username string  `json:"username"`
+	Status   string  `json:"status"`
+	UserID   *string `json:"userId,omitempty"`
+	Error    *string `json:"error,omitempty"`
+}
+
+// UserImportRowsRepresentation is the list of the results of a users import
+type UserImportRowsRepresentation []UserImportRowRepresentation
+
+// UserImportReportRepresentation is the report of a users import run in dry-run mode
+type UserImportReportRepresentation struct {
+	Total   int                          `json:"total"`
+	Valid   int                          `json:"valid"`
+	Invalid int                          `json:"invalid"`
+	Rows    UserImportRowsRepresentation `json:"rows"`
+}
+
+// UserImportJobRepresentation is the progress of an asynchronous users import
+type UserImportJobRepresentation struct {
+	ID         string `json:"id"`
+	Status     string `json:"status"`
+	Total      int    `json:"total"`
+	Processed  int    `json:"processed"`
+	Created    int    `json:"created"`
+	Failed     int    `json:"failed"`
+	StartedAt  int64  `json:"startedAt"`
+	FinishedAt *int64 `json:"finishedAt,omitempty"`
+}
+
+// Operations which can be applied to a list of users
+const (
+	BulkOperationLock              = "lock"
+	BulkOperationUnlock            = "unlock"
+	BulkOperationDelete            = "delete"
+	BulkOperationAddGroup          = "addGroup"
+	BulkOperationRemoveGroup       = "removeGroup"
+	BulkOperationSendReminderEmail = "sendReminderEmail"
+	BulkOperationResetSmsCounter   = "resetSmsCounter"
+)
+
+// Status of a bulk operation job and of its results
+const (
+	BulkOperationStatusRunning = "running"
+	BulkOperationStatusDone    = "done"
+	BulkOperationStatusSuccess = "success"
+	BulkOperationStatusFailed  = "failed"
+	// The operation is waiting for the approval of a second operator
+	BulkOperationStatusPendingApproval = "pendingApproval"
+)
+
+// BulkOperationRepresentation is an operation applied to users given either by their IDs or by a search query
+// GroupID is required by the addGroup and removeGroup operations
+type BulkOperationRepresentation struct {
+	Operation *string                       `json:"operation"`
+	UserIDs   *[]string                     `json:"userIds,omitempty"`
+	Query     *BulkUsersQueryRepresentation `json:"query,omitempty"`
+	GroupID   *string                       `json:"groupId,omitempty"`
+}
+
+// BulkUsersQueryRepresentation selects the users of a bulk operation as GetUsers does
+type BulkUsersQueryRepresentation struct {
+	GroupIDs []string `json:"groupIds"`
+	Search   *string  `json:"search,omitempty"`
+}
+
+// BulkOperationJobRepresentation is the progress and the per-user outcome of a bulk operation
+type BulkOperationJobRepresentation struct {
+	ID              string                              `json:"id"`
+	Operation       string                              `json:"operation"`
+	Status          string                              `json:"status"`
+	Total           int                                 `json:"total"`
+	Processed       int                                 `json:"processed"`
+	Succeeded       int                                 `json:"succeeded"`
+	Failed          int                                 `json:"failed"`
+	PendingApproval int                                 `json:"pendingApproval"`
+	StartedAt       int64                               `json:"startedAt"`
+	FinishedAt      *int64                              `json:"finishedAt,omitempty"`
+	Results         []BulkOperationResultRepresentation `json:"results"`
+}
+
+// BulkOperationResultRepresentation is the outcome of a bulk operation for a user
+type BulkOperationResultRepresentation struct {
+	UserID string  `json:"userId"`
+	Status string  `json:"status"`
+	Error  *string `json:"error,omitempty"`
+}
+
+// Criteria on which two users are detected as duplicates
+const (
+	DuplicateCriterionEmail       = "email"
+	DuplicateCriterionPhoneNumber = "phoneNumber"
+	DuplicateCriterionIdentity    = "identity"
+)
+
+// DuplicateUsersRepresentation is a pair of users which are likely to be the same person. The score is between 0 and 1
+type DuplicateUsersRepresentation struct {
+	User      UserRepresentation `json:"user"`
+	Duplicate UserRepresentation `json:"duplicate"`
+	Score     float64            `json:"score"`
+	MatchedOn []string           `json:"matchedOn"`
+}
+
+// UsersMergeRepresentation selects what is moved from the duplicate user to the kept user before the duplicate is disabled
+type UsersMergeRepresentation struct {
+	DuplicateUserID     *string `json:"duplicateUserId"`
+	Groups              *bool   `json:"groups,omitempty"`
+	Attributes          *bool   `json:"attributes,omitempty"`
+	FederatedIdentities *bool   `json:"federatedIdentities,omitempty"`
+	Checks              *bool   `json:"checks,omitempty"`
+	Details             *bool   `json:"details,omitempty"`
+}
+
+// UserChangeRepresentation is an entry of the history of the identity changes of a user
+type UserChangeRepresentation struct {
+	ChangedAt *int64                      `json:"changedAt,omitempty"`
+	Actor     *string                     `json:"actor,omitempty"`
+	Channel   *string                     `json:"channel,omitempty"`
+	Changes   []FieldChangeRepresentation `json:"changes"`
+}
+
+// FieldChangeRepresentation is the change of the value of an identity field
+type FieldChangeRepresentation struct {
+	Field    *string `json:"field,omitempty"`
+	OldValue *string `json:"oldValue,omitempty"`
+	NewValue *string `json:"newValue,omitempty"`
+}
+
 // RealmRepresentation struct
 type RealmRepresentation struct {
 	ID              *string `json:"id,omitempty"`
@@ -99,6 +265,25 @@ type AttackDetectionStatusRepresentation struct {
 	LastFailure   *int64  `json:"lastFailure,omitempty"`
 }
 
+// UserSessionRepresentation struct
+type UserSessionRepresentation struct {
+	ID         *string            `json:"id,omitempty"`
+	Username   *string            `json:"username,omitempty"`
+	UserID     *string            `json:"userId,omitempty"`
+	IPAddress  *string            `json:"ipAddress,omitempty"`
+	Start      *int64             `json:"start,omitempty"`
+	LastAccess *int64             `json:"lastAccess,omitempty"`
+	Clients    *map[string]string `json:"clients,omitempty"`
+}
+
+// UserConsentRepresentation struct
+type UserConsentRepresentation struct {
+	ClientID            *string   `json:"clientId,omitempty"`
+	GrantedClientScopes *[]string `json:"grantedClientScopes,omitempty"`
+	CreatedDate         *int64    `json:"createdDate,omitempty"`
+	LastUpdatedDate     *int64    `json:"lastUpdatedDate,omitempty"`
+}
+
 // RoleRepresentation struct
 type RoleRepresentation struct {
 	ClientRole  *bool   `json:"clientRole,omitempty"`
@@ -111,8 +296,11 @@ type RoleRepresentation struct {
 
 // GroupRepresentation struct
 type GroupRepresentation struct {
-	ID   *string `json:"id,omitempty"`
-	Name *string `json:"name,omitempty"`
+	ID         *string                `json:"id,omitempty"`
+	Name       *string                `json:"name,omitempty"`
+	Path       *string                `json:"path,omitempty"`
+	Attributes *map[string][]string   `json:"attributes,omitempty"`
+	SubGroups  *[]GroupRepresentation `json:"subGroups,omitempty"`
 }
 
 // AuthorizationsRepresentation struct
@@ -126,6 +314,103 @@ type ActionRepresentation struct {
 	Scope *string `json:"scope"`
 }
 
+// AuthorizationCheckRepresentation struct
+type AuthorizationCheckRepresentation struct {
+	Realm         *string                           `json:"realm"`
+	UserID        *string                           `json:"userId"`
+	Groups        []string                          `json:"groups"`
+	Action        *string                           `json:"action"`
+	TargetRealm   *string                           `json:"targetRealm"`
+	TargetGroup   *string                           `json:"targetGroup,omitempty"`
+	Allowed       *bool                             `json:"allowed"`
+	MatchingRules []AuthorizationRuleRepresentation `json:"matchingRules"`
+	MissingRules  []AuthorizationRuleRepresentation `json:"missingRules"`
+}
+
+// AuthorizationRuleRepresentation struct
+type AuthorizationRuleRepresentation struct {
+	Group       *string `json:"group"`
+	Action      *string `json:"action"`
+	TargetRealm *string `json:"targetRealm"`
+	TargetGroup *string `json:"targetGroup,omitempty"`
+}
+
+// AuthorizationTemplateRepresentation struct
+type AuthorizationTemplateRepresentation struct {
+	Name   *string                                    `json:"name,omitempty"`
+	Matrix *map[string]map[string]map[string]struct{} `json:"matrix"`
+}
+
+// AuthorizationTemplateRealmPlaceholder can be used as target realm in the matrix of a template. It is replaced by
+// the target realm given when the template is applied
+const AuthorizationTemplateRealmPlaceholder = "{realm}"
+
+// AuthorizationsCopyRepresentation struct
+type AuthorizationsCopyRepresentation struct {
+	SourceRealm   *string                            `json:"sourceRealm"`
+	TargetRealm   *string                            `json:"targetRealm"`
+	DryRun        *bool                              `json:"dryRun"`
+	Groups        []AuthorizationsDiffRepresentation `json:"groups"`
+	MissingGroups []string                           `json:"missingGroups"`
+}
+
+// AuthorizationsDiffRepresentation struct
+type AuthorizationsDiffRepresentation struct {
+	Group   *string                           `json:"group"`
+	Added   []AuthorizationRuleRepresentation `json:"added"`
+	Removed []AuthorizationRuleRepresentation `json:"removed"`
+}
+
+// AuthorizationsVersionRepresentation struct
+type AuthorizationsVersionRepresentation struct {
+	Version   *int                                       `json:"version"`
+	Author    *string                                    `json:"author"`
+	CreatedAt *int64                                     `json:"createdAt"`
+	Matrix    *map[string]map[string]map[string]struct{} `json:"matrix"`
+	Added     []AuthorizationRuleRepresentation          `json:"added"`
+	Removed   []AuthorizationRuleRepresentation          `json:"removed"`
+}
+
+// RealmConfigurationVersionRepresentation struct
+type RealmConfigurationVersionRepresentation struct {
+	Version       *int             `json:"version"`
+	Author        *string          `json:"author"`
+	CreatedAt     *int64           `json:"createdAt"`
+	Configuration *json.RawMessage `json:"configuration"`
+}
+
+// RealmConfigurationDiffRepresentation struct
+type RealmConfigurationDiffRepresentation struct {
+	Changes []RealmConfigurationFieldDiffRepresentation `json:"changes"`
+}
+
+// RealmConfigurationFieldDiffRepresentation struct
+type RealmConfigurationFieldDiffRepresentation struct {
+	Field    *string          `json:"field"`
+	OldValue *json.RawMessage `json:"oldValue"`
+	NewValue *json.RawMessage `json:"newValue"`
+}
+
+// TrustIDGroupRepresentation struct
+type TrustIDGroupRepresentation struct {
+	Name          *string `json:"name"`
+	ExpiresAt     *int64  `json:"expiresAt,omitempty"`
+	Justification *string `json:"justification,omitempty"`
+	GrantedBy     *string `json:"grantedBy,omitempty"`
+}
+
+// PendingRequestRepresentation struct
+type PendingRequestRepresentation struct {
+	ID          *string          `json:"id"`
+	Action      *string          `json:"action"`
+	TargetID    *string          `json:"targetId"`
+	Parameters  *json.RawMessage `json:"parameters,omitempty"`
+	RequesterID *string          `json:"requesterId"`
+	Requester   *string          `json:"requester"`
+	CreatedAt   *int64           `json:"createdAt"`
+	ExpiresAt   *int64           `json:"expiresAt"`
+}
+
 // PasswordRepresentation struct
 type PasswordRepresentation struct {
 	Value *string `json:"value,omitempty"`
@@ -159,6 +444,11 @@ var (
 	allowedBoConfKeys    = map[string]bool{BOConfKeyCustomers: true, BOConfKeyTeams: true}
 	allowedAdminConfMode = map[string]bool{"trustID": true, "corporate": true}
 	allowedBarcodeType   = map[string]bool{"CODE128": true}
+	// Actions which can be submitted to the approval of a second operator
+	allowedApprovalActions = map[string]bool{"MGMT_DeleteUser": true, "MGMT_ResetPassword": true, "MGMT_DeleteCredentialsForUser": true,
+		"MGMT_UpdateAuthorizations": true, "MGMT_SetTrustIDGroups": true, "MGMT_MergeUsers": true}
+	allowedBulkOperation = map[string]bool{BulkOperationLock: true, BulkOperationUnlock: true, BulkOperationDelete: true, BulkOperationAddGroup: true,
+		BulkOperationRemoveGroup: true, BulkOperationSendReminderEmail: true, BulkOperationResetSmsCounter: true}
 )
 
 // BackOfficeConfiguration type
@@ -169,6 +459,8 @@ type RealmAdminConfiguration struct {
 	Mode            *string                   `json:"mode"`
 	AvailableChecks map[string]bool           `json:"available-checks"`
 	Accreditations  []RealmAdminAccreditation `json:"accreditations"`
+	TrustIDGroups   *[]string                 `json:"trustid-groups"`
+	ApprovalActions *[]string                 `json:"approval-actions"`
 }
 
 // RealmAdminAccreditation struct
@@ -180,8 +472,9 @@ type RealmAdminAccreditation struct {
 
 // FederatedIdentityRepresentation struct
 type FederatedIdentityRepresentation struct {
-	UserID   *string `json:"userID,omitempty"`
-	Username *string `json:"username,omitempty"`
+	IdentityProvider *string `json:"identityProvider,omitempty"`
+	UserID           *string `json:"userID,omitempty"`
+	Username         *string `json:"username,omitempty"`
 }
 
 // RequiredAction type
@@ -201,6 +494,31 @@ func ConvertCredential(credKc *kc.CredentialRepresentation) CredentialRepresenta
 	return cred
 }
 
+// ConvertUserSession creates an API user session from a KC user session
+func ConvertUserSession(sessionKc *kc.UserSessionRepresentation) UserSessionRepresentation {
+	var session UserSessionRepresentation
+	session.ID = sessionKc.ID
+	session.Username = sessionKc.Username
+	session.UserID = sessionKc.UserID
+	session.IPAddress = sessionKc.IPAddress
+	session.Start = sessionKc.Start
+	session.LastAccess = sessionKc.LastAccess
+	session.Clients = sessionKc.Clients
+
+	return session
+}
+
+// ConvertUserConsent creates an API user consent from a KC user consent
+func ConvertUserConsent(consentKc *kc.UserConsentRepresentation) UserConsentRepresentation {
+	var consent UserConsentRepresentation
+	consent.ClientID = consentKc.ClientID
+	consent.GrantedClientScopes = consentKc.GrantedClientScopes
+	consent.CreatedDate = consentKc.CreatedDate
+	consent.LastUpdatedDate = consentKc.LastUpdatedDate
+
+	return consent
+}
+
 // ConvertAttackDetectionStatus creates a brute force status from a map
 func ConvertAttackDetectionStatus(status map[string]interface{}) AttackDetectionStatusRepresentation {
 	var res AttackDetectionStatusRepresentation
@@ -324,11 +642,54 @@ func ConvertToKCUser(user UserRepresentation) kc.UserRepresentation {
 	return userRep
 }
 
+// ConvertToAPIRole creates an API role representation from a KC role
+func ConvertToAPIRole(role kc.RoleRepresentation) RoleRepresentation {
+	return RoleRepresentation{
+		ID:          role.ID,
+		Name:        role.Name,
+		Composite:   role.Composite,
+		ClientRole:  role.ClientRole,
+		ContainerID: role.ContainerID,
+		Description: role.Description,
+	}
+}
+
+// ConvertToKCRole creates a KC role representation from an API role
+func ConvertToKCRole(role RoleRepresentation) kc.RoleRepresentation {
+	return kc.RoleRepresentation{
+		ID:          role.ID,
+		Name:        role.Name,
+		Composite:   role.Composite,
+		ClientRole:  role.ClientRole,
+		ContainerID: role.ContainerID,
+		Description: role.Description,
+	}
+}
+
 // ConvertToKCGroup creates a KC group representation from an API group
 func ConvertToKCGroup(group GroupRepresentation) kc.GroupRepresentation {
 	return kc.GroupRepresentation{
-		Name: group.Name,
+		Name:       group.Name,
+		Attributes: group.Attributes,
+	}
+}
+
+// ConvertToAPIGroup creates an API group representation from a KC group, including its subgroups
+func ConvertToAPIGroup(group kc.GroupRepresentation) GroupRepresentation {
+	var groupRep = GroupRepresentation{
+		ID:         group.ID,
+		Name:       group.Name,
+		Path:       group.Path,
+		Attributes: group.Attributes,
+	}
+	if group.SubGroups != nil {
+		var subGroups = []GroupRepresentation{}
+		for _, subGroup := range *group.SubGroups {
+			subGroups = append(subGroups, ConvertToAPIGroup(subGroup))
+		}
+		groupRep.SubGroups = &subGroups
 	}
+	return groupRep
 }
 
 // ConvertToAPIAuthorizations creates a API authorization representation from an array of DB Authorization
@@ -363,6 +724,82 @@ func ConvertToAPIAuthorizations(authorizations []configuration.Authorization) Au
 
 }
 
+// ConvertToAPIAuthorizationRules creates an array of AuthorizationRuleRepresentation from DB Authorizations
+func ConvertToAPIAuthorizationRules(authorizations []configuration.Authorization) []AuthorizationRuleRepresentation {
+	var rules = []AuthorizationRuleRepresentation{}
+
+	for _, authz := range authorizations {
+		rules = append(rules, AuthorizationRuleRepresentation{
+			Group:       authz.GroupName,
+			Action:      authz.Action,
+			TargetRealm: authz.TargetRealmID,
+			TargetGroup: authz.TargetGroupName,
+		})
+	}
+
+	return rules
+}
+
+// ConvertToAPIAuthorizationTemplate creates an API authorization template representation from its DB version
+func ConvertToAPIAuthorizationTemplate(template dto.AuthorizationTemplate) AuthorizationTemplateRepresentation {
+	var name = template.Name
+	var matrix = template.Matrix
+	if matrix == nil {
+		matrix = make(map[string]map[string]map[string]struct{})
+	}
+	return AuthorizationTemplateRepresentation{
+		Name:   &name,
+		Matrix: &matrix,
+	}
+}
+
+// ConvertToAPITrustIDGroup creates an API trustID group representation from a trustID group assignment
+func ConvertToAPITrustIDGroup(assignment keycloakb.TrustIDGroupAssignment) TrustIDGroupRepresentation {
+	var name = assignment.Group
+	return TrustIDGroupRepresentation{
+		Name:          &name,
+		ExpiresAt:     assignment.ExpiresAt,
+		Justification: assignment.Justification,
+		GrantedBy:     assignment.GrantedBy,
+	}
+}
+
+// ConvertToAPIPendingRequest creates an API pending request representation from its DB version
+func ConvertToAPIPendingRequest(request dto.PendingRequest) PendingRequestRepresentation {
+	var res = PendingRequestRepresentation{
+		ID:          &request.ID,
+		Action:      &request.Action,
+		TargetID:    &request.TargetID,
+		RequesterID: &request.RequesterID,
+		Requester:   &request.Requester,
+		CreatedAt:   &request.CreatedAt,
+		ExpiresAt:   &request.ExpiresAt,
+	}
+	if request.Parameters != "" {
+		var parameters = json.RawMessage(request.Parameters)
+		res.Parameters = &parameters
+	}
+	return res
+}
+
+// ConvertToAPIUserChange creates an API user change representation from its DB version
+func ConvertToAPIUserChange(change dto.DBUserChange) UserChangeRepresentation {
+	var res = UserChangeRepresentation{
+		ChangedAt: &change.ChangedAt,
+		Actor:     &change.Actor,
+		Channel:   &change.Channel,
+		Changes:   []FieldChangeRepresentation{},
+	}
+	for i := range change.Changes {
+		res.Changes = append(res.Changes, FieldChangeRepresentation{
+			Field:    &change.Changes[i].Field,
+			OldValue: change.Changes[i].OldValue,
+			NewValue: change.Changes[i].NewValue,
+		})
+	}
+	return res
+}
+
 // ConvertToDBAuthorizations creates an array of DB Authorization from an API AuthorizationsRepresentation
 func ConvertToDBAuthorizations(realmID, groupName string, apiAuthorizations AuthorizationsRepresentation) []configuration.Authorization {
 	var authorizations = []configuration.Authorization{}
@@ -433,6 +870,17 @@ func ConvertToKCFedID(fedID FederatedIdentityRepresentation) kc.FederatedIdentit
 	return kcFedID
 }
 
+// ConvertToAPIFedID creates an API federated identity representation from a KC federated identity representation
+func ConvertToAPIFedID(kcFedID kc.FederatedIdentityRepresentation) FederatedIdentityRepresentation {
+	var fedID FederatedIdentityRepresentation
+
+	fedID.IdentityProvider = kcFedID.IdentityProvider
+	fedID.UserID = kcFedID.UserID
+	fedID.Username = kcFedID.UserName
+
+	return fedID
+}
+
 // CreateDefaultRealmAdminConfiguration creates a default admin configuration
 func CreateDefaultRealmAdminConfiguration() RealmAdminConfiguration {
 	var mode = "corporate"
@@ -561,6 +1009,28 @@ func (group GroupRepresentation) Validate() error {
 		Status()
 }
 
+// Validate is a validator for AuthorizationTemplateRepresentation
+func (template AuthorizationTemplateRepresentation) Validate() error {
+	if template.Matrix == nil {
+		return errorhandler.CreateMissingParameterError(constants.Matrix)
+	}
+	var v = validation.NewParameterValidator().
+		ValidateParameterRegExp(constants.Name, template.Name, constants.RegExpName, false)
+	for action := range *template.Matrix {
+		var value = action
+		v = v.ValidateParameterRegExp(constants.Action, &value, RegExpAction, true)
+	}
+	return v.Status()
+}
+
+// Validate is a validator for TrustIDGroupRepresentation
+func (group TrustIDGroupRepresentation) Validate() error {
+	return validation.NewParameterValidator().
+		ValidateParameterNotNil(constants.TrustIDGroupName, group.Name).
+		ValidateParameterRegExp(constants.Justification, group.Justification, constants.RegExpDescription, false).
+		Status()
+}
+
 // Validate is a validator for PasswordRepresentation
 func (password PasswordRepresentation) Validate() error {
 	return validation.NewParameterValidator().
@@ -584,9 +1054,41 @@ func (rac RealmAdminConfiguration) Validate() error {
 	return validation.NewParameterValidator().
 		ValidateParameterIn("mode", rac.Mode, allowedAdminConfMode, true).
 		ValidateParameterFunc(rac.validateAvailableChecks).
+		ValidateParameterFunc(rac.validateTrustIDGroups).
+		ValidateParameterFunc(rac.validateApprovalActions).
 		Status()
 }
 
+func (rac RealmAdminConfiguration) validateApprovalActions() error {
+	if rac.ApprovalActions == nil {
+		return nil
+	}
+	for _, action := range *rac.ApprovalActions {
+		if !allowedApprovalActions[action] {
+			return errorhandler.CreateBadRequestError(constants.MsgErrInvalidParam + "." + constants.ApprovalActions)
+		}
+	}
+	return nil
+}
+
+func (rac RealmAdminConfiguration) validateTrustIDGroups() error {
+	if rac.TrustIDGroups == nil {
+		return nil
+	}
+	var groups = make(map[string]bool)
+	for _, group := range *rac.TrustIDGroups {
+		var value = group
+		if err := validation.NewParameterValidator().ValidateParameterRegExp(constants.TrustIDGroupName, &value, constants.RegExpName, true).Status(); err != nil {
+			return err
+		}
+		if groups[group] {
+			return errorhandler.CreateBadRequestError(constants.MsgErrInvalidParam + "." + constants.TrustIDGroupName)
+		}
+		groups[group] = true
+	}
+	return nil
+}
+
 func (rac RealmAdminConfiguration) validateAvailableChecks() error {
 	var accredConditions, err = rac.validateAccreditations()
 	if err != nil {
@@ -642,14 +1144,150 @@ func (requiredAction RequiredAction) Validate() error {
 	return nil
 }
 
+// Validate is a validator for BulkOperationRepresentation. Users must be given either by their IDs or by a query
+func (op BulkOperationRepresentation) Validate() error {
+	var v = validation.NewParameterValidator().
+		ValidateParameterIn(constants.BulkOperation, op.Operation, allowedBulkOperation, true)
+
+	if (op.UserIDs == nil) == (op.Query == nil) {
+		return errorhandler.CreateBadRequestError(constants.MsgErrInvalidParam + "." + constants.UserIDs)
+	}
+	if op.UserIDs != nil {
+		if len(*op.UserIDs) == 0 {
+			return errorhandler.CreateMissingParameterError(constants.UserIDs)
+		}
+		for _, userID := range *op.UserIDs {
+			v = v.ValidateParameterRegExp(constants.UserID, &userID, constants.RegExpID, true)
+		}
+	}
+	if op.Query != nil {
+		if len(op.Query.GroupIDs) == 0 {
+			return errorhandler.CreateMissingParameterError(constants.GroupIDs)
+		}
+		for _, groupID := range op.Query.GroupIDs {
+			v = v.ValidateParameterRegExp(constants.GroupID, &groupID, constants.RegExpID, true)
+		}
+		v = v.ValidateParameterRegExp(constants.Search, op.Query.Search, constants.RegExpSearch, false)
+	}
+
+	var groupMandatory = op.Operation != nil && (*op.Operation == BulkOperationAddGroup || *op.Operation == BulkOperationRemoveGroup)
+	return v.ValidateParameterRegExp(constants.GroupID, op.GroupID, constants.RegExpID, groupMandatory).
+		Status()
+}
+
 // Validate is a validator for FederatedIdentityRepresentation
 func (fedID FederatedIdentityRepresentation) Validate() error {
 	return validation.NewParameterValidator().
+		ValidateParameterRegExp(constants.IdentityProvider, fedID.IdentityProvider, RegExpIdentityProvider, false).
 		ValidateParameterRegExp(constants.UserID, fedID.UserID, constants.RegExpID, true).
 		ValidateParameterRegExp(constants.Username, fedID.Username, constants.RegExpUsername, true).
 		Status()
 }
 
+// Validate is a validator for UsersMergeRepresentation
+func (merge UsersMergeRepresentation) Validate() error {
+	return validation.NewParameterValidator().
+		ValidateParameterRegExp(constants.DuplicateUserID, merge.DuplicateUserID, constants.RegExpID, true).
+		Status()
+}
+
+// ValidateIdentityProvider validates the alias of the identity provider of a federated identity
+func ValidateIdentityProvider(provider string) error {
+	return validation.NewParameterValidator().
+		ValidateParameterRegExp(constants.IdentityProvider, &provider, RegExpIdentityProvider, true).
+		Status()
+}
+
+// Setters of the UserRepresentation fields which can be provided in a users import CSV file. Group IDs are separated by '|'
+var userImportCSVColumns = map[string]func(*UserRepresentation, string){
+	"username":             func(u *UserRepresentation, v string) { u.Username = &v },
+	"email":                func(u *UserRepresentation, v string) { u.Email = &v },
+	"firstName":            func(u *UserRepresentation, v string) { u.FirstName = &v },
+	"lastName":             func(u *UserRepresentation, v string) { u.LastName = &v },
+	"gender":               func(u *UserRepresentation, v string) { u.Gender = &v },
+	"phoneNumber":          func(u *UserRepresentation, v string) { u.PhoneNumber = &v },
+	"birthDate":            func(u *UserRepresentation, v string) { u.BirthDate = &v },
+	"birthLocation":        func(u *UserRepresentation, v string) { u.BirthLocation = &v },
+	"idDocumentType":       func(u *UserRepresentation, v string) { u.IDDocumentType = &v },
+	"idDocumentNumber":     func(u *UserRepresentation, v string) { u.IDDocumentNumber = &v },
+	"idDocumentExpiration": func(u *UserRepresentation, v string) { u.IDDocumentExpiration = &v },
+	"locale":               func(u *UserRepresentation, v string) { u.Locale = &v },
+	"label":                func(u *UserRepresentation, v string) { u.Label = &v },
+	"groups": func(u *UserRepresentation, v string) {
+		var groups = strings.Split(v, "|")
+		u.Groups = &groups
+	},
+}
+
+// ParseUsersImportCSV reads the users of an import file in CSV format. The first line is a header giving the name of the columns
+// Empty cells are ignored
+func ParseUsersImportCSV(content string) ([]UserImportEntry, error) {
+	var records, err = csv.NewReader(strings.NewReader(content)).ReadAll()
+	if err != nil || len(records) == 0 {
+		return nil, errorhandler.CreateBadRequestError(constants.MsgErrInvalidParam + "." + constants.Body)
+	}
+
+	var setters []func(*UserRepresentation, string)
+	for _, column := range records[0] {
+		var setter, ok = userImportCSVColumns[strings.TrimSpace(column)]
+		if !ok {
+			return nil, errorhandler.CreateBadRequestError(constants.MsgErrInvalidParam + "." + constants.Body + "." + column)
+		}
+		setters = append(setters, setter)
+	}
+
+	var res []UserImportEntry
+	for i, record := range records[1:] {
+		var user UserRepresentation
+		for j, value := range record {
+			if value != "" {
+				setters[j](&user, value)
+			}
+		}
+		res = append(res, UserImportEntry{Line: i + 2, User: user})
+	}
+	return res, nil
+}
+
+// ParseUsersImportJSONLines reads the users of an import file containing one JSON UserRepresentation per line
+// Empty lines are ignored
+func ParseUsersImportJSONLines(content string) ([]UserImportEntry, error) {
+	var res []UserImportEntry
+	var scanner = bufio.NewScanner(strings.NewReader(content))
+	scanner.Buffer(nil, 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		var text = strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		var user UserRepresentation
+		if err := json.Unmarshal([]byte(text), &user); err != nil {
+			return nil, errorhandler.CreateBadRequestError(constants.MsgErrInvalidParam + "." + constants.Body + ".line" + strconv.Itoa(line))
+		}
+		res = append(res, UserImportEntry{Line: line, User: user})
+	}
+	if scanner.Err() != nil {
+		return nil, errorhandler.CreateBadRequestError(constants.MsgErrInvalidParam + "." + constants.Body)
+	}
+	return res, nil
+}
+
+// ToCSV converts the results of a users import to CSV records, including a header
+func (rows UserImportRowsRepresentation) ToCSV() [][]string {
+	var res = [][]string{{"line", "username", "status", "userId", "error"}}
+	for _, row := range rows {
+		var userID, errMsg string
+		if row.UserID != nil {
+			userID = *row.UserID
+		}
+		if row.Error != nil {
+			errMsg = *row.Error
+		}
+		res = append(res, []string{strconv.Itoa(row.Line), row.Username, row.Status, userID, errMsg})
+	}
+	return res
+}
+
 // Regular expressions for parameters validation
 const (
 	RegExpID          = constants.RegExpID
@@ -678,10 +1316,27 @@ const (
 	// RequiredAction
 	RegExpRequiredAction = constants.RegExpRequiredAction
 
+	// Federated identities
+	RegExpIdentityProvider = constants.RegExpName
+
+	// Users search
+	RegExpAccreditationType = constants.RegExpName
+	RegExpIDDocumentNumber  = constants.RegExpIDDocumentNumber
+
 	// Others
 	RegExpRealmName = constants.RegExpRealmName
 	RegExpSearch    = constants.RegExpSearch
 	RegExpLifespan  = constants.RegExpLifespan
 	RegExpGroupIds  = constants.RegExpGroupIds
 	RegExpNumber    = constants.RegExpNumber
+
+	// Users import
+	RegExpImportFormat     = `^(csv|jsonl)$`
+	RegExpBool             = `^(true|false)$`
+	RegExpRequiredActions  = `^[a-zA-Z0-9-_]{1,255}(,[a-zA-Z0-9-_]{1,255}){0,10}$`
+	RegExpJobID            = `^[a-f0-9]{32}$`
+	RegExpPendingRequestID = `^[a-f0-9]{32}$`
+
+	// Authorizations check
+	RegExpAction = `^[A-Z]+_[a-zA-Z]+$`
 )
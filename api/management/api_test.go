@@ -9,6 +9,8 @@ import (
 	"github.com/cloudtrust/common-service/configuration"
 	"github.com/cloudtrust/common-service/log"
 	"github.com/cloudtrust/keycloak-bridge/internal/constants"
+	"github.com/cloudtrust/keycloak-bridge/internal/dto"
+	"github.com/cloudtrust/keycloak-bridge/internal/keycloakb"
 	kc "github.com/cloudtrust/keycloak-client"
 	"github.com/stretchr/testify/assert"
 )
@@ -32,6 +34,32 @@ func TestConvertCredential(t *testing.T) {
 	assert.Equal(t, "{}", *ConvertCredential(&credKc).CredentialData)
 }
 
+func TestConvertUserSession(t *testing.T) {
+	var sessionID = "f4b9d2c6-0c4e-4a3b-9d1f-5a8e2b7c6d10"
+	var ipAddress = "10.0.0.1"
+	var start = int64(1600000000000)
+	var clients = map[string]string{"client-uuid": "backoffice"}
+	var sessionKc = kc.UserSessionRepresentation{ID: &sessionID, IPAddress: &ipAddress, Start: &start, Clients: &clients}
+
+	var res = ConvertUserSession(&sessionKc)
+	assert.Equal(t, sessionID, *res.ID)
+	assert.Equal(t, ipAddress, *res.IPAddress)
+	assert.Equal(t, start, *res.Start)
+	assert.Equal(t, clients, *res.Clients)
+	assert.Nil(t, res.LastAccess)
+}
+
+func TestConvertUserConsent(t *testing.T) {
+	var clientID = "backoffice"
+	var scopes = []string{"openid", "offline_access"}
+	var consentKc = kc.UserConsentRepresentation{ClientID: &clientID, GrantedClientScopes: &scopes}
+
+	var res = ConvertUserConsent(&consentKc)
+	assert.Equal(t, clientID, *res.ClientID)
+	assert.Equal(t, scopes, *res.GrantedClientScopes)
+	assert.Nil(t, res.CreatedDate)
+}
+
 func TestConvertAttackDetectionStatus(t *testing.T) {
 	t.Run("missing keys", func(t *testing.T) {
 		var status = map[string]interface{}{}
@@ -186,6 +214,26 @@ func TestConvertToKCUser(t *testing.T) {
 
 }
 
+func TestConvertRole(t *testing.T) {
+	var id = "1234-7454-4516"
+	var name = "role"
+	var description = "description"
+	var clientRole = true
+	var composite = false
+	var containerID = "container"
+	var role = RoleRepresentation{ID: &id, Name: &name, Description: &description, ClientRole: &clientRole, Composite: &composite, ContainerID: &containerID}
+
+	var roleKc = ConvertToKCRole(role)
+	assert.Equal(t, id, *roleKc.ID)
+	assert.Equal(t, name, *roleKc.Name)
+	assert.Equal(t, description, *roleKc.Description)
+	assert.Equal(t, clientRole, *roleKc.ClientRole)
+	assert.Equal(t, composite, *roleKc.Composite)
+	assert.Equal(t, containerID, *roleKc.ContainerID)
+
+	assert.Equal(t, role, ConvertToAPIRole(roleKc))
+}
+
 func TestConvertToKCGroup(t *testing.T) {
 	var group GroupRepresentation
 
@@ -193,6 +241,27 @@ func TestConvertToKCGroup(t *testing.T) {
 	var name = "a name"
 	group.Name = &name
 	assert.Equal(t, name, *ConvertToKCGroup(group).Name)
+
+	// Attributes
+	var attributes = map[string][]string{"costCenter": {"1234"}}
+	group.Attributes = &attributes
+	assert.Equal(t, attributes, *ConvertToKCGroup(group).Attributes)
+}
+
+func TestConvertToAPIGroup(t *testing.T) {
+	var id = "41dbf4a8-32a9-4000-8c17-edc854c31231"
+	var name = "parent"
+	var subName = "child"
+	var subGroups = []kc.GroupRepresentation{{Name: &subName}}
+
+	var res = ConvertToAPIGroup(kc.GroupRepresentation{ID: &id, Name: &name})
+	assert.Equal(t, id, *res.ID)
+	assert.Equal(t, name, *res.Name)
+	assert.Nil(t, res.SubGroups)
+
+	res = ConvertToAPIGroup(kc.GroupRepresentation{ID: &id, Name: &name, SubGroups: &subGroups})
+	assert.Len(t, *res.SubGroups, 1)
+	assert.Equal(t, subName, *(*res.SubGroups)[0].Name)
 }
 
 func TestConvertToDBAuthorizations(t *testing.T) {
@@ -297,6 +366,78 @@ func TestConvertToAPIAuthorizations(t *testing.T) {
 	assert.Equal(t, true, ok)
 }
 
+func TestConvertToAPIAuthorizationRules(t *testing.T) {
+	var master = "master"
+	var groupName = "groupName"
+	var action = "action"
+	var any = "*"
+
+	assert.Len(t, ConvertToAPIAuthorizationRules(nil), 0)
+
+	var rules = ConvertToAPIAuthorizationRules([]configuration.Authorization{
+		{RealmID: &master, GroupName: &groupName, Action: &action, TargetRealmID: &any},
+	})
+	assert.Len(t, rules, 1)
+	assert.Equal(t, groupName, *rules[0].Group)
+	assert.Equal(t, action, *rules[0].Action)
+	assert.Equal(t, any, *rules[0].TargetRealm)
+	assert.Nil(t, rules[0].TargetGroup)
+}
+
+func TestConvertToAPIAuthorizationTemplate(t *testing.T) {
+	var template = ConvertToAPIAuthorizationTemplate(dto.AuthorizationTemplate{Name: "support"})
+	assert.Equal(t, "support", *template.Name)
+	assert.NotNil(t, template.Matrix)
+	assert.Len(t, *template.Matrix, 0)
+
+	var matrix = map[string]map[string]map[string]struct{}{"MGMT_GetUsers": {AuthorizationTemplateRealmPlaceholder: {"*": {}}}}
+	template = ConvertToAPIAuthorizationTemplate(dto.AuthorizationTemplate{Name: "support", Matrix: matrix})
+	assert.Equal(t, matrix, *template.Matrix)
+}
+
+func TestConvertToAPITrustIDGroup(t *testing.T) {
+	var expiresAt = int64(1600000000)
+	var grantedBy = "admin"
+	var group = ConvertToAPITrustIDGroup(keycloakb.TrustIDGroupAssignment{Group: "l1_support_agent", ExpiresAt: &expiresAt, GrantedBy: &grantedBy})
+	assert.Equal(t, "l1_support_agent", *group.Name)
+	assert.Equal(t, expiresAt, *group.ExpiresAt)
+	assert.Equal(t, grantedBy, *group.GrantedBy)
+	assert.Nil(t, group.Justification)
+}
+
+func TestConvertToAPIPendingRequest(t *testing.T) {
+	var request = dto.PendingRequest{ID: "request-id", Action: "MGMT_DeleteUser", TargetID: "user-id", RequesterID: "requester-id",
+		Requester: "requester", CreatedAt: 1600000000, ExpiresAt: 1600086400}
+
+	var res = ConvertToAPIPendingRequest(request)
+	assert.Equal(t, "request-id", *res.ID)
+	assert.Equal(t, "MGMT_DeleteUser", *res.Action)
+	assert.Equal(t, "requester", *res.Requester)
+	assert.Equal(t, int64(1600086400), *res.ExpiresAt)
+	assert.Nil(t, res.Parameters)
+
+	request.Parameters = `{"credentialId":"cred-id"}`
+	res = ConvertToAPIPendingRequest(request)
+	assert.Equal(t, `{"credentialId":"cred-id"}`, string(*res.Parameters))
+}
+
+func TestConvertToAPIUserChange(t *testing.T) {
+	var oldValue, newValue = "Doe", "Smith"
+	var change = dto.DBUserChange{ChangedAt: 1600000000, Actor: "operator", Channel: "management",
+		Changes: []dto.DBFieldChange{{Field: "lastName", OldValue: &oldValue, NewValue: &newValue}, {Field: "idDocumentType"}}}
+
+	var res = ConvertToAPIUserChange(change)
+	assert.Equal(t, int64(1600000000), *res.ChangedAt)
+	assert.Equal(t, "operator", *res.Actor)
+	assert.Equal(t, "management", *res.Channel)
+	assert.Len(t, res.Changes, 2)
+	assert.Equal(t, "lastName", *res.Changes[0].Field)
+	assert.Equal(t, oldValue, *res.Changes[0].OldValue)
+	assert.Equal(t, newValue, *res.Changes[0].NewValue)
+	assert.Equal(t, "idDocumentType", *res.Changes[1].Field)
+	assert.Nil(t, res.Changes[1].NewValue)
+}
+
 func TestConvertRequiredAction(t *testing.T) {
 	var raKc kc.RequiredActionProviderRepresentation
 	var alias = "alias"
@@ -323,6 +464,14 @@ func TestFederatedIdentityRepresentation(t *testing.T) {
 		assert.Equal(t, username, *res.UserName)
 	})
 
+	t.Run("ConvertToAPIFedID", func(t *testing.T) {
+		var provider = "provider"
+		var res = ConvertToAPIFedID(kc.FederatedIdentityRepresentation{IdentityProvider: &provider, UserID: &userID, UserName: &username})
+		assert.Equal(t, provider, *res.IdentityProvider)
+		assert.Equal(t, userID, *res.UserID)
+		assert.Equal(t, username, *res.Username)
+	})
+
 	t.Run("Validate - fails", func(t *testing.T) {
 		assert.NotNil(t, fir.Validate())
 	})
@@ -489,6 +638,30 @@ func TestValidateGroupRepresentation(t *testing.T) {
 	}
 }
 
+func TestValidateAuthorizationTemplateRepresentation(t *testing.T) {
+	var name = "support"
+	var invalidName = "support *"
+	var matrix = map[string]map[string]map[string]struct{}{"MGMT_GetUsers": {AuthorizationTemplateRealmPlaceholder: {"*": {}}}}
+	var invalidMatrix = map[string]map[string]map[string]struct{}{"get users": {}}
+
+	assert.Nil(t, AuthorizationTemplateRepresentation{Name: &name, Matrix: &matrix}.Validate())
+	assert.Nil(t, AuthorizationTemplateRepresentation{Matrix: &matrix}.Validate())
+	assert.NotNil(t, AuthorizationTemplateRepresentation{Name: &name}.Validate())
+	assert.NotNil(t, AuthorizationTemplateRepresentation{Name: &invalidName, Matrix: &matrix}.Validate())
+	assert.NotNil(t, AuthorizationTemplateRepresentation{Name: &name, Matrix: &invalidMatrix}.Validate())
+}
+
+func TestValidateTrustIDGroupRepresentation(t *testing.T) {
+	var name = "l1_support_agent"
+	var justification = "support ticket #42"
+	var tooLong = strings.Repeat("x", 256)
+
+	assert.Nil(t, TrustIDGroupRepresentation{Name: &name}.Validate())
+	assert.Nil(t, TrustIDGroupRepresentation{Name: &name, Justification: &justification}.Validate())
+	assert.NotNil(t, TrustIDGroupRepresentation{Justification: &justification}.Validate())
+	assert.NotNil(t, TrustIDGroupRepresentation{Name: &name, Justification: &tooLong}.Validate())
+}
+
 func TestValidatePasswordRepresentation(t *testing.T) {
 	{
 		password := createValidPasswordRepresentation()
@@ -563,6 +736,33 @@ func TestValidateRealmAdminConfiguration(t *testing.T) {
 		realmAdminConf.AvailableChecks["invalid-key"] = false
 		assert.NotNil(t, realmAdminConf.Validate())
 	})
+	t.Run("Valid trustID groups", func(t *testing.T) {
+		var realmAdminConf = createValidRealmAdminConfiguration()
+		realmAdminConf.TrustIDGroups = &[]string{"l1_support_agent", "registration_officer"}
+		assert.Nil(t, realmAdminConf.Validate())
+		realmAdminConf.TrustIDGroups = &[]string{}
+		assert.Nil(t, realmAdminConf.Validate())
+	})
+	t.Run("Invalid trustID group name", func(t *testing.T) {
+		var realmAdminConf = createValidRealmAdminConfiguration()
+		realmAdminConf.TrustIDGroups = &[]string{"support agent"}
+		assert.NotNil(t, realmAdminConf.Validate())
+	})
+	t.Run("Duplicated trustID group", func(t *testing.T) {
+		var realmAdminConf = createValidRealmAdminConfiguration()
+		realmAdminConf.TrustIDGroups = &[]string{"l1_support_agent", "l1_support_agent"}
+		assert.NotNil(t, realmAdminConf.Validate())
+	})
+	t.Run("Valid approval actions", func(t *testing.T) {
+		var realmAdminConf = createValidRealmAdminConfiguration()
+		realmAdminConf.ApprovalActions = &[]string{"MGMT_DeleteUser", "MGMT_UpdateAuthorizations"}
+		assert.Nil(t, realmAdminConf.Validate())
+	})
+	t.Run("Action which can't be approved", func(t *testing.T) {
+		var realmAdminConf = createValidRealmAdminConfiguration()
+		realmAdminConf.ApprovalActions = &[]string{"MGMT_GetUser"}
+		assert.NotNil(t, realmAdminConf.Validate())
+	})
 	t.Run("Missing accreditation for enabled check", func(t *testing.T) {
 		var realmAdminConf = createValidRealmAdminConfiguration()
 		realmAdminConf.AvailableChecks["IDnow"] = true
@@ -614,6 +814,53 @@ func TestValidateFederatedIdentityRepresentation(t *testing.T) {
 	fi.UserID = &userID
 	fi.Username = nil
 	assert.NotNil(t, fi.Validate())
+
+	var provider = "my-provider"
+	fi.Username = &username
+	fi.IdentityProvider = &provider
+	assert.Nil(t, fi.Validate())
+
+	var invalidProvider = "<provider>"
+	fi.IdentityProvider = &invalidProvider
+	assert.NotNil(t, fi.Validate())
+}
+
+func TestValidateIdentityProvider(t *testing.T) {
+	assert.Nil(t, ValidateIdentityProvider("my-provider"))
+	assert.NotNil(t, ValidateIdentityProvider(""))
+	assert.NotNil(t, ValidateIdentityProvider("<provider>"))
+}
+
+func TestValidateUsersMergeRepresentation(t *testing.T) {
+	var userID = "abcd1234-abcd-1234-efgh-abcd1234efgh"
+	var invalid = "invalid"
+
+	assert.NotNil(t, UsersMergeRepresentation{}.Validate())
+	assert.NotNil(t, UsersMergeRepresentation{DuplicateUserID: &invalid}.Validate())
+	assert.Nil(t, UsersMergeRepresentation{DuplicateUserID: &userID}.Validate())
+}
+
+func TestValidateBulkOperationRepresentation(t *testing.T) {
+	var lock = BulkOperationLock
+	var addGroup = BulkOperationAddGroup
+	var unknown = "unknown"
+	var groupID = "f467ed7c-0a1d-4eee-9bb8-669c6f89c0ee"
+	var userIDs = []string{"abcd1234-abcd-1234-efgh-abcd1234efgh"}
+	var invalidUserIDs = []string{"invalid"}
+	var query = BulkUsersQueryRepresentation{GroupIDs: []string{groupID}}
+
+	assert.Nil(t, BulkOperationRepresentation{Operation: &lock, UserIDs: &userIDs}.Validate())
+	assert.Nil(t, BulkOperationRepresentation{Operation: &lock, Query: &query}.Validate())
+	assert.Nil(t, BulkOperationRepresentation{Operation: &addGroup, UserIDs: &userIDs, GroupID: &groupID}.Validate())
+
+	assert.NotNil(t, BulkOperationRepresentation{UserIDs: &userIDs}.Validate())
+	assert.NotNil(t, BulkOperationRepresentation{Operation: &unknown, UserIDs: &userIDs}.Validate())
+	assert.NotNil(t, BulkOperationRepresentation{Operation: &lock}.Validate())
+	assert.NotNil(t, BulkOperationRepresentation{Operation: &lock, UserIDs: &userIDs, Query: &query}.Validate())
+	assert.NotNil(t, BulkOperationRepresentation{Operation: &lock, UserIDs: &[]string{}}.Validate())
+	assert.NotNil(t, BulkOperationRepresentation{Operation: &lock, UserIDs: &invalidUserIDs}.Validate())
+	assert.NotNil(t, BulkOperationRepresentation{Operation: &lock, Query: &BulkUsersQueryRepresentation{}}.Validate())
+	assert.NotNil(t, BulkOperationRepresentation{Operation: &addGroup, UserIDs: &userIDs}.Validate())
 }
 
 func createValidUserRepresentation() UserRepresentation {
@@ -701,3 +948,52 @@ func createValidRealmCustomConfiguration() RealmCustomConfiguration {
 func createValidRequiredAction() RequiredAction {
 	return RequiredAction("verify-email")
 }
+
+func TestParseUsersImportCSV(t *testing.T) {
+	t.Run("Empty file", func(t *testing.T) {
+		var _, err = ParseUsersImportCSV("")
+		assert.NotNil(t, err)
+	})
+	t.Run("Unknown column", func(t *testing.T) {
+		var _, err = ParseUsersImportCSV("username,unknown\nuser1,value\n")
+		assert.NotNil(t, err)
+	})
+	t.Run("Success", func(t *testing.T) {
+		var users, err = ParseUsersImportCSV("username,email,idDocumentNumber,groups\nuser1,user1@example.com,,grp1|grp2\nuser2,,123456,\n")
+		assert.Nil(t, err)
+		assert.Len(t, users, 2)
+		assert.Equal(t, 2, users[0].Line)
+		assert.Equal(t, "user1@example.com", *users[0].User.Email)
+		assert.Nil(t, users[0].User.IDDocumentNumber)
+		assert.Equal(t, []string{"grp1", "grp2"}, *users[0].User.Groups)
+		assert.Equal(t, "123456", *users[1].User.IDDocumentNumber)
+		assert.Nil(t, users[1].User.Groups)
+	})
+}
+
+func TestParseUsersImportJSONLines(t *testing.T) {
+	t.Run("Invalid JSON", func(t *testing.T) {
+		var _, err = ParseUsersImportJSONLines(`{"username":"user1"}` + "\nnot json\n")
+		assert.NotNil(t, err)
+	})
+	t.Run("Success", func(t *testing.T) {
+		var users, err = ParseUsersImportJSONLines(`{"username":"user1","birthLocation":"Lausanne"}` + "\n\n" + `{"username":"user2"}` + "\n")
+		assert.Nil(t, err)
+		assert.Len(t, users, 2)
+		assert.Equal(t, "Lausanne", *users[0].User.BirthLocation)
+		assert.Equal(t, 3, users[1].Line)
+	})
+}
+
+func TestUserImportRowsToCSV(t *testing.T) {
+	var userID = "user-id"
+	var errMsg = "error"
+	var rows = UserImportRowsRepresentation{
+		{Line: 2, Username: "user1", Status: UserImportStatusCreated, UserID: &userID},
+		{Line: 3, Username: "user2", Status: UserImportStatusFailed, Error: &errMsg},
+	}
+	var records = rows.ToCSV()
+	assert.Len(t, records, 3)
+	assert.Equal(t, []string{"2", "user1", "created", "user-id", ""}, records[1])
+	assert.Equal(t, []string{"3", "user2", "failed", "", "error"}, records[2])
+}
@@ -2,8 +2,14 @@ package apistatistics
 
 import (
 	"database/sql"
+	"strconv"
+	"strings"
+	"time"
 
+	errorhandler "github.com/cloudtrust/common-service/errors"
+	"github.com/cloudtrust/common-service/validation"
 	api_events "github.com/cloudtrust/keycloak-bridge/api/events"
+	"github.com/cloudtrust/keycloak-bridge/internal/constants"
 	kc "github.com/cloudtrust/keycloak-client"
 )
 
@@ -13,6 +19,17 @@ const (
 	RegExpNumber          = `^\d+$`
 	RegExpTimeshift       = `^[+-]\d{1,4}$`
 	RegExpTwoDigitsNumber = `^\d{1,2}$`
+	RegExpBool            = `^(true|false)$`
+	RegExpFormat          = `^csv$`
+	RegExpAccredType      = `^[a-zA-Z0-9_-]{1,64}$`
+	RegExpOverviewSort    = `^(realm|users|activeUsers|loginsLastDay|loginsLastWeek|loginsLastMonth|failedLoginRate)$`
+	RegExpSortOrder       = `^(asc|desc)$`
+)
+
+// Status of a migration report
+const (
+	MigrationReportStatusDone    = "done"
+	MigrationReportStatusPending = "pending"
 )
 
 // ActionRepresentation struct
@@ -51,6 +68,165 @@ type StatisticsConnectionRepresentation struct {
 	IP     string `json:"IP"`
 }
 
+// StatisticsFailuresRepresentation elements returned by GetStatisticsFailures
+type StatisticsFailuresRepresentation struct {
+	FailedAuthentications StatisticsConnectionsRepresentation `json:"failedAuthentications,omitempty"`
+	Lockouts              StatisticsConnectionsRepresentation `json:"lockouts,omitempty"`
+}
+
+// StatisticsFailuresBreakdownRepresentation elements returned by GetStatisticsFailuresBreakdown
+type StatisticsFailuresBreakdownRepresentation struct {
+	ByErrorCode map[string]int64 `json:"byErrorCode"`
+	ByClientID  map[string]int64 `json:"byClientId"`
+}
+
+// StatisticsRatioRepresentation elements returned by GetStatisticsAuthenticationsRatio
+type StatisticsRatioRepresentation struct {
+	Period       int64   `json:"period"`
+	Successes    int64   `json:"successes"`
+	Failures     int64   `json:"failures"`
+	SuccessRatio float64 `json:"successRatio"`
+}
+
+// StatisticsRegistrationsRepresentation elements returned by GetStatisticsRegistrations
+type StatisticsRegistrationsRepresentation struct {
+	Steps     []StatisticsRegistrationStepRepresentation   `json:"steps"`
+	Periods   []StatisticsRegistrationPeriodRepresentation `json:"periods"`
+	Abandoned int64                                        `json:"abandoned"`
+}
+
+// StatisticsRegistrationPeriodRepresentation is the registration funnel of the users registered during a period
+type StatisticsRegistrationPeriodRepresentation struct {
+	Period int64                                      `json:"period"`
+	Steps  []StatisticsRegistrationStepRepresentation `json:"steps"`
+}
+
+// StatisticsRegistrationStepRepresentation is a step of the registration funnel
+// ConversionRate is relative to the number of registered users, MedianDelay is given in seconds since the previous step reached by the users
+type StatisticsRegistrationStepRepresentation struct {
+	Name           string  `json:"name"`
+	Count          int64   `json:"count"`
+	ConversionRate float64 `json:"conversionRate"`
+	MedianDelay    *int64  `json:"medianDelay,omitempty"`
+}
+
+// MigrationReportSummaryRepresentation elements returned by GetMigrationReportSummary
+type MigrationReportSummaryRepresentation struct {
+	Status      string                                        `json:"status"`
+	ComputedAt  *int64                                        `json:"computedAt,omitempty"`
+	Migrated    int64                                         `json:"migrated"`
+	NotMigrated int64                                         `json:"notMigrated"`
+	Groups      map[string]MigrationReportCountRepresentation `json:"groups,omitempty"`
+}
+
+// MigrationReportCountRepresentation is the number of migrated and not migrated users of a group
+type MigrationReportCountRepresentation struct {
+	Migrated    int64 `json:"migrated"`
+	NotMigrated int64 `json:"notMigrated"`
+}
+
+// MigrationReportUsersPageRepresentation elements returned by GetMigrationReportUsers
+type MigrationReportUsersPageRepresentation struct {
+	Users      []MigrationReportUserRepresentation `json:"users"`
+	Count      int                                 `json:"count"`
+	ComputedAt *int64                              `json:"computedAt,omitempty"`
+}
+
+// MigrationReportUserRepresentation is the migration status of a user
+type MigrationReportUserRepresentation struct {
+	Username string `json:"username"`
+	Migrated bool   `json:"migrated"`
+}
+
+// InactiveUsersPageRepresentation elements returned by GetInactiveUsers
+type InactiveUsersPageRepresentation struct {
+	Users      []InactiveUserRepresentation `json:"users"`
+	Count      int                          `json:"count"`
+	ComputedAt *int64                       `json:"computedAt,omitempty"`
+}
+
+// InactiveUserRepresentation is a user who did not log in since a given number of days
+// CreatedTimestamp is given in milliseconds (as in Keycloak) and LastConnection in seconds (as in the other statistics)
+type InactiveUserRepresentation struct {
+	ID               string `json:"id"`
+	Username         string `json:"username"`
+	Enabled          bool   `json:"enabled"`
+	CreatedTimestamp *int64 `json:"createdTimestamp,omitempty"`
+	LastConnection   *int64 `json:"lastConnection,omitempty"`
+}
+
+// InactiveUsersLockMaxUsers is the maximum number of users locked by a call to LockInactiveUsers
+const InactiveUsersLockMaxUsers = 1000
+
+// InactiveUsersLockRequest gives the IDs of the inactive users reviewed by the operator
+type InactiveUsersLockRequest struct {
+	UserIDs []string `json:"userIds"`
+}
+
+// InactiveUsersLockRepresentation elements returned by LockInactiveUsers. Skipped users are no longer inactive or are already locked
+type InactiveUsersLockRepresentation struct {
+	Locked  []string `json:"locked"`
+	Skipped []string `json:"skipped"`
+	Failed  []string `json:"failed"`
+}
+
+// Validate is a validator for InactiveUsersLockRequest
+func (r InactiveUsersLockRequest) Validate() error {
+	if len(r.UserIDs) == 0 {
+		return errorhandler.CreateMissingParameterError(constants.UserIDs)
+	}
+	if len(r.UserIDs) > InactiveUsersLockMaxUsers {
+		return errorhandler.CreateBadRequestError(constants.MsgErrInvalidParam + "." + constants.UserIDs)
+	}
+	var v = validation.NewParameterValidator()
+	for _, userID := range r.UserIDs {
+		v = v.ValidateParameterRegExp(constants.UserID, &userID, constants.RegExpID, true)
+	}
+	return v.Status()
+}
+
+// StatisticsAccreditationsRepresentation counts the accreditations of a given type
+type StatisticsAccreditationsRepresentation struct {
+	Active  int64 `json:"active"`
+	Revoked int64 `json:"revoked"`
+	Expired int64 `json:"expired"`
+}
+
+// ExpiringAccreditationsPageRepresentation elements returned by GetExpiringAccreditations
+type ExpiringAccreditationsPageRepresentation struct {
+	Accreditations []ExpiringAccreditationRepresentation `json:"accreditations"`
+	Count          int                                   `json:"count"`
+}
+
+// ExpiringAccreditationRepresentation is an active accreditation which expires soon
+type ExpiringAccreditationRepresentation struct {
+	UserID     string `json:"userId"`
+	Username   string `json:"username"`
+	Type       string `json:"type"`
+	ExpiryDate string `json:"expiryDate"`
+}
+
+// StatisticsOverviewRepresentation elements returned by GetStatisticsOverview
+// The statistics of the unavailable realms could not be computed: they are not part of Realms
+type StatisticsOverviewRepresentation struct {
+	Realms            []StatisticsRealmOverviewRepresentation `json:"realms"`
+	UnavailableRealms []string                                `json:"unavailableRealms"`
+}
+
+// StatisticsRealmOverviewRepresentation summarises the statistics of a realm
+// Active users are the users who successfully logged in during the last month. The failed login rate is computed on the last month.
+type StatisticsRealmOverviewRepresentation struct {
+	Realm                 string  `json:"realm"`
+	Users                 int64   `json:"users"`
+	DisabledUsers         int64   `json:"disabledUsers"`
+	ActiveUsers           int64   `json:"activeUsers"`
+	LoginsLastDay         int64   `json:"loginsLastDay"`
+	LoginsLastWeek        int64   `json:"loginsLastWeek"`
+	LoginsLastMonth       int64   `json:"loginsLastMonth"`
+	FailedLoginsLastMonth int64   `json:"failedLoginsLastMonth"`
+	FailedLoginRate       float64 `json:"failedLoginRate"`
+}
+
 // DbConnectionRepresentation is a non serializable StatisticsConnectionRepresentation read from database
 type DbConnectionRepresentation struct {
 	Date   sql.NullString
@@ -69,6 +245,77 @@ func (dbc *DbConnectionRepresentation) ToConnRepresentation() StatisticsConnecti
 	}
 }
 
+// Add counts a migrated or not migrated user
+func (s *MigrationReportSummaryRepresentation) Add(migrated bool) {
+	if migrated {
+		s.Migrated++
+	} else {
+		s.NotMigrated++
+	}
+}
+
+// Add counts a migrated or not migrated user
+func (c *MigrationReportCountRepresentation) Add(migrated bool) {
+	if migrated {
+		c.Migrated++
+	} else {
+		c.NotMigrated++
+	}
+}
+
+// ToCSV converts a page of inactive users to CSV records, including a header
+func (p InactiveUsersPageRepresentation) ToCSV() [][]string {
+	var formatTime = func(value *int64, unit time.Duration) string {
+		if value == nil {
+			return ""
+		}
+		return time.Unix(0, *value*int64(unit)).UTC().Format(time.RFC3339)
+	}
+
+	var res = [][]string{{"id", "username", "enabled", "createdTimestamp", "lastConnection"}}
+	for _, user := range p.Users {
+		res = append(res, []string{
+			escapeCSVFormula(user.ID),
+			escapeCSVFormula(user.Username),
+			strconv.FormatBool(user.Enabled),
+			formatTime(user.CreatedTimestamp, time.Millisecond),
+			formatTime(user.LastConnection, time.Second),
+		})
+	}
+	return res
+}
+
+// escapeCSVFormula prevents a value from being interpreted as a formula when the CSV file is opened in a spreadsheet
+func escapeCSVFormula(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
+
+// ToCSV converts a statistics overview to CSV records, including a header. Unavailable realms come last, without values
+func (o StatisticsOverviewRepresentation) ToCSV() [][]string {
+	var res = [][]string{{"realm", "users", "disabledUsers", "activeUsers", "loginsLastDay", "loginsLastWeek", "loginsLastMonth", "failedLoginsLastMonth", "failedLoginRate", "status"}}
+	for _, realm := range o.Realms {
+		res = append(res, []string{
+			realm.Realm,
+			strconv.FormatInt(realm.Users, 10),
+			strconv.FormatInt(realm.DisabledUsers, 10),
+			strconv.FormatInt(realm.ActiveUsers, 10),
+			strconv.FormatInt(realm.LoginsLastDay, 10),
+			strconv.FormatInt(realm.LoginsLastWeek, 10),
+			strconv.FormatInt(realm.LoginsLastMonth, 10),
+			strconv.FormatInt(realm.FailedLoginsLastMonth, 10),
+			strconv.FormatFloat(realm.FailedLoginRate, 'f', 4, 64),
+			"ok",
+		})
+	}
+	for _, realm := range o.UnavailableRealms {
+		res = append(res, []string{realm, "", "", "", "", "", "", "", "", "unavailable"})
+	}
+	return res
+}
+
 // ConvertToAPIStatisticsUsers converts users statistics from KC model to API one
 func ConvertToAPIStatisticsUsers(statistics kc.StatisticsUsersRepresentation) StatisticsUsersRepresentation {
 	var statisticsAPI = StatisticsUsersRepresentation{}
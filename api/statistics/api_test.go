@@ -34,3 +34,28 @@ func TestConvertToAPIStatisticsUsers(t *testing.T) {
 	}
 	assert.Equal(t, expected, ConvertToAPIStatisticsUsers(stats))
 }
+
+func TestInactiveUsersToCSV(t *testing.T) {
+	var page = InactiveUsersPageRepresentation{Users: []InactiveUserRepresentation{
+		{ID: "id-1", Username: "john", Enabled: true},
+		{ID: "id-2", Username: "=HYPERLINK(\"http://evil\")"},
+		{ID: "id-3", Username: "-2+3"},
+		{ID: "id-4", Username: "@SUM(A1)"},
+	}}
+
+	var records = page.ToCSV()
+
+	assert.Len(t, records, 5)
+	assert.Equal(t, []string{"id-1", "john", "true", "", ""}, records[1])
+	assert.Equal(t, "'=HYPERLINK(\"http://evil\")", records[2][1])
+	assert.Equal(t, "'-2+3", records[3][1])
+	assert.Equal(t, "'@SUM(A1)", records[4][1])
+}
+
+func TestValidateInactiveUsersLockRequest(t *testing.T) {
+	var userID = "f467ed7c-0a1d-4eee-9bb8-669c6f89c000"
+	assert.NotNil(t, InactiveUsersLockRequest{}.Validate())
+	assert.NotNil(t, InactiveUsersLockRequest{UserIDs: []string{"not an ID"}}.Validate())
+	assert.NotNil(t, InactiveUsersLockRequest{UserIDs: make([]string, InactiveUsersLockMaxUsers+1)}.Validate())
+	assert.Nil(t, InactiveUsersLockRequest{UserIDs: []string{userID}}.Validate())
+}
@@ -136,6 +136,15 @@ const (
 	cfgSsePublicURL             = "sse-public-url"
 	cfgDbAesGcmKey              = "db-aesgcm-key"
 	cfgDbAesGcmTagSize          = "db-aesgcm-tag-size"
+	cfgDbBlindIndexKey          = "db-blind-index-key"
+	cfgDbBlindIndexBackfill     = "db-blind-index-backfill"
+	cfgStatisticsRollups        = "statistics-rollups"
+	cfgStatisticsRollupsDays    = "statistics-rollups-backfill-days"
+	cfgBulkOperationsRate       = "bulk-operations-rate"
+	cfgSagaReconcileInterval    = "saga-reconcile-interval"
+	cfgSagaGracePeriod          = "saga-grace-period"
+	cfgTrustIDGroupsExpiry      = "trustid-groups-expiry-interval"
+	cfgPendingRequestsExpiry    = "pending-requests-expiration"
 )
 
 func init() {
@@ -240,6 +249,26 @@ func main() {
 		// Access logs
 		accessLogsEnabled = c.GetBool(cfgAccessLogsEnabled)
 
+		// Statistics rollups
+		statisticsRollupsEnabled      = c.GetBool(cfgStatisticsRollups)
+		statisticsRollupsBackfillDays = c.GetInt(cfgStatisticsRollupsDays)
+
+		// Bulk operations and imports of users: maximum number of users processed per second
+		bulkOperationsRate = c.GetInt(cfgBulkOperationsRate)
+
+		// Operations left pending in Keycloak and users DB are reconciled at this interval once older than the grace period
+		sagaReconcileInterval = c.GetDuration(cfgSagaReconcileInterval)
+		sagaGracePeriod       = c.GetDuration(cfgSagaGracePeriod)
+
+		// User details stored before the ID document number was indexed are indexed at startup
+		blindIndexBackfill = c.GetBool(cfgDbBlindIndexBackfill)
+
+		// TrustID groups assigned for a limited time are removed at this interval once expired
+		trustIDGroupsExpiryInterval = c.GetDuration(cfgTrustIDGroupsExpiry)
+
+		// Sensitive actions waiting for the approval of a second operator expire after this delay
+		pendingRequestsExpiration = c.GetDuration(cfgPendingRequestsExpiry)
+
 		// Register parameters
 		registerEnabled  = c.GetBool(cfgRegisterEnabled)
 		registerRealm    = c.GetString(cfgRegisterRealm)
@@ -329,6 +358,13 @@ func main() {
 		return
 	}
 
+	// Security - blind index used to search users by encrypted PII
+	blindIndex, err := keycloakb.NewBlindIndexFromBase64(c.GetString(cfgDbBlindIndexKey))
+	if err != nil {
+		logger.Error(ctx, "msg", "could not create blind index instance", "error", err)
+		return
+	}
+
 	// Security - allowed trustID groups
 	var trustIDGroups = c.GetStringSlice(cfgTrustIDGroups)
 
@@ -503,6 +539,62 @@ func main() {
 		}
 	}
 
+	// Statistics rollups: counters are maintained when events are stored and read by the statistics service
+	var rollupsDBModule keycloakb.RollupsDBModule
+	if statisticsRollupsEnabled {
+		rollupsDBModule = keycloakb.NewRollupsDBModule(eventsDBConn)
+
+		if statisticsRollupsBackfillDays > 0 {
+			go func() {
+				var rollupsLogger = log.With(logger, "svc", "rollups")
+				// The counters of the current day are live: only the past days are recomputed
+				var to = time.Now().UTC().Truncate(24 * time.Hour)
+				var from = to.AddDate(0, 0, -statisticsRollupsBackfillDays)
+				if err := rollupsDBModule.Backfill(context.Background(), from, to); err != nil {
+					rollupsLogger.Error(ctx, "msg", "could not backfill statistics rollups", "err", err.Error())
+					return
+				}
+				rollupsLogger.Info(ctx, "msg", "statistics rollups backfilled", "from", from, "to", to)
+			}()
+		}
+	}
+
+	// ID document numbers stored before the blind index was introduced are indexed in the background
+	if blindIndexBackfill {
+		go func() {
+			var blindIndexLogger = log.With(logger, "svc", "blind_index")
+			var usersDBModule = keycloakb.NewUsersDetailsDBModule(usersRwDBConn, aesEncryption, blindIndex, blindIndexLogger)
+			var indexed, err = usersDBModule.IndexIDDocumentNumbers(context.Background())
+			if err != nil {
+				blindIndexLogger.Error(ctx, "msg", "could not index ID document numbers", "err", err.Error(), "indexed", indexed)
+				return
+			}
+			blindIndexLogger.Info(ctx, "msg", "ID document numbers indexed", "indexed", indexed)
+		}()
+	}
+
+	// Operations written both in Keycloak and in the users DB: failures are compensated and operations left pending
+	// (for instance after a crash) are reconciled periodically
+	var sagaModule keycloakb.SagaModule
+	{
+		if sagaReconcileInterval <= 0 || sagaGracePeriod <= 0 {
+			logger.Error(ctx, "msg", "saga reconciliation interval (saga-reconcile-interval) and grace period (saga-grace-period) must be positive")
+			return
+		}
+
+		var sagaLogger = log.With(logger, "svc", "saga")
+		var usersDBModule = keycloakb.NewUsersDetailsDBModule(usersRwDBConn, aesEncryption, blindIndex, sagaLogger)
+		sagaModule = keycloakb.NewSagaModule(usersRwDBConn, aesEncryption, keycloakClient, usersDBModule, technicalTokenProvider, sagaLogger)
+
+		go func() {
+			var tic = time.NewTicker(sagaReconcileInterval)
+			defer tic.Stop()
+			for range tic.C {
+				_ = sagaModule.Reconcile(context.Background(), sagaGracePeriod)
+			}
+		}()
+	}
+
 	// Event service.
 	var eventEndpoints = event.Endpoints{}
 	{
@@ -528,6 +620,9 @@ func main() {
 		var eventsDBModule database.EventsDBModule
 		{
 			eventsDBModule = database.NewEventsDBModule(eventsDBConn)
+			if rollupsDBModule != nil {
+				eventsDBModule = event.MakeEventsDBModuleRollupMW(rollupsDBModule, log.With(eventLogger, "mw", "module", "unit", "rollups"))(eventsDBModule)
+			}
 			eventsDBModule = event.MakeEventsDBModuleInstrumentingMW(influxMetrics.NewHistogram("eventsDB_module"))(eventsDBModule)
 			eventsDBModule = event.MakeEventsDBModuleLoggingMW(log.With(eventLogger, "mw", "module", "unit", "eventsDB"))(eventsDBModule)
 			eventsDBModule = event.MakeEventsDBModuleTracingMW(tracer)(eventsDBModule)
@@ -576,10 +671,34 @@ func main() {
 	}
 
 	baseEventsDBModule := database.NewEventsDBModule(eventsDBConn)
+	if rollupsDBModule != nil {
+		baseEventsDBModule = event.MakeEventsDBModuleRollupMW(rollupsDBModule, log.With(logger, "mw", "module", "unit", "rollups"))(baseEventsDBModule)
+	}
 
 	// new module for reading events from the DB
 	eventsRODBModule := keycloakb.NewEventsDBModule(eventsRODBConn)
 
+	// TrustID groups assigned for a limited time are removed periodically once expired
+	{
+		if trustIDGroupsExpiryInterval <= 0 {
+			logger.Error(ctx, "msg", "TrustID groups expiry interval (trustid-groups-expiry-interval) must be positive")
+			return
+		}
+
+		var trustIDGroupsLogger = log.With(logger, "svc", "trustid_groups")
+		var usersDBModule = keycloakb.NewUsersDetailsDBModule(usersRwDBConn, aesEncryption, blindIndex, trustIDGroupsLogger)
+		var eventsDBModule = configureEventsDbModule(baseEventsDBModule, influxMetrics, trustIDGroupsLogger, tracer)
+		var trustIDGroupsModule = keycloakb.NewTrustIDGroupsModule(keycloakClient, usersDBModule, eventsDBModule, technicalTokenProvider, trustIDGroupsLogger)
+
+		go func() {
+			var tic = time.NewTicker(trustIDGroupsExpiryInterval)
+			defer tic.Stop()
+			for range tic.C {
+				_ = trustIDGroupsModule.RemoveExpired(context.Background())
+			}
+		}()
+	}
+
 	// Validation service.
 	var validationEndpoints validation.Endpoints
 	{
@@ -589,7 +708,7 @@ func main() {
 		eventsDBModule := configureEventsDbModule(baseEventsDBModule, influxMetrics, validationLogger, tracer)
 
 		// module for storing and retrieving details of the users
-		var usersDBModule = keycloakb.NewUsersDetailsDBModule(usersRwDBConn, aesEncryption, validationLogger)
+		var usersDBModule = keycloakb.NewUsersDetailsDBModule(usersRwDBConn, aesEncryption, blindIndex, validationLogger)
 
 		// accreditations module
 		var accredsModule keycloakb.AccreditationsModule
@@ -608,26 +727,6 @@ func main() {
 		}
 	}
 
-	// Statistics service.
-	var statisticsEndpoints statistics.Endpoints
-	{
-		var statisticsLogger = log.With(logger, "svc", "statistics")
-
-		statisticsComponent := statistics.NewComponent(eventsRODBModule, keycloakClient, statisticsLogger)
-		statisticsComponent = statistics.MakeAuthorizationManagementComponentMW(log.With(statisticsLogger, "mw", "endpoint"), authorizationManager)(statisticsComponent)
-
-		var rateLimitStatistics = rateLimit[RateKeyStatistics]
-		statisticsEndpoints = statistics.Endpoints{
-			GetActions:                      prepareEndpoint(statistics.MakeGetActionsEndpoint(statisticsComponent), "get_actions", influxMetrics, statisticsLogger, tracer, rateLimitStatistics),
-			GetStatistics:                   prepareEndpoint(statistics.MakeGetStatisticsEndpoint(statisticsComponent), "get_statistics", influxMetrics, statisticsLogger, tracer, rateLimitStatistics),
-			GetStatisticsUsers:              prepareEndpoint(statistics.MakeGetStatisticsUsersEndpoint(statisticsComponent), "get_statistics_users", influxMetrics, statisticsLogger, tracer, rateLimitStatistics),
-			GetStatisticsAuthentications:    prepareEndpoint(statistics.MakeGetStatisticsAuthenticationsEndpoint(statisticsComponent), "get_statistics_authentications", influxMetrics, statisticsLogger, tracer, rateLimitStatistics),
-			GetStatisticsAuthenticationsLog: prepareEndpoint(statistics.MakeGetStatisticsAuthenticationsLogEndpoint(statisticsComponent), "get_statistics_authentications_log", influxMetrics, statisticsLogger, tracer, rateLimitStatistics),
-			GetStatisticsAuthenticators:     prepareEndpoint(statistics.MakeGetStatisticsAuthenticatorsEndpoint(statisticsComponent), "get_statistics_authenticators", influxMetrics, statisticsLogger, tracer, rateLimitStatistics),
-			GetMigrationReport:              prepareEndpoint(statistics.MakeGetMigrationReportEndpoint(statisticsComponent), "get_migration_report", influxMetrics, statisticsLogger, tracer, rateLimitStatistics),
-		}
-	}
-
 	// Events service.
 	var eventsEndpoints events.Endpoints
 	{
@@ -649,6 +748,7 @@ func main() {
 	}
 
 	// Management service.
+	var managementComponent management.Component
 	var managementEndpoints = management.Endpoints{}
 	{
 		var managementLogger = log.With(logger, "svc", "management")
@@ -660,13 +760,34 @@ func main() {
 		var configDBModule = createConfigurationDBModule(configurationRwDBConn, influxMetrics, managementLogger)
 
 		// module for storing and retrieving details of the users
-		var usersDBModule = keycloakb.NewUsersDetailsDBModule(usersRwDBConn, aesEncryption, managementLogger)
+		var usersDBModule = keycloakb.NewUsersDetailsDBModule(usersRwDBConn, aesEncryption, blindIndex, managementLogger)
+
+		// bulk operations and users imports share the same rate
+		bulkLimiter, err := management.NewBulkLimiter(bulkOperationsRate)
+		if err != nil {
+			logger.Error(ctx, "msg", "could not create bulk operations limiter", "error", err)
+			return
+		}
+
+		if pendingRequestsExpiration <= 0 {
+			logger.Error(ctx, "msg", "pending requests expiration (pending-requests-expiration) must be positive")
+			return
+		}
 
 		var keycloakComponent management.Component
 		{
-			keycloakComponent = management.NewComponent(keycloakClient, usersDBModule, eventsDBModule, configDBModule, trustIDGroups, managementLogger)
+			keycloakComponent = management.NewComponent(keycloakClient, usersDBModule, eventsDBModule, configDBModule, sagaModule, trustIDGroups, bulkLimiter, managementLogger)
+			keycloakComponent = management.MakeApprovalComponentMW(keycloakClient, configDBModule, eventsDBModule, pendingRequestsExpiration, managementLogger)(keycloakComponent)
 			keycloakComponent = management.MakeAuthorizationManagementComponentMW(log.With(managementLogger, "mw", "endpoint"), authorizationManager)(keycloakComponent)
 		}
+		managementComponent = keycloakComponent
+
+		// bulk operations go through the authorization middleware for each user
+		var bulkComponent management.BulkComponent
+		{
+			bulkComponent = management.NewBulkComponent(keycloakComponent, bulkLimiter, managementLogger)
+			bulkComponent = management.MakeAuthorizationBulkComponentMW(log.With(managementLogger, "mw", "endpoint"), authorizationManager)(bulkComponent)
+		}
 
 		var rateLimitMgmt = rateLimit[RateKeyManagement]
 		managementEndpoints = management.Endpoints{
@@ -680,12 +801,21 @@ func main() {
 			GetRequiredActions: prepareEndpoint(management.MakeGetRequiredActionsEndpoint(keycloakComponent), "get_required-actions_endpoint", influxMetrics, managementLogger, tracer, rateLimitMgmt),
 
 			CreateUser:                prepareEndpoint(management.MakeCreateUserEndpoint(keycloakComponent, managementLogger), "create_user_endpoint", influxMetrics, managementLogger, tracer, rateLimitMgmt),
+			ImportUsers:               prepareEndpoint(management.MakeImportUsersEndpoint(keycloakComponent), "import_users_endpoint", influxMetrics, managementLogger, tracer, rateLimitMgmt),
+			GetUsersImportJob:         prepareEndpoint(management.MakeGetUsersImportJobEndpoint(keycloakComponent), "get_users_import_job_endpoint", influxMetrics, managementLogger, tracer, rateLimitMgmt),
+			GetUsersImportResults:     prepareEndpoint(management.MakeGetUsersImportResultsEndpoint(keycloakComponent), "get_users_import_results_endpoint", influxMetrics, managementLogger, tracer, rateLimitMgmt),
+			StartBulkOperation:        prepareEndpoint(management.MakeStartBulkOperationEndpoint(bulkComponent), "start_bulk_operation_endpoint", influxMetrics, managementLogger, tracer, rateLimitMgmt),
+			GetBulkOperation:          prepareEndpoint(management.MakeGetBulkOperationEndpoint(bulkComponent), "get_bulk_operation_endpoint", influxMetrics, managementLogger, tracer, rateLimitMgmt),
 			GetUser:                   prepareEndpoint(management.MakeGetUserEndpoint(keycloakComponent), "get_user_endpoint", influxMetrics, managementLogger, tracer, rateLimitMgmt),
 			UpdateUser:                prepareEndpoint(management.MakeUpdateUserEndpoint(keycloakComponent), "update_user_endpoint", influxMetrics, managementLogger, tracer, rateLimitMgmt),
+			GetUserHistory:            prepareEndpoint(management.MakeGetUserHistoryEndpoint(keycloakComponent), "get_user_history_endpoint", influxMetrics, managementLogger, tracer, rateLimitMgmt),
 			LockUser:                  prepareEndpoint(management.MakeLockUserEndpoint(keycloakComponent), "lock_user_endpoint", influxMetrics, managementLogger, tracer, rateLimitMgmt),
 			UnlockUser:                prepareEndpoint(management.MakeUnlockUserEndpoint(keycloakComponent), "unlock_user_endpoint", influxMetrics, managementLogger, tracer, rateLimitMgmt),
 			DeleteUser:                prepareEndpoint(management.MakeDeleteUserEndpoint(keycloakComponent), "delete_user_endpoint", influxMetrics, managementLogger, tracer, rateLimitMgmt),
 			GetUsers:                  prepareEndpoint(management.MakeGetUsersEndpoint(keycloakComponent), "get_users_endpoint", influxMetrics, managementLogger, tracer, rateLimitMgmt),
+			SearchUsers:               prepareEndpoint(management.MakeSearchUsersEndpoint(keycloakComponent), "search_users_endpoint", influxMetrics, managementLogger, tracer, rateLimitMgmt),
+			GetDuplicateUsers:         prepareEndpoint(management.MakeGetDuplicateUsersEndpoint(keycloakComponent), "get_duplicate_users_endpoint", influxMetrics, managementLogger, tracer, rateLimitMgmt),
+			MergeUsers:                prepareEndpoint(management.MakeMergeUsersEndpoint(keycloakComponent), "merge_users_endpoint", influxMetrics, managementLogger, tracer, rateLimitMgmt),
 			GetUserAccountStatus:      prepareEndpoint(management.MakeGetUserAccountStatusEndpoint(keycloakComponent), "get_user_accountstatus", influxMetrics, managementLogger, tracer, rateLimitMgmt),
 			GetGroupsOfUser:           prepareEndpoint(management.MakeGetGroupsOfUserEndpoint(keycloakComponent), "get_user_groups", influxMetrics, managementLogger, tracer, rateLimitMgmt),
 			AddGroupToUser:            prepareEndpoint(management.MakeAddGroupToUserEndpoint(keycloakComponent), "add_user_group", influxMetrics, managementLogger, tracer, rateLimitMgmt),
@@ -695,19 +825,50 @@ func main() {
 			SetTrustIDGroupsToUser:    prepareEndpoint(management.MakeSetTrustIDGroupsToUserEndpoint(keycloakComponent), "set_user_trustid_groups_endpoint", influxMetrics, managementLogger, tracer, rateLimitMgmt),
 			GetRolesOfUser:            prepareEndpoint(management.MakeGetRolesOfUserEndpoint(keycloakComponent), "get_user_roles", influxMetrics, managementLogger, tracer, rateLimitMgmt),
 
-			GetRoles: prepareEndpoint(management.MakeGetRolesEndpoint(keycloakComponent), "get_roles_endpoint", influxMetrics, managementLogger, tracer, rateLimitMgmt),
-			GetRole:  prepareEndpoint(management.MakeGetRoleEndpoint(keycloakComponent), "get_role_endpoint", influxMetrics, managementLogger, tracer, rateLimitMgmt),
+			GetRoles:             prepareEndpoint(management.MakeGetRolesEndpoint(keycloakComponent), "get_roles_endpoint", influxMetrics, managementLogger, tracer, rateLimitMgmt),
+			GetRole:              prepareEndpoint(management.MakeGetRoleEndpoint(keycloakComponent), "get_role_endpoint", influxMetrics, managementLogger, tracer, rateLimitMgmt),
+			CreateRole:           prepareEndpoint(management.MakeCreateRoleEndpoint(keycloakComponent, managementLogger), "create_role_endpoint", influxMetrics, managementLogger, tracer, rateLimitMgmt),
+			UpdateRole:           prepareEndpoint(management.MakeUpdateRoleEndpoint(keycloakComponent), "update_role_endpoint", influxMetrics, managementLogger, tracer, rateLimitMgmt),
+			DeleteRole:           prepareEndpoint(management.MakeDeleteRoleEndpoint(keycloakComponent), "delete_role_endpoint", influxMetrics, managementLogger, tracer, rateLimitMgmt),
+			GetRoleComposites:    prepareEndpoint(management.MakeGetRoleCompositesEndpoint(keycloakComponent), "get_role_composites_endpoint", influxMetrics, managementLogger, tracer, rateLimitMgmt),
+			AddRoleComposites:    prepareEndpoint(management.MakeAddRoleCompositesEndpoint(keycloakComponent), "add_role_composites_endpoint", influxMetrics, managementLogger, tracer, rateLimitMgmt),
+			DeleteRoleComposites: prepareEndpoint(management.MakeDeleteRoleCompositesEndpoint(keycloakComponent), "delete_role_composites_endpoint", influxMetrics, managementLogger, tracer, rateLimitMgmt),
+			GetUsersWithRole:     prepareEndpoint(management.MakeGetUsersWithRoleEndpoint(keycloakComponent), "get_users_with_role_endpoint", influxMetrics, managementLogger, tracer, rateLimitMgmt),
 
 			GetGroups:            prepareEndpoint(management.MakeGetGroupsEndpoint(keycloakComponent), "get_groups_endpoint", influxMetrics, managementLogger, tracer, rateLimitMgmt),
 			CreateGroup:          prepareEndpoint(management.MakeCreateGroupEndpoint(keycloakComponent, managementLogger), "create_group_endpoint", influxMetrics, managementLogger, tracer, rateLimitMgmt),
 			DeleteGroup:          prepareEndpoint(management.MakeDeleteGroupEndpoint(keycloakComponent), "delete_group_endpoint", influxMetrics, managementLogger, tracer, rateLimitMgmt),
+			GetGroup:             prepareEndpoint(management.MakeGetGroupEndpoint(keycloakComponent), "get_group_endpoint", influxMetrics, managementLogger, tracer, rateLimitMgmt),
+			UpdateGroup:          prepareEndpoint(management.MakeUpdateGroupEndpoint(keycloakComponent), "update_group_endpoint", influxMetrics, managementLogger, tracer, rateLimitMgmt),
+			CreateGroupChild:     prepareEndpoint(management.MakeCreateGroupChildEndpoint(keycloakComponent, managementLogger), "create_group_child_endpoint", influxMetrics, managementLogger, tracer, rateLimitMgmt),
+			MoveGroup:            prepareEndpoint(management.MakeMoveGroupEndpoint(keycloakComponent), "move_group_endpoint", influxMetrics, managementLogger, tracer, rateLimitMgmt),
+			GetGroupMembers:      prepareEndpoint(management.MakeGetGroupMembersEndpoint(keycloakComponent), "get_group_members_endpoint", influxMetrics, managementLogger, tracer, rateLimitMgmt),
 			GetAuthorizations:    prepareEndpoint(management.MakeGetAuthorizationsEndpoint(keycloakComponent), "get_authorizations_endpoint", influxMetrics, managementLogger, tracer, rateLimitMgmt),
 			UpdateAuthorizations: prepareEndpoint(management.MakeUpdateAuthorizationsEndpoint(keycloakComponent), "update_authorizations_endpoint", influxMetrics, managementLogger, tracer, rateLimitMgmt),
-
-			GetClientRoles:       prepareEndpoint(management.MakeGetClientRolesEndpoint(keycloakComponent), "get_client_roles_endpoint", influxMetrics, managementLogger, tracer, rateLimitMgmt),
-			CreateClientRole:     prepareEndpoint(management.MakeCreateClientRoleEndpoint(keycloakComponent, managementLogger), "create_client_role_endpoint", influxMetrics, managementLogger, tracer, rateLimitMgmt),
-			GetClientRoleForUser: prepareEndpoint(management.MakeGetClientRolesForUserEndpoint(keycloakComponent), "get_client_roles_for_user_endpoint", influxMetrics, managementLogger, tracer, rateLimitMgmt),
-			AddClientRoleToUser:  prepareEndpoint(management.MakeAddClientRolesToUserEndpoint(keycloakComponent), "get_client_roles_for_user_endpoint", influxMetrics, managementLogger, tracer, rateLimitMgmt),
+			CheckAuthorization:   prepareEndpoint(management.MakeCheckAuthorizationEndpoint(keycloakComponent), "check_authorization_endpoint", influxMetrics, managementLogger, tracer, rateLimitMgmt),
+
+			GetAuthorizationTemplates:   prepareEndpoint(management.MakeGetAuthorizationTemplatesEndpoint(keycloakComponent), "get_authorization_templates_endpoint", influxMetrics, managementLogger, tracer, rateLimitMgmt),
+			GetAuthorizationTemplate:    prepareEndpoint(management.MakeGetAuthorizationTemplateEndpoint(keycloakComponent), "get_authorization_template_endpoint", influxMetrics, managementLogger, tracer, rateLimitMgmt),
+			UpdateAuthorizationTemplate: prepareEndpoint(management.MakeUpdateAuthorizationTemplateEndpoint(keycloakComponent), "update_authorization_template_endpoint", influxMetrics, managementLogger, tracer, rateLimitMgmt),
+			DeleteAuthorizationTemplate: prepareEndpoint(management.MakeDeleteAuthorizationTemplateEndpoint(keycloakComponent), "delete_authorization_template_endpoint", influxMetrics, managementLogger, tracer, rateLimitMgmt),
+			ApplyAuthorizationTemplate:  prepareEndpoint(management.MakeApplyAuthorizationTemplateEndpoint(keycloakComponent), "apply_authorization_template_endpoint", influxMetrics, managementLogger, tracer, rateLimitMgmt),
+			CopyAuthorizations:          prepareEndpoint(management.MakeCopyAuthorizationsEndpoint(keycloakComponent), "copy_authorizations_endpoint", influxMetrics, managementLogger, tracer, rateLimitMgmt),
+
+			GetAuthorizationsVersions:     prepareEndpoint(management.MakeGetAuthorizationsVersionsEndpoint(keycloakComponent), "get_authorizations_versions_endpoint", influxMetrics, managementLogger, tracer, rateLimitMgmt),
+			GetAuthorizationsVersionsDiff: prepareEndpoint(management.MakeGetAuthorizationsVersionsDiffEndpoint(keycloakComponent), "get_authorizations_versions_diff_endpoint", influxMetrics, managementLogger, tracer, rateLimitMgmt),
+			RollbackAuthorizations:        prepareEndpoint(management.MakeRollbackAuthorizationsEndpoint(keycloakComponent), "rollback_authorizations_endpoint", influxMetrics, managementLogger, tracer, rateLimitMgmt),
+
+			GetPendingRequests:    prepareEndpoint(management.MakeGetPendingRequestsEndpoint(keycloakComponent), "get_pending_requests_endpoint", influxMetrics, managementLogger, tracer, rateLimitMgmt),
+			GetPendingRequest:     prepareEndpoint(management.MakeGetPendingRequestEndpoint(keycloakComponent), "get_pending_request_endpoint", influxMetrics, managementLogger, tracer, rateLimitMgmt),
+			ApprovePendingRequest: prepareEndpoint(management.MakeApprovePendingRequestEndpoint(keycloakComponent), "approve_pending_request_endpoint", influxMetrics, managementLogger, tracer, rateLimitMgmt),
+			RejectPendingRequest:  prepareEndpoint(management.MakeRejectPendingRequestEndpoint(keycloakComponent), "reject_pending_request_endpoint", influxMetrics, managementLogger, tracer, rateLimitMgmt),
+
+			GetClientRoles:           prepareEndpoint(management.MakeGetClientRolesEndpoint(keycloakComponent), "get_client_roles_endpoint", influxMetrics, managementLogger, tracer, rateLimitMgmt),
+			CreateClientRole:         prepareEndpoint(management.MakeCreateClientRoleEndpoint(keycloakComponent, managementLogger), "create_client_role_endpoint", influxMetrics, managementLogger, tracer, rateLimitMgmt),
+			DeleteClientRole:         prepareEndpoint(management.MakeDeleteClientRoleEndpoint(keycloakComponent), "delete_client_role_endpoint", influxMetrics, managementLogger, tracer, rateLimitMgmt),
+			GetClientRoleForUser:     prepareEndpoint(management.MakeGetClientRolesForUserEndpoint(keycloakComponent), "get_client_roles_for_user_endpoint", influxMetrics, managementLogger, tracer, rateLimitMgmt),
+			AddClientRoleToUser:      prepareEndpoint(management.MakeAddClientRolesToUserEndpoint(keycloakComponent), "get_client_roles_for_user_endpoint", influxMetrics, managementLogger, tracer, rateLimitMgmt),
+			DeleteClientRoleFromUser: prepareEndpoint(management.MakeDeleteClientRoleFromUserEndpoint(keycloakComponent), "delete_client_role_from_user_endpoint", influxMetrics, managementLogger, tracer, rateLimitMgmt),
 
 			ResetPassword:                  prepareEndpointWithoutLogging(management.MakeResetPasswordEndpoint(keycloakComponent), "reset_password_endpoint", influxMetrics, tracer, rateLimitMgmt),
 			ExecuteActionsEmail:            prepareEndpoint(management.MakeExecuteActionsEmailEndpoint(keycloakComponent), "execute_actions_email_endpoint", influxMetrics, managementLogger, tracer, rateLimitMgmt),
@@ -720,17 +881,78 @@ func main() {
 			ResetCredentialFailuresForUser: prepareEndpoint(management.MakeResetCredentialFailuresForUserEndpoint(keycloakComponent), "reset_credential_failures_for_user_endpoint", influxMetrics, managementLogger, tracer, rateLimitMgmt),
 			ClearUserLoginFailures:         prepareEndpoint(management.MakeClearUserLoginFailures(keycloakComponent), "clear_user_login_failures_endpoint", influxMetrics, managementLogger, tracer, rateLimitMgmt),
 			GetAttackDetectionStatus:       prepareEndpoint(management.MakeGetAttackDetectionStatus(keycloakComponent), "get_attack_detection_status_endpoint", influxMetrics, managementLogger, tracer, rateLimitMgmt),
+			GetSessionsOfUser:              prepareEndpoint(management.MakeGetSessionsOfUserEndpoint(keycloakComponent), "get_sessions_of_user_endpoint", influxMetrics, managementLogger, tracer, rateLimitMgmt),
+			LogoutUser:                     prepareEndpoint(management.MakeLogoutUserEndpoint(keycloakComponent), "logout_user_endpoint", influxMetrics, managementLogger, tracer, rateLimitMgmt),
+			DeleteSessionOfUser:            prepareEndpoint(management.MakeDeleteSessionOfUserEndpoint(keycloakComponent), "delete_session_of_user_endpoint", influxMetrics, managementLogger, tracer, rateLimitMgmt),
+			GetOfflineSessionsOfUser:       prepareEndpoint(management.MakeGetOfflineSessionsOfUserEndpoint(keycloakComponent), "get_offline_sessions_of_user_endpoint", influxMetrics, managementLogger, tracer, rateLimitMgmt),
+			GetConsentsOfUser:              prepareEndpoint(management.MakeGetConsentsOfUserEndpoint(keycloakComponent), "get_consents_of_user_endpoint", influxMetrics, managementLogger, tracer, rateLimitMgmt),
+			RevokeConsentOfUser:            prepareEndpoint(management.MakeRevokeConsentOfUserEndpoint(keycloakComponent), "revoke_consent_of_user_endpoint", influxMetrics, managementLogger, tracer, rateLimitMgmt),
 
 			GetRealmCustomConfiguration:    prepareEndpoint(management.MakeGetRealmCustomConfigurationEndpoint(keycloakComponent), "get_realm_custom_config_endpoint", influxMetrics, managementLogger, tracer, rateLimitMgmt),
 			UpdateRealmCustomConfiguration: prepareEndpoint(management.MakeUpdateRealmCustomConfigurationEndpoint(keycloakComponent), "update_realm_custom_config_endpoint", influxMetrics, managementLogger, tracer, rateLimitMgmt),
 			GetRealmAdminConfiguration:     prepareEndpoint(management.MakeGetRealmAdminConfigurationEndpoint(keycloakComponent), "get_realm_admin_config_endpoint", influxMetrics, managementLogger, tracer, rateLimitMgmt),
 			UpdateRealmAdminConfiguration:  prepareEndpoint(management.MakeUpdateRealmAdminConfigurationEndpoint(keycloakComponent), "update_realm_admin_config_endpoint", influxMetrics, managementLogger, tracer, rateLimitMgmt),
 
+			GetRealmCustomConfigurationVersions:     prepareEndpoint(management.MakeGetRealmCustomConfigurationVersionsEndpoint(keycloakComponent), "get_realm_custom_config_versions_endpoint", influxMetrics, managementLogger, tracer, rateLimitMgmt),
+			GetRealmCustomConfigurationVersionsDiff: prepareEndpoint(management.MakeGetRealmCustomConfigurationVersionsDiffEndpoint(keycloakComponent), "get_realm_custom_config_versions_diff_endpoint", influxMetrics, managementLogger, tracer, rateLimitMgmt),
+			RollbackRealmCustomConfiguration:        prepareEndpoint(management.MakeRollbackRealmCustomConfigurationEndpoint(keycloakComponent), "rollback_realm_custom_config_endpoint", influxMetrics, managementLogger, tracer, rateLimitMgmt),
+			GetRealmAdminConfigurationVersions:      prepareEndpoint(management.MakeGetRealmAdminConfigurationVersionsEndpoint(keycloakComponent), "get_realm_admin_config_versions_endpoint", influxMetrics, managementLogger, tracer, rateLimitMgmt),
+			GetRealmAdminConfigurationVersionsDiff:  prepareEndpoint(management.MakeGetRealmAdminConfigurationVersionsDiffEndpoint(keycloakComponent), "get_realm_admin_config_versions_diff_endpoint", influxMetrics, managementLogger, tracer, rateLimitMgmt),
+			RollbackRealmAdminConfiguration:         prepareEndpoint(management.MakeRollbackRealmAdminConfigurationEndpoint(keycloakComponent), "rollback_realm_admin_config_endpoint", influxMetrics, managementLogger, tracer, rateLimitMgmt),
+
 			GetRealmBackOfficeConfiguration:     prepareEndpoint(management.MakeGetRealmBackOfficeConfigurationEndpoint(keycloakComponent), "get_realm_back_office_config_endpoint", influxMetrics, managementLogger, tracer, rateLimitMgmt),
 			UpdateRealmBackOfficeConfiguration:  prepareEndpoint(management.MakeUpdateRealmBackOfficeConfigurationEndpoint(keycloakComponent), "update_realm_back_office_config_endpoint", influxMetrics, managementLogger, tracer, rateLimitMgmt),
 			GetUserRealmBackOfficeConfiguration: prepareEndpoint(management.MakeGetUserRealmBackOfficeConfigurationEndpoint(keycloakComponent), "get_user_realm_back_office_config_endpoint", influxMetrics, managementLogger, tracer, rateLimitMgmt),
 
-			LinkShadowUser: prepareEndpoint(management.MakeLinkShadowUserEndpoint(keycloakComponent), "link_shadow_user_endpoint", influxMetrics, managementLogger, tracer, rateLimitMgmt),
+			LinkShadowUser:         prepareEndpoint(management.MakeLinkShadowUserEndpoint(keycloakComponent), "link_shadow_user_endpoint", influxMetrics, managementLogger, tracer, rateLimitMgmt),
+			GetFederatedIdentities: prepareEndpoint(management.MakeGetFederatedIdentitiesEndpoint(keycloakComponent), "get_federated_identities_endpoint", influxMetrics, managementLogger, tracer, rateLimitMgmt),
+			UnlinkShadowUser:       prepareEndpoint(management.MakeUnlinkShadowUserEndpoint(keycloakComponent), "unlink_shadow_user_endpoint", influxMetrics, managementLogger, tracer, rateLimitMgmt),
+		}
+	}
+
+	// Statistics service.
+	var statisticsEndpoints statistics.Endpoints
+	{
+		var statisticsLogger = log.With(logger, "svc", "statistics")
+
+		var registerRealms []string
+		if registerEnabled {
+			registerRealms = append(registerRealms, registerRealm)
+		}
+		for _, corpRegisterConf := range corpRegisters {
+			registerRealms = append(registerRealms, corpRegisterConf.Realm)
+		}
+
+		var statisticsCounter keycloakb.EventsCounter = eventsRODBModule
+		if statisticsRollupsEnabled {
+			statisticsCounter = keycloakb.NewRollupsDBModule(eventsRODBConn)
+		}
+
+		statisticsComponent := statistics.NewComponent(eventsRODBModule, statisticsCounter, keycloakClient, technicalTokenProvider, technicalRealm, managementComponent, authorizationManager, registerRealms, statisticsLogger)
+		statisticsComponent = statistics.MakeAuthorizationManagementComponentMW(log.With(statisticsLogger, "mw", "endpoint"), authorizationManager)(statisticsComponent)
+
+		var rateLimitStatistics = rateLimit[RateKeyStatistics]
+		statisticsEndpoints = statistics.Endpoints{
+			GetActions:                         prepareEndpoint(statistics.MakeGetActionsEndpoint(statisticsComponent), "get_actions", influxMetrics, statisticsLogger, tracer, rateLimitStatistics),
+			GetStatistics:                      prepareEndpoint(statistics.MakeGetStatisticsEndpoint(statisticsComponent), "get_statistics", influxMetrics, statisticsLogger, tracer, rateLimitStatistics),
+			GetStatisticsUsers:                 prepareEndpoint(statistics.MakeGetStatisticsUsersEndpoint(statisticsComponent), "get_statistics_users", influxMetrics, statisticsLogger, tracer, rateLimitStatistics),
+			GetStatisticsAuthentications:       prepareEndpoint(statistics.MakeGetStatisticsAuthenticationsEndpoint(statisticsComponent), "get_statistics_authentications", influxMetrics, statisticsLogger, tracer, rateLimitStatistics),
+			GetStatisticsAuthenticationsLog:    prepareEndpoint(statistics.MakeGetStatisticsAuthenticationsLogEndpoint(statisticsComponent), "get_statistics_authentications_log", influxMetrics, statisticsLogger, tracer, rateLimitStatistics),
+			GetStatisticsAuthenticators:        prepareEndpoint(statistics.MakeGetStatisticsAuthenticatorsEndpoint(statisticsComponent), "get_statistics_authenticators", influxMetrics, statisticsLogger, tracer, rateLimitStatistics),
+			GetMigrationReport:                 prepareEndpoint(statistics.MakeGetMigrationReportEndpoint(statisticsComponent), "get_migration_report", influxMetrics, statisticsLogger, tracer, rateLimitStatistics),
+			GetStatisticsFailures:              prepareEndpoint(statistics.MakeGetStatisticsFailuresEndpoint(statisticsComponent), "get_statistics_failures", influxMetrics, statisticsLogger, tracer, rateLimitStatistics),
+			GetStatisticsFailedAuthentications: prepareEndpoint(statistics.MakeGetStatisticsFailedAuthenticationsEndpoint(statisticsComponent), "get_statistics_failed_authentications", influxMetrics, statisticsLogger, tracer, rateLimitStatistics),
+			GetStatisticsLockouts:              prepareEndpoint(statistics.MakeGetStatisticsLockoutsEndpoint(statisticsComponent), "get_statistics_lockouts", influxMetrics, statisticsLogger, tracer, rateLimitStatistics),
+			GetStatisticsFailuresBreakdown:     prepareEndpoint(statistics.MakeGetStatisticsFailuresBreakdownEndpoint(statisticsComponent), "get_statistics_failures_breakdown", influxMetrics, statisticsLogger, tracer, rateLimitStatistics),
+			GetStatisticsAuthenticationsRatio:  prepareEndpoint(statistics.MakeGetStatisticsAuthenticationsRatioEndpoint(statisticsComponent), "get_statistics_authentications_ratio", influxMetrics, statisticsLogger, tracer, rateLimitStatistics),
+			GetStatisticsRegistrations:         prepareEndpoint(statistics.MakeGetStatisticsRegistrationsEndpoint(statisticsComponent), "get_statistics_registrations", influxMetrics, statisticsLogger, tracer, rateLimitStatistics),
+			GetMigrationReportSummary:          prepareEndpoint(statistics.MakeGetMigrationReportSummaryEndpoint(statisticsComponent), "get_migration_report_summary", influxMetrics, statisticsLogger, tracer, rateLimitStatistics),
+			GetMigrationReportUsers:            prepareEndpoint(statistics.MakeGetMigrationReportUsersEndpoint(statisticsComponent), "get_migration_report_users", influxMetrics, statisticsLogger, tracer, rateLimitStatistics),
+			GetInactiveUsers:                   prepareEndpoint(statistics.MakeGetInactiveUsersEndpoint(statisticsComponent), "get_inactive_users", influxMetrics, statisticsLogger, tracer, rateLimitStatistics),
+			LockInactiveUsers:                  prepareEndpoint(statistics.MakeLockInactiveUsersEndpoint(statisticsComponent), "lock_inactive_users", influxMetrics, statisticsLogger, tracer, rateLimitStatistics),
+			GetStatisticsAccreditations:        prepareEndpoint(statistics.MakeGetStatisticsAccreditationsEndpoint(statisticsComponent), "get_statistics_accreditations", influxMetrics, statisticsLogger, tracer, rateLimitStatistics),
+			GetExpiringAccreditations:          prepareEndpoint(statistics.MakeGetExpiringAccreditationsEndpoint(statisticsComponent), "get_expiring_accreditations", influxMetrics, statisticsLogger, tracer, rateLimitStatistics),
+			GetStatisticsOverview:              prepareEndpoint(statistics.MakeGetStatisticsOverviewEndpoint(statisticsComponent), "get_statistics_overview", influxMetrics, statisticsLogger, tracer, rateLimitStatistics),
 		}
 	}
 
@@ -750,7 +972,7 @@ func main() {
 		}
 
 		// module for storing and retrieving details of the self-registered users
-		var usersDBModule = keycloakb.NewUsersDetailsDBModule(usersRwDBConn, aesEncryption, accountLogger)
+		var usersDBModule = keycloakb.NewUsersDetailsDBModule(usersRwDBConn, aesEncryption, blindIndex, accountLogger)
 
 		// new module for account service
 		accountComponent := account.NewComponent(keycloakClient.AccountClient(), eventsDBModule, configDBModule, usersDBModule, accountLogger)
@@ -786,7 +1008,7 @@ func main() {
 		}
 
 		// module for storing and retrieving details of the self-registered users
-		var usersDBModule = keycloakb.NewUsersDetailsDBModule(usersRwDBConn, aesEncryption, mobileLogger)
+		var usersDBModule = keycloakb.NewUsersDetailsDBModule(usersRwDBConn, aesEncryption, blindIndex, mobileLogger)
 
 		// new module for mobile service
 		mobileComponent := mobile.NewComponent(keycloakClient, configDBModule, usersDBModule, technicalTokenProvider, mobileLogger)
@@ -811,10 +1033,10 @@ func main() {
 			var configDBModule = createConfigurationDBModule(configurationRwDBConn, influxMetrics, registerLogger)
 
 			// module for storing and retrieving details of the self-registered users
-			var usersDBModule = keycloakb.NewUsersDetailsDBModule(usersRwDBConn, aesEncryption, registerLogger)
+			var usersDBModule = keycloakb.NewUsersDetailsDBModule(usersRwDBConn, aesEncryption, blindIndex, registerLogger)
 
 			// new module for register service
-			registerComponentBuilder := register.NewComponentBuilder(keycloakPublicURL, keycloakClient, technicalTokenProvider, usersDBModule, configDBModule, eventsDBModule, registerLogger)
+			registerComponentBuilder := register.NewComponentBuilder(keycloakPublicURL, keycloakClient, technicalTokenProvider, usersDBModule, configDBModule, eventsDBModule, sagaModule, registerLogger)
 			if err := registerComponentBuilder.AddTargetRealm(socialRealmConfiguration); err != nil {
 				registerLogger.Error(ctx, "msg", "Can't initialize register component. Check the provided group names", "err", err.Error(), "realm", registerRealm)
 				return
@@ -851,7 +1073,7 @@ func main() {
 		eventsDBModule := configureEventsDbModule(baseEventsDBModule, influxMetrics, kycLogger, tracer)
 
 		// module for storing and retrieving details of the users
-		var usersDBModule = keycloakb.NewUsersDetailsDBModule(usersRwDBConn, aesEncryption, kycLogger)
+		var usersDBModule = keycloakb.NewUsersDetailsDBModule(usersRwDBConn, aesEncryption, blindIndex, kycLogger)
 
 		// accreditations module
 		var accredsModule keycloakb.AccreditationsModule
@@ -861,7 +1083,7 @@ func main() {
 		}
 
 		// new module for KYC service
-		kycComponent := kyc.NewComponent(technicalTokenProvider, registerRealm, keycloakClient, usersDBModule, eventsDBModule, accredsModule, kycLogger)
+		kycComponent := kyc.NewComponent(technicalTokenProvider, registerRealm, keycloakClient, usersDBModule, eventsDBModule, accredsModule, sagaModule, kycLogger)
 		kycComponent = kyc.MakeAuthorizationRegisterComponentMW(registerRealm, authorizationManager, endpointPhysicalCheckAvailabilityChecker, log.With(kycLogger, "mw", "endpoint"))(kycComponent)
 
 		var rateLimitKyc = rateLimit[RateKeyKYC]
@@ -963,14 +1185,40 @@ func main() {
 		var getStatisticsAuthenticationsHandler = configureStatisiticsHandler(keycloakb.ComponentName, ComponentID, idGenerator, keycloakClient, audienceRequired, tracer, logger)(statisticsEndpoints.GetStatisticsAuthentications)
 		var getStatisticsAuthenticationsLogHandler = configureStatisiticsHandler(keycloakb.ComponentName, ComponentID, idGenerator, keycloakClient, audienceRequired, tracer, logger)(statisticsEndpoints.GetStatisticsAuthenticationsLog)
 		var getMigrationReportHandler = configureStatisiticsHandler(keycloakb.ComponentName, ComponentID, idGenerator, keycloakClient, audienceRequired, tracer, logger)(statisticsEndpoints.GetMigrationReport)
+		var getStatisticsFailuresHandler = configureStatisiticsHandler(keycloakb.ComponentName, ComponentID, idGenerator, keycloakClient, audienceRequired, tracer, logger)(statisticsEndpoints.GetStatisticsFailures)
+		var getStatisticsFailedAuthenticationsHandler = configureStatisiticsHandler(keycloakb.ComponentName, ComponentID, idGenerator, keycloakClient, audienceRequired, tracer, logger)(statisticsEndpoints.GetStatisticsFailedAuthentications)
+		var getStatisticsLockoutsHandler = configureStatisiticsHandler(keycloakb.ComponentName, ComponentID, idGenerator, keycloakClient, audienceRequired, tracer, logger)(statisticsEndpoints.GetStatisticsLockouts)
+		var getStatisticsFailuresBreakdownHandler = configureStatisiticsHandler(keycloakb.ComponentName, ComponentID, idGenerator, keycloakClient, audienceRequired, tracer, logger)(statisticsEndpoints.GetStatisticsFailuresBreakdown)
+		var getStatisticsAuthenticationsRatioHandler = configureStatisiticsHandler(keycloakb.ComponentName, ComponentID, idGenerator, keycloakClient, audienceRequired, tracer, logger)(statisticsEndpoints.GetStatisticsAuthenticationsRatio)
+		var getStatisticsRegistrationsHandler = configureStatisiticsHandler(keycloakb.ComponentName, ComponentID, idGenerator, keycloakClient, audienceRequired, tracer, logger)(statisticsEndpoints.GetStatisticsRegistrations)
+		var getMigrationReportSummaryHandler = configureStatisiticsHandler(keycloakb.ComponentName, ComponentID, idGenerator, keycloakClient, audienceRequired, tracer, logger)(statisticsEndpoints.GetMigrationReportSummary)
+		var getMigrationReportUsersHandler = configureStatisiticsHandler(keycloakb.ComponentName, ComponentID, idGenerator, keycloakClient, audienceRequired, tracer, logger)(statisticsEndpoints.GetMigrationReportUsers)
+		var getInactiveUsersHandler = configureStatisiticsHandler(keycloakb.ComponentName, ComponentID, idGenerator, keycloakClient, audienceRequired, tracer, logger)(statisticsEndpoints.GetInactiveUsers)
+		var lockInactiveUsersHandler = configureStatisiticsHandler(keycloakb.ComponentName, ComponentID, idGenerator, keycloakClient, audienceRequired, tracer, logger)(statisticsEndpoints.LockInactiveUsers)
+		var getStatisticsAccreditationsHandler = configureStatisiticsHandler(keycloakb.ComponentName, ComponentID, idGenerator, keycloakClient, audienceRequired, tracer, logger)(statisticsEndpoints.GetStatisticsAccreditations)
+		var getExpiringAccreditationsHandler = configureStatisiticsHandler(keycloakb.ComponentName, ComponentID, idGenerator, keycloakClient, audienceRequired, tracer, logger)(statisticsEndpoints.GetExpiringAccreditations)
+		var getStatisticsOverviewHandler = configureStatisiticsHandler(keycloakb.ComponentName, ComponentID, idGenerator, keycloakClient, audienceRequired, tracer, logger)(statisticsEndpoints.GetStatisticsOverview)
 
 		route.Path("/statistics/actions").Methods("GET").Handler(getStatisticsActionsHandler)
+		route.Path("/statistics/overview").Methods("GET").Handler(getStatisticsOverviewHandler)
 		route.Path("/statistics/realms/{realm}").Methods("GET").Handler(getStatisticsHandler)
 		route.Path("/statistics/realms/{realm}/users").Methods("GET").Handler(getStatisticsUsersHandler)
 		route.Path("/statistics/realms/{realm}/authenticators").Methods("GET").Handler(getStatisticsAuthenticatorsHandler)
 		route.Path("/statistics/realms/{realm}/authentications-graph").Methods("GET").Handler(getStatisticsAuthenticationsHandler)
 		route.Path("/statistics/realms/{realm}/authentications-log").Methods("GET").Handler(getStatisticsAuthenticationsLogHandler)
 		route.Path("/statistics/realms/{realm}/migration").Methods("GET").Handler(getMigrationReportHandler)
+		route.Path("/statistics/realms/{realm}/migration/summary").Methods("GET").Handler(getMigrationReportSummaryHandler)
+		route.Path("/statistics/realms/{realm}/migration/users").Methods("GET").Handler(getMigrationReportUsersHandler)
+		route.Path("/statistics/realms/{realm}/failures").Methods("GET").Handler(getStatisticsFailuresHandler)
+		route.Path("/statistics/realms/{realm}/failures-breakdown").Methods("GET").Handler(getStatisticsFailuresBreakdownHandler)
+		route.Path("/statistics/realms/{realm}/failed-authentications-graph").Methods("GET").Handler(getStatisticsFailedAuthenticationsHandler)
+		route.Path("/statistics/realms/{realm}/lockouts-graph").Methods("GET").Handler(getStatisticsLockoutsHandler)
+		route.Path("/statistics/realms/{realm}/authentications-ratio-graph").Methods("GET").Handler(getStatisticsAuthenticationsRatioHandler)
+		route.Path("/statistics/realms/{realm}/registrations").Methods("GET").Handler(getStatisticsRegistrationsHandler)
+		route.Path("/statistics/realms/{realm}/inactive-users").Methods("GET").Handler(getInactiveUsersHandler)
+		route.Path("/statistics/realms/{realm}/inactive-users/lock").Methods("POST").Handler(lockInactiveUsersHandler)
+		route.Path("/statistics/realms/{realm}/accreditations").Methods("GET").Handler(getStatisticsAccreditationsHandler)
+		route.Path("/statistics/realms/{realm}/accreditations/expiring").Methods("GET").Handler(getExpiringAccreditationsHandler)
 
 		// Events
 		var getEventsActionsHandler = configureEventsHandler(keycloakb.ComponentName, ComponentID, idGenerator, keycloakClient, audienceRequired, tracer, logger)(eventsEndpoints.GetActions)
@@ -995,12 +1243,21 @@ func main() {
 		var getRequiredActionsHandler = configureManagementHandler(keycloakb.ComponentName, ComponentID, idGenerator, keycloakClient, audienceRequired, tracer, logger)(managementEndpoints.GetRequiredActions)
 
 		var createUserHandler = configureManagementHandler(keycloakb.ComponentName, ComponentID, idGenerator, keycloakClient, audienceRequired, tracer, logger)(managementEndpoints.CreateUser)
+		var importUsersHandler = configureManagementHandler(keycloakb.ComponentName, ComponentID, idGenerator, keycloakClient, audienceRequired, tracer, logger)(managementEndpoints.ImportUsers)
+		var getUsersImportJobHandler = configureManagementHandler(keycloakb.ComponentName, ComponentID, idGenerator, keycloakClient, audienceRequired, tracer, logger)(managementEndpoints.GetUsersImportJob)
+		var getUsersImportResultsHandler = configureManagementHandler(keycloakb.ComponentName, ComponentID, idGenerator, keycloakClient, audienceRequired, tracer, logger)(managementEndpoints.GetUsersImportResults)
+		var startBulkOperationHandler = configureManagementHandler(keycloakb.ComponentName, ComponentID, idGenerator, keycloakClient, audienceRequired, tracer, logger)(managementEndpoints.StartBulkOperation)
+		var getBulkOperationHandler = configureManagementHandler(keycloakb.ComponentName, ComponentID, idGenerator, keycloakClient, audienceRequired, tracer, logger)(managementEndpoints.GetBulkOperation)
 		var getUserHandler = configureManagementHandler(keycloakb.ComponentName, ComponentID, idGenerator, keycloakClient, audienceRequired, tracer, logger)(managementEndpoints.GetUser)
 		var updateUserHandler = configureManagementHandler(keycloakb.ComponentName, ComponentID, idGenerator, keycloakClient, audienceRequired, tracer, logger)(managementEndpoints.UpdateUser)
+		var getUserHistoryHandler = configureManagementHandler(keycloakb.ComponentName, ComponentID, idGenerator, keycloakClient, audienceRequired, tracer, logger)(managementEndpoints.GetUserHistory)
 		var lockUserHandler = configureManagementHandler(keycloakb.ComponentName, ComponentID, idGenerator, keycloakClient, audienceRequired, tracer, logger)(managementEndpoints.LockUser)
 		var unlockUserHandler = configureManagementHandler(keycloakb.ComponentName, ComponentID, idGenerator, keycloakClient, audienceRequired, tracer, logger)(managementEndpoints.UnlockUser)
 		var deleteUserHandler = configureManagementHandler(keycloakb.ComponentName, ComponentID, idGenerator, keycloakClient, audienceRequired, tracer, logger)(managementEndpoints.DeleteUser)
 		var getUsersHandler = configureManagementHandler(keycloakb.ComponentName, ComponentID, idGenerator, keycloakClient, audienceRequired, tracer, logger)(managementEndpoints.GetUsers)
+		var searchUsersHandler = configureManagementHandler(keycloakb.ComponentName, ComponentID, idGenerator, keycloakClient, audienceRequired, tracer, logger)(managementEndpoints.SearchUsers)
+		var getDuplicateUsersHandler = configureManagementHandler(keycloakb.ComponentName, ComponentID, idGenerator, keycloakClient, audienceRequired, tracer, logger)(managementEndpoints.GetDuplicateUsers)
+		var mergeUsersHandler = configureManagementHandler(keycloakb.ComponentName, ComponentID, idGenerator, keycloakClient, audienceRequired, tracer, logger)(managementEndpoints.MergeUsers)
 		var getRolesForUserHandler = configureManagementHandler(keycloakb.ComponentName, ComponentID, idGenerator, keycloakClient, audienceRequired, tracer, logger)(managementEndpoints.GetRolesOfUser)
 		var getGroupsForUserHandler = configureManagementHandler(keycloakb.ComponentName, ComponentID, idGenerator, keycloakClient, audienceRequired, tracer, logger)(managementEndpoints.GetGroupsOfUser)
 		var addGroupToUserHandler = configureManagementHandler(keycloakb.ComponentName, ComponentID, idGenerator, keycloakClient, audienceRequired, tracer, logger)(managementEndpoints.AddGroupToUser)
@@ -1012,17 +1269,45 @@ func main() {
 
 		var getClientRoleForUserHandler = configureManagementHandler(keycloakb.ComponentName, ComponentID, idGenerator, keycloakClient, audienceRequired, tracer, logger)(managementEndpoints.GetClientRoleForUser)
 		var addClientRoleToUserHandler = configureManagementHandler(keycloakb.ComponentName, ComponentID, idGenerator, keycloakClient, audienceRequired, tracer, logger)(managementEndpoints.AddClientRoleToUser)
+		var deleteClientRoleFromUserHandler = configureManagementHandler(keycloakb.ComponentName, ComponentID, idGenerator, keycloakClient, audienceRequired, tracer, logger)(managementEndpoints.DeleteClientRoleFromUser)
 
 		var getRolesHandler = configureManagementHandler(keycloakb.ComponentName, ComponentID, idGenerator, keycloakClient, audienceRequired, tracer, logger)(managementEndpoints.GetRoles)
 		var getRoleHandler = configureManagementHandler(keycloakb.ComponentName, ComponentID, idGenerator, keycloakClient, audienceRequired, tracer, logger)(managementEndpoints.GetRole)
 		var getClientRolesHandler = configureManagementHandler(keycloakb.ComponentName, ComponentID, idGenerator, keycloakClient, audienceRequired, tracer, logger)(managementEndpoints.GetClientRoles)
 		var createClientRolesHandler = configureManagementHandler(keycloakb.ComponentName, ComponentID, idGenerator, keycloakClient, audienceRequired, tracer, logger)(managementEndpoints.CreateClientRole)
+		var deleteClientRoleHandler = configureManagementHandler(keycloakb.ComponentName, ComponentID, idGenerator, keycloakClient, audienceRequired, tracer, logger)(managementEndpoints.DeleteClientRole)
+		var createRoleHandler = configureManagementHandler(keycloakb.ComponentName, ComponentID, idGenerator, keycloakClient, audienceRequired, tracer, logger)(managementEndpoints.CreateRole)
+		var updateRoleHandler = configureManagementHandler(keycloakb.ComponentName, ComponentID, idGenerator, keycloakClient, audienceRequired, tracer, logger)(managementEndpoints.UpdateRole)
+		var deleteRoleHandler = configureManagementHandler(keycloakb.ComponentName, ComponentID, idGenerator, keycloakClient, audienceRequired, tracer, logger)(managementEndpoints.DeleteRole)
+		var getRoleCompositesHandler = configureManagementHandler(keycloakb.ComponentName, ComponentID, idGenerator, keycloakClient, audienceRequired, tracer, logger)(managementEndpoints.GetRoleComposites)
+		var addRoleCompositesHandler = configureManagementHandler(keycloakb.ComponentName, ComponentID, idGenerator, keycloakClient, audienceRequired, tracer, logger)(managementEndpoints.AddRoleComposites)
+		var deleteRoleCompositesHandler = configureManagementHandler(keycloakb.ComponentName, ComponentID, idGenerator, keycloakClient, audienceRequired, tracer, logger)(managementEndpoints.DeleteRoleComposites)
+		var getUsersWithRoleHandler = configureManagementHandler(keycloakb.ComponentName, ComponentID, idGenerator, keycloakClient, audienceRequired, tracer, logger)(managementEndpoints.GetUsersWithRole)
 
 		var getGroupsHandler = configureManagementHandler(keycloakb.ComponentName, ComponentID, idGenerator, keycloakClient, audienceRequired, tracer, logger)(managementEndpoints.GetGroups)
 		var createGroupHandler = configureManagementHandler(keycloakb.ComponentName, ComponentID, idGenerator, keycloakClient, audienceRequired, tracer, logger)(managementEndpoints.CreateGroup)
 		var deleteGroupHandler = configureManagementHandler(keycloakb.ComponentName, ComponentID, idGenerator, keycloakClient, audienceRequired, tracer, logger)(managementEndpoints.DeleteGroup)
+		var getGroupHandler = configureManagementHandler(keycloakb.ComponentName, ComponentID, idGenerator, keycloakClient, audienceRequired, tracer, logger)(managementEndpoints.GetGroup)
+		var updateGroupHandler = configureManagementHandler(keycloakb.ComponentName, ComponentID, idGenerator, keycloakClient, audienceRequired, tracer, logger)(managementEndpoints.UpdateGroup)
+		var createGroupChildHandler = configureManagementHandler(keycloakb.ComponentName, ComponentID, idGenerator, keycloakClient, audienceRequired, tracer, logger)(managementEndpoints.CreateGroupChild)
+		var moveGroupHandler = configureManagementHandler(keycloakb.ComponentName, ComponentID, idGenerator, keycloakClient, audienceRequired, tracer, logger)(managementEndpoints.MoveGroup)
+		var getGroupMembersHandler = configureManagementHandler(keycloakb.ComponentName, ComponentID, idGenerator, keycloakClient, audienceRequired, tracer, logger)(managementEndpoints.GetGroupMembers)
 		var getAuthorizationsHandler = configureManagementHandler(keycloakb.ComponentName, ComponentID, idGenerator, keycloakClient, audienceRequired, tracer, logger)(managementEndpoints.GetAuthorizations)
 		var updateAuthorizationsHandler = configureManagementHandler(keycloakb.ComponentName, ComponentID, idGenerator, keycloakClient, audienceRequired, tracer, logger)(managementEndpoints.UpdateAuthorizations)
+		var checkAuthorizationHandler = configureManagementHandler(keycloakb.ComponentName, ComponentID, idGenerator, keycloakClient, audienceRequired, tracer, logger)(managementEndpoints.CheckAuthorization)
+		var getAuthorizationTemplatesHandler = configureManagementHandler(keycloakb.ComponentName, ComponentID, idGenerator, keycloakClient, audienceRequired, tracer, logger)(managementEndpoints.GetAuthorizationTemplates)
+		var getAuthorizationTemplateHandler = configureManagementHandler(keycloakb.ComponentName, ComponentID, idGenerator, keycloakClient, audienceRequired, tracer, logger)(managementEndpoints.GetAuthorizationTemplate)
+		var updateAuthorizationTemplateHandler = configureManagementHandler(keycloakb.ComponentName, ComponentID, idGenerator, keycloakClient, audienceRequired, tracer, logger)(managementEndpoints.UpdateAuthorizationTemplate)
+		var deleteAuthorizationTemplateHandler = configureManagementHandler(keycloakb.ComponentName, ComponentID, idGenerator, keycloakClient, audienceRequired, tracer, logger)(managementEndpoints.DeleteAuthorizationTemplate)
+		var applyAuthorizationTemplateHandler = configureManagementHandler(keycloakb.ComponentName, ComponentID, idGenerator, keycloakClient, audienceRequired, tracer, logger)(managementEndpoints.ApplyAuthorizationTemplate)
+		var copyAuthorizationsHandler = configureManagementHandler(keycloakb.ComponentName, ComponentID, idGenerator, keycloakClient, audienceRequired, tracer, logger)(managementEndpoints.CopyAuthorizations)
+		var getAuthorizationsVersionsHandler = configureManagementHandler(keycloakb.ComponentName, ComponentID, idGenerator, keycloakClient, audienceRequired, tracer, logger)(managementEndpoints.GetAuthorizationsVersions)
+		var getAuthorizationsVersionsDiffHandler = configureManagementHandler(keycloakb.ComponentName, ComponentID, idGenerator, keycloakClient, audienceRequired, tracer, logger)(managementEndpoints.GetAuthorizationsVersionsDiff)
+		var rollbackAuthorizationsHandler = configureManagementHandler(keycloakb.ComponentName, ComponentID, idGenerator, keycloakClient, audienceRequired, tracer, logger)(managementEndpoints.RollbackAuthorizations)
+		var getPendingRequestsHandler = configureManagementHandler(keycloakb.ComponentName, ComponentID, idGenerator, keycloakClient, audienceRequired, tracer, logger)(managementEndpoints.GetPendingRequests)
+		var getPendingRequestHandler = configureManagementHandler(keycloakb.ComponentName, ComponentID, idGenerator, keycloakClient, audienceRequired, tracer, logger)(managementEndpoints.GetPendingRequest)
+		var approvePendingRequestHandler = configureManagementHandler(keycloakb.ComponentName, ComponentID, idGenerator, keycloakClient, audienceRequired, tracer, logger)(managementEndpoints.ApprovePendingRequest)
+		var rejectPendingRequestHandler = configureManagementHandler(keycloakb.ComponentName, ComponentID, idGenerator, keycloakClient, audienceRequired, tracer, logger)(managementEndpoints.RejectPendingRequest)
 		var getManagementActionsHandler = configureManagementHandler(keycloakb.ComponentName, ComponentID, idGenerator, keycloakClient, audienceRequired, tracer, logger)(managementEndpoints.GetActions)
 
 		var resetPasswordHandler = configureManagementHandler(keycloakb.ComponentName, ComponentID, idGenerator, keycloakClient, audienceRequired, tracer, logger)(managementEndpoints.ResetPassword)
@@ -1037,21 +1322,41 @@ func main() {
 		var resetCredentialFailuresForUserHandler = configureManagementHandler(keycloakb.ComponentName, ComponentID, idGenerator, keycloakClient, audienceRequired, tracer, logger)(managementEndpoints.ResetCredentialFailuresForUser)
 		var clearUserLoginFailuresHandler = configureManagementHandler(keycloakb.ComponentName, ComponentID, idGenerator, keycloakClient, audienceRequired, tracer, logger)(managementEndpoints.ClearUserLoginFailures)
 		var getAttackDetectionStatusHandler = configureManagementHandler(keycloakb.ComponentName, ComponentID, idGenerator, keycloakClient, audienceRequired, tracer, logger)(managementEndpoints.GetAttackDetectionStatus)
+		var getSessionsOfUserHandler = configureManagementHandler(keycloakb.ComponentName, ComponentID, idGenerator, keycloakClient, audienceRequired, tracer, logger)(managementEndpoints.GetSessionsOfUser)
+		var logoutUserHandler = configureManagementHandler(keycloakb.ComponentName, ComponentID, idGenerator, keycloakClient, audienceRequired, tracer, logger)(managementEndpoints.LogoutUser)
+		var deleteSessionOfUserHandler = configureManagementHandler(keycloakb.ComponentName, ComponentID, idGenerator, keycloakClient, audienceRequired, tracer, logger)(managementEndpoints.DeleteSessionOfUser)
+		var getOfflineSessionsOfUserHandler = configureManagementHandler(keycloakb.ComponentName, ComponentID, idGenerator, keycloakClient, audienceRequired, tracer, logger)(managementEndpoints.GetOfflineSessionsOfUser)
+		var getConsentsOfUserHandler = configureManagementHandler(keycloakb.ComponentName, ComponentID, idGenerator, keycloakClient, audienceRequired, tracer, logger)(managementEndpoints.GetConsentsOfUser)
+		var revokeConsentOfUserHandler = configureManagementHandler(keycloakb.ComponentName, ComponentID, idGenerator, keycloakClient, audienceRequired, tracer, logger)(managementEndpoints.RevokeConsentOfUser)
 
 		var getRealmCustomConfigurationHandler = configureManagementHandler(keycloakb.ComponentName, ComponentID, idGenerator, keycloakClient, audienceRequired, tracer, logger)(managementEndpoints.GetRealmCustomConfiguration)
 		var updateRealmCustomConfigurationHandler = configureManagementHandler(keycloakb.ComponentName, ComponentID, idGenerator, keycloakClient, audienceRequired, tracer, logger)(managementEndpoints.UpdateRealmCustomConfiguration)
 		var getRealmAdminConfigurationHandler = configureManagementHandler(keycloakb.ComponentName, ComponentID, idGenerator, keycloakClient, audienceRequired, tracer, logger)(managementEndpoints.GetRealmAdminConfiguration)
 		var updateRealmAdminConfigurationHandler = configureManagementHandler(keycloakb.ComponentName, ComponentID, idGenerator, keycloakClient, audienceRequired, tracer, logger)(managementEndpoints.UpdateRealmAdminConfiguration)
+		var getRealmCustomConfigurationVersionsHandler = configureManagementHandler(keycloakb.ComponentName, ComponentID, idGenerator, keycloakClient, audienceRequired, tracer, logger)(managementEndpoints.GetRealmCustomConfigurationVersions)
+		var getRealmCustomConfigurationVersionsDiffHandler = configureManagementHandler(keycloakb.ComponentName, ComponentID, idGenerator, keycloakClient, audienceRequired, tracer, logger)(managementEndpoints.GetRealmCustomConfigurationVersionsDiff)
+		var rollbackRealmCustomConfigurationHandler = configureManagementHandler(keycloakb.ComponentName, ComponentID, idGenerator, keycloakClient, audienceRequired, tracer, logger)(managementEndpoints.RollbackRealmCustomConfiguration)
+		var getRealmAdminConfigurationVersionsHandler = configureManagementHandler(keycloakb.ComponentName, ComponentID, idGenerator, keycloakClient, audienceRequired, tracer, logger)(managementEndpoints.GetRealmAdminConfigurationVersions)
+		var getRealmAdminConfigurationVersionsDiffHandler = configureManagementHandler(keycloakb.ComponentName, ComponentID, idGenerator, keycloakClient, audienceRequired, tracer, logger)(managementEndpoints.GetRealmAdminConfigurationVersionsDiff)
+		var rollbackRealmAdminConfigurationHandler = configureManagementHandler(keycloakb.ComponentName, ComponentID, idGenerator, keycloakClient, audienceRequired, tracer, logger)(managementEndpoints.RollbackRealmAdminConfiguration)
 
 		var getRealmBackOfficeConfigurationHandler = configureManagementHandler(keycloakb.ComponentName, ComponentID, idGenerator, keycloakClient, audienceRequired, tracer, logger)(managementEndpoints.GetRealmBackOfficeConfiguration)
 		var updateRealmBackOfficeConfigurationHandler = configureManagementHandler(keycloakb.ComponentName, ComponentID, idGenerator, keycloakClient, audienceRequired, tracer, logger)(managementEndpoints.UpdateRealmBackOfficeConfiguration)
 		var getUserRealmBackOfficeConfigurationHandler = configureManagementHandler(keycloakb.ComponentName, ComponentID, idGenerator, keycloakClient, audienceRequired, tracer, logger)(managementEndpoints.GetUserRealmBackOfficeConfiguration)
 
 		var linkShadowUserHandler = configureManagementHandler(keycloakb.ComponentName, ComponentID, idGenerator, keycloakClient, audienceRequired, tracer, logger)(managementEndpoints.LinkShadowUser)
+		var getFederatedIdentitiesHandler = configureManagementHandler(keycloakb.ComponentName, ComponentID, idGenerator, keycloakClient, audienceRequired, tracer, logger)(managementEndpoints.GetFederatedIdentities)
+		var unlinkShadowUserHandler = configureManagementHandler(keycloakb.ComponentName, ComponentID, idGenerator, keycloakClient, audienceRequired, tracer, logger)(managementEndpoints.UnlinkShadowUser)
 
 		// actions
 		managementSubroute.Path("/actions").Methods("GET").Handler(getManagementActionsHandler)
 
+		// authorization templates
+		managementSubroute.Path("/authorization-templates").Methods("GET").Handler(getAuthorizationTemplatesHandler)
+		managementSubroute.Path("/authorization-templates/{templateName}").Methods("GET").Handler(getAuthorizationTemplateHandler)
+		managementSubroute.Path("/authorization-templates/{templateName}").Methods("PUT").Handler(updateAuthorizationTemplateHandler)
+		managementSubroute.Path("/authorization-templates/{templateName}").Methods("DELETE").Handler(deleteAuthorizationTemplateHandler)
+
 		// realms
 		managementSubroute.Path("/realms").Methods("GET").Handler(getRealmsHandler)
 		managementSubroute.Path("/realms/{realm}").Methods("GET").Handler(getRealmHandler)
@@ -1069,11 +1374,20 @@ func main() {
 		// users
 		managementSubroute.Path("/realms/{realm}/users").Methods("GET").Handler(getUsersHandler)
 		managementSubroute.Path("/realms/{realm}/users").Methods("POST").Handler(createUserHandler)
+		managementSubroute.Path("/realms/{realm}/users/search").Methods("GET").Handler(searchUsersHandler)
+		managementSubroute.Path("/realms/{realm}/users/duplicates").Methods("GET").Handler(getDuplicateUsersHandler)
+		managementSubroute.Path("/realms/{realm}/users/import").Methods("POST").Handler(importUsersHandler)
+		managementSubroute.Path("/realms/{realm}/users/import/{jobID}").Methods("GET").Handler(getUsersImportJobHandler)
+		managementSubroute.Path("/realms/{realm}/users/import/{jobID}/results").Methods("GET").Handler(getUsersImportResultsHandler)
+		managementSubroute.Path("/realms/{realm}/users/bulk").Methods("POST").Handler(startBulkOperationHandler)
+		managementSubroute.Path("/realms/{realm}/users/bulk/{jobID}").Methods("GET").Handler(getBulkOperationHandler)
 		managementSubroute.Path("/realms/{realm}/users/{userID}").Methods("GET").Handler(getUserHandler)
 		managementSubroute.Path("/realms/{realm}/users/{userID}").Methods("PUT").Handler(updateUserHandler)
 		managementSubroute.Path("/realms/{realm}/users/{userID}").Methods("DELETE").Handler(deleteUserHandler)
+		managementSubroute.Path("/realms/{realm}/users/{userID}/history").Methods("GET").Handler(getUserHistoryHandler)
 		managementSubroute.Path("/realms/{realm}/users/{userID}/lock").Methods("PUT").Handler(lockUserHandler)
 		managementSubroute.Path("/realms/{realm}/users/{userID}/unlock").Methods("PUT").Handler(unlockUserHandler)
+		managementSubroute.Path("/realms/{realm}/users/{userID}/merge").Methods("POST").Handler(mergeUsersHandler)
 		managementSubroute.Path("/realms/{realm}/users/{userID}/groups").Methods("GET").Handler(getGroupsForUserHandler)
 		managementSubroute.Path("/realms/{realm}/users/{userID}/groups/{groupID}").Methods("POST").Handler(addGroupToUserHandler)
 		managementSubroute.Path("/realms/{realm}/users/{userID}/groups/{groupID}").Methods("DELETE").Handler(deleteGroupForUserHandler)
@@ -1085,6 +1399,7 @@ func main() {
 		// role mappings
 		managementSubroute.Path("/realms/{realm}/users/{userID}/role-mappings/clients/{clientID}").Methods("GET").Handler(getClientRoleForUserHandler)
 		managementSubroute.Path("/realms/{realm}/users/{userID}/role-mappings/clients/{clientID}").Methods("POST").Handler(addClientRoleToUserHandler)
+		managementSubroute.Path("/realms/{realm}/users/{userID}/role-mappings/clients/{clientID}/{roleID}").Methods("DELETE").Handler(deleteClientRoleFromUserHandler)
 
 		managementSubroute.Path("/realms/{realm}/users/{userID}/reset-password").Methods("PUT").Handler(resetPasswordHandler)
 		managementSubroute.Path("/realms/{realm}/users/{userID}/execute-actions-email").Methods("PUT").Handler(executeActionsEmailHandler)
@@ -1101,31 +1416,72 @@ func main() {
 		managementSubroute.Path("/realms/{realm}/users/{userID}/clear-login-failures").Methods("DELETE").Handler(clearUserLoginFailuresHandler)
 		managementSubroute.Path("/realms/{realm}/users/{userID}/attack-detection-status").Methods("GET").Handler(getAttackDetectionStatusHandler)
 
+		// sessions and consents
+		managementSubroute.Path("/realms/{realm}/users/{userID}/sessions").Methods("GET").Handler(getSessionsOfUserHandler)
+		managementSubroute.Path("/realms/{realm}/users/{userID}/sessions").Methods("DELETE").Handler(logoutUserHandler)
+		managementSubroute.Path("/realms/{realm}/users/{userID}/sessions/{sessionID}").Methods("DELETE").Handler(deleteSessionOfUserHandler)
+		managementSubroute.Path("/realms/{realm}/users/{userID}/offline-sessions/{clientID}").Methods("GET").Handler(getOfflineSessionsOfUserHandler)
+		managementSubroute.Path("/realms/{realm}/users/{userID}/consents").Methods("GET").Handler(getConsentsOfUserHandler)
+		managementSubroute.Path("/realms/{realm}/users/{userID}/consents/{clientID}").Methods("DELETE").Handler(revokeConsentOfUserHandler)
+
 		// roles
 		managementSubroute.Path("/realms/{realm}/roles").Methods("GET").Handler(getRolesHandler)
+		managementSubroute.Path("/realms/{realm}/roles").Methods("POST").Handler(createRoleHandler)
 		managementSubroute.Path("/realms/{realm}/roles-by-id/{roleID}").Methods("GET").Handler(getRoleHandler)
+		managementSubroute.Path("/realms/{realm}/roles-by-id/{roleID}").Methods("PUT").Handler(updateRoleHandler)
+		managementSubroute.Path("/realms/{realm}/roles-by-id/{roleID}").Methods("DELETE").Handler(deleteRoleHandler)
+		managementSubroute.Path("/realms/{realm}/roles-by-id/{roleID}/composites").Methods("GET").Handler(getRoleCompositesHandler)
+		managementSubroute.Path("/realms/{realm}/roles-by-id/{roleID}/composites").Methods("POST").Handler(addRoleCompositesHandler)
+		managementSubroute.Path("/realms/{realm}/roles-by-id/{roleID}/composites").Methods("DELETE").Handler(deleteRoleCompositesHandler)
+		managementSubroute.Path("/realms/{realm}/roles-by-id/{roleID}/users").Methods("GET").Handler(getUsersWithRoleHandler)
 		managementSubroute.Path("/realms/{realm}/clients/{clientID}/roles").Methods("GET").Handler(getClientRolesHandler)
 		managementSubroute.Path("/realms/{realm}/clients/{clientID}/roles").Methods("POST").Handler(createClientRolesHandler)
+		managementSubroute.Path("/realms/{realm}/clients/{clientID}/roles/{roleID}").Methods("DELETE").Handler(deleteClientRoleHandler)
 
 		// groups
 		managementSubroute.Path("/realms/{realm}/groups").Methods("GET").Handler(getGroupsHandler)
 		managementSubroute.Path("/realms/{realm}/groups").Methods("POST").Handler(createGroupHandler)
 		managementSubroute.Path("/realms/{realm}/groups/{groupID}").Methods("DELETE").Handler(deleteGroupHandler)
+		managementSubroute.Path("/realms/{realm}/groups/{groupID}").Methods("GET").Handler(getGroupHandler)
+		managementSubroute.Path("/realms/{realm}/groups/{groupID}").Methods("PUT").Handler(updateGroupHandler)
+		managementSubroute.Path("/realms/{realm}/groups/{groupID}/children").Methods("POST").Handler(createGroupChildHandler)
+		managementSubroute.Path("/realms/{realm}/groups/{groupID}/children/{childGroupID}").Methods("PUT").Handler(moveGroupHandler)
+		managementSubroute.Path("/realms/{realm}/groups/{groupID}/members").Methods("GET").Handler(getGroupMembersHandler)
 		managementSubroute.Path("/realms/{realm}/groups/{groupID}/authorizations").Methods("GET").Handler(getAuthorizationsHandler)
 		managementSubroute.Path("/realms/{realm}/groups/{groupID}/authorizations").Methods("PUT").Handler(updateAuthorizationsHandler)
+		managementSubroute.Path("/realms/{realm}/authorizations/check").Methods("GET").Handler(checkAuthorizationHandler)
+		managementSubroute.Path("/realms/{realm}/authorizations/copy").Methods("POST").Handler(copyAuthorizationsHandler)
+		managementSubroute.Path("/realms/{realm}/groups/{groupID}/authorizations/templates/{templateName}").Methods("PUT").Handler(applyAuthorizationTemplateHandler)
+		managementSubroute.Path("/realms/{realm}/groups/{groupID}/authorizations/versions").Methods("GET").Handler(getAuthorizationsVersionsHandler)
+		managementSubroute.Path("/realms/{realm}/groups/{groupID}/authorizations/versions/diff").Methods("GET").Handler(getAuthorizationsVersionsDiffHandler)
+		managementSubroute.Path("/realms/{realm}/groups/{groupID}/authorizations/versions/{version}/rollback").Methods("POST").Handler(rollbackAuthorizationsHandler)
+
+		// sensitive actions waiting for the approval of a second operator
+		managementSubroute.Path("/realms/{realm}/pending-requests").Methods("GET").Handler(getPendingRequestsHandler)
+		managementSubroute.Path("/realms/{realm}/pending-requests/{requestID}").Methods("GET").Handler(getPendingRequestHandler)
+		managementSubroute.Path("/realms/{realm}/pending-requests/{requestID}/approve").Methods("POST").Handler(approvePendingRequestHandler)
+		managementSubroute.Path("/realms/{realm}/pending-requests/{requestID}/reject").Methods("POST").Handler(rejectPendingRequestHandler)
 
 		// custom configuration per realm
 		managementSubroute.Path("/realms/{realm}/configuration").Methods("GET").Handler(getRealmCustomConfigurationHandler)
 		managementSubroute.Path("/realms/{realm}/configuration").Methods("PUT").Handler(updateRealmCustomConfigurationHandler)
 		managementSubroute.Path("/realms/{realm}/admin-configuration").Methods("GET").Handler(getRealmAdminConfigurationHandler)
 		managementSubroute.Path("/realms/{realm}/admin-configuration").Methods("PUT").Handler(updateRealmAdminConfigurationHandler)
+		managementSubroute.Path("/realms/{realm}/configuration/versions").Methods("GET").Handler(getRealmCustomConfigurationVersionsHandler)
+		managementSubroute.Path("/realms/{realm}/configuration/versions/diff").Methods("GET").Handler(getRealmCustomConfigurationVersionsDiffHandler)
+		managementSubroute.Path("/realms/{realm}/configuration/versions/{version}/rollback").Methods("POST").Handler(rollbackRealmCustomConfigurationHandler)
+		managementSubroute.Path("/realms/{realm}/admin-configuration/versions").Methods("GET").Handler(getRealmAdminConfigurationVersionsHandler)
+		managementSubroute.Path("/realms/{realm}/admin-configuration/versions/diff").Methods("GET").Handler(getRealmAdminConfigurationVersionsDiffHandler)
+		managementSubroute.Path("/realms/{realm}/admin-configuration/versions/{version}/rollback").Methods("POST").Handler(rollbackRealmAdminConfigurationHandler)
 
 		managementSubroute.Path("/realms/{realm}/backoffice-configuration/groups").Methods("GET").Handler(getRealmBackOfficeConfigurationHandler)
 		managementSubroute.Path("/realms/{realm}/backoffice-configuration/groups").Methods("PUT").Handler(updateRealmBackOfficeConfigurationHandler)
 		managementSubroute.Path("/realms/{realm}/backoffice-configuration").Methods("GET").Handler(getUserRealmBackOfficeConfigurationHandler)
 
 		// brokering - shadow users
+		managementSubroute.Path("/realms/{realm}/users/{userID}/federated-identity").Methods("GET").Handler(getFederatedIdentitiesHandler)
 		managementSubroute.Path("/realms/{realm}/users/{userID}/federated-identity/{provider}").Methods("POST").Handler(linkShadowUserHandler)
+		managementSubroute.Path("/realms/{realm}/users/{userID}/federated-identity/{provider}").Methods("DELETE").Handler(unlinkShadowUserHandler)
 
 		// KYC handlers
 		var kycGetActionsHandler = configureKYCHandler(keycloakb.ComponentName, ComponentID, idGenerator, keycloakClient, audienceRequired, tracer, endpointPhysicalCheckAvailabilityChecker, false, logger)(kycEndpoints.GetActions)
@@ -1324,6 +1680,8 @@ func config(ctx context.Context, logger log.Logger) *viper.Viper {
 	//Encryption key
 	v.SetDefault(cfgDbAesGcmTagSize, 16)
 	v.SetDefault(cfgDbAesGcmKey, "")
+	v.SetDefault(cfgDbBlindIndexKey, "")
+	v.SetDefault(cfgDbBlindIndexBackfill, false)
 
 	// CORS configuration
 	v.SetDefault(cfgAllowedOrigins, []string{})
@@ -1365,6 +1723,23 @@ func config(ctx context.Context, logger log.Logger) *viper.Viper {
 	v.SetDefault(cfgRateKeyRegister, 1000)
 	v.SetDefault(cfgRateKeyKYC, 1000)
 
+	// Statistics rollups (table audit_rollup must exist in the audit database)
+	v.SetDefault(cfgStatisticsRollups, false)
+	v.SetDefault(cfgStatisticsRollupsDays, 0)
+
+	// Bulk operations on users
+	v.SetDefault(cfgBulkOperationsRate, 10)
+
+	// Reconciliation of the operations written both in Keycloak and in the users DB
+	v.SetDefault(cfgSagaReconcileInterval, "5m")
+	v.SetDefault(cfgSagaGracePeriod, "10m")
+
+	// Removal of the expired trustID groups
+	v.SetDefault(cfgTrustIDGroupsExpiry, "5m")
+
+	// Expiration of the requests waiting for an approval
+	v.SetDefault(cfgPendingRequestsExpiry, "24h")
+
 	// Influx DB client default.
 	v.SetDefault("influx", false)
 	v.SetDefault("influx-host-port", "")
@@ -1443,6 +1818,9 @@ func config(ctx context.Context, logger log.Logger) *viper.Viper {
 	v.BindEnv(cfgDbAesGcmKey, "CT_BRIDGE_DB_AES_KEY")
 	censoredParameters[cfgDbAesGcmKey] = true
 
+	v.BindEnv(cfgDbBlindIndexKey, "CT_BRIDGE_DB_BLIND_INDEX_KEY")
+	censoredParameters[cfgDbBlindIndexKey] = true
+
 	// Load and log config.
 	v.SetConfigFile(v.GetString(cfgConfigFile))
 	var err = v.ReadInConfig()
@@ -15,6 +15,8 @@ const (
 	MsgErrUnknown              = "unknowError"
 	MsgErrNotConfigured        = "notConfigured"
 	MsgErrUnverified           = "unverifiedFlag"
+	MsgErrOutdated             = "outdatedVersion"
+	MsgErrTooManyUsers         = "tooManyUsers"
 
 	BodyContent                       = "bodyContent"
 	RealmConfiguration                = "realmConfiguration"
@@ -43,6 +45,7 @@ const (
 	GroupIDs                          = "groupIds"
 	RoleID                            = "roleId"
 	CredentialID                      = "credentialID"
+	SessionID                         = "sessionID"
 	Locale                            = "locale"
 	Description                       = "description"
 	ContainerID                       = "containerId"
@@ -66,7 +69,31 @@ const (
 	Exclude                           = "exclude"
 	Unit                              = "unit"
 	Max                               = "max"
+	First                             = "first"
+	Days                              = "days"
 	Timeshift                         = "timeshift"
 	IdentityProvider                  = "identityProvider"
 	TrustIDGroupName                  = "trustIDGroupName"
+	UserIDs                           = "userIds"
+	Search                            = "search"
+	BulkOperation                     = "operation"
+	BulkQuery                         = "query"
+	Action                            = "action"
+	TargetRealm                       = "targetRealm"
+	TargetGroup                       = "targetGroup"
+	AuthorizationTemplate             = "authorizationTemplate"
+	Matrix                            = "matrix"
+	AuthorizationsVersion             = "authorizationsVersion"
+	RealmConfigurationVersion         = "realmConfigurationVersion"
+	From                              = "from"
+	To                                = "to"
+	Version                           = "version"
+	PendingRequest                    = "pendingRequest"
+	ApprovalActions                   = "approvalActions"
+	ExpiresAt                         = "expiresAt"
+	Justification                     = "justification"
+	DuplicateUserID                   = "duplicateUserId"
+	IfMatch                           = "ifMatch"
+	SortBy                            = "sortBy"
+	Accreditation                     = "accreditation"
 )
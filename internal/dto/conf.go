@@ -2,3 +2,45 @@ package dto
 
 // BackOfficeConfiguration definition
 type BackOfficeConfiguration map[string]map[string][]string
+
+// AuthorizationTemplate definition. The matrix gives for each action the allowed target groups per target realm
+type AuthorizationTemplate struct {
+	Name   string
+	Matrix map[string]map[string]map[string]struct{}
+}
+
+// AuthorizationsVersion is a snapshot of the authorizations of a group taken when they are modified
+type AuthorizationsVersion struct {
+	RealmID   string
+	GroupName string
+	Version   int
+	Author    string
+	CreatedAt int64
+	Before    map[string]map[string]map[string]struct{}
+	After     map[string]map[string]map[string]struct{}
+}
+
+// RealmConfigurationVersion is a snapshot of the custom or admin configuration of a realm taken when it is modified.
+// Configuration holds the configuration as JSON
+type RealmConfigurationVersion struct {
+	RealmID       string
+	Type          string
+	Version       int
+	Author        string
+	CreatedAt     int64
+	Configuration string
+}
+
+// PendingRequest is a sensitive management action waiting for the approval of a second operator. Parameters holds the
+// parameters of the action as JSON
+type PendingRequest struct {
+	ID          string
+	RealmName   string
+	Action      string
+	TargetID    string
+	Parameters  string
+	RequesterID string
+	Requester   string
+	CreatedAt   int64
+	ExpiresAt   int64
+}
@@ -24,3 +24,26 @@ type DBCheck struct {
 	ProofType *string
 	Comment   *string
 }
+
+// DBTrustIDGroupExpiry is the expiry date (unix timestamp) of a trustID group assigned to a user for a limited time
+type DBTrustIDGroupExpiry struct {
+	RealmID   string
+	UserID    string
+	GroupName string
+	ExpiresAt int64
+}
+
+// DBFieldChange is the former and the new value of a changed field of a user
+type DBFieldChange struct {
+	Field    string  `json:"field"`
+	OldValue *string `json:"old,omitempty"`
+	NewValue *string `json:"new,omitempty"`
+}
+
+// DBUserChange is an update of the identity fields of a user. ChangedAt is a unix timestamp
+type DBUserChange struct {
+	ChangedAt int64
+	Actor     string
+	Channel   string
+	Changes   []DBFieldChange
+}
@@ -0,0 +1,52 @@
+package keycloakb
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"unicode"
+)
+
+const blindIndexMinKeySize = 32
+
+// BlindIndex computes a keyed hash of a value stored encrypted, so that the rows containing this value can be found
+// without decrypting any of them. The realm is part of the hash: identical values of different realms are not linked
+type BlindIndex interface {
+	Hash(realm string, value string) []byte
+}
+
+type hmacBlindIndex struct {
+	key []byte
+}
+
+// NewBlindIndexFromBase64 creates a HMAC-SHA256 blind index. The key must be different from the encryption key
+func NewBlindIndexFromBase64(base64Key string) (BlindIndex, error) {
+	var key, err = base64.StdEncoding.DecodeString(base64Key)
+	if err != nil {
+		return nil, err
+	}
+	if len(key) < blindIndexMinKeySize {
+		return nil, errors.New("blind index key must be at least 32 bytes long")
+	}
+	return &hmacBlindIndex{key: key}, nil
+}
+
+func (b *hmacBlindIndex) Hash(realm string, value string) []byte {
+	var mac = hmac.New(sha256.New, b.key)
+	mac.Write([]byte(realm))
+	mac.Write([]byte{0})
+	mac.Write([]byte(normalizeBlindIndexValue(value)))
+	return mac.Sum(nil)
+}
+
+// normalizeBlindIndexValue ignores the case and the separators typed when a value is copied from a document
+func normalizeBlindIndexValue(value string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) || r == '-' || r == '.' {
+			return -1
+		}
+		return unicode.ToUpper(r)
+	}, value)
+}
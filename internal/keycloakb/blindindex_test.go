@@ -0,0 +1,39 @@
+package keycloakb
+
+import (
+	"encoding/base64"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBlindIndex(t *testing.T) {
+	var key = base64.StdEncoding.EncodeToString([]byte("0123456789abcdef0123456789abcdef"))
+
+	t.Run("Invalid base64 key", func(t *testing.T) {
+		var _, err = NewBlindIndexFromBase64("not base64!")
+		assert.NotNil(t, err)
+	})
+	t.Run("Key too short", func(t *testing.T) {
+		var _, err = NewBlindIndexFromBase64(base64.StdEncoding.EncodeToString([]byte("short")))
+		assert.NotNil(t, err)
+	})
+
+	var blindIndex, err = NewBlindIndexFromBase64(key)
+	assert.Nil(t, err)
+
+	t.Run("Hash is normalized", func(t *testing.T) {
+		var hash = blindIndex.Hash("realm", "AB123-456.78")
+		assert.Len(t, hash, 32)
+		assert.Equal(t, hash, blindIndex.Hash("realm", " ab 12345678"))
+	})
+	t.Run("Hash depends on value and realm", func(t *testing.T) {
+		var hash = blindIndex.Hash("realm", "AB12345678")
+		assert.NotEqual(t, hash, blindIndex.Hash("realm", "AB12345679"))
+		assert.NotEqual(t, hash, blindIndex.Hash("other-realm", "AB12345678"))
+	})
+	t.Run("Hash depends on key", func(t *testing.T) {
+		var otherIndex, _ = NewBlindIndexFromBase64(base64.StdEncoding.EncodeToString([]byte("fedcba9876543210fedcba9876543210")))
+		assert.NotEqual(t, blindIndex.Hash("realm", "AB12345678"), otherIndex.Hash("realm", "AB12345678"))
+	})
+}
@@ -26,17 +26,38 @@ type configDBModuleInstrumentingMW struct {
 type ConfigurationDBModule interface {
 	NewTransaction(context context.Context) (sqltypes.Transaction, error)
 	GetConfigurations(context.Context, string) (configuration.RealmConfiguration, configuration.RealmAdminConfiguration, error)
-	StoreOrUpdateConfiguration(context.Context, string, configuration.RealmConfiguration) error
+	StoreOrUpdateConfiguration(context.Context, sqltypes.Transaction, string, configuration.RealmConfiguration) error
 	GetConfiguration(context.Context, string) (configuration.RealmConfiguration, error)
-	StoreOrUpdateAdminConfiguration(context.Context, string, configuration.RealmAdminConfiguration) error
+	StoreOrUpdateAdminConfiguration(context.Context, sqltypes.Transaction, string, configuration.RealmAdminConfiguration) error
 	GetAdminConfiguration(context.Context, string) (configuration.RealmAdminConfiguration, error)
+	GetTrustIDGroups(context context.Context, realmID string) ([]string, error)
+	StoreOrUpdateTrustIDGroups(context context.Context, tx sqltypes.Transaction, realmID string, groups []string) error
+	GetApprovalActions(context context.Context, realmID string) ([]string, error)
+	StoreOrUpdateApprovalActions(context context.Context, tx sqltypes.Transaction, realmID string, actions []string) error
 	GetBackOfficeConfiguration(context.Context, string, []string) (dto.BackOfficeConfiguration, error)
 	DeleteBackOfficeConfiguration(context.Context, string, string, string, *string, *string) error
 	InsertBackOfficeConfiguration(context.Context, string, string, string, string, []string) error
 	GetAuthorizations(context context.Context, realmID string, groupName string) ([]configuration.Authorization, error)
-	CreateAuthorization(context context.Context, authz configuration.Authorization) error
-	DeleteAuthorizations(context context.Context, realmID string, groupName string) error
-	DeleteAllAuthorizationsWithGroup(context context.Context, realmName, groupName string) error
+	GetRealmAuthorizations(context context.Context, realmID string) ([]configuration.Authorization, error)
+	GetAuthorizationsOnGroup(context context.Context, targetRealmID, targetGroupName string) ([]configuration.Authorization, error)
+	CreateAuthorization(context context.Context, tx sqltypes.Transaction, authz configuration.Authorization) error
+	DeleteAuthorizations(context context.Context, tx sqltypes.Transaction, realmID string, groupName string) error
+	DeleteAllAuthorizationsWithGroup(context context.Context, tx sqltypes.Transaction, realmName, groupName string) error
+	RenameGroup(context context.Context, tx sqltypes.Transaction, realmID, oldGroupName, newGroupName string) error
+	GetAuthorizationTemplates(context context.Context) ([]dto.AuthorizationTemplate, error)
+	GetAuthorizationTemplate(context context.Context, templateName string) (dto.AuthorizationTemplate, error)
+	StoreOrUpdateAuthorizationTemplate(context context.Context, template dto.AuthorizationTemplate) error
+	DeleteAuthorizationTemplate(context context.Context, templateName string) error
+	CreateAuthorizationsVersion(context context.Context, tx sqltypes.Transaction, version dto.AuthorizationsVersion) error
+	GetAuthorizationsVersions(context context.Context, realmID string, groupName string) ([]dto.AuthorizationsVersion, error)
+	GetAuthorizationsVersion(context context.Context, realmID string, groupName string, version int) (dto.AuthorizationsVersion, error)
+	CreateRealmConfigurationVersion(context context.Context, tx sqltypes.Transaction, version dto.RealmConfigurationVersion) error
+	GetRealmConfigurationVersions(context context.Context, realmID string, configType string) ([]dto.RealmConfigurationVersion, error)
+	GetRealmConfigurationVersion(context context.Context, realmID string, configType string, version int) (dto.RealmConfigurationVersion, error)
+	CreatePendingRequest(context context.Context, request dto.PendingRequest) error
+	GetPendingRequests(context context.Context, realmName string, now time.Time) ([]dto.PendingRequest, error)
+	GetPendingRequest(context context.Context, realmName string, requestID string) (dto.PendingRequest, error)
+	DeletePendingRequest(context context.Context, realmName string, requestID string) (bool, error)
 }
 
 // MakeConfigurationDBModuleInstrumentingMW makes an instrumenting middleware at module level.
@@ -66,11 +87,11 @@ func (m *configDBModuleInstrumentingMW) GetConfigurations(ctx context.Context, r
 }
 
 // configDBModuleInstrumentingMW implements Module.
-func (m *configDBModuleInstrumentingMW) StoreOrUpdateConfiguration(ctx context.Context, realmName string, config configuration.RealmConfiguration) error {
+func (m *configDBModuleInstrumentingMW) StoreOrUpdateConfiguration(ctx context.Context, tx sqltypes.Transaction, realmName string, config configuration.RealmConfiguration) error {
 	defer func(begin time.Time) {
 		m.h.With(KeyCorrelationID, ctx.Value(cs.CtContextCorrelationID).(string)).Observe(time.Since(begin).Seconds())
 	}(time.Now())
-	return m.next.StoreOrUpdateConfiguration(ctx, realmName, config)
+	return m.next.StoreOrUpdateConfiguration(ctx, tx, realmName, config)
 }
 
 // configDBModuleInstrumentingMW implements Module.
@@ -82,11 +103,11 @@ func (m *configDBModuleInstrumentingMW) GetConfiguration(ctx context.Context, re
 }
 
 // configDBModuleInstrumentingMW implements Module.
-func (m *configDBModuleInstrumentingMW) StoreOrUpdateAdminConfiguration(ctx context.Context, realmName string, config configuration.RealmAdminConfiguration) error {
+func (m *configDBModuleInstrumentingMW) StoreOrUpdateAdminConfiguration(ctx context.Context, tx sqltypes.Transaction, realmName string, config configuration.RealmAdminConfiguration) error {
 	defer func(begin time.Time) {
 		m.h.With(KeyCorrelationID, ctx.Value(cs.CtContextCorrelationID).(string)).Observe(time.Since(begin).Seconds())
 	}(time.Now())
-	return m.next.StoreOrUpdateAdminConfiguration(ctx, realmName, config)
+	return m.next.StoreOrUpdateAdminConfiguration(ctx, tx, realmName, config)
 }
 
 // configDBModuleInstrumentingMW implements Module.
@@ -128,25 +149,193 @@ func (m *configDBModuleInstrumentingMW) GetAuthorizations(ctx context.Context, r
 }
 
 // configDBModuleInstrumentingMW implements Module.
-func (m *configDBModuleInstrumentingMW) CreateAuthorization(ctx context.Context, auth configuration.Authorization) error {
+func (m *configDBModuleInstrumentingMW) GetAuthorizationsOnGroup(ctx context.Context, targetRealmID, targetGroupName string) ([]configuration.Authorization, error) {
 	defer func(begin time.Time) {
 		m.h.With(KeyCorrelationID, ctx.Value(cs.CtContextCorrelationID).(string)).Observe(time.Since(begin).Seconds())
 	}(time.Now())
-	return m.next.CreateAuthorization(ctx, auth)
+	return m.next.GetAuthorizationsOnGroup(ctx, targetRealmID, targetGroupName)
 }
 
 // configDBModuleInstrumentingMW implements Module.
-func (m *configDBModuleInstrumentingMW) DeleteAuthorizations(ctx context.Context, realmID string, groupID string) error {
+func (m *configDBModuleInstrumentingMW) CreateAuthorization(ctx context.Context, tx sqltypes.Transaction, auth configuration.Authorization) error {
 	defer func(begin time.Time) {
 		m.h.With(KeyCorrelationID, ctx.Value(cs.CtContextCorrelationID).(string)).Observe(time.Since(begin).Seconds())
 	}(time.Now())
-	return m.next.DeleteAuthorizations(ctx, realmID, groupID)
+	return m.next.CreateAuthorization(ctx, tx, auth)
 }
 
 // configDBModuleInstrumentingMW implements Module.
-func (m *configDBModuleInstrumentingMW) DeleteAllAuthorizationsWithGroup(ctx context.Context, realmID, groupName string) error {
+func (m *configDBModuleInstrumentingMW) DeleteAuthorizations(ctx context.Context, tx sqltypes.Transaction, realmID string, groupID string) error {
 	defer func(begin time.Time) {
 		m.h.With(KeyCorrelationID, ctx.Value(cs.CtContextCorrelationID).(string)).Observe(time.Since(begin).Seconds())
 	}(time.Now())
-	return m.next.DeleteAllAuthorizationsWithGroup(ctx, realmID, groupName)
+	return m.next.DeleteAuthorizations(ctx, tx, realmID, groupID)
+}
+
+// configDBModuleInstrumentingMW implements Module.
+func (m *configDBModuleInstrumentingMW) DeleteAllAuthorizationsWithGroup(ctx context.Context, tx sqltypes.Transaction, realmID, groupName string) error {
+	defer func(begin time.Time) {
+		m.h.With(KeyCorrelationID, ctx.Value(cs.CtContextCorrelationID).(string)).Observe(time.Since(begin).Seconds())
+	}(time.Now())
+	return m.next.DeleteAllAuthorizationsWithGroup(ctx, tx, realmID, groupName)
+}
+
+// configDBModuleInstrumentingMW implements Module.
+func (m *configDBModuleInstrumentingMW) RenameGroup(ctx context.Context, tx sqltypes.Transaction, realmID, oldGroupName, newGroupName string) error {
+	defer func(begin time.Time) {
+		m.h.With(KeyCorrelationID, ctx.Value(cs.CtContextCorrelationID).(string)).Observe(time.Since(begin).Seconds())
+	}(time.Now())
+	return m.next.RenameGroup(ctx, tx, realmID, oldGroupName, newGroupName)
+}
+
+// configDBModuleInstrumentingMW implements Module.
+func (m *configDBModuleInstrumentingMW) GetRealmAuthorizations(ctx context.Context, realmID string) ([]configuration.Authorization, error) {
+	defer func(begin time.Time) {
+		m.h.With(KeyCorrelationID, ctx.Value(cs.CtContextCorrelationID).(string)).Observe(time.Since(begin).Seconds())
+	}(time.Now())
+	return m.next.GetRealmAuthorizations(ctx, realmID)
+}
+
+// configDBModuleInstrumentingMW implements Module.
+func (m *configDBModuleInstrumentingMW) GetAuthorizationTemplates(ctx context.Context) ([]dto.AuthorizationTemplate, error) {
+	defer func(begin time.Time) {
+		m.h.With(KeyCorrelationID, ctx.Value(cs.CtContextCorrelationID).(string)).Observe(time.Since(begin).Seconds())
+	}(time.Now())
+	return m.next.GetAuthorizationTemplates(ctx)
+}
+
+// configDBModuleInstrumentingMW implements Module.
+func (m *configDBModuleInstrumentingMW) GetAuthorizationTemplate(ctx context.Context, templateName string) (dto.AuthorizationTemplate, error) {
+	defer func(begin time.Time) {
+		m.h.With(KeyCorrelationID, ctx.Value(cs.CtContextCorrelationID).(string)).Observe(time.Since(begin).Seconds())
+	}(time.Now())
+	return m.next.GetAuthorizationTemplate(ctx, templateName)
+}
+
+// configDBModuleInstrumentingMW implements Module.
+func (m *configDBModuleInstrumentingMW) StoreOrUpdateAuthorizationTemplate(ctx context.Context, template dto.AuthorizationTemplate) error {
+	defer func(begin time.Time) {
+		m.h.With(KeyCorrelationID, ctx.Value(cs.CtContextCorrelationID).(string)).Observe(time.Since(begin).Seconds())
+	}(time.Now())
+	return m.next.StoreOrUpdateAuthorizationTemplate(ctx, template)
+}
+
+// configDBModuleInstrumentingMW implements Module.
+func (m *configDBModuleInstrumentingMW) DeleteAuthorizationTemplate(ctx context.Context, templateName string) error {
+	defer func(begin time.Time) {
+		m.h.With(KeyCorrelationID, ctx.Value(cs.CtContextCorrelationID).(string)).Observe(time.Since(begin).Seconds())
+	}(time.Now())
+	return m.next.DeleteAuthorizationTemplate(ctx, templateName)
+}
+
+// configDBModuleInstrumentingMW implements Module.
+func (m *configDBModuleInstrumentingMW) CreateAuthorizationsVersion(ctx context.Context, tx sqltypes.Transaction, version dto.AuthorizationsVersion) error {
+	defer func(begin time.Time) {
+		m.h.With(KeyCorrelationID, ctx.Value(cs.CtContextCorrelationID).(string)).Observe(time.Since(begin).Seconds())
+	}(time.Now())
+	return m.next.CreateAuthorizationsVersion(ctx, tx, version)
+}
+
+// configDBModuleInstrumentingMW implements Module.
+func (m *configDBModuleInstrumentingMW) GetAuthorizationsVersions(ctx context.Context, realmID string, groupName string) ([]dto.AuthorizationsVersion, error) {
+	defer func(begin time.Time) {
+		m.h.With(KeyCorrelationID, ctx.Value(cs.CtContextCorrelationID).(string)).Observe(time.Since(begin).Seconds())
+	}(time.Now())
+	return m.next.GetAuthorizationsVersions(ctx, realmID, groupName)
+}
+
+// configDBModuleInstrumentingMW implements Module.
+func (m *configDBModuleInstrumentingMW) GetAuthorizationsVersion(ctx context.Context, realmID string, groupName string, version int) (dto.AuthorizationsVersion, error) {
+	defer func(begin time.Time) {
+		m.h.With(KeyCorrelationID, ctx.Value(cs.CtContextCorrelationID).(string)).Observe(time.Since(begin).Seconds())
+	}(time.Now())
+	return m.next.GetAuthorizationsVersion(ctx, realmID, groupName, version)
+}
+
+// configDBModuleInstrumentingMW implements Module.
+func (m *configDBModuleInstrumentingMW) CreateRealmConfigurationVersion(ctx context.Context, tx sqltypes.Transaction, version dto.RealmConfigurationVersion) error {
+	defer func(begin time.Time) {
+		m.h.With(KeyCorrelationID, ctx.Value(cs.CtContextCorrelationID).(string)).Observe(time.Since(begin).Seconds())
+	}(time.Now())
+	return m.next.CreateRealmConfigurationVersion(ctx, tx, version)
+}
+
+// configDBModuleInstrumentingMW implements Module.
+func (m *configDBModuleInstrumentingMW) GetRealmConfigurationVersions(ctx context.Context, realmID string, configType string) ([]dto.RealmConfigurationVersion, error) {
+	defer func(begin time.Time) {
+		m.h.With(KeyCorrelationID, ctx.Value(cs.CtContextCorrelationID).(string)).Observe(time.Since(begin).Seconds())
+	}(time.Now())
+	return m.next.GetRealmConfigurationVersions(ctx, realmID, configType)
+}
+
+// configDBModuleInstrumentingMW implements Module.
+func (m *configDBModuleInstrumentingMW) GetRealmConfigurationVersion(ctx context.Context, realmID string, configType string, version int) (dto.RealmConfigurationVersion, error) {
+	defer func(begin time.Time) {
+		m.h.With(KeyCorrelationID, ctx.Value(cs.CtContextCorrelationID).(string)).Observe(time.Since(begin).Seconds())
+	}(time.Now())
+	return m.next.GetRealmConfigurationVersion(ctx, realmID, configType, version)
+}
+
+// configDBModuleInstrumentingMW implements Module.
+func (m *configDBModuleInstrumentingMW) GetTrustIDGroups(ctx context.Context, realmID string) ([]string, error) {
+	defer func(begin time.Time) {
+		m.h.With(KeyCorrelationID, ctx.Value(cs.CtContextCorrelationID).(string)).Observe(time.Since(begin).Seconds())
+	}(time.Now())
+	return m.next.GetTrustIDGroups(ctx, realmID)
+}
+
+// configDBModuleInstrumentingMW implements Module.
+func (m *configDBModuleInstrumentingMW) StoreOrUpdateTrustIDGroups(ctx context.Context, tx sqltypes.Transaction, realmID string, groups []string) error {
+	defer func(begin time.Time) {
+		m.h.With(KeyCorrelationID, ctx.Value(cs.CtContextCorrelationID).(string)).Observe(time.Since(begin).Seconds())
+	}(time.Now())
+	return m.next.StoreOrUpdateTrustIDGroups(ctx, tx, realmID, groups)
+}
+
+// configDBModuleInstrumentingMW implements Module.
+func (m *configDBModuleInstrumentingMW) GetApprovalActions(ctx context.Context, realmID string) ([]string, error) {
+	defer func(begin time.Time) {
+		m.h.With(KeyCorrelationID, ctx.Value(cs.CtContextCorrelationID).(string)).Observe(time.Since(begin).Seconds())
+	}(time.Now())
+	return m.next.GetApprovalActions(ctx, realmID)
+}
+
+// configDBModuleInstrumentingMW implements Module.
+func (m *configDBModuleInstrumentingMW) StoreOrUpdateApprovalActions(ctx context.Context, tx sqltypes.Transaction, realmID string, actions []string) error {
+	defer func(begin time.Time) {
+		m.h.With(KeyCorrelationID, ctx.Value(cs.CtContextCorrelationID).(string)).Observe(time.Since(begin).Seconds())
+	}(time.Now())
+	return m.next.StoreOrUpdateApprovalActions(ctx, tx, realmID, actions)
+}
+
+// configDBModuleInstrumentingMW implements Module.
+func (m *configDBModuleInstrumentingMW) CreatePendingRequest(ctx context.Context, request dto.PendingRequest) error {
+	defer func(begin time.Time) {
+		m.h.With(KeyCorrelationID, ctx.Value(cs.CtContextCorrelationID).(string)).Observe(time.Since(begin).Seconds())
+	}(time.Now())
+	return m.next.CreatePendingRequest(ctx, request)
+}
+
+// configDBModuleInstrumentingMW implements Module.
+func (m *configDBModuleInstrumentingMW) GetPendingRequests(ctx context.Context, realmName string, now time.Time) ([]dto.PendingRequest, error) {
+	defer func(begin time.Time) {
+		m.h.With(KeyCorrelationID, ctx.Value(cs.CtContextCorrelationID).(string)).Observe(time.Since(begin).Seconds())
+	}(time.Now())
+	return m.next.GetPendingRequests(ctx, realmName, now)
+}
+
+// configDBModuleInstrumentingMW implements Module.
+func (m *configDBModuleInstrumentingMW) GetPendingRequest(ctx context.Context, realmName string, requestID string) (dto.PendingRequest, error) {
+	defer func(begin time.Time) {
+		m.h.With(KeyCorrelationID, ctx.Value(cs.CtContextCorrelationID).(string)).Observe(time.Since(begin).Seconds())
+	}(time.Now())
+	return m.next.GetPendingRequest(ctx, realmName, requestID)
+}
+
+// configDBModuleInstrumentingMW implements Module.
+func (m *configDBModuleInstrumentingMW) DeletePendingRequest(ctx context.Context, realmName string, requestID string) (bool, error) {
+	defer func(begin time.Time) {
+		m.h.With(KeyCorrelationID, ctx.Value(cs.CtContextCorrelationID).(string)).Observe(time.Since(begin).Seconds())
+	}(time.Now())
+	return m.next.DeletePendingRequest(ctx, realmName, requestID)
 }
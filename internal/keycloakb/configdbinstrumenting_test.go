@@ -34,6 +34,7 @@ func TestComponentInstrumentingMW(t *testing.T) {
 	var groupName = groupNames[0]
 	var confType = "customers"
 	var adminConfig = configuration.RealmAdminConfiguration{}
+	var now = time.Now()
 
 	t.Run("Get configurations", func(t *testing.T) {
 		mockComponent.EXPECT().GetConfigurations(ctx, realmID).Return(configuration.RealmConfiguration{}, configuration.RealmAdminConfiguration{}, nil)
@@ -63,15 +64,15 @@ func TestComponentInstrumentingMW(t *testing.T) {
 	})
 
 	t.Run("Update configuration", func(t *testing.T) {
-		mockComponent.EXPECT().StoreOrUpdateConfiguration(ctx, "realmID", configuration.RealmConfiguration{}).Return(nil).Times(1)
+		mockComponent.EXPECT().StoreOrUpdateConfiguration(ctx, nil, "realmID", configuration.RealmConfiguration{}).Return(nil).Times(1)
 		mockHistogram.EXPECT().With("correlation_id", corrID).Return(mockHistogram).Times(1)
 		mockHistogram.EXPECT().Observe(gomock.Any()).Return().Times(1)
-		m.StoreOrUpdateConfiguration(ctx, "realmID", configuration.RealmConfiguration{})
+		m.StoreOrUpdateConfiguration(ctx, nil, "realmID", configuration.RealmConfiguration{})
 	})
 	t.Run("Update configuration without correlation ID", func(t *testing.T) {
-		mockComponent.EXPECT().StoreOrUpdateConfiguration(context.Background(), "realmID", configuration.RealmConfiguration{}).Return(nil).Times(1)
+		mockComponent.EXPECT().StoreOrUpdateConfiguration(context.Background(), nil, "realmID", configuration.RealmConfiguration{}).Return(nil).Times(1)
 		var f = func() {
-			m.StoreOrUpdateConfiguration(context.Background(), "realmID", configuration.RealmConfiguration{})
+			m.StoreOrUpdateConfiguration(context.Background(), nil, "realmID", configuration.RealmConfiguration{})
 		}
 		assert.Panics(t, f)
 	})
@@ -92,16 +93,16 @@ func TestComponentInstrumentingMW(t *testing.T) {
 	})
 
 	t.Run("Update admin configuration with correlation ID", func(t *testing.T) {
-		mockComponent.EXPECT().StoreOrUpdateAdminConfiguration(ctx, "realmID", gomock.Any()).Return(nil).Times(1)
+		mockComponent.EXPECT().StoreOrUpdateAdminConfiguration(ctx, nil, "realmID", gomock.Any()).Return(nil).Times(1)
 		mockHistogram.EXPECT().With("correlation_id", corrID).Return(mockHistogram).Times(1)
 		mockHistogram.EXPECT().Observe(gomock.Any()).Return().Times(1)
-		m.StoreOrUpdateAdminConfiguration(ctx, "realmID", configuration.RealmAdminConfiguration{})
+		m.StoreOrUpdateAdminConfiguration(ctx, nil, "realmID", configuration.RealmAdminConfiguration{})
 	})
 
 	t.Run("Update configuration without correlation ID", func(t *testing.T) {
-		mockComponent.EXPECT().StoreOrUpdateAdminConfiguration(context.Background(), "realmID", gomock.Any()).Return(nil).Times(1)
+		mockComponent.EXPECT().StoreOrUpdateAdminConfiguration(context.Background(), nil, "realmID", gomock.Any()).Return(nil).Times(1)
 		assert.Panics(t, func() {
-			m.StoreOrUpdateAdminConfiguration(context.Background(), "realmID", configuration.RealmAdminConfiguration{})
+			m.StoreOrUpdateAdminConfiguration(context.Background(), nil, "realmID", configuration.RealmAdminConfiguration{})
 		})
 	})
 
@@ -142,4 +143,220 @@ func TestComponentInstrumentingMW(t *testing.T) {
 			m.InsertBackOfficeConfiguration(context.Background(), realmID, groupName, confType, realmID, groupNames)
 		})
 	})
+	t.Run("Rename group", func(t *testing.T) {
+		mockComponent.EXPECT().RenameGroup(ctx, nil, realmID, groupName, "new-name").Return(nil)
+		mockHistogram.EXPECT().With("correlation_id", corrID).Return(mockHistogram).Times(1)
+		mockHistogram.EXPECT().Observe(gomock.Any()).Return().Times(1)
+		m.RenameGroup(ctx, nil, realmID, groupName, "new-name")
+	})
+	t.Run("Rename group without correlation ID", func(t *testing.T) {
+		mockComponent.EXPECT().RenameGroup(context.Background(), nil, realmID, groupName, "new-name").Return(nil)
+		assert.Panics(t, func() {
+			m.RenameGroup(context.Background(), nil, realmID, groupName, "new-name")
+		})
+	})
+	t.Run("Get realm authorizations", func(t *testing.T) {
+		mockComponent.EXPECT().GetRealmAuthorizations(ctx, realmID).Return(nil, nil)
+		mockHistogram.EXPECT().With("correlation_id", corrID).Return(mockHistogram).Times(1)
+		mockHistogram.EXPECT().Observe(gomock.Any()).Return().Times(1)
+		m.GetRealmAuthorizations(ctx, realmID)
+	})
+	t.Run("Get realm authorizations without correlation ID", func(t *testing.T) {
+		mockComponent.EXPECT().GetRealmAuthorizations(context.Background(), realmID).Return(nil, nil)
+		assert.Panics(t, func() {
+			m.GetRealmAuthorizations(context.Background(), realmID)
+		})
+	})
+	t.Run("Get authorizations on group", func(t *testing.T) {
+		mockComponent.EXPECT().GetAuthorizationsOnGroup(ctx, realmID, groupName).Return(nil, nil)
+		mockHistogram.EXPECT().With("correlation_id", corrID).Return(mockHistogram).Times(1)
+		mockHistogram.EXPECT().Observe(gomock.Any()).Return().Times(1)
+		m.GetAuthorizationsOnGroup(ctx, realmID, groupName)
+	})
+	t.Run("Get authorizations on group without correlation ID", func(t *testing.T) {
+		mockComponent.EXPECT().GetAuthorizationsOnGroup(context.Background(), realmID, groupName).Return(nil, nil)
+		assert.Panics(t, func() {
+			m.GetAuthorizationsOnGroup(context.Background(), realmID, groupName)
+		})
+	})
+	t.Run("Get authorization templates", func(t *testing.T) {
+		mockComponent.EXPECT().GetAuthorizationTemplates(ctx).Return(nil, nil)
+		mockHistogram.EXPECT().With("correlation_id", corrID).Return(mockHistogram).Times(1)
+		mockHistogram.EXPECT().Observe(gomock.Any()).Return().Times(1)
+		m.GetAuthorizationTemplates(ctx)
+	})
+	t.Run("Get authorization templates without correlation ID", func(t *testing.T) {
+		mockComponent.EXPECT().GetAuthorizationTemplates(context.Background()).Return(nil, nil)
+		assert.Panics(t, func() {
+			m.GetAuthorizationTemplates(context.Background())
+		})
+	})
+	t.Run("Get authorization template", func(t *testing.T) {
+		mockComponent.EXPECT().GetAuthorizationTemplate(ctx, "template").Return(dto.AuthorizationTemplate{}, nil)
+		mockHistogram.EXPECT().With("correlation_id", corrID).Return(mockHistogram).Times(1)
+		mockHistogram.EXPECT().Observe(gomock.Any()).Return().Times(1)
+		m.GetAuthorizationTemplate(ctx, "template")
+	})
+	t.Run("Get authorization template without correlation ID", func(t *testing.T) {
+		mockComponent.EXPECT().GetAuthorizationTemplate(context.Background(), "template").Return(dto.AuthorizationTemplate{}, nil)
+		assert.Panics(t, func() {
+			m.GetAuthorizationTemplate(context.Background(), "template")
+		})
+	})
+	t.Run("Store authorization template", func(t *testing.T) {
+		mockComponent.EXPECT().StoreOrUpdateAuthorizationTemplate(ctx, dto.AuthorizationTemplate{}).Return(nil)
+		mockHistogram.EXPECT().With("correlation_id", corrID).Return(mockHistogram).Times(1)
+		mockHistogram.EXPECT().Observe(gomock.Any()).Return().Times(1)
+		m.StoreOrUpdateAuthorizationTemplate(ctx, dto.AuthorizationTemplate{})
+	})
+	t.Run("Store authorization template without correlation ID", func(t *testing.T) {
+		mockComponent.EXPECT().StoreOrUpdateAuthorizationTemplate(context.Background(), dto.AuthorizationTemplate{}).Return(nil)
+		assert.Panics(t, func() {
+			m.StoreOrUpdateAuthorizationTemplate(context.Background(), dto.AuthorizationTemplate{})
+		})
+	})
+	t.Run("Delete authorization template", func(t *testing.T) {
+		mockComponent.EXPECT().DeleteAuthorizationTemplate(ctx, "template").Return(nil)
+		mockHistogram.EXPECT().With("correlation_id", corrID).Return(mockHistogram).Times(1)
+		mockHistogram.EXPECT().Observe(gomock.Any()).Return().Times(1)
+		m.DeleteAuthorizationTemplate(ctx, "template")
+	})
+	t.Run("Delete authorization template without correlation ID", func(t *testing.T) {
+		mockComponent.EXPECT().DeleteAuthorizationTemplate(context.Background(), "template").Return(nil)
+		assert.Panics(t, func() {
+			m.DeleteAuthorizationTemplate(context.Background(), "template")
+		})
+	})
+	t.Run("Create authorizations version", func(t *testing.T) {
+		mockComponent.EXPECT().CreateAuthorizationsVersion(ctx, nil, dto.AuthorizationsVersion{}).Return(nil)
+		mockHistogram.EXPECT().With("correlation_id", corrID).Return(mockHistogram).Times(1)
+		mockHistogram.EXPECT().Observe(gomock.Any()).Return().Times(1)
+		m.CreateAuthorizationsVersion(ctx, nil, dto.AuthorizationsVersion{})
+	})
+	t.Run("Create authorizations version without correlation ID", func(t *testing.T) {
+		mockComponent.EXPECT().CreateAuthorizationsVersion(context.Background(), nil, dto.AuthorizationsVersion{}).Return(nil)
+		assert.Panics(t, func() {
+			m.CreateAuthorizationsVersion(context.Background(), nil, dto.AuthorizationsVersion{})
+		})
+	})
+	t.Run("Get authorizations versions", func(t *testing.T) {
+		mockComponent.EXPECT().GetAuthorizationsVersions(ctx, realmID, groupName).Return(nil, nil)
+		mockHistogram.EXPECT().With("correlation_id", corrID).Return(mockHistogram).Times(1)
+		mockHistogram.EXPECT().Observe(gomock.Any()).Return().Times(1)
+		m.GetAuthorizationsVersions(ctx, realmID, groupName)
+	})
+	t.Run("Get authorizations versions without correlation ID", func(t *testing.T) {
+		mockComponent.EXPECT().GetAuthorizationsVersions(context.Background(), realmID, groupName).Return(nil, nil)
+		assert.Panics(t, func() {
+			m.GetAuthorizationsVersions(context.Background(), realmID, groupName)
+		})
+	})
+	t.Run("Get authorizations version", func(t *testing.T) {
+		mockComponent.EXPECT().GetAuthorizationsVersion(ctx, realmID, groupName, 1).Return(dto.AuthorizationsVersion{}, nil)
+		mockHistogram.EXPECT().With("correlation_id", corrID).Return(mockHistogram).Times(1)
+		mockHistogram.EXPECT().Observe(gomock.Any()).Return().Times(1)
+		m.GetAuthorizationsVersion(ctx, realmID, groupName, 1)
+	})
+	t.Run("Get authorizations version without correlation ID", func(t *testing.T) {
+		mockComponent.EXPECT().GetAuthorizationsVersion(context.Background(), realmID, groupName, 1).Return(dto.AuthorizationsVersion{}, nil)
+		assert.Panics(t, func() {
+			m.GetAuthorizationsVersion(context.Background(), realmID, groupName, 1)
+		})
+	})
+	t.Run("Get trustID groups", func(t *testing.T) {
+		mockComponent.EXPECT().GetTrustIDGroups(ctx, realmID).Return(nil, nil)
+		mockHistogram.EXPECT().With("correlation_id", corrID).Return(mockHistogram).Times(1)
+		mockHistogram.EXPECT().Observe(gomock.Any()).Return().Times(1)
+		m.GetTrustIDGroups(ctx, realmID)
+	})
+	t.Run("Get trustID groups without correlation ID", func(t *testing.T) {
+		mockComponent.EXPECT().GetTrustIDGroups(context.Background(), realmID).Return(nil, nil)
+		assert.Panics(t, func() {
+			m.GetTrustIDGroups(context.Background(), realmID)
+		})
+	})
+	t.Run("Store trustID groups", func(t *testing.T) {
+		mockComponent.EXPECT().StoreOrUpdateTrustIDGroups(ctx, nil, realmID, []string{"grp1"}).Return(nil)
+		mockHistogram.EXPECT().With("correlation_id", corrID).Return(mockHistogram).Times(1)
+		mockHistogram.EXPECT().Observe(gomock.Any()).Return().Times(1)
+		m.StoreOrUpdateTrustIDGroups(ctx, nil, realmID, []string{"grp1"})
+	})
+	t.Run("Store trustID groups without correlation ID", func(t *testing.T) {
+		mockComponent.EXPECT().StoreOrUpdateTrustIDGroups(context.Background(), nil, realmID, []string{"grp1"}).Return(nil)
+		assert.Panics(t, func() {
+			m.StoreOrUpdateTrustIDGroups(context.Background(), nil, realmID, []string{"grp1"})
+		})
+	})
+	t.Run("Get approval actions", func(t *testing.T) {
+		mockComponent.EXPECT().GetApprovalActions(ctx, realmID).Return(nil, nil)
+		mockHistogram.EXPECT().With("correlation_id", corrID).Return(mockHistogram).Times(1)
+		mockHistogram.EXPECT().Observe(gomock.Any()).Return().Times(1)
+		m.GetApprovalActions(ctx, realmID)
+	})
+	t.Run("Get approval actions without correlation ID", func(t *testing.T) {
+		mockComponent.EXPECT().GetApprovalActions(context.Background(), realmID).Return(nil, nil)
+		assert.Panics(t, func() {
+			m.GetApprovalActions(context.Background(), realmID)
+		})
+	})
+	t.Run("Store approval actions", func(t *testing.T) {
+		mockComponent.EXPECT().StoreOrUpdateApprovalActions(ctx, nil, realmID, []string{"MGMT_DeleteUser"}).Return(nil)
+		mockHistogram.EXPECT().With("correlation_id", corrID).Return(mockHistogram).Times(1)
+		mockHistogram.EXPECT().Observe(gomock.Any()).Return().Times(1)
+		m.StoreOrUpdateApprovalActions(ctx, nil, realmID, []string{"MGMT_DeleteUser"})
+	})
+	t.Run("Store approval actions without correlation ID", func(t *testing.T) {
+		mockComponent.EXPECT().StoreOrUpdateApprovalActions(context.Background(), nil, realmID, []string{"MGMT_DeleteUser"}).Return(nil)
+		assert.Panics(t, func() {
+			m.StoreOrUpdateApprovalActions(context.Background(), nil, realmID, []string{"MGMT_DeleteUser"})
+		})
+	})
+	t.Run("Create pending request", func(t *testing.T) {
+		mockComponent.EXPECT().CreatePendingRequest(ctx, dto.PendingRequest{}).Return(nil)
+		mockHistogram.EXPECT().With("correlation_id", corrID).Return(mockHistogram).Times(1)
+		mockHistogram.EXPECT().Observe(gomock.Any()).Return().Times(1)
+		m.CreatePendingRequest(ctx, dto.PendingRequest{})
+	})
+	t.Run("Create pending request without correlation ID", func(t *testing.T) {
+		mockComponent.EXPECT().CreatePendingRequest(context.Background(), dto.PendingRequest{}).Return(nil)
+		assert.Panics(t, func() {
+			m.CreatePendingRequest(context.Background(), dto.PendingRequest{})
+		})
+	})
+	t.Run("Get pending requests", func(t *testing.T) {
+		mockComponent.EXPECT().GetPendingRequests(ctx, realmID, now).Return(nil, nil)
+		mockHistogram.EXPECT().With("correlation_id", corrID).Return(mockHistogram).Times(1)
+		mockHistogram.EXPECT().Observe(gomock.Any()).Return().Times(1)
+		m.GetPendingRequests(ctx, realmID, now)
+	})
+	t.Run("Get pending requests without correlation ID", func(t *testing.T) {
+		mockComponent.EXPECT().GetPendingRequests(context.Background(), realmID, now).Return(nil, nil)
+		assert.Panics(t, func() {
+			m.GetPendingRequests(context.Background(), realmID, now)
+		})
+	})
+	t.Run("Get pending request", func(t *testing.T) {
+		mockComponent.EXPECT().GetPendingRequest(ctx, realmID, "request-id").Return(dto.PendingRequest{}, nil)
+		mockHistogram.EXPECT().With("correlation_id", corrID).Return(mockHistogram).Times(1)
+		mockHistogram.EXPECT().Observe(gomock.Any()).Return().Times(1)
+		m.GetPendingRequest(ctx, realmID, "request-id")
+	})
+	t.Run("Get pending request without correlation ID", func(t *testing.T) {
+		mockComponent.EXPECT().GetPendingRequest(context.Background(), realmID, "request-id").Return(dto.PendingRequest{}, nil)
+		assert.Panics(t, func() {
+			m.GetPendingRequest(context.Background(), realmID, "request-id")
+		})
+	})
+	t.Run("Delete pending request", func(t *testing.T) {
+		mockComponent.EXPECT().DeletePendingRequest(ctx, realmID, "request-id").Return(true, nil)
+		mockHistogram.EXPECT().With("correlation_id", corrID).Return(mockHistogram).Times(1)
+		mockHistogram.EXPECT().Observe(gomock.Any()).Return().Times(1)
+		m.DeletePendingRequest(ctx, realmID, "request-id")
+	})
+	t.Run("Delete pending request without correlation ID", func(t *testing.T) {
+		mockComponent.EXPECT().DeletePendingRequest(context.Background(), realmID, "request-id").Return(true, nil)
+		assert.Panics(t, func() {
+			m.DeletePendingRequest(context.Background(), realmID, "request-id")
+		})
+	})
 }
@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"encoding/json"
 	"strings"
+	"time"
 
 	"github.com/cloudtrust/common-service/configuration"
 	"github.com/cloudtrust/common-service/database/sqltypes"
@@ -21,6 +22,24 @@ const (
 	updateAdminConfigStmt = `INSERT INTO realm_configuration (realm_id, admin_configuration)
 	  VALUES (?, ?)
 	  ON DUPLICATE KEY UPDATE admin_configuration = ?;`
+
+	// The trustID groups which can be assigned in a realm are stored as a JSON array in a nullable column of the realm
+	// configuration. NULL means that the groups of the bridge configuration are allowed:
+	//
+	//	ALTER TABLE realm_configuration ADD COLUMN trustid_groups TEXT NULL;
+	selectTrustIDGroupsStmt = `SELECT trustid_groups FROM realm_configuration WHERE realm_id = ?;`
+	updateTrustIDGroupsStmt = `INSERT INTO realm_configuration (realm_id, trustid_groups)
+	  VALUES (?, ?)
+	  ON DUPLICATE KEY UPDATE trustid_groups = ?;`
+
+	// The sensitive actions which must be approved by a second operator in a realm are stored as a JSON array in a
+	// nullable column of the realm configuration:
+	//
+	//	ALTER TABLE realm_configuration ADD COLUMN approval_actions TEXT NULL;
+	selectApprovalActionsStmt = `SELECT approval_actions FROM realm_configuration WHERE realm_id = ?;`
+	updateApprovalActionsStmt = `INSERT INTO realm_configuration (realm_id, approval_actions)
+	  VALUES (?, ?)
+	  ON DUPLICATE KEY UPDATE approval_actions = ?;`
 	selectBOConfigStmt = `
 		SELECT distinct target_realm_id, target_type, target_group_name
 		FROM backoffice_configuration
@@ -38,11 +57,102 @@ const (
 		  AND (? IS NULL OR target_type=?)
 		  AND (? IS NULL OR target_group_name=?)
 	`
-	selectAuthzStmt = `SELECT realm_id, group_name, action, target_realm_id, target_group_name FROM authorizations WHERE realm_id = ? AND group_name = ?;`
-	createAuthzStmt = `INSERT INTO authorizations (realm_id, group_name, action, target_realm_id, target_group_name) 
+	selectAuthzStmt       = `SELECT realm_id, group_name, action, target_realm_id, target_group_name FROM authorizations WHERE realm_id = ? AND group_name = ?;`
+	selectRealmAuthzStmt  = `SELECT realm_id, group_name, action, target_realm_id, target_group_name FROM authorizations WHERE realm_id = ?;`
+	selectTargetAuthzStmt = `SELECT realm_id, group_name, action, target_realm_id, target_group_name FROM authorizations WHERE target_realm_id = ? AND target_group_name = ?;`
+	createAuthzStmt       = `INSERT INTO authorizations (realm_id, group_name, action, target_realm_id, target_group_name) 
 		VALUES (?, ?, ?, ?, ?);`
 	deleteAuthzStmt             = `DELETE FROM authorizations WHERE realm_id = ? AND group_name = ?;`
 	deleteAllAuthzWithGroupStmt = `DELETE FROM authorizations WHERE (realm_id = ? AND group_name = ?) OR (target_realm_id = ? AND target_group_name = ?);`
+	renameAuthzGroupStmt        = `UPDATE authorizations SET group_name = ? WHERE realm_id = ? AND group_name = ?;`
+	renameAuthzTargetGroupStmt  = `UPDATE authorizations SET target_group_name = ? WHERE target_realm_id = ? AND target_group_name = ?;`
+	renameBOConfGroupStmt       = `UPDATE backoffice_configuration SET group_name = ? WHERE realm_id = ? AND group_name = ?;`
+	renameBOConfTargetGroupStmt = `UPDATE backoffice_configuration SET target_group_name = ? WHERE target_realm_id = ? AND target_group_name = ?;`
+	renameAuthzHistoryGroupStmt = `UPDATE authorizations_history SET group_name = ? WHERE realm_id = ? AND group_name = ?;`
+
+	// Authorization templates are stored in the configuration database:
+	//
+	//	CREATE TABLE authorization_templates (
+	//	  template_name VARCHAR(255) NOT NULL,
+	//	  matrix TEXT NOT NULL,
+	//	  PRIMARY KEY (template_name)
+	//	);
+	selectAuthzTemplatesStmt = `SELECT template_name, matrix FROM authorization_templates ORDER BY template_name;`
+	selectAuthzTemplateStmt  = `SELECT template_name, matrix FROM authorization_templates WHERE template_name = ?;`
+	updateAuthzTemplateStmt  = `INSERT INTO authorization_templates (template_name, matrix)
+	  VALUES (?, ?)
+	  ON DUPLICATE KEY UPDATE matrix = ?;`
+	deleteAuthzTemplateStmt = `DELETE FROM authorization_templates WHERE template_name = ?;`
+
+	// Each change of the authorizations of a group is recorded as a new version:
+	//
+	//	CREATE TABLE authorizations_history (
+	//	  realm_id VARCHAR(255) NOT NULL,
+	//	  group_name VARCHAR(255) NOT NULL,
+	//	  version INT NOT NULL,
+	//	  author VARCHAR(255) NOT NULL,
+	//	  created_at BIGINT NOT NULL,
+	//	  before_matrix TEXT NOT NULL,
+	//	  after_matrix TEXT NOT NULL,
+	//	  PRIMARY KEY (realm_id, group_name, version)
+	//	);
+	// The last version is locked until the end of the transaction so that concurrent changes get distinct versions
+	selectLastAuthzVersionStmt = `SELECT COALESCE(MAX(version), 0) FROM authorizations_history WHERE realm_id = ? AND group_name = ? FOR UPDATE;`
+	createAuthzVersionStmt     = `INSERT INTO authorizations_history (realm_id, group_name, version, author, created_at, before_matrix, after_matrix)
+	  VALUES (?, ?, ?, ?, ?, ?, ?);`
+	selectAuthzVersionsStmt = `SELECT realm_id, group_name, version, author, created_at, before_matrix, after_matrix FROM authorizations_history
+	  WHERE realm_id = ? AND group_name = ? ORDER BY version DESC;`
+	selectAuthzVersionStmt = `SELECT realm_id, group_name, version, author, created_at, before_matrix, after_matrix FROM authorizations_history
+	  WHERE realm_id = ? AND group_name = ? AND version = ?;`
+
+	// Each update of the custom or admin configuration of a realm is recorded as a new version:
+	//
+	//	CREATE TABLE realm_configuration_history (
+	//	  realm_id VARCHAR(255) NOT NULL,
+	//	  config_type VARCHAR(16) NOT NULL,
+	//	  version INT NOT NULL,
+	//	  author VARCHAR(255) NOT NULL,
+	//	  created_at BIGINT NOT NULL,
+	//	  configuration TEXT NOT NULL,
+	//	  PRIMARY KEY (realm_id, config_type, version)
+	//	);
+	// The last version is locked until the end of the transaction so that concurrent changes get distinct versions
+	selectLastRealmConfigVersionStmt = `SELECT COALESCE(MAX(version), 0) FROM realm_configuration_history WHERE realm_id = ? AND config_type = ? FOR UPDATE;`
+	createRealmConfigVersionStmt     = `INSERT INTO realm_configuration_history (realm_id, config_type, version, author, created_at, configuration)
+	  VALUES (?, ?, ?, ?, ?, ?);`
+	selectRealmConfigVersionsStmt = `SELECT realm_id, config_type, version, author, created_at, configuration FROM realm_configuration_history
+	  WHERE realm_id = ? AND config_type = ? ORDER BY version DESC;`
+	selectRealmConfigVersionStmt = `SELECT realm_id, config_type, version, author, created_at, configuration FROM realm_configuration_history
+	  WHERE realm_id = ? AND config_type = ? AND version = ?;`
+
+	// The requests waiting for the approval of a second operator are stored until they are approved, rejected or expired:
+	//
+	//	CREATE TABLE pending_requests (
+	//	  request_id VARCHAR(32) NOT NULL,
+	//	  realm_id VARCHAR(255) NOT NULL,
+	//	  action VARCHAR(255) NOT NULL,
+	//	  target_id VARCHAR(36) NOT NULL,
+	//	  parameters TEXT NOT NULL,
+	//	  requester_id VARCHAR(36) NOT NULL,
+	//	  requester VARCHAR(255) NOT NULL,
+	//	  created_at BIGINT NOT NULL,
+	//	  expires_at BIGINT NOT NULL,
+	//	  PRIMARY KEY (request_id),
+	//	  INDEX (realm_id, expires_at)
+	//	);
+	createPendingRequestStmt = `INSERT INTO pending_requests (request_id, realm_id, action, target_id, parameters, requester_id, requester, created_at, expires_at)
+	  VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?);`
+	selectPendingRequestsStmt = `SELECT request_id, realm_id, action, target_id, parameters, requester_id, requester, created_at, expires_at FROM pending_requests
+	  WHERE realm_id = ? AND expires_at > ? ORDER BY created_at;`
+	selectPendingRequestStmt = `SELECT request_id, realm_id, action, target_id, parameters, requester_id, requester, created_at, expires_at FROM pending_requests
+	  WHERE realm_id = ? AND request_id = ?;`
+	deletePendingRequestStmt = `DELETE FROM pending_requests WHERE realm_id = ? AND request_id = ?;`
+)
+
+// Types of the realm configurations which are versioned
+const (
+	RealmConfigurationTypeCustom = "custom"
+	RealmConfigurationTypeAdmin  = "admin"
 )
 
 // Scanner used to get data from SQL cursors
@@ -77,7 +187,8 @@ func (c *configurationDBModule) GetConfigurations(ctx context.Context, realmID s
 	return config, adminConfig, err
 }
 
-func (c *configurationDBModule) StoreOrUpdateConfiguration(context context.Context, realmID string, config configuration.RealmConfiguration) error {
+// StoreOrUpdateConfiguration stores the custom configuration of a realm in the given transaction
+func (c *configurationDBModule) StoreOrUpdateConfiguration(context context.Context, tx sqltypes.Transaction, realmID string, config configuration.RealmConfiguration) error {
 	// transform customConfig object into JSON string
 	configJSON, err := json.Marshal(config)
 	if err != nil {
@@ -85,7 +196,7 @@ func (c *configurationDBModule) StoreOrUpdateConfiguration(context context.Conte
 	}
 
 	// update value in DB
-	_, err = c.db.Exec(updateConfigStmt, realmID, string(configJSON), string(configJSON))
+	_, err = tx.Exec(updateConfigStmt, realmID, string(configJSON), string(configJSON))
 	return err
 }
 
@@ -101,11 +212,12 @@ func (c *configurationDBModule) GetConfiguration(ctx context.Context, realmID st
 	return config, err
 }
 
-func (c *configurationDBModule) StoreOrUpdateAdminConfiguration(context context.Context, realmID string, config configuration.RealmAdminConfiguration) error {
+// StoreOrUpdateAdminConfiguration stores the admin configuration of a realm in the given transaction
+func (c *configurationDBModule) StoreOrUpdateAdminConfiguration(context context.Context, tx sqltypes.Transaction, realmID string, config configuration.RealmAdminConfiguration) error {
 	var bytes, _ = json.Marshal(config)
 	var configJSON = string(bytes)
 	// update value in DB
-	var _, err = c.db.Exec(updateAdminConfigStmt, realmID, configJSON, configJSON)
+	var _, err = tx.Exec(updateAdminConfigStmt, realmID, configJSON, configJSON)
 	return err
 }
 
@@ -113,6 +225,78 @@ func (c *configurationDBModule) GetAdminConfiguration(ctx context.Context, realm
 	return c.ConfigurationReaderDBModule.GetAdminConfiguration(ctx, realmID)
 }
 
+// GetTrustIDGroups returns the trustID groups allowed in a realm or nil if the realm uses the default ones
+func (c *configurationDBModule) GetTrustIDGroups(ctx context.Context, realmID string) ([]string, error) {
+	var groupsJSON sql.NullString
+	var err = c.db.QueryRow(selectTrustIDGroupsStmt, realmID).Scan(&groupsJSON)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	} else if err != nil {
+		c.logger.Warn(ctx, "msg", "Can't get trustID groups", "error", err.Error(), "realmID", realmID)
+		return nil, err
+	}
+	if !groupsJSON.Valid {
+		return nil, nil
+	}
+
+	var groups = make([]string, 0)
+	if err = json.Unmarshal([]byte(groupsJSON.String), &groups); err != nil {
+		c.logger.Warn(ctx, "msg", "Can't unmarshal trustID groups", "error", err.Error(), "realmID", realmID)
+		return nil, err
+	}
+	return groups, nil
+}
+
+// StoreOrUpdateTrustIDGroups sets the trustID groups allowed in a realm in the given transaction. A nil value restores the default ones
+func (c *configurationDBModule) StoreOrUpdateTrustIDGroups(ctx context.Context, tx sqltypes.Transaction, realmID string, groups []string) error {
+	var groupsJSON sql.NullString
+	if groups != nil {
+		var bytes, _ = json.Marshal(groups)
+		groupsJSON = sql.NullString{String: string(bytes), Valid: true}
+	}
+	var _, err = tx.Exec(updateTrustIDGroupsStmt, realmID, groupsJSON, groupsJSON)
+	if err != nil {
+		c.logger.Warn(ctx, "msg", "Can't store trustID groups", "error", err.Error(), "realmID", realmID)
+	}
+	return err
+}
+
+// GetApprovalActions returns the actions which must be approved by a second operator in a realm
+func (c *configurationDBModule) GetApprovalActions(ctx context.Context, realmID string) ([]string, error) {
+	var actionsJSON sql.NullString
+	var err = c.db.QueryRow(selectApprovalActionsStmt, realmID).Scan(&actionsJSON)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	} else if err != nil {
+		c.logger.Warn(ctx, "msg", "Can't get approval actions", "error", err.Error(), "realmID", realmID)
+		return nil, err
+	}
+	if !actionsJSON.Valid {
+		return nil, nil
+	}
+
+	var actions = make([]string, 0)
+	if err = json.Unmarshal([]byte(actionsJSON.String), &actions); err != nil {
+		c.logger.Warn(ctx, "msg", "Can't unmarshal approval actions", "error", err.Error(), "realmID", realmID)
+		return nil, err
+	}
+	return actions, nil
+}
+
+// StoreOrUpdateApprovalActions sets the actions which must be approved by a second operator in a realm in the given transaction
+func (c *configurationDBModule) StoreOrUpdateApprovalActions(ctx context.Context, tx sqltypes.Transaction, realmID string, actions []string) error {
+	var actionsJSON sql.NullString
+	if len(actions) > 0 {
+		var bytes, _ = json.Marshal(actions)
+		actionsJSON = sql.NullString{String: string(bytes), Valid: true}
+	}
+	var _, err = tx.Exec(updateApprovalActionsStmt, realmID, actionsJSON, actionsJSON)
+	if err != nil {
+		c.logger.Warn(ctx, "msg", "Can't store approval actions", "error", err.Error(), "realmID", realmID)
+	}
+	return err
+}
+
 func (c *configurationDBModule) GetBackOfficeConfiguration(ctx context.Context, realmID string, groupNames []string) (dto.BackOfficeConfiguration, error) {
 	var sqlRequest = strings.Replace(selectBOConfigStmt, "???", "?"+strings.Repeat(",?", len(groupNames)-1), 1)
 	var args = []interface{}{realmID}
@@ -192,22 +376,340 @@ func (c *configurationDBModule) GetAuthorizations(ctx context.Context, realmID s
 	return res, nil
 }
 
-func (c *configurationDBModule) CreateAuthorization(context context.Context, auth configuration.Authorization) error {
-	_, err := c.db.Exec(createAuthzStmt, nullableString(auth.RealmID), nullableString(auth.GroupName),
+// GetRealmAuthorizations gets the authorizations of all the groups of a realm
+func (c *configurationDBModule) GetRealmAuthorizations(ctx context.Context, realmID string) ([]configuration.Authorization, error) {
+	rows, err := c.db.Query(selectRealmAuthzStmt, realmID)
+	if err != nil {
+		c.logger.Warn(ctx, "msg", "Can't get authorizations", "error", err.Error(), "realmID", realmID)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var res = make([]configuration.Authorization, 0)
+	for rows.Next() {
+		authz, err := c.scanAuthorization(rows)
+		if err != nil {
+			c.logger.Warn(ctx, "msg", "Can't get authorizations. Scan failed", "error", err.Error(), "realmID", realmID)
+			return nil, err
+		}
+		res = append(res, authz)
+	}
+
+	return res, nil
+}
+
+// GetAuthorizationsOnGroup gets the authorizations of all the groups which target a group
+func (c *configurationDBModule) GetAuthorizationsOnGroup(ctx context.Context, targetRealmID, targetGroupName string) ([]configuration.Authorization, error) {
+	rows, err := c.db.Query(selectTargetAuthzStmt, targetRealmID, targetGroupName)
+	if err != nil {
+		c.logger.Warn(ctx, "msg", "Can't get authorizations", "error", err.Error(), "targetRealmID", targetRealmID, "targetGroupName", targetGroupName)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var res = make([]configuration.Authorization, 0)
+	for rows.Next() {
+		authz, err := c.scanAuthorization(rows)
+		if err != nil {
+			c.logger.Warn(ctx, "msg", "Can't get authorizations. Scan failed", "error", err.Error(), "targetRealmID", targetRealmID, "targetGroupName", targetGroupName)
+			return nil, err
+		}
+		res = append(res, authz)
+	}
+
+	return res, nil
+}
+
+// CreateAuthorization creates an authorization in the given transaction
+func (c *configurationDBModule) CreateAuthorization(context context.Context, tx sqltypes.Transaction, auth configuration.Authorization) error {
+	_, err := tx.Exec(createAuthzStmt, nullableString(auth.RealmID), nullableString(auth.GroupName),
 		nullableString(auth.Action), nullableString(auth.TargetRealmID), nullableString(auth.TargetGroupName))
 	return err
 }
 
-func (c *configurationDBModule) DeleteAuthorizations(context context.Context, realmID string, groupName string) error {
-	_, err := c.db.Exec(deleteAuthzStmt, realmID, groupName)
+// DeleteAuthorizations deletes the authorizations of a group in the given transaction
+func (c *configurationDBModule) DeleteAuthorizations(context context.Context, tx sqltypes.Transaction, realmID string, groupName string) error {
+	_, err := tx.Exec(deleteAuthzStmt, realmID, groupName)
+	return err
+}
+
+// DeleteAllAuthorizationsWithGroup deletes the authorizations of a group and the authorizations targeting it in the given transaction
+func (c *configurationDBModule) DeleteAllAuthorizationsWithGroup(context context.Context, tx sqltypes.Transaction, realmID, groupName string) error {
+	_, err := tx.Exec(deleteAllAuthzWithGroupStmt, realmID, groupName, realmID, groupName)
+	return err
+}
+
+// RenameGroup replaces the name of a group in the authorizations, in their versions and in the back-office configuration,
+// as owner or as target. The updates are done in the given transaction
+func (c *configurationDBModule) RenameGroup(ctx context.Context, tx sqltypes.Transaction, realmID, oldGroupName, newGroupName string) error {
+	for _, stmt := range []string{renameAuthzGroupStmt, renameAuthzTargetGroupStmt, renameBOConfGroupStmt, renameBOConfTargetGroupStmt, renameAuthzHistoryGroupStmt} {
+		if _, err := tx.Exec(stmt, newGroupName, realmID, oldGroupName); err != nil {
+			c.logger.Warn(ctx, "msg", "Can't rename group", "error", err.Error(), "realmID", realmID, "groupName", oldGroupName, "newGroupName", newGroupName)
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *configurationDBModule) GetAuthorizationTemplates(ctx context.Context) ([]dto.AuthorizationTemplate, error) {
+	rows, err := c.db.Query(selectAuthzTemplatesStmt)
+	if err != nil {
+		c.logger.Warn(ctx, "msg", "Can't get authorization templates", "error", err.Error())
+		return nil, err
+	}
+	defer rows.Close()
+
+	var res = make([]dto.AuthorizationTemplate, 0)
+	for rows.Next() {
+		template, err := c.scanAuthorizationTemplate(rows)
+		if err != nil {
+			c.logger.Warn(ctx, "msg", "Can't get authorization templates. Scan failed", "error", err.Error())
+			return nil, err
+		}
+		res = append(res, template)
+	}
+
+	return res, nil
+}
+
+func (c *configurationDBModule) GetAuthorizationTemplate(ctx context.Context, templateName string) (dto.AuthorizationTemplate, error) {
+	template, err := c.scanAuthorizationTemplate(c.db.QueryRow(selectAuthzTemplateStmt, templateName))
+	if err == sql.ErrNoRows {
+		return dto.AuthorizationTemplate{}, errorhandler.CreateNotFoundError(msg.AuthorizationTemplate)
+	} else if err != nil {
+		c.logger.Warn(ctx, "msg", "Can't get authorization template", "error", err.Error(), "template", templateName)
+		return dto.AuthorizationTemplate{}, err
+	}
+	return template, nil
+}
+
+func (c *configurationDBModule) StoreOrUpdateAuthorizationTemplate(ctx context.Context, template dto.AuthorizationTemplate) error {
+	matrixJSON, _ := json.Marshal(template.Matrix)
+	_, err := c.db.Exec(updateAuthzTemplateStmt, template.Name, string(matrixJSON), string(matrixJSON))
+	if err != nil {
+		c.logger.Warn(ctx, "msg", "Can't store authorization template", "error", err.Error(), "template", template.Name)
+	}
+	return err
+}
+
+func (c *configurationDBModule) DeleteAuthorizationTemplate(ctx context.Context, templateName string) error {
+	_, err := c.db.Exec(deleteAuthzTemplateStmt, templateName)
+	if err != nil {
+		c.logger.Warn(ctx, "msg", "Can't delete authorization template", "error", err.Error(), "template", templateName)
+	}
 	return err
 }
 
-func (c *configurationDBModule) DeleteAllAuthorizationsWithGroup(context context.Context, realmID, groupName string) error {
-	_, err := c.db.Exec(deleteAllAuthzWithGroupStmt, realmID, groupName, realmID, groupName)
+func (c *configurationDBModule) scanAuthorizationTemplate(scanner Scanner) (dto.AuthorizationTemplate, error) {
+	var (
+		templateName string
+		matrixJSON   string
+	)
+
+	err := scanner.Scan(&templateName, &matrixJSON)
+	if err != nil {
+		return dto.AuthorizationTemplate{}, err
+	}
+
+	var template = dto.AuthorizationTemplate{Name: templateName}
+	if err = json.Unmarshal([]byte(matrixJSON), &template.Matrix); err != nil {
+		return dto.AuthorizationTemplate{}, err
+	}
+	return template, nil
+}
+
+// CreateAuthorizationsVersion records a new version of the authorizations of a group in the given transaction. The version
+// number follows the last one, which stays locked until the transaction ends
+func (c *configurationDBModule) CreateAuthorizationsVersion(ctx context.Context, tx sqltypes.Transaction, version dto.AuthorizationsVersion) error {
+	var lastVersion int
+	if err := tx.QueryRow(selectLastAuthzVersionStmt, version.RealmID, version.GroupName).Scan(&lastVersion); err != nil {
+		c.logger.Warn(ctx, "msg", "Can't get last authorizations version", "error", err.Error(), "realmID", version.RealmID, "groupName", version.GroupName)
+		return err
+	}
+
+	beforeJSON, _ := json.Marshal(version.Before)
+	afterJSON, _ := json.Marshal(version.After)
+	_, err := tx.Exec(createAuthzVersionStmt, version.RealmID, version.GroupName, lastVersion+1, version.Author, version.CreatedAt,
+		string(beforeJSON), string(afterJSON))
+	if err != nil {
+		c.logger.Warn(ctx, "msg", "Can't record authorizations version", "error", err.Error(), "realmID", version.RealmID, "groupName", version.GroupName)
+	}
 	return err
 }
 
+// GetAuthorizationsVersions gets the versions of the authorizations of a group, the most recent first
+func (c *configurationDBModule) GetAuthorizationsVersions(ctx context.Context, realmID string, groupName string) ([]dto.AuthorizationsVersion, error) {
+	rows, err := c.db.Query(selectAuthzVersionsStmt, realmID, groupName)
+	if err != nil {
+		c.logger.Warn(ctx, "msg", "Can't get authorizations versions", "error", err.Error(), "realmID", realmID, "groupName", groupName)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var res = make([]dto.AuthorizationsVersion, 0)
+	for rows.Next() {
+		version, err := c.scanAuthorizationsVersion(rows)
+		if err != nil {
+			c.logger.Warn(ctx, "msg", "Can't get authorizations versions. Scan failed", "error", err.Error(), "realmID", realmID, "groupName", groupName)
+			return nil, err
+		}
+		res = append(res, version)
+	}
+
+	return res, nil
+}
+
+func (c *configurationDBModule) GetAuthorizationsVersion(ctx context.Context, realmID string, groupName string, version int) (dto.AuthorizationsVersion, error) {
+	res, err := c.scanAuthorizationsVersion(c.db.QueryRow(selectAuthzVersionStmt, realmID, groupName, version))
+	if err == sql.ErrNoRows {
+		return dto.AuthorizationsVersion{}, errorhandler.CreateNotFoundError(msg.AuthorizationsVersion)
+	} else if err != nil {
+		c.logger.Warn(ctx, "msg", "Can't get authorizations version", "error", err.Error(), "realmID", realmID, "groupName", groupName, "version", version)
+		return dto.AuthorizationsVersion{}, err
+	}
+	return res, nil
+}
+
+func (c *configurationDBModule) scanAuthorizationsVersion(scanner Scanner) (dto.AuthorizationsVersion, error) {
+	var (
+		version    dto.AuthorizationsVersion
+		beforeJSON string
+		afterJSON  string
+	)
+
+	err := scanner.Scan(&version.RealmID, &version.GroupName, &version.Version, &version.Author, &version.CreatedAt, &beforeJSON, &afterJSON)
+	if err != nil {
+		return dto.AuthorizationsVersion{}, err
+	}
+
+	if err = json.Unmarshal([]byte(beforeJSON), &version.Before); err != nil {
+		return dto.AuthorizationsVersion{}, err
+	}
+	if err = json.Unmarshal([]byte(afterJSON), &version.After); err != nil {
+		return dto.AuthorizationsVersion{}, err
+	}
+	return version, nil
+}
+
+// CreateRealmConfigurationVersion records a new version of a configuration of a realm in the given transaction. The version
+// number follows the last one, which stays locked until the transaction ends
+func (c *configurationDBModule) CreateRealmConfigurationVersion(ctx context.Context, tx sqltypes.Transaction, version dto.RealmConfigurationVersion) error {
+	var lastVersion int
+	if err := tx.QueryRow(selectLastRealmConfigVersionStmt, version.RealmID, version.Type).Scan(&lastVersion); err != nil {
+		c.logger.Warn(ctx, "msg", "Can't get last realm configuration version", "error", err.Error(), "realmID", version.RealmID, "type", version.Type)
+		return err
+	}
+
+	_, err := tx.Exec(createRealmConfigVersionStmt, version.RealmID, version.Type, lastVersion+1, version.Author, version.CreatedAt, version.Configuration)
+	if err != nil {
+		c.logger.Warn(ctx, "msg", "Can't record realm configuration version", "error", err.Error(), "realmID", version.RealmID, "type", version.Type)
+	}
+	return err
+}
+
+// GetRealmConfigurationVersions gets the versions of a configuration of a realm, the most recent first
+func (c *configurationDBModule) GetRealmConfigurationVersions(ctx context.Context, realmID string, configType string) ([]dto.RealmConfigurationVersion, error) {
+	rows, err := c.db.Query(selectRealmConfigVersionsStmt, realmID, configType)
+	if err != nil {
+		c.logger.Warn(ctx, "msg", "Can't get realm configuration versions", "error", err.Error(), "realmID", realmID, "type", configType)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var res = make([]dto.RealmConfigurationVersion, 0)
+	for rows.Next() {
+		var version dto.RealmConfigurationVersion
+		err = rows.Scan(&version.RealmID, &version.Type, &version.Version, &version.Author, &version.CreatedAt, &version.Configuration)
+		if err != nil {
+			c.logger.Warn(ctx, "msg", "Can't get realm configuration versions. Scan failed", "error", err.Error(), "realmID", realmID, "type", configType)
+			return nil, err
+		}
+		res = append(res, version)
+	}
+
+	return res, nil
+}
+
+// GetRealmConfigurationVersion gets a version of a configuration of a realm
+func (c *configurationDBModule) GetRealmConfigurationVersion(ctx context.Context, realmID string, configType string, version int) (dto.RealmConfigurationVersion, error) {
+	var res dto.RealmConfigurationVersion
+	err := c.db.QueryRow(selectRealmConfigVersionStmt, realmID, configType, version).Scan(&res.RealmID, &res.Type, &res.Version, &res.Author,
+		&res.CreatedAt, &res.Configuration)
+	if err == sql.ErrNoRows {
+		return dto.RealmConfigurationVersion{}, errorhandler.CreateNotFoundError(msg.RealmConfigurationVersion)
+	} else if err != nil {
+		c.logger.Warn(ctx, "msg", "Can't get realm configuration version", "error", err.Error(), "realmID", realmID, "type", configType, "version", version)
+		return dto.RealmConfigurationVersion{}, err
+	}
+	return res, nil
+}
+
+// CreatePendingRequest stores a request waiting for the approval of a second operator
+func (c *configurationDBModule) CreatePendingRequest(ctx context.Context, request dto.PendingRequest) error {
+	_, err := c.db.Exec(createPendingRequestStmt, request.ID, request.RealmName, request.Action, request.TargetID, request.Parameters,
+		request.RequesterID, request.Requester, request.CreatedAt, request.ExpiresAt)
+	if err != nil {
+		c.logger.Warn(ctx, "msg", "Can't store pending request", "error", err.Error(), "realmID", request.RealmName, "action", request.Action)
+	}
+	return err
+}
+
+// GetPendingRequests gets the requests of a realm which are not expired at the given time, the oldest first
+func (c *configurationDBModule) GetPendingRequests(ctx context.Context, realmName string, now time.Time) ([]dto.PendingRequest, error) {
+	rows, err := c.db.Query(selectPendingRequestsStmt, realmName, now.Unix())
+	if err != nil {
+		c.logger.Warn(ctx, "msg", "Can't get pending requests", "error", err.Error(), "realmID", realmName)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var res = make([]dto.PendingRequest, 0)
+	for rows.Next() {
+		var request dto.PendingRequest
+		if err = c.scanPendingRequest(rows, &request); err != nil {
+			c.logger.Warn(ctx, "msg", "Can't get pending requests. Scan failed", "error", err.Error(), "realmID", realmName)
+			return nil, err
+		}
+		res = append(res, request)
+	}
+
+	return res, nil
+}
+
+// GetPendingRequest gets a request, whether it is expired or not
+func (c *configurationDBModule) GetPendingRequest(ctx context.Context, realmName string, requestID string) (dto.PendingRequest, error) {
+	var request dto.PendingRequest
+	var err = c.scanPendingRequest(c.db.QueryRow(selectPendingRequestStmt, realmName, requestID), &request)
+	if err == sql.ErrNoRows {
+		return dto.PendingRequest{}, errorhandler.CreateNotFoundError(msg.PendingRequest)
+	} else if err != nil {
+		c.logger.Warn(ctx, "msg", "Can't get pending request", "error", err.Error(), "realmID", realmName, "requestID", requestID)
+		return dto.PendingRequest{}, err
+	}
+	return request, nil
+}
+
+// DeletePendingRequest deletes a request and tells whether it still existed. As only one caller can delete a given
+// request, this is used to ensure an approved request is executed only once
+func (c *configurationDBModule) DeletePendingRequest(ctx context.Context, realmName string, requestID string) (bool, error) {
+	res, err := c.db.Exec(deletePendingRequestStmt, realmName, requestID)
+	if err != nil {
+		c.logger.Warn(ctx, "msg", "Can't delete pending request", "error", err.Error(), "realmID", realmName, "requestID", requestID)
+		return false, err
+	}
+	count, err := res.RowsAffected()
+	if err != nil {
+		c.logger.Warn(ctx, "msg", "Can't delete pending request", "error", err.Error(), "realmID", realmName, "requestID", requestID)
+		return false, err
+	}
+	return count > 0, nil
+}
+
+func (c *configurationDBModule) scanPendingRequest(scanner Scanner, request *dto.PendingRequest) error {
+	return scanner.Scan(&request.ID, &request.RealmName, &request.Action, &request.TargetID, &request.Parameters,
+		&request.RequesterID, &request.Requester, &request.CreatedAt, &request.ExpiresAt)
+}
+
 func (c *configurationDBModule) NewTransaction(context context.Context) (sqltypes.Transaction, error) {
 	return c.db.BeginTx(context, nil)
 }
@@ -3,15 +3,19 @@ package keycloakb
 import (
 	"context"
 	"database/sql"
+	"database/sql/driver"
 	"encoding/json"
 	"errors"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/cloudtrust/common-service/configuration"
+	errorhandler "github.com/cloudtrust/common-service/errors"
 	"github.com/cloudtrust/common-service/log"
 
 	msg "github.com/cloudtrust/keycloak-bridge/internal/constants"
+	"github.com/cloudtrust/keycloak-bridge/internal/dto"
 	"github.com/cloudtrust/keycloak-bridge/internal/keycloakb/mock"
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
@@ -21,11 +25,12 @@ func TestConfigurationDBModule(t *testing.T) {
 	var mockCtrl = gomock.NewController(t)
 	defer mockCtrl.Finish()
 	var mockDB = mock.NewCloudtrustDB(mockCtrl)
+	var mockTx = mock.NewTransaction(mockCtrl)
 	var mockLogger = log.NewNopLogger()
 
-	mockDB.EXPECT().Exec(gomock.Any(), "realmId", gomock.Any(), gomock.Any()).Return(nil, nil).Times(1)
+	mockTx.EXPECT().Exec(gomock.Any(), "realmId", gomock.Any(), gomock.Any()).Return(nil, nil).Times(1)
 	var configDBModule = NewConfigurationDBModule(mockDB, mockLogger)
-	var err = configDBModule.StoreOrUpdateConfiguration(context.Background(), "realmId", configuration.RealmConfiguration{})
+	var err = configDBModule.StoreOrUpdateConfiguration(context.Background(), mockTx, "realmId", configuration.RealmConfiguration{})
 	assert.Nil(t, err)
 }
 
@@ -127,6 +132,7 @@ func TestStoreOrGetAdminConfiguration(t *testing.T) {
 
 	var mockDB = mock.NewCloudtrustDB(mockCtrl)
 	var mockSQLRow = mock.NewSQLRow(mockCtrl)
+	var mockTx = mock.NewTransaction(mockCtrl)
 	var mockLogger = log.NewNopLogger()
 
 	var configDBModule = NewConfigurationDBModule(mockDB, mockLogger)
@@ -137,12 +143,12 @@ func TestStoreOrGetAdminConfiguration(t *testing.T) {
 	var ctx = context.TODO()
 
 	t.Run("Store-SQL fails", func(t *testing.T) {
-		mockDB.EXPECT().Exec(gomock.Any(), gomock.Any()).Return(nil, sqlError)
-		assert.Equal(t, sqlError, configDBModule.StoreOrUpdateAdminConfiguration(ctx, realmID, adminConfig))
+		mockTx.EXPECT().Exec(gomock.Any(), gomock.Any()).Return(nil, sqlError)
+		assert.Equal(t, sqlError, configDBModule.StoreOrUpdateAdminConfiguration(ctx, mockTx, realmID, adminConfig))
 	})
 	t.Run("Store-success", func(t *testing.T) {
-		mockDB.EXPECT().Exec(gomock.Any(), gomock.Any()).Return(nil, nil)
-		assert.Nil(t, configDBModule.StoreOrUpdateAdminConfiguration(ctx, realmID, adminConfig))
+		mockTx.EXPECT().Exec(gomock.Any(), gomock.Any()).Return(nil, nil)
+		assert.Nil(t, configDBModule.StoreOrUpdateAdminConfiguration(ctx, mockTx, realmID, adminConfig))
 	})
 	t.Run("Get-SQL query fails", func(t *testing.T) {
 		mockDB.EXPECT().QueryRow(gomock.Any(), realmID).Return(mockSQLRow)
@@ -170,6 +176,141 @@ func TestStoreOrGetAdminConfiguration(t *testing.T) {
 	})
 }
 
+func TestTrustIDGroups(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	var mockDB = mock.NewCloudtrustDB(mockCtrl)
+	var mockSQLRow = mock.NewSQLRow(mockCtrl)
+	var mockTx = mock.NewTransaction(mockCtrl)
+	var mockLogger = log.NewNopLogger()
+
+	var configDBModule = NewConfigurationDBModule(mockDB, mockLogger)
+	var expectedError = errors.New("error")
+	var realmID = "realm-id"
+	var ctx = context.TODO()
+
+	var expectGroups = func(value sql.NullString, err error) {
+		mockDB.EXPECT().QueryRow(selectTrustIDGroupsStmt, realmID).Return(mockSQLRow)
+		mockSQLRow.EXPECT().Scan(gomock.Any()).DoAndReturn(func(groups *sql.NullString) error {
+			*groups = value
+			return err
+		})
+	}
+
+	t.Run("GET-SQL query fails", func(t *testing.T) {
+		expectGroups(sql.NullString{}, expectedError)
+		var _, err = configDBModule.GetTrustIDGroups(ctx, realmID)
+		assert.Equal(t, expectedError, err)
+	})
+	t.Run("GET-Realm not configured", func(t *testing.T) {
+		expectGroups(sql.NullString{}, sql.ErrNoRows)
+		var groups, err = configDBModule.GetTrustIDGroups(ctx, realmID)
+		assert.Nil(t, err)
+		assert.Nil(t, groups)
+	})
+	t.Run("GET-Default groups", func(t *testing.T) {
+		expectGroups(sql.NullString{}, nil)
+		var groups, err = configDBModule.GetTrustIDGroups(ctx, realmID)
+		assert.Nil(t, err)
+		assert.Nil(t, groups)
+	})
+	t.Run("GET-Invalid JSON", func(t *testing.T) {
+		expectGroups(sql.NullString{String: "[", Valid: true}, nil)
+		var _, err = configDBModule.GetTrustIDGroups(ctx, realmID)
+		assert.NotNil(t, err)
+	})
+	t.Run("GET-Success", func(t *testing.T) {
+		expectGroups(sql.NullString{String: `["grp1","grp2"]`, Valid: true}, nil)
+		var groups, err = configDBModule.GetTrustIDGroups(ctx, realmID)
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"grp1", "grp2"}, groups)
+	})
+	t.Run("GET-No group allowed", func(t *testing.T) {
+		expectGroups(sql.NullString{String: `[]`, Valid: true}, nil)
+		var groups, err = configDBModule.GetTrustIDGroups(ctx, realmID)
+		assert.Nil(t, err)
+		assert.NotNil(t, groups)
+		assert.Len(t, groups, 0)
+	})
+
+	t.Run("STORE-Fails", func(t *testing.T) {
+		var value = sql.NullString{String: `["grp1"]`, Valid: true}
+		mockTx.EXPECT().Exec(updateTrustIDGroupsStmt, realmID, value, value).Return(nil, expectedError)
+		var err = configDBModule.StoreOrUpdateTrustIDGroups(ctx, mockTx, realmID, []string{"grp1"})
+		assert.Equal(t, expectedError, err)
+	})
+	t.Run("STORE-Restore default groups", func(t *testing.T) {
+		mockTx.EXPECT().Exec(updateTrustIDGroupsStmt, realmID, sql.NullString{}, sql.NullString{}).Return(nil, nil)
+		var err = configDBModule.StoreOrUpdateTrustIDGroups(ctx, mockTx, realmID, nil)
+		assert.Nil(t, err)
+	})
+}
+
+func TestApprovalActions(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	var mockDB = mock.NewCloudtrustDB(mockCtrl)
+	var mockSQLRow = mock.NewSQLRow(mockCtrl)
+	var mockTx = mock.NewTransaction(mockCtrl)
+	var mockLogger = log.NewNopLogger()
+
+	var configDBModule = NewConfigurationDBModule(mockDB, mockLogger)
+	var expectedError = errors.New("error")
+	var realmID = "realm-id"
+	var ctx = context.TODO()
+
+	var expectActions = func(value sql.NullString, err error) {
+		mockDB.EXPECT().QueryRow(selectApprovalActionsStmt, realmID).Return(mockSQLRow)
+		mockSQLRow.EXPECT().Scan(gomock.Any()).DoAndReturn(func(actions *sql.NullString) error {
+			*actions = value
+			return err
+		})
+	}
+
+	t.Run("GET-SQL query fails", func(t *testing.T) {
+		expectActions(sql.NullString{}, expectedError)
+		var _, err = configDBModule.GetApprovalActions(ctx, realmID)
+		assert.Equal(t, expectedError, err)
+	})
+	t.Run("GET-Realm not configured", func(t *testing.T) {
+		expectActions(sql.NullString{}, sql.ErrNoRows)
+		var actions, err = configDBModule.GetApprovalActions(ctx, realmID)
+		assert.Nil(t, err)
+		assert.Nil(t, actions)
+	})
+	t.Run("GET-No approval", func(t *testing.T) {
+		expectActions(sql.NullString{}, nil)
+		var actions, err = configDBModule.GetApprovalActions(ctx, realmID)
+		assert.Nil(t, err)
+		assert.Nil(t, actions)
+	})
+	t.Run("GET-Invalid JSON", func(t *testing.T) {
+		expectActions(sql.NullString{String: "[", Valid: true}, nil)
+		var _, err = configDBModule.GetApprovalActions(ctx, realmID)
+		assert.NotNil(t, err)
+	})
+	t.Run("GET-Success", func(t *testing.T) {
+		expectActions(sql.NullString{String: `["MGMT_DeleteUser"]`, Valid: true}, nil)
+		var actions, err = configDBModule.GetApprovalActions(ctx, realmID)
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"MGMT_DeleteUser"}, actions)
+	})
+
+	t.Run("STORE-Fails", func(t *testing.T) {
+		var value = sql.NullString{String: `["MGMT_DeleteUser"]`, Valid: true}
+		mockTx.EXPECT().Exec(updateApprovalActionsStmt, realmID, value, value).Return(nil, expectedError)
+		var err = configDBModule.StoreOrUpdateApprovalActions(ctx, mockTx, realmID, []string{"MGMT_DeleteUser"})
+		assert.Equal(t, expectedError, err)
+	})
+	t.Run("STORE-No approval", func(t *testing.T) {
+		mockTx.EXPECT().Exec(updateApprovalActionsStmt, realmID, sql.NullString{}, sql.NullString{}).Return(nil, nil)
+		var err = configDBModule.StoreOrUpdateApprovalActions(ctx, mockTx, realmID, []string{})
+		assert.Nil(t, err)
+	})
+}
+
 func TestBackOfficeConfiguration(t *testing.T) {
 	var mockCtrl = gomock.NewController(t)
 	defer mockCtrl.Finish()
@@ -253,3 +394,574 @@ func TestBackOfficeConfiguration(t *testing.T) {
 		assert.Nil(t, err)
 	})
 }
+
+func TestRenameGroup(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	var mockDB = mock.NewCloudtrustDB(mockCtrl)
+	var mockTx = mock.NewTransaction(mockCtrl)
+	var mockLogger = log.NewNopLogger()
+
+	var configDBModule = NewConfigurationDBModule(mockDB, mockLogger)
+	var expectedError = errors.New("error")
+	var realmID = "my-realm"
+	var oldName = "old-group"
+	var newName = "new-group"
+	var ctx = context.TODO()
+
+	t.Run("Update fails", func(t *testing.T) {
+		mockTx.EXPECT().Exec(renameAuthzGroupStmt, newName, realmID, oldName).Return(nil, nil)
+		mockTx.EXPECT().Exec(renameAuthzTargetGroupStmt, newName, realmID, oldName).Return(nil, expectedError)
+		var err = configDBModule.RenameGroup(ctx, mockTx, realmID, oldName, newName)
+		assert.Equal(t, expectedError, err)
+	})
+	t.Run("Success", func(t *testing.T) {
+		gomock.InOrder(
+			mockTx.EXPECT().Exec(renameAuthzGroupStmt, newName, realmID, oldName).Return(nil, nil),
+			mockTx.EXPECT().Exec(renameAuthzTargetGroupStmt, newName, realmID, oldName).Return(nil, nil),
+			mockTx.EXPECT().Exec(renameBOConfGroupStmt, newName, realmID, oldName).Return(nil, nil),
+			mockTx.EXPECT().Exec(renameBOConfTargetGroupStmt, newName, realmID, oldName).Return(nil, nil),
+			mockTx.EXPECT().Exec(renameAuthzHistoryGroupStmt, newName, realmID, oldName).Return(nil, nil),
+		)
+		var err = configDBModule.RenameGroup(ctx, mockTx, realmID, oldName, newName)
+		assert.Nil(t, err)
+	})
+}
+
+func TestAuthorizationsInTransaction(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	var mockDB = mock.NewCloudtrustDB(mockCtrl)
+	var mockTx = mock.NewTransaction(mockCtrl)
+
+	var configDBModule = NewConfigurationDBModule(mockDB, log.NewNopLogger())
+	var expectedError = errors.New("error")
+	var realmID = "my-realm"
+	var groupName = "my-group"
+	var action = "MGMT_GetUsers"
+	var ctx = context.TODO()
+
+	t.Run("Create authorization", func(t *testing.T) {
+		var authz = configuration.Authorization{RealmID: &realmID, GroupName: &groupName, Action: &action}
+		mockTx.EXPECT().Exec(createAuthzStmt, &realmID, &groupName, &action, nil, nil).Return(nil, expectedError)
+		assert.Equal(t, expectedError, configDBModule.CreateAuthorization(ctx, mockTx, authz))
+	})
+	t.Run("Delete authorizations", func(t *testing.T) {
+		mockTx.EXPECT().Exec(deleteAuthzStmt, realmID, groupName).Return(nil, nil)
+		assert.Nil(t, configDBModule.DeleteAuthorizations(ctx, mockTx, realmID, groupName))
+	})
+	t.Run("Delete all authorizations with group", func(t *testing.T) {
+		mockTx.EXPECT().Exec(deleteAllAuthzWithGroupStmt, realmID, groupName, realmID, groupName).Return(nil, nil)
+		assert.Nil(t, configDBModule.DeleteAllAuthorizationsWithGroup(ctx, mockTx, realmID, groupName))
+	})
+}
+
+func TestGetAuthorizationsOnGroup(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	var mockDB = mock.NewCloudtrustDB(mockCtrl)
+	var mockSQLRows = mock.NewSQLRows(mockCtrl)
+
+	var configDBModule = NewConfigurationDBModule(mockDB, log.NewNopLogger())
+	var expectedError = errors.New("error")
+	var realmID = "my-realm"
+	var groupName = "my-group"
+	var ctx = context.TODO()
+
+	t.Run("SQL query fails", func(t *testing.T) {
+		mockDB.EXPECT().Query(selectTargetAuthzStmt, realmID, groupName).Return(nil, expectedError)
+		var _, err = configDBModule.GetAuthorizationsOnGroup(ctx, realmID, groupName)
+		assert.Equal(t, expectedError, err)
+	})
+	t.Run("Success", func(t *testing.T) {
+		mockDB.EXPECT().Query(selectTargetAuthzStmt, realmID, groupName).Return(mockSQLRows, nil)
+		gomock.InOrder(
+			mockSQLRows.EXPECT().Next().Return(true),
+			mockSQLRows.EXPECT().Scan(gomock.Any()).DoAndReturn(func(realm, group, action *string, targetRealm, targetGroup *sql.NullString) error {
+				*realm = "master"
+				*group = "operators"
+				*action = "MGMT_GetUsers"
+				*targetRealm = sql.NullString{String: realmID, Valid: true}
+				*targetGroup = sql.NullString{String: groupName, Valid: true}
+				return nil
+			}),
+			mockSQLRows.EXPECT().Next().Return(false),
+		)
+		mockSQLRows.EXPECT().Close()
+		var res, err = configDBModule.GetAuthorizationsOnGroup(ctx, realmID, groupName)
+		assert.Nil(t, err)
+		assert.Len(t, res, 1)
+		assert.Equal(t, "operators", *res[0].GroupName)
+	})
+}
+
+func TestGetRealmAuthorizations(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	var mockDB = mock.NewCloudtrustDB(mockCtrl)
+	var mockSQLRows = mock.NewSQLRows(mockCtrl)
+	var mockLogger = log.NewNopLogger()
+
+	var configDBModule = NewConfigurationDBModule(mockDB, mockLogger)
+	var expectedError = errors.New("error")
+	var realmID = "my-realm"
+	var ctx = context.TODO()
+
+	t.Run("SQL query fails", func(t *testing.T) {
+		mockDB.EXPECT().Query(selectRealmAuthzStmt, realmID).Return(nil, expectedError)
+		var _, err = configDBModule.GetRealmAuthorizations(ctx, realmID)
+		assert.Equal(t, expectedError, err)
+	})
+	t.Run("Scan fails", func(t *testing.T) {
+		mockDB.EXPECT().Query(selectRealmAuthzStmt, realmID).Return(mockSQLRows, nil)
+		mockSQLRows.EXPECT().Next().Return(true)
+		mockSQLRows.EXPECT().Scan(gomock.Any()).Return(expectedError)
+		mockSQLRows.EXPECT().Close()
+		var _, err = configDBModule.GetRealmAuthorizations(ctx, realmID)
+		assert.Equal(t, expectedError, err)
+	})
+	t.Run("Success", func(t *testing.T) {
+		gomock.InOrder(
+			mockDB.EXPECT().Query(selectRealmAuthzStmt, realmID).Return(mockSQLRows, nil),
+			mockSQLRows.EXPECT().Next().Return(true),
+			mockSQLRows.EXPECT().Scan(gomock.Any()).DoAndReturn(func(realm, group, action *string, targetRealm, targetGroup *sql.NullString) error {
+				*realm = realmID
+				*group = "group1"
+				*action = "MGMT_GetUsers"
+				*targetRealm = sql.NullString{String: "*", Valid: true}
+				return nil
+			}),
+			mockSQLRows.EXPECT().Next().Return(false),
+			mockSQLRows.EXPECT().Close(),
+		)
+		var authz, err = configDBModule.GetRealmAuthorizations(ctx, realmID)
+		assert.Nil(t, err)
+		assert.Len(t, authz, 1)
+		assert.Equal(t, "group1", *authz[0].GroupName)
+		assert.Equal(t, "*", *authz[0].TargetRealmID)
+		assert.Nil(t, authz[0].TargetGroupName)
+	})
+}
+
+func TestAuthorizationTemplates(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	var mockDB = mock.NewCloudtrustDB(mockCtrl)
+	var mockSQLRows = mock.NewSQLRows(mockCtrl)
+	var mockSQLRow = mock.NewSQLRow(mockCtrl)
+	var mockLogger = log.NewNopLogger()
+
+	var configDBModule = NewConfigurationDBModule(mockDB, mockLogger)
+	var expectedError = errors.New("error")
+	var templateName = "support"
+	var matrixJSON = `{"MGMT_GetUsers":{"{realm}":{"*":{}}}}`
+	var template = dto.AuthorizationTemplate{
+		Name:   templateName,
+		Matrix: map[string]map[string]map[string]struct{}{"MGMT_GetUsers": {"{realm}": {"*": {}}}},
+	}
+	var ctx = context.TODO()
+
+	t.Run("GET ALL-SQL query fails", func(t *testing.T) {
+		mockDB.EXPECT().Query(selectAuthzTemplatesStmt).Return(nil, expectedError)
+		var _, err = configDBModule.GetAuthorizationTemplates(ctx)
+		assert.Equal(t, expectedError, err)
+	})
+	t.Run("GET ALL-Invalid JSON", func(t *testing.T) {
+		mockDB.EXPECT().Query(selectAuthzTemplatesStmt).Return(mockSQLRows, nil)
+		mockSQLRows.EXPECT().Next().Return(true)
+		mockSQLRows.EXPECT().Scan(gomock.Any()).DoAndReturn(func(name, matrix *string) error {
+			*name = templateName
+			*matrix = "{"
+			return nil
+		})
+		mockSQLRows.EXPECT().Close()
+		var _, err = configDBModule.GetAuthorizationTemplates(ctx)
+		assert.NotNil(t, err)
+	})
+	t.Run("GET ALL-Success", func(t *testing.T) {
+		gomock.InOrder(
+			mockDB.EXPECT().Query(selectAuthzTemplatesStmt).Return(mockSQLRows, nil),
+			mockSQLRows.EXPECT().Next().Return(true),
+			mockSQLRows.EXPECT().Scan(gomock.Any()).DoAndReturn(func(name, matrix *string) error {
+				*name = templateName
+				*matrix = matrixJSON
+				return nil
+			}),
+			mockSQLRows.EXPECT().Next().Return(false),
+			mockSQLRows.EXPECT().Close(),
+		)
+		var templates, err = configDBModule.GetAuthorizationTemplates(ctx)
+		assert.Nil(t, err)
+		assert.Equal(t, []dto.AuthorizationTemplate{template}, templates)
+	})
+
+	t.Run("GET-Not found", func(t *testing.T) {
+		mockDB.EXPECT().QueryRow(selectAuthzTemplateStmt, templateName).Return(mockSQLRow)
+		mockSQLRow.EXPECT().Scan(gomock.Any()).Return(sql.ErrNoRows)
+		var _, err = configDBModule.GetAuthorizationTemplate(ctx, templateName)
+		assert.NotNil(t, err)
+		assert.IsType(t, errorhandler.Error{}, err)
+	})
+	t.Run("GET-Unexpected SQL error", func(t *testing.T) {
+		mockDB.EXPECT().QueryRow(selectAuthzTemplateStmt, templateName).Return(mockSQLRow)
+		mockSQLRow.EXPECT().Scan(gomock.Any()).Return(expectedError)
+		var _, err = configDBModule.GetAuthorizationTemplate(ctx, templateName)
+		assert.Equal(t, expectedError, err)
+	})
+	t.Run("GET-Success", func(t *testing.T) {
+		mockDB.EXPECT().QueryRow(selectAuthzTemplateStmt, templateName).Return(mockSQLRow)
+		mockSQLRow.EXPECT().Scan(gomock.Any()).DoAndReturn(func(name, matrix *string) error {
+			*name = templateName
+			*matrix = matrixJSON
+			return nil
+		})
+		var res, err = configDBModule.GetAuthorizationTemplate(ctx, templateName)
+		assert.Nil(t, err)
+		assert.Equal(t, template, res)
+	})
+
+	t.Run("STORE-Fails", func(t *testing.T) {
+		mockDB.EXPECT().Exec(updateAuthzTemplateStmt, templateName, matrixJSON, matrixJSON).Return(nil, expectedError)
+		var err = configDBModule.StoreOrUpdateAuthorizationTemplate(ctx, template)
+		assert.Equal(t, expectedError, err)
+	})
+	t.Run("STORE-Success", func(t *testing.T) {
+		mockDB.EXPECT().Exec(updateAuthzTemplateStmt, templateName, matrixJSON, matrixJSON).Return(nil, nil)
+		var err = configDBModule.StoreOrUpdateAuthorizationTemplate(ctx, template)
+		assert.Nil(t, err)
+	})
+
+	t.Run("DELETE-Fails", func(t *testing.T) {
+		mockDB.EXPECT().Exec(deleteAuthzTemplateStmt, templateName).Return(nil, expectedError)
+		var err = configDBModule.DeleteAuthorizationTemplate(ctx, templateName)
+		assert.Equal(t, expectedError, err)
+	})
+	t.Run("DELETE-Success", func(t *testing.T) {
+		mockDB.EXPECT().Exec(deleteAuthzTemplateStmt, templateName).Return(nil, nil)
+		var err = configDBModule.DeleteAuthorizationTemplate(ctx, templateName)
+		assert.Nil(t, err)
+	})
+}
+
+func TestAuthorizationsVersions(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	var mockDB = mock.NewCloudtrustDB(mockCtrl)
+	var mockSQLRows = mock.NewSQLRows(mockCtrl)
+	var mockSQLRow = mock.NewSQLRow(mockCtrl)
+	var mockTx = mock.NewTransaction(mockCtrl)
+	var mockLogger = log.NewNopLogger()
+
+	var configDBModule = NewConfigurationDBModule(mockDB, mockLogger)
+	var expectedError = errors.New("error")
+	var realmID = "my-realm"
+	var groupName = "my-group"
+	var now = time.Now().Unix()
+	var version = dto.AuthorizationsVersion{
+		RealmID:   realmID,
+		GroupName: groupName,
+		Version:   2,
+		Author:    "admin",
+		CreatedAt: now,
+		Before:    map[string]map[string]map[string]struct{}{},
+		After:     map[string]map[string]map[string]struct{}{"MGMT_GetUsers": {realmID: {"*": {}}}},
+	}
+	var afterJSON = `{"MGMT_GetUsers":{"my-realm":{"*":{}}}}`
+	var scanVersion = func(realm, group *string, v *int, author *string, createdAt *int64, before, after *string) error {
+		*realm = realmID
+		*group = groupName
+		*v = 2
+		*author = "admin"
+		*createdAt = now
+		*before = "{}"
+		*after = afterJSON
+		return nil
+	}
+	var ctx = context.TODO()
+
+	var expectLastVersion = func(err error) {
+		mockTx.EXPECT().QueryRow(selectLastAuthzVersionStmt, realmID, groupName).Return(mockSQLRow)
+		mockSQLRow.EXPECT().Scan(gomock.Any()).DoAndReturn(func(last *int) error {
+			*last = 1
+			return err
+		})
+	}
+
+	t.Run("CREATE-Can't get last version", func(t *testing.T) {
+		expectLastVersion(expectedError)
+		assert.Equal(t, expectedError, configDBModule.CreateAuthorizationsVersion(ctx, mockTx, version))
+	})
+	t.Run("CREATE-Fails", func(t *testing.T) {
+		expectLastVersion(nil)
+		mockTx.EXPECT().Exec(createAuthzVersionStmt, realmID, groupName, 2, "admin", now, "{}", afterJSON).Return(nil, expectedError)
+		assert.Equal(t, expectedError, configDBModule.CreateAuthorizationsVersion(ctx, mockTx, version))
+	})
+	t.Run("CREATE-Success", func(t *testing.T) {
+		expectLastVersion(nil)
+		mockTx.EXPECT().Exec(createAuthzVersionStmt, realmID, groupName, 2, "admin", now, "{}", afterJSON).Return(nil, nil)
+		assert.Nil(t, configDBModule.CreateAuthorizationsVersion(ctx, mockTx, version))
+	})
+
+	t.Run("GET ALL-SQL query fails", func(t *testing.T) {
+		mockDB.EXPECT().Query(selectAuthzVersionsStmt, realmID, groupName).Return(nil, expectedError)
+		var _, err = configDBModule.GetAuthorizationsVersions(ctx, realmID, groupName)
+		assert.Equal(t, expectedError, err)
+	})
+	t.Run("GET ALL-Scan fails", func(t *testing.T) {
+		mockDB.EXPECT().Query(selectAuthzVersionsStmt, realmID, groupName).Return(mockSQLRows, nil)
+		mockSQLRows.EXPECT().Next().Return(true)
+		mockSQLRows.EXPECT().Scan(gomock.Any()).Return(expectedError)
+		mockSQLRows.EXPECT().Close()
+		var _, err = configDBModule.GetAuthorizationsVersions(ctx, realmID, groupName)
+		assert.Equal(t, expectedError, err)
+	})
+	t.Run("GET ALL-Success", func(t *testing.T) {
+		gomock.InOrder(
+			mockDB.EXPECT().Query(selectAuthzVersionsStmt, realmID, groupName).Return(mockSQLRows, nil),
+			mockSQLRows.EXPECT().Next().Return(true),
+			mockSQLRows.EXPECT().Scan(gomock.Any()).DoAndReturn(scanVersion),
+			mockSQLRows.EXPECT().Next().Return(false),
+			mockSQLRows.EXPECT().Close(),
+		)
+		var versions, err = configDBModule.GetAuthorizationsVersions(ctx, realmID, groupName)
+		assert.Nil(t, err)
+		assert.Equal(t, []dto.AuthorizationsVersion{version}, versions)
+	})
+
+	t.Run("GET-Not found", func(t *testing.T) {
+		mockDB.EXPECT().QueryRow(selectAuthzVersionStmt, realmID, groupName, 2).Return(mockSQLRow)
+		mockSQLRow.EXPECT().Scan(gomock.Any()).Return(sql.ErrNoRows)
+		var _, err = configDBModule.GetAuthorizationsVersion(ctx, realmID, groupName, 2)
+		assert.IsType(t, errorhandler.Error{}, err)
+	})
+	t.Run("GET-Unexpected SQL error", func(t *testing.T) {
+		mockDB.EXPECT().QueryRow(selectAuthzVersionStmt, realmID, groupName, 2).Return(mockSQLRow)
+		mockSQLRow.EXPECT().Scan(gomock.Any()).Return(expectedError)
+		var _, err = configDBModule.GetAuthorizationsVersion(ctx, realmID, groupName, 2)
+		assert.Equal(t, expectedError, err)
+	})
+	t.Run("GET-Success", func(t *testing.T) {
+		mockDB.EXPECT().QueryRow(selectAuthzVersionStmt, realmID, groupName, 2).Return(mockSQLRow)
+		mockSQLRow.EXPECT().Scan(gomock.Any()).DoAndReturn(scanVersion)
+		var res, err = configDBModule.GetAuthorizationsVersion(ctx, realmID, groupName, 2)
+		assert.Nil(t, err)
+		assert.Equal(t, version, res)
+	})
+}
+
+func TestRealmConfigurationVersions(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	var mockDB = mock.NewCloudtrustDB(mockCtrl)
+	var mockSQLRows = mock.NewSQLRows(mockCtrl)
+	var mockSQLRow = mock.NewSQLRow(mockCtrl)
+	var mockTx = mock.NewTransaction(mockCtrl)
+	var mockLogger = log.NewNopLogger()
+
+	var configDBModule = NewConfigurationDBModule(mockDB, mockLogger)
+	var expectedError = errors.New("error")
+	var realmID = "my-realm"
+	var now = time.Now().Unix()
+	var version = dto.RealmConfigurationVersion{
+		RealmID:       realmID,
+		Type:          RealmConfigurationTypeCustom,
+		Version:       3,
+		Author:        "admin",
+		CreatedAt:     now,
+		Configuration: `{"barcode_type":"CODE128"}`,
+	}
+	var scanVersion = func(realm, configType *string, v *int, author *string, createdAt *int64, config *string) error {
+		*realm = realmID
+		*configType = RealmConfigurationTypeCustom
+		*v = 3
+		*author = "admin"
+		*createdAt = now
+		*config = `{"barcode_type":"CODE128"}`
+		return nil
+	}
+	var ctx = context.TODO()
+
+	var expectLastVersion = func(err error) {
+		mockTx.EXPECT().QueryRow(selectLastRealmConfigVersionStmt, realmID, RealmConfigurationTypeCustom).Return(mockSQLRow)
+		mockSQLRow.EXPECT().Scan(gomock.Any()).DoAndReturn(func(last *int) error {
+			*last = 2
+			return err
+		})
+	}
+
+	t.Run("CREATE-Can't get last version", func(t *testing.T) {
+		expectLastVersion(expectedError)
+		assert.Equal(t, expectedError, configDBModule.CreateRealmConfigurationVersion(ctx, mockTx, version))
+	})
+	t.Run("CREATE-Fails", func(t *testing.T) {
+		expectLastVersion(nil)
+		mockTx.EXPECT().Exec(createRealmConfigVersionStmt, realmID, RealmConfigurationTypeCustom, 3, "admin", now, version.Configuration).Return(nil, expectedError)
+		assert.Equal(t, expectedError, configDBModule.CreateRealmConfigurationVersion(ctx, mockTx, version))
+	})
+	t.Run("CREATE-Success", func(t *testing.T) {
+		expectLastVersion(nil)
+		mockTx.EXPECT().Exec(createRealmConfigVersionStmt, realmID, RealmConfigurationTypeCustom, 3, "admin", now, version.Configuration).Return(nil, nil)
+		assert.Nil(t, configDBModule.CreateRealmConfigurationVersion(ctx, mockTx, version))
+	})
+
+	t.Run("GET ALL-SQL query fails", func(t *testing.T) {
+		mockDB.EXPECT().Query(selectRealmConfigVersionsStmt, realmID, RealmConfigurationTypeCustom).Return(nil, expectedError)
+		var _, err = configDBModule.GetRealmConfigurationVersions(ctx, realmID, RealmConfigurationTypeCustom)
+		assert.Equal(t, expectedError, err)
+	})
+	t.Run("GET ALL-Scan fails", func(t *testing.T) {
+		mockDB.EXPECT().Query(selectRealmConfigVersionsStmt, realmID, RealmConfigurationTypeCustom).Return(mockSQLRows, nil)
+		mockSQLRows.EXPECT().Next().Return(true)
+		mockSQLRows.EXPECT().Scan(gomock.Any()).Return(expectedError)
+		mockSQLRows.EXPECT().Close()
+		var _, err = configDBModule.GetRealmConfigurationVersions(ctx, realmID, RealmConfigurationTypeCustom)
+		assert.Equal(t, expectedError, err)
+	})
+	t.Run("GET ALL-Success", func(t *testing.T) {
+		gomock.InOrder(
+			mockDB.EXPECT().Query(selectRealmConfigVersionsStmt, realmID, RealmConfigurationTypeCustom).Return(mockSQLRows, nil),
+			mockSQLRows.EXPECT().Next().Return(true),
+			mockSQLRows.EXPECT().Scan(gomock.Any()).DoAndReturn(scanVersion),
+			mockSQLRows.EXPECT().Next().Return(false),
+			mockSQLRows.EXPECT().Close(),
+		)
+		var versions, err = configDBModule.GetRealmConfigurationVersions(ctx, realmID, RealmConfigurationTypeCustom)
+		assert.Nil(t, err)
+		assert.Equal(t, []dto.RealmConfigurationVersion{version}, versions)
+	})
+
+	t.Run("GET-Not found", func(t *testing.T) {
+		mockDB.EXPECT().QueryRow(selectRealmConfigVersionStmt, realmID, RealmConfigurationTypeCustom, 3).Return(mockSQLRow)
+		mockSQLRow.EXPECT().Scan(gomock.Any()).Return(sql.ErrNoRows)
+		var _, err = configDBModule.GetRealmConfigurationVersion(ctx, realmID, RealmConfigurationTypeCustom, 3)
+		assert.IsType(t, errorhandler.Error{}, err)
+	})
+	t.Run("GET-Unexpected SQL error", func(t *testing.T) {
+		mockDB.EXPECT().QueryRow(selectRealmConfigVersionStmt, realmID, RealmConfigurationTypeCustom, 3).Return(mockSQLRow)
+		mockSQLRow.EXPECT().Scan(gomock.Any()).Return(expectedError)
+		var _, err = configDBModule.GetRealmConfigurationVersion(ctx, realmID, RealmConfigurationTypeCustom, 3)
+		assert.Equal(t, expectedError, err)
+	})
+	t.Run("GET-Success", func(t *testing.T) {
+		mockDB.EXPECT().QueryRow(selectRealmConfigVersionStmt, realmID, RealmConfigurationTypeCustom, 3).Return(mockSQLRow)
+		mockSQLRow.EXPECT().Scan(gomock.Any()).DoAndReturn(scanVersion)
+		var res, err = configDBModule.GetRealmConfigurationVersion(ctx, realmID, RealmConfigurationTypeCustom, 3)
+		assert.Nil(t, err)
+		assert.Equal(t, version, res)
+	})
+}
+
+func TestPendingRequests(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	var mockDB = mock.NewCloudtrustDB(mockCtrl)
+	var mockSQLRows = mock.NewSQLRows(mockCtrl)
+	var mockSQLRow = mock.NewSQLRow(mockCtrl)
+	var mockLogger = log.NewNopLogger()
+
+	var configDBModule = NewConfigurationDBModule(mockDB, mockLogger)
+	var expectedError = errors.New("error")
+	var realmName = "my-realm"
+	var requestID = "0123456789abcdef0123456789abcdef"
+	var now = time.Now()
+	var request = dto.PendingRequest{
+		ID:          requestID,
+		RealmName:   realmName,
+		Action:      "MGMT_DeleteUser",
+		TargetID:    "user-id",
+		Parameters:  "{}",
+		RequesterID: "requester-id",
+		Requester:   "requester",
+		CreatedAt:   now.Unix(),
+		ExpiresAt:   now.Add(time.Hour).Unix(),
+	}
+	var scanRequest = func(id, realm, action, targetID, parameters, requesterID, requester *string, createdAt, expiresAt *int64) error {
+		*id, *realm, *action, *targetID, *parameters = request.ID, request.RealmName, request.Action, request.TargetID, request.Parameters
+		*requesterID, *requester, *createdAt, *expiresAt = request.RequesterID, request.Requester, request.CreatedAt, request.ExpiresAt
+		return nil
+	}
+	var ctx = context.TODO()
+
+	t.Run("CREATE-Fails", func(t *testing.T) {
+		mockDB.EXPECT().Exec(createPendingRequestStmt, requestID, realmName, "MGMT_DeleteUser", "user-id", "{}", "requester-id", "requester",
+			request.CreatedAt, request.ExpiresAt).Return(nil, expectedError)
+		assert.Equal(t, expectedError, configDBModule.CreatePendingRequest(ctx, request))
+	})
+	t.Run("CREATE-Success", func(t *testing.T) {
+		mockDB.EXPECT().Exec(createPendingRequestStmt, requestID, realmName, "MGMT_DeleteUser", "user-id", "{}", "requester-id", "requester",
+			request.CreatedAt, request.ExpiresAt).Return(nil, nil)
+		assert.Nil(t, configDBModule.CreatePendingRequest(ctx, request))
+	})
+
+	t.Run("GET ALL-SQL query fails", func(t *testing.T) {
+		mockDB.EXPECT().Query(selectPendingRequestsStmt, realmName, now.Unix()).Return(nil, expectedError)
+		var _, err = configDBModule.GetPendingRequests(ctx, realmName, now)
+		assert.Equal(t, expectedError, err)
+	})
+	t.Run("GET ALL-Scan fails", func(t *testing.T) {
+		mockDB.EXPECT().Query(selectPendingRequestsStmt, realmName, now.Unix()).Return(mockSQLRows, nil)
+		mockSQLRows.EXPECT().Next().Return(true)
+		mockSQLRows.EXPECT().Scan(gomock.Any()).Return(expectedError)
+		mockSQLRows.EXPECT().Close()
+		var _, err = configDBModule.GetPendingRequests(ctx, realmName, now)
+		assert.Equal(t, expectedError, err)
+	})
+	t.Run("GET ALL-Success", func(t *testing.T) {
+		gomock.InOrder(
+			mockDB.EXPECT().Query(selectPendingRequestsStmt, realmName, now.Unix()).Return(mockSQLRows, nil),
+			mockSQLRows.EXPECT().Next().Return(true),
+			mockSQLRows.EXPECT().Scan(gomock.Any()).DoAndReturn(scanRequest),
+			mockSQLRows.EXPECT().Next().Return(false),
+			mockSQLRows.EXPECT().Close(),
+		)
+		var requests, err = configDBModule.GetPendingRequests(ctx, realmName, now)
+		assert.Nil(t, err)
+		assert.Equal(t, []dto.PendingRequest{request}, requests)
+	})
+
+	t.Run("GET-Not found", func(t *testing.T) {
+		mockDB.EXPECT().QueryRow(selectPendingRequestStmt, realmName, requestID).Return(mockSQLRow)
+		mockSQLRow.EXPECT().Scan(gomock.Any()).Return(sql.ErrNoRows)
+		var _, err = configDBModule.GetPendingRequest(ctx, realmName, requestID)
+		assert.IsType(t, errorhandler.Error{}, err)
+	})
+	t.Run("GET-Unexpected SQL error", func(t *testing.T) {
+		mockDB.EXPECT().QueryRow(selectPendingRequestStmt, realmName, requestID).Return(mockSQLRow)
+		mockSQLRow.EXPECT().Scan(gomock.Any()).Return(expectedError)
+		var _, err = configDBModule.GetPendingRequest(ctx, realmName, requestID)
+		assert.Equal(t, expectedError, err)
+	})
+	t.Run("GET-Success", func(t *testing.T) {
+		mockDB.EXPECT().QueryRow(selectPendingRequestStmt, realmName, requestID).Return(mockSQLRow)
+		mockSQLRow.EXPECT().Scan(gomock.Any()).DoAndReturn(scanRequest)
+		var res, err = configDBModule.GetPendingRequest(ctx, realmName, requestID)
+		assert.Nil(t, err)
+		assert.Equal(t, request, res)
+	})
+
+	t.Run("DELETE-Fails", func(t *testing.T) {
+		mockDB.EXPECT().Exec(deletePendingRequestStmt, realmName, requestID).Return(nil, expectedError)
+		var _, err = configDBModule.DeletePendingRequest(ctx, realmName, requestID)
+		assert.Equal(t, expectedError, err)
+	})
+	t.Run("DELETE-Already deleted", func(t *testing.T) {
+		mockDB.EXPECT().Exec(deletePendingRequestStmt, realmName, requestID).Return(driver.RowsAffected(0), nil)
+		var deleted, err = configDBModule.DeletePendingRequest(ctx, realmName, requestID)
+		assert.Nil(t, err)
+		assert.False(t, deleted)
+	})
+	t.Run("DELETE-Success", func(t *testing.T) {
+		mockDB.EXPECT().Exec(deletePendingRequestStmt, realmName, requestID).Return(driver.RowsAffected(1), nil)
+		var deleted, err = configDBModule.DeletePendingRequest(ctx, realmName, requestID)
+		assert.Nil(t, err)
+		assert.True(t, deleted)
+	})
+}
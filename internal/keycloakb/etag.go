@@ -0,0 +1,58 @@
+package keycloakb
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	errorhandler "github.com/cloudtrust/common-service/errors"
+	msg "github.com/cloudtrust/keycloak-bridge/internal/constants"
+)
+
+// ETagReply is the reply of an endpoint giving the version of the returned resource in the ETag header
+type ETagReply struct {
+	ETag  string
+	Value interface{}
+}
+
+// ComputeETag computes a strong entity tag from the JSON representation of the values a resource is made of
+func ComputeETag(values ...interface{}) (string, error) {
+	var hash = sha256.New()
+	for _, value := range values {
+		var bytes, err = json.Marshal(value)
+		if err != nil {
+			return "", err
+		}
+		hash.Write(bytes)
+		hash.Write([]byte{0})
+	}
+	return `"` + hex.EncodeToString(hash.Sum(nil)) + `"`, nil
+}
+
+// CheckIfMatch checks that the resource made of the given values did not change since the client read it. ifMatch is the
+// If-Match header of the request: when it is empty, the client does not ask for a check. When none of its entity tags
+// matches, a 412 Precondition Failed error is returned
+func CheckIfMatch(ifMatch string, values ...interface{}) error {
+	if ifMatch == "" {
+		return nil
+	}
+
+	var etag, err = ComputeETag(values...)
+	if err != nil {
+		return err
+	}
+
+	for _, value := range strings.Split(ifMatch, ",") {
+		value = strings.TrimSpace(value)
+		if value == "*" || value == etag {
+			return nil
+		}
+	}
+
+	return errorhandler.Error{
+		Status:  http.StatusPreconditionFailed,
+		Message: ComponentName + "." + msg.MsgErrOutdated + "." + msg.IfMatch,
+	}
+}
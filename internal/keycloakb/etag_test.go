@@ -0,0 +1,58 @@
+package keycloakb
+
+import (
+	"net/http"
+	"testing"
+
+	errorhandler "github.com/cloudtrust/common-service/errors"
+	"github.com/cloudtrust/keycloak-bridge/internal/dto"
+	kc "github.com/cloudtrust/keycloak-client"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestComputeETag(t *testing.T) {
+	var firstName = "John"
+	var otherName = "Jane"
+	var user = kc.UserRepresentation{FirstName: &firstName}
+	var details = dto.DBUser{}
+
+	var etag, err = ComputeETag(user, details)
+	assert.Nil(t, err)
+	assert.Regexp(t, `^"[0-9a-f]{64}"$`, etag)
+
+	t.Run("Same values give the same entity tag", func(t *testing.T) {
+		var other, _ = ComputeETag(kc.UserRepresentation{FirstName: &firstName}, dto.DBUser{})
+		assert.Equal(t, etag, other)
+	})
+	t.Run("Different values give different entity tags", func(t *testing.T) {
+		var other, _ = ComputeETag(kc.UserRepresentation{FirstName: &otherName}, details)
+		assert.NotEqual(t, etag, other)
+	})
+	t.Run("Values can't be marshalled", func(t *testing.T) {
+		var _, err = ComputeETag(make(chan int))
+		assert.NotNil(t, err)
+	})
+}
+
+func TestCheckIfMatch(t *testing.T) {
+	var firstName = "John"
+	var user = kc.UserRepresentation{FirstName: &firstName}
+	var etag, _ = ComputeETag(user)
+
+	t.Run("No If-Match header", func(t *testing.T) {
+		assert.Nil(t, CheckIfMatch("", user))
+	})
+	t.Run("Wildcard", func(t *testing.T) {
+		assert.Nil(t, CheckIfMatch("*", user))
+	})
+	t.Run("Matching entity tag", func(t *testing.T) {
+		assert.Nil(t, CheckIfMatch(etag, user))
+		assert.Nil(t, CheckIfMatch(`"abc", `+etag, user))
+	})
+	t.Run("Resource modified since it was read", func(t *testing.T) {
+		var otherName = "Jane"
+		var err = CheckIfMatch(etag, kc.UserRepresentation{FirstName: &otherName})
+		assert.IsType(t, errorhandler.Error{}, err)
+		assert.Equal(t, http.StatusPreconditionFailed, err.(errorhandler.Error).Status)
+	})
+}
@@ -2,6 +2,7 @@ package keycloakb
 
 import (
 	"context"
+	"database/sql"
 	"encoding/json"
 	"errors"
 	"regexp"
@@ -27,6 +28,41 @@ type EventsDBModule interface {
 	GetTotalConnectionsDaysCount(context.Context, string, *time.Location, int) ([][]int64, error)
 	GetTotalConnectionsMonthsCount(context.Context, string, *time.Location, int) ([][]int64, error)
 	GetLastConnections(context.Context, string, string) ([]api_stat.StatisticsConnectionRepresentation, error)
+	GetTotalEventsCount(context.Context, string, string, string) (int64, error)
+	GetTotalEventsHoursCount(context.Context, string, string, *time.Location, int) ([][]int64, error)
+	GetTotalEventsDaysCount(context.Context, string, string, *time.Location, int) ([][]int64, error)
+	GetTotalEventsMonthsCount(context.Context, string, string, *time.Location, int) ([][]int64, error)
+	GetFailuresCountByErrorCode(context.Context, string, string) (map[string]int64, error)
+	GetFailuresCountByClientID(context.Context, string, string) (map[string]int64, error)
+	GetRegistrationSteps(context.Context, string, time.Time, time.Time) ([]UserRegistrationSteps, error)
+	GetLastConnectionPerUser(context.Context, string) (map[string]int64, error)
+	GetUsersWithEventsSince(context.Context, string, time.Time, ...string) ([]string, error)
+	GetActiveUsersCount(context.Context, string, string) (int64, error)
+}
+
+// Values of ct_event_type used to compute authentication statistics
+const (
+	CtEventTypeLogonOK           = "LOGON_OK"
+	CtEventTypeLogonError        = "LOGON_ERROR"
+	CtEventTypeTemporarilyLocked = "TEMPORARILY_LOCKED"
+)
+
+// Values of ct_event_type of the steps of the registration funnel
+const (
+	CtEventTypeRegisterUser         = "REGISTER_USER"
+	CtEventTypeEmailConfirmed       = "EMAIL_CONFIRMED"
+	CtEventTypePasswordReset        = "PASSWORD_RESET"
+	CtEventTypeValidateUser         = "VALIDATE_USER"
+	CtEventTypeValidationStoreCheck = "VALIDATION_STORE_CHECK"
+)
+
+// CtEventTypeAccreditationsRevoked is the ct_event_type of the revocation of the accreditations of a user by an operator
+const CtEventTypeAccreditationsRevoked = "ACCREDITATIONS_REVOKED"
+
+// UserRegistrationSteps gives the time (unix timestamp) at which a registered user reached each step of the registration funnel
+type UserRegistrationSteps struct {
+	UserID string
+	Steps  map[string]int64
 }
 
 type eventsDBModule struct {
@@ -72,30 +108,30 @@ const (
 	selectLastConnectionTimeStmt      = `SELECT ifnull(unix_timestamp(max(audit_time)), 0) FROM audit WHERE realm_name=? AND ct_event_type='LOGON_OK'`
 	selectAuditSummaryOriginStmt      = `SELECT distinct origin FROM audit;`
 	selectAuditSummaryCtEventTypeStmt = `SELECT distinct ct_event_type FROM audit;`
-	selectConnectionsCount            = `SELECT count(1) FROM audit WHERE realm_name=? AND ct_event_type='LOGON_OK' AND date_add(audit_time, INTERVAL ##INTERVAL##)>now()`
-	selectConnectionsHoursCount       = `
+	selectEventsCount                 = `SELECT count(1) FROM audit WHERE realm_name=? AND ct_event_type=? AND date_add(audit_time, INTERVAL ##INTERVAL##)>now()`
+	selectEventsHoursCount            = `
 			SELECT date_format(date_add(audit_time, INTERVAL ? MINUTE), '%H'), count(1)
 			FROM audit
 			WHERE realm_name=?
-			  AND ct_event_type='LOGON_OK'
+			  AND ct_event_type=?
 			  AND audit_time between date_add(?, INTERVAL -1 DAY) and ?
 			GROUP by date_format(date_add(audit_time, INTERVAL ? MINUTE), '%Y-%m-%d %H')
 			ORDER BY audit_time
 	`
-	selectConnectionsDaysCount = `
+	selectEventsDaysCount = `
 			SELECT date_format(date_add(audit_time, INTERVAL ? MINUTE), '%d'), count(1)
 			FROM audit
 			WHERE realm_name=?
-			  AND ct_event_type='LOGON_OK' 
+			  AND ct_event_type=?
 			  AND audit_time between date_add(?, INTERVAL -1 MONTH) and ?
 			GROUP by date_format(date_add(audit_time, INTERVAL ? MINUTE), '%Y-%m-%d')
 			ORDER BY audit_time
 	`
-	selectConnectionsMonthsCount = `
+	selectEventsMonthsCount = `
 			SELECT date_format(date_add(audit_time, INTERVAL ? MINUTE), '%m'), count(1)
 			FROM audit
 			WHERE realm_name=?
-			  AND ct_event_type='LOGON_OK'
+			  AND ct_event_type=?
 			  AND audit_time between date_add(?, INTERVAL -12 MONTH) and ?
 			GROUP by date_format(date_add(audit_time, INTERVAL ? MINUTE), '%Y-%m')
 			ORDER BY audit_time
@@ -105,6 +141,56 @@ const (
 							ORDER BY audit_time DESC
 							LIMIT ?;
 				`
+	selectFailuresCountByErrorCode = `
+			SELECT ifnull(json_unquote(json_extract(additional_info, '$.error')), ''), count(1)
+			FROM audit
+			WHERE realm_name=?
+			  AND ct_event_type IN ('LOGON_ERROR', 'TEMPORARILY_LOCKED')
+			  AND date_add(audit_time, INTERVAL ##INTERVAL##)>now()
+			GROUP BY json_unquote(json_extract(additional_info, '$.error'))
+	`
+	selectFailuresCountByClientID = `
+			SELECT ifnull(client_id, ''), count(1)
+			FROM audit
+			WHERE realm_name=?
+			  AND ct_event_type IN ('LOGON_ERROR', 'TEMPORARILY_LOCKED')
+			  AND date_add(audit_time, INTERVAL ##INTERVAL##)>now()
+			GROUP BY client_id
+	`
+	selectLastConnectionPerUserStmt = `
+			SELECT user_id, unix_timestamp(max(audit_time))
+			FROM audit
+			WHERE realm_name=?
+			  AND ct_event_type='LOGON_OK'
+			  AND user_id IS NOT NULL
+			GROUP BY user_id
+	`
+	selectUsersWithEventsSinceStmt = `
+			SELECT DISTINCT user_id
+			FROM audit
+			WHERE realm_name=?
+			  AND audit_time>=?
+			  AND user_id IS NOT NULL
+	`
+	selectActiveUsersCountStmt  = `SELECT count(DISTINCT user_id) FROM audit WHERE realm_name=? AND ct_event_type='LOGON_OK' AND date_add(audit_time, INTERVAL ##INTERVAL##)>now()`
+	selectRegistrationStepsStmt = `
+			SELECT reg.user_id, unix_timestamp(reg.registration_time), step.ct_event_type, unix_timestamp(min(step.audit_time))
+			FROM (
+				SELECT user_id, min(audit_time) AS registration_time
+				FROM audit
+				WHERE realm_name=?
+				  AND ct_event_type='REGISTER_USER'
+				  AND audit_time between ? and ?
+				GROUP BY user_id
+			) reg
+			LEFT JOIN audit step
+			  ON step.realm_name=?
+			  AND step.user_id=reg.user_id
+			  AND step.ct_event_type IN ('EMAIL_CONFIRMED', 'PASSWORD_RESET', 'VALIDATE_USER', 'VALIDATION_STORE_CHECK')
+			  AND step.audit_time>=reg.registration_time
+			GROUP BY reg.user_id, reg.registration_time, step.ct_event_type
+			ORDER BY reg.registration_time
+	`
 )
 
 func createAuditEventsParametersFromMap(m map[string]string) (selectAuditEventsParameters, error) {
@@ -149,8 +235,8 @@ func createStats(size int, firstValue, minValue, maxValue int, descending bool)
 	return res
 }
 
-func (cm *eventsDBModule) executeConnectionsQuery(stats [][]int64, query string, realmName string, maxTime time.Time, minutesShift int) error {
-	rows, err := cm.db.Query(query, minutesShift, realmName, maxTime, maxTime, minutesShift)
+func executeConnectionsQuery(db sqltypes.CloudtrustDB, stats [][]int64, query string, realmName string, ctEventType string, maxTime time.Time, minutesShift int) error {
+	rows, err := db.Query(query, minutesShift, realmName, ctEventType, maxTime, maxTime, minutesShift)
 	if err != nil {
 		return err
 	}
@@ -261,54 +347,84 @@ func (cm *eventsDBModule) GetLastConnection(_ context.Context, realmName string)
 }
 
 // GetTotalConnectionsCount gets the number of connection for the given realm during the specified duration
-func (cm *eventsDBModule) GetTotalConnectionsCount(_ context.Context, realmName string, durationLabel string) (int64, error) {
-	var matched, err = regexp.MatchString(`^\d+ [A-Za-z]+$`, durationLabel)
-	if !matched || err != nil {
-		return 0, errors.New(msg.MsgErrInvalidParam + "." + msg.DurationLabel)
+func (cm *eventsDBModule) GetTotalConnectionsCount(ctx context.Context, realmName string, durationLabel string) (int64, error) {
+	return cm.GetTotalEventsCount(ctx, realmName, CtEventTypeLogonOK, durationLabel)
+}
+
+// GetTotalConnectionsHoursCount gets the number of connections for the given realm for the last 24 hours, hour by hour
+func (cm *eventsDBModule) GetTotalConnectionsHoursCount(ctx context.Context, realmName string, location *time.Location, minutesShift int) ([][]int64, error) {
+	return cm.GetTotalEventsHoursCount(ctx, realmName, CtEventTypeLogonOK, location, minutesShift)
+}
+
+// GetTotalConnectionsDaysCount gets the number of connections for the given realm for the last 30 days, day by day
+func (cm *eventsDBModule) GetTotalConnectionsDaysCount(ctx context.Context, realmName string, location *time.Location, minutesShift int) ([][]int64, error) {
+	return cm.GetTotalEventsDaysCount(ctx, realmName, CtEventTypeLogonOK, location, minutesShift)
+}
+
+// GetTotalConnectionsMonthsCount gets the number of connections for the given realm for the last 12 months, month by month
+func (cm *eventsDBModule) GetTotalConnectionsMonthsCount(ctx context.Context, realmName string, location *time.Location, minutesShift int) ([][]int64, error) {
+	return cm.GetTotalEventsMonthsCount(ctx, realmName, CtEventTypeLogonOK, location, minutesShift)
+}
+
+// GetTotalEventsCount gets the number of events of the given type for the given realm during the specified duration
+func (cm *eventsDBModule) GetTotalEventsCount(_ context.Context, realmName string, ctEventType string, durationLabel string) (int64, error) {
+	var err = checkDurationLabel(durationLabel)
+	if err != nil {
+		return 0, err
 	}
 	var res = int64(0)
-	var row = cm.db.QueryRow(strings.ReplaceAll(selectConnectionsCount, "##INTERVAL##", durationLabel), realmName)
+	var row = cm.db.QueryRow(strings.ReplaceAll(selectEventsCount, "##INTERVAL##", durationLabel), realmName, ctEventType)
 	err = row.Scan(&res)
 	return res, err
 }
 
-// GetTotalConnectionsHoursCount gets the number of connections for the given realm for the last 24 hours, hour by hour
-func (cm *eventsDBModule) GetTotalConnectionsHoursCount(_ context.Context, realmName string, location *time.Location, minutesShift int) ([][]int64, error) {
+// GetTotalEventsHoursCount gets the number of events of the given type for the given realm for the last 24 hours, hour by hour
+func (cm *eventsDBModule) GetTotalEventsHoursCount(_ context.Context, realmName string, ctEventType string, location *time.Location, minutesShift int) ([][]int64, error) {
 	var now = time.Now()
 	var nowLocalized = now.In(location)
 	var res = createStats(24, nowLocalized.Hour(), 0, 23, false)
 
 	maxTime := NextHour(nowLocalized)
-	err := cm.executeConnectionsQuery(res, selectConnectionsHoursCount, realmName, maxTime, minutesShift)
+	err := executeConnectionsQuery(cm.db, res, selectEventsHoursCount, realmName, ctEventType, maxTime, minutesShift)
 
 	return res, err
 }
 
-// GetTotalConnectionsHoursCount gets the number of connections for the given realm for the last 30 days, day by day
-func (cm *eventsDBModule) GetTotalConnectionsDaysCount(_ context.Context, realmName string, location *time.Location, minutesShift int) ([][]int64, error) {
+// GetTotalEventsDaysCount gets the number of events of the given type for the given realm for the last 30 days, day by day
+func (cm *eventsDBModule) GetTotalEventsDaysCount(_ context.Context, realmName string, ctEventType string, location *time.Location, minutesShift int) ([][]int64, error) {
 	var now = time.Now()
 	var nowLocalized = now.In(location)
 	var maxDay = ThisMonth(nowLocalized).Add(-time.Hour).Day()
 	var res = createStats(maxDay, nowLocalized.Day(), 1, maxDay, false)
 
 	maxTime := NextDay(nowLocalized)
-	err := cm.executeConnectionsQuery(res, selectConnectionsDaysCount, realmName, maxTime, minutesShift)
+	err := executeConnectionsQuery(cm.db, res, selectEventsDaysCount, realmName, ctEventType, maxTime, minutesShift)
 
 	return res, err
 }
 
-// GetTotalConnectionsHoursCount gets the number of connections for the given realm for the last 24 hours, hour by hour
-func (cm *eventsDBModule) GetTotalConnectionsMonthsCount(_ context.Context, realmName string, location *time.Location, minutesShift int) ([][]int64, error) {
+// GetTotalEventsMonthsCount gets the number of events of the given type for the given realm for the last 12 months, month by month
+func (cm *eventsDBModule) GetTotalEventsMonthsCount(_ context.Context, realmName string, ctEventType string, location *time.Location, minutesShift int) ([][]int64, error) {
 	var now = time.Now()
 	var nowLocalized = now.In(location)
 	var res = createStats(12, int(nowLocalized.Month()), 1, 12, false)
 
 	maxTime := NextMonth(nowLocalized)
-	err := cm.executeConnectionsQuery(res, selectConnectionsMonthsCount, realmName, maxTime, minutesShift)
+	err := executeConnectionsQuery(cm.db, res, selectEventsMonthsCount, realmName, ctEventType, maxTime, minutesShift)
 
 	return res, err
 }
 
+// GetFailuresCountByErrorCode gets the number of failed authentications for the given realm during the specified duration, grouped by error code
+func (cm *eventsDBModule) GetFailuresCountByErrorCode(_ context.Context, realmName string, durationLabel string) (map[string]int64, error) {
+	return cm.queryCountByKey(selectFailuresCountByErrorCode, realmName, durationLabel)
+}
+
+// GetFailuresCountByClientID gets the number of failed authentications for the given realm during the specified duration, grouped by client
+func (cm *eventsDBModule) GetFailuresCountByClientID(_ context.Context, realmName string, durationLabel string) (map[string]int64, error) {
+	return cm.queryCountByKey(selectFailuresCountByClientID, realmName, durationLabel)
+}
+
 // GetLastConnections gives information on the last authentications
 func (cm *eventsDBModule) GetLastConnections(_ context.Context, realmName string, nbConnections string) ([]api_stat.StatisticsConnectionRepresentation, error) {
 
@@ -335,6 +451,103 @@ func (cm *eventsDBModule) GetLastConnections(_ context.Context, realmName string
 	return res, err
 }
 
+// GetRegistrationSteps gets, for the users registered in the given realm between two dates, the time at which they reached each step of the registration funnel
+func (cm *eventsDBModule) GetRegistrationSteps(_ context.Context, realmName string, from time.Time, to time.Time) ([]UserRegistrationSteps, error) {
+	rows, err := cm.db.Query(selectRegistrationStepsStmt, realmName, from, to, realmName)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var res = []UserRegistrationSteps{}
+	var indexes = make(map[string]int)
+	for rows.Next() {
+		var userID string
+		var registrationTime int64
+		var stepType sql.NullString
+		var stepTime sql.NullInt64
+		if err = rows.Scan(&userID, &registrationTime, &stepType, &stepTime); err != nil {
+			return nil, err
+		}
+		var idx, ok = indexes[userID]
+		if !ok {
+			idx = len(res)
+			indexes[userID] = idx
+			res = append(res, UserRegistrationSteps{
+				UserID: userID,
+				Steps:  map[string]int64{CtEventTypeRegisterUser: registrationTime},
+			})
+		}
+		if stepType.Valid && stepTime.Valid {
+			res[idx].Steps[stepType.String] = stepTime.Int64
+		}
+	}
+
+	return res, rows.Err()
+}
+
+// GetLastConnectionPerUser gets the time of the last successful connection of each user of the given realm
+func (cm *eventsDBModule) GetLastConnectionPerUser(_ context.Context, realmName string) (map[string]int64, error) {
+	rows, err := cm.db.Query(selectLastConnectionPerUserStmt, realmName)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var res = make(map[string]int64)
+	for rows.Next() {
+		var userID string
+		var lastConnection int64
+		if err = rows.Scan(&userID, &lastConnection); err != nil {
+			return nil, err
+		}
+		res[userID] = lastConnection
+	}
+
+	return res, rows.Err()
+}
+
+// GetUsersWithEventsSince gets the identifiers of the users of the given realm concerned by at least one event since the given time.
+// When event types are given, only the events of these types are considered
+func (cm *eventsDBModule) GetUsersWithEventsSince(_ context.Context, realmName string, since time.Time, ctEventTypes ...string) ([]string, error) {
+	var query = selectUsersWithEventsSinceStmt
+	var params = []interface{}{realmName, since}
+	if len(ctEventTypes) > 0 {
+		query += "AND ct_event_type IN (" + strings.TrimSuffix(strings.Repeat("?,", len(ctEventTypes)), ",") + ")"
+		for _, ctEventType := range ctEventTypes {
+			params = append(params, ctEventType)
+		}
+	}
+
+	rows, err := cm.db.Query(query, params...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var res = []string{}
+	for rows.Next() {
+		var userID string
+		if err = rows.Scan(&userID); err != nil {
+			return nil, err
+		}
+		res = append(res, userID)
+	}
+
+	return res, rows.Err()
+}
+
+// GetActiveUsersCount gets the number of distinct users of the given realm who successfully connected during the specified duration
+func (cm *eventsDBModule) GetActiveUsersCount(_ context.Context, realmName string, durationLabel string) (int64, error) {
+	if err := checkDurationLabel(durationLabel); err != nil {
+		return 0, err
+	}
+	var res = int64(0)
+	var row = cm.db.QueryRow(strings.ReplaceAll(selectActiveUsersCountStmt, "##INTERVAL##", durationLabel), realmName)
+	var err = row.Scan(&res)
+	return res, err
+}
+
 func getSQLParam(m map[string]string, name string, defaultValue interface{}) interface{} {
 	if value, ok := m[name]; ok {
 		return value
@@ -342,6 +555,38 @@ func getSQLParam(m map[string]string, name string, defaultValue interface{}) int
 	return defaultValue
 }
 
+func checkDurationLabel(durationLabel string) error {
+	var matched, err = regexp.MatchString(`^\d+ [A-Za-z]+$`, durationLabel)
+	if !matched || err != nil {
+		return errors.New(msg.MsgErrInvalidParam + "." + msg.DurationLabel)
+	}
+	return nil
+}
+
+func (cm *eventsDBModule) queryCountByKey(query string, realmName string, durationLabel string) (map[string]int64, error) {
+	if err := checkDurationLabel(durationLabel); err != nil {
+		return nil, err
+	}
+
+	rows, err := cm.db.Query(strings.ReplaceAll(query, "##INTERVAL##", durationLabel), realmName)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var res = make(map[string]int64)
+	for rows.Next() {
+		var key string
+		var count int64
+		if err = rows.Scan(&key, &count); err != nil {
+			return nil, err
+		}
+		res[key] = count
+	}
+
+	return res, rows.Err()
+}
+
 func (cm *eventsDBModule) queryStringArray(request string) ([]string, error) {
 	var res []string
 	rows, err := cm.db.Query(request)
@@ -3,7 +3,9 @@ package keycloakb
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"testing"
+	"time"
 
 	errorhandler "github.com/cloudtrust/common-service/errors"
 	api "github.com/cloudtrust/keycloak-bridge/api/events"
@@ -91,6 +93,87 @@ func TestModuleGetTotalConnectionsCount(t *testing.T) {
 	}
 }
 
+func TestModuleGetFailuresCount(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	dbEvents := mock.NewDBEvents(mockCtrl)
+	module := NewEventsDBModule(dbEvents)
+
+	// Check SQL injection
+	{
+		_, err := module.GetFailuresCountByErrorCode(context.TODO(), "realm", "1 DAY'; TRUNCATE TABLE PASSWORD; select '")
+		assert.NotNil(t, err)
+		_, err = module.GetFailuresCountByClientID(context.TODO(), "realm", "1 DAY'; TRUNCATE TABLE PASSWORD; select '")
+		assert.NotNil(t, err)
+		_, err = module.GetTotalEventsCount(context.TODO(), "realm", CtEventTypeLogonError, "1 DAY'; TRUNCATE TABLE PASSWORD; select '")
+		assert.NotNil(t, err)
+	}
+
+	// Query fails
+	{
+		var expectedError = errors.New("query fails")
+		dbEvents.EXPECT().Query(gomock.Any(), "realm").Return(nil, expectedError).Times(1)
+		_, err := module.GetFailuresCountByClientID(context.TODO(), "realm", "1 DAY")
+		assert.Equal(t, expectedError, err)
+	}
+}
+
+func TestModuleGetLastConnectionPerUser(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	dbEvents := mock.NewDBEvents(mockCtrl)
+	module := NewEventsDBModule(dbEvents)
+
+	var expectedError = errors.New("query fails")
+	dbEvents.EXPECT().Query(selectLastConnectionPerUserStmt, "realm").Return(nil, expectedError).Times(1)
+	_, err := module.GetLastConnectionPerUser(context.TODO(), "realm")
+	assert.Equal(t, expectedError, err)
+}
+
+func TestModuleGetUsersWithEventsSince(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	dbEvents := mock.NewDBEvents(mockCtrl)
+	module := NewEventsDBModule(dbEvents)
+
+	var since = time.Now()
+	var expectedError = errors.New("query fails")
+	dbEvents.EXPECT().Query(selectUsersWithEventsSinceStmt, "realm", since).Return(nil, expectedError).Times(1)
+	_, err := module.GetUsersWithEventsSince(context.TODO(), "realm", since)
+	assert.Equal(t, expectedError, err)
+}
+
+func TestModuleGetUsersWithEventsSinceOfTypes(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	dbEvents := mock.NewDBEvents(mockCtrl)
+	module := NewEventsDBModule(dbEvents)
+
+	var since = time.Now()
+	var expectedError = errors.New("query fails")
+	dbEvents.EXPECT().Query(selectUsersWithEventsSinceStmt+"AND ct_event_type IN (?,?)", "realm", since, CtEventTypeValidateUser, CtEventTypeAccreditationsRevoked).Return(nil, expectedError).Times(1)
+	_, err := module.GetUsersWithEventsSince(context.TODO(), "realm", since, CtEventTypeValidateUser, CtEventTypeAccreditationsRevoked)
+	assert.Equal(t, expectedError, err)
+}
+
+func TestModuleGetActiveUsersCount(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	dbEvents := mock.NewDBEvents(mockCtrl)
+	module := NewEventsDBModule(dbEvents)
+
+	// Check SQL injection
+	{
+		_, err := module.GetActiveUsersCount(context.TODO(), "realm", "1 MONTH'; TRUNCATE TABLE PASSWORD; select '")
+		assert.NotNil(t, err)
+	}
+}
+
 func TestCreateStats(t *testing.T) {
 	assert.Equal(t, [][]int64{{3, 0}, {2, 0}, {9, 0}, {8, 0}, {7, 0}}, createStats(5, 3, 2, 9, true))
 	assert.Equal(t, [][]int64{{7, 0}, {8, 0}, {9, 0}, {2, 0}, {3, 0}}, createStats(5, 3, 2, 9, false))
@@ -3,5 +3,8 @@ package keycloakb
 //go:generate mockgen -destination=./mock/instrumenting.go -package=mock -mock_names=Histogram=Histogram github.com/cloudtrust/common-service/metrics Histogram
 //go:generate mockgen -destination=./mock/configdbinstrumenting.go -package=mock -mock_names=ConfigurationDBModule=ConfigurationDBModule,AccredsKeycloakClient=AccredsKeycloakClient github.com/cloudtrust/keycloak-bridge/internal/keycloakb ConfigurationDBModule,AccredsKeycloakClient
 //go:generate mockgen -destination=./mock/keycloak_client.go -package=mock -mock_names=KeycloakClient=KeycloakClient github.com/cloudtrust/keycloak-bridge/internal/keycloakb KeycloakClient
-//go:generate mockgen -destination=./mock/sqltypes.go -package=mock -mock_names=CloudtrustDB=CloudtrustDB,SQLRow=SQLRow,SQLRows=SQLRows github.com/cloudtrust/common-service/database/sqltypes CloudtrustDB,SQLRow,SQLRows
+//go:generate mockgen -destination=./mock/sqltypes.go -package=mock -mock_names=CloudtrustDB=CloudtrustDB,SQLRow=SQLRow,SQLRows=SQLRows,Transaction=Transaction github.com/cloudtrust/common-service/database/sqltypes CloudtrustDB,SQLRow,SQLRows,Transaction
 //go:generate mockgen -destination=./mock/security.go -package=mock -mock_names=EncrypterDecrypter=EncrypterDecrypter github.com/cloudtrust/common-service/security EncrypterDecrypter
+//go:generate mockgen -destination=./mock/sagamodule.go -package=mock -mock_names=SagaKeycloakClient=SagaKeycloakClient,UsersDetailsDBModule=UsersDetailsDBModule github.com/cloudtrust/keycloak-bridge/internal/keycloakb SagaKeycloakClient,UsersDetailsDBModule
+//go:generate mockgen -destination=./mock/toolbox.go -package=mock -mock_names=OidcTokenProvider=OidcTokenProvider github.com/cloudtrust/keycloak-client/toolbox OidcTokenProvider
+//go:generate mockgen -destination=./mock/trustidgroupsmodule.go -package=mock -mock_names=TrustIDGroupsKeycloakClient=TrustIDGroupsKeycloakClient,TrustIDGroupsEventsDBModule=TrustIDGroupsEventsDBModule github.com/cloudtrust/keycloak-bridge/internal/keycloakb TrustIDGroupsKeycloakClient,TrustIDGroupsEventsDBModule
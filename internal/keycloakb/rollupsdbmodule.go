@@ -0,0 +1,256 @@
+package keycloakb
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cloudtrust/common-service/database/sqltypes"
+	msg "github.com/cloudtrust/keycloak-bridge/internal/constants"
+)
+
+// EventsCounter counts the events of a realm. It is implemented by the events DB module, which scans the audit table,
+// and by the rollups DB module, which reads the materialised counters
+type EventsCounter interface {
+	GetTotalConnectionsCount(context.Context, string, string) (int64, error)
+	GetTotalConnectionsHoursCount(context.Context, string, *time.Location, int) ([][]int64, error)
+	GetTotalConnectionsDaysCount(context.Context, string, *time.Location, int) ([][]int64, error)
+	GetTotalConnectionsMonthsCount(context.Context, string, *time.Location, int) ([][]int64, error)
+	GetTotalEventsCount(context.Context, string, string, string) (int64, error)
+	GetTotalEventsHoursCount(context.Context, string, string, *time.Location, int) ([][]int64, error)
+	GetTotalEventsDaysCount(context.Context, string, string, *time.Location, int) ([][]int64, error)
+	GetTotalEventsMonthsCount(context.Context, string, string, *time.Location, int) ([][]int64, error)
+}
+
+// RollupsDBModule maintains hourly and daily counters of the audit events per realm and ct_event_type.
+// Counters are stored in the audit database:
+//
+//	CREATE TABLE audit_rollup (
+//	  realm_name VARCHAR(255) NOT NULL,
+//	  ct_event_type VARCHAR(50) NOT NULL,
+//	  period_unit CHAR(1) NOT NULL,
+//	  period_start DATETIME NOT NULL,
+//	  events_count BIGINT NOT NULL,
+//	  PRIMARY KEY (realm_name, ct_event_type, period_unit, period_start)
+//	);
+//
+// period_unit is H for hourly counters and D for daily counters. Periods are expressed in UTC.
+type RollupsDBModule interface {
+	EventsCounter
+	Increment(ctx context.Context, realmName string, ctEventType string, eventTime time.Time) error
+	Backfill(ctx context.Context, from time.Time, to time.Time) error
+}
+
+const (
+	rollupUnitHour = "H"
+	rollupUnitDay  = "D"
+
+	incrementRollupsStmt = `
+			INSERT INTO audit_rollup (realm_name, ct_event_type, period_unit, period_start, events_count)
+			VALUES (?, ?, 'H', ?, 1), (?, ?, 'D', ?, 1)
+			ON DUPLICATE KEY UPDATE events_count=events_count+1
+	`
+	backfillHourlyRollupsStmt = `
+			REPLACE INTO audit_rollup (realm_name, ct_event_type, period_unit, period_start, events_count)
+			SELECT realm_name, ct_event_type, 'H', date_format(audit_time, '%Y-%m-%d %H:00:00'), count(1)
+			FROM audit
+			WHERE audit_time>=? AND audit_time<?
+			  AND realm_name IS NOT NULL
+			  AND ct_event_type IS NOT NULL
+			GROUP BY realm_name, ct_event_type, date_format(audit_time, '%Y-%m-%d %H:00:00')
+	`
+	backfillDailyRollupsStmt = `
+			REPLACE INTO audit_rollup (realm_name, ct_event_type, period_unit, period_start, events_count)
+			SELECT realm_name, ct_event_type, 'D', date_format(period_start, '%Y-%m-%d 00:00:00'), sum(events_count)
+			FROM audit_rollup
+			WHERE period_unit='H'
+			  AND period_start>=? AND period_start<?
+			GROUP BY realm_name, ct_event_type, date_format(period_start, '%Y-%m-%d 00:00:00')
+	`
+	selectRollupsCountStmt = `
+			SELECT ifnull(sum(events_count), 0)
+			FROM audit_rollup
+			WHERE realm_name=?
+			  AND ct_event_type=?
+			  AND ((period_unit='D' AND period_start>=? AND period_start<?)
+			    OR (period_unit='H' AND period_start>=? AND (period_start<? OR period_start>=?)))
+	`
+	selectRollupsHoursCountStmt = `
+			SELECT date_format(date_add(period_start, INTERVAL ? MINUTE), '%H'), sum(events_count)
+			FROM audit_rollup
+			WHERE realm_name=?
+			  AND ct_event_type=?
+			  AND period_unit='H'
+			  AND period_start between date_add(?, INTERVAL -1 DAY) and ?
+			GROUP by date_format(date_add(period_start, INTERVAL ? MINUTE), '%Y-%m-%d %H')
+			ORDER BY min(period_start)
+	`
+	selectRollupsDaysCountStmt = `
+			SELECT date_format(date_add(period_start, INTERVAL ? MINUTE), '%d'), sum(events_count)
+			FROM audit_rollup
+			WHERE realm_name=?
+			  AND ct_event_type=?
+			  AND period_unit='H'
+			  AND period_start between date_add(?, INTERVAL -1 MONTH) and ?
+			GROUP by date_format(date_add(period_start, INTERVAL ? MINUTE), '%Y-%m-%d')
+			ORDER BY min(period_start)
+	`
+	selectRollupsMonthsCountStmt = `
+			SELECT date_format(date_add(period_start, INTERVAL ? MINUTE), '%m'), sum(events_count)
+			FROM audit_rollup
+			WHERE realm_name=?
+			  AND ct_event_type=?
+			  AND period_unit='H'
+			  AND period_start between date_add(?, INTERVAL -12 MONTH) and ?
+			GROUP by date_format(date_add(period_start, INTERVAL ? MINUTE), '%Y-%m')
+			ORDER BY min(period_start)
+	`
+)
+
+type rollupsDBModule struct {
+	db sqltypes.CloudtrustDB
+}
+
+// NewRollupsDBModule returns a rollups DB module.
+func NewRollupsDBModule(db sqltypes.CloudtrustDB) RollupsDBModule {
+	return &rollupsDBModule{
+		db: db,
+	}
+}
+
+// Increment increments the hourly and daily counters of the given event type
+func (rm *rollupsDBModule) Increment(_ context.Context, realmName string, ctEventType string, eventTime time.Time) error {
+	var hour = eventTime.UTC().Truncate(time.Hour)
+	var day = truncateDay(eventTime)
+	_, err := rm.db.Exec(incrementRollupsStmt, realmName, ctEventType, hour, realmName, ctEventType, day)
+	return err
+}
+
+// Backfill recomputes the counters from the audit table for the whole days between from and to.
+// Increments done on the covered days while the backfill is running may be lost: it should be run over past periods
+// or when the rollups are not yet maintained. The current day is never covered as its counters are live
+func (rm *rollupsDBModule) Backfill(_ context.Context, from time.Time, to time.Time) error {
+	var start = truncateDay(from)
+	var end = truncateDay(to)
+	if !end.Equal(to.UTC()) {
+		end = end.Add(24 * time.Hour)
+	}
+	if today := truncateDay(time.Now()); end.After(today) {
+		end = today
+	}
+	if !start.Before(end) {
+		return nil
+	}
+
+	if _, err := rm.db.Exec(backfillHourlyRollupsStmt, start, end); err != nil {
+		return err
+	}
+	_, err := rm.db.Exec(backfillDailyRollupsStmt, start, end)
+	return err
+}
+
+// GetTotalConnectionsCount gets the number of successful connections for the given realm during the specified duration
+func (rm *rollupsDBModule) GetTotalConnectionsCount(ctx context.Context, realmName string, durationLabel string) (int64, error) {
+	return rm.GetTotalEventsCount(ctx, realmName, CtEventTypeLogonOK, durationLabel)
+}
+
+// GetTotalConnectionsHoursCount gets the number of successful connections for the given realm for the last 24 hours, hour by hour
+func (rm *rollupsDBModule) GetTotalConnectionsHoursCount(ctx context.Context, realmName string, location *time.Location, minutesShift int) ([][]int64, error) {
+	return rm.GetTotalEventsHoursCount(ctx, realmName, CtEventTypeLogonOK, location, minutesShift)
+}
+
+// GetTotalConnectionsDaysCount gets the number of successful connections for the given realm for the last 30 days, day by day
+func (rm *rollupsDBModule) GetTotalConnectionsDaysCount(ctx context.Context, realmName string, location *time.Location, minutesShift int) ([][]int64, error) {
+	return rm.GetTotalEventsDaysCount(ctx, realmName, CtEventTypeLogonOK, location, minutesShift)
+}
+
+// GetTotalConnectionsMonthsCount gets the number of successful connections for the given realm for the last 12 months, month by month
+func (rm *rollupsDBModule) GetTotalConnectionsMonthsCount(ctx context.Context, realmName string, location *time.Location, minutesShift int) ([][]int64, error) {
+	return rm.GetTotalEventsMonthsCount(ctx, realmName, CtEventTypeLogonOK, location, minutesShift)
+}
+
+// GetTotalEventsCount gets the number of events of the given type for the given realm during the specified duration.
+// The precision is one hour: the counter of the hour in which the duration begins is fully included
+func (rm *rollupsDBModule) GetTotalEventsCount(_ context.Context, realmName string, ctEventType string, durationLabel string) (int64, error) {
+	var now = time.Now().UTC()
+	var from, err = subtractDurationLabel(now, durationLabel)
+	if err != nil {
+		return 0, err
+	}
+
+	// Full days are read from the daily counters, the remaining hours from the hourly counters
+	var hourFrom = from.Truncate(time.Hour)
+	var dayFrom = truncateDay(hourFrom)
+	if !dayFrom.Equal(hourFrom) {
+		dayFrom = dayFrom.Add(24 * time.Hour)
+	}
+	var dayTo = truncateDay(now)
+	if !dayFrom.Before(dayTo) {
+		dayFrom, dayTo = hourFrom, hourFrom
+	}
+
+	var res = int64(0)
+	var row = rm.db.QueryRow(selectRollupsCountStmt, realmName, ctEventType, dayFrom, dayTo, hourFrom, dayFrom, dayTo)
+	err = row.Scan(&res)
+	return res, err
+}
+
+// GetTotalEventsHoursCount gets the number of events of the given type for the given realm for the last 24 hours, hour by hour
+func (rm *rollupsDBModule) GetTotalEventsHoursCount(_ context.Context, realmName string, ctEventType string, location *time.Location, minutesShift int) ([][]int64, error) {
+	var nowLocalized = time.Now().In(location)
+	var res = createStats(24, nowLocalized.Hour(), 0, 23, false)
+
+	err := executeConnectionsQuery(rm.db, res, selectRollupsHoursCountStmt, realmName, ctEventType, NextHour(nowLocalized), minutesShift)
+
+	return res, err
+}
+
+// GetTotalEventsDaysCount gets the number of events of the given type for the given realm for the last 30 days, day by day
+func (rm *rollupsDBModule) GetTotalEventsDaysCount(_ context.Context, realmName string, ctEventType string, location *time.Location, minutesShift int) ([][]int64, error) {
+	var nowLocalized = time.Now().In(location)
+	var maxDay = ThisMonth(nowLocalized).Add(-time.Hour).Day()
+	var res = createStats(maxDay, nowLocalized.Day(), 1, maxDay, false)
+
+	err := executeConnectionsQuery(rm.db, res, selectRollupsDaysCountStmt, realmName, ctEventType, NextDay(nowLocalized), minutesShift)
+
+	return res, err
+}
+
+// GetTotalEventsMonthsCount gets the number of events of the given type for the given realm for the last 12 months, month by month
+func (rm *rollupsDBModule) GetTotalEventsMonthsCount(_ context.Context, realmName string, ctEventType string, location *time.Location, minutesShift int) ([][]int64, error) {
+	var nowLocalized = time.Now().In(location)
+	var res = createStats(12, int(nowLocalized.Month()), 1, 12, false)
+
+	err := executeConnectionsQuery(rm.db, res, selectRollupsMonthsCountStmt, realmName, ctEventType, NextMonth(nowLocalized), minutesShift)
+
+	return res, err
+}
+
+func truncateDay(value time.Time) time.Time {
+	var utc = value.UTC()
+	return time.Date(utc.Year(), utc.Month(), utc.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// subtractDurationLabel subtracts a duration expressed as in the audit queries (i.e. 12 HOUR, 1 DAY, 1 WEEK, 1 MONTH, 1 YEAR)
+func subtractDurationLabel(value time.Time, durationLabel string) (time.Time, error) {
+	if err := checkDurationLabel(durationLabel); err != nil {
+		return value, err
+	}
+	var parts = strings.Fields(durationLabel)
+	var count, _ = strconv.Atoi(parts[0])
+	switch strings.ToUpper(parts[1]) {
+	case "HOUR":
+		return value.Add(-time.Duration(count) * time.Hour), nil
+	case "DAY":
+		return value.AddDate(0, 0, -count), nil
+	case "WEEK":
+		return value.AddDate(0, 0, -7*count), nil
+	case "MONTH":
+		return value.AddDate(0, -count, 0), nil
+	case "YEAR":
+		return value.AddDate(-count, 0, 0), nil
+	}
+	return value, errors.New(msg.MsgErrInvalidParam + "." + msg.DurationLabel)
+}
@@ -0,0 +1,99 @@
+package keycloakb
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/cloudtrust/keycloak-bridge/internal/keycloakb/mock"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRollupsIncrement(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+	var mockDB = mock.NewCloudtrustDB(mockCtrl)
+	var module = NewRollupsDBModule(mockDB)
+
+	var eventTime = time.Date(2020, time.March, 5, 14, 35, 12, 0, time.UTC)
+	var hour = time.Date(2020, time.March, 5, 14, 0, 0, 0, time.UTC)
+	var day = time.Date(2020, time.March, 5, 0, 0, 0, 0, time.UTC)
+
+	mockDB.EXPECT().Exec(incrementRollupsStmt, "realm", "LOGON_OK", hour, "realm", "LOGON_OK", day).Return(nil, nil)
+	assert.Nil(t, module.Increment(context.Background(), "realm", "LOGON_OK", eventTime))
+}
+
+func TestRollupsBackfill(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+	var mockDB = mock.NewCloudtrustDB(mockCtrl)
+	var module = NewRollupsDBModule(mockDB)
+
+	var from = time.Date(2020, time.March, 5, 14, 35, 12, 0, time.UTC)
+	var to = time.Date(2020, time.March, 7, 10, 0, 0, 0, time.UTC)
+	var start = time.Date(2020, time.March, 5, 0, 0, 0, 0, time.UTC)
+	var end = time.Date(2020, time.March, 8, 0, 0, 0, 0, time.UTC)
+	var errDB = errors.New("db error")
+
+	t.Run("Hourly counters fail", func(t *testing.T) {
+		mockDB.EXPECT().Exec(backfillHourlyRollupsStmt, start, end).Return(nil, errDB)
+		assert.Equal(t, errDB, module.Backfill(context.Background(), from, to))
+	})
+	t.Run("Success", func(t *testing.T) {
+		mockDB.EXPECT().Exec(backfillHourlyRollupsStmt, start, end).Return(nil, nil)
+		mockDB.EXPECT().Exec(backfillDailyRollupsStmt, start, end).Return(nil, nil)
+		assert.Nil(t, module.Backfill(context.Background(), from, to))
+	})
+	t.Run("Current day is not covered", func(t *testing.T) {
+		var today = time.Now().UTC().Truncate(24 * time.Hour)
+		mockDB.EXPECT().Exec(backfillHourlyRollupsStmt, today.AddDate(0, 0, -2), today).Return(nil, nil)
+		mockDB.EXPECT().Exec(backfillDailyRollupsStmt, today.AddDate(0, 0, -2), today).Return(nil, nil)
+		assert.Nil(t, module.Backfill(context.Background(), today.AddDate(0, 0, -2), time.Now()))
+	})
+	t.Run("Nothing to backfill", func(t *testing.T) {
+		assert.Nil(t, module.Backfill(context.Background(), time.Now(), time.Now()))
+	})
+}
+
+func TestRollupsGetTotalEventsCount(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+	var mockDB = mock.NewCloudtrustDB(mockCtrl)
+	var module = NewRollupsDBModule(mockDB)
+
+	// Check SQL injection
+	_, err := module.GetTotalEventsCount(context.Background(), "realm", "LOGON_OK", "1 DAY'; TRUNCATE TABLE PASSWORD; select '")
+	assert.NotNil(t, err)
+
+	// Unknown unit
+	_, err = module.GetTotalEventsCount(context.Background(), "realm", "LOGON_OK", "1 CENTURY")
+	assert.NotNil(t, err)
+
+	// Success
+	var mockSQLRow = mock.NewSQLRow(mockCtrl)
+	mockDB.EXPECT().QueryRow(selectRollupsCountStmt, "realm", "LOGON_OK", gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(mockSQLRow)
+	mockSQLRow.EXPECT().Scan(gomock.Any()).DoAndReturn(func(count *int64) error {
+		*count = 12
+		return nil
+	})
+	count, err := module.GetTotalEventsCount(context.Background(), "realm", "LOGON_OK", "1 WEEK")
+	assert.Nil(t, err)
+	assert.Equal(t, int64(12), count)
+}
+
+func TestSubtractDurationLabel(t *testing.T) {
+	var ref = time.Date(2020, time.March, 5, 14, 0, 0, 0, time.UTC)
+	for label, expected := range map[string]time.Time{
+		"12 HOUR": time.Date(2020, time.March, 5, 2, 0, 0, 0, time.UTC),
+		"1 DAY":   time.Date(2020, time.March, 4, 14, 0, 0, 0, time.UTC),
+		"1 WEEK":  time.Date(2020, time.February, 27, 14, 0, 0, 0, time.UTC),
+		"1 MONTH": time.Date(2020, time.February, 5, 14, 0, 0, 0, time.UTC),
+		"1 YEAR":  time.Date(2019, time.March, 5, 14, 0, 0, 0, time.UTC),
+	} {
+		var res, err = subtractDurationLabel(ref, label)
+		assert.Nil(t, err)
+		assert.Equal(t, expected, res, label)
+	}
+}
@@ -0,0 +1,443 @@
+package keycloakb
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/cloudtrust/common-service/database/sqltypes"
+	"github.com/cloudtrust/common-service/security"
+	"github.com/cloudtrust/keycloak-bridge/internal/dto"
+	kc "github.com/cloudtrust/keycloak-client"
+	"github.com/cloudtrust/keycloak-client/toolbox"
+	"github.com/pkg/errors"
+)
+
+// Kinds of the operations which write both in Keycloak and in the users database
+const (
+	// SagaCreateUser is compensated by deleting the user from Keycloak and its details from the database
+	SagaCreateUser = "CREATE_USER"
+	// SagaUpdateUser is compensated by restoring the previous Keycloak user and the previous details. The reconciler only
+	// restores the previous Keycloak user when Keycloak still holds the state written by the operation
+	SagaUpdateUser = "UPDATE_USER"
+	// SagaDeleteUser is compensated by replaying the deletion of the user details
+	SagaDeleteUser = "DELETE_USER"
+)
+
+const (
+	insertPendingOperationStmt = `INSERT INTO pending_operations (operation_id, realm_id, user_id, kind, data, created_at)
+	  VALUES (?, ?, ?, ?, ?, ?);`
+	updatePendingOperationUserStmt = `UPDATE pending_operations SET user_id=?, data=? WHERE operation_id=?;`
+	deletePendingOperationStmt     = `DELETE FROM pending_operations WHERE operation_id=?;`
+	selectPendingOperationsStmt    = `
+	  SELECT operation_id, realm_id, user_id, kind, data, created_at
+	  FROM pending_operations
+	  WHERE created_at<?;`
+)
+
+// SagaOperation is an operation which writes both in Keycloak and in the users database.
+// PreviousUser, PreviousDetails, TargetUser and TargetDetails are only used by SagaUpdateUser: they are the state of the
+// user before the operation and the state written by the operation. A SagaCreateUser operation is recorded before the
+// creation of the user: it is identified by Username until Keycloak gives the ID of the user
+type SagaOperation struct {
+	ID              string                 `json:"-"`
+	Kind            string                 `json:"-"`
+	Realm           string                 `json:"-"`
+	UserID          string                 `json:"-"`
+	CreatedAt       time.Time              `json:"-"`
+	PreviousUser    *kc.UserRepresentation `json:"previousUser,omitempty"`
+	PreviousDetails *dto.DBUser            `json:"previousDetails,omitempty"`
+	TargetUser      *kc.UserRepresentation `json:"targetUser,omitempty"`
+	TargetDetails   *dto.DBUser            `json:"targetDetails,omitempty"`
+	Username        string                 `json:"username,omitempty"`
+}
+
+// SagaKeycloakClient is the minimum Keycloak client interface used to compensate operations
+type SagaKeycloakClient interface {
+	GetUser(accessToken string, realmName, userID string) (kc.UserRepresentation, error)
+	GetUsers(accessToken string, reqRealmName, targetRealmName string, paramKV ...string) (kc.UsersPageRepresentation, error)
+	UpdateUser(accessToken string, realmName, userID string, user kc.UserRepresentation) error
+	DeleteUser(accessToken string, realmName, userID string) error
+}
+
+// SagaModule records the operations which write both in Keycloak and in the users database until they complete.
+// Pending operations are stored in the users database:
+//
+//	CREATE TABLE pending_operations (
+//	  operation_id CHAR(32) NOT NULL,
+//	  realm_id VARCHAR(255) NOT NULL,
+//	  user_id CHAR(36) NOT NULL,
+//	  kind VARCHAR(20) NOT NULL,
+//	  data BLOB,
+//	  created_at BIGINT NOT NULL,
+//	  PRIMARY KEY (operation_id)
+//	);
+//
+// data contains the previous state of the user, encrypted as the user details. created_at is the number of milliseconds
+// elapsed since the epoch, which does not depend on the time zone of the database session.
+// An operation which fails is compensated immediately by its caller. Operations left pending after a crash are
+// processed by Reconcile: a created user is kept if its details have been stored (a user whose ID has not been
+// recorded is searched by its username and only removed if it has been created after the operation began, which
+// assumes the clocks of Keycloak and of the bridge are synchronized), an update is completed if Keycloak and the
+// database hold the written state and the previous user is only restored in Keycloak if Keycloak still holds exactly
+// the written state (any other state comes from a later change), and the deletion of the user details is replayed if
+// the user no longer exists in Keycloak.
+type SagaModule interface {
+	Begin(ctx context.Context, op SagaOperation) (SagaOperation, error)
+	SetUserID(ctx context.Context, op SagaOperation, userID string) (SagaOperation, error)
+	Complete(ctx context.Context, op SagaOperation) error
+	Compensate(ctx context.Context, accessToken string, op SagaOperation) error
+	Reconcile(ctx context.Context, olderThan time.Duration) error
+}
+
+type sagaModule struct {
+	db             sqltypes.CloudtrustDB
+	cipher         security.EncrypterDecrypter
+	keycloakClient SagaKeycloakClient
+	usersDBModule  UsersDetailsDBModule
+	tokenProvider  toolbox.OidcTokenProvider
+	logger         Logger
+}
+
+// NewSagaModule returns a saga module. The token provider is used by Reconcile, which runs without any caller
+func NewSagaModule(db sqltypes.CloudtrustDB, cipher security.EncrypterDecrypter, keycloakClient SagaKeycloakClient, usersDBModule UsersDetailsDBModule,
+	tokenProvider toolbox.OidcTokenProvider, logger Logger) SagaModule {
+	return &sagaModule{
+		db:             db,
+		cipher:         cipher,
+		keycloakClient: keycloakClient,
+		usersDBModule:  usersDBModule,
+		tokenProvider:  tokenProvider,
+		logger:         logger,
+	}
+}
+
+// Begin records a pending operation and returns it with its identifier
+func (sm *sagaModule) Begin(ctx context.Context, op SagaOperation) (SagaOperation, error) {
+	var bytes = make([]byte, 16)
+	if _, err := rand.Read(bytes); err != nil {
+		sm.logger.Warn(ctx, "msg", "Can't generate pending operation ID", "err", err.Error())
+		return op, err
+	}
+
+	encryptedData, err := sm.encrypt(ctx, op)
+	if err != nil {
+		return op, err
+	}
+
+	var id = hex.EncodeToString(bytes)
+	var createdAt = time.Now().UTC()
+	if _, err = sm.db.Exec(insertPendingOperationStmt, id, op.Realm, op.UserID, op.Kind, encryptedData, toEpochMillis(createdAt)); err != nil {
+		sm.logger.Warn(ctx, "msg", "Can't store pending operation", "err", err.Error(), "kind", op.Kind, "realmID", op.Realm, "userID", op.UserID)
+		return op, err
+	}
+
+	op.ID = id
+	op.CreatedAt = createdAt
+	return op, nil
+}
+
+// SetUserID records the ID given by Keycloak to the user created by a SagaCreateUser operation
+func (sm *sagaModule) SetUserID(ctx context.Context, op SagaOperation, userID string) (SagaOperation, error) {
+	op.UserID = userID
+	var encryptedData, err = sm.encrypt(ctx, op)
+	if err != nil {
+		return op, err
+	}
+	if _, err = sm.db.Exec(updatePendingOperationUserStmt, op.UserID, encryptedData, op.ID); err != nil {
+		sm.logger.Warn(ctx, "msg", "Can't update pending operation", "err", err.Error(), "id", op.ID, "realmID", op.Realm, "userID", op.UserID)
+		return op, err
+	}
+	return op, nil
+}
+
+// encrypt encrypts the data of an operation as the user details, using the user ID as additional data
+func (sm *sagaModule) encrypt(ctx context.Context, op SagaOperation) ([]byte, error) {
+	dataJSON, err := json.Marshal(op)
+	if err != nil {
+		return nil, err
+	}
+	encryptedData, err := sm.cipher.Encrypt(dataJSON, []byte(op.UserID))
+	if err != nil {
+		sm.logger.Warn(ctx, "msg", "Can't encrypt the pending operation", "err", err.Error(), "realmID", op.Realm, "userID", op.UserID)
+		return nil, err
+	}
+	return encryptedData, nil
+}
+
+// Complete removes a pending operation once all its writes succeeded or once it has been compensated
+func (sm *sagaModule) Complete(ctx context.Context, op SagaOperation) error {
+	if op.ID == "" {
+		return nil
+	}
+	var _, err = sm.db.Exec(deletePendingOperationStmt, op.ID)
+	if err != nil {
+		sm.logger.Warn(ctx, "msg", "Can't remove pending operation", "err", err.Error(), "id", op.ID)
+	}
+	return err
+}
+
+// Compensate reverts the writes of an operation and completes it. An operation which has not been recorded (empty ID)
+// is compensated as well. If the compensation fails, the operation stays pending for the reconciler
+func (sm *sagaModule) Compensate(ctx context.Context, accessToken string, op SagaOperation) error {
+	var err error
+
+	switch op.Kind {
+	case SagaCreateUser:
+		if op.UserID == "" {
+			// The operation was interrupted before Keycloak gave the ID of the user: nothing to revert if it can't be found
+			if op.UserID, err = sm.findCreatedUser(accessToken, op); err != nil || op.UserID == "" {
+				break
+			}
+		}
+		if err = sm.keycloakClient.DeleteUser(accessToken, op.Realm, op.UserID); err != nil && !isNotFound(err) {
+			break
+		}
+		err = sm.usersDBModule.DeleteUserDetails(ctx, op.Realm, op.UserID)
+	case SagaUpdateUser:
+		if op.PreviousUser != nil {
+			if err = sm.keycloakClient.UpdateUser(accessToken, op.Realm, op.UserID, *op.PreviousUser); err != nil {
+				break
+			}
+		}
+		if op.PreviousDetails != nil {
+			err = sm.usersDBModule.StoreOrUpdateUserDetails(ctx, op.Realm, *op.PreviousDetails)
+		}
+	case SagaDeleteUser:
+		err = sm.usersDBModule.DeleteUserDetails(ctx, op.Realm, op.UserID)
+	default:
+		err = errors.New("unknown pending operation kind " + op.Kind)
+	}
+
+	if err != nil {
+		sm.logger.Error(ctx, "msg", "Can't compensate operation", "err", err.Error(), "id", op.ID, "kind", op.Kind, "realmID", op.Realm, "userID", op.UserID)
+		return err
+	}
+	sm.logger.Info(ctx, "msg", "Operation compensated", "id", op.ID, "kind", op.Kind, "realmID", op.Realm, "userID", op.UserID)
+	return sm.Complete(ctx, op)
+}
+
+// Reconcile processes the operations pending for more than olderThan. Several instances may reconcile concurrently:
+// each compensation is idempotent
+func (sm *sagaModule) Reconcile(ctx context.Context, olderThan time.Duration) error {
+	var ops, err = sm.getPendingOperations(ctx, time.Now().UTC().Add(-olderThan))
+	if err != nil {
+		sm.logger.Warn(ctx, "msg", "Can't get pending operations", "err", err.Error())
+		return err
+	}
+	if len(ops) == 0 {
+		return nil
+	}
+
+	accessToken, err := sm.tokenProvider.ProvideToken(ctx)
+	if err != nil {
+		sm.logger.Warn(ctx, "msg", "Can't get OIDC token", "err", err.Error())
+		return err
+	}
+
+	for _, op := range ops {
+		// Errors are logged: the operation will be processed again by the next reconciliation
+		_ = sm.reconcile(ctx, accessToken, op)
+	}
+	return nil
+}
+
+func (sm *sagaModule) reconcile(ctx context.Context, accessToken string, op SagaOperation) error {
+	switch op.Kind {
+	case SagaCreateUser:
+		if op.UserID == "" {
+			// The details are only stored once the ID of the user is recorded
+			break
+		}
+		// The details are only stored after the creation of the user: if they exist, the operation succeeded
+		var details, err = sm.usersDBModule.GetUserDetails(ctx, op.Realm, op.UserID)
+		if err != nil {
+			return err
+		}
+		if details.BirthLocation != nil || details.IDDocumentType != nil || details.IDDocumentNumber != nil || details.IDDocumentExpiration != nil {
+			return sm.Complete(ctx, op)
+		}
+	case SagaUpdateUser:
+		return sm.reconcileUpdate(ctx, accessToken, op)
+	case SagaDeleteUser:
+		// If the user still exists, it has not been deleted from Keycloak and its details must be kept
+		var _, err = sm.keycloakClient.GetUser(accessToken, op.Realm, op.UserID)
+		if err == nil {
+			return sm.Complete(ctx, op)
+		}
+		if !isNotFound(err) {
+			sm.logger.Warn(ctx, "msg", "Can't get user from Keycloak", "err", err.Error(), "realmID", op.Realm, "userID", op.UserID)
+			return err
+		}
+	}
+	return sm.Compensate(ctx, accessToken, op)
+}
+
+// reconcileUpdate completes an update which reached both Keycloak and the database. The previous user is only restored
+// when Keycloak holds exactly the state written by the operation while the database does not: the details have not
+// been written and don't need to be restored. A user in any other state has not been updated or has been changed since
+func (sm *sagaModule) reconcileUpdate(ctx context.Context, accessToken string, op SagaOperation) error {
+	if op.TargetUser == nil {
+		// Recorded without the written state: reverting it could overwrite a later change
+		sm.logger.Warn(ctx, "msg", "Can't reconcile update without the written state", "id", op.ID, "realmID", op.Realm, "userID", op.UserID)
+		return sm.Complete(ctx, op)
+	}
+
+	var current, err = sm.keycloakClient.GetUser(accessToken, op.Realm, op.UserID)
+	if isNotFound(err) {
+		return sm.Complete(ctx, op)
+	} else if err != nil {
+		sm.logger.Warn(ctx, "msg", "Can't get user from Keycloak", "err", err.Error(), "realmID", op.Realm, "userID", op.UserID)
+		return err
+	}
+	if !holdsUserState(current, *op.TargetUser) {
+		return sm.Complete(ctx, op)
+	}
+
+	if op.TargetDetails != nil {
+		details, err := sm.usersDBModule.GetUserDetails(ctx, op.Realm, op.UserID)
+		if err != nil {
+			return err
+		}
+		if !sameUserDetails(details, *op.TargetDetails) && op.PreviousUser != nil {
+			if err = sm.keycloakClient.UpdateUser(accessToken, op.Realm, op.UserID, *op.PreviousUser); err != nil {
+				sm.logger.Error(ctx, "msg", "Can't compensate operation", "err", err.Error(), "id", op.ID, "kind", op.Kind, "realmID", op.Realm, "userID", op.UserID)
+				return err
+			}
+			sm.logger.Info(ctx, "msg", "Operation compensated", "id", op.ID, "kind", op.Kind, "realmID", op.Realm, "userID", op.UserID)
+		}
+	}
+	return sm.Complete(ctx, op)
+}
+
+// holdsUserState tells whether the Keycloak user has the values written by an update. Keycloak stores usernames and
+// emails in lower case
+func holdsUserState(current kc.UserRepresentation, written kc.UserRepresentation) bool {
+	return sameFoldedString(current.Username, written.Username) &&
+		sameFoldedString(current.Email, written.Email) &&
+		sameString(current.FirstName, written.FirstName) &&
+		sameString(current.LastName, written.LastName) &&
+		sameBool(current.Enabled, written.Enabled) &&
+		sameBool(current.EmailVerified, written.EmailVerified) &&
+		sameAttributes(current.Attributes, written.Attributes)
+}
+
+// sameUserDetails tells whether the details of a user have the given values
+func sameUserDetails(current dto.DBUser, written dto.DBUser) bool {
+	return sameString(current.BirthLocation, written.BirthLocation) &&
+		sameString(current.IDDocumentType, written.IDDocumentType) &&
+		sameString(current.IDDocumentNumber, written.IDDocumentNumber) &&
+		sameString(current.IDDocumentExpiration, written.IDDocumentExpiration)
+}
+
+// A nil written value has not been written and matches any current value
+func sameString(current, written *string) bool {
+	return written == nil || (current != nil && *current == *written)
+}
+
+func sameFoldedString(current, written *string) bool {
+	return written == nil || (current != nil && strings.EqualFold(*current, *written))
+}
+
+func sameBool(current, written *bool) bool {
+	return written == nil || (current != nil && *current == *written)
+}
+
+// Keycloak replaces all the attributes of a user and drops the attributes without value
+func sameAttributes(current, written *kc.Attributes) bool {
+	if written == nil {
+		return true
+	}
+	var currentAttributes = kc.Attributes{}
+	if current != nil {
+		currentAttributes = *current
+	}
+	for key, values := range *written {
+		if !sameValues(currentAttributes[key], values) {
+			return false
+		}
+	}
+	for key, values := range currentAttributes {
+		if _, ok := (*written)[key]; !ok && len(values) > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func sameValues(current, written []string) bool {
+	if len(current) != len(written) {
+		return false
+	}
+	for i := range written {
+		if current[i] != written[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// findCreatedUser returns the ID of the user created by the operation or an empty string if there is none. A user
+// with the same username created before the operation began belongs to someone else and is ignored
+func (sm *sagaModule) findCreatedUser(accessToken string, op SagaOperation) (string, error) {
+	if op.Username == "" {
+		return "", nil
+	}
+	var users, err = sm.keycloakClient.GetUsers(accessToken, op.Realm, op.Realm, "username", op.Username)
+	if err != nil {
+		return "", err
+	}
+	for _, user := range users.Users {
+		// The search on the username is not exact and Keycloak stores usernames in lower case
+		if user.ID == nil || user.Username == nil || !strings.EqualFold(*user.Username, op.Username) {
+			continue
+		}
+		if user.CreatedTimestamp != nil && *user.CreatedTimestamp >= toEpochMillis(op.CreatedAt) {
+			return *user.ID, nil
+		}
+	}
+	return "", nil
+}
+
+func (sm *sagaModule) getPendingOperations(ctx context.Context, createdBefore time.Time) ([]SagaOperation, error) {
+	var rows, err = sm.db.Query(selectPendingOperationsStmt, toEpochMillis(createdBefore))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var res []SagaOperation
+	for rows.Next() {
+		var op SagaOperation
+		var encryptedData, dataJSON []byte
+		var createdAt int64
+
+		if err = rows.Scan(&op.ID, &op.Realm, &op.UserID, &op.Kind, &encryptedData, &createdAt); err != nil {
+			return nil, err
+		}
+		dataJSON, err = sm.cipher.Decrypt(encryptedData, []byte(op.UserID))
+		if err != nil {
+			sm.logger.Warn(ctx, "msg", "Can't decrypt the pending operation", "err", err.Error(), "id", op.ID)
+			return nil, err
+		}
+		if err = json.Unmarshal(dataJSON, &op); err != nil {
+			return nil, err
+		}
+		op.CreatedAt = time.Unix(0, createdAt*int64(time.Millisecond)).UTC()
+		res = append(res, op)
+	}
+	return res, rows.Err()
+}
+
+func toEpochMillis(t time.Time) int64 {
+	return t.UnixNano() / int64(time.Millisecond)
+}
+
+func isNotFound(err error) bool {
+	var e, ok = errors.Cause(err).(kc.HTTPError)
+	return ok && e.HTTPStatus == http.StatusNotFound
+}
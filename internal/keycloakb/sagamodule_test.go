@@ -0,0 +1,317 @@
+package keycloakb
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/cloudtrust/common-service/log"
+	"github.com/cloudtrust/keycloak-bridge/internal/dto"
+	"github.com/cloudtrust/keycloak-bridge/internal/keycloakb/mock"
+	kc "github.com/cloudtrust/keycloak-client"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSagaBeginAndComplete(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+	var mockDB = mock.NewCloudtrustDB(mockCtrl)
+	var mockCrypter = mock.NewEncrypterDecrypter(mockCtrl)
+
+	var sagaModule = NewSagaModule(mockDB, mockCrypter, nil, nil, nil, log.NewNopLogger())
+	var ctx = context.TODO()
+	var realm = "my-realm"
+	var userID = "user-id"
+	var op = SagaOperation{Kind: SagaDeleteUser, Realm: realm, UserID: userID}
+
+	t.Run("Encryption fails", func(t *testing.T) {
+		var cryptError = errors.New("crypt error")
+		mockCrypter.EXPECT().Encrypt(gomock.Any(), []byte(userID)).Return(nil, cryptError)
+		var _, err = sagaModule.Begin(ctx, op)
+		assert.Equal(t, cryptError, err)
+	})
+	t.Run("Insert fails", func(t *testing.T) {
+		var sqlError = errors.New("sql error")
+		mockCrypter.EXPECT().Encrypt(gomock.Any(), []byte(userID)).Return([]byte("encrypted"), nil)
+		mockDB.EXPECT().Exec(insertPendingOperationStmt, gomock.Any(), realm, userID, SagaDeleteUser, []byte("encrypted"), gomock.Any()).Return(nil, sqlError)
+		var _, err = sagaModule.Begin(ctx, op)
+		assert.Equal(t, sqlError, err)
+	})
+	t.Run("Success", func(t *testing.T) {
+		mockCrypter.EXPECT().Encrypt(gomock.Any(), []byte(userID)).Return([]byte("encrypted"), nil)
+		var createdAt int64
+		mockDB.EXPECT().Exec(insertPendingOperationStmt, gomock.Any(), realm, userID, SagaDeleteUser, []byte("encrypted"), gomock.Any()).
+			DoAndReturn(func(_ string, args ...interface{}) (sql.Result, error) {
+				createdAt = args[5].(int64)
+				return nil, nil
+			})
+		var res, err = sagaModule.Begin(ctx, op)
+		assert.Nil(t, err)
+		assert.Len(t, res.ID, 32)
+		assert.Equal(t, toEpochMillis(res.CreatedAt), createdAt)
+
+		mockDB.EXPECT().Exec(deletePendingOperationStmt, res.ID).Return(nil, nil)
+		assert.Nil(t, sagaModule.Complete(ctx, res))
+	})
+	t.Run("Complete an operation which has not been recorded", func(t *testing.T) {
+		assert.Nil(t, sagaModule.Complete(ctx, op))
+	})
+}
+
+func TestSagaSetUserID(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+	var mockDB = mock.NewCloudtrustDB(mockCtrl)
+	var mockCrypter = mock.NewEncrypterDecrypter(mockCtrl)
+
+	var sagaModule = NewSagaModule(mockDB, mockCrypter, nil, nil, nil, log.NewNopLogger())
+	var ctx = context.TODO()
+	var userID = "user-id"
+	var op = SagaOperation{ID: "op-id", Kind: SagaCreateUser, Realm: "my-realm", Username: "username"}
+
+	t.Run("Encryption fails", func(t *testing.T) {
+		var cryptError = errors.New("crypt error")
+		mockCrypter.EXPECT().Encrypt(gomock.Any(), []byte(userID)).Return(nil, cryptError)
+		var _, err = sagaModule.SetUserID(ctx, op, userID)
+		assert.Equal(t, cryptError, err)
+	})
+	t.Run("Update fails", func(t *testing.T) {
+		var sqlError = errors.New("sql error")
+		mockCrypter.EXPECT().Encrypt(gomock.Any(), []byte(userID)).Return([]byte("encrypted"), nil)
+		mockDB.EXPECT().Exec(updatePendingOperationUserStmt, userID, []byte("encrypted"), "op-id").Return(nil, sqlError)
+		var _, err = sagaModule.SetUserID(ctx, op, userID)
+		assert.Equal(t, sqlError, err)
+	})
+	t.Run("Success", func(t *testing.T) {
+		mockCrypter.EXPECT().Encrypt(gomock.Any(), []byte(userID)).Return([]byte("encrypted"), nil)
+		mockDB.EXPECT().Exec(updatePendingOperationUserStmt, userID, []byte("encrypted"), "op-id").Return(nil, nil)
+		var res, err = sagaModule.SetUserID(ctx, op, userID)
+		assert.Nil(t, err)
+		assert.Equal(t, userID, res.UserID)
+		assert.Equal(t, "username", res.Username)
+	})
+}
+
+func TestSagaCompensate(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+	var mockDB = mock.NewCloudtrustDB(mockCtrl)
+	var mockKeycloakClient = mock.NewSagaKeycloakClient(mockCtrl)
+	var mockUsersDB = mock.NewUsersDetailsDBModule(mockCtrl)
+
+	var sagaModule = NewSagaModule(mockDB, nil, mockKeycloakClient, mockUsersDB, nil, log.NewNopLogger())
+	var ctx = context.TODO()
+	var accessToken = "TOKEN=="
+	var realm = "my-realm"
+	var userID = "user-id"
+	var kcError = errors.New("kc error")
+
+	t.Run("Create user: Keycloak deletion fails", func(t *testing.T) {
+		var op = SagaOperation{ID: "op-id", Kind: SagaCreateUser, Realm: realm, UserID: userID}
+		mockKeycloakClient.EXPECT().DeleteUser(accessToken, realm, userID).Return(kcError)
+		assert.Equal(t, kcError, sagaModule.Compensate(ctx, accessToken, op))
+	})
+	t.Run("Create user: user already deleted from Keycloak", func(t *testing.T) {
+		var op = SagaOperation{ID: "op-id", Kind: SagaCreateUser, Realm: realm, UserID: userID}
+		mockKeycloakClient.EXPECT().DeleteUser(accessToken, realm, userID).Return(kc.HTTPError{HTTPStatus: http.StatusNotFound})
+		mockUsersDB.EXPECT().DeleteUserDetails(ctx, realm, userID).Return(nil)
+		mockDB.EXPECT().Exec(deletePendingOperationStmt, "op-id").Return(nil, nil)
+		assert.Nil(t, sagaModule.Compensate(ctx, accessToken, op))
+	})
+	t.Run("Create user: operation not recorded", func(t *testing.T) {
+		var op = SagaOperation{Kind: SagaCreateUser, Realm: realm, UserID: userID}
+		mockKeycloakClient.EXPECT().DeleteUser(accessToken, realm, userID).Return(nil)
+		mockUsersDB.EXPECT().DeleteUserDetails(ctx, realm, userID).Return(nil)
+		assert.Nil(t, sagaModule.Compensate(ctx, accessToken, op))
+	})
+	t.Run("Create user: ID not recorded", func(t *testing.T) {
+		var username = "username"
+		var createdAt = time.Now()
+		var before = createdAt.Add(-time.Hour).UnixNano() / int64(time.Millisecond)
+		var after = createdAt.Add(time.Second).UnixNano() / int64(time.Millisecond)
+		var otherUserID = "other-user-id"
+		var upperUsername = "USERNAME"
+		var op = SagaOperation{ID: "op-id", Kind: SagaCreateUser, Realm: realm, Username: username, CreatedAt: createdAt}
+
+		t.Run("Search fails", func(t *testing.T) {
+			mockKeycloakClient.EXPECT().GetUsers(accessToken, realm, realm, "username", username).Return(kc.UsersPageRepresentation{}, kcError)
+			assert.Equal(t, kcError, sagaModule.Compensate(ctx, accessToken, op))
+		})
+		t.Run("User has not been created", func(t *testing.T) {
+			mockKeycloakClient.EXPECT().GetUsers(accessToken, realm, realm, "username", username).Return(kc.UsersPageRepresentation{}, nil)
+			mockDB.EXPECT().Exec(deletePendingOperationStmt, "op-id").Return(nil, nil)
+			assert.Nil(t, sagaModule.Compensate(ctx, accessToken, op))
+		})
+		t.Run("User with the same username existed before the operation", func(t *testing.T) {
+			var users = []kc.UserRepresentation{{ID: &otherUserID, Username: &username, CreatedTimestamp: &before}}
+			mockKeycloakClient.EXPECT().GetUsers(accessToken, realm, realm, "username", username).Return(kc.UsersPageRepresentation{Users: users}, nil)
+			mockDB.EXPECT().Exec(deletePendingOperationStmt, "op-id").Return(nil, nil)
+			assert.Nil(t, sagaModule.Compensate(ctx, accessToken, op))
+		})
+		t.Run("Created user is deleted", func(t *testing.T) {
+			var users = []kc.UserRepresentation{{ID: &otherUserID, Username: &otherUserID, CreatedTimestamp: &after}, {ID: &userID, Username: &upperUsername, CreatedTimestamp: &after}}
+			mockKeycloakClient.EXPECT().GetUsers(accessToken, realm, realm, "username", username).Return(kc.UsersPageRepresentation{Users: users}, nil)
+			mockKeycloakClient.EXPECT().DeleteUser(accessToken, realm, userID).Return(nil)
+			mockUsersDB.EXPECT().DeleteUserDetails(ctx, realm, userID).Return(nil)
+			mockDB.EXPECT().Exec(deletePendingOperationStmt, "op-id").Return(nil, nil)
+			assert.Nil(t, sagaModule.Compensate(ctx, accessToken, op))
+		})
+	})
+	t.Run("Update user", func(t *testing.T) {
+		var previousUser = kc.UserRepresentation{ID: &userID}
+		var previousDetails = dto.DBUser{UserID: &userID}
+		var op = SagaOperation{ID: "op-id", Kind: SagaUpdateUser, Realm: realm, UserID: userID, PreviousUser: &previousUser, PreviousDetails: &previousDetails}
+		mockKeycloakClient.EXPECT().UpdateUser(accessToken, realm, userID, previousUser).Return(nil)
+		mockUsersDB.EXPECT().StoreOrUpdateUserDetails(ctx, realm, previousDetails).Return(nil)
+		mockDB.EXPECT().Exec(deletePendingOperationStmt, "op-id").Return(nil, nil)
+		assert.Nil(t, sagaModule.Compensate(ctx, accessToken, op))
+	})
+	t.Run("Update user: Keycloak update fails", func(t *testing.T) {
+		var previousUser = kc.UserRepresentation{ID: &userID}
+		var op = SagaOperation{ID: "op-id", Kind: SagaUpdateUser, Realm: realm, UserID: userID, PreviousUser: &previousUser}
+		mockKeycloakClient.EXPECT().UpdateUser(accessToken, realm, userID, previousUser).Return(kcError)
+		assert.Equal(t, kcError, sagaModule.Compensate(ctx, accessToken, op))
+	})
+	t.Run("Delete user", func(t *testing.T) {
+		var op = SagaOperation{ID: "op-id", Kind: SagaDeleteUser, Realm: realm, UserID: userID}
+		mockUsersDB.EXPECT().DeleteUserDetails(ctx, realm, userID).Return(nil)
+		mockDB.EXPECT().Exec(deletePendingOperationStmt, "op-id").Return(nil, nil)
+		assert.Nil(t, sagaModule.Compensate(ctx, accessToken, op))
+	})
+	t.Run("Unknown kind", func(t *testing.T) {
+		assert.NotNil(t, sagaModule.Compensate(ctx, accessToken, SagaOperation{Kind: "UNKNOWN"}))
+	})
+}
+
+func TestSagaReconcile(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+	var mockDB = mock.NewCloudtrustDB(mockCtrl)
+	var mockSQLRows = mock.NewSQLRows(mockCtrl)
+	var mockCrypter = mock.NewEncrypterDecrypter(mockCtrl)
+	var mockKeycloakClient = mock.NewSagaKeycloakClient(mockCtrl)
+	var mockUsersDB = mock.NewUsersDetailsDBModule(mockCtrl)
+	var mockTokenProvider = mock.NewOidcTokenProvider(mockCtrl)
+
+	var sagaModule = NewSagaModule(mockDB, mockCrypter, mockKeycloakClient, mockUsersDB, mockTokenProvider, log.NewNopLogger())
+	var ctx = context.TODO()
+	var accessToken = "TOKEN=="
+	var realm = "my-realm"
+	var userID = "user-id"
+	var birthLocation = "Lausanne"
+
+	var expectPendingOperations = func(ops ...SagaOperation) {
+		var calls = []*gomock.Call{mockDB.EXPECT().Query(selectPendingOperationsStmt, gomock.Any()).Return(mockSQLRows, nil)}
+		for i, op := range ops {
+			var id = string(rune('a' + i))
+			var k = op.Kind
+			calls = append(calls, mockSQLRows.EXPECT().Next().Return(true))
+			calls = append(calls, mockSQLRows.EXPECT().Scan(gomock.Any()).DoAndReturn(
+				func(opID, opRealm, opUserID, opKind *string, data *[]byte, createdAt *int64) error {
+					*opID = id
+					*opRealm = realm
+					*opUserID = userID
+					*opKind = k
+					*data = []byte("encrypted")
+					*createdAt = toEpochMillis(time.Now().Add(-time.Hour))
+					return nil
+				}))
+			calls = append(calls, mockCrypter.EXPECT().Decrypt([]byte("encrypted"), []byte(userID)).Return(json.Marshal(op)))
+		}
+		calls = append(calls, mockSQLRows.EXPECT().Next().Return(false))
+		gomock.InOrder(calls...)
+		mockSQLRows.EXPECT().Close().Return(nil)
+		mockSQLRows.EXPECT().Err().Return(nil)
+	}
+
+	t.Run("Query fails", func(t *testing.T) {
+		var sqlError = errors.New("sql error")
+		mockDB.EXPECT().Query(selectPendingOperationsStmt, gomock.Any()).Return(nil, sqlError)
+		assert.Equal(t, sqlError, sagaModule.Reconcile(ctx, time.Minute))
+	})
+	t.Run("No pending operation", func(t *testing.T) {
+		expectPendingOperations()
+		assert.Nil(t, sagaModule.Reconcile(ctx, time.Minute))
+	})
+	t.Run("Can't get token", func(t *testing.T) {
+		var tokenError = errors.New("token error")
+		expectPendingOperations(SagaOperation{Kind: SagaDeleteUser})
+		mockTokenProvider.EXPECT().ProvideToken(ctx).Return("", tokenError)
+		assert.Equal(t, tokenError, sagaModule.Reconcile(ctx, time.Minute))
+	})
+	t.Run("Created user with details is completed, created user without details is deleted", func(t *testing.T) {
+		expectPendingOperations(SagaOperation{Kind: SagaCreateUser}, SagaOperation{Kind: SagaCreateUser})
+		mockTokenProvider.EXPECT().ProvideToken(ctx).Return(accessToken, nil)
+		gomock.InOrder(
+			mockUsersDB.EXPECT().GetUserDetails(ctx, realm, userID).Return(dto.DBUser{UserID: &userID, BirthLocation: &birthLocation}, nil),
+			mockDB.EXPECT().Exec(deletePendingOperationStmt, "a").Return(nil, nil),
+			mockUsersDB.EXPECT().GetUserDetails(ctx, realm, userID).Return(dto.DBUser{UserID: &userID}, nil),
+			mockKeycloakClient.EXPECT().DeleteUser(accessToken, realm, userID).Return(nil),
+			mockUsersDB.EXPECT().DeleteUserDetails(ctx, realm, userID).Return(nil),
+			mockDB.EXPECT().Exec(deletePendingOperationStmt, "b").Return(nil, nil),
+		)
+		assert.Nil(t, sagaModule.Reconcile(ctx, time.Minute))
+	})
+	t.Run("Existing user keeps its details, details of deleted user are deleted", func(t *testing.T) {
+		expectPendingOperations(SagaOperation{Kind: SagaDeleteUser}, SagaOperation{Kind: SagaDeleteUser})
+		mockTokenProvider.EXPECT().ProvideToken(ctx).Return(accessToken, nil)
+		gomock.InOrder(
+			mockKeycloakClient.EXPECT().GetUser(accessToken, realm, userID).Return(kc.UserRepresentation{ID: &userID}, nil),
+			mockDB.EXPECT().Exec(deletePendingOperationStmt, "a").Return(nil, nil),
+			mockKeycloakClient.EXPECT().GetUser(accessToken, realm, userID).Return(kc.UserRepresentation{}, kc.HTTPError{HTTPStatus: http.StatusNotFound}),
+			mockUsersDB.EXPECT().DeleteUserDetails(ctx, realm, userID).Return(nil),
+			mockDB.EXPECT().Exec(deletePendingOperationStmt, "b").Return(nil, nil),
+		)
+		assert.Nil(t, sagaModule.Reconcile(ctx, time.Minute))
+	})
+	t.Run("Updated user", func(t *testing.T) {
+		var previousName, targetName, laterName = "John", "Jane", "Jo"
+		var previousBirthLocation = "Geneva"
+		var previousUser = kc.UserRepresentation{ID: &userID, FirstName: &previousName}
+		var targetUser = kc.UserRepresentation{ID: &userID, FirstName: &targetName}
+		var previousDetails = dto.DBUser{UserID: &userID, BirthLocation: &previousBirthLocation}
+		var targetDetails = dto.DBUser{UserID: &userID, BirthLocation: &birthLocation}
+		var update = SagaOperation{Kind: SagaUpdateUser, PreviousUser: &previousUser, PreviousDetails: &previousDetails,
+			TargetUser: &targetUser, TargetDetails: &targetDetails}
+
+		t.Run("Written state is completed, Keycloak holding the written state without the details is restored", func(t *testing.T) {
+			expectPendingOperations(update, update)
+			mockTokenProvider.EXPECT().ProvideToken(ctx).Return(accessToken, nil)
+			gomock.InOrder(
+				mockKeycloakClient.EXPECT().GetUser(accessToken, realm, userID).Return(targetUser, nil),
+				mockUsersDB.EXPECT().GetUserDetails(ctx, realm, userID).Return(targetDetails, nil),
+				mockDB.EXPECT().Exec(deletePendingOperationStmt, "a").Return(nil, nil),
+				mockKeycloakClient.EXPECT().GetUser(accessToken, realm, userID).Return(targetUser, nil),
+				mockUsersDB.EXPECT().GetUserDetails(ctx, realm, userID).Return(previousDetails, nil),
+				mockKeycloakClient.EXPECT().UpdateUser(accessToken, realm, userID, previousUser).Return(nil),
+				mockDB.EXPECT().Exec(deletePendingOperationStmt, "b").Return(nil, nil),
+			)
+			assert.Nil(t, sagaModule.Reconcile(ctx, time.Minute))
+		})
+		t.Run("User not updated or changed since is not touched", func(t *testing.T) {
+			expectPendingOperations(update, update, SagaOperation{Kind: SagaUpdateUser, PreviousUser: &previousUser})
+			mockTokenProvider.EXPECT().ProvideToken(ctx).Return(accessToken, nil)
+			gomock.InOrder(
+				mockKeycloakClient.EXPECT().GetUser(accessToken, realm, userID).Return(previousUser, nil),
+				mockDB.EXPECT().Exec(deletePendingOperationStmt, "a").Return(nil, nil),
+				mockKeycloakClient.EXPECT().GetUser(accessToken, realm, userID).Return(kc.UserRepresentation{ID: &userID, FirstName: &laterName}, nil),
+				mockDB.EXPECT().Exec(deletePendingOperationStmt, "b").Return(nil, nil),
+				mockDB.EXPECT().Exec(deletePendingOperationStmt, "c").Return(nil, nil),
+			)
+			assert.Nil(t, sagaModule.Reconcile(ctx, time.Minute))
+		})
+		t.Run("Can't get user", func(t *testing.T) {
+			var kcError = errors.New("keycloak error")
+			expectPendingOperations(update)
+			mockTokenProvider.EXPECT().ProvideToken(ctx).Return(accessToken, nil)
+			mockKeycloakClient.EXPECT().GetUser(accessToken, realm, userID).Return(kc.UserRepresentation{}, kcError)
+			// The operation is processed again by the next reconciliation
+			assert.Nil(t, sagaModule.Reconcile(ctx, time.Minute))
+		})
+	})
+}
@@ -0,0 +1,216 @@
+package keycloakb
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cloudtrust/common-service/database"
+	"github.com/cloudtrust/keycloak-bridge/internal/constants"
+	"github.com/cloudtrust/keycloak-bridge/internal/dto"
+	kc "github.com/cloudtrust/keycloak-client"
+	"github.com/cloudtrust/keycloak-client/toolbox"
+)
+
+// TrustIDGroupAssignment is the assignment of a trustID group to a user. The names of the groups are stored in the
+// trustIDGroups attribute. Assignments with an expiry date (unix timestamp) or a justification are also stored as JSON
+// in the trustIDGroupsExpiry attribute
+type TrustIDGroupAssignment struct {
+	Group         string  `json:"group"`
+	ExpiresAt     *int64  `json:"expiresAt,omitempty"`
+	Justification *string `json:"justification,omitempty"`
+	GrantedBy     *string `json:"grantedBy,omitempty"`
+}
+
+// TrustIDGroupAssignmentMaxLength is the maximum length of an assignment stored as JSON: Keycloak stores each value of
+// an attribute in a column of 255 characters
+const TrustIDGroupAssignmentMaxLength = 255
+
+// FitsInAttribute checks if the assignment can be stored as a single value of the trustIDGroupsExpiry attribute
+func (a TrustIDGroupAssignment) FitsInAttribute() bool {
+	var bytes, err = json.Marshal(a)
+	return err == nil && len(bytes) <= TrustIDGroupAssignmentMaxLength
+}
+
+// IsExpired checks if the assignment expired at the given time
+func (a TrustIDGroupAssignment) IsExpired(now time.Time) bool {
+	return a.ExpiresAt != nil && *a.ExpiresAt <= now.Unix()
+}
+
+// GetTrustIDGroupAssignments returns the trustID groups of a user (without leading slash) with their expiry information
+func GetTrustIDGroupAssignments(kcUser kc.UserRepresentation) []TrustIDGroupAssignment {
+	var details = make(map[string]TrustIDGroupAssignment)
+	for _, value := range kcUser.GetAttribute(constants.AttrbTrustIDGroupsExpiry) {
+		var assignment TrustIDGroupAssignment
+		if err := json.Unmarshal([]byte(value), &assignment); err == nil {
+			details[assignment.Group] = assignment
+		}
+	}
+
+	var res = make([]TrustIDGroupAssignment, 0)
+	for _, grp := range kcUser.GetAttribute(constants.AttrbTrustIDGroups) {
+		grp = strings.TrimPrefix(grp, "/")
+		if assignment, ok := details[grp]; ok {
+			res = append(res, assignment)
+		} else {
+			res = append(res, TrustIDGroupAssignment{Group: grp})
+		}
+	}
+	return res
+}
+
+// SetTrustIDGroupAssignments writes the trustID groups of a user and their expiry information in its attributes
+func SetTrustIDGroupAssignments(kcUser *kc.UserRepresentation, assignments []TrustIDGroupAssignment) {
+	if kcUser.Attributes == nil {
+		var emptyMap = make(kc.Attributes)
+		kcUser.Attributes = &emptyMap
+	}
+
+	var groups, details []string
+	for _, assignment := range assignments {
+		groups = append(groups, "/"+assignment.Group)
+		if assignment.ExpiresAt != nil || assignment.Justification != nil {
+			var bytes, _ = json.Marshal(assignment)
+			details = append(details, string(bytes))
+		}
+	}
+
+	(*kcUser.Attributes)[constants.AttrbTrustIDGroups] = groups
+	if len(details) == 0 {
+		delete(*kcUser.Attributes, constants.AttrbTrustIDGroupsExpiry)
+	} else {
+		(*kcUser.Attributes)[constants.AttrbTrustIDGroupsExpiry] = details
+	}
+}
+
+// GetTrustIDGroupsExpiries returns the expiry dates of the given assignments, as indexed in the users database
+func GetTrustIDGroupsExpiries(realm, userID string, assignments []TrustIDGroupAssignment) []dto.DBTrustIDGroupExpiry {
+	var res []dto.DBTrustIDGroupExpiry
+	for _, assignment := range assignments {
+		if assignment.ExpiresAt != nil {
+			res = append(res, dto.DBTrustIDGroupExpiry{
+				RealmID:   realm,
+				UserID:    userID,
+				GroupName: assignment.Group,
+				ExpiresAt: *assignment.ExpiresAt,
+			})
+		}
+	}
+	return res
+}
+
+// TrustIDGroupsKeycloakClient is the minimum Keycloak client interface used to remove expired trustID groups
+type TrustIDGroupsKeycloakClient interface {
+	GetUser(accessToken string, realmName, userID string) (kc.UserRepresentation, error)
+	UpdateUser(accessToken string, realmName, userID string, user kc.UserRepresentation) error
+}
+
+// TrustIDGroupsEventsDBModule is the minimum events module interface used to audit the expiry of trustID groups
+type TrustIDGroupsEventsDBModule interface {
+	ReportEvent(ctx context.Context, apiCall string, origin string, values ...string) error
+}
+
+// TrustIDGroupsModule removes the trustID groups assigned for a limited time once they expired. The expired
+// assignments are found in the users database and each removal is recorded as a TRUSTID_GROUP_EXPIRED event
+type TrustIDGroupsModule interface {
+	RemoveExpired(ctx context.Context) error
+}
+
+type trustIDGroupsModule struct {
+	keycloakClient TrustIDGroupsKeycloakClient
+	usersDBModule  UsersDetailsDBModule
+	eventsDBModule TrustIDGroupsEventsDBModule
+	tokenProvider  toolbox.OidcTokenProvider
+	logger         Logger
+}
+
+// NewTrustIDGroupsModule returns a trustID groups module. The token provider is used by RemoveExpired, which runs without any caller
+func NewTrustIDGroupsModule(keycloakClient TrustIDGroupsKeycloakClient, usersDBModule UsersDetailsDBModule, eventsDBModule TrustIDGroupsEventsDBModule,
+	tokenProvider toolbox.OidcTokenProvider, logger Logger) TrustIDGroupsModule {
+	return &trustIDGroupsModule{
+		keycloakClient: keycloakClient,
+		usersDBModule:  usersDBModule,
+		eventsDBModule: eventsDBModule,
+		tokenProvider:  tokenProvider,
+		logger:         logger,
+	}
+}
+
+// RemoveExpired removes the expired trustID groups from the users. Several instances may run concurrently: a group
+// which has already been removed is not reported again
+func (tm *trustIDGroupsModule) RemoveExpired(ctx context.Context) error {
+	var now = time.Now()
+	var expiries, err = tm.usersDBModule.GetExpiredTrustIDGroups(ctx, now)
+	if err != nil {
+		tm.logger.Warn(ctx, "msg", "Can't get expired trustID groups", "err", err.Error())
+		return err
+	}
+	if len(expiries) == 0 {
+		return nil
+	}
+
+	accessToken, err := tm.tokenProvider.ProvideToken(ctx)
+	if err != nil {
+		tm.logger.Warn(ctx, "msg", "Can't get OIDC token", "err", err.Error())
+		return err
+	}
+
+	var processed = make(map[string]bool)
+	for _, expiry := range expiries {
+		var key = expiry.RealmID + "/" + expiry.UserID
+		if processed[key] {
+			continue
+		}
+		processed[key] = true
+		// Errors are logged: the user will be processed again by the next run
+		_ = tm.removeExpired(ctx, accessToken, expiry.RealmID, expiry.UserID, now)
+	}
+	return nil
+}
+
+func (tm *trustIDGroupsModule) removeExpired(ctx context.Context, accessToken string, realm, userID string, now time.Time) error {
+	var kcUser, err = tm.keycloakClient.GetUser(accessToken, realm, userID)
+	if err != nil {
+		if !isNotFound(err) {
+			tm.logger.Warn(ctx, "msg", "Can't get user from Keycloak", "err", err.Error(), "realmID", realm, "userID", userID)
+			return err
+		}
+		// The user no longer exists: its expiry dates are not needed anymore
+		return tm.usersDBModule.UpdateTrustIDGroupsExpiries(ctx, realm, userID, nil)
+	}
+	ConvertLegacyAttribute(&kcUser)
+
+	var kept, expired []TrustIDGroupAssignment
+	for _, assignment := range GetTrustIDGroupAssignments(kcUser) {
+		if assignment.IsExpired(now) {
+			expired = append(expired, assignment)
+		} else {
+			kept = append(kept, assignment)
+		}
+	}
+
+	if len(expired) > 0 {
+		SetTrustIDGroupAssignments(&kcUser, kept)
+		if err = tm.keycloakClient.UpdateUser(accessToken, realm, userID, kcUser); err != nil {
+			tm.logger.Warn(ctx, "msg", "Can't remove expired trustID groups", "err", err.Error(), "realmID", realm, "userID", userID)
+			return err
+		}
+		for _, assignment := range expired {
+			tm.reportEvent(ctx, "TRUSTID_GROUP_EXPIRED", database.CtEventRealmName, realm, database.CtEventUserID, userID,
+				database.CtEventAdditionalInfo, database.CreateAdditionalInfo("trustIDGroup", assignment.Group, "expiresAt", strconv.FormatInt(*assignment.ExpiresAt, 10)))
+		}
+		tm.logger.Info(ctx, "msg", "Expired trustID groups removed", "realmID", realm, "userID", userID, "count", len(expired))
+	}
+
+	// The index is rebuilt from the attributes of the user, which are the reference
+	return tm.usersDBModule.UpdateTrustIDGroupsExpiries(ctx, realm, userID, GetTrustIDGroupsExpiries(realm, userID, kept))
+}
+
+func (tm *trustIDGroupsModule) reportEvent(ctx context.Context, apiCall string, values ...string) {
+	if err := tm.eventsDBModule.ReportEvent(ctx, apiCall, "back-office", values...); err != nil {
+		//store in the logs also the event that failed to be stored in the DB
+		LogUnrecordedEvent(ctx, tm.logger, apiCall, err.Error(), values...)
+	}
+}
@@ -0,0 +1,148 @@
+package keycloakb
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cloudtrust/common-service/log"
+	"github.com/cloudtrust/keycloak-bridge/internal/constants"
+	"github.com/cloudtrust/keycloak-bridge/internal/dto"
+	"github.com/cloudtrust/keycloak-bridge/internal/keycloakb/mock"
+	kc "github.com/cloudtrust/keycloak-client"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTrustIDGroupAssignments(t *testing.T) {
+	var expiresAt = int64(1600000000)
+	var justification = "support ticket #42"
+
+	t.Run("Get assignments", func(t *testing.T) {
+		var attributes = kc.Attributes{
+			constants.AttrbTrustIDGroups:       []string{"/grp1", "grp2"},
+			constants.AttrbTrustIDGroupsExpiry: []string{`{"group":"grp1","expiresAt":1600000000}`, "not JSON"},
+		}
+		var assignments = GetTrustIDGroupAssignments(kc.UserRepresentation{Attributes: &attributes})
+		assert.Len(t, assignments, 2)
+		assert.Equal(t, "grp1", assignments[0].Group)
+		assert.Equal(t, expiresAt, *assignments[0].ExpiresAt)
+		assert.Equal(t, TrustIDGroupAssignment{Group: "grp2"}, assignments[1])
+	})
+	t.Run("No trustID groups", func(t *testing.T) {
+		assert.Len(t, GetTrustIDGroupAssignments(kc.UserRepresentation{}), 0)
+	})
+	t.Run("Set assignments", func(t *testing.T) {
+		var kcUser kc.UserRepresentation
+		SetTrustIDGroupAssignments(&kcUser, []TrustIDGroupAssignment{{Group: "grp1", ExpiresAt: &expiresAt, Justification: &justification}, {Group: "grp2"}})
+		assert.Equal(t, []string{"/grp1", "/grp2"}, kcUser.GetAttribute(constants.AttrbTrustIDGroups))
+		assert.Len(t, kcUser.GetAttribute(constants.AttrbTrustIDGroupsExpiry), 1)
+		assert.Equal(t, []TrustIDGroupAssignment{{Group: "grp1", ExpiresAt: &expiresAt, Justification: &justification}, {Group: "grp2"}}, GetTrustIDGroupAssignments(kcUser))
+
+		SetTrustIDGroupAssignments(&kcUser, []TrustIDGroupAssignment{{Group: "grp2"}})
+		assert.Nil(t, kcUser.GetAttribute(constants.AttrbTrustIDGroupsExpiry))
+	})
+	t.Run("Assignment stored in a single attribute value", func(t *testing.T) {
+		var grantedBy = "admin"
+		var emptyJustification = ""
+		var bytes, _ = json.Marshal(TrustIDGroupAssignment{Group: "grp1", ExpiresAt: &expiresAt, Justification: &emptyJustification, GrantedBy: &grantedBy})
+		var longest = strings.Repeat("a", TrustIDGroupAssignmentMaxLength-len(bytes))
+		var tooLong = longest + "a"
+
+		assert.True(t, TrustIDGroupAssignment{Group: "grp1", ExpiresAt: &expiresAt, Justification: &longest, GrantedBy: &grantedBy}.FitsInAttribute())
+		assert.False(t, TrustIDGroupAssignment{Group: "grp1", ExpiresAt: &expiresAt, Justification: &tooLong, GrantedBy: &grantedBy}.FitsInAttribute())
+	})
+	t.Run("Expiries", func(t *testing.T) {
+		var expiries = GetTrustIDGroupsExpiries("realm", "user", []TrustIDGroupAssignment{{Group: "grp1", ExpiresAt: &expiresAt}, {Group: "grp2"}})
+		assert.Equal(t, []dto.DBTrustIDGroupExpiry{{RealmID: "realm", UserID: "user", GroupName: "grp1", ExpiresAt: expiresAt}}, expiries)
+	})
+}
+
+func TestTrustIDGroupsRemoveExpired(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+	var mockKeycloakClient = mock.NewTrustIDGroupsKeycloakClient(mockCtrl)
+	var mockUsersDB = mock.NewUsersDetailsDBModule(mockCtrl)
+	var mockEventsDB = mock.NewTrustIDGroupsEventsDBModule(mockCtrl)
+	var mockTokenProvider = mock.NewOidcTokenProvider(mockCtrl)
+
+	var trustIDGroupsModule = NewTrustIDGroupsModule(mockKeycloakClient, mockUsersDB, mockEventsDB, mockTokenProvider, log.NewNopLogger())
+	var ctx = context.TODO()
+	var accessToken = "TOKEN=="
+	var realm = "my-realm"
+	var userID = "user-id"
+	var past = time.Now().Add(-time.Hour).Unix()
+	var future = time.Now().Add(time.Hour).Unix()
+	var expired = []dto.DBTrustIDGroupExpiry{
+		{RealmID: realm, UserID: userID, GroupName: "grp1", ExpiresAt: past},
+		{RealmID: realm, UserID: userID, GroupName: "grp2", ExpiresAt: past},
+	}
+	var anyError = errors.New("error")
+
+	var createUser = func() kc.UserRepresentation {
+		var kcUser = kc.UserRepresentation{ID: &userID}
+		SetTrustIDGroupAssignments(&kcUser, []TrustIDGroupAssignment{
+			{Group: "grp1", ExpiresAt: &past},
+			{Group: "grp2", ExpiresAt: &past},
+			{Group: "grp3", ExpiresAt: &future},
+			{Group: "grp4"},
+		})
+		return kcUser
+	}
+
+	t.Run("Can't get expired trustID groups", func(t *testing.T) {
+		mockUsersDB.EXPECT().GetExpiredTrustIDGroups(ctx, gomock.Any()).Return(nil, anyError)
+		assert.Equal(t, anyError, trustIDGroupsModule.RemoveExpired(ctx))
+	})
+	t.Run("No expired trustID group", func(t *testing.T) {
+		mockUsersDB.EXPECT().GetExpiredTrustIDGroups(ctx, gomock.Any()).Return(nil, nil)
+		assert.Nil(t, trustIDGroupsModule.RemoveExpired(ctx))
+	})
+	t.Run("Can't get token", func(t *testing.T) {
+		mockUsersDB.EXPECT().GetExpiredTrustIDGroups(ctx, gomock.Any()).Return(expired, nil)
+		mockTokenProvider.EXPECT().ProvideToken(ctx).Return("", anyError)
+		assert.Equal(t, anyError, trustIDGroupsModule.RemoveExpired(ctx))
+	})
+	t.Run("Can't get user: will be processed by next run", func(t *testing.T) {
+		mockUsersDB.EXPECT().GetExpiredTrustIDGroups(ctx, gomock.Any()).Return(expired, nil)
+		mockTokenProvider.EXPECT().ProvideToken(ctx).Return(accessToken, nil)
+		mockKeycloakClient.EXPECT().GetUser(accessToken, realm, userID).Return(kc.UserRepresentation{}, anyError).Times(1)
+		assert.Nil(t, trustIDGroupsModule.RemoveExpired(ctx))
+	})
+	t.Run("User does not exist anymore", func(t *testing.T) {
+		mockUsersDB.EXPECT().GetExpiredTrustIDGroups(ctx, gomock.Any()).Return(expired, nil)
+		mockTokenProvider.EXPECT().ProvideToken(ctx).Return(accessToken, nil)
+		mockKeycloakClient.EXPECT().GetUser(accessToken, realm, userID).Return(kc.UserRepresentation{}, kc.HTTPError{HTTPStatus: http.StatusNotFound})
+		mockUsersDB.EXPECT().UpdateTrustIDGroupsExpiries(ctx, realm, userID, nil).Return(nil)
+		assert.Nil(t, trustIDGroupsModule.RemoveExpired(ctx))
+	})
+	t.Run("Can't update user", func(t *testing.T) {
+		mockUsersDB.EXPECT().GetExpiredTrustIDGroups(ctx, gomock.Any()).Return(expired, nil)
+		mockTokenProvider.EXPECT().ProvideToken(ctx).Return(accessToken, nil)
+		mockKeycloakClient.EXPECT().GetUser(accessToken, realm, userID).Return(createUser(), nil)
+		mockKeycloakClient.EXPECT().UpdateUser(accessToken, realm, userID, gomock.Any()).Return(anyError)
+		assert.Nil(t, trustIDGroupsModule.RemoveExpired(ctx))
+	})
+	t.Run("Expired groups are removed and reported", func(t *testing.T) {
+		mockUsersDB.EXPECT().GetExpiredTrustIDGroups(ctx, gomock.Any()).Return(expired, nil)
+		mockTokenProvider.EXPECT().ProvideToken(ctx).Return(accessToken, nil)
+		mockKeycloakClient.EXPECT().GetUser(accessToken, realm, userID).Return(createUser(), nil)
+		mockKeycloakClient.EXPECT().UpdateUser(accessToken, realm, userID, gomock.Any()).DoAndReturn(
+			func(_, _, _ string, kcUser kc.UserRepresentation) error {
+				var assignments = GetTrustIDGroupAssignments(kcUser)
+				assert.Len(t, assignments, 2)
+				assert.Equal(t, "grp3", assignments[0].Group)
+				assert.Equal(t, "grp4", assignments[1].Group)
+				return nil
+			})
+		mockEventsDB.EXPECT().ReportEvent(ctx, "TRUSTID_GROUP_EXPIRED", "back-office", gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).Times(2)
+		mockUsersDB.EXPECT().UpdateTrustIDGroupsExpiries(ctx, realm, userID, []dto.DBTrustIDGroupExpiry{
+			{RealmID: realm, UserID: userID, GroupName: "grp3", ExpiresAt: future},
+		}).Return(nil)
+		assert.Nil(t, trustIDGroupsModule.RemoveExpired(ctx))
+	})
+}
@@ -0,0 +1,44 @@
+package keycloakb
+
+import (
+	"github.com/cloudtrust/keycloak-bridge/internal/constants"
+	"github.com/cloudtrust/keycloak-bridge/internal/dto"
+	kc "github.com/cloudtrust/keycloak-client"
+)
+
+// Channels through which the identity of a user is changed
+const (
+	HistoryChannelManagement = "management"
+	HistoryChannelAccount    = "account"
+	HistoryChannelKYC        = "kyc"
+)
+
+// GetIdentityChanges compares the identity fields of a user before and after an update. A field which is not given in
+// the updated user is not changed
+func GetIdentityChanges(oldUser kc.UserRepresentation, oldDetails dto.DBUser, newUser kc.UserRepresentation, newDetails dto.DBUser) []dto.DBFieldChange {
+	var fields = []struct {
+		name     string
+		oldValue *string
+		newValue *string
+	}{
+		{"username", oldUser.Username, newUser.Username},
+		{"email", oldUser.Email, newUser.Email},
+		{"firstName", oldUser.FirstName, newUser.FirstName},
+		{"lastName", oldUser.LastName, newUser.LastName},
+		{"phoneNumber", oldUser.GetAttributeString(constants.AttrbPhoneNumber), newUser.GetAttributeString(constants.AttrbPhoneNumber)},
+		{"gender", oldUser.GetAttributeString(constants.AttrbGender), newUser.GetAttributeString(constants.AttrbGender)},
+		{"birthDate", oldUser.GetAttributeString(constants.AttrbBirthDate), newUser.GetAttributeString(constants.AttrbBirthDate)},
+		{"birthLocation", oldDetails.BirthLocation, newDetails.BirthLocation},
+		{"idDocumentType", oldDetails.IDDocumentType, newDetails.IDDocumentType},
+		{"idDocumentNumber", oldDetails.IDDocumentNumber, newDetails.IDDocumentNumber},
+		{"idDocumentExpiration", oldDetails.IDDocumentExpiration, newDetails.IDDocumentExpiration},
+	}
+
+	var changes []dto.DBFieldChange
+	for _, field := range fields {
+		if field.newValue != nil && (field.oldValue == nil || *field.oldValue != *field.newValue) {
+			changes = append(changes, dto.DBFieldChange{Field: field.name, OldValue: field.oldValue, NewValue: field.newValue})
+		}
+	}
+	return changes
+}
@@ -0,0 +1,38 @@
+package keycloakb
+
+import (
+	"testing"
+
+	"github.com/cloudtrust/keycloak-bridge/internal/constants"
+	"github.com/cloudtrust/keycloak-bridge/internal/dto"
+	kc "github.com/cloudtrust/keycloak-client"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetIdentityChanges(t *testing.T) {
+	var ptr = func(value string) *string {
+		return &value
+	}
+	var oldAttributes = make(kc.Attributes)
+	oldAttributes.SetString(constants.AttrbPhoneNumber, "+41791234567")
+	var oldUser = kc.UserRepresentation{Username: ptr("jdoe"), FirstName: ptr("John"), LastName: ptr("Doe"), Attributes: &oldAttributes}
+	var oldDetails = dto.DBUser{IDDocumentNumber: ptr("AB123456")}
+
+	t.Run("No change", func(t *testing.T) {
+		assert.Len(t, GetIdentityChanges(oldUser, oldDetails, oldUser, oldDetails), 0)
+		assert.Len(t, GetIdentityChanges(oldUser, oldDetails, kc.UserRepresentation{}, dto.DBUser{}), 0)
+	})
+	t.Run("Changes", func(t *testing.T) {
+		var newAttributes = make(kc.Attributes)
+		newAttributes.SetString(constants.AttrbPhoneNumber, "+41797654321")
+		var newUser = kc.UserRepresentation{Username: ptr("jdoe"), LastName: ptr("Smith"), Attributes: &newAttributes}
+		var newDetails = dto.DBUser{IDDocumentNumber: ptr("AB123456"), IDDocumentType: ptr("PASSPORT")}
+
+		var changes = GetIdentityChanges(oldUser, oldDetails, newUser, newDetails)
+		assert.Equal(t, []dto.DBFieldChange{
+			{Field: "lastName", OldValue: ptr("Doe"), NewValue: ptr("Smith")},
+			{Field: "phoneNumber", OldValue: ptr("+41791234567"), NewValue: ptr("+41797654321")},
+			{Field: "idDocumentType", OldValue: nil, NewValue: ptr("PASSPORT")},
+		}, changes)
+	})
+}
@@ -15,9 +15,9 @@ import (
 )
 
 const (
-	updateUserDetailsStmt = `INSERT INTO user_details (realm_id, user_id, details)
-	  VALUES (?, ?, ?) 
-	  ON DUPLICATE KEY UPDATE details=?;`
+	updateUserDetailsStmt = `INSERT INTO user_details (realm_id, user_id, details, id_document_index)
+	  VALUES (?, ?, ?, ?) 
+	  ON DUPLICATE KEY UPDATE details=?, id_document_index=?;`
 	selectUserDetailsStmt = `
 	  SELECT details
 	  FROM user_details
@@ -31,21 +31,97 @@ const (
 	  FROM checks
 	  WHERE realm_id=?
 		AND user_id=?;`
+	deleteTrustIDGroupsExpiriesStmt = `DELETE FROM trustid_groups_expiry WHERE realm_id=? AND user_id=?;`
+	createTrustIDGroupExpiryStmt    = `INSERT INTO trustid_groups_expiry (realm_id, user_id, group_name, expires_at)
+	  VALUES (?, ?, ?, ?);`
+	selectExpiredTrustIDGroupsStmt = `
+	  SELECT realm_id, user_id, group_name, expires_at
+	  FROM trustid_groups_expiry
+	  WHERE expires_at<=?;`
+	deleteChecksStmt            = `DELETE FROM checks WHERE realm_id=? AND user_id=?;`
+	selectUsersByIDDocumentStmt = `
+	  SELECT user_id
+	  FROM user_details
+	  WHERE realm_id=?
+		AND id_document_index=?;`
+	selectUnindexedUserDetailsStmt = `
+	  SELECT realm_id, user_id, details
+	  FROM user_details
+	  WHERE id_document_index IS NULL
+		AND (realm_id, user_id) > (?, ?)
+	  ORDER BY realm_id, user_id
+	  LIMIT ?;`
+	updateIDDocumentIndexStmt = `UPDATE user_details SET id_document_index=? WHERE realm_id=? AND user_id=? AND details=? AND id_document_index IS NULL;`
+	createUserChangeStmt      = `INSERT INTO user_history (realm_id, user_id, changed_at, actor, channel, changes)
+	  VALUES (?, ?, ?, ?, ?, ?);`
+	selectUserHistoryStmt = `
+	  SELECT changed_at, actor, channel, changes
+	  FROM user_history
+	  WHERE realm_id=?
+		AND user_id=?
+	  ORDER BY changed_at DESC, id DESC;`
 )
 
 // UsersDetailsDBModule interface
+//
+// The expiry dates of the trustID groups assigned for a limited time are indexed so that expired assignments can be
+// found without going through all the users of Keycloak:
+//
+//	CREATE TABLE trustid_groups_expiry (
+//	  realm_id VARCHAR(255) NOT NULL,
+//	  user_id CHAR(36) NOT NULL,
+//	  group_name VARCHAR(255) NOT NULL,
+//	  expires_at BIGINT NOT NULL,
+//	  PRIMARY KEY (realm_id, user_id, group_name),
+//	  INDEX (expires_at)
+//	);
+//
+// The ID document number is encrypted with the other details of the user. A blind index of this number is stored
+// beside so that users can be found by document number:
+//
+//	ALTER TABLE user_details ADD COLUMN id_document_index VARBINARY(32) NULL, ADD INDEX (realm_id, id_document_index);
+//
+// Details stored before the column was added are indexed by IndexIDDocumentNumbers, run at startup when
+// db-blind-index-backfill is enabled. It has to be run once after the migration, and again whenever the blind index key
+// changes (after setting the column back to NULL).
+//
+// The changes of the identity fields of the users are kept for the KYC audit trail. The former and new values are
+// encrypted as the details of the users:
+//
+//	CREATE TABLE user_history (
+//	  id BIGINT NOT NULL AUTO_INCREMENT,
+//	  realm_id VARCHAR(255) NOT NULL,
+//	  user_id CHAR(36) NOT NULL,
+//	  changed_at BIGINT NOT NULL,
+//	  actor VARCHAR(255) NOT NULL,
+//	  channel VARCHAR(32) NOT NULL,
+//	  changes BLOB NOT NULL,
+//	  PRIMARY KEY (id),
+//	  INDEX (realm_id, user_id)
+//	);
 type UsersDetailsDBModule interface {
 	StoreOrUpdateUserDetails(ctx context.Context, realm string, user dto.DBUser) error
 	GetUserDetails(ctx context.Context, realm string, userID string) (dto.DBUser, error)
 	DeleteUserDetails(ctx context.Context, realm string, userID string) error
 	CreateCheck(ctx context.Context, realm string, userID string, check dto.DBCheck) error
 	GetChecks(ctx context.Context, realm string, userID string) ([]dto.DBCheck, error)
+	MoveChecks(ctx context.Context, realm string, fromUserID string, toUserID string) error
+	StoreUserChange(ctx context.Context, realm string, userID string, change dto.DBUserChange) error
+	GetUserHistory(ctx context.Context, realm string, userID string) ([]dto.DBUserChange, error)
+	UpdateTrustIDGroupsExpiries(ctx context.Context, realm string, userID string, expiries []dto.DBTrustIDGroupExpiry) error
+	GetExpiredTrustIDGroups(ctx context.Context, expiredAt time.Time) ([]dto.DBTrustIDGroupExpiry, error)
+	FindUsersByIDDocumentNumber(ctx context.Context, realm string, documentNumber string) ([]string, error)
+	IndexIDDocumentNumbers(ctx context.Context) (int, error)
 }
 
+// Number of user details read at once by IndexIDDocumentNumbers
+const idDocumentIndexBatchSize = 500
+
 type usersDBModule struct {
-	db     sqltypes.CloudtrustDB
-	cipher security.EncrypterDecrypter
-	logger log.Logger
+	db         sqltypes.CloudtrustDB
+	cipher     security.EncrypterDecrypter
+	blindIndex BlindIndex
+	logger     log.Logger
 }
 
 func nullStringToPtr(value sql.NullString) *string {
@@ -65,11 +141,12 @@ func nullStringToDatePtr(value sql.NullString) *time.Time {
 }
 
 // NewUsersDetailsDBModule returns a UsersDB module.
-func NewUsersDetailsDBModule(db sqltypes.CloudtrustDB, cipher security.EncrypterDecrypter, logger log.Logger) UsersDetailsDBModule {
+func NewUsersDetailsDBModule(db sqltypes.CloudtrustDB, cipher security.EncrypterDecrypter, blindIndex BlindIndex, logger log.Logger) UsersDetailsDBModule {
 	return &usersDBModule{
-		db:     db,
-		cipher: cipher,
-		logger: logger,
+		db:         db,
+		cipher:     cipher,
+		blindIndex: blindIndex,
+		logger:     logger,
 	}
 }
 
@@ -86,8 +163,14 @@ func (c *usersDBModule) StoreOrUpdateUserDetails(ctx context.Context, realm stri
 		return err
 	}
 
+	// index the ID document number
+	var documentIndex []byte
+	if user.IDDocumentNumber != nil && *user.IDDocumentNumber != "" {
+		documentIndex = c.blindIndex.Hash(realm, *user.IDDocumentNumber)
+	}
+
 	// update value in DB
-	_, err = c.db.Exec(updateUserDetailsStmt, realm, user.UserID, encryptedData, encryptedData)
+	_, err = c.db.Exec(updateUserDetailsStmt, realm, user.UserID, encryptedData, documentIndex, encryptedData, documentIndex)
 	return err
 }
 
@@ -117,23 +200,103 @@ func (c *usersDBModule) GetUserDetails(ctx context.Context, realm string, userID
 	}
 }
 
+// FindUsersByIDDocumentNumber returns the IDs of the users of a realm having the given ID document number
+func (c *usersDBModule) FindUsersByIDDocumentNumber(ctx context.Context, realm string, documentNumber string) ([]string, error) {
+	rows, err := c.db.Query(selectUsersByIDDocumentStmt, realm, c.blindIndex.Hash(realm, documentNumber))
+	if err != nil {
+		c.logger.Warn(ctx, "msg", "Can't find users by ID document number", "error", err.Error(), "realmID", realm)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var res []string
+	for rows.Next() {
+		var userID string
+		if err = rows.Scan(&userID); err != nil {
+			c.logger.Warn(ctx, "msg", "Can't find users by ID document number. Scan failed", "error", err.Error(), "realmID", realm)
+			return nil, err
+		}
+		res = append(res, userID)
+	}
+	return res, rows.Err()
+}
+
+type unindexedUserDetails struct {
+	realm            string
+	userID           string
+	encryptedDetails []byte
+}
+
+// IndexIDDocumentNumbers computes the blind index of the ID document number of the user details which have none and
+// returns the number of indexed users. Details without ID document number are left unindexed. A row is only indexed if
+// it has not been updated meanwhile, as an update stores the index of its own details
+func (c *usersDBModule) IndexIDDocumentNumbers(ctx context.Context) (int, error) {
+	var indexed = 0
+	var lastRealm, lastUserID = "", ""
+	for {
+		var batch, err = c.getUnindexedUserDetails(ctx, lastRealm, lastUserID)
+		if err != nil {
+			return indexed, err
+		}
+		for _, row := range batch {
+			detailsJSON, err := c.cipher.Decrypt(row.encryptedDetails, []byte(row.userID))
+			if err != nil {
+				// Other users can still be indexed
+				c.logger.Warn(ctx, "msg", "Can't decrypt the user details", "error", err.Error(), "realmID", row.realm, "userID", row.userID)
+				continue
+			}
+			var details dto.DBUser
+			if err = json.Unmarshal(detailsJSON, &details); err != nil {
+				c.logger.Warn(ctx, "msg", "Can't unmarshal the user details", "error", err.Error(), "realmID", row.realm, "userID", row.userID)
+				continue
+			}
+			if details.IDDocumentNumber == nil || *details.IDDocumentNumber == "" {
+				continue
+			}
+			var documentIndex = c.blindIndex.Hash(row.realm, *details.IDDocumentNumber)
+			if _, err = c.db.Exec(updateIDDocumentIndexStmt, documentIndex, row.realm, row.userID, row.encryptedDetails); err != nil {
+				c.logger.Warn(ctx, "msg", "Can't store the ID document index", "error", err.Error(), "realmID", row.realm, "userID", row.userID)
+				return indexed, err
+			}
+			indexed++
+		}
+		if len(batch) < idDocumentIndexBatchSize {
+			return indexed, nil
+		}
+		lastRealm, lastUserID = batch[len(batch)-1].realm, batch[len(batch)-1].userID
+	}
+}
+
+// getUnindexedUserDetails returns the next batch of user details without ID document index, ordered by realm and user ID
+func (c *usersDBModule) getUnindexedUserDetails(ctx context.Context, afterRealm string, afterUserID string) ([]unindexedUserDetails, error) {
+	rows, err := c.db.Query(selectUnindexedUserDetailsStmt, afterRealm, afterUserID, idDocumentIndexBatchSize)
+	if err != nil {
+		c.logger.Warn(ctx, "msg", "Can't get the user details to index", "error", err.Error())
+		return nil, err
+	}
+	defer rows.Close()
+
+	var res []unindexedUserDetails
+	for rows.Next() {
+		var row unindexedUserDetails
+		if err = rows.Scan(&row.realm, &row.userID, &row.encryptedDetails); err != nil {
+			c.logger.Warn(ctx, "msg", "Can't get the user details to index. Scan failed", "error", err.Error())
+			return nil, err
+		}
+		res = append(res, row)
+	}
+	return res, rows.Err()
+}
+
 func (c *usersDBModule) DeleteUserDetails(ctx context.Context, realm string, userID string) error {
 	_, err := c.db.Exec(deleteUserDetailsStmt, realm, userID)
 	return err
 }
 
 func (c *usersDBModule) CreateCheck(ctx context.Context, realm string, userID string, check dto.DBCheck) error {
-	var proofData *[]byte
-	var err error
-
-	if check.ProofData != nil {
-		// encrypt the proof data & protect integrity of userID associated to the proof data
-		encryptedData, err := c.cipher.Encrypt(*check.ProofData, []byte(userID))
-		if err != nil {
-			c.logger.Warn(ctx, "msg", "Can't encrypt the proof data", "error", err.Error(), "realmID", realm, "userID", userID)
-			return err
-		}
-		proofData = &encryptedData
+	proofData, err := c.encryptProofData(ctx, realm, userID, check.ProofData)
+	if err != nil {
+		return err
 	}
 
 	// insert check in DB
@@ -144,6 +307,63 @@ func (c *usersDBModule) CreateCheck(ctx context.Context, realm string, userID st
 	return err
 }
 
+func (c *usersDBModule) encryptProofData(ctx context.Context, realm string, userID string, proofData *[]byte) (*[]byte, error) {
+	if proofData == nil {
+		return nil, nil
+	}
+	// encrypt the proof data & protect integrity of userID associated to the proof data
+	encryptedData, err := c.cipher.Encrypt(*proofData, []byte(userID))
+	if err != nil {
+		c.logger.Warn(ctx, "msg", "Can't encrypt the proof data", "error", err.Error(), "realmID", realm, "userID", userID)
+		return nil, err
+	}
+	return &encryptedData, nil
+}
+
+// MoveChecks moves the checks of a user to another user. As the proof data is bound to the user it belongs to, it is
+// encrypted again for the new user
+func (c *usersDBModule) MoveChecks(ctx context.Context, realm string, fromUserID string, toUserID string) error {
+	checks, err := c.GetChecks(ctx, realm, fromUserID)
+	if err != nil {
+		c.logger.Warn(ctx, "msg", "Can't get the checks to move", "error", err.Error(), "realmID", realm, "userID", fromUserID)
+		return err
+	}
+
+	tx, err := c.db.BeginTx(ctx, nil)
+	if err != nil {
+		c.logger.Warn(ctx, "msg", "Can't start transaction", "error", err.Error(), "realmID", realm, "userID", fromUserID)
+		return err
+	}
+	// Rollbacks the transaction if it has not been committed
+	defer tx.Close()
+
+	for _, check := range checks {
+		if check.ProofData != nil && len(*check.ProofData) == 0 {
+			check.ProofData = nil
+		}
+		proofData, err := c.encryptProofData(ctx, realm, toUserID, check.ProofData)
+		if err != nil {
+			return err
+		}
+		if _, err = tx.Exec(createCheckStmt, realm, toUserID, check.Operator,
+			check.DateTime, check.Status, check.Type, check.Nature,
+			check.ProofType, proofData, check.Comment); err != nil {
+			c.logger.Warn(ctx, "msg", "Can't store moved check", "error", err.Error(), "realmID", realm, "userID", toUserID)
+			return err
+		}
+	}
+	if _, err = tx.Exec(deleteChecksStmt, realm, fromUserID); err != nil {
+		c.logger.Warn(ctx, "msg", "Can't delete moved checks", "error", err.Error(), "realmID", realm, "userID", fromUserID)
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		c.logger.Warn(ctx, "msg", "Can't commit moved checks", "error", err.Error(), "realmID", realm, "userID", fromUserID)
+		return err
+	}
+	return nil
+}
+
 func (c *usersDBModule) GetChecks(ctx context.Context, realm string, userID string) ([]dto.DBCheck, error) {
 	var rows, err = c.db.Query(selectCheckStmt, realm, userID)
 
@@ -190,3 +410,99 @@ func (c *usersDBModule) GetChecks(ctx context.Context, realm string, userID stri
 
 	return result, err
 }
+
+// StoreUserChange records a change of the identity fields of a user
+func (c *usersDBModule) StoreUserChange(ctx context.Context, realm string, userID string, change dto.DBUserChange) error {
+	changesJSON, err := json.Marshal(change.Changes)
+	if err != nil {
+		return err
+	}
+	// encrypt the changes & protect integrity of userID associated to the changes
+	encryptedChanges, err := c.cipher.Encrypt(changesJSON, []byte(userID))
+	if err != nil {
+		c.logger.Warn(ctx, "msg", "Can't encrypt the user changes", "error", err.Error(), "realmID", realm, "userID", userID)
+		return err
+	}
+
+	_, err = c.db.Exec(createUserChangeStmt, realm, userID, change.ChangedAt, change.Actor, change.Channel, encryptedChanges)
+	return err
+}
+
+// GetUserHistory returns the changes of the identity fields of a user, the most recent first
+func (c *usersDBModule) GetUserHistory(ctx context.Context, realm string, userID string) ([]dto.DBUserChange, error) {
+	rows, err := c.db.Query(selectUserHistoryStmt, realm, userID)
+	if err != nil {
+		c.logger.Warn(ctx, "msg", "Can't get user history", "error", err.Error(), "realmID", realm, "userID", userID)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var res = []dto.DBUserChange{}
+	for rows.Next() {
+		var change dto.DBUserChange
+		var encryptedChanges []byte
+		if err = rows.Scan(&change.ChangedAt, &change.Actor, &change.Channel, &encryptedChanges); err != nil {
+			c.logger.Warn(ctx, "msg", "Can't get user history. Scan failed", "error", err.Error(), "realmID", realm, "userID", userID)
+			return nil, err
+		}
+		changesJSON, err := c.cipher.Decrypt(encryptedChanges, []byte(userID))
+		if err != nil {
+			c.logger.Warn(ctx, "msg", "Can't decrypt the user changes", "error", err.Error(), "realmID", realm, "userID", userID)
+			return nil, err
+		}
+		if err = json.Unmarshal(changesJSON, &change.Changes); err != nil {
+			return nil, err
+		}
+		res = append(res, change)
+	}
+	return res, rows.Err()
+}
+
+// UpdateTrustIDGroupsExpiries replaces the expiry dates of the trustID groups of a user
+func (c *usersDBModule) UpdateTrustIDGroupsExpiries(ctx context.Context, realm string, userID string, expiries []dto.DBTrustIDGroupExpiry) error {
+	tx, err := c.db.BeginTx(ctx, nil)
+	if err != nil {
+		c.logger.Warn(ctx, "msg", "Can't start transaction", "error", err.Error(), "realmID", realm, "userID", userID)
+		return err
+	}
+	// Rollbacks the transaction if it has not been committed
+	defer tx.Close()
+
+	if _, err = tx.Exec(deleteTrustIDGroupsExpiriesStmt, realm, userID); err != nil {
+		c.logger.Warn(ctx, "msg", "Can't delete trustID groups expiries", "error", err.Error(), "realmID", realm, "userID", userID)
+		return err
+	}
+	for _, expiry := range expiries {
+		if _, err = tx.Exec(createTrustIDGroupExpiryStmt, realm, userID, expiry.GroupName, expiry.ExpiresAt); err != nil {
+			c.logger.Warn(ctx, "msg", "Can't store trustID group expiry", "error", err.Error(), "realmID", realm, "userID", userID, "groupName", expiry.GroupName)
+			return err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		c.logger.Warn(ctx, "msg", "Can't commit trustID groups expiries", "error", err.Error(), "realmID", realm, "userID", userID)
+		return err
+	}
+	return nil
+}
+
+// GetExpiredTrustIDGroups returns the trustID groups which expired at the given time
+func (c *usersDBModule) GetExpiredTrustIDGroups(ctx context.Context, expiredAt time.Time) ([]dto.DBTrustIDGroupExpiry, error) {
+	rows, err := c.db.Query(selectExpiredTrustIDGroupsStmt, expiredAt.Unix())
+	if err != nil {
+		c.logger.Warn(ctx, "msg", "Can't get expired trustID groups", "error", err.Error())
+		return nil, err
+	}
+	defer rows.Close()
+
+	var res []dto.DBTrustIDGroupExpiry
+	for rows.Next() {
+		var expiry dto.DBTrustIDGroupExpiry
+		if err = rows.Scan(&expiry.RealmID, &expiry.UserID, &expiry.GroupName, &expiry.ExpiresAt); err != nil {
+			c.logger.Warn(ctx, "msg", "Can't get expired trustID groups. Scan failed", "error", err.Error())
+			return nil, err
+		}
+		res = append(res, expiry)
+	}
+	return res, rows.Err()
+}
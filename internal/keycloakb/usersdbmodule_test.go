@@ -6,6 +6,7 @@ import (
 	"encoding/base64"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/cloudtrust/common-service/log"
 	"github.com/cloudtrust/keycloak-bridge/internal/dto"
@@ -14,6 +15,8 @@ import (
 	"github.com/stretchr/testify/assert"
 )
 
+var testBlindIndex = &hmacBlindIndex{key: []byte("0123456789abcdef0123456789abcdef")}
+
 func TestStoreOrUpdateUserDetails(t *testing.T) {
 	var mockCtrl = gomock.NewController(t)
 	defer mockCtrl.Finish()
@@ -23,27 +26,36 @@ func TestStoreOrUpdateUserDetails(t *testing.T) {
 	var userID = "123789"
 	t.Run("Update succesful", func(t *testing.T) {
 
-		mockDB.EXPECT().Exec(gomock.Any(), "realmId", &userID, gomock.Any(), gomock.Any()).Return(nil, nil).Times(1)
-		var configDBModule = NewUsersDetailsDBModule(mockDB, mockCrypter, log.NewNopLogger())
+		mockDB.EXPECT().Exec(gomock.Any(), "realmId", &userID, gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, nil).Times(1)
+		var configDBModule = NewUsersDetailsDBModule(mockDB, mockCrypter, testBlindIndex, log.NewNopLogger())
 		mockCrypter.EXPECT().Encrypt(gomock.Any(), gomock.Any()).Return(nil, nil).Times(1)
 		var err = configDBModule.StoreOrUpdateUserDetails(context.Background(), "realmId", dto.DBUser{UserID: &userID})
 		assert.Nil(t, err)
 	})
 	t.Run("Update user: error at encryption", func(t *testing.T) {
 		var unexpectedError = errors.New("incorrect key")
-		var configDBModule = NewUsersDetailsDBModule(mockDB, mockCrypter, log.NewNopLogger())
+		var configDBModule = NewUsersDetailsDBModule(mockDB, mockCrypter, testBlindIndex, log.NewNopLogger())
 		mockCrypter.EXPECT().Encrypt(gomock.Any(), gomock.Any()).Return(nil, unexpectedError).Times(1)
 		var err = configDBModule.StoreOrUpdateUserDetails(context.Background(), "realmId", dto.DBUser{UserID: &userID})
 		assert.Equal(t, unexpectedError, err)
 	})
 	t.Run("Update user: DB error", func(t *testing.T) {
 		var unexpectedError = errors.New("error")
-		mockDB.EXPECT().Exec(gomock.Any(), "realmId", &userID, gomock.Any(), gomock.Any()).Return(nil, unexpectedError).Times(1)
-		var configDBModule = NewUsersDetailsDBModule(mockDB, mockCrypter, log.NewNopLogger())
+		mockDB.EXPECT().Exec(gomock.Any(), "realmId", &userID, gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, unexpectedError).Times(1)
+		var configDBModule = NewUsersDetailsDBModule(mockDB, mockCrypter, testBlindIndex, log.NewNopLogger())
 		mockCrypter.EXPECT().Encrypt(gomock.Any(), gomock.Any()).Return(nil, nil).Times(1)
 		var err = configDBModule.StoreOrUpdateUserDetails(context.Background(), "realmId", dto.DBUser{UserID: &userID})
 		assert.Equal(t, unexpectedError, err)
 	})
+	t.Run("Update user with ID document number", func(t *testing.T) {
+		var documentNumber = "AB123456"
+		var documentIndex = testBlindIndex.Hash("realmId", documentNumber)
+		mockDB.EXPECT().Exec(gomock.Any(), "realmId", &userID, gomock.Any(), documentIndex, gomock.Any(), documentIndex).Return(nil, nil).Times(1)
+		var configDBModule = NewUsersDetailsDBModule(mockDB, mockCrypter, testBlindIndex, log.NewNopLogger())
+		mockCrypter.EXPECT().Encrypt(gomock.Any(), gomock.Any()).Return(nil, nil).Times(1)
+		var err = configDBModule.StoreOrUpdateUserDetails(context.Background(), "realmId", dto.DBUser{UserID: &userID, IDDocumentNumber: &documentNumber})
+		assert.Nil(t, err)
+	})
 
 }
 
@@ -64,7 +76,7 @@ func TestGetUserDB(t *testing.T) {
 		mockDB.EXPECT().QueryRow(gomock.Any(), realm, userID).Return(mockSQLRow)
 		mockSQLRow.EXPECT().Scan(gomock.Any()).Return(unexpectedError)
 
-		var configDBModule = NewUsersDetailsDBModule(mockDB, mockCrypter, log.NewNopLogger())
+		var configDBModule = NewUsersDetailsDBModule(mockDB, mockCrypter, testBlindIndex, log.NewNopLogger())
 		var _, err = configDBModule.GetUserDetails(ctx, realm, userID)
 		assert.Equal(t, unexpectedError, err)
 	})
@@ -73,7 +85,7 @@ func TestGetUserDB(t *testing.T) {
 		mockDB.EXPECT().QueryRow(gomock.Any(), realm, userID).Return(mockSQLRow)
 		mockSQLRow.EXPECT().Scan(gomock.Any()).Return(sql.ErrNoRows)
 
-		var configDBModule = NewUsersDetailsDBModule(mockDB, mockCrypter, log.NewNopLogger())
+		var configDBModule = NewUsersDetailsDBModule(mockDB, mockCrypter, testBlindIndex, log.NewNopLogger())
 		var user, err = configDBModule.GetUserDetails(ctx, realm, userID)
 		assert.Nil(t, err)
 		assert.NotNil(t, user)
@@ -87,7 +99,7 @@ func TestGetUserDB(t *testing.T) {
 			return nil
 		})
 		mockCrypter.EXPECT().Decrypt(gomock.Any(), gomock.Any()).Return(nil, unexpectedError).Times(1)
-		var configDBModule = NewUsersDetailsDBModule(mockDB, mockCrypter, log.NewNopLogger())
+		var configDBModule = NewUsersDetailsDBModule(mockDB, mockCrypter, testBlindIndex, log.NewNopLogger())
 		var _, err = configDBModule.GetUserDetails(ctx, realm, userID)
 		assert.Equal(t, unexpectedError, err)
 	})
@@ -100,7 +112,7 @@ func TestGetUserDB(t *testing.T) {
 			return nil
 		})
 		mockCrypter.EXPECT().Decrypt(gomock.Any(), gomock.Any()).Return([]byte(`{"birth_location": "Antananarivo"}`), nil).Times(1)
-		var configDBModule = NewUsersDetailsDBModule(mockDB, mockCrypter, log.NewNopLogger())
+		var configDBModule = NewUsersDetailsDBModule(mockDB, mockCrypter, testBlindIndex, log.NewNopLogger())
 		var user, err = configDBModule.GetUserDetails(ctx, realm, userID)
 		assert.Nil(t, err)
 		assert.Equal(t, "Antananarivo", *user.BirthLocation)
@@ -114,7 +126,7 @@ func TestGetUserInformation(t *testing.T) {
 	var mockDB = mock.NewCloudtrustDB(mockCtrl)
 	var mockSQLRows = mock.NewSQLRows(mockCtrl)
 	var mockCrypter = mock.NewEncrypterDecrypter(mockCtrl)
-	var usersDBModule = NewUsersDetailsDBModule(mockDB, mockCrypter, log.NewNopLogger())
+	var usersDBModule = NewUsersDetailsDBModule(mockDB, mockCrypter, testBlindIndex, log.NewNopLogger())
 
 	var realm = "my-realm"
 	var userID = "user-id"
@@ -218,14 +230,14 @@ func TestCreateCheck(t *testing.T) {
 
 		mockDB.EXPECT().Exec(gomock.Any(), realm, userID, gomock.Any(), gomock.Any(),
 			gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, nil).Times(1)
-		var configDBModule = NewUsersDetailsDBModule(mockDB, mockCrypter, log.NewNopLogger())
+		var configDBModule = NewUsersDetailsDBModule(mockDB, mockCrypter, testBlindIndex, log.NewNopLogger())
 		mockCrypter.EXPECT().Encrypt(gomock.Any(), gomock.Any()).Return(nil, nil).Times(1)
 		var err = configDBModule.CreateCheck(context.Background(), realm, userID, dto.DBCheck{ProofData: &proofData})
 		assert.Nil(t, err)
 	})
 	t.Run("Create check: error at encryption", func(t *testing.T) {
 		var unexpectedError = errors.New("incorrect key")
-		var configDBModule = NewUsersDetailsDBModule(mockDB, mockCrypter, log.NewNopLogger())
+		var configDBModule = NewUsersDetailsDBModule(mockDB, mockCrypter, testBlindIndex, log.NewNopLogger())
 		mockCrypter.EXPECT().Encrypt(gomock.Any(), gomock.Any()).Return(nil, unexpectedError).Times(1)
 		var err = configDBModule.CreateCheck(context.Background(), realm, userID, dto.DBCheck{ProofData: &proofData})
 		assert.Equal(t, unexpectedError, err)
@@ -234,9 +246,349 @@ func TestCreateCheck(t *testing.T) {
 		var unexpectedError = errors.New("error")
 		mockDB.EXPECT().Exec(gomock.Any(), realm, userID, gomock.Any(), gomock.Any(),
 			gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, unexpectedError).Times(1)
-		var configDBModule = NewUsersDetailsDBModule(mockDB, mockCrypter, log.NewNopLogger())
+		var configDBModule = NewUsersDetailsDBModule(mockDB, mockCrypter, testBlindIndex, log.NewNopLogger())
 		mockCrypter.EXPECT().Encrypt(gomock.Any(), gomock.Any()).Return(nil, nil).Times(1)
 		var err = configDBModule.CreateCheck(context.Background(), realm, userID, dto.DBCheck{ProofData: &proofData})
 		assert.Equal(t, unexpectedError, err)
 	})
 }
+
+func TestMoveChecks(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+	var mockDB = mock.NewCloudtrustDB(mockCtrl)
+	var mockSQLRows = mock.NewSQLRows(mockCtrl)
+	var mockTx = mock.NewTransaction(mockCtrl)
+	var mockCrypter = mock.NewEncrypterDecrypter(mockCtrl)
+
+	var usersDBModule = NewUsersDetailsDBModule(mockDB, mockCrypter, testBlindIndex, log.NewNopLogger())
+	var realm = "my-realm"
+	var fromUserID = "duplicate-id"
+	var toUserID = "user-id"
+	var encryptedProof = []byte("encrypted proof")
+	var proof = []byte("proof")
+	var reencryptedProof = []byte("re-encrypted proof")
+	var expectedError = errors.New("error")
+	var ctx = context.TODO()
+
+	var expectCheckRow = func() {
+		mockDB.EXPECT().Query(selectCheckStmt, realm, fromUserID).Return(mockSQLRows, nil)
+		gomock.InOrder(
+			mockSQLRows.EXPECT().Next().Return(true),
+			mockSQLRows.EXPECT().Scan(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(),
+				gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(func(dest ...interface{}) error {
+				*(dest[9].(*[]byte)) = encryptedProof
+				return nil
+			}),
+			mockSQLRows.EXPECT().Next().Return(false),
+		)
+		mockCrypter.EXPECT().Decrypt(encryptedProof, []byte(fromUserID)).Return(proof, nil)
+	}
+
+	t.Run("Can't get checks", func(t *testing.T) {
+		mockDB.EXPECT().Query(selectCheckStmt, realm, fromUserID).Return(nil, expectedError)
+		var err = usersDBModule.MoveChecks(ctx, realm, fromUserID, toUserID)
+		assert.Equal(t, expectedError, err)
+	})
+	t.Run("Can't start transaction", func(t *testing.T) {
+		expectCheckRow()
+		mockDB.EXPECT().BeginTx(ctx, nil).Return(nil, expectedError)
+		var err = usersDBModule.MoveChecks(ctx, realm, fromUserID, toUserID)
+		assert.Equal(t, expectedError, err)
+	})
+	t.Run("Delete fails", func(t *testing.T) {
+		expectCheckRow()
+		mockDB.EXPECT().BeginTx(ctx, nil).Return(mockTx, nil)
+		mockCrypter.EXPECT().Encrypt(proof, []byte(toUserID)).Return(reencryptedProof, nil)
+		mockTx.EXPECT().Exec(createCheckStmt, realm, toUserID, gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(),
+			gomock.Any(), &reencryptedProof, gomock.Any()).Return(nil, nil)
+		mockTx.EXPECT().Exec(deleteChecksStmt, realm, fromUserID).Return(nil, expectedError)
+		mockTx.EXPECT().Close()
+		var err = usersDBModule.MoveChecks(ctx, realm, fromUserID, toUserID)
+		assert.Equal(t, expectedError, err)
+	})
+	t.Run("Success", func(t *testing.T) {
+		expectCheckRow()
+		mockDB.EXPECT().BeginTx(ctx, nil).Return(mockTx, nil)
+		mockCrypter.EXPECT().Encrypt(proof, []byte(toUserID)).Return(reencryptedProof, nil)
+		gomock.InOrder(
+			mockTx.EXPECT().Exec(createCheckStmt, realm, toUserID, gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(),
+				gomock.Any(), &reencryptedProof, gomock.Any()).Return(nil, nil),
+			mockTx.EXPECT().Exec(deleteChecksStmt, realm, fromUserID).Return(nil, nil),
+			mockTx.EXPECT().Commit().Return(nil),
+		)
+		mockTx.EXPECT().Close()
+		var err = usersDBModule.MoveChecks(ctx, realm, fromUserID, toUserID)
+		assert.Nil(t, err)
+	})
+}
+
+func TestUpdateTrustIDGroupsExpiries(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+	var mockDB = mock.NewCloudtrustDB(mockCtrl)
+	var mockTx = mock.NewTransaction(mockCtrl)
+	var mockCrypter = mock.NewEncrypterDecrypter(mockCtrl)
+
+	var usersDBModule = NewUsersDetailsDBModule(mockDB, mockCrypter, testBlindIndex, log.NewNopLogger())
+	var realm = "my-realm"
+	var userID = "user-id"
+	var expiries = []dto.DBTrustIDGroupExpiry{{RealmID: realm, UserID: userID, GroupName: "grp1", ExpiresAt: 1600000000}}
+	var expectedError = errors.New("error")
+	var ctx = context.TODO()
+
+	t.Run("Can't start transaction", func(t *testing.T) {
+		mockDB.EXPECT().BeginTx(ctx, nil).Return(nil, expectedError)
+		var err = usersDBModule.UpdateTrustIDGroupsExpiries(ctx, realm, userID, expiries)
+		assert.Equal(t, expectedError, err)
+	})
+	t.Run("Insert fails", func(t *testing.T) {
+		mockDB.EXPECT().BeginTx(ctx, nil).Return(mockTx, nil)
+		mockTx.EXPECT().Exec(deleteTrustIDGroupsExpiriesStmt, realm, userID).Return(nil, nil)
+		mockTx.EXPECT().Exec(createTrustIDGroupExpiryStmt, realm, userID, "grp1", int64(1600000000)).Return(nil, expectedError)
+		mockTx.EXPECT().Close()
+		var err = usersDBModule.UpdateTrustIDGroupsExpiries(ctx, realm, userID, expiries)
+		assert.Equal(t, expectedError, err)
+	})
+	t.Run("Success", func(t *testing.T) {
+		mockDB.EXPECT().BeginTx(ctx, nil).Return(mockTx, nil)
+		gomock.InOrder(
+			mockTx.EXPECT().Exec(deleteTrustIDGroupsExpiriesStmt, realm, userID).Return(nil, nil),
+			mockTx.EXPECT().Exec(createTrustIDGroupExpiryStmt, realm, userID, "grp1", int64(1600000000)).Return(nil, nil),
+			mockTx.EXPECT().Commit().Return(nil),
+		)
+		mockTx.EXPECT().Close()
+		var err = usersDBModule.UpdateTrustIDGroupsExpiries(ctx, realm, userID, expiries)
+		assert.Nil(t, err)
+	})
+}
+
+func TestGetExpiredTrustIDGroups(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+	var mockDB = mock.NewCloudtrustDB(mockCtrl)
+	var mockSQLRows = mock.NewSQLRows(mockCtrl)
+	var mockCrypter = mock.NewEncrypterDecrypter(mockCtrl)
+
+	var usersDBModule = NewUsersDetailsDBModule(mockDB, mockCrypter, testBlindIndex, log.NewNopLogger())
+	var now = time.Now()
+	var ctx = context.TODO()
+
+	t.Run("Query fails", func(t *testing.T) {
+		var expectedError = errors.New("error")
+		mockDB.EXPECT().Query(selectExpiredTrustIDGroupsStmt, now.Unix()).Return(nil, expectedError)
+		var _, err = usersDBModule.GetExpiredTrustIDGroups(ctx, now)
+		assert.Equal(t, expectedError, err)
+	})
+	t.Run("Success", func(t *testing.T) {
+		mockDB.EXPECT().Query(selectExpiredTrustIDGroupsStmt, now.Unix()).Return(mockSQLRows, nil)
+		gomock.InOrder(
+			mockSQLRows.EXPECT().Next().Return(true),
+			mockSQLRows.EXPECT().Scan(gomock.Any()).DoAndReturn(func(realm, userID, groupName *string, expiresAt *int64) error {
+				*realm = "my-realm"
+				*userID = "user-id"
+				*groupName = "grp1"
+				*expiresAt = now.Unix()
+				return nil
+			}),
+			mockSQLRows.EXPECT().Next().Return(false),
+		)
+		mockSQLRows.EXPECT().Close().Return(nil)
+		mockSQLRows.EXPECT().Err().Return(nil)
+		var res, err = usersDBModule.GetExpiredTrustIDGroups(ctx, now)
+		assert.Nil(t, err)
+		assert.Equal(t, []dto.DBTrustIDGroupExpiry{{RealmID: "my-realm", UserID: "user-id", GroupName: "grp1", ExpiresAt: now.Unix()}}, res)
+	})
+}
+
+func TestFindUsersByIDDocumentNumber(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+	var mockDB = mock.NewCloudtrustDB(mockCtrl)
+	var mockSQLRows = mock.NewSQLRows(mockCtrl)
+	var mockCrypter = mock.NewEncrypterDecrypter(mockCtrl)
+
+	var usersDBModule = NewUsersDetailsDBModule(mockDB, mockCrypter, testBlindIndex, log.NewNopLogger())
+	var realm = "my-realm"
+	var documentNumber = "AB123456"
+	var documentIndex = testBlindIndex.Hash(realm, documentNumber)
+	var ctx = context.TODO()
+
+	t.Run("Query fails", func(t *testing.T) {
+		var expectedError = errors.New("error")
+		mockDB.EXPECT().Query(selectUsersByIDDocumentStmt, realm, documentIndex).Return(nil, expectedError)
+		var _, err = usersDBModule.FindUsersByIDDocumentNumber(ctx, realm, documentNumber)
+		assert.Equal(t, expectedError, err)
+	})
+	t.Run("Scan fails", func(t *testing.T) {
+		var expectedError = errors.New("error")
+		mockDB.EXPECT().Query(selectUsersByIDDocumentStmt, realm, documentIndex).Return(mockSQLRows, nil)
+		mockSQLRows.EXPECT().Next().Return(true)
+		mockSQLRows.EXPECT().Scan(gomock.Any()).Return(expectedError)
+		mockSQLRows.EXPECT().Close().Return(nil)
+		var _, err = usersDBModule.FindUsersByIDDocumentNumber(ctx, realm, documentNumber)
+		assert.Equal(t, expectedError, err)
+	})
+	t.Run("Success", func(t *testing.T) {
+		mockDB.EXPECT().Query(selectUsersByIDDocumentStmt, realm, documentIndex).Return(mockSQLRows, nil)
+		gomock.InOrder(
+			mockSQLRows.EXPECT().Next().Return(true),
+			mockSQLRows.EXPECT().Scan(gomock.Any()).DoAndReturn(func(userID *string) error {
+				*userID = "user-id"
+				return nil
+			}),
+			mockSQLRows.EXPECT().Next().Return(false),
+		)
+		mockSQLRows.EXPECT().Close().Return(nil)
+		mockSQLRows.EXPECT().Err().Return(nil)
+		var res, err = usersDBModule.FindUsersByIDDocumentNumber(ctx, realm, documentNumber)
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"user-id"}, res)
+	})
+}
+
+func TestStoreUserChange(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+	var mockDB = mock.NewCloudtrustDB(mockCtrl)
+	var mockCrypter = mock.NewEncrypterDecrypter(mockCtrl)
+
+	var usersDBModule = NewUsersDetailsDBModule(mockDB, mockCrypter, testBlindIndex, log.NewNopLogger())
+	var realm = "my-realm"
+	var userID = "user-id"
+	var oldValue, newValue = "Doe", "Smith"
+	var change = dto.DBUserChange{ChangedAt: 1600000000, Actor: "operator", Channel: HistoryChannelManagement,
+		Changes: []dto.DBFieldChange{{Field: "lastName", OldValue: &oldValue, NewValue: &newValue}}}
+	var changesJSON = []byte(`[{"field":"lastName","old":"Doe","new":"Smith"}]`)
+	var encryptedChanges = []byte("encrypted")
+	var expectedError = errors.New("error")
+	var ctx = context.TODO()
+
+	t.Run("Encryption fails", func(t *testing.T) {
+		mockCrypter.EXPECT().Encrypt(changesJSON, []byte(userID)).Return(nil, expectedError)
+		var err = usersDBModule.StoreUserChange(ctx, realm, userID, change)
+		assert.Equal(t, expectedError, err)
+	})
+	t.Run("Success", func(t *testing.T) {
+		mockCrypter.EXPECT().Encrypt(changesJSON, []byte(userID)).Return(encryptedChanges, nil)
+		mockDB.EXPECT().Exec(createUserChangeStmt, realm, userID, int64(1600000000), "operator", HistoryChannelManagement, encryptedChanges).Return(nil, nil)
+		var err = usersDBModule.StoreUserChange(ctx, realm, userID, change)
+		assert.Nil(t, err)
+	})
+}
+
+func TestGetUserHistory(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+	var mockDB = mock.NewCloudtrustDB(mockCtrl)
+	var mockSQLRows = mock.NewSQLRows(mockCtrl)
+	var mockCrypter = mock.NewEncrypterDecrypter(mockCtrl)
+
+	var usersDBModule = NewUsersDetailsDBModule(mockDB, mockCrypter, testBlindIndex, log.NewNopLogger())
+	var realm = "my-realm"
+	var userID = "user-id"
+	var encryptedChanges = []byte("encrypted")
+	var expectedError = errors.New("error")
+	var ctx = context.TODO()
+
+	var expectRow = func() {
+		gomock.InOrder(
+			mockSQLRows.EXPECT().Next().Return(true),
+			mockSQLRows.EXPECT().Scan(gomock.Any()).DoAndReturn(func(changedAt *int64, actor, channel *string, changes *[]byte) error {
+				*changedAt = 1600000000
+				*actor = "operator"
+				*channel = HistoryChannelKYC
+				*changes = encryptedChanges
+				return nil
+			}),
+		)
+	}
+
+	t.Run("Query fails", func(t *testing.T) {
+		mockDB.EXPECT().Query(selectUserHistoryStmt, realm, userID).Return(nil, expectedError)
+		var _, err = usersDBModule.GetUserHistory(ctx, realm, userID)
+		assert.Equal(t, expectedError, err)
+	})
+	t.Run("Decryption fails", func(t *testing.T) {
+		mockDB.EXPECT().Query(selectUserHistoryStmt, realm, userID).Return(mockSQLRows, nil)
+		expectRow()
+		mockCrypter.EXPECT().Decrypt(encryptedChanges, []byte(userID)).Return(nil, expectedError)
+		mockSQLRows.EXPECT().Close().Return(nil)
+		var _, err = usersDBModule.GetUserHistory(ctx, realm, userID)
+		assert.Equal(t, expectedError, err)
+	})
+	t.Run("Success", func(t *testing.T) {
+		mockDB.EXPECT().Query(selectUserHistoryStmt, realm, userID).Return(mockSQLRows, nil)
+		expectRow()
+		mockCrypter.EXPECT().Decrypt(encryptedChanges, []byte(userID)).Return([]byte(`[{"field":"idDocumentNumber","new":"AB123456"}]`), nil)
+		mockSQLRows.EXPECT().Next().Return(false)
+		mockSQLRows.EXPECT().Close().Return(nil)
+		mockSQLRows.EXPECT().Err().Return(nil)
+		var res, err = usersDBModule.GetUserHistory(ctx, realm, userID)
+		assert.Nil(t, err)
+		assert.Len(t, res, 1)
+		assert.Equal(t, int64(1600000000), res[0].ChangedAt)
+		assert.Equal(t, HistoryChannelKYC, res[0].Channel)
+		assert.Equal(t, "idDocumentNumber", res[0].Changes[0].Field)
+		assert.Nil(t, res[0].Changes[0].OldValue)
+		assert.Equal(t, "AB123456", *res[0].Changes[0].NewValue)
+	})
+}
+
+func TestIndexIDDocumentNumbers(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+	var mockDB = mock.NewCloudtrustDB(mockCtrl)
+	var mockSQLRows = mock.NewSQLRows(mockCtrl)
+	var mockCrypter = mock.NewEncrypterDecrypter(mockCtrl)
+
+	var usersDBModule = NewUsersDetailsDBModule(mockDB, mockCrypter, testBlindIndex, log.NewNopLogger())
+	var realm = "my-realm"
+	var documentNumber = "AB123456"
+	var documentIndex = testBlindIndex.Hash(realm, documentNumber)
+	var ctx = context.TODO()
+
+	var expectRows = func(userIDs ...string) {
+		mockDB.EXPECT().Query(selectUnindexedUserDetailsStmt, "", "", idDocumentIndexBatchSize).Return(mockSQLRows, nil)
+		var calls []*gomock.Call
+		for _, userID := range userIDs {
+			var id = userID
+			calls = append(calls, mockSQLRows.EXPECT().Next().Return(true),
+				mockSQLRows.EXPECT().Scan(gomock.Any()).DoAndReturn(func(realmID *string, userID *string, details *[]byte) error {
+					*realmID = realm
+					*userID = id
+					*details = []byte("encrypted-" + id)
+					return nil
+				}))
+		}
+		calls = append(calls, mockSQLRows.EXPECT().Next().Return(false))
+		gomock.InOrder(calls...)
+		mockSQLRows.EXPECT().Close().Return(nil)
+		mockSQLRows.EXPECT().Err().Return(nil)
+	}
+
+	t.Run("Query fails", func(t *testing.T) {
+		var expectedError = errors.New("error")
+		mockDB.EXPECT().Query(selectUnindexedUserDetailsStmt, "", "", idDocumentIndexBatchSize).Return(nil, expectedError)
+		var _, err = usersDBModule.IndexIDDocumentNumbers(ctx)
+		assert.Equal(t, expectedError, err)
+	})
+	t.Run("Update fails", func(t *testing.T) {
+		var expectedError = errors.New("error")
+		expectRows("user-1")
+		mockCrypter.EXPECT().Decrypt([]byte("encrypted-user-1"), []byte("user-1")).Return([]byte(`{"id_document_num":"AB123456"}`), nil)
+		mockDB.EXPECT().Exec(updateIDDocumentIndexStmt, documentIndex, realm, "user-1", []byte("encrypted-user-1")).Return(nil, expectedError)
+		var _, err = usersDBModule.IndexIDDocumentNumbers(ctx)
+		assert.Equal(t, expectedError, err)
+	})
+	t.Run("Success", func(t *testing.T) {
+		expectRows("user-1", "user-2", "user-3")
+		mockCrypter.EXPECT().Decrypt([]byte("encrypted-user-1"), []byte("user-1")).Return(nil, errors.New("error"))
+		mockCrypter.EXPECT().Decrypt([]byte("encrypted-user-2"), []byte("user-2")).Return([]byte(`{"birth_location":"Rolle"}`), nil)
+		mockCrypter.EXPECT().Decrypt([]byte("encrypted-user-3"), []byte("user-3")).Return([]byte(`{"id_document_num":"AB123456"}`), nil)
+		mockDB.EXPECT().Exec(updateIDDocumentIndexStmt, documentIndex, realm, "user-3", []byte("encrypted-user-3")).Return(nil, nil)
+		var indexed, err = usersDBModule.IndexIDDocumentNumbers(ctx)
+		assert.Nil(t, err)
+		assert.Equal(t, 1, indexed)
+	})
+}
@@ -125,12 +125,12 @@ func (c *authorizationComponentMW) DeleteCredential(ctx context.Context, credent
 	return c.next.DeleteCredential(ctx, credentialID)
 }
 
-func (c *authorizationComponentMW) GetAccount(ctx context.Context) (api.AccountRepresentation, error) {
+func (c *authorizationComponentMW) GetAccount(ctx context.Context) (api.AccountRepresentation, string, error) {
 	// No restriction for this call
 	return c.next.GetAccount(ctx)
 }
 
-func (c *authorizationComponentMW) UpdateAccount(ctx context.Context, account api.AccountRepresentation) error {
+func (c *authorizationComponentMW) UpdateAccount(ctx context.Context, account api.AccountRepresentation, ifMatch string) error {
 	var action = UpdateAccount
 	var currentRealm = ctx.Value(cs.CtContextRealm).(string)
 
@@ -154,7 +154,7 @@ func (c *authorizationComponentMW) UpdateAccount(ctx context.Context, account ap
 		return security.ForbiddenError{}
 	}
 
-	return c.next.UpdateAccount(ctx, account)
+	return c.next.UpdateAccount(ctx, account, ifMatch)
 }
 
 func (c *authorizationComponentMW) DeleteAccount(ctx context.Context) error {
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/cloudtrust/common-service/configuration"
 
@@ -56,8 +57,8 @@ type Component interface {
 	UpdateLabelCredential(ctx context.Context, credentialID string, label string) error
 	DeleteCredential(ctx context.Context, credentialID string) error
 	MoveCredential(ctx context.Context, credentialID string, previousCredentialID string) error
-	GetAccount(ctx context.Context) (api.AccountRepresentation, error)
-	UpdateAccount(context.Context, api.AccountRepresentation) error
+	GetAccount(ctx context.Context) (api.AccountRepresentation, string, error)
+	UpdateAccount(ctx context.Context, user api.AccountRepresentation, ifMatch string) error
 	DeleteAccount(context.Context) error
 	GetConfiguration(context.Context, string) (api.Configuration, error)
 	SendVerifyEmail(ctx context.Context) error
@@ -68,6 +69,7 @@ type Component interface {
 type UsersDetailsDBModule interface {
 	StoreOrUpdateUserDetails(ctx context.Context, realm string, user dto.DBUser) error
 	GetUserDetails(ctx context.Context, realm string, userID string) (dto.DBUser, error)
+	StoreUserChange(ctx context.Context, realm string, userID string, change dto.DBUserChange) error
 }
 
 // Component is the management component.
@@ -129,7 +131,7 @@ func (c *component) UpdatePassword(ctx context.Context, currentPassword, newPass
 	return nil
 }
 
-func (c *component) GetAccount(ctx context.Context) (api.AccountRepresentation, error) {
+func (c *component) GetAccount(ctx context.Context) (api.AccountRepresentation, string, error) {
 	var accessToken = ctx.Value(cs.CtContextAccessToken).(string)
 	var realm = ctx.Value(cs.CtContextRealm).(string)
 	var userID = ctx.Value(cs.CtContextUserID).(string)
@@ -139,14 +141,20 @@ func (c *component) GetAccount(ctx context.Context) (api.AccountRepresentation,
 
 	if err != nil {
 		c.logger.Warn(ctx, "err", err.Error())
-		return userRep, err
+		return userRep, "", err
 	}
 	keycloakb.ConvertLegacyAttribute(&userKc)
 
 	dbUser, err := c.usersDBModule.GetUserDetails(ctx, realm, userID)
 	if err != nil {
 		c.logger.Warn(ctx, "err", err.Error())
-		return userRep, err
+		return userRep, "", err
+	}
+
+	etag, err := keycloakb.ComputeETag(userKc, dbUser)
+	if err != nil {
+		c.logger.Warn(ctx, "err", err.Error())
+		return userRep, "", err
 	}
 
 	userRep = api.ConvertToAPIAccount(ctx, userKc, c.logger)
@@ -155,14 +163,14 @@ func (c *component) GetAccount(ctx context.Context) (api.AccountRepresentation,
 	userRep.IDDocumentNumber = dbUser.IDDocumentNumber
 	userRep.IDDocumentExpiration = dbUser.IDDocumentExpiration
 
-	return userRep, nil
+	return userRep, etag, nil
 }
 
 func isUpdated(newValue *string, oldValue *string) bool {
 	return newValue != nil && (oldValue == nil || *newValue != *oldValue)
 }
 
-func (c *component) UpdateAccount(ctx context.Context, user api.AccountRepresentation) error {
+func (c *component) UpdateAccount(ctx context.Context, user api.AccountRepresentation, ifMatch string) error {
 	var accessToken = ctx.Value(cs.CtContextAccessToken).(string)
 	var realm = ctx.Value(cs.CtContextRealm).(string)
 	var userID = ctx.Value(cs.CtContextUserID).(string)
@@ -184,6 +192,11 @@ func (c *component) UpdateAccount(ctx context.Context, user api.AccountRepresent
 		return err
 	}
 
+	if err = keycloakb.CheckIfMatch(ifMatch, oldUserKc, oldUser); err != nil {
+		c.logger.Info(ctx, "msg", "Account modified since it was read", "userID", userID)
+		return err
+	}
+
 	var emailVerified, phoneNumberVerified *bool
 	var actions []string
 
@@ -265,6 +278,19 @@ func (c *component) UpdateAccount(ctx context.Context, user api.AccountRepresent
 		return err
 	}
 
+	// The history of the user is not critical: a failure is only logged
+	if changes := keycloakb.GetIdentityChanges(oldUserKc, oldUser, userRep, dbUser); len(changes) > 0 {
+		var change = dto.DBUserChange{
+			ChangedAt: time.Now().Unix(),
+			Actor:     username,
+			Channel:   keycloakb.HistoryChannelAccount,
+			Changes:   changes,
+		}
+		if errHistory := c.usersDBModule.StoreUserChange(ctx, realm, userID, change); errHistory != nil {
+			c.logger.Warn(ctx, "msg", "Can't store the change in the history of the user", "err", errHistory.Error())
+		}
+	}
+
 	var attributes = make(map[string]string)
 	if prevEmail != nil && c.sendEmail(ctx, emailTemplateUpdatedEmail, emailSubjectUpdatedEmail, prevEmail, attributes) == nil {
 		c.reportEvent(ctx, "EMAIL_CHANGED_EMAIL_SENT", database.CtEventRealmName, realm, database.CtEventUserID, userID, database.CtEventUsername, username)
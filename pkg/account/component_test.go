@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net/http"
 	"strconv"
 	"testing"
 	"time"
@@ -11,6 +12,7 @@ import (
 	cs "github.com/cloudtrust/common-service"
 	"github.com/cloudtrust/common-service/configuration"
 	"github.com/cloudtrust/common-service/database"
+	errorhandler "github.com/cloudtrust/common-service/errors"
 	"github.com/cloudtrust/common-service/log"
 	account_api "github.com/cloudtrust/keycloak-bridge/api/account"
 	api "github.com/cloudtrust/keycloak-bridge/api/account"
@@ -21,6 +23,7 @@ import (
 
 	"github.com/cloudtrust/keycloak-bridge/internal/constants"
 	"github.com/cloudtrust/keycloak-bridge/internal/dto"
+	"github.com/cloudtrust/keycloak-bridge/internal/keycloakb"
 )
 
 func TestUpdatePassword(t *testing.T) {
@@ -198,7 +201,28 @@ func TestUpdateAccount(t *testing.T) {
 	t.Run("GetAccount fails", func(t *testing.T) {
 		mockKeycloakAccountClient.EXPECT().GetAccount(accessToken, realmName).Return(kcUserRep, anError)
 
-		var err = accountComponent.UpdateAccount(ctx, userRep)
+		var err = accountComponent.UpdateAccount(ctx, userRep, "")
+
+		assert.Equal(t, anError, err)
+	})
+
+	t.Run("Error - account modified since it was read", func(t *testing.T) {
+		mockKeycloakAccountClient.EXPECT().GetAccount(accessToken, realmName).Return(kcUserRep, nil).Times(1)
+		mockUsersDetailsDBModule.EXPECT().GetUserDetails(ctx, realmName, userID).Return(dbUser, nil)
+
+		err := accountComponent.UpdateAccount(ctx, userRep, "\"outdated\"")
+
+		assert.NotNil(t, err)
+		assert.Equal(t, http.StatusPreconditionFailed, err.(errorhandler.Error).Status)
+	})
+
+	t.Run("If-Match matches the current version of the account", func(t *testing.T) {
+		var etag, _ = keycloakb.ComputeETag(kcUserRep, dbUser)
+		mockKeycloakAccountClient.EXPECT().GetAccount(accessToken, realmName).Return(kcUserRep, nil).Times(1)
+		mockUsersDetailsDBModule.EXPECT().GetUserDetails(ctx, realmName, userID).Return(dbUser, nil)
+		mockKeycloakAccountClient.EXPECT().UpdateAccount(accessToken, realmName, gomock.Any()).Return(anError).Times(1)
+
+		err := accountComponent.UpdateAccount(ctx, userRep, etag)
 
 		assert.Equal(t, anError, err)
 	})
@@ -218,7 +242,7 @@ func TestUpdateAccount(t *testing.T) {
 		mockUsersDetailsDBModule.EXPECT().GetUserDetails(ctx, realmName, userID).Return(dbUser, nil)
 		mockUsersDetailsDBModule.EXPECT().StoreOrUpdateUserDetails(ctx, realmName, gomock.Any()).Return(nil)
 
-		err := accountComponent.UpdateAccount(ctx, userRep)
+		err := accountComponent.UpdateAccount(ctx, userRep, "")
 
 		assert.Nil(t, err)
 	})
@@ -227,7 +251,7 @@ func TestUpdateAccount(t *testing.T) {
 		mockKeycloakAccountClient.EXPECT().GetAccount(accessToken, realmName).Return(kcUserRep, nil).Times(1)
 		mockUsersDetailsDBModule.EXPECT().GetUserDetails(ctx, realmName, userID).Return(dto.DBUser{}, errors.New("db error"))
 
-		err := accountComponent.UpdateAccount(ctx, userRep)
+		err := accountComponent.UpdateAccount(ctx, userRep, "")
 
 		assert.NotNil(t, err)
 	})
@@ -240,7 +264,7 @@ func TestUpdateAccount(t *testing.T) {
 		}, nil)
 		mockUsersDetailsDBModule.EXPECT().StoreOrUpdateUserDetails(ctx, realmName, gomock.Any()).Return(errors.New("db error"))
 
-		err := accountComponent.UpdateAccount(ctx, userRep)
+		err := accountComponent.UpdateAccount(ctx, userRep, "")
 
 		assert.NotNil(t, err)
 	})
@@ -267,6 +291,13 @@ func TestUpdateAccount(t *testing.T) {
 			UserID: &userID,
 		}, nil)
 		mockUsersDetailsDBModule.EXPECT().StoreOrUpdateUserDetails(ctx, realmName, gomock.Any()).Return(nil)
+		mockUsersDetailsDBModule.EXPECT().StoreUserChange(ctx, realmName, userID, gomock.Any()).DoAndReturn(
+			func(ctx context.Context, realm string, userID string, change dto.DBUserChange) error {
+				assert.Equal(t, username, change.Actor)
+				assert.Equal(t, keycloakb.HistoryChannelAccount, change.Channel)
+				assert.Contains(t, change.Changes, dto.DBFieldChange{Field: "email", OldValue: &oldEmail, NewValue: &email})
+				return nil
+			})
 		// Mail updated
 		mockKeycloakAccountClient.EXPECT().SendEmail(accessToken, realmName, emailTemplateUpdatedEmail, emailSubjectUpdatedEmail, &oldEmail, gomock.Any()).Return(nil)
 		mockEventDBModule.EXPECT().ReportEvent(ctx, "EMAIL_CHANGED_EMAIL_SENT", "self-service", database.CtEventRealmName, realmName,
@@ -276,7 +307,7 @@ func TestUpdateAccount(t *testing.T) {
 		mockEventDBModule.EXPECT().ReportEvent(ctx, "PROFILE_CHANGED_EMAIL_SENT", "self-service", database.CtEventRealmName, realmName,
 			database.CtEventUserID, userID, database.CtEventUsername, username).Return(nil)
 
-		err := accountComponent.UpdateAccount(ctx, userRep)
+		err := accountComponent.UpdateAccount(ctx, userRep, "")
 
 		assert.Nil(t, err)
 		userRep.PhoneNumber = &phoneNumber
@@ -312,8 +343,9 @@ func TestUpdateAccount(t *testing.T) {
 			UserID: &userID,
 		}, nil)
 		mockUsersDetailsDBModule.EXPECT().StoreOrUpdateUserDetails(ctx, realmName, gomock.Any()).Return(nil)
+		mockUsersDetailsDBModule.EXPECT().StoreUserChange(ctx, realmName, userID, gomock.Any()).Return(nil)
 
-		err := accountComponent.UpdateAccount(ctx, userRep)
+		err := accountComponent.UpdateAccount(ctx, userRep, "")
 
 		assert.Nil(t, err)
 		userRep.Email = &email
@@ -335,7 +367,7 @@ func TestUpdateAccount(t *testing.T) {
 			UserID: &userID,
 		}, nil)
 
-		err := accountComponent.UpdateAccount(ctx, userRep)
+		err := accountComponent.UpdateAccount(ctx, userRep, "")
 
 		assert.Equal(t, anError, err)
 	})
@@ -360,8 +392,10 @@ func TestUpdateAccount(t *testing.T) {
 			UserID: &userID,
 		}, nil)
 		mockUsersDetailsDBModule.EXPECT().StoreOrUpdateUserDetails(ctx, realmName, gomock.Any()).Return(nil)
+		// A failure when storing the history is not returned
+		mockUsersDetailsDBModule.EXPECT().StoreUserChange(ctx, realmName, userID, gomock.Any()).Return(errors.New("db error"))
 
-		err := accountComponent.UpdateAccount(ctx, userRepWithoutAttr)
+		err := accountComponent.UpdateAccount(ctx, userRepWithoutAttr, "")
 
 		assert.Nil(t, err)
 	})
@@ -369,7 +403,7 @@ func TestUpdateAccount(t *testing.T) {
 	t.Run("Error - get user", func(t *testing.T) {
 		mockKeycloakAccountClient.EXPECT().GetAccount(accessToken, realmName).Return(kc.UserRepresentation{}, fmt.Errorf("Unexpected error")).Times(1)
 
-		err := accountComponent.UpdateAccount(ctx, api.AccountRepresentation{})
+		err := accountComponent.UpdateAccount(ctx, api.AccountRepresentation{}, "")
 
 		assert.NotNil(t, err)
 	})
@@ -384,7 +418,7 @@ func TestUpdateAccount(t *testing.T) {
 		}, nil)
 		mockKeycloakAccountClient.EXPECT().UpdateAccount(accessToken, realmName, gomock.Any()).Return(fmt.Errorf("Unexpected error")).Times(1)
 
-		err := accountComponent.UpdateAccount(ctx, api.AccountRepresentation{})
+		err := accountComponent.UpdateAccount(ctx, api.AccountRepresentation{}, "")
 
 		assert.NotNil(t, err)
 	})
@@ -413,7 +447,7 @@ func TestGetUser(t *testing.T) {
 
 	t.Run("Call to Keycloak fails", func(t *testing.T) {
 		mockKeycloakAccountClient.EXPECT().GetAccount(accessToken, realmName).Return(kc.UserRepresentation{}, fmt.Errorf("Unexpected error"))
-		_, err := accountComponent.GetAccount(ctx)
+		_, _, err := accountComponent.GetAccount(ctx)
 
 		assert.NotNil(t, err)
 	})
@@ -422,7 +456,7 @@ func TestGetUser(t *testing.T) {
 		var dbError = errors.New("db error")
 		mockKeycloakAccountClient.EXPECT().GetAccount(accessToken, realmName).Return(kc.UserRepresentation{}, nil)
 		mockUsersDetailsDBModule.EXPECT().GetUserDetails(ctx, realmName, userID).Return(dto.DBUser{}, dbError)
-		_, err := accountComponent.GetAccount(ctx)
+		_, _, err := accountComponent.GetAccount(ctx)
 
 		assert.Equal(t, dbError, err)
 	})
@@ -466,9 +500,10 @@ func TestGetUser(t *testing.T) {
 		}, nil)
 		mockEventDBModule.EXPECT().ReportEvent(ctx, "GET_DETAILS", "back-office", gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
 
-		apiUserRep, err := accountComponent.GetAccount(ctx)
+		apiUserRep, etag, err := accountComponent.GetAccount(ctx)
 
 		assert.Nil(t, err)
+		assert.NotEqual(t, "", etag)
 		assert.Equal(t, username, *apiUserRep.Username)
 		assert.Equal(t, email, *apiUserRep.Email)
 		assert.Equal(t, gender, *apiUserRep.Gender)
@@ -496,9 +531,10 @@ func TestGetUser(t *testing.T) {
 		}, nil)
 		mockEventDBModule.EXPECT().ReportEvent(ctx, "GET_DETAILS", "back-office", gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
 
-		apiUserRep, err := accountComponent.GetAccount(ctx)
+		apiUserRep, etag, err := accountComponent.GetAccount(ctx)
 
 		assert.Nil(t, err)
+		assert.NotEqual(t, "", etag)
 		assert.Equal(t, username, *apiUserRep.Username)
 		assert.Equal(t, email, *apiUserRep.Email)
 		assert.Equal(t, gender, *apiUserRep.Gender)
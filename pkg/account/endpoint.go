@@ -8,6 +8,7 @@ import (
 	errrorhandler "github.com/cloudtrust/common-service/errors"
 	api "github.com/cloudtrust/keycloak-bridge/api/account"
 	msg "github.com/cloudtrust/keycloak-bridge/internal/constants"
+	"github.com/cloudtrust/keycloak-bridge/internal/keycloakb"
 	"github.com/go-kit/kit/endpoint"
 )
 
@@ -112,7 +113,11 @@ func MakeMoveCredentialEndpoint(component Component) cs.Endpoint {
 // MakeGetAccountEndpoint makes the GetAccount endpoint to get connected user's info.
 func MakeGetAccountEndpoint(component Component) cs.Endpoint {
 	return func(ctx context.Context, _ interface{}) (interface{}, error) {
-		return component.GetAccount(ctx)
+		account, etag, err := component.GetAccount(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return keycloakb.ETagReply{ETag: etag, Value: account}, nil
 	}
 }
 
@@ -131,7 +136,7 @@ func MakeUpdateAccountEndpoint(component Component) cs.Endpoint {
 			return nil, err
 		}
 
-		return nil, component.UpdateAccount(ctx, body)
+		return nil, component.UpdateAccount(ctx, body, m[ReqIfMatch])
 	}
 }
 
@@ -5,6 +5,7 @@ import (
 	"testing"
 
 	account_api "github.com/cloudtrust/keycloak-bridge/api/account"
+	"github.com/cloudtrust/keycloak-bridge/internal/keycloakb"
 	"github.com/cloudtrust/keycloak-bridge/pkg/account/mock"
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
@@ -121,11 +122,12 @@ func TestMakeGetAccountEndpoint(t *testing.T) {
 	defer mockCtrl.Finish()
 
 	mockAccountComponent := mock.NewComponent(mockCtrl)
-	mockAccountComponent.EXPECT().GetAccount(gomock.Any()).Return(account_api.AccountRepresentation{}, nil).Times(1)
+	mockAccountComponent.EXPECT().GetAccount(gomock.Any()).Return(account_api.AccountRepresentation{}, "\"etag\"", nil).Times(1)
 
 	m := map[string]string{}
-	_, err := MakeGetAccountEndpoint(mockAccountComponent)(context.Background(), m)
+	res, err := MakeGetAccountEndpoint(mockAccountComponent)(context.Background(), m)
 	assert.Nil(t, err)
+	assert.Equal(t, keycloakb.ETagReply{ETag: "\"etag\"", Value: account_api.AccountRepresentation{}}, res)
 }
 
 func TestMakeUpdateAccountEndpoint(t *testing.T) {
@@ -133,10 +135,11 @@ func TestMakeUpdateAccountEndpoint(t *testing.T) {
 	defer mockCtrl.Finish()
 
 	mockAccountComponent := mock.NewComponent(mockCtrl)
-	mockAccountComponent.EXPECT().UpdateAccount(gomock.Any(), account_api.AccountRepresentation{}).Return(nil).Times(1)
+	mockAccountComponent.EXPECT().UpdateAccount(gomock.Any(), account_api.AccountRepresentation{}, "\"etag\"").Return(nil).Times(1)
 	{
 		m := map[string]string{}
 		m[ReqBody] = "{ \"userLabel\": \"label\"}"
+		m[ReqIfMatch] = "\"etag\""
 		_, err := MakeUpdateAccountEndpoint(mockAccountComponent)(context.Background(), m)
 		assert.Nil(t, err)
 	}
@@ -7,6 +7,7 @@ import (
 	commonhttp "github.com/cloudtrust/common-service/http"
 	"github.com/cloudtrust/common-service/log"
 	account_api "github.com/cloudtrust/keycloak-bridge/api/account"
+	"github.com/cloudtrust/keycloak-bridge/internal/keycloakb"
 	"github.com/go-kit/kit/endpoint"
 	http_transport "github.com/go-kit/kit/transport/http"
 )
@@ -14,6 +15,8 @@ import (
 // Parameter names
 const (
 	ReqBody = "body"
+	// ReqIfMatch is not a parameter of the request but its If-Match header
+	ReqIfMatch = "ifMatch"
 
 	PrmCredentialID     = "credentialID"
 	PrmPrevCredentialID = "previousCredentialID"
@@ -25,7 +28,7 @@ const (
 func MakeAccountHandler(e endpoint.Endpoint, logger log.Logger) *http_transport.Server {
 	return http_transport.NewServer(e,
 		decodeAccountRequest,
-		commonhttp.EncodeReply,
+		encodeAccountReply,
 		http_transport.ServerErrorEncoder(commonhttp.ErrorHandler(logger)),
 	)
 }
@@ -41,5 +44,22 @@ func decodeAccountRequest(ctx context.Context, req *http.Request) (interface{},
 		PrmQryRealmID: account_api.RegExpRealmName,
 	}
 
-	return commonhttp.DecodeRequest(ctx, req, pathParams, queryParams)
+	var request, err = commonhttp.DecodeRequest(ctx, req, pathParams, queryParams)
+	if err != nil {
+		return nil, err
+	}
+
+	// optimistic concurrency: the client gives the version of the account it read before updating it
+	request.(map[string]string)[ReqIfMatch] = req.Header.Get("If-Match")
+
+	return request, nil
+}
+
+// encodeAccountReply encodes the reply.
+func encodeAccountReply(ctx context.Context, w http.ResponseWriter, rep interface{}) error {
+	if r, ok := rep.(keycloakb.ETagReply); ok {
+		w.Header().Set("ETag", r.ETag)
+		return commonhttp.EncodeReply(ctx, w, r.Value)
+	}
+	return commonhttp.EncodeReply(ctx, w, rep)
 }
@@ -0,0 +1,70 @@
+package event
+
+import (
+	"context"
+	"time"
+
+	"github.com/cloudtrust/common-service/database"
+	"github.com/cloudtrust/common-service/log"
+)
+
+// RollupsModule is the interface used to maintain the statistics rollups
+type RollupsModule interface {
+	Increment(ctx context.Context, realmName string, ctEventType string, eventTime time.Time) error
+}
+
+// Rollup middleware for the events DB module: it updates the statistics rollups once an event is stored.
+type eventsDBModuleRollupMW struct {
+	rollups RollupsModule
+	logger  log.Logger
+	next    database.EventsDBModule
+}
+
+// MakeEventsDBModuleRollupMW makes a middleware which maintains the statistics rollups.
+// A failure to update the rollups does not prevent the event from being stored.
+func MakeEventsDBModuleRollupMW(rollups RollupsModule, logger log.Logger) func(database.EventsDBModule) database.EventsDBModule {
+	return func(next database.EventsDBModule) database.EventsDBModule {
+		return &eventsDBModuleRollupMW{
+			rollups: rollups,
+			logger:  logger,
+			next:    next,
+		}
+	}
+}
+
+func (m *eventsDBModuleRollupMW) Store(ctx context.Context, mp map[string]string) error {
+	if err := m.next.Store(ctx, mp); err != nil {
+		return err
+	}
+
+	var eventTime, err = time.Parse(timeFormat, mp[database.CtEventAuditTime])
+	if err != nil {
+		eventTime = time.Now()
+	}
+	m.increment(ctx, mp[database.CtEventRealmName], mp[database.CtEventType], eventTime)
+	return nil
+}
+
+func (m *eventsDBModuleRollupMW) ReportEvent(ctx context.Context, apiCall string, origin string, values ...string) error {
+	if err := m.next.ReportEvent(ctx, apiCall, origin, values...); err != nil {
+		return err
+	}
+
+	var realmName string
+	for i := 0; i+1 < len(values); i += 2 {
+		if values[i] == database.CtEventRealmName {
+			realmName = values[i+1]
+		}
+	}
+	m.increment(ctx, realmName, apiCall, time.Now())
+	return nil
+}
+
+func (m *eventsDBModuleRollupMW) increment(ctx context.Context, realmName string, ctEventType string, eventTime time.Time) {
+	if realmName == "" || ctEventType == "" {
+		return
+	}
+	if err := m.rollups.Increment(ctx, realmName, ctEventType, eventTime); err != nil {
+		m.logger.Warn(ctx, "msg", "Can't update statistics rollups", "err", err.Error(), "realm", realmName, "ctEventType", ctEventType)
+	}
+}
@@ -0,0 +1,62 @@
+package event
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/cloudtrust/common-service/database"
+	"github.com/cloudtrust/keycloak-bridge/pkg/event/mock"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEventsDBModuleRollupMW(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+	var mockEventsDBModule = mock.NewEventsDBModule(mockCtrl)
+	var mockRollups = mock.NewRollupsModule(mockCtrl)
+	var mockLogger = mock.NewLogger(mockCtrl)
+
+	var m = MakeEventsDBModuleRollupMW(mockRollups, mockLogger)(mockEventsDBModule)
+
+	var ctx = context.Background()
+	var eventTime = time.Date(2020, 3, 14, 15, 9, 26, 0, time.UTC)
+	var mp = map[string]string{
+		database.CtEventRealmName: "realm",
+		database.CtEventType:      "LOGON_OK",
+		database.CtEventAuditTime: eventTime.Format(timeFormat),
+	}
+	var anError = errors.New("any error")
+
+	t.Run("Store fails", func(t *testing.T) {
+		mockEventsDBModule.EXPECT().Store(ctx, mp).Return(anError)
+		assert.Equal(t, anError, m.Store(ctx, mp))
+	})
+	t.Run("Store without realm", func(t *testing.T) {
+		var noRealm = map[string]string{database.CtEventType: "LOGON_OK"}
+		mockEventsDBModule.EXPECT().Store(ctx, noRealm).Return(nil)
+		assert.Nil(t, m.Store(ctx, noRealm))
+	})
+	t.Run("Store, rollups update fails", func(t *testing.T) {
+		mockEventsDBModule.EXPECT().Store(ctx, mp).Return(nil)
+		mockRollups.EXPECT().Increment(ctx, "realm", "LOGON_OK", eventTime).Return(anError)
+		mockLogger.EXPECT().Warn(gomock.Any(), gomock.Any())
+		assert.Nil(t, m.Store(ctx, mp))
+	})
+	t.Run("Store", func(t *testing.T) {
+		mockEventsDBModule.EXPECT().Store(ctx, mp).Return(nil)
+		mockRollups.EXPECT().Increment(ctx, "realm", "LOGON_OK", eventTime).Return(nil)
+		assert.Nil(t, m.Store(ctx, mp))
+	})
+	t.Run("ReportEvent fails", func(t *testing.T) {
+		mockEventsDBModule.EXPECT().ReportEvent(ctx, "API_CALL", "back-office", database.CtEventRealmName, "realm").Return(anError)
+		assert.Equal(t, anError, m.ReportEvent(ctx, "API_CALL", "back-office", database.CtEventRealmName, "realm"))
+	})
+	t.Run("ReportEvent", func(t *testing.T) {
+		mockEventsDBModule.EXPECT().ReportEvent(ctx, "API_CALL", "back-office", database.CtEventUserID, "user", database.CtEventRealmName, "realm").Return(nil)
+		mockRollups.EXPECT().Increment(ctx, "realm", "API_CALL", gomock.Any()).Return(nil)
+		assert.Nil(t, m.ReportEvent(ctx, "API_CALL", "back-office", database.CtEventUserID, "user", database.CtEventRealmName, "realm"))
+	})
+}
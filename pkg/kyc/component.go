@@ -32,6 +32,7 @@ type UsersDetailsDBModule interface {
 	StoreOrUpdateUserDetails(ctx context.Context, realm string, user dto.DBUser) error
 	GetUserDetails(ctx context.Context, realm string, userID string) (dto.DBUser, error)
 	CreateCheck(ctx context.Context, realm string, userID string, check dto.DBCheck) error
+	StoreUserChange(ctx context.Context, realm string, userID string, change dto.DBUserChange) error
 }
 
 // EventsDBModule is the interface of the audit events module
@@ -57,11 +58,12 @@ type component struct {
 	usersDBModule   UsersDetailsDBModule
 	eventsDBModule  database.EventsDBModule
 	accredsModule   keycloakb.AccreditationsModule
+	sagaModule      keycloakb.SagaModule
 	logger          internal.Logger
 }
 
 // NewComponent returns the management component.
-func NewComponent(tokenProvider toolbox.OidcTokenProvider, socialRealmName string, keycloakClient KeycloakClient, usersDBModule UsersDetailsDBModule, eventsDBModule EventsDBModule, accredsModule keycloakb.AccreditationsModule, logger internal.Logger) Component {
+func NewComponent(tokenProvider toolbox.OidcTokenProvider, socialRealmName string, keycloakClient KeycloakClient, usersDBModule UsersDetailsDBModule, eventsDBModule EventsDBModule, accredsModule keycloakb.AccreditationsModule, sagaModule keycloakb.SagaModule, logger internal.Logger) Component {
 	return &component{
 		tokenProvider:   tokenProvider,
 		socialRealmName: socialRealmName,
@@ -69,6 +71,7 @@ func NewComponent(tokenProvider toolbox.OidcTokenProvider, socialRealmName strin
 		usersDBModule:   usersDBModule,
 		eventsDBModule:  eventsDBModule,
 		accredsModule:   accredsModule,
+		sagaModule:      sagaModule,
 		logger:          logger,
 	}
 }
@@ -196,6 +199,15 @@ func (c *component) validateUser(ctx context.Context, accessToken string, realmN
 		return err
 	}
 
+	// The user is restored in Keycloak and in database if the details or the check can't be stored
+	var previousKcUser kc.UserRepresentation
+	previousKcUser, err = c.keycloakClient.GetUser(accessToken, realmName, userID)
+	if err != nil {
+		c.logger.Warn(ctx, "msg", "Can't get user from Keycloak", "err", err.Error())
+		return err
+	}
+	var previousDbUser = dbUser
+
 	var now = time.Now()
 
 	dbUser.BirthLocation = user.BirthLocation
@@ -204,9 +216,18 @@ func (c *component) validateUser(ctx context.Context, accessToken string, realmN
 	dbUser.IDDocumentExpiration = user.IDDocumentExpiration
 
 	user.ExportToKeycloak(&kcUser)
+
+	var targetKcUser, targetDbUser = kcUser, dbUser
+	saga, err := c.sagaModule.Begin(ctx, keycloakb.SagaOperation{Kind: keycloakb.SagaUpdateUser, Realm: realmName, UserID: userID,
+		PreviousUser: &previousKcUser, PreviousDetails: &previousDbUser, TargetUser: &targetKcUser, TargetDetails: &targetDbUser})
+	if err != nil {
+		return err
+	}
+
 	err = c.keycloakClient.UpdateUser(accessToken, realmName, userID, kcUser)
 	if err != nil {
 		c.logger.Warn(ctx, "msg", "Failed to update user through Keycloak API", "err", err.Error())
+		_ = c.sagaModule.Complete(ctx, saga)
 		return err
 	}
 
@@ -214,6 +235,7 @@ func (c *component) validateUser(ctx context.Context, accessToken string, realmN
 	err = c.usersDBModule.StoreOrUpdateUserDetails(ctx, realmName, dbUser)
 	if err != nil {
 		c.logger.Warn(ctx, "msg", "Can't store user details in database", "err", err.Error())
+		_ = c.sagaModule.Compensate(ctx, accessToken, saga)
 		return err
 	}
 
@@ -230,12 +252,22 @@ func (c *component) validateUser(ctx context.Context, accessToken string, realmN
 	err = c.usersDBModule.CreateCheck(ctx, realmName, userID, validation)
 	if err != nil {
 		c.logger.Warn(ctx, "msg", "Can't store validation check in database", "err", err.Error())
+		_ = c.sagaModule.Compensate(ctx, accessToken, saga)
 		return err
 	}
+	_ = c.sagaModule.Complete(ctx, saga)
 
 	// store the API call into the DB
 	c.reportEvent(ctx, "VALIDATE_USER", database.CtEventRealmName, realmName, database.CtEventUserID, userID, database.CtEventUsername, *user.Username)
 
+	// Store the identity changes in the history of the user: the user is validated even if it fails
+	if changes := keycloakb.GetIdentityChanges(previousKcUser, previousDbUser, kcUser, dbUser); len(changes) > 0 {
+		var change = dto.DBUserChange{ChangedAt: now.Unix(), Actor: operatorName, Channel: keycloakb.HistoryChannelKYC, Changes: changes}
+		if err = c.usersDBModule.StoreUserChange(ctx, realmName, userID, change); err != nil {
+			c.logger.Warn(ctx, "msg", "Can't store the change in the history of the user", "err", err.Error())
+		}
+	}
+
 	return nil
 }
 
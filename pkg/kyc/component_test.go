@@ -9,6 +9,7 @@ import (
 	"github.com/cloudtrust/common-service/configuration"
 	log "github.com/cloudtrust/common-service/log"
 	"github.com/cloudtrust/keycloak-bridge/internal/dto"
+	"github.com/cloudtrust/keycloak-bridge/internal/keycloakb"
 	"github.com/cloudtrust/keycloak-bridge/pkg/kyc/mock"
 	kc "github.com/cloudtrust/keycloak-client"
 	"github.com/golang/mock/gomock"
@@ -25,7 +26,7 @@ func TestGetActions(t *testing.T) {
 	var mockAccreditations = mock.NewAccreditationsModule(mockCtrl)
 	var mockTokenProvider = mock.NewOidcTokenProvider(mockCtrl)
 
-	var component = NewComponent(mockTokenProvider, "realm", mockKeycloakClient, mockUsersDB, mockEventsDB, mockAccreditations, log.NewNopLogger())
+	var component = NewComponent(mockTokenProvider, "realm", mockKeycloakClient, mockUsersDB, mockEventsDB, mockAccreditations, nil, log.NewNopLogger())
 
 	t.Run("GetActions", func(t *testing.T) {
 		var res, err = component.GetActions(context.TODO())
@@ -69,7 +70,7 @@ func TestGetUserByUsernameInSocialRealmComponent(t *testing.T) {
 	var kcGroupSearch = []kc.GroupRepresentation{kcGroup1, kcGroup2}
 	var ctx = context.WithValue(context.Background(), cs.CtContextAccessToken, accessToken)
 
-	var component = NewComponent(mockTokenProvider, realm, mockKeycloakClient, mockUsersDB, mockEventsDB, mockAccreditations, log.NewNopLogger())
+	var component = NewComponent(mockTokenProvider, realm, mockKeycloakClient, mockUsersDB, mockEventsDB, mockAccreditations, nil, log.NewNopLogger())
 
 	t.Run("Failed to retrieve OIDC token", func(t *testing.T) {
 		var oidcError = errors.New("oidc error")
@@ -145,7 +146,7 @@ func TestGetUserInSocialRealmComponent(t *testing.T) {
 	}
 	var ctx = context.WithValue(context.Background(), cs.CtContextAccessToken, accessToken)
 
-	var component = NewComponent(mockTokenProvider, realm, mockKeycloakClient, mockUsersDB, mockEventsDB, mockAccreditations, log.NewNopLogger())
+	var component = NewComponent(mockTokenProvider, realm, mockKeycloakClient, mockUsersDB, mockEventsDB, mockAccreditations, nil, log.NewNopLogger())
 
 	t.Run("Failed to retrieve OIDC token", func(t *testing.T) {
 		var oidcError = errors.New("oidc error")
@@ -205,6 +206,7 @@ func TestValidateUserInSocialRealm(t *testing.T) {
 	var mockEventsDB = mock.NewEventsDBModule(mockCtrl)
 	var mockAccreditations = mock.NewAccreditationsModule(mockCtrl)
 	var mockTokenProvider = mock.NewOidcTokenProvider(mockCtrl)
+	var mockSaga = mock.NewSagaModule(mockCtrl)
 
 	var targetRealm = "cloudtrust"
 	var validUser = createValidUser()
@@ -214,8 +216,9 @@ func TestValidateUserInSocialRealm(t *testing.T) {
 	var accessToken = "abcdef"
 	var ctx = context.TODO()
 	var dbUser = dto.DBUser{UserID: &userID}
+	var saga = keycloakb.SagaOperation{ID: "saga-id", Kind: keycloakb.SagaUpdateUser, Realm: targetRealm, UserID: userID}
 
-	var component = NewComponent(mockTokenProvider, targetRealm, mockKeycloakClient, mockUsersDB, mockEventsDB, mockAccreditations, log.NewNopLogger())
+	var component = NewComponent(mockTokenProvider, targetRealm, mockKeycloakClient, mockUsersDB, mockEventsDB, mockAccreditations, mockSaga, log.NewNopLogger())
 
 	ctx = context.WithValue(ctx, cs.CtContextUsername, "operator")
 
@@ -261,12 +264,38 @@ func TestValidateUserInSocialRealm(t *testing.T) {
 		assert.NotNil(t, err)
 	})
 
+	t.Run("Can't get previous user from Keycloak", func(t *testing.T) {
+		var kcError = errors.New("keycloak error")
+		mockTokenProvider.EXPECT().ProvideToken(ctx).Return(accessToken, nil)
+		mockAccreditations.EXPECT().GetUserAndPrepareAccreditations(ctx, accessToken, targetRealm, userID, configuration.CheckKeyPhysical).Return(kcUser, 0, nil)
+		mockUsersDB.EXPECT().GetUserDetails(ctx, targetRealm, userID).Return(dbUser, nil)
+		mockKeycloakClient.EXPECT().GetUser(accessToken, targetRealm, userID).Return(kc.UserRepresentation{}, kcError)
+
+		var err = component.ValidateUserInSocialRealm(ctx, userID, validUser)
+		assert.Equal(t, kcError, err)
+	})
+
+	t.Run("Can't record pending operation", func(t *testing.T) {
+		var sagaError = errors.New("saga error")
+		mockTokenProvider.EXPECT().ProvideToken(ctx).Return(accessToken, nil)
+		mockAccreditations.EXPECT().GetUserAndPrepareAccreditations(ctx, accessToken, targetRealm, userID, configuration.CheckKeyPhysical).Return(kcUser, 0, nil)
+		mockUsersDB.EXPECT().GetUserDetails(ctx, targetRealm, userID).Return(dbUser, nil)
+		mockKeycloakClient.EXPECT().GetUser(accessToken, targetRealm, userID).Return(kcUser, nil)
+		mockSaga.EXPECT().Begin(ctx, gomock.Any()).Return(keycloakb.SagaOperation{}, sagaError)
+
+		var err = component.ValidateUserInSocialRealm(ctx, userID, validUser)
+		assert.Equal(t, sagaError, err)
+	})
+
 	t.Run("Keycloak update fails", func(t *testing.T) {
 		var kcError = errors.New("keycloak error")
 		mockTokenProvider.EXPECT().ProvideToken(ctx).Return(accessToken, nil)
 		mockAccreditations.EXPECT().GetUserAndPrepareAccreditations(ctx, accessToken, targetRealm, userID, configuration.CheckKeyPhysical).Return(kcUser, 0, nil)
 		mockUsersDB.EXPECT().GetUserDetails(ctx, targetRealm, userID).Return(dbUser, nil)
+		mockKeycloakClient.EXPECT().GetUser(accessToken, targetRealm, userID).Return(kcUser, nil)
+		mockSaga.EXPECT().Begin(ctx, gomock.Any()).Return(saga, nil)
 		mockKeycloakClient.EXPECT().UpdateUser(accessToken, targetRealm, userID, gomock.Any()).Return(kcError)
+		mockSaga.EXPECT().Complete(ctx, saga).Return(nil)
 
 		var err = component.ValidateUserInSocialRealm(ctx, userID, validUser)
 		assert.Equal(t, kcError, err)
@@ -277,8 +306,15 @@ func TestValidateUserInSocialRealm(t *testing.T) {
 		mockTokenProvider.EXPECT().ProvideToken(ctx).Return(accessToken, nil)
 		mockAccreditations.EXPECT().GetUserAndPrepareAccreditations(ctx, accessToken, targetRealm, userID, configuration.CheckKeyPhysical).Return(kcUser, 0, nil)
 		mockUsersDB.EXPECT().GetUserDetails(ctx, targetRealm, userID).Return(dbUser, nil)
+		mockKeycloakClient.EXPECT().GetUser(accessToken, targetRealm, userID).Return(kcUser, nil)
+		mockSaga.EXPECT().Begin(ctx, gomock.Any()).DoAndReturn(func(_ context.Context, op keycloakb.SagaOperation) (keycloakb.SagaOperation, error) {
+			assert.Equal(t, kcUser, *op.PreviousUser)
+			assert.Equal(t, dbUser, *op.PreviousDetails)
+			return saga, nil
+		})
 		mockKeycloakClient.EXPECT().UpdateUser(accessToken, targetRealm, userID, gomock.Any()).Return(nil)
 		mockUsersDB.EXPECT().StoreOrUpdateUserDetails(ctx, targetRealm, gomock.Any()).Return(dbError)
+		mockSaga.EXPECT().Compensate(ctx, accessToken, saga).Return(nil)
 
 		var err = component.ValidateUserInSocialRealm(ctx, userID, validUser)
 		assert.Equal(t, dbError, err)
@@ -289,9 +325,12 @@ func TestValidateUserInSocialRealm(t *testing.T) {
 		mockTokenProvider.EXPECT().ProvideToken(ctx).Return(accessToken, nil)
 		mockAccreditations.EXPECT().GetUserAndPrepareAccreditations(ctx, accessToken, targetRealm, userID, configuration.CheckKeyPhysical).Return(kcUser, 0, nil)
 		mockUsersDB.EXPECT().GetUserDetails(ctx, targetRealm, userID).Return(dbUser, nil)
+		mockKeycloakClient.EXPECT().GetUser(accessToken, targetRealm, userID).Return(kcUser, nil)
+		mockSaga.EXPECT().Begin(ctx, gomock.Any()).Return(saga, nil)
 		mockKeycloakClient.EXPECT().UpdateUser(accessToken, targetRealm, userID, gomock.Any()).Return(nil)
 		mockUsersDB.EXPECT().StoreOrUpdateUserDetails(ctx, targetRealm, gomock.Any()).Return(nil)
 		mockUsersDB.EXPECT().CreateCheck(ctx, targetRealm, userID, gomock.Any()).Return(dbError)
+		mockSaga.EXPECT().Compensate(ctx, accessToken, saga).Return(nil)
 
 		var err = component.ValidateUserInSocialRealm(ctx, userID, validUser)
 		assert.Equal(t, dbError, err)
@@ -301,10 +340,20 @@ func TestValidateUserInSocialRealm(t *testing.T) {
 		mockTokenProvider.EXPECT().ProvideToken(ctx).Return(accessToken, nil)
 		mockAccreditations.EXPECT().GetUserAndPrepareAccreditations(ctx, accessToken, targetRealm, userID, configuration.CheckKeyPhysical).Return(kcUser, 0, nil)
 		mockUsersDB.EXPECT().GetUserDetails(ctx, targetRealm, userID).Return(dbUser, nil)
+		mockKeycloakClient.EXPECT().GetUser(accessToken, targetRealm, userID).Return(kcUser, nil)
+		mockSaga.EXPECT().Begin(ctx, gomock.Any()).Return(saga, nil)
 		mockKeycloakClient.EXPECT().UpdateUser(accessToken, targetRealm, userID, gomock.Any()).Return(nil)
 		mockUsersDB.EXPECT().StoreOrUpdateUserDetails(ctx, targetRealm, gomock.Any()).Return(nil)
 		mockUsersDB.EXPECT().CreateCheck(ctx, targetRealm, userID, gomock.Any()).Return(nil)
+		mockSaga.EXPECT().Complete(ctx, saga).Return(nil)
 		mockEventsDB.EXPECT().ReportEvent(gomock.Any(), "VALIDATE_USER", "back-office", gomock.Any())
+		mockUsersDB.EXPECT().StoreUserChange(ctx, targetRealm, userID, gomock.Any()).DoAndReturn(
+			func(_ context.Context, _ string, _ string, change dto.DBUserChange) error {
+				assert.Equal(t, "operator", change.Actor)
+				assert.Equal(t, keycloakb.HistoryChannelKYC, change.Channel)
+				assert.Contains(t, change.Changes, dto.DBFieldChange{Field: "idDocumentNumber", NewValue: validUser.IDDocumentNumber})
+				return nil
+			})
 
 		var err = component.ValidateUserInSocialRealm(ctx, userID, validUser)
 		assert.Nil(t, err)
@@ -314,10 +363,14 @@ func TestValidateUserInSocialRealm(t *testing.T) {
 		mockTokenProvider.EXPECT().ProvideToken(ctx).Return(accessToken, nil)
 		mockAccreditations.EXPECT().GetUserAndPrepareAccreditations(ctx, accessToken, targetRealm, userID, configuration.CheckKeyPhysical).Return(kcUser, 0, nil)
 		mockUsersDB.EXPECT().GetUserDetails(ctx, targetRealm, userID).Return(dbUser, nil)
+		mockKeycloakClient.EXPECT().GetUser(accessToken, targetRealm, userID).Return(kcUser, nil)
+		mockSaga.EXPECT().Begin(ctx, gomock.Any()).Return(saga, nil)
 		mockKeycloakClient.EXPECT().UpdateUser(accessToken, targetRealm, userID, gomock.Any()).Return(nil)
 		mockUsersDB.EXPECT().StoreOrUpdateUserDetails(ctx, targetRealm, gomock.Any()).Return(nil)
 		mockUsersDB.EXPECT().CreateCheck(ctx, targetRealm, userID, gomock.Any()).Return(nil)
+		mockSaga.EXPECT().Complete(ctx, saga).Return(nil)
 		mockEventsDB.EXPECT().ReportEvent(gomock.Any(), "VALIDATE_USER", "back-office", gomock.Any()).Return(errors.New("report fails"))
+		mockUsersDB.EXPECT().StoreUserChange(ctx, targetRealm, userID, gomock.Any()).Return(errors.New("history fails"))
 
 		var err = component.ValidateUserInSocialRealm(ctx, userID, validUser)
 		assert.Nil(t, err)
@@ -333,6 +386,7 @@ func TestValidateUser(t *testing.T) {
 	var mockEventsDB = mock.NewEventsDBModule(mockCtrl)
 	var mockAccreditations = mock.NewAccreditationsModule(mockCtrl)
 	var mockTokenProvider = mock.NewOidcTokenProvider(mockCtrl)
+	var mockSaga = mock.NewSagaModule(mockCtrl)
 
 	var targetRealm = "cloudtrust"
 	var validUser = createValidUser()
@@ -342,18 +396,23 @@ func TestValidateUser(t *testing.T) {
 	var accessToken = "abcdef"
 	var ctx = context.TODO()
 	var dbUser = dto.DBUser{UserID: &userID}
+	var saga = keycloakb.SagaOperation{ID: "saga-id", Kind: keycloakb.SagaUpdateUser, Realm: targetRealm, UserID: userID}
 
-	var component = NewComponent(mockTokenProvider, targetRealm, mockKeycloakClient, mockUsersDB, mockEventsDB, mockAccreditations, log.NewNopLogger())
+	var component = NewComponent(mockTokenProvider, targetRealm, mockKeycloakClient, mockUsersDB, mockEventsDB, mockAccreditations, mockSaga, log.NewNopLogger())
 
 	ctx = context.WithValue(ctx, cs.CtContextAccessToken, accessToken)
 	ctx = context.WithValue(ctx, cs.CtContextUsername, "operator")
 
 	mockAccreditations.EXPECT().GetUserAndPrepareAccreditations(ctx, accessToken, targetRealm, userID, configuration.CheckKeyPhysical).Return(kcUser, 0, nil)
 	mockUsersDB.EXPECT().GetUserDetails(ctx, targetRealm, userID).Return(dbUser, nil)
+	mockKeycloakClient.EXPECT().GetUser(accessToken, targetRealm, userID).Return(kcUser, nil)
+	mockSaga.EXPECT().Begin(ctx, gomock.Any()).Return(saga, nil)
 	mockKeycloakClient.EXPECT().UpdateUser(accessToken, targetRealm, userID, gomock.Any()).Return(nil)
 	mockUsersDB.EXPECT().StoreOrUpdateUserDetails(ctx, targetRealm, gomock.Any()).Return(nil)
 	mockUsersDB.EXPECT().CreateCheck(ctx, targetRealm, userID, gomock.Any()).Return(nil)
+	mockSaga.EXPECT().Complete(ctx, saga).Return(nil)
 	mockEventsDB.EXPECT().ReportEvent(gomock.Any(), "VALIDATE_USER", "back-office", gomock.Any())
+	mockUsersDB.EXPECT().StoreUserChange(ctx, targetRealm, userID, gomock.Any()).Return(nil)
 
 	var err = component.ValidateUser(ctx, targetRealm, userID, validUser)
 	assert.Nil(t, err)
@@ -0,0 +1,552 @@
+package management
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	cs "github.com/cloudtrust/common-service"
+	"github.com/cloudtrust/common-service/database"
+	errorhandler "github.com/cloudtrust/common-service/errors"
+	"github.com/cloudtrust/common-service/security"
+	api "github.com/cloudtrust/keycloak-bridge/api/management"
+	"github.com/cloudtrust/keycloak-bridge/internal/constants"
+	"github.com/cloudtrust/keycloak-bridge/internal/dto"
+	"github.com/cloudtrust/keycloak-bridge/internal/keycloakb"
+)
+
+// PendingApproval is returned by the component instead of executing an action which must be approved by a second
+// operator. The endpoints convert it into a PendingApprovalReply
+type PendingApproval struct {
+	Request api.PendingRequestRepresentation
+}
+
+func (p PendingApproval) Error() string {
+	return "pending approval " + *p.Request.ID
+}
+
+// Operations which are submitted to the approval required for another action are recorded under their own name
+const (
+	pendingApplyAuthorizationTemplate      = "MGMT_ApplyAuthorizationTemplate"
+	pendingRollbackAuthorizations          = "MGMT_RollbackAuthorizations"
+	pendingRollbackRealmAdminConfiguration = "MGMT_RollbackRealmAdminConfiguration"
+)
+
+// Parameters of a pending DeleteCredentialsForUser
+type credentialParameters struct {
+	CredentialID string `json:"credentialId"`
+}
+
+// Parameters of a pending ApplyAuthorizationTemplate
+type authorizationTemplateParameters struct {
+	TemplateName string `json:"templateName"`
+	TargetRealm  string `json:"targetRealm"`
+}
+
+// Parameters of a pending CopyAuthorizations. The target realm is the realm of the request
+type copyAuthorizationsParameters struct {
+	SourceRealm string `json:"sourceRealm"`
+}
+
+// Parameters of a pending rollback
+type versionParameters struct {
+	Version int `json:"version"`
+}
+
+// approvedRequestKey is the context key of the pending request executed once approved: the events reported by the
+// executed action mention its requester
+type approvedRequestKey struct{}
+
+type approvalComponentMW struct {
+	Component
+	keycloakClient KeycloakClient
+	configDBModule keycloakb.ConfigurationDBModule
+	eventDBModule  database.EventsDBModule
+	expiration     time.Duration
+	logger         keycloakb.Logger
+}
+
+// MakeApprovalComponentMW submits the sensitive actions listed in the admin configuration of a realm to the approval of
+// a second operator. Instead of being executed, such an action is stored as a pending request which expires after the
+// given delay. The other operations which have the same effect are submitted to the same approval: applying a template,
+// copying, rolling back authorizations and deleting or renaming a group need the approval of MGMT_UpdateAuthorizations
+// and merging users the approval of MGMT_MergeUsers or MGMT_DeleteUser. An admin configuration which removes approval
+// actions always needs an approval.
+func MakeApprovalComponentMW(keycloakClient KeycloakClient, configDBModule keycloakb.ConfigurationDBModule, eventDBModule database.EventsDBModule,
+	expiration time.Duration, logger keycloakb.Logger) func(Component) Component {
+	return func(next Component) Component {
+		return &approvalComponentMW{
+			Component:      next,
+			keycloakClient: keycloakClient,
+			configDBModule: configDBModule,
+			eventDBModule:  eventDBModule,
+			expiration:     expiration,
+			logger:         logger,
+		}
+	}
+}
+
+func (c *approvalComponentMW) DeleteUser(ctx context.Context, realmName, userID string) error {
+	var required, err = c.isApprovalRequired(ctx, realmName, MGMTDeleteUser)
+	if err != nil {
+		return err
+	}
+	if required {
+		return c.createPendingRequest(ctx, realmName, MGMTDeleteUser.String(), userID, nil)
+	}
+	return c.Component.DeleteUser(ctx, realmName, userID)
+}
+
+func (c *approvalComponentMW) ResetPassword(ctx context.Context, realmName string, userID string, password api.PasswordRepresentation) (string, error) {
+	var required, err = c.isApprovalRequired(ctx, realmName, MGMTResetPassword)
+	if err != nil {
+		return "", err
+	}
+	if required {
+		// A password can't be kept in the pending request: it will be generated when the request is approved
+		if password.Value != nil {
+			return "", errorhandler.CreateBadRequestError(constants.MsgErrInvalidParam + "." + constants.Password)
+		}
+		return "", c.createPendingRequest(ctx, realmName, MGMTResetPassword.String(), userID, nil)
+	}
+	return c.Component.ResetPassword(ctx, realmName, userID, password)
+}
+
+func (c *approvalComponentMW) DeleteCredentialsForUser(ctx context.Context, realmName string, userID string, credentialID string) error {
+	var required, err = c.isApprovalRequired(ctx, realmName, MGMTDeleteCredentialsForUser)
+	if err != nil {
+		return err
+	}
+	if required {
+		return c.createPendingRequest(ctx, realmName, MGMTDeleteCredentialsForUser.String(), userID, credentialParameters{CredentialID: credentialID})
+	}
+	return c.Component.DeleteCredentialsForUser(ctx, realmName, userID, credentialID)
+}
+
+func (c *approvalComponentMW) UpdateAuthorizations(ctx context.Context, realmName string, groupID string, group api.AuthorizationsRepresentation) error {
+	var required, err = c.isApprovalRequired(ctx, realmName, MGMTUpdateAuthorizations)
+	if err != nil {
+		return err
+	}
+	if required {
+		return c.createPendingRequest(ctx, realmName, MGMTUpdateAuthorizations.String(), groupID, group)
+	}
+	return c.Component.UpdateAuthorizations(ctx, realmName, groupID, group)
+}
+
+func (c *approvalComponentMW) SetTrustIDGroupsToUser(ctx context.Context, realmName, userID string, groups []api.TrustIDGroupRepresentation) error {
+	var required, err = c.isApprovalRequired(ctx, realmName, MGMTSetTrustIDGroups)
+	if err != nil {
+		return err
+	}
+	if required {
+		return c.createPendingRequest(ctx, realmName, MGMTSetTrustIDGroups.String(), userID, groups)
+	}
+	return c.Component.SetTrustIDGroupsToUser(ctx, realmName, userID, groups)
+}
+
+func (c *approvalComponentMW) ApplyAuthorizationTemplate(ctx context.Context, realmName string, groupID string, templateName string, targetRealm string) error {
+	var required, err = c.isApprovalRequired(ctx, realmName, MGMTUpdateAuthorizations)
+	if err != nil {
+		return err
+	}
+	if required {
+		return c.createPendingRequest(ctx, realmName, pendingApplyAuthorizationTemplate, groupID,
+			authorizationTemplateParameters{TemplateName: templateName, TargetRealm: targetRealm})
+	}
+	return c.Component.ApplyAuthorizationTemplate(ctx, realmName, groupID, templateName, targetRealm)
+}
+
+func (c *approvalComponentMW) RollbackAuthorizations(ctx context.Context, realmName string, groupID string, version int) error {
+	var required, err = c.isApprovalRequired(ctx, realmName, MGMTUpdateAuthorizations)
+	if err != nil {
+		return err
+	}
+	if required {
+		return c.createPendingRequest(ctx, realmName, pendingRollbackAuthorizations, groupID, versionParameters{Version: version})
+	}
+	return c.Component.RollbackAuthorizations(ctx, realmName, groupID, version)
+}
+
+// CopyAuthorizations needs the approval of the target realm, whose authorizations are modified. A dry run is not intercepted
+func (c *approvalComponentMW) CopyAuthorizations(ctx context.Context, sourceRealm string, targetRealm string, dryRun bool) (api.AuthorizationsCopyRepresentation, error) {
+	if !dryRun {
+		var required, err = c.isApprovalRequired(ctx, targetRealm, MGMTUpdateAuthorizations)
+		if err != nil {
+			return api.AuthorizationsCopyRepresentation{}, err
+		}
+		if required {
+			return api.AuthorizationsCopyRepresentation{}, c.createPendingRequest(ctx, targetRealm, MGMTCopyAuthorizations.String(), targetRealm,
+				copyAuthorizationsParameters{SourceRealm: sourceRealm})
+		}
+	}
+	return c.Component.CopyAuthorizations(ctx, sourceRealm, targetRealm, dryRun)
+}
+
+// DeleteGroup deletes the authorizations of the group and the ones targeting it
+func (c *approvalComponentMW) DeleteGroup(ctx context.Context, realmName string, groupID string) error {
+	var required, err = c.isApprovalRequired(ctx, realmName, MGMTUpdateAuthorizations)
+	if err != nil {
+		return err
+	}
+	if required {
+		return c.createPendingRequest(ctx, realmName, MGMTDeleteGroup.String(), groupID, nil)
+	}
+	return c.Component.DeleteGroup(ctx, realmName, groupID)
+}
+
+// UpdateGroup renames the group in the authorizations when its name changes
+func (c *approvalComponentMW) UpdateGroup(ctx context.Context, realmName string, groupID string, group api.GroupRepresentation) error {
+	if group.Name != nil {
+		var required, err = c.isApprovalRequired(ctx, realmName, MGMTUpdateAuthorizations)
+		if err != nil {
+			return err
+		}
+		if required {
+			var accessToken = ctx.Value(cs.CtContextAccessToken).(string)
+			currentGroup, err := c.keycloakClient.GetGroup(accessToken, realmName, groupID)
+			if err != nil {
+				c.logger.Warn(ctx, "err", err.Error())
+				return err
+			}
+			if currentGroup.Name == nil || *currentGroup.Name != *group.Name {
+				return c.createPendingRequest(ctx, realmName, MGMTUpdateGroup.String(), groupID, group)
+			}
+		}
+	}
+	return c.Component.UpdateGroup(ctx, realmName, groupID, group)
+}
+
+// MergeUsers disables the duplicate user: it also needs an approval when the deletion of a user does
+func (c *approvalComponentMW) MergeUsers(ctx context.Context, realmName string, userID string, merge api.UsersMergeRepresentation) error {
+	var required, err = c.isApprovalRequired(ctx, realmName, MGMTMergeUsers, MGMTDeleteUser)
+	if err != nil {
+		return err
+	}
+	if required {
+		return c.createPendingRequest(ctx, realmName, MGMTMergeUsers.String(), userID, merge)
+	}
+	return c.Component.MergeUsers(ctx, realmName, userID, merge)
+}
+
+func (c *approvalComponentMW) UpdateRealmAdminConfiguration(ctx context.Context, realmName string, adminConfig api.RealmAdminConfiguration, ifMatch string) error {
+	var _, approvalActions, err = c.getApprovalActions(ctx, realmName)
+	if err != nil {
+		return err
+	}
+	if removesApprovalActions(approvalActions, adminConfig) {
+		return c.createPendingRequest(ctx, realmName, MGMTUpdateRealmAdminConfiguration.String(), realmName, adminConfig)
+	}
+	return c.Component.UpdateRealmAdminConfiguration(ctx, realmName, adminConfig, ifMatch)
+}
+
+func (c *approvalComponentMW) RollbackRealmAdminConfiguration(ctx context.Context, realmName string, version int) error {
+	var realmID, approvalActions, err = c.getApprovalActions(ctx, realmName)
+	if err != nil {
+		return err
+	}
+	if len(approvalActions) > 0 {
+		oldVersion, err := c.configDBModule.GetRealmConfigurationVersion(ctx, realmID, keycloakb.RealmConfigurationTypeAdmin, version)
+		if err != nil {
+			c.logger.Warn(ctx, "err", err.Error())
+			return err
+		}
+		var adminConfig api.RealmAdminConfiguration
+		if err = json.Unmarshal([]byte(oldVersion.Configuration), &adminConfig); err != nil {
+			c.logger.Warn(ctx, "err", err.Error())
+			return err
+		}
+		if removesApprovalActions(approvalActions, adminConfig) {
+			return c.createPendingRequest(ctx, realmName, pendingRollbackRealmAdminConfiguration, realmName, versionParameters{Version: version})
+		}
+	}
+	return c.Component.RollbackRealmAdminConfiguration(ctx, realmName, version)
+}
+
+// removesApprovalActions tells whether some of the current approval actions are not in the admin configuration
+func removesApprovalActions(approvalActions []string, adminConfig api.RealmAdminConfiguration) bool {
+	var kept = make(map[string]bool)
+	if adminConfig.ApprovalActions != nil {
+		for _, action := range *adminConfig.ApprovalActions {
+			kept[action] = true
+		}
+	}
+	for _, action := range approvalActions {
+		if !kept[action] {
+			return true
+		}
+	}
+	return false
+}
+
+// isApprovalRequired tells whether one of the given actions must be approved in the realm
+func (c *approvalComponentMW) isApprovalRequired(ctx context.Context, realmName string, actions ...security.Action) (bool, error) {
+	var _, approvalActions, err = c.getApprovalActions(ctx, realmName)
+	if err != nil {
+		return false, err
+	}
+
+	for _, approvalAction := range approvalActions {
+		for _, action := range actions {
+			if approvalAction == action.String() {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// getApprovalActions returns the ID of the realm and its approval actions
+func (c *approvalComponentMW) getApprovalActions(ctx context.Context, realmName string) (string, []string, error) {
+	var accessToken = ctx.Value(cs.CtContextAccessToken).(string)
+
+	realm, err := c.keycloakClient.GetRealm(accessToken, realmName)
+	if err != nil {
+		c.logger.Warn(ctx, "err", err.Error())
+		return "", nil, err
+	}
+
+	approvalActions, err := c.configDBModule.GetApprovalActions(ctx, *realm.ID)
+	if err != nil {
+		c.logger.Warn(ctx, "err", err.Error())
+		return "", nil, err
+	}
+	return *realm.ID, approvalActions, nil
+}
+
+// createPendingRequest stores the operation with its parameters and returns a PendingApproval error when successful
+func (c *approvalComponentMW) createPendingRequest(ctx context.Context, realmName string, operation string, targetID string, parameters interface{}) error {
+	var requestID, err = newRandomID()
+	if err != nil {
+		c.logger.Warn(ctx, "msg", "Can't generate pending request ID", "err", err.Error())
+		return err
+	}
+
+	var parametersJSON string
+	if parameters != nil {
+		var bytes, _ = json.Marshal(parameters)
+		parametersJSON = string(bytes)
+	}
+
+	var now = time.Now()
+	var request = dto.PendingRequest{
+		ID:          requestID,
+		RealmName:   realmName,
+		Action:      operation,
+		TargetID:    targetID,
+		Parameters:  parametersJSON,
+		RequesterID: ctx.Value(cs.CtContextUserID).(string),
+		Requester:   ctx.Value(cs.CtContextUsername).(string),
+		CreatedAt:   now.Unix(),
+		ExpiresAt:   now.Add(c.expiration).Unix(),
+	}
+	if err = c.configDBModule.CreatePendingRequest(ctx, request); err != nil {
+		c.logger.Warn(ctx, "err", err.Error())
+		return err
+	}
+
+	var apiCall = "API_PENDING_REQUEST_CREATION"
+	var values = []string{database.CtEventRealmName, realmName, database.CtEventAdditionalInfo,
+		database.CreateAdditionalInfo("requestId", requestID, "action", request.Action, "targetId", targetID)}
+	if errEvent := c.eventDBModule.ReportEvent(ctx, apiCall, "back-office", values...); errEvent != nil {
+		keycloakb.LogUnrecordedEvent(ctx, c.logger, apiCall, errEvent.Error(), values...)
+	}
+
+	return PendingApproval{Request: api.ConvertToAPIPendingRequest(request)}
+}
+
+// GetPendingRequests gets the requests of a realm waiting for an approval
+func (c *component) GetPendingRequests(ctx context.Context, realmName string) ([]api.PendingRequestRepresentation, error) {
+	requests, err := c.configDBModule.GetPendingRequests(ctx, realmName, time.Now())
+	if err != nil {
+		c.logger.Warn(ctx, "err", err.Error())
+		return nil, err
+	}
+
+	var res = []api.PendingRequestRepresentation{}
+	for _, request := range requests {
+		res = append(res, api.ConvertToAPIPendingRequest(request))
+	}
+	return res, nil
+}
+
+// GetPendingRequest gets a request waiting for an approval
+func (c *component) GetPendingRequest(ctx context.Context, realmName string, requestID string) (api.PendingRequestRepresentation, error) {
+	request, err := c.getPendingRequest(ctx, realmName, requestID)
+	if err != nil {
+		return api.PendingRequestRepresentation{}, err
+	}
+	return api.ConvertToAPIPendingRequest(request), nil
+}
+
+// ApprovePendingRequest executes a pending request with its original parameters. The approver must not be the requester.
+// The request is removed before its execution so that it can't be approved twice, and restored if the execution fails.
+// When the approved request is a password reset, the generated password is returned
+func (c *component) ApprovePendingRequest(ctx context.Context, realmName string, requestID string) (string, error) {
+	request, err := c.completePendingRequest(ctx, realmName, requestID)
+	if err != nil {
+		return "", err
+	}
+
+	pwd, err := c.executePendingRequest(context.WithValue(ctx, approvedRequestKey{}, request), realmName, request)
+	if err != nil {
+		c.logger.Warn(ctx, "msg", "Can't execute approved request", "err", err.Error(), "requestID", requestID, "action", request.Action)
+		if errRestore := c.configDBModule.CreatePendingRequest(ctx, request); errRestore != nil {
+			c.logger.Error(ctx, "msg", "Can't restore pending request", "err", errRestore.Error(), "requestID", requestID, "action", request.Action)
+		}
+		return "", err
+	}
+
+	c.reportEvent(ctx, "API_PENDING_REQUEST_APPROVAL", database.CtEventRealmName, realmName, database.CtEventAdditionalInfo,
+		database.CreateAdditionalInfo("requestId", requestID, "action", request.Action, "targetId", request.TargetID,
+			"requester", request.Requester, "approver", ctx.Value(cs.CtContextUsername).(string)))
+
+	return pwd, nil
+}
+
+// RejectPendingRequest deletes a pending request without executing it. The operator must not be the requester
+func (c *component) RejectPendingRequest(ctx context.Context, realmName string, requestID string) error {
+	request, err := c.completePendingRequest(ctx, realmName, requestID)
+	if err != nil {
+		return err
+	}
+
+	c.reportEvent(ctx, "API_PENDING_REQUEST_REJECTION", database.CtEventRealmName, realmName, database.CtEventAdditionalInfo,
+		database.CreateAdditionalInfo("requestId", requestID, "action", request.Action, "targetId", request.TargetID,
+			"requester", request.Requester, "approver", ctx.Value(cs.CtContextUsername).(string)))
+
+	return nil
+}
+
+func (c *component) getPendingRequest(ctx context.Context, realmName string, requestID string) (dto.PendingRequest, error) {
+	request, err := c.configDBModule.GetPendingRequest(ctx, realmName, requestID)
+	if err != nil {
+		c.logger.Warn(ctx, "err", err.Error())
+		return dto.PendingRequest{}, err
+	}
+	if request.ExpiresAt <= time.Now().Unix() {
+		return dto.PendingRequest{}, errorhandler.CreateNotFoundError(constants.PendingRequest)
+	}
+	return request, nil
+}
+
+// completePendingRequest removes a request approved or rejected by the current operator. If several operators complete
+// the same request concurrently, only the first one succeeds
+func (c *component) completePendingRequest(ctx context.Context, realmName string, requestID string) (dto.PendingRequest, error) {
+	request, err := c.getPendingRequest(ctx, realmName, requestID)
+	if err != nil {
+		return dto.PendingRequest{}, err
+	}
+
+	if request.RequesterID == ctx.Value(cs.CtContextUserID).(string) {
+		c.logger.Warn(ctx, "msg", "A pending request can't be completed by its requester", "requestID", requestID)
+		return dto.PendingRequest{}, security.ForbiddenError{}
+	}
+
+	deleted, err := c.configDBModule.DeletePendingRequest(ctx, realmName, requestID)
+	if err != nil {
+		c.logger.Warn(ctx, "err", err.Error())
+		return dto.PendingRequest{}, err
+	}
+	if !deleted {
+		return dto.PendingRequest{}, errorhandler.CreateNotFoundError(constants.PendingRequest)
+	}
+	return request, nil
+}
+
+func (c *component) executePendingRequest(ctx context.Context, realmName string, request dto.PendingRequest) (string, error) {
+	switch request.Action {
+	case MGMTDeleteUser.String():
+		return "", c.DeleteUser(ctx, realmName, request.TargetID)
+	case MGMTResetPassword.String():
+		return c.ResetPassword(ctx, realmName, request.TargetID, api.PasswordRepresentation{})
+	case MGMTDeleteCredentialsForUser.String():
+		var parameters credentialParameters
+		if err := json.Unmarshal([]byte(request.Parameters), &parameters); err != nil {
+			return "", err
+		}
+		return "", c.DeleteCredentialsForUser(ctx, realmName, request.TargetID, parameters.CredentialID)
+	case MGMTUpdateAuthorizations.String():
+		var authorizations api.AuthorizationsRepresentation
+		if err := json.Unmarshal([]byte(request.Parameters), &authorizations); err != nil {
+			return "", err
+		}
+		return "", c.UpdateAuthorizations(ctx, realmName, request.TargetID, authorizations)
+	case MGMTSetTrustIDGroups.String():
+		var groups []api.TrustIDGroupRepresentation
+		if err := json.Unmarshal([]byte(request.Parameters), &groups); err != nil {
+			return "", err
+		}
+		return "", c.SetTrustIDGroupsToUser(ctx, realmName, request.TargetID, groups)
+	case pendingApplyAuthorizationTemplate:
+		var parameters authorizationTemplateParameters
+		if err := json.Unmarshal([]byte(request.Parameters), &parameters); err != nil {
+			return "", err
+		}
+		return "", c.ApplyAuthorizationTemplate(ctx, realmName, request.TargetID, parameters.TemplateName, parameters.TargetRealm)
+	case pendingRollbackAuthorizations:
+		var parameters versionParameters
+		if err := json.Unmarshal([]byte(request.Parameters), &parameters); err != nil {
+			return "", err
+		}
+		return "", c.RollbackAuthorizations(ctx, realmName, request.TargetID, parameters.Version)
+	case MGMTCopyAuthorizations.String():
+		var parameters copyAuthorizationsParameters
+		if err := json.Unmarshal([]byte(request.Parameters), &parameters); err != nil {
+			return "", err
+		}
+		var _, err = c.CopyAuthorizations(ctx, parameters.SourceRealm, realmName, false)
+		return "", err
+	case MGMTDeleteGroup.String():
+		return "", c.DeleteGroup(ctx, realmName, request.TargetID)
+	case MGMTUpdateGroup.String():
+		var group api.GroupRepresentation
+		if err := json.Unmarshal([]byte(request.Parameters), &group); err != nil {
+			return "", err
+		}
+		return "", c.UpdateGroup(ctx, realmName, request.TargetID, group)
+	case MGMTMergeUsers.String():
+		var merge api.UsersMergeRepresentation
+		if err := json.Unmarshal([]byte(request.Parameters), &merge); err != nil {
+			return "", err
+		}
+		return "", c.MergeUsers(ctx, realmName, request.TargetID, merge)
+	case MGMTUpdateRealmAdminConfiguration.String():
+		var adminConfig api.RealmAdminConfiguration
+		if err := json.Unmarshal([]byte(request.Parameters), &adminConfig); err != nil {
+			return "", err
+		}
+		return "", c.UpdateRealmAdminConfiguration(ctx, realmName, adminConfig, "")
+	case pendingRollbackRealmAdminConfiguration:
+		var parameters versionParameters
+		if err := json.Unmarshal([]byte(request.Parameters), &parameters); err != nil {
+			return "", err
+		}
+		return "", c.RollbackRealmAdminConfiguration(ctx, realmName, parameters.Version)
+	}
+	return "", errorhandler.CreateInternalServerError(constants.PendingRequest)
+}
+
+// withApprovedRequest adds the requester of the approved request being executed to the values of an event
+func withApprovedRequest(ctx context.Context, values []string) []string {
+	var request, ok = ctx.Value(approvedRequestKey{}).(dto.PendingRequest)
+	if !ok {
+		return values
+	}
+
+	var additionalInfo = map[string]interface{}{}
+	var res = make([]string, 0, len(values)+2)
+	for i := 0; i+1 < len(values); i += 2 {
+		if values[i] == database.CtEventAdditionalInfo {
+			_ = json.Unmarshal([]byte(values[i+1]), &additionalInfo)
+			continue
+		}
+		res = append(res, values[i], values[i+1])
+	}
+	additionalInfo["requestId"] = request.ID
+	additionalInfo["requester"] = request.Requester
+	additionalInfo["approver"] = ctx.Value(cs.CtContextUsername)
+
+	var bytes, _ = json.Marshal(additionalInfo)
+	return append(res, database.CtEventAdditionalInfo, string(bytes))
+}
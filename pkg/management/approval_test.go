@@ -0,0 +1,359 @@
+package management
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	cs "github.com/cloudtrust/common-service"
+	"github.com/cloudtrust/common-service/database"
+	errorhandler "github.com/cloudtrust/common-service/errors"
+	"github.com/cloudtrust/common-service/log"
+	"github.com/cloudtrust/common-service/security"
+	api "github.com/cloudtrust/keycloak-bridge/api/management"
+	"github.com/cloudtrust/keycloak-bridge/internal/dto"
+	"github.com/cloudtrust/keycloak-bridge/internal/keycloakb"
+	"github.com/cloudtrust/keycloak-bridge/pkg/management/mock"
+	kc "github.com/cloudtrust/keycloak-client"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApprovalComponentMW(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+	var mockKeycloakClient = mock.NewKeycloakClient(mockCtrl)
+	var mockConfigurationDBModule = mock.NewConfigurationDBModule(mockCtrl)
+	var mockEventDBModule = mock.NewEventDBModule(mockCtrl)
+	var mockComponent = mock.NewManagementComponent(mockCtrl)
+
+	var approvalMW = MakeApprovalComponentMW(mockKeycloakClient, mockConfigurationDBModule, mockEventDBModule, time.Hour, log.NewNopLogger())(mockComponent)
+
+	var accessToken = "TOKEN=="
+	var realmName = "my-realm"
+	var realmID = "1234-5678"
+	var userID = "user-id"
+	var groupID = "group-id"
+	var anyError = errors.New("error")
+	var ctx = context.WithValue(context.Background(), cs.CtContextAccessToken, accessToken)
+	ctx = context.WithValue(ctx, cs.CtContextUserID, "requester-id")
+	ctx = context.WithValue(ctx, cs.CtContextUsername, "requester")
+
+	var expectApprovalActions = func(actions ...string) {
+		mockKeycloakClient.EXPECT().GetRealm(accessToken, realmName).Return(kc.RealmRepresentation{ID: &realmID}, nil)
+		mockConfigurationDBModule.EXPECT().GetApprovalActions(ctx, realmID).Return(actions, nil)
+	}
+	var expectPendingRequest = func(action, targetID, parameters string) {
+		mockConfigurationDBModule.EXPECT().CreatePendingRequest(ctx, gomock.Any()).DoAndReturn(func(_ context.Context, request dto.PendingRequest) error {
+			assert.Len(t, request.ID, 32)
+			assert.Equal(t, realmName, request.RealmName)
+			assert.Equal(t, action, request.Action)
+			assert.Equal(t, targetID, request.TargetID)
+			assert.Equal(t, parameters, request.Parameters)
+			assert.Equal(t, "requester-id", request.RequesterID)
+			assert.Equal(t, "requester", request.Requester)
+			assert.Equal(t, request.CreatedAt+3600, request.ExpiresAt)
+			return nil
+		})
+		mockEventDBModule.EXPECT().ReportEvent(ctx, "API_PENDING_REQUEST_CREATION", "back-office", gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil)
+	}
+
+	t.Run("Can't get realm", func(t *testing.T) {
+		mockKeycloakClient.EXPECT().GetRealm(accessToken, realmName).Return(kc.RealmRepresentation{}, anyError)
+		assert.Equal(t, anyError, approvalMW.DeleteUser(ctx, realmName, userID))
+	})
+	t.Run("Can't get approval actions", func(t *testing.T) {
+		mockKeycloakClient.EXPECT().GetRealm(accessToken, realmName).Return(kc.RealmRepresentation{ID: &realmID}, nil)
+		mockConfigurationDBModule.EXPECT().GetApprovalActions(ctx, realmID).Return(nil, anyError)
+		assert.Equal(t, anyError, approvalMW.DeleteUser(ctx, realmName, userID))
+	})
+	t.Run("Can't store pending request", func(t *testing.T) {
+		expectApprovalActions(MGMTDeleteUser.String())
+		mockConfigurationDBModule.EXPECT().CreatePendingRequest(ctx, gomock.Any()).Return(anyError)
+		assert.Equal(t, anyError, approvalMW.DeleteUser(ctx, realmName, userID))
+	})
+
+	t.Run("Delete user-No approval required", func(t *testing.T) {
+		expectApprovalActions(MGMTResetPassword.String())
+		mockComponent.EXPECT().DeleteUser(ctx, realmName, userID).Return(nil)
+		assert.Nil(t, approvalMW.DeleteUser(ctx, realmName, userID))
+	})
+	t.Run("Delete user-Pending approval", func(t *testing.T) {
+		expectApprovalActions(MGMTDeleteUser.String())
+		expectPendingRequest(MGMTDeleteUser.String(), userID, "")
+		var err = approvalMW.DeleteUser(ctx, realmName, userID)
+		assert.IsType(t, PendingApproval{}, err)
+		assert.Equal(t, MGMTDeleteUser.String(), *err.(PendingApproval).Request.Action)
+	})
+
+	t.Run("Reset password-No approval required", func(t *testing.T) {
+		var pwd = "P@ssw0rd"
+		expectApprovalActions()
+		mockComponent.EXPECT().ResetPassword(ctx, realmName, userID, api.PasswordRepresentation{Value: &pwd}).Return("", nil)
+		var _, err = approvalMW.ResetPassword(ctx, realmName, userID, api.PasswordRepresentation{Value: &pwd})
+		assert.Nil(t, err)
+	})
+	t.Run("Reset password-Password can't be kept in a pending request", func(t *testing.T) {
+		var pwd = "P@ssw0rd"
+		expectApprovalActions(MGMTResetPassword.String())
+		var _, err = approvalMW.ResetPassword(ctx, realmName, userID, api.PasswordRepresentation{Value: &pwd})
+		assert.IsType(t, errorhandler.Error{}, err)
+	})
+	t.Run("Reset password-Pending approval", func(t *testing.T) {
+		expectApprovalActions(MGMTResetPassword.String())
+		expectPendingRequest(MGMTResetPassword.String(), userID, "")
+		var _, err = approvalMW.ResetPassword(ctx, realmName, userID, api.PasswordRepresentation{})
+		assert.IsType(t, PendingApproval{}, err)
+	})
+
+	t.Run("Delete credential-No approval required", func(t *testing.T) {
+		expectApprovalActions()
+		mockComponent.EXPECT().DeleteCredentialsForUser(ctx, realmName, userID, "cred-id").Return(nil)
+		assert.Nil(t, approvalMW.DeleteCredentialsForUser(ctx, realmName, userID, "cred-id"))
+	})
+	t.Run("Delete credential-Pending approval", func(t *testing.T) {
+		expectApprovalActions(MGMTDeleteCredentialsForUser.String())
+		expectPendingRequest(MGMTDeleteCredentialsForUser.String(), userID, `{"credentialId":"cred-id"}`)
+		assert.IsType(t, PendingApproval{}, approvalMW.DeleteCredentialsForUser(ctx, realmName, userID, "cred-id"))
+	})
+
+	t.Run("Update authorizations-No approval required", func(t *testing.T) {
+		expectApprovalActions()
+		mockComponent.EXPECT().UpdateAuthorizations(ctx, realmName, groupID, api.AuthorizationsRepresentation{}).Return(nil)
+		assert.Nil(t, approvalMW.UpdateAuthorizations(ctx, realmName, groupID, api.AuthorizationsRepresentation{}))
+	})
+	t.Run("Update authorizations-Pending approval", func(t *testing.T) {
+		var matrix = map[string]map[string]map[string]struct{}{"MGMT_GetUsers": {realmName: {"*": {}}}}
+		expectApprovalActions(MGMTUpdateAuthorizations.String())
+		expectPendingRequest(MGMTUpdateAuthorizations.String(), groupID, `{"matrix":{"MGMT_GetUsers":{"my-realm":{"*":{}}}}}`)
+		assert.IsType(t, PendingApproval{}, approvalMW.UpdateAuthorizations(ctx, realmName, groupID, api.AuthorizationsRepresentation{Matrix: &matrix}))
+	})
+
+	t.Run("Set trustID groups-No approval required", func(t *testing.T) {
+		var groups []api.TrustIDGroupRepresentation
+		expectApprovalActions()
+		mockComponent.EXPECT().SetTrustIDGroupsToUser(ctx, realmName, userID, groups).Return(nil)
+		assert.Nil(t, approvalMW.SetTrustIDGroupsToUser(ctx, realmName, userID, groups))
+	})
+	t.Run("Set trustID groups-Pending approval", func(t *testing.T) {
+		var group = "l1_support_agent"
+		expectApprovalActions(MGMTSetTrustIDGroups.String())
+		expectPendingRequest(MGMTSetTrustIDGroups.String(), userID, `[{"name":"l1_support_agent"}]`)
+		assert.IsType(t, PendingApproval{}, approvalMW.SetTrustIDGroupsToUser(ctx, realmName, userID, []api.TrustIDGroupRepresentation{{Name: &group}}))
+	})
+
+	t.Run("Apply authorization template-Pending approval", func(t *testing.T) {
+		expectApprovalActions(MGMTUpdateAuthorizations.String())
+		expectPendingRequest(pendingApplyAuthorizationTemplate, groupID, `{"templateName":"template","targetRealm":"DEP"}`)
+		assert.IsType(t, PendingApproval{}, approvalMW.ApplyAuthorizationTemplate(ctx, realmName, groupID, "template", "DEP"))
+	})
+	t.Run("Rollback authorizations-No approval required", func(t *testing.T) {
+		expectApprovalActions(MGMTDeleteUser.String())
+		mockComponent.EXPECT().RollbackAuthorizations(ctx, realmName, groupID, 3).Return(nil)
+		assert.Nil(t, approvalMW.RollbackAuthorizations(ctx, realmName, groupID, 3))
+	})
+	t.Run("Rollback authorizations-Pending approval", func(t *testing.T) {
+		expectApprovalActions(MGMTUpdateAuthorizations.String())
+		expectPendingRequest(pendingRollbackAuthorizations, groupID, `{"version":3}`)
+		assert.IsType(t, PendingApproval{}, approvalMW.RollbackAuthorizations(ctx, realmName, groupID, 3))
+	})
+	t.Run("Copy authorizations-Dry run is not intercepted", func(t *testing.T) {
+		mockComponent.EXPECT().CopyAuthorizations(ctx, "DEP", realmName, true).Return(api.AuthorizationsCopyRepresentation{}, nil)
+		var _, err = approvalMW.CopyAuthorizations(ctx, "DEP", realmName, true)
+		assert.Nil(t, err)
+	})
+	t.Run("Copy authorizations-Pending approval in the target realm", func(t *testing.T) {
+		expectApprovalActions(MGMTUpdateAuthorizations.String())
+		expectPendingRequest(MGMTCopyAuthorizations.String(), realmName, `{"sourceRealm":"DEP"}`)
+		var _, err = approvalMW.CopyAuthorizations(ctx, "DEP", realmName, false)
+		assert.IsType(t, PendingApproval{}, err)
+	})
+	t.Run("Delete group-Pending approval", func(t *testing.T) {
+		expectApprovalActions(MGMTUpdateAuthorizations.String())
+		expectPendingRequest(MGMTDeleteGroup.String(), groupID, "")
+		assert.IsType(t, PendingApproval{}, approvalMW.DeleteGroup(ctx, realmName, groupID))
+	})
+	t.Run("Update group-Same name is not intercepted", func(t *testing.T) {
+		var name = "support"
+		var group = api.GroupRepresentation{Name: &name}
+		expectApprovalActions(MGMTUpdateAuthorizations.String())
+		mockKeycloakClient.EXPECT().GetGroup(accessToken, realmName, groupID).Return(kc.GroupRepresentation{Name: &name}, nil)
+		mockComponent.EXPECT().UpdateGroup(ctx, realmName, groupID, group).Return(nil)
+		assert.Nil(t, approvalMW.UpdateGroup(ctx, realmName, groupID, group))
+	})
+	t.Run("Update group-Renaming is pending approval", func(t *testing.T) {
+		var name, newName = "support", "admins"
+		expectApprovalActions(MGMTUpdateAuthorizations.String())
+		mockKeycloakClient.EXPECT().GetGroup(accessToken, realmName, groupID).Return(kc.GroupRepresentation{Name: &name}, nil)
+		expectPendingRequest(MGMTUpdateGroup.String(), groupID, `{"name":"admins"}`)
+		assert.IsType(t, PendingApproval{}, approvalMW.UpdateGroup(ctx, realmName, groupID, api.GroupRepresentation{Name: &newName}))
+	})
+	t.Run("Merge users-Pending approval when user deletion needs approval", func(t *testing.T) {
+		var duplicateUserID = "duplicate-id"
+		expectApprovalActions(MGMTDeleteUser.String())
+		expectPendingRequest(MGMTMergeUsers.String(), userID, `{"duplicateUserId":"duplicate-id"}`)
+		assert.IsType(t, PendingApproval{}, approvalMW.MergeUsers(ctx, realmName, userID, api.UsersMergeRepresentation{DuplicateUserID: &duplicateUserID}))
+	})
+
+	t.Run("Admin configuration-Adding approval actions is not intercepted", func(t *testing.T) {
+		var adminConfig = api.RealmAdminConfiguration{ApprovalActions: &[]string{MGMTDeleteUser.String(), MGMTResetPassword.String()}}
+		expectApprovalActions(MGMTDeleteUser.String())
+		mockComponent.EXPECT().UpdateRealmAdminConfiguration(ctx, realmName, adminConfig, "etag").Return(nil)
+		assert.Nil(t, approvalMW.UpdateRealmAdminConfiguration(ctx, realmName, adminConfig, "etag"))
+	})
+	t.Run("Admin configuration-Removing approval actions is pending approval", func(t *testing.T) {
+		expectApprovalActions(MGMTDeleteUser.String())
+		mockConfigurationDBModule.EXPECT().CreatePendingRequest(ctx, gomock.Any()).DoAndReturn(func(_ context.Context, request dto.PendingRequest) error {
+			assert.Equal(t, MGMTUpdateRealmAdminConfiguration.String(), request.Action)
+			assert.Equal(t, realmName, request.TargetID)
+			return nil
+		})
+		mockEventDBModule.EXPECT().ReportEvent(ctx, "API_PENDING_REQUEST_CREATION", "back-office", gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil)
+		assert.IsType(t, PendingApproval{}, approvalMW.UpdateRealmAdminConfiguration(ctx, realmName, api.RealmAdminConfiguration{}, ""))
+	})
+	t.Run("Admin configuration-Rollback removing approval actions is pending approval", func(t *testing.T) {
+		expectApprovalActions(MGMTDeleteUser.String())
+		mockConfigurationDBModule.EXPECT().GetRealmConfigurationVersion(ctx, realmID, keycloakb.RealmConfigurationTypeAdmin, 2).
+			Return(dto.RealmConfigurationVersion{Configuration: `{"mode":"trustID"}`}, nil)
+		expectPendingRequest(pendingRollbackRealmAdminConfiguration, realmName, `{"version":2}`)
+		assert.IsType(t, PendingApproval{}, approvalMW.RollbackRealmAdminConfiguration(ctx, realmName, 2))
+	})
+	t.Run("Admin configuration-Rollback without approval actions is not intercepted", func(t *testing.T) {
+		expectApprovalActions()
+		mockComponent.EXPECT().RollbackRealmAdminConfiguration(ctx, realmName, 2).Return(nil)
+		assert.Nil(t, approvalMW.RollbackRealmAdminConfiguration(ctx, realmName, 2))
+	})
+
+	t.Run("Other actions are not intercepted", func(t *testing.T) {
+		mockComponent.EXPECT().LockUser(ctx, realmName, userID).Return(nil)
+		assert.Nil(t, approvalMW.LockUser(ctx, realmName, userID))
+	})
+}
+
+func TestPendingRequests(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+	var mockKeycloakClient = mock.NewKeycloakClient(mockCtrl)
+	var mockUsersDetailsDBModule = mock.NewUsersDetailsDBModule(mockCtrl)
+	var mockEventDBModule = mock.NewEventDBModule(mockCtrl)
+	var mockConfigurationDBModule = mock.NewConfigurationDBModule(mockCtrl)
+
+	var component = NewComponent(mockKeycloakClient, mockUsersDetailsDBModule, mockEventDBModule, mockConfigurationDBModule, nil, nil, nil, log.NewNopLogger())
+
+	var accessToken = "TOKEN=="
+	var realmName = "my-realm"
+	var userID = "user-id"
+	var requestID = "0123456789abcdef0123456789abcdef"
+	var anyError = errors.New("error")
+	var ctx = context.WithValue(context.Background(), cs.CtContextAccessToken, accessToken)
+	ctx = context.WithValue(ctx, cs.CtContextUserID, "approver-id")
+	ctx = context.WithValue(ctx, cs.CtContextUsername, "approver")
+
+	var createRequest = func(action string, parameters string) dto.PendingRequest {
+		var now = time.Now()
+		return dto.PendingRequest{
+			ID:          requestID,
+			RealmName:   realmName,
+			Action:      action,
+			TargetID:    userID,
+			Parameters:  parameters,
+			RequesterID: "requester-id",
+			Requester:   "requester",
+			CreatedAt:   now.Unix(),
+			ExpiresAt:   now.Add(time.Hour).Unix(),
+		}
+	}
+
+	t.Run("List-Fails", func(t *testing.T) {
+		mockConfigurationDBModule.EXPECT().GetPendingRequests(ctx, realmName, gomock.Any()).Return(nil, anyError)
+		var _, err = component.GetPendingRequests(ctx, realmName)
+		assert.Equal(t, anyError, err)
+	})
+	t.Run("List-Success", func(t *testing.T) {
+		mockConfigurationDBModule.EXPECT().GetPendingRequests(ctx, realmName, gomock.Any()).Return([]dto.PendingRequest{createRequest(MGMTDeleteUser.String(), "")}, nil)
+		var res, err = component.GetPendingRequests(ctx, realmName)
+		assert.Nil(t, err)
+		assert.Len(t, res, 1)
+		assert.Equal(t, requestID, *res[0].ID)
+	})
+
+	t.Run("Get-Fails", func(t *testing.T) {
+		mockConfigurationDBModule.EXPECT().GetPendingRequest(ctx, realmName, requestID).Return(dto.PendingRequest{}, anyError)
+		var _, err = component.GetPendingRequest(ctx, realmName, requestID)
+		assert.Equal(t, anyError, err)
+	})
+	t.Run("Get-Expired", func(t *testing.T) {
+		var request = createRequest(MGMTDeleteUser.String(), "")
+		request.ExpiresAt = time.Now().Add(-time.Minute).Unix()
+		mockConfigurationDBModule.EXPECT().GetPendingRequest(ctx, realmName, requestID).Return(request, nil)
+		var _, err = component.GetPendingRequest(ctx, realmName, requestID)
+		assert.IsType(t, errorhandler.Error{}, err)
+	})
+	t.Run("Get-Success", func(t *testing.T) {
+		mockConfigurationDBModule.EXPECT().GetPendingRequest(ctx, realmName, requestID).Return(createRequest(MGMTDeleteUser.String(), ""), nil)
+		var res, err = component.GetPendingRequest(ctx, realmName, requestID)
+		assert.Nil(t, err)
+		assert.Equal(t, "requester", *res.Requester)
+	})
+
+	t.Run("Approve-Requester can't approve its own request", func(t *testing.T) {
+		var requesterCtx = context.WithValue(ctx, cs.CtContextUserID, "requester-id")
+		mockConfigurationDBModule.EXPECT().GetPendingRequest(requesterCtx, realmName, requestID).Return(createRequest(MGMTResetPassword.String(), ""), nil)
+		var _, err = component.ApprovePendingRequest(requesterCtx, realmName, requestID)
+		assert.Equal(t, security.ForbiddenError{}, err)
+	})
+	t.Run("Approve-Can't delete request", func(t *testing.T) {
+		mockConfigurationDBModule.EXPECT().GetPendingRequest(ctx, realmName, requestID).Return(createRequest(MGMTResetPassword.String(), ""), nil)
+		mockConfigurationDBModule.EXPECT().DeletePendingRequest(ctx, realmName, requestID).Return(false, anyError)
+		var _, err = component.ApprovePendingRequest(ctx, realmName, requestID)
+		assert.Equal(t, anyError, err)
+	})
+	t.Run("Approve-Request completed concurrently", func(t *testing.T) {
+		mockConfigurationDBModule.EXPECT().GetPendingRequest(ctx, realmName, requestID).Return(createRequest(MGMTResetPassword.String(), ""), nil)
+		mockConfigurationDBModule.EXPECT().DeletePendingRequest(ctx, realmName, requestID).Return(false, nil)
+		var _, err = component.ApprovePendingRequest(ctx, realmName, requestID)
+		assert.IsType(t, errorhandler.Error{}, err)
+	})
+	t.Run("Approve-Invalid parameters", func(t *testing.T) {
+		var request = createRequest(MGMTDeleteCredentialsForUser.String(), "{")
+		mockConfigurationDBModule.EXPECT().GetPendingRequest(ctx, realmName, requestID).Return(request, nil)
+		mockConfigurationDBModule.EXPECT().DeletePendingRequest(ctx, realmName, requestID).Return(true, nil)
+		mockConfigurationDBModule.EXPECT().CreatePendingRequest(ctx, request).Return(nil)
+		var _, err = component.ApprovePendingRequest(ctx, realmName, requestID)
+		assert.NotNil(t, err)
+	})
+	t.Run("Approve-Execution fails, request is restored", func(t *testing.T) {
+		var request = createRequest(MGMTDeleteCredentialsForUser.String(), `{"credentialId":"cred-id"}`)
+		mockConfigurationDBModule.EXPECT().GetPendingRequest(ctx, realmName, requestID).Return(request, nil)
+		mockConfigurationDBModule.EXPECT().DeletePendingRequest(ctx, realmName, requestID).Return(true, nil)
+		mockKeycloakClient.EXPECT().GetCredentials(accessToken, realmName, userID).Return(nil, anyError)
+		mockConfigurationDBModule.EXPECT().CreatePendingRequest(ctx, request).Return(nil)
+		var _, err = component.ApprovePendingRequest(ctx, realmName, requestID)
+		assert.Equal(t, anyError, err)
+	})
+	t.Run("Approve-Password reset", func(t *testing.T) {
+		mockConfigurationDBModule.EXPECT().GetPendingRequest(ctx, realmName, requestID).Return(createRequest(MGMTResetPassword.String(), ""), nil)
+		mockConfigurationDBModule.EXPECT().DeletePendingRequest(ctx, realmName, requestID).Return(true, nil)
+		mockKeycloakClient.EXPECT().ResetPassword(accessToken, realmName, userID, gomock.Any()).Return(nil)
+		// The event of the executed action mentions the requester
+		mockEventDBModule.EXPECT().ReportEvent(gomock.Any(), "INIT_PASSWORD", "back-office", database.CtEventRealmName, realmName, database.CtEventUserID, userID,
+			database.CtEventAdditionalInfo, `{"approver":"approver","requestId":"0123456789abcdef0123456789abcdef","requester":"requester"}`).Return(nil)
+		mockEventDBModule.EXPECT().ReportEvent(ctx, "API_PENDING_REQUEST_APPROVAL", "back-office", gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil)
+		var pwd, err = component.ApprovePendingRequest(ctx, realmName, requestID)
+		assert.Nil(t, err)
+		assert.NotEqual(t, "", pwd)
+	})
+
+	t.Run("Reject-Requester can't reject its own request", func(t *testing.T) {
+		var requesterCtx = context.WithValue(ctx, cs.CtContextUserID, "requester-id")
+		mockConfigurationDBModule.EXPECT().GetPendingRequest(requesterCtx, realmName, requestID).Return(createRequest(MGMTDeleteUser.String(), ""), nil)
+		assert.Equal(t, security.ForbiddenError{}, component.RejectPendingRequest(requesterCtx, realmName, requestID))
+	})
+	t.Run("Reject-Success", func(t *testing.T) {
+		mockConfigurationDBModule.EXPECT().GetPendingRequest(ctx, realmName, requestID).Return(createRequest(MGMTDeleteUser.String(), ""), nil)
+		mockConfigurationDBModule.EXPECT().DeletePendingRequest(ctx, realmName, requestID).Return(true, nil)
+		mockEventDBModule.EXPECT().ReportEvent(ctx, "API_PENDING_REQUEST_REJECTION", "back-office", gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil)
+		assert.Nil(t, component.RejectPendingRequest(ctx, realmName, requestID))
+	})
+}
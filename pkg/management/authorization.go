@@ -2,6 +2,7 @@ package management
 
 import (
 	"context"
+	"encoding/json"
 
 	cs "github.com/cloudtrust/common-service"
 	"github.com/cloudtrust/common-service/log"
@@ -35,7 +36,15 @@ var (
 	MGMTLockUser                            = newAction("MGMT_LockUser", security.ScopeGroup)
 	MGMTUnlockUser                          = newAction("MGMT_UnlockUser", security.ScopeGroup)
 	MGMTGetUsers                            = newAction("MGMT_GetUsers", security.ScopeGroup)
+	MGMTSearchUsers                         = newAction("MGMT_SearchUsers", security.ScopeGroup)
+	MGMTGetDuplicateUsers                   = newAction("MGMT_GetDuplicateUsers", security.ScopeGroup)
+	MGMTMergeUsers                          = newAction("MGMT_MergeUsers", security.ScopeGroup)
+	MGMTGetUserHistory                      = newAction("MGMT_GetUserHistory", security.ScopeGroup)
 	MGMTCreateUser                          = newAction("MGMT_CreateUser", security.ScopeGroup)
+	MGMTImportUsers                         = newAction("MGMT_ImportUsers", security.ScopeGroup)
+	MGMTGetUsersImportJob                   = newAction("MGMT_GetUsersImportJob", security.ScopeRealm)
+	MGMTStartBulkOperation                  = newAction("MGMT_StartBulkOperation", security.ScopeRealm)
+	MGMTGetBulkOperation                    = newAction("MGMT_GetBulkOperation", security.ScopeRealm)
 	MGMTGetUserAccountStatus                = newAction("MGMT_GetUserAccountStatus", security.ScopeGroup)
 	MGMTGetRolesOfUser                      = newAction("MGMT_GetRolesOfUser", security.ScopeGroup)
 	MGMTGetGroupsOfUser                     = newAction("MGMT_GetGroupsOfUser", security.ScopeGroup)
@@ -46,6 +55,7 @@ var (
 	MGMTSetTrustIDGroups                    = newAction("MGMT_SetTrustIDGroups", security.ScopeGroup)
 	MGMTGetClientRolesForUser               = newAction("MGMT_GetClientRolesForUser", security.ScopeGroup)
 	MGMTAddClientRolesToUser                = newAction("MGMT_AddClientRolesToUser", security.ScopeGroup)
+	MGMTDeleteClientRolesFromUser           = newAction("MGMT_DeleteClientRolesFromUser", security.ScopeGroup)
 	MGMTResetPassword                       = newAction("MGMT_ResetPassword", security.ScopeGroup)
 	MGMTExecuteActionsEmail                 = newAction("MGMT_ExecuteActionsEmail", security.ScopeGroup)
 	MGMTSendNewEnrolmentCode                = newAction("MGMT_SendNewEnrolmentCode", security.ScopeGroup)
@@ -57,15 +67,37 @@ var (
 	MGMTResetCredentialFailuresForUser      = newAction("MGMT_ResetCredentialFailuresForUser", security.ScopeGroup)
 	MGMTClearUserLoginFailures              = newAction("MGMT_ClearUserLoginFailures", security.ScopeGroup)
 	MGMTGetAttackDetectionStatus            = newAction("MGMT_GetAttackDetectionStatus", security.ScopeGroup)
+	MGMTGetSessionsOfUser                   = newAction("MGMT_GetSessionsOfUser", security.ScopeGroup)
+	MGMTLogoutUser                          = newAction("MGMT_LogoutUser", security.ScopeGroup)
+	MGMTGetOfflineSessionsOfUser            = newAction("MGMT_GetOfflineSessionsOfUser", security.ScopeGroup)
+	MGMTGetConsentsOfUser                   = newAction("MGMT_GetConsentsOfUser", security.ScopeGroup)
+	MGMTRevokeConsentOfUser                 = newAction("MGMT_RevokeConsentOfUser", security.ScopeGroup)
 	MGMTGetRoles                            = newAction("MGMT_GetRoles", security.ScopeRealm)
 	MGMTGetRole                             = newAction("MGMT_GetRole", security.ScopeRealm)
+	MGMTCreateRole                          = newAction("MGMT_CreateRole", security.ScopeRealm)
+	MGMTUpdateRole                          = newAction("MGMT_UpdateRole", security.ScopeRealm)
+	MGMTDeleteRole                          = newAction("MGMT_DeleteRole", security.ScopeRealm)
+	MGMTGetRoleComposites                   = newAction("MGMT_GetRoleComposites", security.ScopeRealm)
+	MGMTUpdateRoleComposites                = newAction("MGMT_UpdateRoleComposites", security.ScopeRealm)
+	MGMTGetUsersWithRole                    = newAction("MGMT_GetUsersWithRole", security.ScopeRealm)
 	MGMTGetGroups                           = newAction("MGMT_GetGroups", security.ScopeRealm)
 	MGMTCreateGroup                         = newAction("MGMT_CreateGroup", security.ScopeRealm)
 	MGMTDeleteGroup                         = newAction("MGMT_DeleteGroup", security.ScopeGroup)
+	MGMTGetGroup                            = newAction("MGMT_GetGroup", security.ScopeGroup)
+	MGMTUpdateGroup                         = newAction("MGMT_UpdateGroup", security.ScopeGroup)
+	MGMTCreateGroupChild                    = newAction("MGMT_CreateGroupChild", security.ScopeGroup)
+	MGMTMoveGroup                           = newAction("MGMT_MoveGroup", security.ScopeGroup)
+	MGMTGetGroupMembers                     = newAction("MGMT_GetGroupMembers", security.ScopeGroup)
 	MGMTGetAuthorizations                   = newAction("MGMT_GetAuthorizations", security.ScopeGroup)
 	MGMTUpdateAuthorizations                = newAction("MGMT_UpdateAuthorizations", security.ScopeGroup)
+	MGMTCheckAuthorizationOfUser            = newAction("MGMT_CheckAuthorizationOfUser", security.ScopeGroup)
+	MGMTGetAuthorizationTemplates           = newAction("MGMT_GetAuthorizationTemplates", security.ScopeGlobal)
+	MGMTUpdateAuthorizationTemplate         = newAction("MGMT_UpdateAuthorizationTemplate", security.ScopeGlobal)
+	MGMTDeleteAuthorizationTemplate         = newAction("MGMT_DeleteAuthorizationTemplate", security.ScopeGlobal)
+	MGMTCopyAuthorizations                  = newAction("MGMT_CopyAuthorizations", security.ScopeRealm)
 	MGMTGetClientRoles                      = newAction("MGMT_GetClientRoles", security.ScopeRealm)
 	MGMTCreateClientRole                    = newAction("MGMT_CreateClientRole", security.ScopeRealm)
+	MGMTDeleteClientRole                    = newAction("MGMT_DeleteClientRole", security.ScopeRealm)
 	MGMTGetRealmCustomConfiguration         = newAction("MGMT_GetRealmCustomConfiguration", security.ScopeRealm)
 	MGMTUpdateRealmCustomConfiguration      = newAction("MGMT_UpdateRealmCustomConfiguration", security.ScopeRealm)
 	MGMTGetRealmAdminConfiguration          = newAction("MGMT_GetRealmAdminConfiguration", security.ScopeRealm)
@@ -74,6 +106,10 @@ var (
 	MGMTUpdateRealmBackOfficeConfiguration  = newAction("MGMT_UpdateRealmBackOfficeConfiguration", security.ScopeGroup)
 	MGMTGetUserRealmBackOfficeConfiguration = newAction("MGMT_GetUserRealmBackOfficeConfiguration", security.ScopeRealm)
 	MGMTLinkShadowUser                      = newAction("MGMT_LinkShadowUser", security.ScopeRealm)
+	MGMTGetFederatedIdentities              = newAction("MGMT_GetFederatedIdentities", security.ScopeGroup)
+	MGMTUnlinkShadowUser                    = newAction("MGMT_UnlinkShadowUser", security.ScopeGroup)
+	MGMTGetPendingRequests                  = newAction("MGMT_GetPendingRequests", security.ScopeRealm)
+	MGMTApprovePendingRequest               = newAction("MGMT_ApprovePendingRequest", security.ScopeRealm)
 )
 
 // Tracking middleware at component level.
@@ -183,18 +219,18 @@ func (c *authorizationComponentMW) DeleteUser(ctx context.Context, realmName, us
 	return c.next.DeleteUser(ctx, realmName, userID)
 }
 
-func (c *authorizationComponentMW) GetUser(ctx context.Context, realmName, userID string) (api.UserRepresentation, error) {
+func (c *authorizationComponentMW) GetUser(ctx context.Context, realmName, userID string) (api.UserRepresentation, string, error) {
 	var action = MGMTGetUser.String()
 	var targetRealm = realmName
 
 	if err := c.authManager.CheckAuthorizationOnTargetUser(ctx, action, targetRealm, userID); err != nil {
-		return api.UserRepresentation{}, err
+		return api.UserRepresentation{}, "", err
 	}
 
 	return c.next.GetUser(ctx, realmName, userID)
 }
 
-func (c *authorizationComponentMW) UpdateUser(ctx context.Context, realmName, userID string, user api.UserRepresentation) error {
+func (c *authorizationComponentMW) UpdateUser(ctx context.Context, realmName, userID string, user api.UserRepresentation, ifMatch string) error {
 	var action = MGMTUpdateUser.String()
 	var targetRealm = realmName
 
@@ -202,7 +238,7 @@ func (c *authorizationComponentMW) UpdateUser(ctx context.Context, realmName, us
 		return err
 	}
 
-	return c.next.UpdateUser(ctx, realmName, userID, user)
+	return c.next.UpdateUser(ctx, realmName, userID, user, ifMatch)
 }
 
 func (c *authorizationComponentMW) LockUser(ctx context.Context, realmName, userID string) error {
@@ -240,6 +276,93 @@ func (c *authorizationComponentMW) GetUsers(ctx context.Context, realmName strin
 	return c.next.GetUsers(ctx, realmName, groupIDs, paramKV...)
 }
 
+func (c *authorizationComponentMW) GetUserHistory(ctx context.Context, realmName, userID string) ([]api.UserChangeRepresentation, error) {
+	var action = MGMTGetUserHistory.String()
+	var targetRealm = realmName
+
+	if err := c.authManager.CheckAuthorizationOnTargetUser(ctx, action, targetRealm, userID); err != nil {
+		return []api.UserChangeRepresentation{}, err
+	}
+
+	return c.next.GetUserHistory(ctx, realmName, userID)
+}
+
+func (c *authorizationComponentMW) SearchUsers(ctx context.Context, realmName string, groupIDs []string, criteria api.UsersSearchRepresentation) (api.UsersPageRepresentation, error) {
+	var action = MGMTSearchUsers.String()
+	var targetRealm = realmName
+
+	for _, groupID := range groupIDs {
+		if err := c.authManager.CheckAuthorizationOnTargetGroupID(ctx, action, targetRealm, groupID); err != nil {
+			return api.UsersPageRepresentation{}, err
+		}
+	}
+
+	return c.next.SearchUsers(ctx, realmName, groupIDs, criteria)
+}
+
+func (c *authorizationComponentMW) GetDuplicateUsers(ctx context.Context, realmName string, groupIDs []string) ([]api.DuplicateUsersRepresentation, error) {
+	var action = MGMTGetDuplicateUsers.String()
+	var targetRealm = realmName
+
+	for _, groupID := range groupIDs {
+		if err := c.authManager.CheckAuthorizationOnTargetGroupID(ctx, action, targetRealm, groupID); err != nil {
+			return nil, err
+		}
+	}
+
+	return c.next.GetDuplicateUsers(ctx, realmName, groupIDs)
+}
+
+func (c *authorizationComponentMW) MergeUsers(ctx context.Context, realmName string, userID string, merge api.UsersMergeRepresentation) error {
+	var action = MGMTMergeUsers.String()
+	var targetRealm = realmName
+
+	if err := c.checkMergeUsers(ctx, action, targetRealm, userID, merge); err != nil {
+		return err
+	}
+
+	return c.next.MergeUsers(ctx, realmName, userID, merge)
+}
+
+func (c *authorizationComponentMW) checkMergeUsers(ctx context.Context, action string, realmName string, userID string, merge api.UsersMergeRepresentation) error {
+	// Both users are modified
+	if err := c.authManager.CheckAuthorizationOnTargetUser(ctx, action, realmName, userID); err != nil {
+		return err
+	}
+	if merge.DuplicateUserID != nil {
+		if err := c.authManager.CheckAuthorizationOnTargetUser(ctx, action, realmName, *merge.DuplicateUserID); err != nil {
+			return err
+		}
+		// The groups of the duplicate are added to the kept user: they are checked as for AddGroupToUser
+		if merge.Groups != nil && *merge.Groups {
+			if err := c.checkMergedGroups(ctx, realmName, userID, *merge.DuplicateUserID); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (c *authorizationComponentMW) checkMergedGroups(ctx context.Context, realmName string, userID string, duplicateUserID string) error {
+	if err := c.authManager.CheckAuthorizationOnTargetUser(ctx, MGMTSetGroupsToUser.String(), realmName, userID); err != nil {
+		return err
+	}
+
+	groups, err := c.next.GetGroupsOfUser(ctx, realmName, duplicateUserID)
+	if err != nil {
+		return err
+	}
+	for _, group := range groups {
+		if group.ID == nil {
+			continue
+		}
+		if err := c.authManager.CheckAuthorizationOnTargetGroupID(ctx, MGMTAssignableGroupsToUser.String(), realmName, *group.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (c *authorizationComponentMW) CreateUser(ctx context.Context, realmName string, user api.UserRepresentation) (string, error) {
 	var action = MGMTCreateUser.String()
 	var targetRealm = realmName
@@ -253,6 +376,69 @@ func (c *authorizationComponentMW) CreateUser(ctx context.Context, realmName str
 	return c.next.CreateUser(ctx, realmName, user)
 }
 
+func (c *authorizationComponentMW) checkUsersImportGroups(ctx context.Context, realmName string, users []api.UserImportEntry) error {
+	var action = MGMTImportUsers.String()
+	var checkedGroups = make(map[string]bool)
+
+	for _, entry := range users {
+		if entry.User.Groups == nil {
+			continue
+		}
+		for _, targetGroup := range *entry.User.Groups {
+			if checkedGroups[targetGroup] {
+				continue
+			}
+			if err := c.authManager.CheckAuthorizationOnTargetGroupID(ctx, action, realmName, targetGroup); err != nil {
+				return err
+			}
+			checkedGroups[targetGroup] = true
+		}
+	}
+	if len(checkedGroups) == 0 {
+		// No group to check: the user must at least be allowed to import users in the realm
+		return c.authManager.CheckAuthorizationOnTargetRealm(ctx, action, realmName)
+	}
+	return nil
+}
+
+func (c *authorizationComponentMW) CheckUsersImport(ctx context.Context, realmName string, users []api.UserImportEntry) (api.UserImportReportRepresentation, error) {
+	if err := c.checkUsersImportGroups(ctx, realmName, users); err != nil {
+		return api.UserImportReportRepresentation{}, err
+	}
+
+	return c.next.CheckUsersImport(ctx, realmName, users)
+}
+
+func (c *authorizationComponentMW) ImportUsers(ctx context.Context, realmName string, users []api.UserImportEntry, actions []api.RequiredAction) (api.UserImportJobRepresentation, error) {
+	if err := c.checkUsersImportGroups(ctx, realmName, users); err != nil {
+		return api.UserImportJobRepresentation{}, err
+	}
+
+	return c.next.ImportUsers(ctx, realmName, users, actions)
+}
+
+func (c *authorizationComponentMW) GetUsersImportJob(ctx context.Context, realmName string, jobID string) (api.UserImportJobRepresentation, error) {
+	var action = MGMTGetUsersImportJob.String()
+	var targetRealm = realmName
+
+	if err := c.authManager.CheckAuthorizationOnTargetRealm(ctx, action, targetRealm); err != nil {
+		return api.UserImportJobRepresentation{}, err
+	}
+
+	return c.next.GetUsersImportJob(ctx, realmName, jobID)
+}
+
+func (c *authorizationComponentMW) GetUsersImportResults(ctx context.Context, realmName string, jobID string) (api.UserImportRowsRepresentation, error) {
+	var action = MGMTGetUsersImportJob.String()
+	var targetRealm = realmName
+
+	if err := c.authManager.CheckAuthorizationOnTargetRealm(ctx, action, targetRealm); err != nil {
+		return nil, err
+	}
+
+	return c.next.GetUsersImportResults(ctx, realmName, jobID)
+}
+
 func (c *authorizationComponentMW) GetUserAccountStatus(ctx context.Context, realmName, userID string) (map[string]bool, error) {
 	var action = MGMTGetUserAccountStatus.String()
 	var targetRealm = realmName
@@ -329,7 +515,7 @@ func (c *authorizationComponentMW) GetAvailableTrustIDGroups(ctx context.Context
 	return c.next.GetAvailableTrustIDGroups(ctx, realmName)
 }
 
-func (c *authorizationComponentMW) GetTrustIDGroupsOfUser(ctx context.Context, realmName, userID string) ([]string, error) {
+func (c *authorizationComponentMW) GetTrustIDGroupsOfUser(ctx context.Context, realmName, userID string) ([]api.TrustIDGroupRepresentation, error) {
 	var action = MGMTGetTrustIDGroups.String()
 	var targetRealm = realmName
 
@@ -340,7 +526,7 @@ func (c *authorizationComponentMW) GetTrustIDGroupsOfUser(ctx context.Context, r
 	return c.next.GetTrustIDGroupsOfUser(ctx, realmName, userID)
 }
 
-func (c *authorizationComponentMW) SetTrustIDGroupsToUser(ctx context.Context, realmName, userID string, groupNames []string) error {
+func (c *authorizationComponentMW) SetTrustIDGroupsToUser(ctx context.Context, realmName, userID string, groups []api.TrustIDGroupRepresentation) error {
 	var action = MGMTSetTrustIDGroups.String()
 	var targetRealm = realmName
 
@@ -348,7 +534,7 @@ func (c *authorizationComponentMW) SetTrustIDGroupsToUser(ctx context.Context, r
 		return err
 	}
 
-	return c.next.SetTrustIDGroupsToUser(ctx, realmName, userID, groupNames)
+	return c.next.SetTrustIDGroupsToUser(ctx, realmName, userID, groups)
 }
 
 func (c *authorizationComponentMW) GetClientRolesForUser(ctx context.Context, realmName, userID, clientID string) ([]api.RoleRepresentation, error) {
@@ -373,6 +559,17 @@ func (c *authorizationComponentMW) AddClientRolesToUser(ctx context.Context, rea
 	return c.next.AddClientRolesToUser(ctx, realmName, userID, clientID, roles)
 }
 
+func (c *authorizationComponentMW) DeleteClientRoleFromUser(ctx context.Context, realmName, userID, clientID string, roleID string) error {
+	var action = MGMTDeleteClientRolesFromUser.String()
+	var targetRealm = realmName
+
+	if err := c.authManager.CheckAuthorizationOnTargetUser(ctx, action, targetRealm, userID); err != nil {
+		return err
+	}
+
+	return c.next.DeleteClientRoleFromUser(ctx, realmName, userID, clientID, roleID)
+}
+
 func (c *authorizationComponentMW) ResetPassword(ctx context.Context, realmName string, userID string, password api.PasswordRepresentation) (string, error) {
 	var action = MGMTResetPassword.String()
 	var targetRealm = realmName
@@ -494,6 +691,72 @@ func (c *authorizationComponentMW) GetAttackDetectionStatus(ctx context.Context,
 	return c.next.GetAttackDetectionStatus(ctx, realmName, userID)
 }
 
+func (c *authorizationComponentMW) GetSessionsOfUser(ctx context.Context, realmName, userID string) ([]api.UserSessionRepresentation, error) {
+	var action = MGMTGetSessionsOfUser.String()
+	var targetRealm = realmName
+
+	if err := c.authManager.CheckAuthorizationOnTargetUser(ctx, action, targetRealm, userID); err != nil {
+		return []api.UserSessionRepresentation{}, err
+	}
+
+	return c.next.GetSessionsOfUser(ctx, realmName, userID)
+}
+
+func (c *authorizationComponentMW) LogoutUser(ctx context.Context, realmName, userID string) error {
+	var action = MGMTLogoutUser.String()
+	var targetRealm = realmName
+
+	if err := c.authManager.CheckAuthorizationOnTargetUser(ctx, action, targetRealm, userID); err != nil {
+		return err
+	}
+
+	return c.next.LogoutUser(ctx, realmName, userID)
+}
+
+func (c *authorizationComponentMW) DeleteSessionOfUser(ctx context.Context, realmName, userID, sessionID string) error {
+	var action = MGMTLogoutUser.String()
+	var targetRealm = realmName
+
+	if err := c.authManager.CheckAuthorizationOnTargetUser(ctx, action, targetRealm, userID); err != nil {
+		return err
+	}
+
+	return c.next.DeleteSessionOfUser(ctx, realmName, userID, sessionID)
+}
+
+func (c *authorizationComponentMW) GetOfflineSessionsOfUser(ctx context.Context, realmName, userID, clientID string) ([]api.UserSessionRepresentation, error) {
+	var action = MGMTGetOfflineSessionsOfUser.String()
+	var targetRealm = realmName
+
+	if err := c.authManager.CheckAuthorizationOnTargetUser(ctx, action, targetRealm, userID); err != nil {
+		return []api.UserSessionRepresentation{}, err
+	}
+
+	return c.next.GetOfflineSessionsOfUser(ctx, realmName, userID, clientID)
+}
+
+func (c *authorizationComponentMW) GetConsentsOfUser(ctx context.Context, realmName, userID string) ([]api.UserConsentRepresentation, error) {
+	var action = MGMTGetConsentsOfUser.String()
+	var targetRealm = realmName
+
+	if err := c.authManager.CheckAuthorizationOnTargetUser(ctx, action, targetRealm, userID); err != nil {
+		return []api.UserConsentRepresentation{}, err
+	}
+
+	return c.next.GetConsentsOfUser(ctx, realmName, userID)
+}
+
+func (c *authorizationComponentMW) RevokeConsentOfUser(ctx context.Context, realmName, userID, clientID string) error {
+	var action = MGMTRevokeConsentOfUser.String()
+	var targetRealm = realmName
+
+	if err := c.authManager.CheckAuthorizationOnTargetUser(ctx, action, targetRealm, userID); err != nil {
+		return err
+	}
+
+	return c.next.RevokeConsentOfUser(ctx, realmName, userID, clientID)
+}
+
 func (c *authorizationComponentMW) GetRoles(ctx context.Context, realmName string) ([]api.RoleRepresentation, error) {
 	var action = MGMTGetRoles.String()
 	var targetRealm = realmName
@@ -516,6 +779,83 @@ func (c *authorizationComponentMW) GetRole(ctx context.Context, realmName string
 	return c.next.GetRole(ctx, realmName, roleID)
 }
 
+func (c *authorizationComponentMW) CreateRole(ctx context.Context, realmName string, role api.RoleRepresentation) (string, error) {
+	var action = MGMTCreateRole.String()
+	var targetRealm = realmName
+
+	if err := c.authManager.CheckAuthorizationOnTargetRealm(ctx, action, targetRealm); err != nil {
+		return "", err
+	}
+
+	return c.next.CreateRole(ctx, realmName, role)
+}
+
+func (c *authorizationComponentMW) UpdateRole(ctx context.Context, realmName string, roleID string, role api.RoleRepresentation) error {
+	var action = MGMTUpdateRole.String()
+	var targetRealm = realmName
+
+	if err := c.authManager.CheckAuthorizationOnTargetRealm(ctx, action, targetRealm); err != nil {
+		return err
+	}
+
+	return c.next.UpdateRole(ctx, realmName, roleID, role)
+}
+
+func (c *authorizationComponentMW) DeleteRole(ctx context.Context, realmName string, roleID string) error {
+	var action = MGMTDeleteRole.String()
+	var targetRealm = realmName
+
+	if err := c.authManager.CheckAuthorizationOnTargetRealm(ctx, action, targetRealm); err != nil {
+		return err
+	}
+
+	return c.next.DeleteRole(ctx, realmName, roleID)
+}
+
+func (c *authorizationComponentMW) GetRoleComposites(ctx context.Context, realmName string, roleID string) ([]api.RoleRepresentation, error) {
+	var action = MGMTGetRoleComposites.String()
+	var targetRealm = realmName
+
+	if err := c.authManager.CheckAuthorizationOnTargetRealm(ctx, action, targetRealm); err != nil {
+		return nil, err
+	}
+
+	return c.next.GetRoleComposites(ctx, realmName, roleID)
+}
+
+func (c *authorizationComponentMW) AddRoleComposites(ctx context.Context, realmName string, roleID string, roles []api.RoleRepresentation) error {
+	var action = MGMTUpdateRoleComposites.String()
+	var targetRealm = realmName
+
+	if err := c.authManager.CheckAuthorizationOnTargetRealm(ctx, action, targetRealm); err != nil {
+		return err
+	}
+
+	return c.next.AddRoleComposites(ctx, realmName, roleID, roles)
+}
+
+func (c *authorizationComponentMW) DeleteRoleComposites(ctx context.Context, realmName string, roleID string, roles []api.RoleRepresentation) error {
+	var action = MGMTUpdateRoleComposites.String()
+	var targetRealm = realmName
+
+	if err := c.authManager.CheckAuthorizationOnTargetRealm(ctx, action, targetRealm); err != nil {
+		return err
+	}
+
+	return c.next.DeleteRoleComposites(ctx, realmName, roleID, roles)
+}
+
+func (c *authorizationComponentMW) GetUsersWithRole(ctx context.Context, realmName string, roleID string, paramKV ...string) ([]api.UserRepresentation, error) {
+	var action = MGMTGetUsersWithRole.String()
+	var targetRealm = realmName
+
+	if err := c.authManager.CheckAuthorizationOnTargetRealm(ctx, action, targetRealm); err != nil {
+		return nil, err
+	}
+
+	return c.next.GetUsersWithRole(ctx, realmName, roleID, paramKV...)
+}
+
 func (c *authorizationComponentMW) GetGroups(ctx context.Context, realmName string) ([]api.GroupRepresentation, error) {
 	var action = MGMTGetGroups.String()
 	var targetRealm = realmName
@@ -549,6 +889,60 @@ func (c *authorizationComponentMW) DeleteGroup(ctx context.Context, realmName st
 	return c.next.DeleteGroup(ctx, realmName, groupID)
 }
 
+func (c *authorizationComponentMW) GetGroup(ctx context.Context, realmName string, groupID string) (api.GroupRepresentation, error) {
+	var action = MGMTGetGroup.String()
+
+	if err := c.authManager.CheckAuthorizationOnTargetGroupID(ctx, action, realmName, groupID); err != nil {
+		return api.GroupRepresentation{}, err
+	}
+
+	return c.next.GetGroup(ctx, realmName, groupID)
+}
+
+func (c *authorizationComponentMW) UpdateGroup(ctx context.Context, realmName string, groupID string, group api.GroupRepresentation) error {
+	var action = MGMTUpdateGroup.String()
+
+	if err := c.authManager.CheckAuthorizationOnTargetGroupID(ctx, action, realmName, groupID); err != nil {
+		return err
+	}
+
+	return c.next.UpdateGroup(ctx, realmName, groupID, group)
+}
+
+func (c *authorizationComponentMW) CreateGroupChild(ctx context.Context, realmName string, groupID string, group api.GroupRepresentation) (string, error) {
+	var action = MGMTCreateGroupChild.String()
+
+	if err := c.authManager.CheckAuthorizationOnTargetGroupID(ctx, action, realmName, groupID); err != nil {
+		return "", err
+	}
+
+	return c.next.CreateGroupChild(ctx, realmName, groupID, group)
+}
+
+func (c *authorizationComponentMW) MoveGroup(ctx context.Context, realmName string, groupID string, childGroupID string) error {
+	var action = MGMTMoveGroup.String()
+
+	// Both the new parent and the moved group must be allowed
+	if err := c.authManager.CheckAuthorizationOnTargetGroupID(ctx, action, realmName, groupID); err != nil {
+		return err
+	}
+	if err := c.authManager.CheckAuthorizationOnTargetGroupID(ctx, action, realmName, childGroupID); err != nil {
+		return err
+	}
+
+	return c.next.MoveGroup(ctx, realmName, groupID, childGroupID)
+}
+
+func (c *authorizationComponentMW) GetGroupMembers(ctx context.Context, realmName string, groupID string, paramKV ...string) ([]api.UserRepresentation, error) {
+	var action = MGMTGetGroupMembers.String()
+
+	if err := c.authManager.CheckAuthorizationOnTargetGroupID(ctx, action, realmName, groupID); err != nil {
+		return nil, err
+	}
+
+	return c.next.GetGroupMembers(ctx, realmName, groupID, paramKV...)
+}
+
 func (c *authorizationComponentMW) GetAuthorizations(ctx context.Context, realmName string, groupID string) (api.AuthorizationsRepresentation, error) {
 	var action = MGMTGetAuthorizations.String()
 
@@ -569,6 +963,210 @@ func (c *authorizationComponentMW) UpdateAuthorizations(ctx context.Context, rea
 	return c.next.UpdateAuthorizations(ctx, realmName, groupID, group)
 }
 
+func (c *authorizationComponentMW) GetAuthorizationsVersions(ctx context.Context, realmName string, groupID string) ([]api.AuthorizationsVersionRepresentation, error) {
+	var action = MGMTGetAuthorizations.String()
+
+	if err := c.authManager.CheckAuthorizationOnTargetGroupID(ctx, action, realmName, groupID); err != nil {
+		return []api.AuthorizationsVersionRepresentation{}, err
+	}
+
+	return c.next.GetAuthorizationsVersions(ctx, realmName, groupID)
+}
+
+func (c *authorizationComponentMW) GetAuthorizationsVersionsDiff(ctx context.Context, realmName string, groupID string, fromVersion int, toVersion int) (api.AuthorizationsDiffRepresentation, error) {
+	var action = MGMTGetAuthorizations.String()
+
+	if err := c.authManager.CheckAuthorizationOnTargetGroupID(ctx, action, realmName, groupID); err != nil {
+		return api.AuthorizationsDiffRepresentation{}, err
+	}
+
+	return c.next.GetAuthorizationsVersionsDiff(ctx, realmName, groupID, fromVersion, toVersion)
+}
+
+func (c *authorizationComponentMW) RollbackAuthorizations(ctx context.Context, realmName string, groupID string, version int) error {
+	var action = MGMTUpdateAuthorizations.String()
+
+	if err := c.authManager.CheckAuthorizationOnTargetGroupID(ctx, action, realmName, groupID); err != nil {
+		return err
+	}
+
+	return c.next.RollbackAuthorizations(ctx, realmName, groupID, version)
+}
+
+func (c *authorizationComponentMW) GetPendingRequests(ctx context.Context, realmName string) ([]api.PendingRequestRepresentation, error) {
+	var action = MGMTGetPendingRequests.String()
+
+	if err := c.authManager.CheckAuthorizationOnTargetRealm(ctx, action, realmName); err != nil {
+		return []api.PendingRequestRepresentation{}, err
+	}
+
+	return c.next.GetPendingRequests(ctx, realmName)
+}
+
+func (c *authorizationComponentMW) GetPendingRequest(ctx context.Context, realmName string, requestID string) (api.PendingRequestRepresentation, error) {
+	var action = MGMTGetPendingRequests.String()
+
+	if err := c.authManager.CheckAuthorizationOnTargetRealm(ctx, action, realmName); err != nil {
+		return api.PendingRequestRepresentation{}, err
+	}
+
+	return c.next.GetPendingRequest(ctx, realmName, requestID)
+}
+
+func (c *authorizationComponentMW) ApprovePendingRequest(ctx context.Context, realmName string, requestID string) (string, error) {
+	if err := c.checkAuthorizationOnPendingRequest(ctx, realmName, requestID); err != nil {
+		return "", err
+	}
+
+	return c.next.ApprovePendingRequest(ctx, realmName, requestID)
+}
+
+func (c *authorizationComponentMW) RejectPendingRequest(ctx context.Context, realmName string, requestID string) error {
+	if err := c.checkAuthorizationOnPendingRequest(ctx, realmName, requestID); err != nil {
+		return err
+	}
+
+	return c.next.RejectPendingRequest(ctx, realmName, requestID)
+}
+
+// checkAuthorizationOnPendingRequest checks that the operator can approve pending requests and is also allowed to
+// execute the requested operation on its target, as when calling it directly
+func (c *authorizationComponentMW) checkAuthorizationOnPendingRequest(ctx context.Context, realmName string, requestID string) error {
+	if err := c.authManager.CheckAuthorizationOnTargetRealm(ctx, MGMTApprovePendingRequest.String(), realmName); err != nil {
+		return err
+	}
+
+	var request, err = c.next.GetPendingRequest(ctx, realmName, requestID)
+	if err != nil {
+		return err
+	}
+
+	var parameters = []byte("null")
+	if request.Parameters != nil {
+		parameters = *request.Parameters
+	}
+
+	switch *request.Action {
+	case MGMTUpdateAuthorizations.String(), pendingApplyAuthorizationTemplate, pendingRollbackAuthorizations:
+		return c.authManager.CheckAuthorizationOnTargetGroupID(ctx, MGMTUpdateAuthorizations.String(), realmName, *request.TargetID)
+	case MGMTDeleteGroup.String(), MGMTUpdateGroup.String():
+		return c.authManager.CheckAuthorizationOnTargetGroupID(ctx, *request.Action, realmName, *request.TargetID)
+	case MGMTCopyAuthorizations.String():
+		var copyParameters copyAuthorizationsParameters
+		if err = json.Unmarshal(parameters, &copyParameters); err != nil {
+			return err
+		}
+		for _, realm := range []string{copyParameters.SourceRealm, realmName} {
+			if err = c.authManager.CheckAuthorizationOnTargetRealm(ctx, *request.Action, realm); err != nil {
+				return err
+			}
+		}
+		return nil
+	case MGMTUpdateRealmAdminConfiguration.String(), pendingRollbackRealmAdminConfiguration:
+		return c.authManager.CheckAuthorizationOnTargetRealm(ctx, MGMTUpdateRealmAdminConfiguration.String(), realmName)
+	case MGMTMergeUsers.String():
+		var merge api.UsersMergeRepresentation
+		if err = json.Unmarshal(parameters, &merge); err != nil {
+			return err
+		}
+		return c.checkMergeUsers(ctx, *request.Action, realmName, *request.TargetID, merge)
+	}
+	return c.authManager.CheckAuthorizationOnTargetUser(ctx, *request.Action, realmName, *request.TargetID)
+}
+
+func (c *authorizationComponentMW) CheckAuthorization(ctx context.Context, realmName, userID, action, targetRealm, targetGroup string) (api.AuthorizationCheckRepresentation, error) {
+	// Any user can check its own authorizations
+	var isCurrentUser = realmName == ctx.Value(cs.CtContextRealm).(string) &&
+		(userID == "" || userID == ctx.Value(cs.CtContextUserID).(string))
+
+	if !isCurrentUser {
+		if err := c.authManager.CheckAuthorizationOnTargetUser(ctx, MGMTCheckAuthorizationOfUser.String(), realmName, userID); err != nil {
+			return api.AuthorizationCheckRepresentation{}, err
+		}
+	}
+
+	return c.next.CheckAuthorization(ctx, realmName, userID, action, targetRealm, targetGroup)
+}
+
+func (c *authorizationComponentMW) GetAuthorizationTemplates(ctx context.Context) ([]api.AuthorizationTemplateRepresentation, error) {
+	var action = MGMTGetAuthorizationTemplates.String()
+
+	// For this method, there is no target realm provided
+	// as parameter, so we pick the current realm of the user.
+	var targetRealm = ctx.Value(cs.CtContextRealm).(string)
+
+	if err := c.authManager.CheckAuthorizationOnTargetRealm(ctx, action, targetRealm); err != nil {
+		return []api.AuthorizationTemplateRepresentation{}, err
+	}
+
+	return c.next.GetAuthorizationTemplates(ctx)
+}
+
+func (c *authorizationComponentMW) GetAuthorizationTemplate(ctx context.Context, templateName string) (api.AuthorizationTemplateRepresentation, error) {
+	var action = MGMTGetAuthorizationTemplates.String()
+
+	// For this method, there is no target realm provided
+	// as parameter, so we pick the current realm of the user.
+	var targetRealm = ctx.Value(cs.CtContextRealm).(string)
+
+	if err := c.authManager.CheckAuthorizationOnTargetRealm(ctx, action, targetRealm); err != nil {
+		return api.AuthorizationTemplateRepresentation{}, err
+	}
+
+	return c.next.GetAuthorizationTemplate(ctx, templateName)
+}
+
+func (c *authorizationComponentMW) UpdateAuthorizationTemplate(ctx context.Context, templateName string, template api.AuthorizationTemplateRepresentation) error {
+	var action = MGMTUpdateAuthorizationTemplate.String()
+
+	// For this method, there is no target realm provided
+	// as parameter, so we pick the current realm of the user.
+	var targetRealm = ctx.Value(cs.CtContextRealm).(string)
+
+	if err := c.authManager.CheckAuthorizationOnTargetRealm(ctx, action, targetRealm); err != nil {
+		return err
+	}
+
+	return c.next.UpdateAuthorizationTemplate(ctx, templateName, template)
+}
+
+func (c *authorizationComponentMW) DeleteAuthorizationTemplate(ctx context.Context, templateName string) error {
+	var action = MGMTDeleteAuthorizationTemplate.String()
+
+	// For this method, there is no target realm provided
+	// as parameter, so we pick the current realm of the user.
+	var targetRealm = ctx.Value(cs.CtContextRealm).(string)
+
+	if err := c.authManager.CheckAuthorizationOnTargetRealm(ctx, action, targetRealm); err != nil {
+		return err
+	}
+
+	return c.next.DeleteAuthorizationTemplate(ctx, templateName)
+}
+
+func (c *authorizationComponentMW) ApplyAuthorizationTemplate(ctx context.Context, realmName string, groupID string, templateName string, targetRealm string) error {
+	var action = MGMTUpdateAuthorizations.String()
+
+	if err := c.authManager.CheckAuthorizationOnTargetGroupID(ctx, action, realmName, groupID); err != nil {
+		return err
+	}
+
+	return c.next.ApplyAuthorizationTemplate(ctx, realmName, groupID, templateName, targetRealm)
+}
+
+func (c *authorizationComponentMW) CopyAuthorizations(ctx context.Context, sourceRealm string, targetRealm string, dryRun bool) (api.AuthorizationsCopyRepresentation, error) {
+	var action = MGMTCopyAuthorizations.String()
+
+	// The authorizations of the source realm are disclosed and the ones of the target realm are modified
+	for _, realm := range []string{sourceRealm, targetRealm} {
+		if err := c.authManager.CheckAuthorizationOnTargetRealm(ctx, action, realm); err != nil {
+			return api.AuthorizationsCopyRepresentation{}, err
+		}
+	}
+
+	return c.next.CopyAuthorizations(ctx, sourceRealm, targetRealm, dryRun)
+}
+
 func (c *authorizationComponentMW) GetClientRoles(ctx context.Context, realmName, idClient string) ([]api.RoleRepresentation, error) {
 	var action = MGMTGetClientRoles.String()
 	var targetRealm = realmName
@@ -591,18 +1189,29 @@ func (c *authorizationComponentMW) CreateClientRole(ctx context.Context, realmNa
 	return c.next.CreateClientRole(ctx, realmName, clientID, role)
 }
 
-func (c *authorizationComponentMW) GetRealmCustomConfiguration(ctx context.Context, realmName string) (api.RealmCustomConfiguration, error) {
+func (c *authorizationComponentMW) DeleteClientRole(ctx context.Context, realmName, clientID string, roleID string) error {
+	var action = MGMTDeleteClientRole.String()
+	var targetRealm = realmName
+
+	if err := c.authManager.CheckAuthorizationOnTargetRealm(ctx, action, targetRealm); err != nil {
+		return err
+	}
+
+	return c.next.DeleteClientRole(ctx, realmName, clientID, roleID)
+}
+
+func (c *authorizationComponentMW) GetRealmCustomConfiguration(ctx context.Context, realmName string) (api.RealmCustomConfiguration, string, error) {
 	var action = MGMTGetRealmCustomConfiguration.String()
 	var targetRealm = realmName
 
 	if err := c.authManager.CheckAuthorizationOnTargetRealm(ctx, action, targetRealm); err != nil {
-		return api.RealmCustomConfiguration{}, err
+		return api.RealmCustomConfiguration{}, "", err
 	}
 
 	return c.next.GetRealmCustomConfiguration(ctx, realmName)
 }
 
-func (c *authorizationComponentMW) UpdateRealmCustomConfiguration(ctx context.Context, realmName string, customConfig api.RealmCustomConfiguration) error {
+func (c *authorizationComponentMW) UpdateRealmCustomConfiguration(ctx context.Context, realmName string, customConfig api.RealmCustomConfiguration, ifMatch string) error {
 	var action = MGMTUpdateRealmCustomConfiguration.String()
 	var targetRealm = realmName
 
@@ -610,21 +1219,87 @@ func (c *authorizationComponentMW) UpdateRealmCustomConfiguration(ctx context.Co
 		return err
 	}
 
-	return c.next.UpdateRealmCustomConfiguration(ctx, realmName, customConfig)
+	return c.next.UpdateRealmCustomConfiguration(ctx, realmName, customConfig, ifMatch)
 }
 
-func (c *authorizationComponentMW) GetRealmAdminConfiguration(ctx context.Context, realmName string) (api.RealmAdminConfiguration, error) {
+func (c *authorizationComponentMW) GetRealmAdminConfiguration(ctx context.Context, realmName string) (api.RealmAdminConfiguration, string, error) {
 	var action = MGMTGetRealmAdminConfiguration.String()
 	var targetRealm = realmName
 
 	if err := c.authManager.CheckAuthorizationOnTargetRealm(ctx, action, targetRealm); err != nil {
-		return api.RealmAdminConfiguration{}, err
+		return api.RealmAdminConfiguration{}, "", err
 	}
 
 	return c.next.GetRealmAdminConfiguration(ctx, realmName)
 }
 
-func (c *authorizationComponentMW) UpdateRealmAdminConfiguration(ctx context.Context, realmName string, adminConfig api.RealmAdminConfiguration) error {
+func (c *authorizationComponentMW) UpdateRealmAdminConfiguration(ctx context.Context, realmName string, adminConfig api.RealmAdminConfiguration, ifMatch string) error {
+	var action = MGMTUpdateRealmAdminConfiguration.String()
+	var targetRealm = realmName
+
+	if err := c.authManager.CheckAuthorizationOnTargetRealm(ctx, action, targetRealm); err != nil {
+		return err
+	}
+
+	return c.next.UpdateRealmAdminConfiguration(ctx, realmName, adminConfig, ifMatch)
+}
+
+func (c *authorizationComponentMW) GetRealmCustomConfigurationVersions(ctx context.Context, realmName string) ([]api.RealmConfigurationVersionRepresentation, error) {
+	var action = MGMTGetRealmCustomConfiguration.String()
+	var targetRealm = realmName
+
+	if err := c.authManager.CheckAuthorizationOnTargetRealm(ctx, action, targetRealm); err != nil {
+		return nil, err
+	}
+
+	return c.next.GetRealmCustomConfigurationVersions(ctx, realmName)
+}
+
+func (c *authorizationComponentMW) GetRealmCustomConfigurationVersionsDiff(ctx context.Context, realmName string, fromVersion int, toVersion int) (api.RealmConfigurationDiffRepresentation, error) {
+	var action = MGMTGetRealmCustomConfiguration.String()
+	var targetRealm = realmName
+
+	if err := c.authManager.CheckAuthorizationOnTargetRealm(ctx, action, targetRealm); err != nil {
+		return api.RealmConfigurationDiffRepresentation{}, err
+	}
+
+	return c.next.GetRealmCustomConfigurationVersionsDiff(ctx, realmName, fromVersion, toVersion)
+}
+
+func (c *authorizationComponentMW) RollbackRealmCustomConfiguration(ctx context.Context, realmName string, version int) error {
+	var action = MGMTUpdateRealmCustomConfiguration.String()
+	var targetRealm = realmName
+
+	if err := c.authManager.CheckAuthorizationOnTargetRealm(ctx, action, targetRealm); err != nil {
+		return err
+	}
+
+	return c.next.RollbackRealmCustomConfiguration(ctx, realmName, version)
+}
+
+func (c *authorizationComponentMW) GetRealmAdminConfigurationVersions(ctx context.Context, realmName string) ([]api.RealmConfigurationVersionRepresentation, error) {
+	var action = MGMTGetRealmAdminConfiguration.String()
+	var targetRealm = realmName
+
+	if err := c.authManager.CheckAuthorizationOnTargetRealm(ctx, action, targetRealm); err != nil {
+		return nil, err
+	}
+
+	return c.next.GetRealmAdminConfigurationVersions(ctx, realmName)
+}
+
+func (c *authorizationComponentMW) GetRealmAdminConfigurationVersionsDiff(ctx context.Context, realmName string, fromVersion int, toVersion int) (api.RealmConfigurationDiffRepresentation, error) {
+	var action = MGMTGetRealmAdminConfiguration.String()
+	var targetRealm = realmName
+
+	if err := c.authManager.CheckAuthorizationOnTargetRealm(ctx, action, targetRealm); err != nil {
+		return api.RealmConfigurationDiffRepresentation{}, err
+	}
+
+	return c.next.GetRealmAdminConfigurationVersionsDiff(ctx, realmName, fromVersion, toVersion)
+}
+
+func (c *authorizationComponentMW) RollbackRealmAdminConfiguration(ctx context.Context, realmName string, version int) error {
 	var action = MGMTUpdateRealmAdminConfiguration.String()
 	var targetRealm = realmName
 
@@ -632,7 +1307,7 @@ func (c *authorizationComponentMW) UpdateRealmAdminConfiguration(ctx context.Con
 		return err
 	}
 
-	return c.next.UpdateRealmAdminConfiguration(ctx, realmName, adminConfig)
+	return c.next.RollbackRealmAdminConfiguration(ctx, realmName, version)
 }
 
 func (c *authorizationComponentMW) GetRealmBackOfficeConfiguration(ctx context.Context, realmName string, groupName string) (api.BackOfficeConfiguration, error) {
@@ -677,3 +1352,64 @@ func (c *authorizationComponentMW) LinkShadowUser(ctx context.Context, realmName
 
 	return c.next.LinkShadowUser(ctx, realmName, userID, provider, fedID)
 }
+
+func (c *authorizationComponentMW) GetFederatedIdentities(ctx context.Context, realmName string, userID string) ([]api.FederatedIdentityRepresentation, error) {
+	var action = MGMTGetFederatedIdentities.String()
+	var targetRealm = realmName
+	if err := c.authManager.CheckAuthorizationOnTargetUser(ctx, action, targetRealm, userID); err != nil {
+		return []api.FederatedIdentityRepresentation{}, err
+	}
+
+	return c.next.GetFederatedIdentities(ctx, realmName, userID)
+}
+
+func (c *authorizationComponentMW) UnlinkShadowUser(ctx context.Context, realmName string, userID string, provider string) error {
+	var action = MGMTUnlinkShadowUser.String()
+	var targetRealm = realmName
+	if err := c.authManager.CheckAuthorizationOnTargetUser(ctx, action, targetRealm, userID); err != nil {
+		return err
+	}
+
+	return c.next.UnlinkShadowUser(ctx, realmName, userID, provider)
+}
+
+// Authorization middleware of the bulk operations. The operation applied to each user is checked again
+// by the authorization middleware of the management component
+type authorizationBulkComponentMW struct {
+	authManager security.AuthorizationManager
+	logger      log.Logger
+	next        BulkComponent
+}
+
+// MakeAuthorizationBulkComponentMW checks authorization and return an error if the action is not allowed.
+func MakeAuthorizationBulkComponentMW(logger log.Logger, authorizationManager security.AuthorizationManager) func(BulkComponent) BulkComponent {
+	return func(next BulkComponent) BulkComponent {
+		return &authorizationBulkComponentMW{
+			authManager: authorizationManager,
+			logger:      logger,
+			next:        next,
+		}
+	}
+}
+
+func (c *authorizationBulkComponentMW) StartBulkOperation(ctx context.Context, realmName string, operation api.BulkOperationRepresentation) (api.BulkOperationJobRepresentation, error) {
+	var action = MGMTStartBulkOperation.String()
+	var targetRealm = realmName
+
+	if err := c.authManager.CheckAuthorizationOnTargetRealm(ctx, action, targetRealm); err != nil {
+		return api.BulkOperationJobRepresentation{}, err
+	}
+
+	return c.next.StartBulkOperation(ctx, realmName, operation)
+}
+
+func (c *authorizationBulkComponentMW) GetBulkOperation(ctx context.Context, realmName string, jobID string) (api.BulkOperationJobRepresentation, error) {
+	var action = MGMTGetBulkOperation.String()
+	var targetRealm = realmName
+
+	if err := c.authManager.CheckAuthorizationOnTargetRealm(ctx, action, targetRealm); err != nil {
+		return api.BulkOperationJobRepresentation{}, err
+	}
+
+	return c.next.GetBulkOperation(ctx, realmName, jobID)
+}
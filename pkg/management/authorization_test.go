@@ -2,6 +2,8 @@ package management
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"testing"
 
 	"github.com/cloudtrust/common-service/configuration"
@@ -36,6 +38,7 @@ func TestDeny(t *testing.T) {
 	var clientID = "789-789-741"
 	var roleID = "456-852-785"
 	var credentialID = "741-865-741"
+	var sessionID = "852-963-741"
 	var userUsername = "toto"
 
 	var roleName = "role"
@@ -43,7 +46,7 @@ func TestDeny(t *testing.T) {
 	var groupID = "123-789-454"
 	var groupIDs = []string{groupID}
 	var groupName = "titi"
-	var grpNames = []string{"grp1", "grp2"}
+	var grpNames = []api.TrustIDGroupRepresentation{{Name: &groupName}}
 
 	var authzMatrix = map[string]map[string]map[string]struct{}{}
 
@@ -127,10 +130,13 @@ func TestDeny(t *testing.T) {
 		err = authorizationMW.DeleteUser(ctx, realmName, userID)
 		assert.Equal(t, security.ForbiddenError{}, err)
 
-		_, err = authorizationMW.GetUser(ctx, realmName, userID)
+		_, _, err = authorizationMW.GetUser(ctx, realmName, userID)
 		assert.Equal(t, security.ForbiddenError{}, err)
 
-		err = authorizationMW.UpdateUser(ctx, realmName, userID, user)
+		err = authorizationMW.UpdateUser(ctx, realmName, userID, user, "")
+		assert.Equal(t, security.ForbiddenError{}, err)
+
+		_, err = authorizationMW.GetUserHistory(ctx, realmName, userID)
 		assert.Equal(t, security.ForbiddenError{}, err)
 
 		err = authorizationMW.LockUser(ctx, realmName, userID)
@@ -143,10 +149,38 @@ func TestDeny(t *testing.T) {
 		_, err = authorizationMW.GetUsers(ctx, realmName, groupIDs)
 		assert.Equal(t, security.ForbiddenError{}, err)
 
+		mockKeycloakClient.EXPECT().GetGroupName(gomock.Any(), gomock.Any(), realmName, groupID).Return(groupName, nil).Times(1)
+		_, err = authorizationMW.SearchUsers(ctx, realmName, groupIDs, api.UsersSearchRepresentation{})
+		assert.Equal(t, security.ForbiddenError{}, err)
+
+		mockKeycloakClient.EXPECT().GetGroupName(gomock.Any(), gomock.Any(), realmName, groupID).Return(groupName, nil).Times(1)
+		_, err = authorizationMW.GetDuplicateUsers(ctx, realmName, groupIDs)
+		assert.Equal(t, security.ForbiddenError{}, err)
+
+		err = authorizationMW.MergeUsers(ctx, realmName, userID, api.UsersMergeRepresentation{DuplicateUserID: &userID})
+		assert.Equal(t, security.ForbiddenError{}, err)
+
 		mockKeycloakClient.EXPECT().GetGroupName(gomock.Any(), gomock.Any(), realmName, groupID).Return(groupName, nil).Times(1)
 		_, err = authorizationMW.CreateUser(ctx, realmName, user)
 		assert.Equal(t, security.ForbiddenError{}, err)
 
+		mockKeycloakClient.EXPECT().GetGroupName(gomock.Any(), gomock.Any(), realmName, groupID).Return(groupName, nil).Times(1)
+		_, err = authorizationMW.CheckUsersImport(ctx, realmName, []api.UserImportEntry{{Line: 1, User: user}})
+		assert.Equal(t, security.ForbiddenError{}, err)
+
+		mockKeycloakClient.EXPECT().GetGroupName(gomock.Any(), gomock.Any(), realmName, groupID).Return(groupName, nil).Times(1)
+		_, err = authorizationMW.ImportUsers(ctx, realmName, []api.UserImportEntry{{Line: 1, User: user}}, nil)
+		assert.Equal(t, security.ForbiddenError{}, err)
+
+		_, err = authorizationMW.CheckUsersImport(ctx, realmName, []api.UserImportEntry{{Line: 1}})
+		assert.Equal(t, security.ForbiddenError{}, err)
+
+		_, err = authorizationMW.GetUsersImportJob(ctx, realmName, "job")
+		assert.Equal(t, security.ForbiddenError{}, err)
+
+		_, err = authorizationMW.GetUsersImportResults(ctx, realmName, "job")
+		assert.Equal(t, security.ForbiddenError{}, err)
+
 		_, err = authorizationMW.GetUserAccountStatus(ctx, realmName, userID)
 		assert.Equal(t, security.ForbiddenError{}, err)
 
@@ -177,6 +211,9 @@ func TestDeny(t *testing.T) {
 		err = authorizationMW.AddClientRolesToUser(ctx, realmName, userID, clientID, roles)
 		assert.Equal(t, security.ForbiddenError{}, err)
 
+		err = authorizationMW.DeleteClientRoleFromUser(ctx, realmName, userID, clientID, roleID)
+		assert.Equal(t, security.ForbiddenError{}, err)
+
 		_, err = authorizationMW.ResetPassword(ctx, realmName, userID, password)
 		assert.Equal(t, security.ForbiddenError{}, err)
 
@@ -210,12 +247,51 @@ func TestDeny(t *testing.T) {
 		_, err = authorizationMW.GetAttackDetectionStatus(ctx, realmName, userID)
 		assert.Equal(t, security.ForbiddenError{}, err)
 
+		_, err = authorizationMW.GetSessionsOfUser(ctx, realmName, userID)
+		assert.Equal(t, security.ForbiddenError{}, err)
+
+		err = authorizationMW.LogoutUser(ctx, realmName, userID)
+		assert.Equal(t, security.ForbiddenError{}, err)
+
+		err = authorizationMW.DeleteSessionOfUser(ctx, realmName, userID, sessionID)
+		assert.Equal(t, security.ForbiddenError{}, err)
+
+		_, err = authorizationMW.GetOfflineSessionsOfUser(ctx, realmName, userID, clientID)
+		assert.Equal(t, security.ForbiddenError{}, err)
+
+		_, err = authorizationMW.GetConsentsOfUser(ctx, realmName, userID)
+		assert.Equal(t, security.ForbiddenError{}, err)
+
+		err = authorizationMW.RevokeConsentOfUser(ctx, realmName, userID, clientID)
+		assert.Equal(t, security.ForbiddenError{}, err)
+
 		_, err = authorizationMW.GetRoles(ctx, realmName)
 		assert.Equal(t, security.ForbiddenError{}, err)
 
 		_, err = authorizationMW.GetRole(ctx, realmName, roleID)
 		assert.Equal(t, security.ForbiddenError{}, err)
 
+		_, err = authorizationMW.CreateRole(ctx, realmName, role)
+		assert.Equal(t, security.ForbiddenError{}, err)
+
+		err = authorizationMW.UpdateRole(ctx, realmName, roleID, role)
+		assert.Equal(t, security.ForbiddenError{}, err)
+
+		err = authorizationMW.DeleteRole(ctx, realmName, roleID)
+		assert.Equal(t, security.ForbiddenError{}, err)
+
+		_, err = authorizationMW.GetRoleComposites(ctx, realmName, roleID)
+		assert.Equal(t, security.ForbiddenError{}, err)
+
+		err = authorizationMW.AddRoleComposites(ctx, realmName, roleID, roles)
+		assert.Equal(t, security.ForbiddenError{}, err)
+
+		err = authorizationMW.DeleteRoleComposites(ctx, realmName, roleID, roles)
+		assert.Equal(t, security.ForbiddenError{}, err)
+
+		_, err = authorizationMW.GetUsersWithRole(ctx, realmName, roleID)
+		assert.Equal(t, security.ForbiddenError{}, err)
+
 		_, err = authorizationMW.GetGroups(ctx, realmName)
 		assert.Equal(t, security.ForbiddenError{}, err)
 
@@ -226,6 +302,26 @@ func TestDeny(t *testing.T) {
 		err = authorizationMW.DeleteGroup(ctx, realmName, groupID)
 		assert.Equal(t, security.ForbiddenError{}, err)
 
+		mockKeycloakClient.EXPECT().GetGroupName(gomock.Any(), gomock.Any(), realmName, groupID).Return(groupName, nil).Times(1)
+		_, err = authorizationMW.GetGroup(ctx, realmName, groupID)
+		assert.Equal(t, security.ForbiddenError{}, err)
+
+		mockKeycloakClient.EXPECT().GetGroupName(gomock.Any(), gomock.Any(), realmName, groupID).Return(groupName, nil).Times(1)
+		err = authorizationMW.UpdateGroup(ctx, realmName, groupID, group)
+		assert.Equal(t, security.ForbiddenError{}, err)
+
+		mockKeycloakClient.EXPECT().GetGroupName(gomock.Any(), gomock.Any(), realmName, groupID).Return(groupName, nil).Times(1)
+		_, err = authorizationMW.CreateGroupChild(ctx, realmName, groupID, group)
+		assert.Equal(t, security.ForbiddenError{}, err)
+
+		mockKeycloakClient.EXPECT().GetGroupName(gomock.Any(), gomock.Any(), realmName, groupID).Return(groupName, nil).Times(1)
+		err = authorizationMW.MoveGroup(ctx, realmName, groupID, groupID)
+		assert.Equal(t, security.ForbiddenError{}, err)
+
+		mockKeycloakClient.EXPECT().GetGroupName(gomock.Any(), gomock.Any(), realmName, groupID).Return(groupName, nil).Times(1)
+		_, err = authorizationMW.GetGroupMembers(ctx, realmName, groupID)
+		assert.Equal(t, security.ForbiddenError{}, err)
+
 		mockKeycloakClient.EXPECT().GetGroupName(gomock.Any(), gomock.Any(), realmName, groupID).Return(groupName, nil).Times(1)
 		_, err = authorizationMW.GetAuthorizations(ctx, realmName, groupID)
 		assert.Equal(t, security.ForbiddenError{}, err)
@@ -234,22 +330,95 @@ func TestDeny(t *testing.T) {
 		err = authorizationMW.UpdateAuthorizations(ctx, realmName, groupID, authz)
 		assert.Equal(t, security.ForbiddenError{}, err)
 
+		var operatorCtx = context.WithValue(ctx, cs.CtContextUserID, "operator-id")
+		_, err = authorizationMW.CheckAuthorization(operatorCtx, realmName, userID, MGMTGetUser.String(), realmName, groupName)
+		assert.Equal(t, security.ForbiddenError{}, err)
+
+		// Checking its own authorizations is always allowed
+		mockManagementComponent.EXPECT().CheckAuthorization(operatorCtx, realmName, "", MGMTGetUser.String(), realmName, groupName).Return(api.AuthorizationCheckRepresentation{}, nil).Times(1)
+		_, err = authorizationMW.CheckAuthorization(operatorCtx, realmName, "", MGMTGetUser.String(), realmName, groupName)
+		assert.Nil(t, err)
+
+		mockKeycloakClient.EXPECT().GetGroupName(gomock.Any(), gomock.Any(), realmName, groupID).Return(groupName, nil).Times(1)
+		_, err = authorizationMW.GetAuthorizationsVersions(ctx, realmName, groupID)
+		assert.Equal(t, security.ForbiddenError{}, err)
+
+		mockKeycloakClient.EXPECT().GetGroupName(gomock.Any(), gomock.Any(), realmName, groupID).Return(groupName, nil).Times(1)
+		_, err = authorizationMW.GetAuthorizationsVersionsDiff(ctx, realmName, groupID, 1, 2)
+		assert.Equal(t, security.ForbiddenError{}, err)
+
+		mockKeycloakClient.EXPECT().GetGroupName(gomock.Any(), gomock.Any(), realmName, groupID).Return(groupName, nil).Times(1)
+		err = authorizationMW.RollbackAuthorizations(ctx, realmName, groupID, 1)
+		assert.Equal(t, security.ForbiddenError{}, err)
+
+		_, err = authorizationMW.GetAuthorizationTemplates(ctx)
+		assert.Equal(t, security.ForbiddenError{}, err)
+
+		_, err = authorizationMW.GetAuthorizationTemplate(ctx, "template")
+		assert.Equal(t, security.ForbiddenError{}, err)
+
+		err = authorizationMW.UpdateAuthorizationTemplate(ctx, "template", api.AuthorizationTemplateRepresentation{Matrix: &authzMatrix})
+		assert.Equal(t, security.ForbiddenError{}, err)
+
+		err = authorizationMW.DeleteAuthorizationTemplate(ctx, "template")
+		assert.Equal(t, security.ForbiddenError{}, err)
+
+		mockKeycloakClient.EXPECT().GetGroupName(gomock.Any(), gomock.Any(), realmName, groupID).Return(groupName, nil).Times(1)
+		err = authorizationMW.ApplyAuthorizationTemplate(ctx, realmName, groupID, "template", "")
+		assert.Equal(t, security.ForbiddenError{}, err)
+
+		_, err = authorizationMW.CopyAuthorizations(ctx, realmName, "DEP", true)
+		assert.Equal(t, security.ForbiddenError{}, err)
+
+		_, err = authorizationMW.GetPendingRequests(ctx, realmName)
+		assert.Equal(t, security.ForbiddenError{}, err)
+
+		_, err = authorizationMW.GetPendingRequest(ctx, realmName, "request-id")
+		assert.Equal(t, security.ForbiddenError{}, err)
+
+		_, err = authorizationMW.ApprovePendingRequest(ctx, realmName, "request-id")
+		assert.Equal(t, security.ForbiddenError{}, err)
+
+		err = authorizationMW.RejectPendingRequest(ctx, realmName, "request-id")
+		assert.Equal(t, security.ForbiddenError{}, err)
+
 		_, err = authorizationMW.GetClientRoles(ctx, realmName, clientID)
 		assert.Equal(t, security.ForbiddenError{}, err)
 
 		_, err = authorizationMW.CreateClientRole(ctx, realmName, clientID, role)
 		assert.Equal(t, security.ForbiddenError{}, err)
 
-		_, err = authorizationMW.GetRealmCustomConfiguration(ctx, realmName)
+		err = authorizationMW.DeleteClientRole(ctx, realmName, clientID, roleID)
+		assert.Equal(t, security.ForbiddenError{}, err)
+
+		_, _, err = authorizationMW.GetRealmCustomConfiguration(ctx, realmName)
+		assert.Equal(t, security.ForbiddenError{}, err)
+
+		err = authorizationMW.UpdateRealmCustomConfiguration(ctx, realmName, customConfig, "")
+		assert.Equal(t, security.ForbiddenError{}, err)
+
+		_, _, err = authorizationMW.GetRealmAdminConfiguration(ctx, realmName)
+		assert.Equal(t, security.ForbiddenError{}, err)
+
+		err = authorizationMW.UpdateRealmAdminConfiguration(ctx, realmName, adminConfig, "")
+		assert.Equal(t, security.ForbiddenError{}, err)
+
+		_, err = authorizationMW.GetRealmCustomConfigurationVersions(ctx, realmName)
 		assert.Equal(t, security.ForbiddenError{}, err)
 
-		err = authorizationMW.UpdateRealmCustomConfiguration(ctx, realmName, customConfig)
+		_, err = authorizationMW.GetRealmCustomConfigurationVersionsDiff(ctx, realmName, 1, 2)
 		assert.Equal(t, security.ForbiddenError{}, err)
 
-		_, err = authorizationMW.GetRealmAdminConfiguration(ctx, realmName)
+		err = authorizationMW.RollbackRealmCustomConfiguration(ctx, realmName, 1)
 		assert.Equal(t, security.ForbiddenError{}, err)
 
-		err = authorizationMW.UpdateRealmAdminConfiguration(ctx, realmName, adminConfig)
+		_, err = authorizationMW.GetRealmAdminConfigurationVersions(ctx, realmName)
+		assert.Equal(t, security.ForbiddenError{}, err)
+
+		_, err = authorizationMW.GetRealmAdminConfigurationVersionsDiff(ctx, realmName, 1, 2)
+		assert.Equal(t, security.ForbiddenError{}, err)
+
+		err = authorizationMW.RollbackRealmAdminConfiguration(ctx, realmName, 1)
 		assert.Equal(t, security.ForbiddenError{}, err)
 
 		_, err = authorizationMW.GetRealmBackOfficeConfiguration(ctx, realmName, groupID)
@@ -263,6 +432,20 @@ func TestDeny(t *testing.T) {
 
 		err = authorizationMW.LinkShadowUser(ctx, realmName, userID, provider, fedID)
 		assert.Equal(t, security.ForbiddenError{}, err)
+
+		_, err = authorizationMW.GetFederatedIdentities(ctx, realmName, userID)
+		assert.Equal(t, security.ForbiddenError{}, err)
+
+		err = authorizationMW.UnlinkShadowUser(ctx, realmName, userID, provider)
+		assert.Equal(t, security.ForbiddenError{}, err)
+
+		var bulkMW = MakeAuthorizationBulkComponentMW(mockLogger, authorizations)(mock.NewBulkComponent(mockCtrl))
+
+		_, err = bulkMW.StartBulkOperation(ctx, realmName, api.BulkOperationRepresentation{})
+		assert.Equal(t, security.ForbiddenError{}, err)
+
+		_, err = bulkMW.GetBulkOperation(ctx, realmName, "job")
+		assert.Equal(t, security.ForbiddenError{}, err)
 	}
 }
 
@@ -282,6 +465,7 @@ func TestAllowed(t *testing.T) {
 	var clientID = "789-789-741"
 	var roleID = "456-852-785"
 	var credentialID = "7845-785-1545"
+	var sessionID = "8523-963-7412"
 	var userUsername = "toto"
 
 	var roleName = "role"
@@ -291,7 +475,7 @@ func TestAllowed(t *testing.T) {
 	var groupID = "123-789-454"
 	var groupIDs = []string{groupID}
 	var groupName = "titi"
-	var grpNames = []string{"grp1", "grp2"}
+	var grpNames = []api.TrustIDGroupRepresentation{{Name: &groupName}}
 
 	var authzMatrix = map[string]map[string]map[string]struct{}{}
 
@@ -391,12 +575,16 @@ func TestAllowed(t *testing.T) {
 		err = authorizationMW.DeleteUser(ctx, realmName, userID)
 		assert.Nil(t, err)
 
-		mockManagementComponent.EXPECT().GetUser(ctx, realmName, userID).Return(api.UserRepresentation{}, nil).Times(1)
-		_, err = authorizationMW.GetUser(ctx, realmName, userID)
+		mockManagementComponent.EXPECT().GetUser(ctx, realmName, userID).Return(api.UserRepresentation{}, "", nil).Times(1)
+		_, _, err = authorizationMW.GetUser(ctx, realmName, userID)
+		assert.Nil(t, err)
+
+		mockManagementComponent.EXPECT().UpdateUser(ctx, realmName, userID, user, "").Return(nil).Times(1)
+		err = authorizationMW.UpdateUser(ctx, realmName, userID, user, "")
 		assert.Nil(t, err)
 
-		mockManagementComponent.EXPECT().UpdateUser(ctx, realmName, userID, user).Return(nil).Times(1)
-		err = authorizationMW.UpdateUser(ctx, realmName, userID, user)
+		mockManagementComponent.EXPECT().GetUserHistory(ctx, realmName, userID).Return([]api.UserChangeRepresentation{}, nil).Times(1)
+		_, err = authorizationMW.GetUserHistory(ctx, realmName, userID)
 		assert.Nil(t, err)
 
 		mockManagementComponent.EXPECT().LockUser(ctx, realmName, userID).Return(nil).Times(1)
@@ -412,11 +600,53 @@ func TestAllowed(t *testing.T) {
 		_, err = authorizationMW.GetUsers(ctx, realmName, groupIDs)
 		assert.Nil(t, err)
 
+		mockKeycloakClient.EXPECT().GetGroupName(gomock.Any(), gomock.Any(), realmName, groupID).Return(groupName, nil).Times(1)
+		mockManagementComponent.EXPECT().SearchUsers(ctx, realmName, groupIDs, api.UsersSearchRepresentation{}).Return(api.UsersPageRepresentation{}, nil).Times(1)
+		_, err = authorizationMW.SearchUsers(ctx, realmName, groupIDs, api.UsersSearchRepresentation{})
+		assert.Nil(t, err)
+
+		mockKeycloakClient.EXPECT().GetGroupName(gomock.Any(), gomock.Any(), realmName, groupID).Return(groupName, nil).Times(1)
+		mockManagementComponent.EXPECT().GetDuplicateUsers(ctx, realmName, groupIDs).Return(nil, nil).Times(1)
+		_, err = authorizationMW.GetDuplicateUsers(ctx, realmName, groupIDs)
+		assert.Nil(t, err)
+
+		var merge = api.UsersMergeRepresentation{DuplicateUserID: &userID}
+		mockManagementComponent.EXPECT().MergeUsers(ctx, realmName, userID, merge).Return(nil).Times(1)
+		err = authorizationMW.MergeUsers(ctx, realmName, userID, merge)
+		assert.Nil(t, err)
+
+		var mergeGroups = true
+		merge.Groups = &mergeGroups
+		mockManagementComponent.EXPECT().GetGroupsOfUser(ctx, realmName, userID).Return([]api.GroupRepresentation{{ID: &groupID}}, nil).Times(1)
+		mockKeycloakClient.EXPECT().GetGroupName(gomock.Any(), gomock.Any(), realmName, groupID).Return(groupName, nil).Times(1)
+		mockManagementComponent.EXPECT().MergeUsers(ctx, realmName, userID, merge).Return(nil).Times(1)
+		err = authorizationMW.MergeUsers(ctx, realmName, userID, merge)
+		assert.Nil(t, err)
+
 		mockKeycloakClient.EXPECT().GetGroupName(gomock.Any(), gomock.Any(), realmName, groupID).Return(groupName, nil).Times(1)
 		mockManagementComponent.EXPECT().CreateUser(ctx, realmName, user).Return("", nil).Times(1)
 		_, err = authorizationMW.CreateUser(ctx, realmName, user)
 		assert.Nil(t, err)
 
+		var importedUsers = []api.UserImportEntry{{Line: 1, User: user}, {Line: 2, User: user}}
+		mockKeycloakClient.EXPECT().GetGroupName(gomock.Any(), gomock.Any(), realmName, groupID).Return(groupName, nil).Times(1)
+		mockManagementComponent.EXPECT().CheckUsersImport(ctx, realmName, importedUsers).Return(api.UserImportReportRepresentation{}, nil).Times(1)
+		_, err = authorizationMW.CheckUsersImport(ctx, realmName, importedUsers)
+		assert.Nil(t, err)
+
+		mockKeycloakClient.EXPECT().GetGroupName(gomock.Any(), gomock.Any(), realmName, groupID).Return(groupName, nil).Times(1)
+		mockManagementComponent.EXPECT().ImportUsers(ctx, realmName, importedUsers, nil).Return(api.UserImportJobRepresentation{}, nil).Times(1)
+		_, err = authorizationMW.ImportUsers(ctx, realmName, importedUsers, nil)
+		assert.Nil(t, err)
+
+		mockManagementComponent.EXPECT().GetUsersImportJob(ctx, realmName, "job").Return(api.UserImportJobRepresentation{}, nil).Times(1)
+		_, err = authorizationMW.GetUsersImportJob(ctx, realmName, "job")
+		assert.Nil(t, err)
+
+		mockManagementComponent.EXPECT().GetUsersImportResults(ctx, realmName, "job").Return(nil, nil).Times(1)
+		_, err = authorizationMW.GetUsersImportResults(ctx, realmName, "job")
+		assert.Nil(t, err)
+
 		mockManagementComponent.EXPECT().GetUserAccountStatus(ctx, realmName, userID).Return(map[string]bool{"enabled": true}, nil).Times(1)
 		_, err = authorizationMW.GetUserAccountStatus(ctx, realmName, userID)
 		assert.Nil(t, err)
@@ -447,6 +677,10 @@ func TestAllowed(t *testing.T) {
 		err = authorizationMW.AddClientRolesToUser(ctx, realmName, userID, clientID, roles)
 		assert.Nil(t, err)
 
+		mockManagementComponent.EXPECT().DeleteClientRoleFromUser(ctx, realmName, userID, clientID, roleID).Return(nil).Times(1)
+		err = authorizationMW.DeleteClientRoleFromUser(ctx, realmName, userID, clientID, roleID)
+		assert.Nil(t, err)
+
 		mockManagementComponent.EXPECT().ResetPassword(ctx, realmName, userID, password).Return("", nil).Times(1)
 		_, err = authorizationMW.ResetPassword(ctx, realmName, userID, password)
 		assert.Nil(t, err)
@@ -492,6 +726,30 @@ func TestAllowed(t *testing.T) {
 		_, err = authorizationMW.GetAttackDetectionStatus(ctx, realmName, userID)
 		assert.Nil(t, err)
 
+		mockManagementComponent.EXPECT().GetSessionsOfUser(ctx, realmName, userID).Return([]api.UserSessionRepresentation{}, nil).Times(1)
+		_, err = authorizationMW.GetSessionsOfUser(ctx, realmName, userID)
+		assert.Nil(t, err)
+
+		mockManagementComponent.EXPECT().LogoutUser(ctx, realmName, userID).Return(nil).Times(1)
+		err = authorizationMW.LogoutUser(ctx, realmName, userID)
+		assert.Nil(t, err)
+
+		mockManagementComponent.EXPECT().DeleteSessionOfUser(ctx, realmName, userID, sessionID).Return(nil).Times(1)
+		err = authorizationMW.DeleteSessionOfUser(ctx, realmName, userID, sessionID)
+		assert.Nil(t, err)
+
+		mockManagementComponent.EXPECT().GetOfflineSessionsOfUser(ctx, realmName, userID, clientID).Return([]api.UserSessionRepresentation{}, nil).Times(1)
+		_, err = authorizationMW.GetOfflineSessionsOfUser(ctx, realmName, userID, clientID)
+		assert.Nil(t, err)
+
+		mockManagementComponent.EXPECT().GetConsentsOfUser(ctx, realmName, userID).Return([]api.UserConsentRepresentation{}, nil).Times(1)
+		_, err = authorizationMW.GetConsentsOfUser(ctx, realmName, userID)
+		assert.Nil(t, err)
+
+		mockManagementComponent.EXPECT().RevokeConsentOfUser(ctx, realmName, userID, clientID).Return(nil).Times(1)
+		err = authorizationMW.RevokeConsentOfUser(ctx, realmName, userID, clientID)
+		assert.Nil(t, err)
+
 		mockManagementComponent.EXPECT().GetRoles(ctx, realmName).Return([]api.RoleRepresentation{}, nil).Times(1)
 		_, err = authorizationMW.GetRoles(ctx, realmName)
 		assert.Nil(t, err)
@@ -500,6 +758,34 @@ func TestAllowed(t *testing.T) {
 		_, err = authorizationMW.GetRole(ctx, realmName, roleID)
 		assert.Nil(t, err)
 
+		mockManagementComponent.EXPECT().CreateRole(ctx, realmName, role).Return("", nil).Times(1)
+		_, err = authorizationMW.CreateRole(ctx, realmName, role)
+		assert.Nil(t, err)
+
+		mockManagementComponent.EXPECT().UpdateRole(ctx, realmName, roleID, role).Return(nil).Times(1)
+		err = authorizationMW.UpdateRole(ctx, realmName, roleID, role)
+		assert.Nil(t, err)
+
+		mockManagementComponent.EXPECT().DeleteRole(ctx, realmName, roleID).Return(nil).Times(1)
+		err = authorizationMW.DeleteRole(ctx, realmName, roleID)
+		assert.Nil(t, err)
+
+		mockManagementComponent.EXPECT().GetRoleComposites(ctx, realmName, roleID).Return([]api.RoleRepresentation{}, nil).Times(1)
+		_, err = authorizationMW.GetRoleComposites(ctx, realmName, roleID)
+		assert.Nil(t, err)
+
+		mockManagementComponent.EXPECT().AddRoleComposites(ctx, realmName, roleID, roles).Return(nil).Times(1)
+		err = authorizationMW.AddRoleComposites(ctx, realmName, roleID, roles)
+		assert.Nil(t, err)
+
+		mockManagementComponent.EXPECT().DeleteRoleComposites(ctx, realmName, roleID, roles).Return(nil).Times(1)
+		err = authorizationMW.DeleteRoleComposites(ctx, realmName, roleID, roles)
+		assert.Nil(t, err)
+
+		mockManagementComponent.EXPECT().GetUsersWithRole(ctx, realmName, roleID).Return([]api.UserRepresentation{}, nil).Times(1)
+		_, err = authorizationMW.GetUsersWithRole(ctx, realmName, roleID)
+		assert.Nil(t, err)
+
 		mockManagementComponent.EXPECT().GetGroups(ctx, realmName).Return([]api.GroupRepresentation{}, nil).Times(1)
 		_, err = authorizationMW.GetGroups(ctx, realmName)
 		assert.Nil(t, err)
@@ -525,6 +811,31 @@ func TestAllowed(t *testing.T) {
 		err = authorizationMW.DeleteGroup(ctx, realmName, groupID)
 		assert.Nil(t, err)
 
+		mockKeycloakClient.EXPECT().GetGroupName(gomock.Any(), gomock.Any(), realmName, groupID).Return(groupName, nil).Times(1)
+		mockManagementComponent.EXPECT().GetGroup(ctx, realmName, groupID).Return(api.GroupRepresentation{}, nil).Times(1)
+		_, err = authorizationMW.GetGroup(ctx, realmName, groupID)
+		assert.Nil(t, err)
+
+		mockKeycloakClient.EXPECT().GetGroupName(gomock.Any(), gomock.Any(), realmName, groupID).Return(groupName, nil).Times(1)
+		mockManagementComponent.EXPECT().UpdateGroup(ctx, realmName, groupID, group).Return(nil).Times(1)
+		err = authorizationMW.UpdateGroup(ctx, realmName, groupID, group)
+		assert.Nil(t, err)
+
+		mockKeycloakClient.EXPECT().GetGroupName(gomock.Any(), gomock.Any(), realmName, groupID).Return(groupName, nil).Times(1)
+		mockManagementComponent.EXPECT().CreateGroupChild(ctx, realmName, groupID, group).Return("", nil).Times(1)
+		_, err = authorizationMW.CreateGroupChild(ctx, realmName, groupID, group)
+		assert.Nil(t, err)
+
+		mockKeycloakClient.EXPECT().GetGroupName(gomock.Any(), gomock.Any(), realmName, groupID).Return(groupName, nil).Times(2)
+		mockManagementComponent.EXPECT().MoveGroup(ctx, realmName, groupID, groupID).Return(nil).Times(1)
+		err = authorizationMW.MoveGroup(ctx, realmName, groupID, groupID)
+		assert.Nil(t, err)
+
+		mockKeycloakClient.EXPECT().GetGroupName(gomock.Any(), gomock.Any(), realmName, groupID).Return(groupName, nil).Times(1)
+		mockManagementComponent.EXPECT().GetGroupMembers(ctx, realmName, groupID, "max", "10").Return([]api.UserRepresentation{}, nil).Times(1)
+		_, err = authorizationMW.GetGroupMembers(ctx, realmName, groupID, "max", "10")
+		assert.Nil(t, err)
+
 		mockKeycloakClient.EXPECT().GetGroupName(gomock.Any(), gomock.Any(), realmName, groupID).Return(groupName, nil).Times(1)
 		mockManagementComponent.EXPECT().GetAuthorizations(ctx, realmName, groupID).Return(api.AuthorizationsRepresentation{}, nil).Times(1)
 		_, err = authorizationMW.GetAuthorizations(ctx, realmName, groupID)
@@ -535,6 +846,88 @@ func TestAllowed(t *testing.T) {
 		err = authorizationMW.UpdateAuthorizations(ctx, realmName, groupID, authz)
 		assert.Nil(t, err)
 
+		var operatorCtx = context.WithValue(ctx, cs.CtContextUserID, "operator-id")
+		mockManagementComponent.EXPECT().CheckAuthorization(operatorCtx, realmName, userID, MGMTGetUser.String(), realmName, groupName).Return(api.AuthorizationCheckRepresentation{}, nil).Times(1)
+		_, err = authorizationMW.CheckAuthorization(operatorCtx, realmName, userID, MGMTGetUser.String(), realmName, groupName)
+		assert.Nil(t, err)
+
+		mockKeycloakClient.EXPECT().GetGroupName(gomock.Any(), gomock.Any(), realmName, groupID).Return(groupName, nil).Times(1)
+		mockManagementComponent.EXPECT().GetAuthorizationsVersions(ctx, realmName, groupID).Return([]api.AuthorizationsVersionRepresentation{}, nil).Times(1)
+		_, err = authorizationMW.GetAuthorizationsVersions(ctx, realmName, groupID)
+		assert.Nil(t, err)
+
+		mockKeycloakClient.EXPECT().GetGroupName(gomock.Any(), gomock.Any(), realmName, groupID).Return(groupName, nil).Times(1)
+		mockManagementComponent.EXPECT().GetAuthorizationsVersionsDiff(ctx, realmName, groupID, 1, 2).Return(api.AuthorizationsDiffRepresentation{}, nil).Times(1)
+		_, err = authorizationMW.GetAuthorizationsVersionsDiff(ctx, realmName, groupID, 1, 2)
+		assert.Nil(t, err)
+
+		mockKeycloakClient.EXPECT().GetGroupName(gomock.Any(), gomock.Any(), realmName, groupID).Return(groupName, nil).Times(1)
+		mockManagementComponent.EXPECT().RollbackAuthorizations(ctx, realmName, groupID, 1).Return(nil).Times(1)
+		err = authorizationMW.RollbackAuthorizations(ctx, realmName, groupID, 1)
+		assert.Nil(t, err)
+
+		mockManagementComponent.EXPECT().GetAuthorizationTemplates(ctx).Return([]api.AuthorizationTemplateRepresentation{}, nil).Times(1)
+		_, err = authorizationMW.GetAuthorizationTemplates(ctx)
+		assert.Nil(t, err)
+
+		mockManagementComponent.EXPECT().GetAuthorizationTemplate(ctx, "template").Return(api.AuthorizationTemplateRepresentation{}, nil).Times(1)
+		_, err = authorizationMW.GetAuthorizationTemplate(ctx, "template")
+		assert.Nil(t, err)
+
+		var template = api.AuthorizationTemplateRepresentation{Matrix: &authzMatrix}
+		mockManagementComponent.EXPECT().UpdateAuthorizationTemplate(ctx, "template", template).Return(nil).Times(1)
+		err = authorizationMW.UpdateAuthorizationTemplate(ctx, "template", template)
+		assert.Nil(t, err)
+
+		mockManagementComponent.EXPECT().DeleteAuthorizationTemplate(ctx, "template").Return(nil).Times(1)
+		err = authorizationMW.DeleteAuthorizationTemplate(ctx, "template")
+		assert.Nil(t, err)
+
+		mockKeycloakClient.EXPECT().GetGroupName(gomock.Any(), gomock.Any(), realmName, groupID).Return(groupName, nil).Times(1)
+		mockManagementComponent.EXPECT().ApplyAuthorizationTemplate(ctx, realmName, groupID, "template", "").Return(nil).Times(1)
+		err = authorizationMW.ApplyAuthorizationTemplate(ctx, realmName, groupID, "template", "")
+		assert.Nil(t, err)
+
+		mockManagementComponent.EXPECT().CopyAuthorizations(ctx, realmName, "DEP", true).Return(api.AuthorizationsCopyRepresentation{}, nil).Times(1)
+		_, err = authorizationMW.CopyAuthorizations(ctx, realmName, "DEP", true)
+		assert.Nil(t, err)
+
+		var deleteUserAction = MGMTDeleteUser.String()
+		var updateAuthorizationsAction = MGMTUpdateAuthorizations.String()
+		var pendingDeletion = api.PendingRequestRepresentation{Action: &deleteUserAction, TargetID: &userID}
+		var pendingAuthorizations = api.PendingRequestRepresentation{Action: &updateAuthorizationsAction, TargetID: &groupID}
+
+		mockManagementComponent.EXPECT().GetPendingRequests(ctx, realmName).Return([]api.PendingRequestRepresentation{}, nil).Times(1)
+		_, err = authorizationMW.GetPendingRequests(ctx, realmName)
+		assert.Nil(t, err)
+
+		mockManagementComponent.EXPECT().GetPendingRequest(ctx, realmName, "request-id").Return(pendingDeletion, nil).Times(1)
+		_, err = authorizationMW.GetPendingRequest(ctx, realmName, "request-id")
+		assert.Nil(t, err)
+
+		mockManagementComponent.EXPECT().GetPendingRequest(ctx, realmName, "request-id").Return(pendingDeletion, nil).Times(1)
+		mockManagementComponent.EXPECT().ApprovePendingRequest(ctx, realmName, "request-id").Return("", nil).Times(1)
+		_, err = authorizationMW.ApprovePendingRequest(ctx, realmName, "request-id")
+		assert.Nil(t, err)
+
+		mockManagementComponent.EXPECT().GetPendingRequest(ctx, realmName, "request-id").Return(pendingAuthorizations, nil).Times(1)
+		mockKeycloakClient.EXPECT().GetGroupName(gomock.Any(), gomock.Any(), realmName, groupID).Return(groupName, nil).Times(1)
+		mockManagementComponent.EXPECT().RejectPendingRequest(ctx, realmName, "request-id").Return(nil).Times(1)
+		err = authorizationMW.RejectPendingRequest(ctx, realmName, "request-id")
+		assert.Nil(t, err)
+
+		var copyAuthorizationsAction = MGMTCopyAuthorizations.String()
+		var copyParameters = json.RawMessage(`{"sourceRealm":"DEP"}`)
+		var pendingCopy = api.PendingRequestRepresentation{Action: &copyAuthorizationsAction, TargetID: &realmName, Parameters: &copyParameters}
+		mockManagementComponent.EXPECT().GetPendingRequest(ctx, realmName, "request-id").Return(pendingCopy, nil).Times(1)
+		mockManagementComponent.EXPECT().ApprovePendingRequest(ctx, realmName, "request-id").Return("", nil).Times(1)
+		_, err = authorizationMW.ApprovePendingRequest(ctx, realmName, "request-id")
+		assert.Nil(t, err)
+
+		mockManagementComponent.EXPECT().GetPendingRequest(ctx, realmName, "request-id").Return(api.PendingRequestRepresentation{}, errors.New("error")).Times(1)
+		_, err = authorizationMW.ApprovePendingRequest(ctx, realmName, "request-id")
+		assert.NotNil(t, err)
+
 		mockManagementComponent.EXPECT().GetClientRoles(ctx, realmName, clientID).Return([]api.RoleRepresentation{}, nil).Times(1)
 		_, err = authorizationMW.GetClientRoles(ctx, realmName, clientID)
 		assert.Nil(t, err)
@@ -543,20 +936,48 @@ func TestAllowed(t *testing.T) {
 		_, err = authorizationMW.CreateClientRole(ctx, realmName, clientID, role)
 		assert.Nil(t, err)
 
-		mockManagementComponent.EXPECT().GetRealmCustomConfiguration(ctx, realmName).Return(customConfig, nil).Times(1)
-		_, err = authorizationMW.GetRealmCustomConfiguration(ctx, realmName)
+		mockManagementComponent.EXPECT().DeleteClientRole(ctx, realmName, clientID, roleID).Return(nil).Times(1)
+		err = authorizationMW.DeleteClientRole(ctx, realmName, clientID, roleID)
+		assert.Nil(t, err)
+
+		mockManagementComponent.EXPECT().GetRealmCustomConfiguration(ctx, realmName).Return(customConfig, "", nil).Times(1)
+		_, _, err = authorizationMW.GetRealmCustomConfiguration(ctx, realmName)
+		assert.Nil(t, err)
+
+		mockManagementComponent.EXPECT().UpdateRealmCustomConfiguration(ctx, realmName, customConfig, "").Return(nil).Times(1)
+		err = authorizationMW.UpdateRealmCustomConfiguration(ctx, realmName, customConfig, "")
+		assert.Nil(t, err)
+
+		mockManagementComponent.EXPECT().GetRealmAdminConfiguration(ctx, realmName).Return(adminConfig, "", nil).Times(1)
+		_, _, err = authorizationMW.GetRealmAdminConfiguration(ctx, realmName)
+		assert.Nil(t, err)
+
+		mockManagementComponent.EXPECT().UpdateRealmAdminConfiguration(ctx, realmName, adminConfig, "").Return(nil).Times(1)
+		err = authorizationMW.UpdateRealmAdminConfiguration(ctx, realmName, adminConfig, "")
 		assert.Nil(t, err)
 
-		mockManagementComponent.EXPECT().UpdateRealmCustomConfiguration(ctx, realmName, customConfig).Return(nil).Times(1)
-		err = authorizationMW.UpdateRealmCustomConfiguration(ctx, realmName, customConfig)
+		mockManagementComponent.EXPECT().GetRealmCustomConfigurationVersions(ctx, realmName).Return([]api.RealmConfigurationVersionRepresentation{}, nil).Times(1)
+		_, err = authorizationMW.GetRealmCustomConfigurationVersions(ctx, realmName)
 		assert.Nil(t, err)
 
-		mockManagementComponent.EXPECT().GetRealmAdminConfiguration(ctx, realmName).Return(adminConfig, nil).Times(1)
-		_, err = authorizationMW.GetRealmAdminConfiguration(ctx, realmName)
+		mockManagementComponent.EXPECT().GetRealmCustomConfigurationVersionsDiff(ctx, realmName, 1, 2).Return(api.RealmConfigurationDiffRepresentation{}, nil).Times(1)
+		_, err = authorizationMW.GetRealmCustomConfigurationVersionsDiff(ctx, realmName, 1, 2)
 		assert.Nil(t, err)
 
-		mockManagementComponent.EXPECT().UpdateRealmAdminConfiguration(ctx, realmName, adminConfig).Return(nil).Times(1)
-		err = authorizationMW.UpdateRealmAdminConfiguration(ctx, realmName, adminConfig)
+		mockManagementComponent.EXPECT().RollbackRealmCustomConfiguration(ctx, realmName, 1).Return(nil).Times(1)
+		err = authorizationMW.RollbackRealmCustomConfiguration(ctx, realmName, 1)
+		assert.Nil(t, err)
+
+		mockManagementComponent.EXPECT().GetRealmAdminConfigurationVersions(ctx, realmName).Return([]api.RealmConfigurationVersionRepresentation{}, nil).Times(1)
+		_, err = authorizationMW.GetRealmAdminConfigurationVersions(ctx, realmName)
+		assert.Nil(t, err)
+
+		mockManagementComponent.EXPECT().GetRealmAdminConfigurationVersionsDiff(ctx, realmName, 1, 2).Return(api.RealmConfigurationDiffRepresentation{}, nil).Times(1)
+		_, err = authorizationMW.GetRealmAdminConfigurationVersionsDiff(ctx, realmName, 1, 2)
+		assert.Nil(t, err)
+
+		mockManagementComponent.EXPECT().RollbackRealmAdminConfiguration(ctx, realmName, 1).Return(nil).Times(1)
+		err = authorizationMW.RollbackRealmAdminConfiguration(ctx, realmName, 1)
 		assert.Nil(t, err)
 
 		mockManagementComponent.EXPECT().GetRealmBackOfficeConfiguration(ctx, realmName, groupID).Return(config, nil).Times(1)
@@ -574,5 +995,24 @@ func TestAllowed(t *testing.T) {
 		mockManagementComponent.EXPECT().LinkShadowUser(ctx, realmName, userID, provider, fedID).Return(nil).Times(1)
 		err = authorizationMW.LinkShadowUser(ctx, realmName, userID, provider, fedID)
 		assert.Nil(t, err)
+
+		mockManagementComponent.EXPECT().GetFederatedIdentities(ctx, realmName, userID).Return([]api.FederatedIdentityRepresentation{}, nil).Times(1)
+		_, err = authorizationMW.GetFederatedIdentities(ctx, realmName, userID)
+		assert.Nil(t, err)
+
+		mockManagementComponent.EXPECT().UnlinkShadowUser(ctx, realmName, userID, provider).Return(nil).Times(1)
+		err = authorizationMW.UnlinkShadowUser(ctx, realmName, userID, provider)
+		assert.Nil(t, err)
+
+		var mockBulkComponent = mock.NewBulkComponent(mockCtrl)
+		var bulkMW = MakeAuthorizationBulkComponentMW(mockLogger, authorizationManager)(mockBulkComponent)
+
+		mockBulkComponent.EXPECT().StartBulkOperation(ctx, realmName, api.BulkOperationRepresentation{}).Return(api.BulkOperationJobRepresentation{}, nil).Times(1)
+		_, err = bulkMW.StartBulkOperation(ctx, realmName, api.BulkOperationRepresentation{})
+		assert.Nil(t, err)
+
+		mockBulkComponent.EXPECT().GetBulkOperation(ctx, realmName, "job").Return(api.BulkOperationJobRepresentation{}, nil).Times(1)
+		_, err = bulkMW.GetBulkOperation(ctx, realmName, "job")
+		assert.Nil(t, err)
 	}
 }
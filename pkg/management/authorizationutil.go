@@ -1,9 +1,14 @@
 package management
 
 import (
+	"context"
 	"errors"
+	"sort"
 
+	cs "github.com/cloudtrust/common-service"
 	"github.com/cloudtrust/common-service/configuration"
+	"github.com/cloudtrust/common-service/log"
+	"github.com/cloudtrust/common-service/security"
 	api "github.com/cloudtrust/keycloak-bridge/api/management"
 )
 
@@ -53,3 +58,207 @@ func checkTarget(authorizations []configuration.Authorization, allowedTargetReal
 	}
 	return nil
 }
+
+// explainAuthorization evaluates an action on a target realm and, for a group scoped action, a target group with the
+// authorization manager of the security package, as if the authorizations of the groups of an operator were the only
+// ones. It returns whether the action is allowed, the rules allowing it and, when it is denied, the rules which would
+// allow it for each group.
+func explainAuthorization(realmID string, groupNames []string, authorizations []configuration.Authorization, action string, scope security.Scope, targetRealm string, targetGroup *string) (bool, []configuration.Authorization, []configuration.Authorization, error) {
+	var matchingRules = []configuration.Authorization{}
+	var missingRules = []configuration.Authorization{}
+
+	if scope != security.ScopeGroup {
+		// The target group is ignored when checking a realm or global scoped action
+		targetGroup = nil
+	}
+
+	for _, authz := range authorizations {
+		if authz.Action == nil || *authz.Action != action || authz.TargetRealmID == nil || authz.RealmID == nil || authz.GroupName == nil {
+			continue
+		}
+		allowed, err := checkAuthorization(realmID, []string{*authz.GroupName}, []configuration.Authorization{authz}, action, targetRealm, targetGroup)
+		if err != nil {
+			return false, nil, nil, err
+		}
+		if allowed {
+			matchingRules = append(matchingRules, authz)
+		}
+	}
+
+	if len(matchingRules) > 0 {
+		return true, matchingRules, missingRules, nil
+	}
+
+	for _, groupName := range groupNames {
+		var group = groupName
+		missingRules = append(missingRules, configuration.Authorization{
+			RealmID:         &realmID,
+			GroupName:       &group,
+			Action:          &action,
+			TargetRealmID:   &targetRealm,
+			TargetGroupName: targetGroup,
+		})
+	}
+
+	return false, matchingRules, missingRules, nil
+}
+
+// checkAuthorization checks an action as the authorization manager does for an operator of the given realm and groups
+func checkAuthorization(realmID string, groupNames []string, authorizations []configuration.Authorization, action, targetRealm string, targetGroup *string) (bool, error) {
+	// The Keycloak client is only used to resolve target users and group IDs, which are not checked here
+	var authorizationManager, err = security.NewAuthorizationManager(staticAuthorizations(authorizations), nil, log.NewNopLogger())
+	if err != nil {
+		return false, err
+	}
+
+	var ctx = context.WithValue(context.Background(), cs.CtContextRealm, realmID)
+	ctx = context.WithValue(ctx, cs.CtContextGroups, groupNames)
+	if targetGroup != nil {
+		err = authorizationManager.CheckAuthorizationOnTargetGroup(ctx, action, targetRealm, *targetGroup)
+	} else {
+		err = authorizationManager.CheckAuthorizationOnTargetRealm(ctx, action, targetRealm)
+	}
+	return err == nil, nil
+}
+
+// staticAuthorizations provides the authorization manager with authorizations which are already loaded
+type staticAuthorizations []configuration.Authorization
+
+func (a staticAuthorizations) GetAuthorizations(ctx context.Context) ([]configuration.Authorization, error) {
+	return a, nil
+}
+
+// groupAuthorizations are the authorizations of a group
+type groupAuthorizations struct {
+	realm          string
+	name           string
+	authorizations []configuration.Authorization
+}
+
+// removeTargetGroup returns the authorizations without the rules on a target group
+func removeTargetGroup(authorizations []configuration.Authorization, targetRealm, targetGroup string) []configuration.Authorization {
+	var res = []configuration.Authorization{}
+	for _, authz := range authorizations {
+		if !isOnTargetGroup(authz, targetRealm, targetGroup) {
+			res = append(res, authz)
+		}
+	}
+	return res
+}
+
+// renameTargetGroup returns the authorizations where the rules on a target group use its new name
+func renameTargetGroup(authorizations []configuration.Authorization, targetRealm, oldGroupName, newGroupName string) []configuration.Authorization {
+	var res = []configuration.Authorization{}
+	for _, authz := range authorizations {
+		if isOnTargetGroup(authz, targetRealm, oldGroupName) {
+			var groupName = newGroupName
+			authz.TargetGroupName = &groupName
+		}
+		res = append(res, authz)
+	}
+	return res
+}
+
+func isOnTargetGroup(authz configuration.Authorization, targetRealm, targetGroup string) bool {
+	return authz.TargetRealmID != nil && *authz.TargetRealmID == targetRealm && authz.TargetGroupName != nil && *authz.TargetGroupName == targetGroup
+}
+
+// actionScope gives the scope of a management action
+func actionScope(name string) (security.Scope, bool) {
+	for _, action := range actions {
+		if action.Name == name {
+			return action.Scope, true
+		}
+	}
+	return "", false
+}
+
+// applyTemplateRealm returns a copy of the matrix of an authorization template where the realm placeholder is replaced
+// by the given target realm
+func applyTemplateRealm(matrix map[string]map[string]map[string]struct{}, targetRealm string) map[string]map[string]map[string]struct{} {
+	var res = make(map[string]map[string]map[string]struct{})
+	for action, realms := range matrix {
+		res[action] = make(map[string]map[string]struct{})
+		for realm, groups := range realms {
+			if realm == api.AuthorizationTemplateRealmPlaceholder {
+				realm = targetRealm
+			}
+			if _, ok := res[action][realm]; !ok {
+				res[action][realm] = make(map[string]struct{})
+			}
+			for group := range groups {
+				res[action][realm][group] = struct{}{}
+			}
+		}
+	}
+	return res
+}
+
+// moveAuthorizations returns a copy of the authorizations of a realm as they would be defined in another realm.
+// Rules targeting the source realm are made to target the other realm
+func moveAuthorizations(authorizations []configuration.Authorization, sourceRealm, targetRealm string) []configuration.Authorization {
+	var res = []configuration.Authorization{}
+	for _, authz := range authorizations {
+		var realmID = targetRealm
+		var moved = configuration.Authorization{
+			RealmID:         &realmID,
+			GroupName:       authz.GroupName,
+			Action:          authz.Action,
+			TargetRealmID:   authz.TargetRealmID,
+			TargetGroupName: authz.TargetGroupName,
+		}
+		if authz.TargetRealmID != nil && *authz.TargetRealmID == sourceRealm {
+			moved.TargetRealmID = &realmID
+		}
+		res = append(res, moved)
+	}
+	return res
+}
+
+// diffAuthorizations gives the rules which have to be added to and removed from the current authorizations to obtain
+// the wanted ones
+func diffAuthorizations(current, wanted []configuration.Authorization) ([]configuration.Authorization, []configuration.Authorization) {
+	var currentRules = indexAuthorizations(current)
+	var wantedRules = indexAuthorizations(wanted)
+
+	var added = []configuration.Authorization{}
+	for _, key := range sortedAuthorizationKeys(wantedRules) {
+		if _, ok := currentRules[key]; !ok {
+			added = append(added, wantedRules[key])
+		}
+	}
+
+	var removed = []configuration.Authorization{}
+	for _, key := range sortedAuthorizationKeys(currentRules) {
+		if _, ok := wantedRules[key]; !ok {
+			removed = append(removed, currentRules[key])
+		}
+	}
+
+	return added, removed
+}
+
+func indexAuthorizations(authorizations []configuration.Authorization) map[string]configuration.Authorization {
+	var res = make(map[string]configuration.Authorization)
+	for _, authz := range authorizations {
+		var key = optionalString(authz.Action) + "|" + optionalString(authz.TargetRealmID) + "|" + optionalString(authz.TargetGroupName)
+		res[key] = authz
+	}
+	return res
+}
+
+func sortedAuthorizationKeys(authorizations map[string]configuration.Authorization) []string {
+	var keys = make([]string, 0, len(authorizations))
+	for key := range authorizations {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func optionalString(value *string) string {
+	if value == nil {
+		return ""
+	}
+	return *value
+}
@@ -4,6 +4,8 @@ import (
 	"testing"
 
 	"github.com/cloudtrust/common-service/configuration"
+	"github.com/cloudtrust/common-service/security"
+	api "github.com/cloudtrust/keycloak-bridge/api/management"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -124,3 +126,165 @@ func TestValidate(t *testing.T) {
 		assert.Nil(t, err)
 	}
 }
+
+func TestExplainAuthorization(t *testing.T) {
+	var realmName = "DEP"
+	var targetRealm = "target"
+	var action = "MGMT_GetUser"
+	var otherAction = "MGMT_DeleteUser"
+	var groupName1 = "groupName1"
+	var groupName2 = "groupName2"
+	var targetGroup = "targetGroup"
+	var otherGroup = "otherGroup"
+	var star = "*"
+	var groupNames = []string{groupName1, groupName2}
+
+	t.Run("No authorization", func(t *testing.T) {
+		var allowed, matching, missing, err = explainAuthorization(realmName, groupNames, nil, action, security.ScopeGroup, targetRealm, &targetGroup)
+		assert.Nil(t, err)
+		assert.False(t, allowed)
+		assert.Len(t, matching, 0)
+		assert.Len(t, missing, 2)
+		assert.Equal(t, groupName1, *missing[0].GroupName)
+		assert.Equal(t, groupName2, *missing[1].GroupName)
+		assert.Equal(t, targetRealm, *missing[0].TargetRealmID)
+		assert.Equal(t, targetGroup, *missing[0].TargetGroupName)
+	})
+	t.Run("Rules on other actions, realms or groups", func(t *testing.T) {
+		var authorizations = []configuration.Authorization{
+			{RealmID: &realmName, GroupName: &groupName1, Action: &otherAction, TargetRealmID: &star, TargetGroupName: &star},
+			{RealmID: &realmName, GroupName: &groupName1, Action: &action, TargetRealmID: &realmName, TargetGroupName: &star},
+			{RealmID: &realmName, GroupName: &groupName1, Action: &action, TargetRealmID: &targetRealm, TargetGroupName: &otherGroup},
+			{RealmID: &realmName, GroupName: &groupName2, Action: &action},
+		}
+		var allowed, matching, missing, err = explainAuthorization(realmName, groupNames, authorizations, action, security.ScopeGroup, targetRealm, &targetGroup)
+		assert.Nil(t, err)
+		assert.False(t, allowed)
+		assert.Len(t, matching, 0)
+		assert.Len(t, missing, 2)
+	})
+	t.Run("Allowed on any realm and group", func(t *testing.T) {
+		var authorizations = []configuration.Authorization{
+			{RealmID: &realmName, GroupName: &groupName2, Action: &action, TargetRealmID: &star, TargetGroupName: &star},
+		}
+		var allowed, matching, missing, err = explainAuthorization(realmName, groupNames, authorizations, action, security.ScopeGroup, targetRealm, &targetGroup)
+		assert.Nil(t, err)
+		assert.True(t, allowed)
+		assert.Equal(t, authorizations, matching)
+		assert.Len(t, missing, 0)
+	})
+	t.Run("Allowed on the target group", func(t *testing.T) {
+		var authorizations = []configuration.Authorization{
+			{RealmID: &realmName, GroupName: &groupName1, Action: &action, TargetRealmID: &targetRealm, TargetGroupName: &targetGroup},
+		}
+		var allowed, matching, _, err = explainAuthorization(realmName, groupNames, authorizations, action, security.ScopeGroup, targetRealm, &targetGroup)
+		assert.Nil(t, err)
+		assert.True(t, allowed)
+		assert.Len(t, matching, 1)
+	})
+	t.Run("Realm scoped action", func(t *testing.T) {
+		var authorizations = []configuration.Authorization{
+			{RealmID: &realmName, GroupName: &groupName1, Action: &action, TargetRealmID: &targetRealm},
+		}
+		var allowed, matching, _, err = explainAuthorization(realmName, groupNames, authorizations, action, security.ScopeRealm, targetRealm, nil)
+		assert.Nil(t, err)
+		assert.True(t, allowed)
+		assert.Len(t, matching, 1)
+	})
+	t.Run("Target group is ignored for a realm scoped action", func(t *testing.T) {
+		var authorizations = []configuration.Authorization{
+			{RealmID: &realmName, GroupName: &groupName1, Action: &action, TargetRealmID: &targetRealm},
+		}
+		var allowed, matching, _, err = explainAuthorization(realmName, groupNames, authorizations, action, security.ScopeRealm, targetRealm, &targetGroup)
+		assert.Nil(t, err)
+		assert.True(t, allowed)
+		assert.Len(t, matching, 1)
+
+		allowed, _, missing, err := explainAuthorization(realmName, groupNames, nil, action, security.ScopeRealm, targetRealm, &targetGroup)
+		assert.Nil(t, err)
+		assert.False(t, allowed)
+		assert.Nil(t, missing[0].TargetGroupName)
+	})
+}
+
+func TestActionScope(t *testing.T) {
+	var scope, ok = actionScope(MGMTGetUser.String())
+	assert.True(t, ok)
+	assert.Equal(t, MGMTGetUser.Scope, scope)
+
+	_, ok = actionScope("MGMT_Unknown")
+	assert.False(t, ok)
+}
+
+func TestApplyTemplateRealm(t *testing.T) {
+	var matrix = map[string]map[string]map[string]struct{}{
+		"MGMT_GetUsers":   {api.AuthorizationTemplateRealmPlaceholder: {"*": {}}},
+		"MGMT_GetActions": {},
+		"MGMT_GetRealm":   {"master": {}},
+	}
+	var res = applyTemplateRealm(matrix, "DEP")
+	assert.Equal(t, map[string]map[string]map[string]struct{}{
+		"MGMT_GetUsers":   {"DEP": {"*": {}}},
+		"MGMT_GetActions": {},
+		"MGMT_GetRealm":   {"master": {}},
+	}, res)
+	// The template itself is not modified
+	assert.Contains(t, matrix["MGMT_GetUsers"], api.AuthorizationTemplateRealmPlaceholder)
+}
+
+func TestMoveAuthorizations(t *testing.T) {
+	var source = "source"
+	var target = "target"
+	var other = "other"
+	var groupName = "groupName"
+	var action = "MGMT_GetUsers"
+	var star = "*"
+
+	var res = moveAuthorizations([]configuration.Authorization{
+		{RealmID: &source, GroupName: &groupName, Action: &action, TargetRealmID: &source, TargetGroupName: &star},
+		{RealmID: &source, GroupName: &groupName, Action: &action, TargetRealmID: &other, TargetGroupName: &star},
+		{RealmID: &source, GroupName: &groupName, Action: &action},
+	}, source, target)
+	assert.Len(t, res, 3)
+	for _, authz := range res {
+		assert.Equal(t, target, *authz.RealmID)
+		assert.Equal(t, groupName, *authz.GroupName)
+	}
+	assert.Equal(t, target, *res[0].TargetRealmID)
+	assert.Equal(t, other, *res[1].TargetRealmID)
+	assert.Nil(t, res[2].TargetRealmID)
+}
+
+func TestDiffAuthorizations(t *testing.T) {
+	var realmName = "DEP"
+	var groupName = "groupName"
+	var getUsers = "MGMT_GetUsers"
+	var deleteUser = "MGMT_DeleteUser"
+	var getActions = "MGMT_GetActions"
+	var star = "*"
+
+	var current = []configuration.Authorization{
+		{RealmID: &realmName, GroupName: &groupName, Action: &getUsers, TargetRealmID: &realmName, TargetGroupName: &star},
+		{RealmID: &realmName, GroupName: &groupName, Action: &deleteUser, TargetRealmID: &realmName, TargetGroupName: &star},
+	}
+	var wanted = []configuration.Authorization{
+		{RealmID: &realmName, GroupName: &groupName, Action: &getUsers, TargetRealmID: &realmName, TargetGroupName: &star},
+		{RealmID: &realmName, GroupName: &groupName, Action: &getActions, TargetRealmID: &star},
+	}
+
+	t.Run("Same authorizations", func(t *testing.T) {
+		var added, removed = diffAuthorizations(current, current)
+		assert.Len(t, added, 0)
+		assert.Len(t, removed, 0)
+	})
+	t.Run("Different authorizations", func(t *testing.T) {
+		var added, removed = diffAuthorizations(current, wanted)
+		assert.Equal(t, []configuration.Authorization{wanted[1]}, added)
+		assert.Equal(t, []configuration.Authorization{current[1]}, removed)
+	})
+	t.Run("No current authorization", func(t *testing.T) {
+		var added, removed = diffAuthorizations(nil, wanted)
+		assert.Len(t, added, 2)
+		assert.Len(t, removed, 0)
+	})
+}
@@ -0,0 +1,236 @@
+package management
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+
+	cs "github.com/cloudtrust/common-service"
+	errorhandler "github.com/cloudtrust/common-service/errors"
+	api "github.com/cloudtrust/keycloak-bridge/api/management"
+	"github.com/cloudtrust/keycloak-bridge/internal/constants"
+	"github.com/cloudtrust/keycloak-bridge/internal/keycloakb"
+	"golang.org/x/time/rate"
+)
+
+const (
+	// Finished bulk operations are kept in memory during this delay so that their results can be downloaded
+	bulkOperationJobRetention = 24 * time.Hour
+	// Maximum number of users selected by the query of a bulk operation
+	bulkOperationMaxUsers = 1000
+)
+
+// BulkComponent is the interface of the bulk operations on users
+type BulkComponent interface {
+	StartBulkOperation(ctx context.Context, realmName string, operation api.BulkOperationRepresentation) (api.BulkOperationJobRepresentation, error)
+	GetBulkOperation(ctx context.Context, realmName string, jobID string) (api.BulkOperationJobRepresentation, error)
+}
+
+type bulkOperationJob struct {
+	realmName string
+	creator   string
+	progress  api.BulkOperationJobRepresentation
+}
+
+type bulkComponent struct {
+	managementComponent Component
+	limiter             *rate.Limiter
+	mutex               sync.Mutex
+	jobs                map[string]*bulkOperationJob
+	logger              keycloakb.Logger
+}
+
+// NewBulkLimiter returns the limiter shared by the bulk operations and the users imports: the users they process are
+// limited to ratePerSecond for all of them
+func NewBulkLimiter(ratePerSecond int) (*rate.Limiter, error) {
+	if ratePerSecond <= 0 {
+		return nil, errors.New("the rate of the bulk operations must be positive")
+	}
+	return rate.NewLimiter(rate.Limit(ratePerSecond), 1), nil
+}
+
+// NewBulkComponent returns the bulk operations component. Each user is processed through the given management component,
+// which is expected to be wrapped by the authorization middleware so that every user is checked as for a single call.
+// The users are processed at the rate of the given limiter
+func NewBulkComponent(managementComponent Component, limiter *rate.Limiter, logger keycloakb.Logger) BulkComponent {
+	return &bulkComponent{
+		managementComponent: managementComponent,
+		limiter:             limiter,
+		jobs:                make(map[string]*bulkOperationJob),
+		logger:              logger,
+	}
+}
+
+// StartBulkOperation resolves the targeted users and starts the asynchronous execution of the operation. A failure on a user
+// does not stop the operation: it is reported in the results of the job
+func (c *bulkComponent) StartBulkOperation(ctx context.Context, realmName string, operation api.BulkOperationRepresentation) (api.BulkOperationJobRepresentation, error) {
+	var userIDs, err = c.getTargetUserIDs(ctx, realmName, operation)
+	if err != nil {
+		return api.BulkOperationJobRepresentation{}, err
+	}
+
+	jobID, err := newRandomID()
+	if err != nil {
+		c.logger.Warn(ctx, "msg", "Can't generate bulk operation job ID", "err", err.Error())
+		return api.BulkOperationJobRepresentation{}, err
+	}
+
+	var results = make([]api.BulkOperationResultRepresentation, 0, len(userIDs))
+	for _, userID := range userIDs {
+		results = append(results, api.BulkOperationResultRepresentation{UserID: userID})
+	}
+	var job = &bulkOperationJob{
+		realmName: realmName,
+		creator:   ctx.Value(cs.CtContextUserID).(string),
+		progress: api.BulkOperationJobRepresentation{
+			ID:        jobID,
+			Operation: *operation.Operation,
+			Status:    api.BulkOperationStatusRunning,
+			Total:     len(userIDs),
+			StartedAt: time.Now().Unix(),
+			Results:   results,
+		},
+	}
+
+	c.mutex.Lock()
+	c.purge(time.Now())
+	c.jobs[jobID] = job
+	var res = job.snapshot()
+	c.mutex.Unlock()
+
+	// The operation outlives the request: it must not be cancelled with it
+	go c.runBulkOperation(detachContext(ctx), job, operation)
+
+	return res, nil
+}
+
+func (c *bulkComponent) getTargetUserIDs(ctx context.Context, realmName string, operation api.BulkOperationRepresentation) ([]string, error) {
+	if operation.UserIDs != nil {
+		var res []string
+		var known = make(map[string]bool)
+		for _, userID := range *operation.UserIDs {
+			if !known[userID] {
+				known[userID] = true
+				res = append(res, userID)
+			}
+		}
+		return res, nil
+	}
+
+	var paramKV = []string{"max", strconv.Itoa(bulkOperationMaxUsers)}
+	if operation.Query.Search != nil {
+		paramKV = append(paramKV, "search", *operation.Query.Search)
+	}
+	var users, err = c.managementComponent.GetUsers(ctx, realmName, operation.Query.GroupIDs, paramKV...)
+	if err != nil {
+		return nil, err
+	}
+	// The page is cut to bulkOperationMaxUsers: the operation would silently skip the other users
+	if users.Count != nil && *users.Count > bulkOperationMaxUsers {
+		c.logger.Info(ctx, "msg", "Bulk operation query selects too many users", "realm", realmName, "count", *users.Count)
+		return nil, errorhandler.CreateBadRequestError(constants.MsgErrTooManyUsers + "." + constants.BulkQuery)
+	}
+	var res []string
+	for _, user := range users.Users {
+		if user.ID != nil {
+			res = append(res, *user.ID)
+		}
+	}
+	return res, nil
+}
+
+func (c *bulkComponent) runBulkOperation(ctx context.Context, job *bulkOperationJob, operation api.BulkOperationRepresentation) {
+	var operationName = *operation.Operation
+
+	for i := range job.progress.Results {
+		var userID = job.progress.Results[i].UserID
+
+		// The limiter is shared by all the bulk operations and must not depend on the request context
+		var err = c.limiter.Wait(context.Background())
+		if err == nil {
+			err = c.applyOperation(ctx, job.realmName, userID, operation)
+		}
+
+		var _, pending = err.(PendingApproval)
+
+		c.mutex.Lock()
+		job.progress.Processed++
+		if pending {
+			// The approval middleware stored the operation on this user as a pending request
+			job.progress.Results[i].Status = api.BulkOperationStatusPendingApproval
+			job.progress.PendingApproval++
+		} else if err != nil {
+			var errMsg = err.Error()
+			job.progress.Results[i].Status = api.BulkOperationStatusFailed
+			job.progress.Results[i].Error = &errMsg
+			job.progress.Failed++
+		} else {
+			job.progress.Results[i].Status = api.BulkOperationStatusSuccess
+			job.progress.Succeeded++
+		}
+		c.mutex.Unlock()
+
+		if err != nil && !pending {
+			c.logger.Warn(ctx, "msg", "Bulk operation failed for user", "operation", operationName, "userID", userID, "err", err.Error())
+		}
+	}
+
+	c.mutex.Lock()
+	var finishedAt = time.Now().Unix()
+	job.progress.Status = api.BulkOperationStatusDone
+	job.progress.FinishedAt = &finishedAt
+	c.mutex.Unlock()
+
+	c.logger.Info(ctx, "msg", "Bulk operation done", "realm", job.realmName, "job", job.progress.ID, "operation", operationName,
+		"succeeded", job.progress.Succeeded, "failed", job.progress.Failed)
+}
+
+func (c *bulkComponent) applyOperation(ctx context.Context, realmName string, userID string, operation api.BulkOperationRepresentation) error {
+	switch *operation.Operation {
+	case api.BulkOperationLock:
+		return c.managementComponent.LockUser(ctx, realmName, userID)
+	case api.BulkOperationUnlock:
+		return c.managementComponent.UnlockUser(ctx, realmName, userID)
+	case api.BulkOperationDelete:
+		return c.managementComponent.DeleteUser(ctx, realmName, userID)
+	case api.BulkOperationAddGroup:
+		return c.managementComponent.AddGroupToUser(ctx, realmName, userID, *operation.GroupID)
+	case api.BulkOperationRemoveGroup:
+		return c.managementComponent.DeleteGroupForUser(ctx, realmName, userID, *operation.GroupID)
+	case api.BulkOperationSendReminderEmail:
+		return c.managementComponent.SendReminderEmail(ctx, realmName, userID)
+	case api.BulkOperationResetSmsCounter:
+		return c.managementComponent.ResetSmsCounter(ctx, realmName, userID)
+	}
+	return errorhandler.CreateBadRequestError("unknownOperation")
+}
+
+// GetBulkOperation gives the progress and the per-user results of a bulk operation. A job is only visible to the user who started it
+func (c *bulkComponent) GetBulkOperation(ctx context.Context, realmName string, jobID string) (api.BulkOperationJobRepresentation, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	var job, ok = c.jobs[jobID]
+	if !ok || job.realmName != realmName || job.creator != ctx.Value(cs.CtContextUserID).(string) {
+		return api.BulkOperationJobRepresentation{}, errorhandler.CreateNotFoundError("bulkOperationJob")
+	}
+	return job.snapshot(), nil
+}
+
+// snapshot copies the progress of the job. The mutex must be held by the caller
+func (j *bulkOperationJob) snapshot() api.BulkOperationJobRepresentation {
+	var res = j.progress
+	res.Results = append([]api.BulkOperationResultRepresentation{}, j.progress.Results...)
+	return res
+}
+
+// purge removes the jobs finished for more than the retention delay. The mutex must be held by the caller
+func (c *bulkComponent) purge(now time.Time) {
+	for jobID, job := range c.jobs {
+		if job.progress.FinishedAt != nil && now.Sub(time.Unix(*job.progress.FinishedAt, 0)) > bulkOperationJobRetention {
+			delete(c.jobs, jobID)
+		}
+	}
+}
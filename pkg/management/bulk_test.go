@@ -0,0 +1,171 @@
+package management
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	cs "github.com/cloudtrust/common-service"
+	errorhandler "github.com/cloudtrust/common-service/errors"
+	"github.com/cloudtrust/common-service/log"
+	api "github.com/cloudtrust/keycloak-bridge/api/management"
+	"github.com/cloudtrust/keycloak-bridge/internal/constants"
+	"github.com/cloudtrust/keycloak-bridge/pkg/management/mock"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func waitBulkOperation(t *testing.T, component BulkComponent, ctx context.Context, realmName string, job api.BulkOperationJobRepresentation) api.BulkOperationJobRepresentation {
+	var err error
+	for i := 0; i < 100 && job.Status != api.BulkOperationStatusDone; i++ {
+		time.Sleep(10 * time.Millisecond)
+		job, err = component.GetBulkOperation(ctx, realmName, job.ID)
+		assert.Nil(t, err)
+	}
+	return job
+}
+
+func TestNewBulkLimiter(t *testing.T) {
+	var _, err = NewBulkLimiter(0)
+	assert.NotNil(t, err)
+
+	_, err = NewBulkLimiter(-1)
+	assert.NotNil(t, err)
+
+	limiter, err := NewBulkLimiter(10)
+	assert.Nil(t, err)
+	assert.NotNil(t, limiter)
+}
+
+func TestBulkOperation(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+	var mockManagementComponent = mock.NewManagementComponent(mockCtrl)
+
+	var limiter, _ = NewBulkLimiter(1000)
+	var bulkComponent = NewBulkComponent(mockManagementComponent, limiter, log.NewNopLogger())
+
+	var realmName = "DEP"
+	var groupID = "41dbf4a8-32a9-4000-8c17-edc854c31231"
+	var userID1 = "f467ed7c-0a1d-4eee-9bb8-669c6f89c000"
+	var userID2 = "f467ed7c-0a1d-4eee-9bb8-669c6f89c001"
+	var ctx = context.WithValue(context.Background(), cs.CtContextUserID, "creator-id")
+
+	t.Run("Lock users given by their IDs, partial failure", func(t *testing.T) {
+		var operation = api.BulkOperationLock
+		var userIDs = []string{userID1, userID2, userID1}
+		mockManagementComponent.EXPECT().LockUser(gomock.Any(), realmName, userID1).Return(nil)
+		mockManagementComponent.EXPECT().LockUser(gomock.Any(), realmName, userID2).Return(errors.New("forbidden"))
+
+		var job, err = bulkComponent.StartBulkOperation(ctx, realmName, api.BulkOperationRepresentation{Operation: &operation, UserIDs: &userIDs})
+		assert.Nil(t, err)
+		assert.Len(t, job.ID, 32)
+		assert.Equal(t, 2, job.Total)
+
+		job = waitBulkOperation(t, bulkComponent, ctx, realmName, job)
+		assert.Equal(t, api.BulkOperationStatusDone, job.Status)
+		assert.Equal(t, 1, job.Succeeded)
+		assert.Equal(t, 1, job.Failed)
+		assert.Equal(t, api.BulkOperationStatusSuccess, job.Results[0].Status)
+		assert.Equal(t, api.BulkOperationStatusFailed, job.Results[1].Status)
+		assert.Equal(t, "forbidden", *job.Results[1].Error)
+
+		// Jobs are only visible in their realm and to their creator
+		_, err = bulkComponent.GetBulkOperation(ctx, "master", job.ID)
+		assert.NotNil(t, err)
+		_, err = bulkComponent.GetBulkOperation(context.WithValue(ctx, cs.CtContextUserID, "other-id"), realmName, job.ID)
+		assert.NotNil(t, err)
+	})
+
+	t.Run("Operation outlives the request", func(t *testing.T) {
+		var operation = api.BulkOperationUnlock
+		var userIDs = []string{userID1}
+		var reqCtx, cancel = context.WithCancel(context.WithValue(ctx, cs.CtContextAccessToken, "TOKEN=="))
+		mockManagementComponent.EXPECT().UnlockUser(gomock.Any(), realmName, userID1).DoAndReturn(
+			func(ctx context.Context, realmName string, userID string) error {
+				assert.Nil(t, ctx.Err())
+				assert.Equal(t, "TOKEN==", ctx.Value(cs.CtContextAccessToken))
+				assert.Equal(t, "creator-id", ctx.Value(cs.CtContextUserID))
+				return nil
+			})
+
+		var job, err = bulkComponent.StartBulkOperation(reqCtx, realmName, api.BulkOperationRepresentation{Operation: &operation, UserIDs: &userIDs})
+		cancel()
+		assert.Nil(t, err)
+
+		job = waitBulkOperation(t, bulkComponent, ctx, realmName, job)
+		assert.Equal(t, 1, job.Succeeded)
+	})
+
+	t.Run("Add group to users given by a query", func(t *testing.T) {
+		var operation = api.BulkOperationAddGroup
+		var search = "john"
+		var query = api.BulkUsersQueryRepresentation{GroupIDs: []string{groupID}, Search: &search}
+		var users = api.UsersPageRepresentation{Users: []api.UserRepresentation{{ID: &userID1}, {ID: &userID2}}}
+		mockManagementComponent.EXPECT().GetUsers(ctx, realmName, []string{groupID}, "max", "1000", "search", search).Return(users, nil)
+		mockManagementComponent.EXPECT().AddGroupToUser(gomock.Any(), realmName, userID1, groupID).Return(nil)
+		mockManagementComponent.EXPECT().AddGroupToUser(gomock.Any(), realmName, userID2, groupID).Return(nil)
+
+		var job, err = bulkComponent.StartBulkOperation(ctx, realmName, api.BulkOperationRepresentation{Operation: &operation, Query: &query, GroupID: &groupID})
+		assert.Nil(t, err)
+
+		job = waitBulkOperation(t, bulkComponent, ctx, realmName, job)
+		assert.Equal(t, api.BulkOperationStatusDone, job.Status)
+		assert.Equal(t, 2, job.Succeeded)
+		assert.Equal(t, 0, job.Failed)
+	})
+
+	t.Run("Delete users needing an approval", func(t *testing.T) {
+		var operation = api.BulkOperationDelete
+		var userIDs = []string{userID1}
+		var requestID = "request-id"
+		mockManagementComponent.EXPECT().DeleteUser(gomock.Any(), realmName, userID1).Return(PendingApproval{Request: api.PendingRequestRepresentation{ID: &requestID}})
+
+		var job, err = bulkComponent.StartBulkOperation(ctx, realmName, api.BulkOperationRepresentation{Operation: &operation, UserIDs: &userIDs})
+		assert.Nil(t, err)
+
+		job = waitBulkOperation(t, bulkComponent, ctx, realmName, job)
+		assert.Equal(t, 1, job.PendingApproval)
+		assert.Equal(t, 0, job.Failed)
+		assert.Equal(t, api.BulkOperationStatusPendingApproval, job.Results[0].Status)
+	})
+
+	t.Run("Query selects too many users", func(t *testing.T) {
+		var operation = api.BulkOperationLock
+		var query = api.BulkUsersQueryRepresentation{GroupIDs: []string{groupID}}
+		var count = 1001
+		var users = api.UsersPageRepresentation{Users: []api.UserRepresentation{{ID: &userID1}, {ID: &userID2}}, Count: &count}
+		mockManagementComponent.EXPECT().GetUsers(ctx, realmName, []string{groupID}, "max", "1000").Return(users, nil)
+
+		var _, err = bulkComponent.StartBulkOperation(ctx, realmName, api.BulkOperationRepresentation{Operation: &operation, Query: &query})
+		assert.Equal(t, errorhandler.CreateBadRequestError(constants.MsgErrTooManyUsers+"."+constants.BulkQuery), err)
+	})
+
+	t.Run("Query fails", func(t *testing.T) {
+		var operation = api.BulkOperationDelete
+		var query = api.BulkUsersQueryRepresentation{GroupIDs: []string{groupID}}
+		mockManagementComponent.EXPECT().GetUsers(ctx, realmName, []string{groupID}, "max", "1000").Return(api.UsersPageRepresentation{}, errors.New("error"))
+
+		var _, err = bulkComponent.StartBulkOperation(ctx, realmName, api.BulkOperationRepresentation{Operation: &operation, Query: &query})
+		assert.NotNil(t, err)
+	})
+
+	t.Run("Unknown job", func(t *testing.T) {
+		var _, err = bulkComponent.GetBulkOperation(ctx, realmName, "0123456789abcdef0123456789abcdef")
+		assert.NotNil(t, err)
+	})
+}
+
+func TestPurgeBulkOperations(t *testing.T) {
+	var now = time.Now()
+	var old = now.Add(-25 * time.Hour).Unix()
+	var component = &bulkComponent{jobs: map[string]*bulkOperationJob{
+		"old":     {progress: api.BulkOperationJobRepresentation{FinishedAt: &old}},
+		"running": {},
+	}}
+
+	component.purge(now)
+	assert.Len(t, component.jobs, 1)
+	assert.Nil(t, component.jobs["old"])
+}
@@ -3,19 +3,26 @@ package management
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"regexp"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	cs "github.com/cloudtrust/common-service"
 	"github.com/cloudtrust/common-service/configuration"
 	"github.com/cloudtrust/common-service/database"
+	"github.com/cloudtrust/common-service/database/sqltypes"
 	errorhandler "github.com/cloudtrust/common-service/errors"
+	"github.com/cloudtrust/common-service/security"
 	api "github.com/cloudtrust/keycloak-bridge/api/management"
 	"github.com/cloudtrust/keycloak-bridge/internal/constants"
 	"github.com/cloudtrust/keycloak-bridge/internal/dto"
 	"github.com/cloudtrust/keycloak-bridge/internal/keycloakb"
 	kc "github.com/cloudtrust/keycloak-client"
 	"github.com/pkg/errors"
+	"golang.org/x/time/rate"
 )
 
 const (
@@ -39,6 +46,7 @@ type KeycloakClient interface {
 	CreateUser(accessToken string, realmName string, targetRealmName string, user kc.UserRepresentation) (string, error)
 	GetClientRoleMappings(accessToken string, realmName, userID, clientID string) ([]kc.RoleRepresentation, error)
 	AddClientRolesToUserRoleMapping(accessToken string, realmName, userID, clientID string, roles []kc.RoleRepresentation) error
+	DeleteClientRolesFromUserRoleMapping(accessToken string, realmName, userID, clientID string, roles []kc.RoleRepresentation) error
 	GetRealmLevelRoleMappings(accessToken string, realmName, userID string) ([]kc.RoleRepresentation, error)
 	ResetPassword(accessToken string, realmName string, userID string, cred kc.CredentialRepresentation) error
 	ExecuteActionsEmail(accessToken string, realmName string, userID string, actions []string, paramKV ...string) error
@@ -47,12 +55,23 @@ type KeycloakClient interface {
 	SendReminderEmail(accessToken string, realmName string, userID string, paramKV ...string) error
 	GetRoles(accessToken string, realmName string) ([]kc.RoleRepresentation, error)
 	GetRole(accessToken string, realmName string, roleID string) (kc.RoleRepresentation, error)
+	CreateRole(accessToken string, realmName string, role kc.RoleRepresentation) (string, error)
+	UpdateRole(accessToken string, realmName string, roleID string, role kc.RoleRepresentation) error
+	DeleteRole(accessToken string, realmName string, roleID string) error
+	GetRoleComposites(accessToken string, realmName string, roleID string) ([]kc.RoleRepresentation, error)
+	AddRoleComposites(accessToken string, realmName string, roleID string, roles []kc.RoleRepresentation) error
+	DeleteRoleComposites(accessToken string, realmName string, roleID string, roles []kc.RoleRepresentation) error
+	GetUsersWithRealmRole(accessToken string, realmName string, roleName string, paramKV ...string) ([]kc.UserRepresentation, error)
+	GetUsersWithClientRole(accessToken string, realmName string, clientID string, roleName string, paramKV ...string) ([]kc.UserRepresentation, error)
 	GetGroups(accessToken string, realmName string) ([]kc.GroupRepresentation, error)
 	GetClientRoles(accessToken string, realmName, idClient string) ([]kc.RoleRepresentation, error)
 	CreateClientRole(accessToken string, realmName, clientID string, role kc.RoleRepresentation) (string, error)
 	GetGroup(accessToken string, realmName, groupID string) (kc.GroupRepresentation, error)
 	CreateGroup(accessToken string, realmName string, group kc.GroupRepresentation) (string, error)
 	DeleteGroup(accessToken string, realmName string, groupID string) error
+	UpdateGroup(accessToken string, realmName string, groupID string, group kc.GroupRepresentation) error
+	CreateGroupChild(accessToken string, realmName string, groupID string, group kc.GroupRepresentation) (string, error)
+	GetGroupMembers(accessToken string, realmName string, groupID string, paramKV ...string) ([]kc.UserRepresentation, error)
 	AssignClientRole(accessToken string, realmName string, groupID string, clientID string, role []kc.RoleRepresentation) error
 	RemoveClientRole(accessToken string, realmName string, groupID string, clientID string, role []kc.RoleRepresentation) error
 	GetGroupClientRoles(accessToken string, realmName string, groupID string, clientID string) ([]kc.RoleRepresentation, error)
@@ -62,8 +81,16 @@ type KeycloakClient interface {
 	DeleteCredential(accessToken string, realmName string, userID string, credentialID string) error
 	ResetPapercardFailures(accessToken string, realmName string, userID string, credentialID string) error
 	LinkShadowUser(accessToken string, realmName string, userID string, provider string, fedID kc.FederatedIdentityRepresentation) error
+	GetFederatedIdentities(accessToken string, realmName string, userID string) ([]kc.FederatedIdentityRepresentation, error)
+	UnlinkShadowUser(accessToken string, realmName string, userID string, provider string) error
 	ClearUserLoginFailures(accessToken string, realmName, userID string) error
 	GetAttackDetectionStatus(accessToken string, realmName, userID string) (map[string]interface{}, error)
+	GetSessionsOfUser(accessToken string, realmName, userID string) ([]kc.UserSessionRepresentation, error)
+	GetOfflineSessionsOfUser(accessToken string, realmName, userID, clientID string) ([]kc.UserSessionRepresentation, error)
+	LogoutUser(accessToken string, realmName, userID string) error
+	DeleteSession(accessToken string, realmName, sessionID string) error
+	GetConsentsOfUser(accessToken string, realmName, userID string) ([]kc.UserConsentRepresentation, error)
+	RevokeConsentOfUser(accessToken string, realmName, userID, clientID string) error
 }
 
 // UsersDetailsDBModule is the interface from the users module
@@ -71,6 +98,11 @@ type UsersDetailsDBModule interface {
 	StoreOrUpdateUserDetails(ctx context.Context, realm string, user dto.DBUser) error
 	GetUserDetails(ctx context.Context, realm string, userID string) (dto.DBUser, error)
 	DeleteUserDetails(ctx context.Context, realm string, userID string) error
+	UpdateTrustIDGroupsExpiries(ctx context.Context, realm string, userID string, expiries []dto.DBTrustIDGroupExpiry) error
+	FindUsersByIDDocumentNumber(ctx context.Context, realm string, documentNumber string) ([]string, error)
+	MoveChecks(ctx context.Context, realm string, fromUserID string, toUserID string) error
+	StoreUserChange(ctx context.Context, realm string, userID string, change dto.DBUserChange) error
+	GetUserHistory(ctx context.Context, realm string, userID string) ([]dto.DBUserChange, error)
 }
 
 // Component is the management component interface.
@@ -84,22 +116,31 @@ type Component interface {
 	GetRequiredActions(ctx context.Context, realmName string) ([]api.RequiredActionRepresentation, error)
 
 	DeleteUser(ctx context.Context, realmName, userID string) error
-	GetUser(ctx context.Context, realmName, userID string) (api.UserRepresentation, error)
-	UpdateUser(ctx context.Context, realmName, userID string, user api.UserRepresentation) error
+	GetUser(ctx context.Context, realmName, userID string) (api.UserRepresentation, string, error)
+	UpdateUser(ctx context.Context, realmName, userID string, user api.UserRepresentation, ifMatch string) error
+	GetUserHistory(ctx context.Context, realmName, userID string) ([]api.UserChangeRepresentation, error)
 	LockUser(ctx context.Context, realmName, userID string) error
 	UnlockUser(ctx context.Context, realmName, userID string) error
 	GetUsers(ctx context.Context, realmName string, groupIDs []string, paramKV ...string) (api.UsersPageRepresentation, error)
+	SearchUsers(ctx context.Context, realmName string, groupIDs []string, criteria api.UsersSearchRepresentation) (api.UsersPageRepresentation, error)
+	GetDuplicateUsers(ctx context.Context, realmName string, groupIDs []string) ([]api.DuplicateUsersRepresentation, error)
+	MergeUsers(ctx context.Context, realmName string, userID string, merge api.UsersMergeRepresentation) error
 	CreateUser(ctx context.Context, realmName string, user api.UserRepresentation) (string, error)
+	CheckUsersImport(ctx context.Context, realmName string, users []api.UserImportEntry) (api.UserImportReportRepresentation, error)
+	ImportUsers(ctx context.Context, realmName string, users []api.UserImportEntry, actions []api.RequiredAction) (api.UserImportJobRepresentation, error)
+	GetUsersImportJob(ctx context.Context, realmName string, jobID string) (api.UserImportJobRepresentation, error)
+	GetUsersImportResults(ctx context.Context, realmName string, jobID string) (api.UserImportRowsRepresentation, error)
 	GetUserAccountStatus(ctx context.Context, realmName, userID string) (map[string]bool, error)
 	GetRolesOfUser(ctx context.Context, realmName, userID string) ([]api.RoleRepresentation, error)
 	GetGroupsOfUser(ctx context.Context, realmName, userID string) ([]api.GroupRepresentation, error)
 	AddGroupToUser(ctx context.Context, realmName, userID string, groupID string) error
 	DeleteGroupForUser(ctx context.Context, realmName, userID string, groupID string) error
 	GetAvailableTrustIDGroups(ctx context.Context, realmName string) ([]string, error)
-	GetTrustIDGroupsOfUser(ctx context.Context, realmName, userID string) ([]string, error)
-	SetTrustIDGroupsToUser(ctx context.Context, realmName, userID string, groupNames []string) error
+	GetTrustIDGroupsOfUser(ctx context.Context, realmName, userID string) ([]api.TrustIDGroupRepresentation, error)
+	SetTrustIDGroupsToUser(ctx context.Context, realmName, userID string, groups []api.TrustIDGroupRepresentation) error
 	GetClientRolesForUser(ctx context.Context, realmName, userID, clientID string) ([]api.RoleRepresentation, error)
 	AddClientRolesToUser(ctx context.Context, realmName, userID, clientID string, roles []api.RoleRepresentation) error
+	DeleteClientRoleFromUser(ctx context.Context, realmName, userID, clientID string, roleID string) error
 
 	ResetPassword(ctx context.Context, realmName string, userID string, password api.PasswordRepresentation) (string, error)
 	ExecuteActionsEmail(ctx context.Context, realmName string, userID string, actions []api.RequiredAction, paramKV ...string) error
@@ -112,26 +153,68 @@ type Component interface {
 	ResetCredentialFailuresForUser(ctx context.Context, realmName string, userID string, credentialID string) error
 	ClearUserLoginFailures(ctx context.Context, realmName, userID string) error
 	GetAttackDetectionStatus(ctx context.Context, realmName, userID string) (api.AttackDetectionStatusRepresentation, error)
+	GetSessionsOfUser(ctx context.Context, realmName, userID string) ([]api.UserSessionRepresentation, error)
+	LogoutUser(ctx context.Context, realmName, userID string) error
+	DeleteSessionOfUser(ctx context.Context, realmName, userID, sessionID string) error
+	GetOfflineSessionsOfUser(ctx context.Context, realmName, userID, clientID string) ([]api.UserSessionRepresentation, error)
+	GetConsentsOfUser(ctx context.Context, realmName, userID string) ([]api.UserConsentRepresentation, error)
+	RevokeConsentOfUser(ctx context.Context, realmName, userID, clientID string) error
 	GetRoles(ctx context.Context, realmName string) ([]api.RoleRepresentation, error)
 	GetRole(ctx context.Context, realmName string, roleID string) (api.RoleRepresentation, error)
+	CreateRole(ctx context.Context, realmName string, role api.RoleRepresentation) (string, error)
+	UpdateRole(ctx context.Context, realmName string, roleID string, role api.RoleRepresentation) error
+	DeleteRole(ctx context.Context, realmName string, roleID string) error
+	GetRoleComposites(ctx context.Context, realmName string, roleID string) ([]api.RoleRepresentation, error)
+	AddRoleComposites(ctx context.Context, realmName string, roleID string, roles []api.RoleRepresentation) error
+	DeleteRoleComposites(ctx context.Context, realmName string, roleID string, roles []api.RoleRepresentation) error
+	GetUsersWithRole(ctx context.Context, realmName string, roleID string, paramKV ...string) ([]api.UserRepresentation, error)
 	GetClientRoles(ctx context.Context, realmName, idClient string) ([]api.RoleRepresentation, error)
 	CreateClientRole(ctx context.Context, realmName, clientID string, role api.RoleRepresentation) (string, error)
+	DeleteClientRole(ctx context.Context, realmName, clientID string, roleID string) error
 
 	GetGroups(ctx context.Context, realmName string) ([]api.GroupRepresentation, error)
 	CreateGroup(ctx context.Context, realmName string, group api.GroupRepresentation) (string, error)
 	DeleteGroup(ctx context.Context, realmName string, groupID string) error
+	GetGroup(ctx context.Context, realmName string, groupID string) (api.GroupRepresentation, error)
+	UpdateGroup(ctx context.Context, realmName string, groupID string, group api.GroupRepresentation) error
+	CreateGroupChild(ctx context.Context, realmName string, groupID string, group api.GroupRepresentation) (string, error)
+	MoveGroup(ctx context.Context, realmName string, groupID string, childGroupID string) error
+	GetGroupMembers(ctx context.Context, realmName string, groupID string, paramKV ...string) ([]api.UserRepresentation, error)
 	GetAuthorizations(ctx context.Context, realmName string, groupID string) (api.AuthorizationsRepresentation, error)
 	UpdateAuthorizations(ctx context.Context, realmName string, groupID string, group api.AuthorizationsRepresentation) error
-
-	GetRealmCustomConfiguration(ctx context.Context, realmName string) (api.RealmCustomConfiguration, error)
-	UpdateRealmCustomConfiguration(ctx context.Context, realmID string, customConfig api.RealmCustomConfiguration) error
-	GetRealmAdminConfiguration(ctx context.Context, realmName string) (api.RealmAdminConfiguration, error)
-	UpdateRealmAdminConfiguration(ctx context.Context, realmID string, adminConfig api.RealmAdminConfiguration) error
+	CheckAuthorization(ctx context.Context, realmName, userID, action, targetRealm, targetGroup string) (api.AuthorizationCheckRepresentation, error)
+	GetAuthorizationTemplates(ctx context.Context) ([]api.AuthorizationTemplateRepresentation, error)
+	GetAuthorizationTemplate(ctx context.Context, templateName string) (api.AuthorizationTemplateRepresentation, error)
+	UpdateAuthorizationTemplate(ctx context.Context, templateName string, template api.AuthorizationTemplateRepresentation) error
+	DeleteAuthorizationTemplate(ctx context.Context, templateName string) error
+	ApplyAuthorizationTemplate(ctx context.Context, realmName string, groupID string, templateName string, targetRealm string) error
+	CopyAuthorizations(ctx context.Context, sourceRealm string, targetRealm string, dryRun bool) (api.AuthorizationsCopyRepresentation, error)
+	GetAuthorizationsVersions(ctx context.Context, realmName string, groupID string) ([]api.AuthorizationsVersionRepresentation, error)
+	GetAuthorizationsVersionsDiff(ctx context.Context, realmName string, groupID string, fromVersion int, toVersion int) (api.AuthorizationsDiffRepresentation, error)
+	RollbackAuthorizations(ctx context.Context, realmName string, groupID string, version int) error
+
+	GetPendingRequests(ctx context.Context, realmName string) ([]api.PendingRequestRepresentation, error)
+	GetPendingRequest(ctx context.Context, realmName string, requestID string) (api.PendingRequestRepresentation, error)
+	ApprovePendingRequest(ctx context.Context, realmName string, requestID string) (string, error)
+	RejectPendingRequest(ctx context.Context, realmName string, requestID string) error
+
+	GetRealmCustomConfiguration(ctx context.Context, realmName string) (api.RealmCustomConfiguration, string, error)
+	UpdateRealmCustomConfiguration(ctx context.Context, realmID string, customConfig api.RealmCustomConfiguration, ifMatch string) error
+	GetRealmAdminConfiguration(ctx context.Context, realmName string) (api.RealmAdminConfiguration, string, error)
+	UpdateRealmAdminConfiguration(ctx context.Context, realmID string, adminConfig api.RealmAdminConfiguration, ifMatch string) error
+	GetRealmCustomConfigurationVersions(ctx context.Context, realmName string) ([]api.RealmConfigurationVersionRepresentation, error)
+	GetRealmCustomConfigurationVersionsDiff(ctx context.Context, realmName string, fromVersion int, toVersion int) (api.RealmConfigurationDiffRepresentation, error)
+	RollbackRealmCustomConfiguration(ctx context.Context, realmName string, version int) error
+	GetRealmAdminConfigurationVersions(ctx context.Context, realmName string) ([]api.RealmConfigurationVersionRepresentation, error)
+	GetRealmAdminConfigurationVersionsDiff(ctx context.Context, realmName string, fromVersion int, toVersion int) (api.RealmConfigurationDiffRepresentation, error)
+	RollbackRealmAdminConfiguration(ctx context.Context, realmName string, version int) error
 	GetRealmBackOfficeConfiguration(ctx context.Context, realmID string, groupName string) (api.BackOfficeConfiguration, error)
 	UpdateRealmBackOfficeConfiguration(ctx context.Context, realmID string, groupName string, config api.BackOfficeConfiguration) error
 	GetUserRealmBackOfficeConfiguration(ctx context.Context, realmID string) (api.BackOfficeConfiguration, error)
 
 	LinkShadowUser(ctx context.Context, realmName string, userID string, provider string, fedID api.FederatedIdentityRepresentation) error
+	GetFederatedIdentities(ctx context.Context, realmName string, userID string) ([]api.FederatedIdentityRepresentation, error)
+	UnlinkShadowUser(ctx context.Context, realmName string, userID string, provider string) error
 }
 
 // Component is the management component.
@@ -140,13 +223,16 @@ type component struct {
 	usersDBModule           UsersDetailsDBModule
 	eventDBModule           database.EventsDBModule
 	configDBModule          keycloakb.ConfigurationDBModule
+	sagaModule              keycloakb.SagaModule
 	authorizedTrustIDGroups map[string]bool
+	usersImportJobs         *usersImportJobs
 	logger                  keycloakb.Logger
 }
 
-// NewComponent returns the management component.
+// NewComponent returns the management component. The users imports share bulkLimiter with the bulk operations
 func NewComponent(keycloakClient KeycloakClient, usersDBModule UsersDetailsDBModule, eventDBModule database.EventsDBModule,
-	configDBModule keycloakb.ConfigurationDBModule, authorizedTrustIDGroups []string, logger keycloakb.Logger) Component {
+	configDBModule keycloakb.ConfigurationDBModule, sagaModule keycloakb.SagaModule, authorizedTrustIDGroups []string,
+	bulkLimiter *rate.Limiter, logger keycloakb.Logger) Component {
 
 	var authzedTrustIDGroups = make(map[string]bool)
 	for _, grp := range authorizedTrustIDGroups {
@@ -158,12 +244,15 @@ func NewComponent(keycloakClient KeycloakClient, usersDBModule UsersDetailsDBMod
 		usersDBModule:           usersDBModule,
 		eventDBModule:           eventDBModule,
 		configDBModule:          configDBModule,
+		sagaModule:              sagaModule,
 		authorizedTrustIDGroups: authzedTrustIDGroups,
+		usersImportJobs:         &usersImportJobs{jobs: make(map[string]*usersImportJob), limiter: bulkLimiter},
 		logger:                  logger,
 	}
 }
 
 func (c *component) reportEvent(ctx context.Context, apiCall string, values ...string) {
+	values = withApprovedRequest(ctx, values)
 	errEvent := c.eventDBModule.ReportEvent(ctx, apiCall, "back-office", values...)
 	if errEvent != nil {
 		//store in the logs also the event that failed to be stored in the DB
@@ -291,40 +380,58 @@ func (c *component) CreateUser(ctx context.Context, realmName string, user api.U
 
 	userRep = api.ConvertToKCUser(user)
 
+	var username = ""
+	if user.Username != nil {
+		username = *user.Username
+	}
+
+	var userInfoToPersist = user.BirthLocation != nil
+	userInfoToPersist = userInfoToPersist || user.IDDocumentType != nil
+	userInfoToPersist = userInfoToPersist || user.IDDocumentNumber != nil
+	userInfoToPersist = userInfoToPersist || user.IDDocumentExpiration != nil
+
+	// The operation is recorded before the creation of the user: if the bridge stops before the details are stored,
+	// the reconciler deletes the user from KC
+	var saga keycloakb.SagaOperation
+	var err error
+	if userInfoToPersist {
+		saga, err = c.sagaModule.Begin(ctx, keycloakb.SagaOperation{Kind: keycloakb.SagaCreateUser, Realm: realmName, Username: username})
+		if err != nil {
+			return "", err
+		}
+	}
+
 	// Store user in KC
 	locationURL, err := c.keycloakClient.CreateUser(accessToken, ctxRealm, realmName, userRep)
 	if err != nil {
 		c.logger.Warn(ctx, "err", err.Error())
+		_ = c.sagaModule.Complete(ctx, saga)
 		return "", err
 	}
 
-	var username = ""
-	if user.Username != nil {
-		username = *user.Username
-	}
-
 	//retrieve the user ID
 	reg := regexp.MustCompile(`[0-9a-fA-F]{8}\-[0-9a-fA-F]{4}\-[0-9a-fA-F]{4}\-[0-9a-fA-F]{4}\-[0-9a-fA-F]{12}`)
 	userID := string(reg.Find([]byte(locationURL)))
 
-	var userInfoToPersist = user.BirthLocation != nil
-	userInfoToPersist = userInfoToPersist || user.IDDocumentType != nil
-	userInfoToPersist = userInfoToPersist || user.IDDocumentNumber != nil
-	userInfoToPersist = userInfoToPersist || user.IDDocumentExpiration != nil
-
 	if userInfoToPersist {
-		// Store user in database
-		err = c.usersDBModule.StoreOrUpdateUserDetails(ctx, realmName, dto.DBUser{
-			UserID:               &userID,
-			BirthLocation:        user.BirthLocation,
-			IDDocumentType:       user.IDDocumentType,
-			IDDocumentNumber:     user.IDDocumentNumber,
-			IDDocumentExpiration: user.IDDocumentExpiration,
-		})
+		// The user is deleted from KC if its details can't be stored
+		saga, err = c.sagaModule.SetUserID(ctx, saga, userID)
+		if err == nil {
+			// Store user in database
+			err = c.usersDBModule.StoreOrUpdateUserDetails(ctx, realmName, dto.DBUser{
+				UserID:               &userID,
+				BirthLocation:        user.BirthLocation,
+				IDDocumentType:       user.IDDocumentType,
+				IDDocumentNumber:     user.IDDocumentNumber,
+				IDDocumentExpiration: user.IDDocumentExpiration,
+			})
+		}
 		if err != nil {
 			c.logger.Warn(ctx, "msg", "Can't store user details in database", "err", err.Error())
+			_ = c.sagaModule.Compensate(ctx, accessToken, saga)
 			return "", err
 		}
+		_ = c.sagaModule.Complete(ctx, saga)
 	}
 
 	//store the API call into the DB
@@ -336,9 +443,16 @@ func (c *component) CreateUser(ctx context.Context, realmName string, user api.U
 func (c *component) DeleteUser(ctx context.Context, realmName, userID string) error {
 	var accessToken = ctx.Value(cs.CtContextAccessToken).(string)
 
-	err := c.keycloakClient.DeleteUser(accessToken, realmName, userID)
+	// The deletion of the user details is replayed by the reconciler if it can't be done now
+	saga, err := c.sagaModule.Begin(ctx, keycloakb.SagaOperation{Kind: keycloakb.SagaDeleteUser, Realm: realmName, UserID: userID})
+	if err != nil {
+		return err
+	}
+
+	err = c.keycloakClient.DeleteUser(accessToken, realmName, userID)
 	if err != nil {
 		c.logger.Warn(ctx, "err", err.Error())
+		_ = c.sagaModule.Complete(ctx, saga)
 		return err
 	}
 
@@ -347,6 +461,7 @@ func (c *component) DeleteUser(ctx context.Context, realmName, userID string) er
 		c.logger.Warn(ctx, "err", err.Error())
 		return err
 	}
+	_ = c.sagaModule.Complete(ctx, saga)
 
 	//store the API call into the DB
 	c.reportEvent(ctx, "API_ACCOUNT_DELETION", database.CtEventRealmName, realmName, database.CtEventUserID, userID)
@@ -354,7 +469,8 @@ func (c *component) DeleteUser(ctx context.Context, realmName, userID string) er
 	return nil
 }
 
-func (c *component) GetUser(ctx context.Context, realmName, userID string) (api.UserRepresentation, error) {
+// GetUser gets a user with the entity tag of its current version, computed from its Keycloak representation and its details
+func (c *component) GetUser(ctx context.Context, realmName, userID string) (api.UserRepresentation, string, error) {
 	var accessToken = ctx.Value(cs.CtContextAccessToken).(string)
 
 	var userRep api.UserRepresentation
@@ -362,7 +478,7 @@ func (c *component) GetUser(ctx context.Context, realmName, userID string) (api.
 
 	if err != nil {
 		c.logger.Warn(ctx, "err", err.Error())
-		return userRep, err
+		return userRep, "", err
 	}
 	keycloakb.ConvertLegacyAttribute(&userKc)
 
@@ -377,7 +493,13 @@ func (c *component) GetUser(ctx context.Context, realmName, userID string) (api.
 	dbUser, err := c.usersDBModule.GetUserDetails(ctx, realmName, *userKc.ID)
 	if err != nil {
 		c.logger.Warn(ctx, "err", err.Error())
-		return api.UserRepresentation{}, err
+		return api.UserRepresentation{}, "", err
+	}
+
+	etag, err := keycloakb.ComputeETag(userKc, dbUser)
+	if err != nil {
+		c.logger.Warn(ctx, "err", err.Error())
+		return api.UserRepresentation{}, "", err
 	}
 
 	userRep.BirthLocation = dbUser.BirthLocation
@@ -388,7 +510,7 @@ func (c *component) GetUser(ctx context.Context, realmName, userID string) (api.
 	//store the API call into the DB
 	c.reportEvent(ctx, "GET_DETAILS", database.CtEventRealmName, realmName, database.CtEventUserID, userID, database.CtEventUsername, username)
 
-	return userRep, nil
+	return userRep, etag, nil
 }
 
 func (c *component) isUpdated(newValue, oldValue *string) bool {
@@ -398,7 +520,8 @@ func (c *component) isUpdated(newValue, oldValue *string) bool {
 	return newValue != nil && *oldValue != *newValue
 }
 
-func (c *component) UpdateUser(ctx context.Context, realmName, userID string, user api.UserRepresentation) error {
+// UpdateUser updates a user. When ifMatch is given, the user must not have been modified since the operator read it
+func (c *component) UpdateUser(ctx context.Context, realmName, userID string, user api.UserRepresentation, ifMatch string) error {
 	var accessToken = ctx.Value(cs.CtContextAccessToken).(string)
 	var userRep kc.UserRepresentation
 
@@ -417,6 +540,12 @@ func (c *component) UpdateUser(ctx context.Context, realmName, userID string, us
 		return err
 	}
 
+	if err = keycloakb.CheckIfMatch(ifMatch, oldUserKc, oldDbUser); err != nil {
+		c.logger.Info(ctx, "msg", "User modified since it was read", "userID", userID)
+		return err
+	}
+	var previousDetails = oldDbUser
+
 	// when the email changes, set the EmailVerified to false
 	if c.isUpdated(user.Email, oldUserKc.Email) {
 		var verified = false
@@ -447,26 +576,12 @@ func (c *component) UpdateUser(ctx context.Context, realmName, userID string, us
 		keycloakb.RevokeAccreditations(&userRep)
 	}
 
-	// Update in KC
-	if err = c.keycloakClient.UpdateUser(accessToken, realmName, userID, userRep); err != nil {
-		c.logger.Warn(ctx, "err", err.Error())
-		return err
-	}
-
-	//store the API call into the DB in case where user.Enable is present
-	if user.Enabled != nil {
-		c.reportLockEvent(ctx, realmName, userID, user.Username, *user.Enabled)
-	}
-
-	// Update in DB user for extra infos
-	// Store user in database
 	var userInfosUpdated = keycloakb.IsUpdated(user.BirthLocation, oldDbUser.BirthLocation)
 	userInfosUpdated = userInfosUpdated || keycloakb.IsUpdated(user.IDDocumentType, oldDbUser.IDDocumentType)
 	userInfosUpdated = userInfosUpdated || keycloakb.IsUpdated(user.IDDocumentNumber, oldDbUser.IDDocumentNumber)
 	userInfosUpdated = userInfosUpdated || keycloakb.IsUpdated(user.IDDocumentExpiration, oldDbUser.IDDocumentExpiration)
 
 	if userInfosUpdated {
-
 		if keycloakb.IsUpdated(user.BirthLocation, oldDbUser.BirthLocation) {
 			oldDbUser.BirthLocation = user.BirthLocation
 		}
@@ -482,17 +597,91 @@ func (c *component) UpdateUser(ctx context.Context, realmName, userID string, us
 		if keycloakb.IsUpdated(user.IDDocumentExpiration, oldDbUser.IDDocumentExpiration) {
 			oldDbUser.IDDocumentExpiration = user.IDDocumentExpiration
 		}
+	}
+
+	// When both KC and DB are updated, the previous user is restored in KC if the DB update fails
+	var saga keycloakb.SagaOperation
+	if userInfosUpdated {
+		var targetUser, targetDbUser = userRep, oldDbUser
+		saga, err = c.sagaModule.Begin(ctx, keycloakb.SagaOperation{Kind: keycloakb.SagaUpdateUser, Realm: realmName, UserID: userID,
+			PreviousUser: &oldUserKc, PreviousDetails: &previousDetails, TargetUser: &targetUser, TargetDetails: &targetDbUser})
+		if err != nil {
+			return err
+		}
+	}
+
+	// Update in KC
+	if err = c.keycloakClient.UpdateUser(accessToken, realmName, userID, userRep); err != nil {
+		c.logger.Warn(ctx, "err", err.Error())
+		if userInfosUpdated {
+			_ = c.sagaModule.Complete(ctx, saga)
+		}
+		return err
+	}
+
+	//store the API call into the DB in case where user.Enable is present
+	if user.Enabled != nil {
+		c.reportLockEvent(ctx, realmName, userID, user.Username, *user.Enabled)
+	}
+
+	// the revocation is recorded as it changes the accreditations of the user
+	if revokeAccreditations && len(oldUserKc.GetAttribute(constants.AttrbAccreditations)) > 0 {
+		c.reportEvent(ctx, keycloakb.CtEventTypeAccreditationsRevoked, database.CtEventRealmName, realmName, database.CtEventUserID, userID)
+	}
 
+	// Update in DB user for extra infos
+	// Store user in database
+	if userInfosUpdated {
 		err = c.usersDBModule.StoreOrUpdateUserDetails(ctx, realmName, oldDbUser)
 		if err != nil {
 			c.logger.Warn(ctx, "msg", "Can't store user details in database", "err", err.Error())
+			_ = c.sagaModule.Compensate(ctx, accessToken, saga)
 			return err
 		}
+		_ = c.sagaModule.Complete(ctx, saga)
 	}
 
+	c.storeUserChange(ctx, realmName, userID, keycloakb.GetIdentityChanges(oldUserKc, previousDetails, userRep, oldDbUser))
+
 	return nil
 }
 
+// storeUserChange records the identity changes made by the current operator in the history of the user. A failure is
+// only logged as the user has already been updated
+func (c *component) storeUserChange(ctx context.Context, realmName, userID string, changes []dto.DBFieldChange) {
+	if len(changes) == 0 {
+		return
+	}
+	var actor, _ = ctx.Value(cs.CtContextUsername).(string)
+	var change = dto.DBUserChange{
+		ChangedAt: time.Now().Unix(),
+		Actor:     actor,
+		Channel:   keycloakb.HistoryChannelManagement,
+		Changes:   changes,
+	}
+	if err := c.usersDBModule.StoreUserChange(ctx, realmName, userID, change); err != nil {
+		c.logger.Warn(ctx, "msg", "Can't store the change in the history of the user", "err", err.Error())
+	}
+}
+
+func (c *component) GetUserHistory(ctx context.Context, realmName, userID string) ([]api.UserChangeRepresentation, error) {
+	changes, err := c.usersDBModule.GetUserHistory(ctx, realmName, userID)
+	if err != nil {
+		c.logger.Warn(ctx, "msg", "Can't get the history of the user", "err", err.Error())
+		return nil, err
+	}
+
+	var res = []api.UserChangeRepresentation{}
+	for _, change := range changes {
+		res = append(res, api.ConvertToAPIUserChange(change))
+	}
+
+	// the history contains personal data: its consultation is recorded
+	c.reportEvent(ctx, "GET_USER_HISTORY", database.CtEventRealmName, realmName, database.CtEventUserID, userID)
+
+	return res, nil
+}
+
 func (c *component) reportLockEvent(ctx context.Context, realmName, userID string, username *string, enabled bool) {
 	var blank = ""
 	if username == nil {
@@ -655,69 +844,192 @@ func (c *component) DeleteGroupForUser(ctx context.Context, realmName, userID st
 }
 
 func (c *component) GetAvailableTrustIDGroups(ctx context.Context, realmName string) ([]string, error) {
-	var res []string
-	for key := range c.authorizedTrustIDGroups {
+	var accessToken = ctx.Value(cs.CtContextAccessToken).(string)
+
+	var allowedGroups, err = c.getAllowedTrustIDGroups(ctx, accessToken, realmName)
+	if err != nil {
+		return nil, err
+	}
+
+	var res = make([]string, 0, len(allowedGroups))
+	for key := range allowedGroups {
 		res = append(res, key)
 	}
+	sort.Strings(res)
+	return res, nil
+}
+
+// getAllowedTrustIDGroups returns the trustID groups which can be assigned in a realm: the groups configured for the
+// realm in the configuration DB or, if the realm has no specific configuration, the groups of the bridge configuration
+func (c *component) getAllowedTrustIDGroups(ctx context.Context, accessToken string, realmName string) (map[string]bool, error) {
+	var realm, err = c.keycloakClient.GetRealm(accessToken, realmName)
+	if err != nil {
+		c.logger.Warn(ctx, "err", err.Error())
+		return nil, err
+	}
+
+	var groups []string
+	groups, err = c.configDBModule.GetTrustIDGroups(ctx, *realm.ID)
+	if err != nil {
+		c.logger.Warn(ctx, "err", err.Error())
+		return nil, err
+	}
+	if groups == nil {
+		return c.authorizedTrustIDGroups, nil
+	}
+
+	var res = make(map[string]bool)
+	for _, grp := range groups {
+		res[grp] = true
+	}
 	return res, nil
 }
 
-func (c *component) GetTrustIDGroupsOfUser(ctx context.Context, realmName, userID string) ([]string, error) {
+func (c *component) GetTrustIDGroupsOfUser(ctx context.Context, realmName, userID string) ([]api.TrustIDGroupRepresentation, error) {
 	var accessToken = ctx.Value(cs.CtContextAccessToken).(string)
 	var currentUser, err = c.keycloakClient.GetUser(accessToken, realmName, userID)
 	if err != nil {
 		c.logger.Warn(ctx, "err", err.Error())
 		return nil, err
 	}
-	var groups = make([]string, 0)
-	for _, grp := range currentUser.GetAttribute(constants.AttrbTrustIDGroups) {
-		if strings.HasPrefix(grp, "/") {
-			grp = grp[1:]
-		}
-		groups = append(groups, grp)
+	var groups = make([]api.TrustIDGroupRepresentation, 0)
+	for _, assignment := range keycloakb.GetTrustIDGroupAssignments(currentUser) {
+		groups = append(groups, api.ConvertToAPITrustIDGroup(assignment))
 	}
 
 	return groups, nil
 }
 
-func (c *component) SetTrustIDGroupsToUser(ctx context.Context, realmName, userID string, groupNames []string) error {
+func (c *component) SetTrustIDGroupsToUser(ctx context.Context, realmName, userID string, groups []api.TrustIDGroupRepresentation) error {
 	var accessToken = ctx.Value(cs.CtContextAccessToken).(string)
+	var username = ctx.Value(cs.CtContextUsername).(string)
+	var now = time.Now()
 
-	// validate the input - trustID groups must be valid
-	var extGroupNames []string
-	for _, groupName := range groupNames {
-		if _, ok := c.authorizedTrustIDGroups[groupName]; ok {
-			extGroupNames = append(extGroupNames, "/"+groupName)
-		} else {
+	var allowedGroups, err = c.getAllowedTrustIDGroups(ctx, accessToken, realmName)
+	if err != nil {
+		return err
+	}
+
+	// validate the input - trustID groups must be valid and expire in the future
+	var assignments []keycloakb.TrustIDGroupAssignment
+	for _, group := range groups {
+		if err := group.Validate(); err != nil {
+			c.logger.Warn(ctx, "err", err.Error())
+			return err
+		}
+		if _, ok := allowedGroups[*group.Name]; !ok {
 			// unauthorized call (unknown trustID group) --> error
-			c.logger.Warn(ctx, "msg", groupName+" group is not allowed to be set as a trustID group")
+			c.logger.Warn(ctx, "msg", *group.Name+" group is not allowed to be set as a trustID group")
 			return errorhandler.CreateBadRequestError(constants.MsgErrInvalidParam + "." + constants.TrustIDGroupName)
 		}
+		if group.ExpiresAt != nil && *group.ExpiresAt <= now.Unix() {
+			c.logger.Warn(ctx, "msg", *group.Name+" trustID group can't be assigned with an expiry date in the past")
+			return errorhandler.CreateBadRequestError(constants.MsgErrInvalidParam + "." + constants.ExpiresAt)
+		}
+		var assignment = keycloakb.TrustIDGroupAssignment{
+			Group:         *group.Name,
+			ExpiresAt:     group.ExpiresAt,
+			Justification: group.Justification,
+			GrantedBy:     &username,
+		}
+		if !assignment.FitsInAttribute() {
+			c.logger.Warn(ctx, "msg", *group.Name+" trustID group can't be assigned with such a long justification")
+			return errorhandler.CreateBadRequestError(constants.MsgErrInvalidParam + "." + constants.Justification)
+		}
+		assignments = append(assignments, assignment)
 	}
 
 	// get the "old" user representation
-	currentUser, err := c.keycloakClient.GetUser(accessToken, realmName, userID)
+	var currentUser kc.UserRepresentation
+	currentUser, err = c.keycloakClient.GetUser(accessToken, realmName, userID)
 	if err != nil {
 		c.logger.Warn(ctx, "err", err.Error())
 		return err
 	}
 	keycloakb.ConvertLegacyAttribute(&currentUser)
 
-	// set the trustID groups attributes
-	if currentUser.Attributes == nil {
-		var emptyMap = make(kc.Attributes)
-		currentUser.Attributes = &emptyMap
+	var granted, removed []keycloakb.TrustIDGroupAssignment
+	assignments, granted, removed = mergeTrustIDGroupAssignments(keycloakb.GetTrustIDGroupAssignments(currentUser), assignments)
+
+	// index the expiry dates before writing them in Keycloak: an expiry date indexed for a group which is not
+	// assigned is ignored and cleaned up by the expiry job
+	var expiries = keycloakb.GetTrustIDGroupsExpiries(realmName, userID, assignments)
+	if err = c.usersDBModule.UpdateTrustIDGroupsExpiries(ctx, realmName, userID, expiries); err != nil {
+		c.logger.Warn(ctx, "err", err.Error())
+		return err
 	}
-	(*currentUser.Attributes)[constants.AttrbTrustIDGroups] = extGroupNames
+
+	// set the trustID groups attributes
+	keycloakb.SetTrustIDGroupAssignments(&currentUser, assignments)
 
 	err = c.keycloakClient.UpdateUser(accessToken, realmName, userID, currentUser)
 	if err != nil {
 		c.logger.Warn(ctx, "err", err.Error())
 		return err
 	}
+
+	//store the API calls into the DB
+	for _, assignment := range granted {
+		c.reportEvent(ctx, "API_TRUSTID_GROUP_GRANT", database.CtEventRealmName, realmName, database.CtEventUserID, userID,
+			database.CtEventAdditionalInfo, trustIDGroupAdditionalInfo(assignment))
+	}
+	for _, assignment := range removed {
+		c.reportEvent(ctx, "API_TRUSTID_GROUP_REMOVAL", database.CtEventRealmName, realmName, database.CtEventUserID, userID,
+			database.CtEventAdditionalInfo, trustIDGroupAdditionalInfo(assignment))
+	}
 	return nil
 }
 
+// mergeTrustIDGroupAssignments computes the assignments of a user from the wanted ones. An unchanged assignment keeps
+// the operator who granted it. New or modified assignments are granted, missing ones are removed
+func mergeTrustIDGroupAssignments(current, wanted []keycloakb.TrustIDGroupAssignment) ([]keycloakb.TrustIDGroupAssignment, []keycloakb.TrustIDGroupAssignment, []keycloakb.TrustIDGroupAssignment) {
+	var currentByGroup = make(map[string]keycloakb.TrustIDGroupAssignment)
+	for _, assignment := range current {
+		currentByGroup[assignment.Group] = assignment
+	}
+
+	var res, granted, removed []keycloakb.TrustIDGroupAssignment
+	var wantedGroups = make(map[string]bool)
+	for _, assignment := range wanted {
+		if wantedGroups[assignment.Group] {
+			continue
+		}
+		wantedGroups[assignment.Group] = true
+		if previous, ok := currentByGroup[assignment.Group]; ok && sameInt64(previous.ExpiresAt, assignment.ExpiresAt) && sameString(previous.Justification, assignment.Justification) {
+			res = append(res, previous)
+			continue
+		}
+		res = append(res, assignment)
+		granted = append(granted, assignment)
+	}
+	for _, assignment := range current {
+		if !wantedGroups[assignment.Group] {
+			removed = append(removed, assignment)
+		}
+	}
+	return res, granted, removed
+}
+
+// trustIDGroupAdditionalInfo describes a trustID group assignment in the additional information of an event
+func trustIDGroupAdditionalInfo(assignment keycloakb.TrustIDGroupAssignment) string {
+	var values = []string{"trustIDGroup", assignment.Group}
+	if assignment.ExpiresAt != nil {
+		values = append(values, "expiresAt", strconv.FormatInt(*assignment.ExpiresAt, 10))
+	}
+	if assignment.Justification != nil {
+		values = append(values, "justification", *assignment.Justification)
+	}
+	return database.CreateAdditionalInfo(values...)
+}
+
+func sameInt64(a, b *int64) bool {
+	return (a == nil && b == nil) || (a != nil && b != nil && *a == *b)
+}
+
+func sameString(a, b *string) bool {
+	return (a == nil && b == nil) || (a != nil && b != nil && *a == *b)
+}
+
 func (c *component) GetClientRolesForUser(ctx context.Context, realmName, userID, clientID string) ([]api.RoleRepresentation, error) {
 	var accessToken = ctx.Value(cs.CtContextAccessToken).(string)
 
@@ -764,9 +1076,35 @@ func (c *component) AddClientRolesToUser(ctx context.Context, realmName, userID,
 
 	if err != nil {
 		c.logger.Warn(ctx, "err", err.Error())
+		return err
 	}
 
-	return err
+	//store the API call into the DB
+	c.reportEvent(ctx, "API_USER_CLIENT_ROLES_ADDITION", database.CtEventRealmName, realmName, database.CtEventUserID, userID,
+		database.CtEventAdditionalInfo, rolesAdditionalInfo(rolesRep, "client_id", clientID))
+
+	return nil
+}
+
+func (c *component) DeleteClientRoleFromUser(ctx context.Context, realmName, userID, clientID string, roleID string) error {
+	var accessToken = ctx.Value(cs.CtContextAccessToken).(string)
+
+	role, err := c.getClientRole(ctx, accessToken, realmName, clientID, roleID)
+	if err != nil {
+		return err
+	}
+
+	err = c.keycloakClient.DeleteClientRolesFromUserRoleMapping(accessToken, realmName, userID, clientID, []kc.RoleRepresentation{role})
+	if err != nil {
+		c.logger.Warn(ctx, "err", err.Error())
+		return err
+	}
+
+	//store the API call into the DB
+	c.reportEvent(ctx, "API_USER_CLIENT_ROLE_REMOVAL", database.CtEventRealmName, realmName, database.CtEventUserID, userID,
+		database.CtEventAdditionalInfo, rolesAdditionalInfo([]kc.RoleRepresentation{role}, "client_id", clientID))
+
+	return nil
 }
 
 func (c *component) ResetPassword(ctx context.Context, realmName string, userID string, password api.PasswordRepresentation) (string, error) {
@@ -1040,6 +1378,119 @@ func (c *component) GetAttackDetectionStatus(ctx context.Context, realmName, use
 	return api.ConvertAttackDetectionStatus(mapValues), nil
 }
 
+func (c *component) GetSessionsOfUser(ctx context.Context, realmName, userID string) ([]api.UserSessionRepresentation, error) {
+	var accessToken = ctx.Value(cs.CtContextAccessToken).(string)
+
+	sessionsKc, err := c.keycloakClient.GetSessionsOfUser(accessToken, realmName, userID)
+	if err != nil {
+		c.logger.Warn(ctx, "err", err.Error())
+		return nil, err
+	}
+
+	var sessionsRep = []api.UserSessionRepresentation{}
+	for _, sessionKc := range sessionsKc {
+		sessionsRep = append(sessionsRep, api.ConvertUserSession(&sessionKc))
+	}
+
+	return sessionsRep, nil
+}
+
+func (c *component) LogoutUser(ctx context.Context, realmName, userID string) error {
+	var accessToken = ctx.Value(cs.CtContextAccessToken).(string)
+
+	if err := c.keycloakClient.LogoutUser(accessToken, realmName, userID); err != nil {
+		c.logger.Warn(ctx, "err", err.Error())
+		return err
+	}
+
+	c.reportEvent(ctx, "API_USER_LOGOUT", database.CtEventRealmName, realmName, database.CtEventUserID, userID)
+
+	return nil
+}
+
+func (c *component) DeleteSessionOfUser(ctx context.Context, realmName, userID, sessionID string) error {
+	var accessToken = ctx.Value(cs.CtContextAccessToken).(string)
+
+	// Sessions are deleted by ID: ensure the session is owned by the user
+	sessionsKc, err := c.keycloakClient.GetSessionsOfUser(accessToken, realmName, userID)
+	if err != nil {
+		c.logger.Warn(ctx, "msg", "Could not obtain list of sessions", "err", err.Error())
+		return err
+	}
+
+	var ownedByUser = false
+	for _, sessionKc := range sessionsKc {
+		if sessionKc.ID != nil && *sessionKc.ID == sessionID {
+			ownedByUser = true
+			break
+		}
+	}
+
+	if !ownedByUser {
+		c.logger.Warn(ctx, "msg", "Try to delete session of another user", "sessionId", sessionID, "userId", userID)
+		return errorhandler.CreateNotFoundError(constants.MsgErrInvalidParam + "." + constants.SessionID)
+	}
+
+	if err = c.keycloakClient.DeleteSession(accessToken, realmName, sessionID); err != nil {
+		c.logger.Warn(ctx, "err", err.Error())
+		return err
+	}
+
+	c.reportEvent(ctx, "API_USER_SESSION_DELETION", database.CtEventRealmName, realmName, database.CtEventUserID, userID,
+		database.CtEventAdditionalInfo, database.CreateAdditionalInfo("session_id", sessionID))
+
+	return nil
+}
+
+func (c *component) GetOfflineSessionsOfUser(ctx context.Context, realmName, userID, clientID string) ([]api.UserSessionRepresentation, error) {
+	var accessToken = ctx.Value(cs.CtContextAccessToken).(string)
+
+	sessionsKc, err := c.keycloakClient.GetOfflineSessionsOfUser(accessToken, realmName, userID, clientID)
+	if err != nil {
+		c.logger.Warn(ctx, "err", err.Error())
+		return nil, err
+	}
+
+	var sessionsRep = []api.UserSessionRepresentation{}
+	for _, sessionKc := range sessionsKc {
+		sessionsRep = append(sessionsRep, api.ConvertUserSession(&sessionKc))
+	}
+
+	return sessionsRep, nil
+}
+
+func (c *component) GetConsentsOfUser(ctx context.Context, realmName, userID string) ([]api.UserConsentRepresentation, error) {
+	var accessToken = ctx.Value(cs.CtContextAccessToken).(string)
+
+	consentsKc, err := c.keycloakClient.GetConsentsOfUser(accessToken, realmName, userID)
+	if err != nil {
+		c.logger.Warn(ctx, "err", err.Error())
+		return nil, err
+	}
+
+	var consentsRep = []api.UserConsentRepresentation{}
+	for _, consentKc := range consentsKc {
+		consentsRep = append(consentsRep, api.ConvertUserConsent(&consentKc))
+	}
+
+	return consentsRep, nil
+}
+
+// RevokeConsentOfUser revokes the consent given by a user to a client. Keycloak also revokes the offline sessions of the user for this client
+func (c *component) RevokeConsentOfUser(ctx context.Context, realmName, userID, clientID string) error {
+	var accessToken = ctx.Value(cs.CtContextAccessToken).(string)
+
+	if err := c.keycloakClient.RevokeConsentOfUser(accessToken, realmName, userID, clientID); err != nil {
+		c.logger.Warn(ctx, "err", err.Error())
+		return err
+	}
+
+	c.reportEvent(ctx, "API_USER_CONSENT_REVOCATION", database.CtEventRealmName, realmName, database.CtEventUserID, userID,
+		database.CtEventAdditionalInfo, database.CreateAdditionalInfo("client_id", clientID))
+
+	return nil
+}
+
 func (c *component) GetRoles(ctx context.Context, realmName string) ([]api.RoleRepresentation, error) {
 	var accessToken = ctx.Value(cs.CtContextAccessToken).(string)
 
@@ -1087,152 +1538,949 @@ func (c *component) GetRole(ctx context.Context, realmName string, roleID string
 	return roleRep, nil
 }
 
-func (c *component) GetGroups(ctx context.Context, realmName string) ([]api.GroupRepresentation, error) {
+func (c *component) CreateRole(ctx context.Context, realmName string, role api.RoleRepresentation) (string, error) {
 	var accessToken = ctx.Value(cs.CtContextAccessToken).(string)
 
-	groupsKc, err := c.keycloakClient.GetGroups(accessToken, realmName)
+	// Client roles are created with CreateClientRole
+	var roleRep = kc.RoleRepresentation{
+		Name:        role.Name,
+		Description: role.Description,
+	}
+
+	locationURL, err := c.keycloakClient.CreateRole(accessToken, realmName, roleRep)
+	if err != nil {
+		c.logger.Warn(ctx, "err", err.Error())
+		return "", err
+	}
+
+	//store the API call into the DB
+	c.reportEvent(ctx, "API_ROLE_CREATION", database.CtEventRealmName, realmName, database.CtEventAdditionalInfo, rolesAdditionalInfo([]kc.RoleRepresentation{roleRep}))
+
+	return locationURL, nil
+}
+
+// UpdateRole updates the name and/or the description of a realm or client role
+func (c *component) UpdateRole(ctx context.Context, realmName string, roleID string, role api.RoleRepresentation) error {
+	var accessToken = ctx.Value(cs.CtContextAccessToken).(string)
+
+	roleKc, err := c.keycloakClient.GetRole(accessToken, realmName, roleID)
+	if err != nil {
+		c.logger.Warn(ctx, "err", err.Error())
+		return err
+	}
+
+	if role.Name != nil {
+		roleKc.Name = role.Name
+	}
+	if role.Description != nil {
+		roleKc.Description = role.Description
+	}
+
+	err = c.keycloakClient.UpdateRole(accessToken, realmName, roleID, roleKc)
+	if err != nil {
+		c.logger.Warn(ctx, "err", err.Error())
+		return err
+	}
+
+	//store the API call into the DB
+	c.reportEvent(ctx, "API_ROLE_UPDATE", database.CtEventRealmName, realmName, database.CtEventAdditionalInfo, rolesAdditionalInfo([]kc.RoleRepresentation{roleKc}))
+
+	return nil
+}
+
+// DeleteRole deletes a realm role. Client roles are deleted with DeleteClientRole
+func (c *component) DeleteRole(ctx context.Context, realmName string, roleID string) error {
+	var accessToken = ctx.Value(cs.CtContextAccessToken).(string)
+
+	roleKc, err := c.keycloakClient.GetRole(accessToken, realmName, roleID)
+	if err != nil {
+		c.logger.Warn(ctx, "err", err.Error())
+		return err
+	}
+
+	if roleKc.ClientRole != nil && *roleKc.ClientRole {
+		return errorhandler.CreateNotFoundError(constants.MsgErrInvalidParam + "." + constants.RoleID)
+	}
+
+	err = c.keycloakClient.DeleteRole(accessToken, realmName, roleID)
+	if err != nil {
+		c.logger.Warn(ctx, "err", err.Error())
+		return err
+	}
+
+	//store the API call into the DB
+	c.reportEvent(ctx, "API_ROLE_DELETION", database.CtEventRealmName, realmName, database.CtEventAdditionalInfo, rolesAdditionalInfo([]kc.RoleRepresentation{roleKc}))
+
+	return nil
+}
+
+func (c *component) GetRoleComposites(ctx context.Context, realmName string, roleID string) ([]api.RoleRepresentation, error) {
+	var accessToken = ctx.Value(cs.CtContextAccessToken).(string)
+
+	rolesKc, err := c.keycloakClient.GetRoleComposites(accessToken, realmName, roleID)
+	if err != nil {
+		c.logger.Warn(ctx, "err", err.Error())
+		return nil, err
+	}
+
+	var rolesRep = []api.RoleRepresentation{}
+	for _, roleKc := range rolesKc {
+		rolesRep = append(rolesRep, api.ConvertToAPIRole(roleKc))
+	}
+
+	return rolesRep, nil
+}
+
+func (c *component) AddRoleComposites(ctx context.Context, realmName string, roleID string, roles []api.RoleRepresentation) error {
+	var accessToken = ctx.Value(cs.CtContextAccessToken).(string)
+
+	var rolesKc = []kc.RoleRepresentation{}
+	for _, role := range roles {
+		rolesKc = append(rolesKc, api.ConvertToKCRole(role))
+	}
+
+	err := c.keycloakClient.AddRoleComposites(accessToken, realmName, roleID, rolesKc)
+	if err != nil {
+		c.logger.Warn(ctx, "err", err.Error())
+		return err
+	}
+
+	//store the API call into the DB
+	c.reportEvent(ctx, "API_ROLE_COMPOSITES_ADDITION", database.CtEventRealmName, realmName, database.CtEventAdditionalInfo, rolesAdditionalInfo(rolesKc, "composite_role_id", roleID))
+
+	return nil
+}
+
+func (c *component) DeleteRoleComposites(ctx context.Context, realmName string, roleID string, roles []api.RoleRepresentation) error {
+	var accessToken = ctx.Value(cs.CtContextAccessToken).(string)
+
+	var rolesKc = []kc.RoleRepresentation{}
+	for _, role := range roles {
+		rolesKc = append(rolesKc, api.ConvertToKCRole(role))
+	}
+
+	err := c.keycloakClient.DeleteRoleComposites(accessToken, realmName, roleID, rolesKc)
+	if err != nil {
+		c.logger.Warn(ctx, "err", err.Error())
+		return err
+	}
+
+	//store the API call into the DB
+	c.reportEvent(ctx, "API_ROLE_COMPOSITES_REMOVAL", database.CtEventRealmName, realmName, database.CtEventAdditionalInfo, rolesAdditionalInfo(rolesKc, "composite_role_id", roleID))
+
+	return nil
+}
+
+// GetUsersWithRole gives the users having directly a realm or client role
+func (c *component) GetUsersWithRole(ctx context.Context, realmName string, roleID string, paramKV ...string) ([]api.UserRepresentation, error) {
+	var accessToken = ctx.Value(cs.CtContextAccessToken).(string)
+
+	roleKc, err := c.keycloakClient.GetRole(accessToken, realmName, roleID)
+	if err != nil {
+		c.logger.Warn(ctx, "err", err.Error())
+		return nil, err
+	}
+
+	// Keycloak gives the users of a role from its name
+	var usersKc []kc.UserRepresentation
+	if roleKc.ClientRole != nil && *roleKc.ClientRole {
+		usersKc, err = c.keycloakClient.GetUsersWithClientRole(accessToken, realmName, *roleKc.ContainerID, *roleKc.Name, paramKV...)
+	} else {
+		usersKc, err = c.keycloakClient.GetUsersWithRealmRole(accessToken, realmName, *roleKc.Name, paramKV...)
+	}
+	if err != nil {
+		c.logger.Warn(ctx, "err", err.Error())
+		return nil, err
+	}
+
+	var usersRep = []api.UserRepresentation{}
+	for _, userKc := range usersKc {
+		usersRep = append(usersRep, api.ConvertToAPIUser(ctx, userKc, c.logger))
+	}
+
+	return usersRep, nil
+}
+
+// getClientRole gets a role and checks that it belongs to the given client
+func (c *component) getClientRole(ctx context.Context, accessToken string, realmName, clientID, roleID string) (kc.RoleRepresentation, error) {
+	roleKc, err := c.keycloakClient.GetRole(accessToken, realmName, roleID)
+	if err != nil {
+		c.logger.Warn(ctx, "err", err.Error())
+		return kc.RoleRepresentation{}, err
+	}
+
+	if roleKc.ClientRole == nil || !*roleKc.ClientRole || roleKc.ContainerID == nil || *roleKc.ContainerID != clientID {
+		return kc.RoleRepresentation{}, errorhandler.CreateNotFoundError(constants.MsgErrInvalidParam + "." + constants.RoleID)
+	}
+	return roleKc, nil
+}
+
+// rolesAdditionalInfo describes roles in the additional information of an event
+func rolesAdditionalInfo(roles []kc.RoleRepresentation, paramKV ...string) string {
+	var ids, names []string
+	for _, role := range roles {
+		if role.ID != nil {
+			ids = append(ids, *role.ID)
+		}
+		if role.Name != nil {
+			names = append(names, *role.Name)
+		}
+	}
+	var values = append(paramKV, "role_ids", strings.Join(ids, ","), "role_names", strings.Join(names, ","))
+	return database.CreateAdditionalInfo(values...)
+}
+
+func (c *component) GetGroups(ctx context.Context, realmName string) ([]api.GroupRepresentation, error) {
+	var accessToken = ctx.Value(cs.CtContextAccessToken).(string)
+
+	groupsKc, err := c.keycloakClient.GetGroups(accessToken, realmName)
+
+	if err != nil {
+		c.logger.Warn(ctx, "err", err.Error())
+		return nil, err
+	}
+
+	var groupsRep = []api.GroupRepresentation{}
+	for _, groupKc := range groupsKc {
+		var groupRep api.GroupRepresentation
+		groupRep.ID = groupKc.ID
+		groupRep.Name = groupKc.Name
+
+		groupsRep = append(groupsRep, groupRep)
+	}
+
+	return groupsRep, nil
+}
+
+func (c *component) CreateGroup(ctx context.Context, realmName string, group api.GroupRepresentation) (string, error) {
+	var accessToken = ctx.Value(cs.CtContextAccessToken).(string)
+
+	var groupRep kc.GroupRepresentation
+	groupRep = api.ConvertToKCGroup(group)
+
+	locationURL, err := c.keycloakClient.CreateGroup(accessToken, realmName, groupRep)
+	if err != nil {
+		c.logger.Warn(ctx, "err", err.Error())
+		return "", err
+	}
+
+	//retrieve the group ID
+	reg := regexp.MustCompile(`[0-9a-fA-F]{8}\-[0-9a-fA-F]{4}\-[0-9a-fA-F]{4}\-[0-9a-fA-F]{4}\-[0-9a-fA-F]{12}`)
+	groupID := string(reg.Find([]byte(locationURL)))
+
+	//store the API call into the DB
+	c.reportEvent(ctx, "API_GROUP_CREATION", database.CtEventRealmName, realmName, database.CtEventGroupID, groupID, database.CtEventGroupName, *group.Name)
+
+	return locationURL, nil
+}
+
+func (c *component) DeleteGroup(ctx context.Context, realmName, groupID string) error {
+	var accessToken = ctx.Value(cs.CtContextAccessToken).(string)
+
+	group, err := c.keycloakClient.GetGroup(accessToken, realmName, groupID)
+	if err != nil {
+		c.logger.Warn(ctx, "err", err.Error())
+		return err
+	}
+
+	var groupName = *group.Name
+
+	err = c.keycloakClient.DeleteGroup(accessToken, realmName, groupID)
+	if err != nil {
+		c.logger.Warn(ctx, "err", err.Error())
+		return err
+	}
+
+	err = c.deleteGroupFromAuthorizations(ctx, realmName, groupName)
+	if err != nil {
+		c.logger.Warn(ctx, "err", err.Error())
+		return err
+	}
+
+	//store the API call into the DB
+	c.reportEvent(ctx, "API_GROUP_DELETION", database.CtEventRealmName, realmName, database.CtEventGroupName, groupName)
+
+	return nil
+}
+
+// deleteGroupFromAuthorizations deletes the authorizations of a group and the rules of the other groups on it in a single
+// transaction. A version is recorded for each group whose authorizations change
+func (c *component) deleteGroupFromAuthorizations(ctx context.Context, realmName, groupName string) error {
+	ownAuthorizations, err := c.configDBModule.GetAuthorizations(ctx, realmName, groupName)
+	if err != nil {
+		return err
+	}
+	groupsOnGroup, err := c.getAuthorizationsOfGroupsOnGroup(ctx, realmName, groupName)
+	if err != nil {
+		return err
+	}
+
+	tx, err := c.configDBModule.NewTransaction(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Close()
+
+	if err = c.configDBModule.DeleteAllAuthorizationsWithGroup(ctx, tx, realmName, groupName); err != nil {
+		return err
+	}
+	if err = c.createAuthorizationsVersion(ctx, tx, realmName, groupName, ownAuthorizations, []configuration.Authorization{}); err != nil {
+		return err
+	}
+	for _, group := range groupsOnGroup {
+		if group.realm == realmName && group.name == groupName {
+			continue
+		}
+		var after = removeTargetGroup(group.authorizations, realmName, groupName)
+		if err = c.createAuthorizationsVersion(ctx, tx, group.realm, group.name, group.authorizations, after); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// renameGroupInAuthorizations renames a group in the authorizations, their versions and the back-office configuration in
+// a single transaction. A version is recorded for each group whose rules on the renamed group change
+func (c *component) renameGroupInAuthorizations(ctx context.Context, realmName, oldGroupName, newGroupName string) error {
+	groupsOnGroup, err := c.getAuthorizationsOfGroupsOnGroup(ctx, realmName, oldGroupName)
+	if err != nil {
+		return err
+	}
+
+	tx, err := c.configDBModule.NewTransaction(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Close()
+
+	if err = c.configDBModule.RenameGroup(ctx, tx, realmName, oldGroupName, newGroupName); err != nil {
+		return err
+	}
+	for _, group := range groupsOnGroup {
+		var groupName = group.name
+		if group.realm == realmName && groupName == oldGroupName {
+			// The renamed group has rules on itself: its versions have already been renamed
+			groupName = newGroupName
+		}
+		var after = renameTargetGroup(group.authorizations, realmName, oldGroupName, newGroupName)
+		if err = c.createAuthorizationsVersion(ctx, tx, group.realm, groupName, group.authorizations, after); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// getAuthorizationsOfGroupsOnGroup gets the authorizations of each group having rules on a target group
+func (c *component) getAuthorizationsOfGroupsOnGroup(ctx context.Context, targetRealm, targetGroup string) ([]groupAuthorizations, error) {
+	rules, err := c.configDBModule.GetAuthorizationsOnGroup(ctx, targetRealm, targetGroup)
+	if err != nil {
+		return nil, err
+	}
+
+	var res []groupAuthorizations
+	var known = make(map[[2]string]struct{})
+	for _, rule := range rules {
+		var key = [2]string{*rule.RealmID, *rule.GroupName}
+		if _, ok := known[key]; ok {
+			continue
+		}
+		known[key] = struct{}{}
+
+		authorizations, err := c.configDBModule.GetAuthorizations(ctx, *rule.RealmID, *rule.GroupName)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, groupAuthorizations{realm: *rule.RealmID, name: *rule.GroupName, authorizations: authorizations})
+	}
+	return res, nil
+}
+
+// createAuthorizationsVersion records a change of the authorizations of a group made by the current user
+func (c *component) createAuthorizationsVersion(ctx context.Context, tx sqltypes.Transaction, realmName, groupName string, before, after []configuration.Authorization) error {
+	var username = ctx.Value(cs.CtContextUsername).(string)
+
+	return c.configDBModule.CreateAuthorizationsVersion(ctx, tx, dto.AuthorizationsVersion{
+		RealmID:   realmName,
+		GroupName: groupName,
+		Author:    username,
+		CreatedAt: time.Now().Unix(),
+		Before:    *api.ConvertToAPIAuthorizations(before).Matrix,
+		After:     *api.ConvertToAPIAuthorizations(after).Matrix,
+	})
+}
+
+func (c *component) GetGroup(ctx context.Context, realmName, groupID string) (api.GroupRepresentation, error) {
+	var accessToken = ctx.Value(cs.CtContextAccessToken).(string)
+
+	group, err := c.keycloakClient.GetGroup(accessToken, realmName, groupID)
+	if err != nil {
+		c.logger.Warn(ctx, "err", err.Error())
+		return api.GroupRepresentation{}, err
+	}
+
+	return api.ConvertToAPIGroup(group), nil
+}
+
+// UpdateGroup updates the name and/or the attributes of a group. As authorizations and back-office configuration reference
+// groups by name, they are renamed as well. If they can't be renamed, the previous name of the group is restored in Keycloak
+func (c *component) UpdateGroup(ctx context.Context, realmName, groupID string, group api.GroupRepresentation) error {
+	var accessToken = ctx.Value(cs.CtContextAccessToken).(string)
+
+	oldGroup, err := c.keycloakClient.GetGroup(accessToken, realmName, groupID)
+	if err != nil {
+		c.logger.Warn(ctx, "err", err.Error())
+		return err
+	}
+
+	var oldGroupName = *oldGroup.Name
+	var updatedGroup = oldGroup
+	if group.Name != nil {
+		updatedGroup.Name = group.Name
+	}
+	if group.Attributes != nil {
+		updatedGroup.Attributes = group.Attributes
+	}
+
+	err = c.keycloakClient.UpdateGroup(accessToken, realmName, groupID, updatedGroup)
+	if err != nil {
+		c.logger.Warn(ctx, "err", err.Error())
+		return err
+	}
+
+	var groupName = *updatedGroup.Name
+	if groupName != oldGroupName {
+		if err = c.renameGroupInAuthorizations(ctx, realmName, oldGroupName, groupName); err != nil {
+			c.logger.Warn(ctx, "msg", "Can't rename group in configuration. Restoring its previous name", "err", err.Error(), "realm", realmName, "group", oldGroupName)
+			if errRestore := c.keycloakClient.UpdateGroup(accessToken, realmName, groupID, oldGroup); errRestore != nil {
+				c.logger.Error(ctx, "msg", "Can't restore the previous name of the group", "err", errRestore.Error(), "realm", realmName, "group", oldGroupName, "newName", groupName)
+			}
+			return err
+		}
+	}
+
+	//store the API call into the DB
+	c.reportEvent(ctx, "API_GROUP_UPDATE", database.CtEventRealmName, realmName, database.CtEventGroupID, groupID, database.CtEventGroupName, groupName)
+
+	return nil
+}
+
+func (c *component) CreateGroupChild(ctx context.Context, realmName, groupID string, group api.GroupRepresentation) (string, error) {
+	var accessToken = ctx.Value(cs.CtContextAccessToken).(string)
+
+	locationURL, err := c.keycloakClient.CreateGroupChild(accessToken, realmName, groupID, api.ConvertToKCGroup(group))
+	if err != nil {
+		c.logger.Warn(ctx, "err", err.Error())
+		return "", err
+	}
+
+	//retrieve the group ID
+	reg := regexp.MustCompile(`[0-9a-fA-F]{8}\-[0-9a-fA-F]{4}\-[0-9a-fA-F]{4}\-[0-9a-fA-F]{4}\-[0-9a-fA-F]{12}`)
+	childGroupID := string(reg.Find([]byte(locationURL)))
+
+	//store the API call into the DB
+	c.reportEvent(ctx, "API_GROUP_CREATION", database.CtEventRealmName, realmName, database.CtEventGroupID, childGroupID, database.CtEventGroupName, *group.Name)
+
+	return locationURL, nil
+}
+
+// MoveGroup moves the group childGroupID (and its own subgroups) under the group groupID
+func (c *component) MoveGroup(ctx context.Context, realmName, groupID, childGroupID string) error {
+	var accessToken = ctx.Value(cs.CtContextAccessToken).(string)
+
+	if groupID == childGroupID {
+		return errorhandler.CreateBadRequestError(constants.MsgErrInvalidParam + "." + constants.GroupID)
+	}
+
+	child, err := c.keycloakClient.GetGroup(accessToken, realmName, childGroupID)
+	if err != nil {
+		c.logger.Warn(ctx, "err", err.Error())
+		return err
+	}
+
+	// Keycloak moves an existing group when it is provided with its ID as a child of another group
+	_, err = c.keycloakClient.CreateGroupChild(accessToken, realmName, groupID, kc.GroupRepresentation{ID: child.ID, Name: child.Name})
+	if err != nil {
+		c.logger.Warn(ctx, "err", err.Error())
+		return err
+	}
+
+	//store the API call into the DB
+	c.reportEvent(ctx, "API_GROUP_MOVE", database.CtEventRealmName, realmName, database.CtEventGroupID, childGroupID, database.CtEventGroupName, *child.Name)
+
+	return nil
+}
+
+func (c *component) GetGroupMembers(ctx context.Context, realmName, groupID string, paramKV ...string) ([]api.UserRepresentation, error) {
+	var accessToken = ctx.Value(cs.CtContextAccessToken).(string)
+
+	usersKc, err := c.keycloakClient.GetGroupMembers(accessToken, realmName, groupID, paramKV...)
+	if err != nil {
+		c.logger.Warn(ctx, "err", err.Error())
+		return nil, err
+	}
+
+	var usersRep = []api.UserRepresentation{}
+	for _, userKc := range usersKc {
+		usersRep = append(usersRep, api.ConvertToAPIUser(ctx, userKc, c.logger))
+	}
+
+	return usersRep, nil
+}
+
+func (c *component) GetAuthorizations(ctx context.Context, realmName string, groupID string) (api.AuthorizationsRepresentation, error) {
+	var accessToken = ctx.Value(cs.CtContextAccessToken).(string)
+	group, err := c.keycloakClient.GetGroup(accessToken, realmName, groupID)
+	if err != nil {
+		c.logger.Warn(ctx, "err", err.Error())
+		return api.AuthorizationsRepresentation{}, err
+	}
 
+	authorizations, err := c.configDBModule.GetAuthorizations(ctx, realmName, *group.Name)
 	if err != nil {
 		c.logger.Warn(ctx, "err", err.Error())
-		return nil, err
+		return api.AuthorizationsRepresentation{}, err
 	}
 
-	var groupsRep = []api.GroupRepresentation{}
-	for _, groupKc := range groupsKc {
-		var groupRep api.GroupRepresentation
-		groupRep.ID = groupKc.ID
-		groupRep.Name = groupKc.Name
+	return api.ConvertToAPIAuthorizations(authorizations), nil
+}
 
-		groupsRep = append(groupsRep, groupRep)
+func (c *component) UpdateAuthorizations(ctx context.Context, realmName string, groupID string, auth api.AuthorizationsRepresentation) error {
+	var accessToken = ctx.Value(cs.CtContextAccessToken).(string)
+
+	group, err := c.keycloakClient.GetGroup(accessToken, realmName, groupID)
+	if err != nil {
+		c.logger.Warn(ctx, "err", err.Error())
+		return err
 	}
 
-	return groupsRep, nil
+	var groupName = *group.Name
+
+	authorizations := api.ConvertToDBAuthorizations(realmName, groupName, auth)
+
+	if err = c.checkAllowedTargetRealmsAndGroupNames(ctx, realmName, authorizations); err != nil {
+		return err
+	}
+
+	if err = c.persistAuthorizations(ctx, realmName, groupID, groupName, authorizations); err != nil {
+		return err
+	}
+
+	c.reportEvent(ctx, "API_AUTHORIZATIONS_UPDATE", database.CtEventRealmName, realmName, database.CtEventGroupName, groupName)
+
+	return nil
 }
 
-func (c *component) CreateGroup(ctx context.Context, realmName string, group api.GroupRepresentation) (string, error) {
+// persistAuthorizations replaces the authorizations of a group by the given ones, which are expected to be valid, and
+// records the change as a new version of the authorizations of the group
+func (c *component) persistAuthorizations(ctx context.Context, realmName, groupID, groupName string, authorizations []configuration.Authorization) error {
 	var accessToken = ctx.Value(cs.CtContextAccessToken).(string)
 
-	var groupRep kc.GroupRepresentation
-	groupRep = api.ConvertToKCGroup(group)
+	// Assign KC roles to groups
+	if err := c.assignKCRolesToGroups(accessToken, realmName, groupID, authorizations); err != nil {
+		c.logger.Warn(ctx, "err", err.Error())
+		return err
+	}
 
-	locationURL, err := c.keycloakClient.CreateGroup(accessToken, realmName, groupRep)
+	// Persists the new authorizations in DB
+	tx, err := c.configDBModule.NewTransaction(ctx)
 	if err != nil {
 		c.logger.Warn(ctx, "err", err.Error())
-		return "", err
+		return err
 	}
+	defer tx.Close()
 
-	//retrieve the group ID
-	reg := regexp.MustCompile(`[0-9a-fA-F]{8}\-[0-9a-fA-F]{4}\-[0-9a-fA-F]{4}\-[0-9a-fA-F]{4}\-[0-9a-fA-F]{12}`)
-	groupID := string(reg.Find([]byte(locationURL)))
+	previousAuthorizations, err := c.configDBModule.GetAuthorizations(ctx, realmName, groupName)
+	if err != nil {
+		c.logger.Warn(ctx, "err", err.Error())
+		return err
+	}
 
-	//store the API call into the DB
-	c.reportEvent(ctx, "API_GROUP_CREATION", database.CtEventRealmName, realmName, database.CtEventGroupID, groupID, database.CtEventGroupName, *group.Name)
+	err = c.configDBModule.DeleteAuthorizations(ctx, tx, realmName, groupName)
+	if err != nil {
+		c.logger.Warn(ctx, "err", err.Error())
+		return err
+	}
 
-	return locationURL, nil
+	for _, authorisation := range authorizations {
+		err = c.configDBModule.CreateAuthorization(ctx, tx, authorisation)
+		if err != nil {
+			c.logger.Warn(ctx, "err", err.Error())
+			return err
+		}
+	}
+
+	err = c.createAuthorizationsVersion(ctx, tx, realmName, groupName, previousAuthorizations, authorizations)
+	if err != nil {
+		c.logger.Warn(ctx, "err", err.Error())
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		c.logger.Warn(ctx, "err", err.Error())
+	}
+	return err
 }
 
-func (c *component) DeleteGroup(ctx context.Context, realmName, groupID string) error {
+// GetAuthorizationsVersions gets the history of the authorizations of a group, the most recent version first. Each
+// version gives the rules added and removed by the change
+func (c *component) GetAuthorizationsVersions(ctx context.Context, realmName string, groupID string) ([]api.AuthorizationsVersionRepresentation, error) {
 	var accessToken = ctx.Value(cs.CtContextAccessToken).(string)
 
 	group, err := c.keycloakClient.GetGroup(accessToken, realmName, groupID)
 	if err != nil {
 		c.logger.Warn(ctx, "err", err.Error())
-		return err
+		return nil, err
+	}
+
+	versions, err := c.configDBModule.GetAuthorizationsVersions(ctx, realmName, *group.Name)
+	if err != nil {
+		c.logger.Warn(ctx, "err", err.Error())
+		return nil, err
 	}
 
+	var res = []api.AuthorizationsVersionRepresentation{}
+	for _, version := range versions {
+		var before = api.ConvertToDBAuthorizations(realmName, version.GroupName, api.AuthorizationsRepresentation{Matrix: &version.Before})
+		var after = api.ConvertToDBAuthorizations(realmName, version.GroupName, api.AuthorizationsRepresentation{Matrix: &version.After})
+		added, removed := diffAuthorizations(before, after)
+
+		var number, author, createdAt, matrix = version.Version, version.Author, version.CreatedAt, version.After
+		res = append(res, api.AuthorizationsVersionRepresentation{
+			Version:   &number,
+			Author:    &author,
+			CreatedAt: &createdAt,
+			Matrix:    &matrix,
+			Added:     api.ConvertToAPIAuthorizationRules(added),
+			Removed:   api.ConvertToAPIAuthorizationRules(removed),
+		})
+	}
+	return res, nil
+}
+
+// GetAuthorizationsVersionsDiff gives the rules added and removed between two versions of the authorizations of a group
+func (c *component) GetAuthorizationsVersionsDiff(ctx context.Context, realmName string, groupID string, fromVersion int, toVersion int) (api.AuthorizationsDiffRepresentation, error) {
+	var accessToken = ctx.Value(cs.CtContextAccessToken).(string)
+
+	group, err := c.keycloakClient.GetGroup(accessToken, realmName, groupID)
+	if err != nil {
+		c.logger.Warn(ctx, "err", err.Error())
+		return api.AuthorizationsDiffRepresentation{}, err
+	}
 	var groupName = *group.Name
 
-	err = c.keycloakClient.DeleteGroup(accessToken, realmName, groupID)
+	from, err := c.configDBModule.GetAuthorizationsVersion(ctx, realmName, groupName, fromVersion)
+	if err != nil {
+		c.logger.Warn(ctx, "err", err.Error())
+		return api.AuthorizationsDiffRepresentation{}, err
+	}
+
+	to, err := c.configDBModule.GetAuthorizationsVersion(ctx, realmName, groupName, toVersion)
+	if err != nil {
+		c.logger.Warn(ctx, "err", err.Error())
+		return api.AuthorizationsDiffRepresentation{}, err
+	}
+
+	added, removed := diffAuthorizations(
+		api.ConvertToDBAuthorizations(realmName, groupName, api.AuthorizationsRepresentation{Matrix: &from.After}),
+		api.ConvertToDBAuthorizations(realmName, groupName, api.AuthorizationsRepresentation{Matrix: &to.After}),
+	)
+
+	return api.AuthorizationsDiffRepresentation{
+		Group:   &groupName,
+		Added:   api.ConvertToAPIAuthorizationRules(added),
+		Removed: api.ConvertToAPIAuthorizationRules(removed),
+	}, nil
+}
+
+// RollbackAuthorizations applies again the authorizations of a group as they were after the given version. They are
+// validated as any new authorizations and the rollback creates a new version
+func (c *component) RollbackAuthorizations(ctx context.Context, realmName string, groupID string, version int) error {
+	var accessToken = ctx.Value(cs.CtContextAccessToken).(string)
+
+	group, err := c.keycloakClient.GetGroup(accessToken, realmName, groupID)
 	if err != nil {
 		c.logger.Warn(ctx, "err", err.Error())
 		return err
 	}
+	var groupName = *group.Name
 
-	err = c.configDBModule.DeleteAllAuthorizationsWithGroup(ctx, realmName, groupName)
+	oldVersion, err := c.configDBModule.GetAuthorizationsVersion(ctx, realmName, groupName, version)
 	if err != nil {
 		c.logger.Warn(ctx, "err", err.Error())
 		return err
 	}
 
-	//store the API call into the DB
-	c.reportEvent(ctx, "API_GROUP_DELETION", database.CtEventRealmName, realmName, database.CtEventGroupName, groupName)
+	var authorizations = api.ConvertToDBAuthorizations(realmName, groupName, api.AuthorizationsRepresentation{Matrix: &oldVersion.After})
+
+	if err = c.checkAllowedTargetRealmsAndGroupNames(ctx, realmName, authorizations); err != nil {
+		return err
+	}
+
+	if err = c.persistAuthorizations(ctx, realmName, groupID, groupName, authorizations); err != nil {
+		return err
+	}
+
+	c.reportEvent(ctx, "API_AUTHORIZATIONS_ROLLBACK", database.CtEventRealmName, realmName, database.CtEventGroupName, groupName,
+		database.CtEventAdditionalInfo, database.CreateAdditionalInfo("version", strconv.Itoa(version)))
 
 	return nil
 }
 
-func (c *component) GetAuthorizations(ctx context.Context, realmName string, groupID string) (api.AuthorizationsRepresentation, error) {
-	var accessToken = ctx.Value(cs.CtContextAccessToken).(string)
-	group, err := c.keycloakClient.GetGroup(accessToken, realmName, groupID)
+func (c *component) GetAuthorizationTemplates(ctx context.Context) ([]api.AuthorizationTemplateRepresentation, error) {
+	templates, err := c.configDBModule.GetAuthorizationTemplates(ctx)
 	if err != nil {
 		c.logger.Warn(ctx, "err", err.Error())
-		return api.AuthorizationsRepresentation{}, err
+		return nil, err
 	}
 
-	authorizations, err := c.configDBModule.GetAuthorizations(ctx, realmName, *group.Name)
+	var res = []api.AuthorizationTemplateRepresentation{}
+	for _, template := range templates {
+		res = append(res, api.ConvertToAPIAuthorizationTemplate(template))
+	}
+	return res, nil
+}
+
+func (c *component) GetAuthorizationTemplate(ctx context.Context, templateName string) (api.AuthorizationTemplateRepresentation, error) {
+	template, err := c.configDBModule.GetAuthorizationTemplate(ctx, templateName)
 	if err != nil {
 		c.logger.Warn(ctx, "err", err.Error())
-		return api.AuthorizationsRepresentation{}, err
+		return api.AuthorizationTemplateRepresentation{}, err
 	}
+	return api.ConvertToAPIAuthorizationTemplate(template), nil
+}
 
-	return api.ConvertToAPIAuthorizations(authorizations), nil
+func (c *component) UpdateAuthorizationTemplate(ctx context.Context, templateName string, template api.AuthorizationTemplateRepresentation) error {
+	var dbTemplate = dto.AuthorizationTemplate{
+		Name:   templateName,
+		Matrix: *template.Matrix,
+	}
+	if err := c.configDBModule.StoreOrUpdateAuthorizationTemplate(ctx, dbTemplate); err != nil {
+		c.logger.Warn(ctx, "err", err.Error())
+		return err
+	}
+
+	c.reportEvent(ctx, "API_AUTHORIZATION_TEMPLATE_UPDATE", database.CtEventAdditionalInfo, database.CreateAdditionalInfo("template", templateName))
+
+	return nil
 }
 
-func (c *component) UpdateAuthorizations(ctx context.Context, realmName string, groupID string, auth api.AuthorizationsRepresentation) error {
+func (c *component) DeleteAuthorizationTemplate(ctx context.Context, templateName string) error {
+	if err := c.configDBModule.DeleteAuthorizationTemplate(ctx, templateName); err != nil {
+		c.logger.Warn(ctx, "err", err.Error())
+		return err
+	}
+
+	c.reportEvent(ctx, "API_AUTHORIZATION_TEMPLATE_DELETION", database.CtEventAdditionalInfo, database.CreateAdditionalInfo("template", templateName))
+
+	return nil
+}
+
+// ApplyAuthorizationTemplate replaces the authorizations of a group by the ones of a template. The realm placeholder of
+// the template is replaced by the target realm, which defaults to the realm of the group
+func (c *component) ApplyAuthorizationTemplate(ctx context.Context, realmName string, groupID string, templateName string, targetRealm string) error {
 	var accessToken = ctx.Value(cs.CtContextAccessToken).(string)
 
+	if targetRealm == "" {
+		targetRealm = realmName
+	}
+
 	group, err := c.keycloakClient.GetGroup(accessToken, realmName, groupID)
 	if err != nil {
 		c.logger.Warn(ctx, "err", err.Error())
 		return err
 	}
-
 	var groupName = *group.Name
 
-	authorizations := api.ConvertToDBAuthorizations(realmName, groupName, auth)
+	template, err := c.configDBModule.GetAuthorizationTemplate(ctx, templateName)
+	if err != nil {
+		c.logger.Warn(ctx, "err", err.Error())
+		return err
+	}
+
+	var matrix = applyTemplateRealm(template.Matrix, targetRealm)
+	var authorizations = api.ConvertToDBAuthorizations(realmName, groupName, api.AuthorizationsRepresentation{Matrix: &matrix})
 
 	if err = c.checkAllowedTargetRealmsAndGroupNames(ctx, realmName, authorizations); err != nil {
 		return err
 	}
 
-	// Assign KC roles to groups
-	if err = c.assignKCRolesToGroups(accessToken, realmName, groupID, authorizations); err != nil {
-		c.logger.Warn(ctx, "err", err.Error())
+	if err = c.persistAuthorizations(ctx, realmName, groupID, groupName, authorizations); err != nil {
 		return err
 	}
 
-	// Persists the new authorizations in DB
-	{
-		tx, err := c.configDBModule.NewTransaction(ctx)
-		if err != nil {
-			c.logger.Warn(ctx, "err", err.Error())
-			return err
+	c.reportEvent(ctx, "API_AUTHORIZATION_TEMPLATE_APPLICATION", database.CtEventRealmName, realmName, database.CtEventGroupName, groupName,
+		database.CtEventAdditionalInfo, database.CreateAdditionalInfo("template", templateName, "target_realm", targetRealm))
+
+	return nil
+}
+
+// CopyAuthorizations copies the authorizations of the groups of a realm to the groups with the same name in another
+// realm. Rules targeting the source realm are made to target the other realm. Groups of the target realm which do not
+// exist in the source realm keep their authorizations. In dry-run mode, the changes are only computed
+func (c *component) CopyAuthorizations(ctx context.Context, sourceRealm string, targetRealm string, dryRun bool) (api.AuthorizationsCopyRepresentation, error) {
+	var accessToken = ctx.Value(cs.CtContextAccessToken).(string)
+
+	sourceAuthorizations, err := c.configDBModule.GetRealmAuthorizations(ctx, sourceRealm)
+	if err != nil {
+		c.logger.Warn(ctx, "err", err.Error())
+		return api.AuthorizationsCopyRepresentation{}, err
+	}
+
+	targetAuthorizations, err := c.configDBModule.GetRealmAuthorizations(ctx, targetRealm)
+	if err != nil {
+		c.logger.Warn(ctx, "err", err.Error())
+		return api.AuthorizationsCopyRepresentation{}, err
+	}
+
+	targetGroups, err := c.keycloakClient.GetGroups(accessToken, targetRealm)
+	if err != nil {
+		c.logger.Warn(ctx, "err", err.Error())
+		return api.AuthorizationsCopyRepresentation{}, err
+	}
+	var targetGroupIDs = make(map[string]string)
+	for _, group := range targetGroups {
+		targetGroupIDs[*group.Name] = *group.ID
+	}
+
+	var wantedByGroup = make(map[string][]configuration.Authorization)
+	var groupNames []string
+	for _, authz := range moveAuthorizations(sourceAuthorizations, sourceRealm, targetRealm) {
+		if _, ok := wantedByGroup[*authz.GroupName]; !ok {
+			groupNames = append(groupNames, *authz.GroupName)
 		}
-		defer tx.Close()
+		wantedByGroup[*authz.GroupName] = append(wantedByGroup[*authz.GroupName], authz)
+	}
+	sort.Strings(groupNames)
 
-		err = c.configDBModule.DeleteAuthorizations(ctx, realmName, groupName)
-		if err != nil {
-			c.logger.Warn(ctx, "err", err.Error())
-			return err
+	var currentByGroup = make(map[string][]configuration.Authorization)
+	for _, authz := range targetAuthorizations {
+		currentByGroup[*authz.GroupName] = append(currentByGroup[*authz.GroupName], authz)
+	}
+
+	var res = api.AuthorizationsCopyRepresentation{
+		SourceRealm:   &sourceRealm,
+		TargetRealm:   &targetRealm,
+		DryRun:        &dryRun,
+		Groups:        []api.AuthorizationsDiffRepresentation{},
+		MissingGroups: []string{},
+	}
+	var changedGroups []string
+	var changedAuthorizations []configuration.Authorization
+	for _, groupName := range groupNames {
+		if _, ok := targetGroupIDs[groupName]; !ok {
+			res.MissingGroups = append(res.MissingGroups, groupName)
+			continue
 		}
 
-		for _, authorisation := range authorizations {
-			err = c.configDBModule.CreateAuthorization(ctx, authorisation)
-			if err != nil {
-				c.logger.Warn(ctx, "err", err.Error())
-				return err
-			}
+		added, removed := diffAuthorizations(currentByGroup[groupName], wantedByGroup[groupName])
+		if len(added) == 0 && len(removed) == 0 {
+			continue
+		}
+
+		var name = groupName
+		res.Groups = append(res.Groups, api.AuthorizationsDiffRepresentation{
+			Group:   &name,
+			Added:   api.ConvertToAPIAuthorizationRules(added),
+			Removed: api.ConvertToAPIAuthorizationRules(removed),
+		})
+		changedGroups = append(changedGroups, groupName)
+		changedAuthorizations = append(changedAuthorizations, wantedByGroup[groupName]...)
+	}
+
+	if len(changedAuthorizations) > 0 {
+		if err = c.checkAllowedTargetRealmsAndGroupNames(ctx, targetRealm, changedAuthorizations); err != nil {
+			return api.AuthorizationsCopyRepresentation{}, err
+		}
+	}
+
+	if dryRun {
+		return res, nil
+	}
+
+	for _, groupName := range changedGroups {
+		if err = c.persistAuthorizations(ctx, targetRealm, targetGroupIDs[groupName], groupName, wantedByGroup[groupName]); err != nil {
+			return api.AuthorizationsCopyRepresentation{}, err
+		}
+	}
+
+	c.reportEvent(ctx, "API_AUTHORIZATIONS_COPY", database.CtEventRealmName, targetRealm,
+		database.CtEventAdditionalInfo, database.CreateAdditionalInfo("source_realm", sourceRealm, "groups", strings.Join(changedGroups, ",")))
+
+	return res, nil
+}
+
+// CheckAuthorization explains whether an operator of the realm is allowed to perform an action. The operator is the current
+// user when no user ID is given. The target realm defaults to the realm of the operator.
+func (c *component) CheckAuthorization(ctx context.Context, realmName, userID, action, targetRealm, targetGroup string) (api.AuthorizationCheckRepresentation, error) {
+	var accessToken = ctx.Value(cs.CtContextAccessToken).(string)
+	var ctxRealm = ctx.Value(cs.CtContextRealm).(string)
+	var ctxUserID = ctx.Value(cs.CtContextUserID).(string)
+
+	if userID == "" {
+		userID = ctxUserID
+	}
+	if targetRealm == "" {
+		targetRealm = realmName
+	}
+
+	var scope, ok = actionScope(action)
+	if !ok {
+		return api.AuthorizationCheckRepresentation{}, errorhandler.CreateBadRequestError(constants.MsgErrInvalidParam + "." + constants.Action)
+	}
+
+	var optionalTargetGroup *string
+	if scope == security.ScopeGroup {
+		if targetGroup == "" {
+			return api.AuthorizationCheckRepresentation{}, errorhandler.CreateMissingParameterError(constants.TargetGroup)
 		}
+		optionalTargetGroup = &targetGroup
+	}
 
-		err = tx.Commit()
+	// Groups of the operator
+	var groupNames []string
+	if realmName == ctxRealm && userID == ctxUserID {
+		groupNames = ctx.Value(cs.CtContextGroups).([]string)
+	} else {
+		groupsKc, err := c.keycloakClient.GetGroupsOfUser(accessToken, realmName, userID)
 		if err != nil {
 			c.logger.Warn(ctx, "err", err.Error())
-			return err
+			return api.AuthorizationCheckRepresentation{}, err
+		}
+		for _, groupKc := range groupsKc {
+			groupNames = append(groupNames, *groupKc.Name)
 		}
 	}
 
-	c.reportEvent(ctx, "API_AUTHORIZATIONS_UPDATE", database.CtEventRealmName, realmName, database.CtEventGroupName, groupName)
+	var authorizations []configuration.Authorization
+	for _, groupName := range groupNames {
+		groupAuthorizations, err := c.configDBModule.GetAuthorizations(ctx, realmName, groupName)
+		if err != nil {
+			c.logger.Warn(ctx, "err", err.Error())
+			return api.AuthorizationCheckRepresentation{}, err
+		}
+		authorizations = append(authorizations, groupAuthorizations...)
+	}
 
-	return nil
+	allowed, matchingRules, missingRules, err := explainAuthorization(realmName, groupNames, authorizations, action, scope, targetRealm, optionalTargetGroup)
+	if err != nil {
+		c.logger.Warn(ctx, "err", err.Error())
+		return api.AuthorizationCheckRepresentation{}, err
+	}
+
+	return api.AuthorizationCheckRepresentation{
+		Realm:         &realmName,
+		UserID:        &userID,
+		Groups:        append([]string{}, groupNames...),
+		Action:        &action,
+		TargetRealm:   &targetRealm,
+		TargetGroup:   optionalTargetGroup,
+		Allowed:       &allowed,
+		MatchingRules: api.ConvertToAPIAuthorizationRules(matchingRules),
+		MissingRules:  api.ConvertToAPIAuthorizationRules(missingRules),
+	}, nil
 }
 
 func (c *component) checkAllowedTargetRealmsAndGroupNames(ctx context.Context, realmName string, authorizations []configuration.Authorization) error {
@@ -1408,23 +2656,64 @@ func (c *component) CreateClientRole(ctx context.Context, realmName, clientID st
 		return "", err
 	}
 
-	return locationURL, nil
+	//store the API call into the DB
+	c.reportEvent(ctx, "API_CLIENT_ROLE_CREATION", database.CtEventRealmName, realmName,
+		database.CtEventAdditionalInfo, rolesAdditionalInfo([]kc.RoleRepresentation{roleRep}, "client_id", clientID))
+
+	return locationURL, nil
+}
+
+func (c *component) DeleteClientRole(ctx context.Context, realmName, clientID string, roleID string) error {
+	var accessToken = ctx.Value(cs.CtContextAccessToken).(string)
+
+	role, err := c.getClientRole(ctx, accessToken, realmName, clientID, roleID)
+	if err != nil {
+		return err
+	}
+
+	err = c.keycloakClient.DeleteRole(accessToken, realmName, roleID)
+	if err != nil {
+		c.logger.Warn(ctx, "err", err.Error())
+		return err
+	}
+
+	//store the API call into the DB
+	c.reportEvent(ctx, "API_CLIENT_ROLE_DELETION", database.CtEventRealmName, realmName,
+		database.CtEventAdditionalInfo, rolesAdditionalInfo([]kc.RoleRepresentation{role}, "client_id", clientID))
+
+	return nil
 }
 
-// Retrieve the configuration from the database
-func (c *component) GetRealmCustomConfiguration(ctx context.Context, realmName string) (api.RealmCustomConfiguration, error) {
+// Retrieve the configuration from the database with the entity tag of its current version
+func (c *component) GetRealmCustomConfiguration(ctx context.Context, realmName string) (api.RealmCustomConfiguration, string, error) {
 	var accessToken = ctx.Value(cs.CtContextAccessToken).(string)
-	var falseBool = false
 
 	// get the realm config from Keycloak
 	realmConfig, err := c.keycloakClient.GetRealm(accessToken, realmName)
 	if err != nil {
 		c.logger.Warn(ctx, "err", err.Error())
-		return api.RealmCustomConfiguration{}, err
+		return api.RealmCustomConfiguration{}, "", err
+	}
+
+	customConfig, err := c.getRealmCustomConfiguration(ctx, *realmConfig.ID)
+	if err != nil {
+		return api.RealmCustomConfiguration{}, "", err
+	}
+
+	etag, err := keycloakb.ComputeETag(customConfig)
+	if err != nil {
+		c.logger.Warn(ctx, "err", err.Error())
+		return api.RealmCustomConfiguration{}, "", err
 	}
+
+	return customConfig, etag, nil
+}
+
+func (c *component) getRealmCustomConfiguration(ctx context.Context, realmID string) (api.RealmCustomConfiguration, error) {
+	var falseBool = false
+
 	// from the realm ID, fetch the custom configuration
-	realmID := realmConfig.ID
-	config, err := c.configDBModule.GetConfiguration(ctx, *realmID)
+	config, err := c.configDBModule.GetConfiguration(ctx, realmID)
 	// DB error
 	if err != nil {
 		switch e := errors.Cause(err).(type) {
@@ -1470,8 +2759,9 @@ func (c *component) GetRealmCustomConfiguration(ctx context.Context, realmName s
 	}, nil
 }
 
-// Update the configuration in the database; verify that the content of the configuration is coherent with Keycloak configuration
-func (c *component) UpdateRealmCustomConfiguration(ctx context.Context, realmName string, customConfig api.RealmCustomConfiguration) error {
+// Update the configuration in the database; verify that the content of the configuration is coherent with Keycloak configuration.
+// When ifMatch is given, the configuration must not have been modified since the operator read it
+func (c *component) UpdateRealmCustomConfiguration(ctx context.Context, realmName string, customConfig api.RealmCustomConfiguration, ifMatch string) error {
 	var accessToken = ctx.Value(cs.CtContextAccessToken).(string)
 
 	// get the realm config from Keycloak
@@ -1480,6 +2770,32 @@ func (c *component) UpdateRealmCustomConfiguration(ctx context.Context, realmNam
 		c.logger.Error(ctx, "err", err.Error())
 		return err
 	}
+
+	if ifMatch != "" {
+		currentConfig, err := c.getRealmCustomConfiguration(ctx, *realmConfig.ID)
+		if err != nil {
+			return err
+		}
+		if err = keycloakb.CheckIfMatch(ifMatch, currentConfig); err != nil {
+			c.logger.Info(ctx, "msg", "Custom configuration modified since it was read", "realm", realmName)
+			return err
+		}
+	}
+
+	if err = c.persistRealmCustomConfiguration(ctx, realmName, *realmConfig.ID, customConfig); err != nil {
+		return err
+	}
+
+	c.reportEvent(ctx, "API_REALM_CUSTOM_CONFIGURATION_UPDATE", database.CtEventRealmName, realmName)
+
+	return nil
+}
+
+// persistRealmCustomConfiguration checks the custom configuration is coherent with the Keycloak configuration, stores
+// it and records it as a new version of the custom configuration of the realm
+func (c *component) persistRealmCustomConfiguration(ctx context.Context, realmName string, realmID string, customConfig api.RealmCustomConfiguration) error {
+	var accessToken = ctx.Value(cs.CtContextAccessToken).(string)
+
 	// get the desired client (from its ID)
 	clients, err := c.keycloakClient.GetClients(accessToken, realmName)
 	if err != nil {
@@ -1519,8 +2835,27 @@ func (c *component) UpdateRealmCustomConfiguration(ctx context.Context, realmNam
 	}
 
 	// from the realm ID, update the custom configuration in the DB
-	realmID := realmConfig.ID
-	return c.configDBModule.StoreOrUpdateConfiguration(ctx, *realmID, config)
+	tx, err := c.configDBModule.NewTransaction(ctx)
+	if err != nil {
+		c.logger.Warn(ctx, "err", err.Error())
+		return err
+	}
+	defer tx.Close()
+
+	if err = c.configDBModule.StoreOrUpdateConfiguration(ctx, tx, realmID, config); err != nil {
+		c.logger.Warn(ctx, "err", err.Error())
+		return err
+	}
+
+	if err = c.createRealmConfigurationVersion(ctx, tx, realmID, keycloakb.RealmConfigurationTypeCustom, customConfig); err != nil {
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		c.logger.Warn(ctx, "err", err.Error())
+	}
+	return err
 }
 
 func (c *component) matchClients(customConfig api.RealmCustomConfiguration, clients []kc.ClientRepresentation) bool {
@@ -1557,32 +2892,71 @@ func (c *component) GetUserRealmBackOfficeConfiguration(ctx context.Context, rea
 	return api.BackOfficeConfiguration(dbResult), nil
 }
 
-// Retrieve the admin configuration from the database
-func (c *component) GetRealmAdminConfiguration(ctx context.Context, realmName string) (api.RealmAdminConfiguration, error) {
+// Retrieve the admin configuration from the database with the entity tag of its current version
+func (c *component) GetRealmAdminConfiguration(ctx context.Context, realmName string) (api.RealmAdminConfiguration, string, error) {
 	var accessToken = ctx.Value(cs.CtContextAccessToken).(string)
 
 	// get the realm config from Keycloak
 	realmConfig, err := c.keycloakClient.GetRealm(accessToken, realmName)
+	if err != nil {
+		c.logger.Warn(ctx, "err", err.Error())
+		return api.RealmAdminConfiguration{}, "", err
+	}
+
+	adminConfig, err := c.getRealmAdminConfiguration(ctx, *realmConfig.ID)
+	if err != nil {
+		return api.RealmAdminConfiguration{}, "", err
+	}
+
+	etag, err := keycloakb.ComputeETag(adminConfig)
+	if err != nil {
+		c.logger.Warn(ctx, "err", err.Error())
+		return api.RealmAdminConfiguration{}, "", err
+	}
+
+	return adminConfig, etag, nil
+}
+
+func (c *component) getRealmAdminConfiguration(ctx context.Context, realmID string) (api.RealmAdminConfiguration, error) {
+	var res api.RealmAdminConfiguration
+	var config, err = c.configDBModule.GetAdminConfiguration(ctx, realmID)
+	if err == nil {
+		res = api.ConvertRealmAdminConfigurationFromDBStruct(config)
+	} else if err == sql.ErrNoRows {
+		res = api.CreateDefaultRealmAdminConfiguration()
+	} else {
+		c.logger.Warn(ctx, "err", err.Error())
+		return api.RealmAdminConfiguration{}, err
+	}
+
+	// trustID groups are not part of the admin configuration stored by the common service
+	var trustIDGroups []string
+	trustIDGroups, err = c.configDBModule.GetTrustIDGroups(ctx, realmID)
 	if err != nil {
 		c.logger.Warn(ctx, "err", err.Error())
 		return api.RealmAdminConfiguration{}, err
 	}
+	if trustIDGroups != nil {
+		res.TrustIDGroups = &trustIDGroups
+	}
 
-	var config configuration.RealmAdminConfiguration
-	config, err = c.configDBModule.GetAdminConfiguration(ctx, *realmConfig.ID)
+	// neither are the actions which must be approved by a second operator
+	var approvalActions []string
+	approvalActions, err = c.configDBModule.GetApprovalActions(ctx, realmID)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return api.CreateDefaultRealmAdminConfiguration(), nil
-		}
 		c.logger.Warn(ctx, "err", err.Error())
 		return api.RealmAdminConfiguration{}, err
 	}
+	if len(approvalActions) > 0 {
+		res.ApprovalActions = &approvalActions
+	}
 
-	return api.ConvertRealmAdminConfigurationFromDBStruct(config), nil
+	return res, nil
 }
 
-// Update the configuration in the database
-func (c *component) UpdateRealmAdminConfiguration(ctx context.Context, realmName string, adminConfig api.RealmAdminConfiguration) error {
+// Update the configuration in the database. When ifMatch is given, the configuration must not have been modified since
+// the operator read it
+func (c *component) UpdateRealmAdminConfiguration(ctx context.Context, realmName string, adminConfig api.RealmAdminConfiguration, ifMatch string) error {
 	var accessToken = ctx.Value(cs.CtContextAccessToken).(string)
 
 	// get the realm config from Keycloak
@@ -1592,15 +2966,279 @@ func (c *component) UpdateRealmAdminConfiguration(ctx context.Context, realmName
 		return err
 	}
 
-	err = c.configDBModule.StoreOrUpdateAdminConfiguration(ctx, *realmRepr.ID, adminConfig.ConvertToDBStruct())
+	if ifMatch != "" {
+		currentConfig, err := c.getRealmAdminConfiguration(ctx, *realmRepr.ID)
+		if err != nil {
+			return err
+		}
+		if err = keycloakb.CheckIfMatch(ifMatch, currentConfig); err != nil {
+			c.logger.Info(ctx, "msg", "Admin configuration modified since it was read", "realm", realmName)
+			return err
+		}
+	}
+
+	if err = c.persistRealmAdminConfiguration(ctx, *realmRepr.ID, adminConfig); err != nil {
+		return err
+	}
+
+	c.reportEvent(ctx, "API_REALM_ADMIN_CONFIGURATION_UPDATE", database.CtEventRealmName, realmName)
+
+	return nil
+}
+
+// persistRealmAdminConfiguration stores the admin configuration, which is expected to be valid, and records it as a
+// new version of the admin configuration of the realm
+func (c *component) persistRealmAdminConfiguration(ctx context.Context, realmID string, adminConfig api.RealmAdminConfiguration) error {
+	tx, err := c.configDBModule.NewTransaction(ctx)
+	if err != nil {
+		c.logger.Warn(ctx, "err", err.Error())
+		return err
+	}
+	defer tx.Close()
+
+	err = c.configDBModule.StoreOrUpdateAdminConfiguration(ctx, tx, realmID, adminConfig.ConvertToDBStruct())
+	if err != nil {
+		c.logger.Warn(ctx, "err", err.Error())
+		return err
+	}
+
+	// without trustID groups, the realm uses the groups of the bridge configuration
+	var trustIDGroups []string
+	if adminConfig.TrustIDGroups != nil {
+		trustIDGroups = append(make([]string, 0), *adminConfig.TrustIDGroups...)
+	}
+	err = c.configDBModule.StoreOrUpdateTrustIDGroups(ctx, tx, realmID, trustIDGroups)
+	if err != nil {
+		c.logger.Warn(ctx, "err", err.Error())
+		return err
+	}
+
+	var approvalActions []string
+	if adminConfig.ApprovalActions != nil {
+		approvalActions = *adminConfig.ApprovalActions
+	}
+	err = c.configDBModule.StoreOrUpdateApprovalActions(ctx, tx, realmID, approvalActions)
+	if err != nil {
+		c.logger.Warn(ctx, "err", err.Error())
+		return err
+	}
+
+	if err = c.createRealmConfigurationVersion(ctx, tx, realmID, keycloakb.RealmConfigurationTypeAdmin, adminConfig); err != nil {
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		c.logger.Warn(ctx, "err", err.Error())
+	}
+	return err
+}
+
+// createRealmConfigurationVersion records the given configuration as the latest version of the configuration of a realm
+func (c *component) createRealmConfigurationVersion(ctx context.Context, tx sqltypes.Transaction, realmID string, configType string, config interface{}) error {
+	var username = ctx.Value(cs.CtContextUsername).(string)
+
+	configJSON, err := json.Marshal(config)
+	if err != nil {
+		c.logger.Warn(ctx, "err", err.Error())
+		return err
+	}
+
+	err = c.configDBModule.CreateRealmConfigurationVersion(ctx, tx, dto.RealmConfigurationVersion{
+		RealmID:       realmID,
+		Type:          configType,
+		Author:        username,
+		CreatedAt:     time.Now().Unix(),
+		Configuration: string(configJSON),
+	})
+	if err != nil {
+		c.logger.Warn(ctx, "err", err.Error())
+	}
+	return err
+}
+
+// GetRealmCustomConfigurationVersions gets the history of the custom configuration of a realm, the most recent version first
+func (c *component) GetRealmCustomConfigurationVersions(ctx context.Context, realmName string) ([]api.RealmConfigurationVersionRepresentation, error) {
+	return c.getRealmConfigurationVersions(ctx, realmName, keycloakb.RealmConfigurationTypeCustom)
+}
+
+// GetRealmCustomConfigurationVersionsDiff gives the fields which changed between two versions of the custom configuration of a realm
+func (c *component) GetRealmCustomConfigurationVersionsDiff(ctx context.Context, realmName string, fromVersion int, toVersion int) (api.RealmConfigurationDiffRepresentation, error) {
+	return c.getRealmConfigurationVersionsDiff(ctx, realmName, keycloakb.RealmConfigurationTypeCustom, fromVersion, toVersion)
+}
+
+// RollbackRealmCustomConfiguration applies again the given version of the custom configuration of a realm. It is
+// validated as any new configuration and the rollback creates a new version
+func (c *component) RollbackRealmCustomConfiguration(ctx context.Context, realmName string, version int) error {
+	var customConfig api.RealmCustomConfiguration
+	realmID, err := c.getRealmConfigurationToRollback(ctx, realmName, keycloakb.RealmConfigurationTypeCustom, version, &customConfig)
+	if err != nil {
+		return err
+	}
+
+	if err = customConfig.Validate(); err != nil {
+		c.logger.Warn(ctx, "err", err.Error())
+		return err
+	}
+
+	if err = c.persistRealmCustomConfiguration(ctx, realmName, realmID, customConfig); err != nil {
+		return err
+	}
+
+	c.reportEvent(ctx, "API_REALM_CUSTOM_CONFIGURATION_ROLLBACK", database.CtEventRealmName, realmName,
+		database.CtEventAdditionalInfo, database.CreateAdditionalInfo("version", strconv.Itoa(version)))
+
+	return nil
+}
+
+// GetRealmAdminConfigurationVersions gets the history of the admin configuration of a realm, the most recent version first
+func (c *component) GetRealmAdminConfigurationVersions(ctx context.Context, realmName string) ([]api.RealmConfigurationVersionRepresentation, error) {
+	return c.getRealmConfigurationVersions(ctx, realmName, keycloakb.RealmConfigurationTypeAdmin)
+}
+
+// GetRealmAdminConfigurationVersionsDiff gives the fields which changed between two versions of the admin configuration of a realm
+func (c *component) GetRealmAdminConfigurationVersionsDiff(ctx context.Context, realmName string, fromVersion int, toVersion int) (api.RealmConfigurationDiffRepresentation, error) {
+	return c.getRealmConfigurationVersionsDiff(ctx, realmName, keycloakb.RealmConfigurationTypeAdmin, fromVersion, toVersion)
+}
+
+// RollbackRealmAdminConfiguration applies again the given version of the admin configuration of a realm. It is
+// validated as any new configuration and the rollback creates a new version
+func (c *component) RollbackRealmAdminConfiguration(ctx context.Context, realmName string, version int) error {
+	var adminConfig api.RealmAdminConfiguration
+	realmID, err := c.getRealmConfigurationToRollback(ctx, realmName, keycloakb.RealmConfigurationTypeAdmin, version, &adminConfig)
 	if err != nil {
+		return err
+	}
+
+	if err = adminConfig.Validate(); err != nil {
 		c.logger.Warn(ctx, "err", err.Error())
 		return err
 	}
 
+	if err = c.persistRealmAdminConfiguration(ctx, realmID, adminConfig); err != nil {
+		return err
+	}
+
+	c.reportEvent(ctx, "API_REALM_ADMIN_CONFIGURATION_ROLLBACK", database.CtEventRealmName, realmName,
+		database.CtEventAdditionalInfo, database.CreateAdditionalInfo("version", strconv.Itoa(version)))
+
 	return nil
 }
 
+func (c *component) getRealmConfigurationVersions(ctx context.Context, realmName string, configType string) ([]api.RealmConfigurationVersionRepresentation, error) {
+	var accessToken = ctx.Value(cs.CtContextAccessToken).(string)
+
+	realmConfig, err := c.keycloakClient.GetRealm(accessToken, realmName)
+	if err != nil {
+		c.logger.Warn(ctx, "err", err.Error())
+		return nil, err
+	}
+
+	versions, err := c.configDBModule.GetRealmConfigurationVersions(ctx, *realmConfig.ID, configType)
+	if err != nil {
+		c.logger.Warn(ctx, "err", err.Error())
+		return nil, err
+	}
+
+	var res = []api.RealmConfigurationVersionRepresentation{}
+	for _, version := range versions {
+		var number, author, createdAt = version.Version, version.Author, version.CreatedAt
+		var config = json.RawMessage(version.Configuration)
+		res = append(res, api.RealmConfigurationVersionRepresentation{
+			Version:       &number,
+			Author:        &author,
+			CreatedAt:     &createdAt,
+			Configuration: &config,
+		})
+	}
+	return res, nil
+}
+
+func (c *component) getRealmConfigurationVersionsDiff(ctx context.Context, realmName string, configType string, fromVersion int, toVersion int) (api.RealmConfigurationDiffRepresentation, error) {
+	var accessToken = ctx.Value(cs.CtContextAccessToken).(string)
+
+	realmConfig, err := c.keycloakClient.GetRealm(accessToken, realmName)
+	if err != nil {
+		c.logger.Warn(ctx, "err", err.Error())
+		return api.RealmConfigurationDiffRepresentation{}, err
+	}
+
+	var fields [2]map[string]json.RawMessage
+	for i, number := range []int{fromVersion, toVersion} {
+		version, err := c.configDBModule.GetRealmConfigurationVersion(ctx, *realmConfig.ID, configType, number)
+		if err != nil {
+			c.logger.Warn(ctx, "err", err.Error())
+			return api.RealmConfigurationDiffRepresentation{}, err
+		}
+		if err = json.Unmarshal([]byte(version.Configuration), &fields[i]); err != nil {
+			c.logger.Warn(ctx, "err", err.Error())
+			return api.RealmConfigurationDiffRepresentation{}, err
+		}
+	}
+
+	return api.RealmConfigurationDiffRepresentation{Changes: diffRealmConfigurations(fields[0], fields[1])}, nil
+}
+
+// diffRealmConfigurations gives the top level fields of a configuration which differ between two versions, sorted by
+// name. A missing field is considered as null
+func diffRealmConfigurations(from map[string]json.RawMessage, to map[string]json.RawMessage) []api.RealmConfigurationFieldDiffRepresentation {
+	var names []string
+	for name := range from {
+		names = append(names, name)
+	}
+	for name := range to {
+		if _, ok := from[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var valueOf = func(values map[string]json.RawMessage, name string) json.RawMessage {
+		if value, ok := values[name]; ok {
+			return value
+		}
+		return json.RawMessage("null")
+	}
+
+	var res = []api.RealmConfigurationFieldDiffRepresentation{}
+	for _, name := range names {
+		var field, oldValue, newValue = name, valueOf(from, name), valueOf(to, name)
+		if string(oldValue) != string(newValue) {
+			res = append(res, api.RealmConfigurationFieldDiffRepresentation{
+				Field:    &field,
+				OldValue: &oldValue,
+				NewValue: &newValue,
+			})
+		}
+	}
+	return res
+}
+
+// getRealmConfigurationToRollback loads the given version of the configuration of a realm in config and returns the
+// ID of the realm
+func (c *component) getRealmConfigurationToRollback(ctx context.Context, realmName string, configType string, version int, config interface{}) (string, error) {
+	var accessToken = ctx.Value(cs.CtContextAccessToken).(string)
+
+	realmConfig, err := c.keycloakClient.GetRealm(accessToken, realmName)
+	if err != nil {
+		c.logger.Warn(ctx, "err", err.Error())
+		return "", err
+	}
+
+	oldVersion, err := c.configDBModule.GetRealmConfigurationVersion(ctx, *realmConfig.ID, configType, version)
+	if err != nil {
+		c.logger.Warn(ctx, "err", err.Error())
+		return "", err
+	}
+
+	if err = json.Unmarshal([]byte(oldVersion.Configuration), config); err != nil {
+		c.logger.Warn(ctx, "err", err.Error())
+		return "", err
+	}
+
+	return *realmConfig.ID, nil
+}
+
 func (c *component) GetRealmBackOfficeConfiguration(ctx context.Context, realmID string, groupName string) (api.BackOfficeConfiguration, error) {
 	var dbResult, err = c.configDBModule.GetBackOfficeConfiguration(ctx, realmID, []string{groupName})
 	if err != nil {
@@ -1722,6 +3360,67 @@ func (c *component) LinkShadowUser(ctx context.Context, realmName string, userID
 		c.logger.Warn(ctx, "err", err.Error())
 		return err
 	}
+
+	c.reportEvent(ctx, "API_FEDERATED_IDENTITY_LINK", database.CtEventRealmName, realmName, database.CtEventUserID, userID,
+		database.CtEventAdditionalInfo, database.CreateAdditionalInfo("identity_provider", provider, "federated_user_id", *fedID.UserID,
+			"federated_username", *fedID.Username))
+
+	return nil
+}
+
+func (c *component) GetFederatedIdentities(ctx context.Context, realmName string, userID string) ([]api.FederatedIdentityRepresentation, error) {
+	var accessToken = ctx.Value(cs.CtContextAccessToken).(string)
+
+	fedIDsKC, err := c.keycloakClient.GetFederatedIdentities(accessToken, realmName, userID)
+	if err != nil {
+		c.logger.Warn(ctx, "err", err.Error())
+		return nil, err
+	}
+
+	var fedIDs = []api.FederatedIdentityRepresentation{}
+	for _, fedIDKC := range fedIDsKC {
+		fedIDs = append(fedIDs, api.ConvertToAPIFedID(fedIDKC))
+	}
+
+	return fedIDs, nil
+}
+
+func (c *component) UnlinkShadowUser(ctx context.Context, realmName string, userID string, provider string) error {
+	var accessToken = ctx.Value(cs.CtContextAccessToken).(string)
+
+	// Get the link first: it is kept in the audit event so that it can be restored
+	fedIDsKC, err := c.keycloakClient.GetFederatedIdentities(accessToken, realmName, userID)
+	if err != nil {
+		c.logger.Warn(ctx, "msg", "Could not obtain list of federated identities", "err", err.Error())
+		return err
+	}
+
+	var linked *kc.FederatedIdentityRepresentation
+	for i, fedIDKC := range fedIDsKC {
+		if fedIDKC.IdentityProvider != nil && *fedIDKC.IdentityProvider == provider {
+			linked = &fedIDsKC[i]
+			break
+		}
+	}
+	if linked == nil {
+		return errorhandler.CreateNotFoundError(constants.IdentityProvider)
+	}
+
+	if err = c.keycloakClient.UnlinkShadowUser(accessToken, realmName, userID, provider); err != nil {
+		c.logger.Warn(ctx, "err", err.Error())
+		return err
+	}
+
+	var additionalInfo = []string{"identity_provider", provider}
+	if linked.UserID != nil {
+		additionalInfo = append(additionalInfo, "federated_user_id", *linked.UserID)
+	}
+	if linked.UserName != nil {
+		additionalInfo = append(additionalInfo, "federated_username", *linked.UserName)
+	}
+	c.reportEvent(ctx, "API_FEDERATED_IDENTITY_UNLINK", database.CtEventRealmName, realmName, database.CtEventUserID, userID,
+		database.CtEventAdditionalInfo, database.CreateAdditionalInfo(additionalInfo...))
+
 	return nil
 }
 
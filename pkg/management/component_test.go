@@ -2,22 +2,27 @@ package management
 
 import (
 	"context"
+	"database/sql"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"net/http"
 	"strconv"
+	"strings"
 	"testing"
 	"time"
 
 	cs "github.com/cloudtrust/common-service"
 	"github.com/cloudtrust/common-service/configuration"
 	"github.com/cloudtrust/common-service/database"
+	"github.com/cloudtrust/common-service/database/sqltypes"
 	commonhttp "github.com/cloudtrust/common-service/errors"
 	errorhandler "github.com/cloudtrust/common-service/errors"
 	"github.com/cloudtrust/common-service/log"
 	api "github.com/cloudtrust/keycloak-bridge/api/management"
 	"github.com/cloudtrust/keycloak-bridge/internal/constants"
 	"github.com/cloudtrust/keycloak-bridge/internal/dto"
+	"github.com/cloudtrust/keycloak-bridge/internal/keycloakb"
 	"github.com/cloudtrust/keycloak-client"
 
 	"github.com/cloudtrust/keycloak-bridge/pkg/management/mock"
@@ -36,7 +41,7 @@ func TestGetActions(t *testing.T) {
 	var mockLogger = log.NewNopLogger()
 	var allowedTrustIDGroups = []string{"grp1", "grp2"}
 
-	var managementComponent = NewComponent(mockKeycloakClient, mockUsersDetailsDBModule, mockEventDBModule, mockConfigurationDBModule, allowedTrustIDGroups, mockLogger)
+	var managementComponent = NewComponent(mockKeycloakClient, mockUsersDetailsDBModule, mockEventDBModule, mockConfigurationDBModule, nil, allowedTrustIDGroups, nil, mockLogger)
 
 	var accessToken = "TOKEN=="
 	var ctx = context.WithValue(context.Background(), cs.CtContextAccessToken, accessToken)
@@ -57,7 +62,7 @@ func TestGetRealms(t *testing.T) {
 	var mockLogger = log.NewNopLogger()
 	var allowedTrustIDGroups = []string{"grp1", "grp2"}
 
-	var managementComponent = NewComponent(mockKeycloakClient, mockUsersDetailsDBModule, mockEventDBModule, mockConfigurationDBModule, allowedTrustIDGroups, mockLogger)
+	var managementComponent = NewComponent(mockKeycloakClient, mockUsersDetailsDBModule, mockEventDBModule, mockConfigurationDBModule, nil, allowedTrustIDGroups, nil, mockLogger)
 
 	var accessToken = "TOKEN=="
 
@@ -123,7 +128,7 @@ func TestGetRealm(t *testing.T) {
 	var mockLogger = log.NewNopLogger()
 	var allowedTrustIDGroups = []string{"grp1", "grp2"}
 
-	var managementComponent = NewComponent(mockKeycloakClient, mockUsersDetailsDBModule, mockEventDBModule, mockConfigurationDBModule, allowedTrustIDGroups, mockLogger)
+	var managementComponent = NewComponent(mockKeycloakClient, mockUsersDetailsDBModule, mockEventDBModule, mockConfigurationDBModule, nil, allowedTrustIDGroups, nil, mockLogger)
 
 	var accessToken = "TOKEN=="
 	var realmName = "master"
@@ -190,7 +195,7 @@ func TestGetClient(t *testing.T) {
 	var mockLogger = log.NewNopLogger()
 	var allowedTrustIDGroups = []string{"grp1", "grp2"}
 
-	var managementComponent = NewComponent(mockKeycloakClient, mockUsersDetailsDBModule, mockEventDBModule, mockConfigurationDBModule, allowedTrustIDGroups, mockLogger)
+	var managementComponent = NewComponent(mockKeycloakClient, mockUsersDetailsDBModule, mockEventDBModule, mockConfigurationDBModule, nil, allowedTrustIDGroups, nil, mockLogger)
 
 	var accessToken = "TOKEN=="
 	var realmName = "master"
@@ -260,7 +265,7 @@ func TestGetClients(t *testing.T) {
 	var mockLogger = log.NewNopLogger()
 	var allowedTrustIDGroups = []string{"grp1", "grp2"}
 
-	var managementComponent = NewComponent(mockKeycloakClient, mockUsersDetailsDBModule, mockEventDBModule, mockConfigurationDBModule, allowedTrustIDGroups, mockLogger)
+	var managementComponent = NewComponent(mockKeycloakClient, mockUsersDetailsDBModule, mockEventDBModule, mockConfigurationDBModule, nil, allowedTrustIDGroups, nil, mockLogger)
 
 	var accessToken = "TOKEN=="
 	var realmName = "master"
@@ -330,7 +335,7 @@ func TestGetRequiredActions(t *testing.T) {
 	var mockLogger = log.NewNopLogger()
 	var allowedTrustIDGroups = []string{"grp1", "grp2"}
 
-	var managementComponent = NewComponent(mockKeycloakClient, mockUsersDetailsDBModule, mockEventDBModule, mockConfigurationDBModule, allowedTrustIDGroups, mockLogger)
+	var managementComponent = NewComponent(mockKeycloakClient, mockUsersDetailsDBModule, mockEventDBModule, mockConfigurationDBModule, nil, allowedTrustIDGroups, nil, mockLogger)
 
 	var accessToken = "TOKEN=="
 	var realmName = "master"
@@ -397,10 +402,11 @@ func TestCreateUser(t *testing.T) {
 	var mockUsersDetailsDBModule = mock.NewUsersDetailsDBModule(mockCtrl)
 	var mockEventDBModule = mock.NewEventDBModule(mockCtrl)
 	var mockConfigurationDBModule = mock.NewConfigurationDBModule(mockCtrl)
+	var mockSagaModule = mock.NewSagaModule(mockCtrl)
 	var mockLogger = mock.NewLogger(mockCtrl)
 	var allowedTrustIDGroups = []string{"grp1", "grp2"}
 
-	var managementComponent = NewComponent(mockKeycloakClient, mockUsersDetailsDBModule, mockEventDBModule, mockConfigurationDBModule, allowedTrustIDGroups, mockLogger)
+	var managementComponent = NewComponent(mockKeycloakClient, mockUsersDetailsDBModule, mockEventDBModule, mockConfigurationDBModule, mockSagaModule, allowedTrustIDGroups, nil, mockLogger)
 
 	var accessToken = "TOKEN=="
 	var username = "test"
@@ -502,6 +508,14 @@ func TestCreateUser(t *testing.T) {
 		ctx = context.WithValue(ctx, cs.CtContextRealm, realmName)
 		ctx = context.WithValue(ctx, cs.CtContextUsername, username)
 
+		var saga = keycloakb.SagaOperation{ID: "saga-id", Kind: keycloakb.SagaCreateUser, Realm: targetRealmName, Username: username}
+		var sagaWithUserID = saga
+		sagaWithUserID.UserID = userID
+		gomock.InOrder(
+			mockSagaModule.EXPECT().Begin(ctx, keycloakb.SagaOperation{Kind: keycloakb.SagaCreateUser, Realm: targetRealmName, Username: username}).Return(saga, nil).Times(1),
+			mockSagaModule.EXPECT().SetUserID(ctx, saga, userID).Return(sagaWithUserID, nil).Times(1),
+			mockSagaModule.EXPECT().Complete(ctx, sagaWithUserID).Return(nil).Times(1),
+		)
 		mockUsersDetailsDBModule.EXPECT().StoreOrUpdateUserDetails(ctx, targetRealmName, gomock.Any()).DoAndReturn(
 			func(ctx context.Context, targetRealmName string, user dto.DBUser) {
 				assert.Equal(t, userID, *user.UserID)
@@ -552,6 +566,7 @@ func TestCreateUser(t *testing.T) {
 
 		var userRep = api.UserRepresentation{}
 		mockLogger.EXPECT().Warn(ctx, "err", "Invalid input")
+		mockSagaModule.EXPECT().Complete(ctx, keycloakb.SagaOperation{}).Return(nil).Times(1)
 
 		location, err := managementComponent.CreateUser(ctx, targetRealmName, userRep)
 
@@ -568,7 +583,11 @@ func TestCreateUser(t *testing.T) {
 		var ctx = context.WithValue(context.Background(), cs.CtContextAccessToken, accessToken)
 		ctx = context.WithValue(ctx, cs.CtContextRealm, realmName)
 
+		var saga = keycloakb.SagaOperation{ID: "saga-id", Kind: keycloakb.SagaCreateUser, Realm: targetRealmName, UserID: userID}
+		mockSagaModule.EXPECT().Begin(ctx, gomock.Any()).Return(saga, nil).Times(1)
+		mockSagaModule.EXPECT().SetUserID(ctx, saga, userID).Return(saga, nil).Times(1)
 		mockUsersDetailsDBModule.EXPECT().StoreOrUpdateUserDetails(ctx, targetRealmName, gomock.Any()).Return(fmt.Errorf("SQL error")).Times(1)
+		mockSagaModule.EXPECT().Compensate(ctx, accessToken, saga).Return(nil).Times(1)
 
 		var birthLocation = "Rolle"
 		var userRep = api.UserRepresentation{
@@ -583,6 +602,41 @@ func TestCreateUser(t *testing.T) {
 		assert.NotNil(t, err)
 		assert.Equal(t, "", location)
 	})
+
+	t.Run("Can't record pending operation: user is not created", func(t *testing.T) {
+		var ctx = context.WithValue(context.Background(), cs.CtContextAccessToken, accessToken)
+		ctx = context.WithValue(ctx, cs.CtContextRealm, realmName)
+
+		var saga = keycloakb.SagaOperation{Kind: keycloakb.SagaCreateUser, Realm: targetRealmName, Username: username}
+		mockSagaModule.EXPECT().Begin(ctx, saga).Return(saga, fmt.Errorf("SQL error")).Times(1)
+
+		var birthLocation = "Rolle"
+		location, err := managementComponent.CreateUser(ctx, targetRealmName, api.UserRepresentation{Username: &username, BirthLocation: &birthLocation})
+
+		assert.NotNil(t, err)
+		assert.Equal(t, "", location)
+	})
+
+	t.Run("Can't record the ID of the created user", func(t *testing.T) {
+		mockKeycloakClient.EXPECT().CreateUser(accessToken, realmName, targetRealmName, gomock.Any()).Return(locationURL, nil).Times(1)
+
+		var ctx = context.WithValue(context.Background(), cs.CtContextAccessToken, accessToken)
+		ctx = context.WithValue(ctx, cs.CtContextRealm, realmName)
+
+		var saga = keycloakb.SagaOperation{ID: "saga-id", Kind: keycloakb.SagaCreateUser, Realm: targetRealmName, Username: username}
+		var sagaWithUserID = saga
+		sagaWithUserID.UserID = userID
+		mockSagaModule.EXPECT().Begin(ctx, gomock.Any()).Return(saga, nil).Times(1)
+		mockSagaModule.EXPECT().SetUserID(ctx, saga, userID).Return(sagaWithUserID, fmt.Errorf("SQL error")).Times(1)
+		mockSagaModule.EXPECT().Compensate(ctx, accessToken, sagaWithUserID).Return(nil).Times(1)
+		mockLogger.EXPECT().Warn(ctx, "msg", "Can't store user details in database", "err", "SQL error")
+
+		var birthLocation = "Rolle"
+		location, err := managementComponent.CreateUser(ctx, targetRealmName, api.UserRepresentation{Username: &username, BirthLocation: &birthLocation})
+
+		assert.NotNil(t, err)
+		assert.Equal(t, "", location)
+	})
 }
 
 func TestDeleteUser(t *testing.T) {
@@ -592,15 +646,17 @@ func TestDeleteUser(t *testing.T) {
 	var mockUsersDetailsDBModule = mock.NewUsersDetailsDBModule(mockCtrl)
 	var mockEventDBModule = mock.NewEventDBModule(mockCtrl)
 	var mockConfigurationDBModule = mock.NewConfigurationDBModule(mockCtrl)
+	var mockSagaModule = mock.NewSagaModule(mockCtrl)
 	var mockLogger = mock.NewLogger(mockCtrl)
 	var allowedTrustIDGroups = []string{"grp1", "grp2"}
 
-	var managementComponent = NewComponent(mockKeycloakClient, mockUsersDetailsDBModule, mockEventDBModule, mockConfigurationDBModule, allowedTrustIDGroups, mockLogger)
+	var managementComponent = NewComponent(mockKeycloakClient, mockUsersDetailsDBModule, mockEventDBModule, mockConfigurationDBModule, mockSagaModule, allowedTrustIDGroups, nil, mockLogger)
 
 	var accessToken = "TOKEN=="
 	var userID = "41dbf4a8-32a9-4000-8c17-edc854c31231"
 	var realmName = "master"
 	var username = "username"
+	var saga = keycloakb.SagaOperation{ID: "saga-id", Kind: keycloakb.SagaDeleteUser, Realm: realmName, UserID: userID}
 
 	t.Run("Delete user with success", func(t *testing.T) {
 		mockKeycloakClient.EXPECT().DeleteUser(accessToken, realmName, userID).Return(nil).Times(1)
@@ -610,6 +666,8 @@ func TestDeleteUser(t *testing.T) {
 		ctx = context.WithValue(ctx, cs.CtContextUsername, username)
 
 		mockUsersDetailsDBModule.EXPECT().DeleteUserDetails(ctx, realmName, userID).Return(nil).Times(1)
+		mockSagaModule.EXPECT().Begin(ctx, gomock.Any()).Return(saga, nil).Times(1)
+		mockSagaModule.EXPECT().Complete(ctx, saga).Return(nil).Times(1)
 
 		mockEventDBModule.EXPECT().ReportEvent(ctx, "API_ACCOUNT_DELETION", "back-office", gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).Times(1)
 
@@ -626,6 +684,8 @@ func TestDeleteUser(t *testing.T) {
 		ctx = context.WithValue(ctx, cs.CtContextUsername, username)
 
 		mockUsersDetailsDBModule.EXPECT().DeleteUserDetails(ctx, realmName, userID).Return(nil).Times(1)
+		mockSagaModule.EXPECT().Begin(ctx, gomock.Any()).Return(saga, nil).Times(1)
+		mockSagaModule.EXPECT().Complete(ctx, saga).Return(nil).Times(1)
 
 		mockEventDBModule.EXPECT().ReportEvent(ctx, "API_ACCOUNT_DELETION", "back-office", database.CtEventRealmName, realmName, database.CtEventUserID, userID).Return(errors.New("error")).Times(1)
 		m := map[string]interface{}{"event_name": "API_ACCOUNT_DELETION", database.CtEventRealmName: realmName, database.CtEventUserID: userID}
@@ -640,6 +700,8 @@ func TestDeleteUser(t *testing.T) {
 		mockKeycloakClient.EXPECT().DeleteUser(accessToken, realmName, userID).Return(fmt.Errorf("Invalid input")).Times(1)
 
 		var ctx = context.WithValue(context.Background(), cs.CtContextAccessToken, accessToken)
+		mockSagaModule.EXPECT().Begin(ctx, gomock.Any()).Return(saga, nil).Times(1)
+		mockSagaModule.EXPECT().Complete(ctx, saga).Return(nil).Times(1)
 		mockLogger.EXPECT().Warn(ctx, "err", "Invalid input")
 
 		err := managementComponent.DeleteUser(ctx, "master", userID)
@@ -651,6 +713,7 @@ func TestDeleteUser(t *testing.T) {
 		mockKeycloakClient.EXPECT().DeleteUser(accessToken, realmName, userID).Return(nil).Times(1)
 
 		var ctx = context.WithValue(context.Background(), cs.CtContextAccessToken, accessToken)
+		mockSagaModule.EXPECT().Begin(ctx, keycloakb.SagaOperation{Kind: keycloakb.SagaDeleteUser, Realm: realmName, UserID: userID}).Return(saga, nil).Times(1)
 		mockUsersDetailsDBModule.EXPECT().DeleteUserDetails(ctx, realmName, userID).Return(fmt.Errorf("SQL Error")).Times(1)
 
 		mockLogger.EXPECT().Warn(ctx, "err", "SQL Error")
@@ -659,6 +722,15 @@ func TestDeleteUser(t *testing.T) {
 
 		assert.NotNil(t, err)
 	})
+
+	t.Run("Can't record pending operation", func(t *testing.T) {
+		var ctx = context.WithValue(context.Background(), cs.CtContextAccessToken, accessToken)
+		mockSagaModule.EXPECT().Begin(ctx, gomock.Any()).Return(keycloakb.SagaOperation{}, fmt.Errorf("SQL Error")).Times(1)
+
+		err := managementComponent.DeleteUser(ctx, "master", userID)
+
+		assert.NotNil(t, err)
+	})
 }
 
 func TestGetUser(t *testing.T) {
@@ -671,7 +743,7 @@ func TestGetUser(t *testing.T) {
 	var mockLogger = mock.NewLogger(mockCtrl)
 	var allowedTrustIDGroups = []string{"grp1", "grp2"}
 
-	var managementComponent = NewComponent(mockKeycloakClient, mockUsersDetailsDBModule, mockEventDBModule, mockConfigurationDBModule, allowedTrustIDGroups, mockLogger)
+	var managementComponent = NewComponent(mockKeycloakClient, mockUsersDetailsDBModule, mockEventDBModule, mockConfigurationDBModule, nil, allowedTrustIDGroups, nil, mockLogger)
 
 	var accessToken = "TOKEN=="
 	var realmName = "master"
@@ -734,9 +806,10 @@ func TestGetUser(t *testing.T) {
 
 		mockEventDBModule.EXPECT().ReportEvent(ctx, "GET_DETAILS", "back-office", gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).Times(1)
 
-		apiUserRep, err := managementComponent.GetUser(ctx, "master", id)
+		apiUserRep, etag, err := managementComponent.GetUser(ctx, "master", id)
 
 		assert.Nil(t, err)
+		assert.NotEqual(t, "", etag)
 		assert.Equal(t, username, *apiUserRep.Username)
 		assert.Equal(t, email, *apiUserRep.Email)
 		assert.Equal(t, enabled, *apiUserRep.Enabled)
@@ -805,7 +878,7 @@ func TestGetUser(t *testing.T) {
 
 		mockEventDBModule.EXPECT().ReportEvent(ctx, "GET_DETAILS", "back-office", gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).Times(1)
 
-		apiUserRep, err := managementComponent.GetUser(ctx, "master", id)
+		apiUserRep, _, err := managementComponent.GetUser(ctx, "master", id)
 
 		assert.Nil(t, err)
 		assert.Equal(t, username, *apiUserRep.Username)
@@ -856,7 +929,7 @@ func TestGetUser(t *testing.T) {
 		eventJSON, _ := json.Marshal(m)
 		mockLogger.EXPECT().Error(ctx, "err", "error", "event", string(eventJSON))
 
-		apiUserRep, err := managementComponent.GetUser(ctx, "master", id)
+		apiUserRep, _, err := managementComponent.GetUser(ctx, "master", id)
 		assert.Nil(t, err)
 		assert.Equal(t, username, *apiUserRep.Username)
 	})
@@ -873,7 +946,7 @@ func TestGetUser(t *testing.T) {
 		mockUsersDetailsDBModule.EXPECT().GetUserDetails(ctx, realmName, id).Return(dto.DBUser{}, fmt.Errorf("SQL Error")).Times(1)
 		mockLogger.EXPECT().Warn(ctx, "err", "SQL Error")
 
-		_, err := managementComponent.GetUser(ctx, "master", id)
+		_, _, err := managementComponent.GetUser(ctx, "master", id)
 
 		assert.NotNil(t, err)
 	})
@@ -885,7 +958,7 @@ func TestGetUser(t *testing.T) {
 		var ctx = context.WithValue(context.Background(), cs.CtContextAccessToken, accessToken)
 		mockLogger.EXPECT().Warn(ctx, "err", "Unexpected error")
 
-		_, err := managementComponent.GetUser(ctx, "master", id)
+		_, _, err := managementComponent.GetUser(ctx, "master", id)
 
 		assert.NotNil(t, err)
 	})
@@ -898,10 +971,11 @@ func TestUpdateUser(t *testing.T) {
 	var mockUsersDetailsDBModule = mock.NewUsersDetailsDBModule(mockCtrl)
 	var mockEventDBModule = mock.NewEventDBModule(mockCtrl)
 	var mockConfigurationDBModule = mock.NewConfigurationDBModule(mockCtrl)
+	var mockSagaModule = mock.NewSagaModule(mockCtrl)
 	var mockLogger = mock.NewLogger(mockCtrl)
 	var allowedTrustIDGroups = []string{"grp1", "grp2"}
 
-	var managementComponent = NewComponent(mockKeycloakClient, mockUsersDetailsDBModule, mockEventDBModule, mockConfigurationDBModule, allowedTrustIDGroups, mockLogger)
+	var managementComponent = NewComponent(mockKeycloakClient, mockUsersDetailsDBModule, mockEventDBModule, mockConfigurationDBModule, mockSagaModule, allowedTrustIDGroups, nil, mockLogger)
 
 	var accessToken = "TOKEN=="
 	var realmName = "master"
@@ -992,8 +1066,34 @@ func TestUpdateUser(t *testing.T) {
 				return nil
 			}).Times(1)
 
-		err := managementComponent.UpdateUser(ctx, "master", id, userRep)
+		err := managementComponent.UpdateUser(ctx, "master", id, userRep, "")
+
+		assert.Nil(t, err)
+	})
+
+	t.Run("Update of the identity of an accredited user", func(t *testing.T) {
+		var accreditedAttributes = make(kc.Attributes)
+		accreditedAttributes.Merge(&attributes)
+		accreditedAttributes.Set(constants.AttrbAccreditations, []string{`{"type":"SHADOW","expiryDate":"01.01.2100"}`})
+		var accreditedUser = kcUserRep
+		accreditedUser.Attributes = &accreditedAttributes
+
+		var newFirstName = "Toto"
+		var userAPI = userRep
+		userAPI.FirstName = &newFirstName
+
+		mockKeycloakClient.EXPECT().GetUser(accessToken, realmName, id).Return(accreditedUser, nil)
+		mockUsersDetailsDBModule.EXPECT().GetUserDetails(ctx, realmName, id).Return(dbUserRep, nil)
+		mockKeycloakClient.EXPECT().UpdateUser(accessToken, realmName, id, gomock.Any()).DoAndReturn(
+			func(accessToken, realmName, id string, kcUserRep kc.UserRepresentation) error {
+				assert.Contains(t, kcUserRep.GetAttribute(constants.AttrbAccreditations)[0], `"revoked":true`)
+				return nil
+			})
+		mockEventDBModule.EXPECT().ReportEvent(ctx, keycloakb.CtEventTypeAccreditationsRevoked, "back-office", database.CtEventRealmName, realmName,
+			database.CtEventUserID, id).Return(nil)
+		mockUsersDetailsDBModule.EXPECT().StoreUserChange(ctx, realmName, id, gomock.Any()).Return(nil)
 
+		err := managementComponent.UpdateUser(ctx, realmName, id, userAPI, "")
 		assert.Nil(t, err)
 	})
 
@@ -1044,8 +1144,27 @@ func TestUpdateUser(t *testing.T) {
 				assert.Equal(t, newIDDocumentExpiration, *user.IDDocumentExpiration)
 				return nil
 			}).Times(1)
+		mockUsersDetailsDBModule.EXPECT().StoreUserChange(ctx, realmName, id, gomock.Any()).DoAndReturn(
+			func(ctx context.Context, realm string, userID string, change dto.DBUserChange) error {
+				assert.Equal(t, username, change.Actor)
+				assert.Equal(t, keycloakb.HistoryChannelManagement, change.Channel)
+				assert.Equal(t, []dto.DBFieldChange{{Field: "idDocumentExpiration", OldValue: &idDocumentExpiration, NewValue: &newIDDocumentExpiration}}, change.Changes)
+				return nil
+			}).Times(1)
 
-		err := managementComponent.UpdateUser(ctx, realmName, id, userAPI)
+		var saga = keycloakb.SagaOperation{ID: "saga-id", Kind: keycloakb.SagaUpdateUser, Realm: realmName, UserID: id}
+		mockSagaModule.EXPECT().Begin(ctx, gomock.Any()).DoAndReturn(
+			func(ctx context.Context, op keycloakb.SagaOperation) (keycloakb.SagaOperation, error) {
+				assert.Equal(t, keycloakb.SagaUpdateUser, op.Kind)
+				assert.Equal(t, kcUserRep, *op.PreviousUser)
+				assert.Equal(t, dbUserRep, *op.PreviousDetails)
+				assert.NotNil(t, op.TargetUser)
+				assert.NotNil(t, op.TargetDetails)
+				return saga, nil
+			}).Times(2)
+		mockSagaModule.EXPECT().Complete(ctx, saga).Return(nil).Times(2)
+
+		err := managementComponent.UpdateUser(ctx, realmName, id, userAPI, "")
 		assert.Nil(t, err)
 
 		newBirthLocation := "21.12.1988"
@@ -1064,8 +1183,13 @@ func TestUpdateUser(t *testing.T) {
 				assert.Equal(t, newIDDocumentExpiration, *user.IDDocumentExpiration)
 				return nil
 			}).Times(1)
+		mockUsersDetailsDBModule.EXPECT().StoreUserChange(ctx, realmName, id, gomock.Any()).DoAndReturn(
+			func(ctx context.Context, realm string, userID string, change dto.DBUserChange) error {
+				assert.Len(t, change.Changes, 4)
+				return nil
+			}).Times(1)
 
-		err = managementComponent.UpdateUser(ctx, realmName, id, userAPI)
+		err = managementComponent.UpdateUser(ctx, realmName, id, userAPI, "")
 		assert.Nil(t, err)
 	})
 
@@ -1109,7 +1233,7 @@ func TestUpdateUser(t *testing.T) {
 
 		mockEventDBModule.EXPECT().ReportEvent(ctx, "LOCK_ACCOUNT", "back-office", gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).Times(1)
 
-		err := managementComponent.UpdateUser(ctx, "master", id, userRepLocked)
+		err := managementComponent.UpdateUser(ctx, "master", id, userRepLocked, "")
 
 		assert.Nil(t, err)
 	})
@@ -1138,7 +1262,7 @@ func TestUpdateUser(t *testing.T) {
 
 		mockEventDBModule.EXPECT().ReportEvent(ctx, "UNLOCK_ACCOUNT", "back-office", gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).Times(1)
 
-		err := managementComponent.UpdateUser(ctx, "master", id, userRepLocked)
+		err := managementComponent.UpdateUser(ctx, "master", id, userRepLocked, "")
 
 		assert.Nil(t, err)
 	})
@@ -1158,8 +1282,9 @@ func TestUpdateUser(t *testing.T) {
 				assert.Equal(t, false, *kcUserRep.EmailVerified)
 				return nil
 			}).Times(1)
+		mockUsersDetailsDBModule.EXPECT().StoreUserChange(ctx, realmName, id, gomock.Any()).Return(nil).Times(1)
 
-		err := managementComponent.UpdateUser(ctx, "master", id, userRep)
+		err := managementComponent.UpdateUser(ctx, "master", id, userRep, "")
 
 		assert.Nil(t, err)
 	})
@@ -1182,8 +1307,9 @@ func TestUpdateUser(t *testing.T) {
 				assert.Equal(t, false, *verified)
 				return nil
 			}).Times(1)
+		mockUsersDetailsDBModule.EXPECT().StoreUserChange(ctx, realmName, id, gomock.Any()).Return(nil).Times(1)
 
-		err := managementComponent.UpdateUser(ctx, "master", id, userRep)
+		err := managementComponent.UpdateUser(ctx, "master", id, userRep, "")
 
 		assert.Nil(t, err)
 	})
@@ -1214,8 +1340,9 @@ func TestUpdateUser(t *testing.T) {
 				assert.Equal(t, true, *verified)
 				return nil
 			}).Times(1)
+		mockUsersDetailsDBModule.EXPECT().StoreUserChange(ctx, realmName, id, gomock.Any()).Return(nil).Times(1)
 
-		err := managementComponent.UpdateUser(ctx, "master", id, userRepWithoutAttr)
+		err := managementComponent.UpdateUser(ctx, "master", id, userRepWithoutAttr, "")
 
 		assert.Nil(t, err)
 	})
@@ -1245,7 +1372,32 @@ func TestUpdateUser(t *testing.T) {
 		mockUsersDetailsDBModule.EXPECT().GetUserDetails(ctx, realmName, id).Return(dbUserRep, nil).Times(1)
 		mockKeycloakClient.EXPECT().UpdateUser(accessToken, realmName, id, gomock.Any()).Return(nil).Times(1)
 
-		err := managementComponent.UpdateUser(ctx, "master", id, userRep)
+		err := managementComponent.UpdateUser(ctx, "master", id, userRep, "")
+
+		assert.Nil(t, err)
+	})
+
+	t.Run("Update user with succces but with error when storing the change in the history", func(t *testing.T) {
+		var oldEmail = "toti@elca.ch"
+		var oldkcUserRep = kc.UserRepresentation{
+			ID:       &id,
+			Username: &username,
+			Email:    &oldEmail,
+		}
+		var userRep = api.UserRepresentation{
+			Email: &email,
+		}
+		mockKeycloakClient.EXPECT().GetUser(accessToken, realmName, id).Return(oldkcUserRep, nil).Times(1)
+		mockUsersDetailsDBModule.EXPECT().GetUserDetails(ctx, realmName, id).Return(dbUserRep, nil).Times(1)
+		mockKeycloakClient.EXPECT().UpdateUser(accessToken, realmName, id, gomock.Any()).Return(nil).Times(1)
+		mockUsersDetailsDBModule.EXPECT().StoreUserChange(ctx, realmName, id, gomock.Any()).DoAndReturn(
+			func(ctx context.Context, realm string, userID string, change dto.DBUserChange) error {
+				assert.Equal(t, []dto.DBFieldChange{{Field: "email", OldValue: &oldEmail, NewValue: &email}}, change.Changes)
+				return errors.New("SQL error")
+			}).Times(1)
+		mockLogger.EXPECT().Warn(ctx, "msg", "Can't store the change in the history of the user", "err", "SQL error")
+
+		err := managementComponent.UpdateUser(ctx, realmName, id, userRep, "")
 
 		assert.Nil(t, err)
 	})
@@ -1256,7 +1408,7 @@ func TestUpdateUser(t *testing.T) {
 
 		var ctx = context.WithValue(context.Background(), cs.CtContextAccessToken, accessToken)
 		mockLogger.EXPECT().Warn(ctx, "err", "Unexpected error")
-		err := managementComponent.UpdateUser(ctx, "master", id, api.UserRepresentation{})
+		err := managementComponent.UpdateUser(ctx, "master", id, api.UserRepresentation{}, "")
 
 		assert.NotNil(t, err)
 	})
@@ -1268,7 +1420,42 @@ func TestUpdateUser(t *testing.T) {
 		var ctx = context.WithValue(context.Background(), cs.CtContextAccessToken, accessToken)
 		mockUsersDetailsDBModule.EXPECT().GetUserDetails(ctx, realmName, id).Return(dto.DBUser{}, fmt.Errorf("SQL Error")).Times(1)
 
-		err := managementComponent.UpdateUser(ctx, "master", id, api.UserRepresentation{})
+		err := managementComponent.UpdateUser(ctx, "master", id, api.UserRepresentation{}, "")
+
+		assert.NotNil(t, err)
+	})
+
+	t.Run("Error - user modified since it was read", func(t *testing.T) {
+		var id = "1234-79894-7594"
+		var kcUserRep = kc.UserRepresentation{
+			ID: &id,
+		}
+		var ctx = context.WithValue(context.Background(), cs.CtContextAccessToken, accessToken)
+
+		mockKeycloakClient.EXPECT().GetUser(accessToken, realmName, id).Return(kcUserRep, nil).Times(1)
+		mockUsersDetailsDBModule.EXPECT().GetUserDetails(ctx, realmName, id).Return(dbUserRep, nil).Times(1)
+		mockLogger.EXPECT().Info(ctx, "msg", "User modified since it was read", "userID", id)
+
+		err := managementComponent.UpdateUser(ctx, "master", id, api.UserRepresentation{}, `"outdated-etag"`)
+
+		assert.IsType(t, errorhandler.Error{}, err)
+		assert.Equal(t, http.StatusPreconditionFailed, err.(errorhandler.Error).Status)
+	})
+
+	t.Run("If-Match matches the current version of the user", func(t *testing.T) {
+		var id = "1234-79894-7594"
+		var kcUserRep = kc.UserRepresentation{
+			ID: &id,
+		}
+		var etag, _ = keycloakb.ComputeETag(kcUserRep, dbUserRep)
+		var ctx = context.WithValue(context.Background(), cs.CtContextAccessToken, accessToken)
+
+		mockKeycloakClient.EXPECT().GetUser(accessToken, realmName, id).Return(kcUserRep, nil).Times(1)
+		mockUsersDetailsDBModule.EXPECT().GetUserDetails(ctx, realmName, id).Return(dbUserRep, nil).Times(1)
+		mockKeycloakClient.EXPECT().UpdateUser(accessToken, realmName, id, gomock.Any()).Return(fmt.Errorf("Unexpected error")).Times(1)
+		mockLogger.EXPECT().Warn(gomock.Any(), "err", "Unexpected error")
+
+		err := managementComponent.UpdateUser(ctx, "master", id, api.UserRepresentation{}, etag)
 
 		assert.NotNil(t, err)
 	})
@@ -1285,7 +1472,7 @@ func TestUpdateUser(t *testing.T) {
 		mockKeycloakClient.EXPECT().UpdateUser(accessToken, realmName, id, gomock.Any()).Return(fmt.Errorf("Unexpected error")).Times(1)
 		mockLogger.EXPECT().Warn(gomock.Any(), "err", "Unexpected error")
 
-		err := managementComponent.UpdateUser(ctx, "master", id, api.UserRepresentation{})
+		err := managementComponent.UpdateUser(ctx, "master", id, api.UserRepresentation{}, "")
 
 		assert.NotNil(t, err)
 	})
@@ -1302,16 +1489,65 @@ func TestUpdateUser(t *testing.T) {
 		mockKeycloakClient.EXPECT().UpdateUser(accessToken, realmName, id, gomock.Any()).Return(nil).Times(1)
 		mockUsersDetailsDBModule.EXPECT().StoreOrUpdateUserDetails(ctx, realmName, gomock.Any()).Return(fmt.Errorf("SQL error")).Times(1)
 		mockLogger.EXPECT().Warn(gomock.Any(), "msg", "Can't store user details in database", "err", "SQL error")
+		var saga = keycloakb.SagaOperation{ID: "saga-id", Kind: keycloakb.SagaUpdateUser, Realm: realmName, UserID: id}
+		mockSagaModule.EXPECT().Begin(ctx, gomock.Any()).Return(saga, nil).Times(1)
+		mockSagaModule.EXPECT().Compensate(ctx, accessToken, saga).Return(nil).Times(1)
 
 		var newIDDocumentType = "Visa"
 		err := managementComponent.UpdateUser(ctx, realmName, id, api.UserRepresentation{
 			IDDocumentExpiration: &newIDDocumentType,
-		})
+		}, "")
 
 		assert.NotNil(t, err)
 	})
 }
 
+func TestGetUserHistory(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+	var mockKeycloakClient = mock.NewKeycloakClient(mockCtrl)
+	var mockUsersDetailsDBModule = mock.NewUsersDetailsDBModule(mockCtrl)
+	var mockEventDBModule = mock.NewEventDBModule(mockCtrl)
+	var mockConfigurationDBModule = mock.NewConfigurationDBModule(mockCtrl)
+
+	var managementComponent = NewComponent(mockKeycloakClient, mockUsersDetailsDBModule, mockEventDBModule, mockConfigurationDBModule, nil, nil, nil, log.NewNopLogger())
+
+	var realmName = "master"
+	var userID = "41dbf4a8-32a9-4000-8c17-edc854c31231"
+	var oldValue, newValue = "Doe", "Smith"
+	var ctx = context.TODO()
+
+	t.Run("Get history from DB fails", func(t *testing.T) {
+		var dbError = errors.New("db error")
+		mockUsersDetailsDBModule.EXPECT().GetUserHistory(ctx, realmName, userID).Return(nil, dbError)
+
+		var _, err = managementComponent.GetUserHistory(ctx, realmName, userID)
+		assert.Equal(t, dbError, err)
+	})
+	t.Run("Empty history", func(t *testing.T) {
+		mockUsersDetailsDBModule.EXPECT().GetUserHistory(ctx, realmName, userID).Return([]dto.DBUserChange{}, nil)
+		mockEventDBModule.EXPECT().ReportEvent(ctx, "GET_USER_HISTORY", "back-office", database.CtEventRealmName, realmName, database.CtEventUserID, userID).Return(nil)
+
+		var res, err = managementComponent.GetUserHistory(ctx, realmName, userID)
+		assert.Nil(t, err)
+		assert.NotNil(t, res)
+		assert.Len(t, res, 0)
+	})
+	t.Run("Success", func(t *testing.T) {
+		var changes = []dto.DBUserChange{{ChangedAt: 1600000000, Actor: "operator", Channel: keycloakb.HistoryChannelKYC,
+			Changes: []dto.DBFieldChange{{Field: "lastName", OldValue: &oldValue, NewValue: &newValue}}}}
+		mockUsersDetailsDBModule.EXPECT().GetUserHistory(ctx, realmName, userID).Return(changes, nil)
+		mockEventDBModule.EXPECT().ReportEvent(ctx, "GET_USER_HISTORY", "back-office", database.CtEventRealmName, realmName, database.CtEventUserID, userID).Return(nil)
+
+		var res, err = managementComponent.GetUserHistory(ctx, realmName, userID)
+		assert.Nil(t, err)
+		assert.Len(t, res, 1)
+		assert.Equal(t, "operator", *res[0].Actor)
+		assert.Equal(t, keycloakb.HistoryChannelKYC, *res[0].Channel)
+		assert.Equal(t, newValue, *res[0].Changes[0].NewValue)
+	})
+}
+
 func TestLockUnlockUser(t *testing.T) {
 	var mockCtrl = gomock.NewController(t)
 	defer mockCtrl.Finish()
@@ -1319,7 +1555,7 @@ func TestLockUnlockUser(t *testing.T) {
 	var mockKeycloakClient = mock.NewKeycloakClient(mockCtrl)
 	var mockEventDBModule = mock.NewEventDBModule(mockCtrl)
 
-	var managementComponent = NewComponent(mockKeycloakClient, nil, mockEventDBModule, nil, nil, log.NewNopLogger())
+	var managementComponent = NewComponent(mockKeycloakClient, nil, mockEventDBModule, nil, nil, nil, nil, log.NewNopLogger())
 
 	var accessToken = "TOKEN=="
 	var realmName = "myrealm"
@@ -1372,7 +1608,7 @@ func TestGetUsers(t *testing.T) {
 	var mockLogger = log.NewNopLogger()
 	var allowedTrustIDGroups = []string{"grp1", "grp2"}
 
-	var managementComponent = NewComponent(mockKeycloakClient, mockUsersDetailsDBModule, mockEventDBModule, mockConfigurationDBModule, allowedTrustIDGroups, mockLogger)
+	var managementComponent = NewComponent(mockKeycloakClient, mockUsersDetailsDBModule, mockEventDBModule, mockConfigurationDBModule, nil, allowedTrustIDGroups, nil, mockLogger)
 
 	var accessToken = "TOKEN=="
 	var realmName = "master"
@@ -1465,7 +1701,7 @@ func TestGetUserAccountStatus(t *testing.T) {
 	var mockLogger = log.NewNopLogger()
 	var allowedTrustIDGroups = []string{"grp1", "grp2"}
 
-	var managementComponent = NewComponent(mockKeycloakClient, mockUsersDetailsDBModule, mockEventDBModule, mockConfigurationDBModule, allowedTrustIDGroups, mockLogger)
+	var managementComponent = NewComponent(mockKeycloakClient, mockUsersDetailsDBModule, mockEventDBModule, mockConfigurationDBModule, nil, allowedTrustIDGroups, nil, mockLogger)
 
 	var accessToken = "TOKEN=="
 	var realmReq = "master"
@@ -1546,7 +1782,7 @@ func TestGetClientRolesForUser(t *testing.T) {
 	var mockLogger = log.NewNopLogger()
 	var allowedTrustIDGroups = []string{"grp1", "grp2"}
 
-	var managementComponent = NewComponent(mockKeycloakClient, mockUsersDetailsDBModule, mockEventDBModule, mockConfigurationDBModule, allowedTrustIDGroups, mockLogger)
+	var managementComponent = NewComponent(mockKeycloakClient, mockUsersDetailsDBModule, mockEventDBModule, mockConfigurationDBModule, nil, allowedTrustIDGroups, nil, mockLogger)
 
 	var accessToken = "TOKEN=="
 	var realmName = "master"
@@ -1612,7 +1848,7 @@ func TestAddClientRolesToUser(t *testing.T) {
 	var mockLogger = log.NewNopLogger()
 	var allowedTrustIDGroups = []string{"grp1", "grp2"}
 
-	var managementComponent = NewComponent(mockKeycloakClient, mockUsersDetailsDBModule, mockEventDBModule, mockConfigurationDBModule, allowedTrustIDGroups, mockLogger)
+	var managementComponent = NewComponent(mockKeycloakClient, mockUsersDetailsDBModule, mockEventDBModule, mockConfigurationDBModule, nil, allowedTrustIDGroups, nil, mockLogger)
 
 	var accessToken = "TOKEN=="
 	var realmName = "master"
@@ -1653,6 +1889,9 @@ func TestAddClientRolesToUser(t *testing.T) {
 		var rolesRep []api.RoleRepresentation
 		rolesRep = append(rolesRep, roleRep)
 
+		mockEventDBModule.EXPECT().ReportEvent(ctx, "API_USER_CLIENT_ROLES_ADDITION", "back-office", database.CtEventRealmName, realmName, database.CtEventUserID, userID,
+			database.CtEventAdditionalInfo, gomock.Any()).Return(nil).Times(1)
+
 		err := managementComponent.AddClientRolesToUser(ctx, "master", userID, clientID, rolesRep)
 
 		assert.Nil(t, err)
@@ -1670,6 +1909,53 @@ func TestAddClientRolesToUser(t *testing.T) {
 	}
 }
 
+func TestDeleteClientRoleFromUser(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+	var mockKeycloakClient = mock.NewKeycloakClient(mockCtrl)
+	var mockEventDBModule = mock.NewEventDBModule(mockCtrl)
+
+	var managementComponent = NewComponent(mockKeycloakClient, nil, mockEventDBModule, nil, nil, nil, nil, log.NewNopLogger())
+
+	var accessToken = "TOKEN=="
+	var realmName = "master"
+	var userID = "789-789-456"
+	var clientID = "456-789-147"
+	var roleID = "1234-7454-4516"
+	var roleName = "role-name"
+	var clientRole = true
+	var otherClientID = "other-client"
+	var ctx = context.WithValue(context.Background(), cs.CtContextAccessToken, accessToken)
+
+	t.Run("Can't get role", func(t *testing.T) {
+		mockKeycloakClient.EXPECT().GetRole(accessToken, realmName, roleID).Return(kc.RoleRepresentation{}, errors.New("error"))
+		var err = managementComponent.DeleteClientRoleFromUser(ctx, realmName, userID, clientID, roleID)
+		assert.NotNil(t, err)
+	})
+	t.Run("Role of another client", func(t *testing.T) {
+		mockKeycloakClient.EXPECT().GetRole(accessToken, realmName, roleID).Return(kc.RoleRepresentation{ID: &roleID, ClientRole: &clientRole, ContainerID: &otherClientID}, nil)
+		var err = managementComponent.DeleteClientRoleFromUser(ctx, realmName, userID, clientID, roleID)
+		assert.NotNil(t, err)
+	})
+
+	var role = kc.RoleRepresentation{ID: &roleID, Name: &roleName, ClientRole: &clientRole, ContainerID: &clientID}
+
+	t.Run("Keycloak error", func(t *testing.T) {
+		mockKeycloakClient.EXPECT().GetRole(accessToken, realmName, roleID).Return(role, nil)
+		mockKeycloakClient.EXPECT().DeleteClientRolesFromUserRoleMapping(accessToken, realmName, userID, clientID, []kc.RoleRepresentation{role}).Return(errors.New("error"))
+		var err = managementComponent.DeleteClientRoleFromUser(ctx, realmName, userID, clientID, roleID)
+		assert.NotNil(t, err)
+	})
+	t.Run("Success", func(t *testing.T) {
+		mockKeycloakClient.EXPECT().GetRole(accessToken, realmName, roleID).Return(role, nil)
+		mockKeycloakClient.EXPECT().DeleteClientRolesFromUserRoleMapping(accessToken, realmName, userID, clientID, []kc.RoleRepresentation{role}).Return(nil)
+		mockEventDBModule.EXPECT().ReportEvent(ctx, "API_USER_CLIENT_ROLE_REMOVAL", "back-office", database.CtEventRealmName, realmName, database.CtEventUserID, userID,
+			database.CtEventAdditionalInfo, database.CreateAdditionalInfo("client_id", clientID, "role_ids", roleID, "role_names", roleName)).Return(nil)
+		var err = managementComponent.DeleteClientRoleFromUser(ctx, realmName, userID, clientID, roleID)
+		assert.Nil(t, err)
+	})
+}
+
 func TestGetRolesOfUser(t *testing.T) {
 	var mockCtrl = gomock.NewController(t)
 	defer mockCtrl.Finish()
@@ -1680,7 +1966,7 @@ func TestGetRolesOfUser(t *testing.T) {
 	var mockLogger = log.NewNopLogger()
 	var allowedTrustIDGroups = []string{"grp1", "grp2"}
 
-	var managementComponent = NewComponent(mockKeycloakClient, mockUsersDetailsDBModule, mockEventDBModule, mockConfigurationDBModule, allowedTrustIDGroups, mockLogger)
+	var managementComponent = NewComponent(mockKeycloakClient, mockUsersDetailsDBModule, mockEventDBModule, mockConfigurationDBModule, nil, allowedTrustIDGroups, nil, mockLogger)
 
 	var accessToken = "TOKEN=="
 	var realmName = "master"
@@ -1745,7 +2031,7 @@ func TestGetGroupsOfUser(t *testing.T) {
 	var mockLogger = log.NewNopLogger()
 	var allowedTrustIDGroups = []string{"grp1", "grp2"}
 
-	var managementComponent = NewComponent(mockKeycloakClient, mockUsersDetailsDBModule, mockEventDBModule, mockConfigurationDBModule, allowedTrustIDGroups, mockLogger)
+	var managementComponent = NewComponent(mockKeycloakClient, mockUsersDetailsDBModule, mockEventDBModule, mockConfigurationDBModule, nil, allowedTrustIDGroups, nil, mockLogger)
 
 	var accessToken = "TOKEN=="
 	var realmName = "master"
@@ -1805,7 +2091,7 @@ func TestSetGroupsToUser(t *testing.T) {
 	var groupID = "user-group-1"
 	var ctx = context.WithValue(context.Background(), cs.CtContextAccessToken, accessToken)
 
-	var managementComponent = NewComponent(mockKeycloakClient, mockUsersDetailsDBModule, mockEventDBModule, mockConfigurationDBModule, allowedTrustIDGroups, mockLogger)
+	var managementComponent = NewComponent(mockKeycloakClient, mockUsersDetailsDBModule, mockEventDBModule, mockConfigurationDBModule, nil, allowedTrustIDGroups, nil, mockLogger)
 
 	t.Run("AddGroupToUser: KC fails", func(t *testing.T) {
 		mockKeycloakClient.EXPECT().AddGroupToUser(accessToken, realmName, userID, groupID).Return(errors.New("kc error"))
@@ -1839,14 +2125,47 @@ func TestGetAvailableTrustIDGroups(t *testing.T) {
 	var mockConfigurationDBModule = mock.NewConfigurationDBModule(mockCtrl)
 	var mockLogger = log.NewNopLogger()
 
-	var allowedTrustIDGroups = []string{"grp1", "grp2"}
+	var allowedTrustIDGroups = []string{"grp2", "grp1"}
 	var realmName = "master"
+	var realmID = "1234-5678"
+	var accessToken = "TOKEN=="
+	var anyError = errors.New("error")
+	var ctx = context.WithValue(context.TODO(), cs.CtContextAccessToken, accessToken)
 
-	var component = NewComponent(mockKeycloakClient, mockUsersDetailsDBModule, mockEventDBModule, mockConfigurationDBModule, allowedTrustIDGroups, mockLogger)
+	var component = NewComponent(mockKeycloakClient, mockUsersDetailsDBModule, mockEventDBModule, mockConfigurationDBModule, nil, allowedTrustIDGroups, nil, mockLogger)
 
-	var res, err = component.GetAvailableTrustIDGroups(context.TODO(), realmName)
-	assert.Nil(t, err)
-	assert.Len(t, res, len(allowedTrustIDGroups))
+	t.Run("Keycloak fails", func(t *testing.T) {
+		mockKeycloakClient.EXPECT().GetRealm(accessToken, realmName).Return(kc.RealmRepresentation{}, anyError)
+		var _, err = component.GetAvailableTrustIDGroups(ctx, realmName)
+		assert.Equal(t, anyError, err)
+	})
+	t.Run("Configuration DB fails", func(t *testing.T) {
+		mockKeycloakClient.EXPECT().GetRealm(accessToken, realmName).Return(kc.RealmRepresentation{ID: &realmID}, nil)
+		mockConfigurationDBModule.EXPECT().GetTrustIDGroups(ctx, realmID).Return(nil, anyError)
+		var _, err = component.GetAvailableTrustIDGroups(ctx, realmName)
+		assert.Equal(t, anyError, err)
+	})
+	t.Run("Default groups", func(t *testing.T) {
+		mockKeycloakClient.EXPECT().GetRealm(accessToken, realmName).Return(kc.RealmRepresentation{ID: &realmID}, nil)
+		mockConfigurationDBModule.EXPECT().GetTrustIDGroups(ctx, realmID).Return(nil, nil)
+		var res, err = component.GetAvailableTrustIDGroups(ctx, realmName)
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"grp1", "grp2"}, res)
+	})
+	t.Run("Groups configured for the realm", func(t *testing.T) {
+		mockKeycloakClient.EXPECT().GetRealm(accessToken, realmName).Return(kc.RealmRepresentation{ID: &realmID}, nil)
+		mockConfigurationDBModule.EXPECT().GetTrustIDGroups(ctx, realmID).Return([]string{"grp3"}, nil)
+		var res, err = component.GetAvailableTrustIDGroups(ctx, realmName)
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"grp3"}, res)
+	})
+	t.Run("No group allowed in the realm", func(t *testing.T) {
+		mockKeycloakClient.EXPECT().GetRealm(accessToken, realmName).Return(kc.RealmRepresentation{ID: &realmID}, nil)
+		mockConfigurationDBModule.EXPECT().GetTrustIDGroups(ctx, realmID).Return([]string{}, nil)
+		var res, err = component.GetAvailableTrustIDGroups(ctx, realmName)
+		assert.Nil(t, err)
+		assert.Len(t, res, 0)
+	})
 }
 
 func TestGetTrustIDGroupsOfUser(t *testing.T) {
@@ -1867,7 +2186,7 @@ func TestGetTrustIDGroupsOfUser(t *testing.T) {
 	var attrbs = keycloak.Attributes{constants.AttrbTrustIDGroups: groups}
 	var ctx = context.WithValue(context.TODO(), cs.CtContextAccessToken, accessToken)
 
-	var component = NewComponent(mockKeycloakClient, mockUsersDetailsDBModule, mockEventDBModule, mockConfigurationDBModule, allowedTrustIDGroups, mockLogger)
+	var component = NewComponent(mockKeycloakClient, mockUsersDetailsDBModule, mockEventDBModule, mockConfigurationDBModule, nil, allowedTrustIDGroups, nil, mockLogger)
 
 	t.Run("Keycloak fails", func(t *testing.T) {
 		mockKeycloakClient.EXPECT().GetUser(accessToken, realmName, userID).Return(kc.UserRepresentation{}, errors.New("kc error"))
@@ -1884,8 +2203,22 @@ func TestGetTrustIDGroupsOfUser(t *testing.T) {
 		mockKeycloakClient.EXPECT().GetUser(accessToken, realmName, userID).Return(kc.UserRepresentation{Attributes: &attrbs}, nil)
 		var res, err = component.GetTrustIDGroupsOfUser(ctx, realmName, userID)
 		assert.Nil(t, err)
-		assert.Equal(t, "some", res[0])
-		assert.Equal(t, "groups", res[1]) // Without heading slash
+		assert.Equal(t, "some", *res[0].Name)
+		assert.Equal(t, "groups", *res[1].Name) // Without heading slash
+		assert.Nil(t, res[0].ExpiresAt)
+	})
+	t.Run("User has groups with expiry date", func(t *testing.T) {
+		var attrbs = keycloak.Attributes{
+			constants.AttrbTrustIDGroups:       groups,
+			constants.AttrbTrustIDGroupsExpiry: []string{`{"group":"groups","expiresAt":1900000000,"justification":"ticket","grantedBy":"admin"}`},
+		}
+		mockKeycloakClient.EXPECT().GetUser(accessToken, realmName, userID).Return(kc.UserRepresentation{Attributes: &attrbs}, nil)
+		var res, err = component.GetTrustIDGroupsOfUser(ctx, realmName, userID)
+		assert.Nil(t, err)
+		assert.Nil(t, res[0].ExpiresAt)
+		assert.Equal(t, int64(1900000000), *res[1].ExpiresAt)
+		assert.Equal(t, "ticket", *res[1].Justification)
+		assert.Equal(t, "admin", *res[1].GrantedBy)
 	})
 }
 
@@ -1899,19 +2232,28 @@ func TestSetTrustIDGroupsToUser(t *testing.T) {
 	var mockLogger = log.NewNopLogger()
 	var allowedTrustIDGroups = []string{"grp1", "grp2"}
 
-	var managementComponent = NewComponent(mockKeycloakClient, mockUsersDetailsDBModule, mockEventDBModule, mockConfigurationDBModule, allowedTrustIDGroups, mockLogger)
+	var managementComponent = NewComponent(mockKeycloakClient, mockUsersDetailsDBModule, mockEventDBModule, mockConfigurationDBModule, nil, allowedTrustIDGroups, nil, mockLogger)
 
 	var accessToken = "TOKEN=="
 
 	var username = "user"
+	var operator = "admin"
 	var realmName = "master"
 	var userID = "789-1234-5678"
+	var grp1, grp2, grp3 = "grp1", "grp2", "grp3"
+	var grpNames = []api.TrustIDGroupRepresentation{{Name: &grp1}, {Name: &grp2}}
+
+	var realmID = "1234-5678"
+	var ctx = context.WithValue(context.Background(), cs.CtContextAccessToken, accessToken)
+	ctx = context.WithValue(ctx, cs.CtContextUsername, operator)
+
+	mockKeycloakClient.EXPECT().GetRealm(accessToken, realmName).Return(kc.RealmRepresentation{ID: &realmID}, nil).AnyTimes()
+	mockConfigurationDBModule.EXPECT().GetTrustIDGroups(ctx, realmID).Return(nil, nil).AnyTimes()
 
 	t.Run("Set groups with success", func(t *testing.T) {
 		var kcUserRep = kc.UserRepresentation{
 			Username: &username,
 		}
-		grpNames := []string{"grp1", "grp2"}
 		extGrpNames := []string{"/grp1", "/grp2"}
 		attrs := make(kc.Attributes)
 		attrs.Set(constants.AttrbTrustIDGroups, extGrpNames)
@@ -1920,32 +2262,107 @@ func TestSetTrustIDGroupsToUser(t *testing.T) {
 			Attributes: &attrs,
 		}
 		mockKeycloakClient.EXPECT().GetUser(accessToken, realmName, userID).Return(kcUserRep, nil).Times(1)
+		mockUsersDetailsDBModule.EXPECT().UpdateTrustIDGroupsExpiries(ctx, realmName, userID, nil).Return(nil).Times(1)
 		mockKeycloakClient.EXPECT().UpdateUser(accessToken, realmName, userID, kcUserRep2).Return(nil).Times(1)
-
-		var ctx = context.WithValue(context.Background(), cs.CtContextAccessToken, accessToken)
+		mockEventDBModule.EXPECT().ReportEvent(ctx, "API_TRUSTID_GROUP_GRANT", "back-office", database.CtEventRealmName, realmName, database.CtEventUserID, userID, database.CtEventAdditionalInfo, gomock.Any()).Return(nil).Times(2)
 
 		err := managementComponent.SetTrustIDGroupsToUser(ctx, realmName, userID, grpNames)
 
 		assert.Nil(t, err)
 	})
 
+	t.Run("Set group with expiry date, keep unchanged group and remove missing group", func(t *testing.T) {
+		var expiresAt = time.Now().Add(time.Hour).Unix()
+		var justification = "support ticket #42"
+		attrs := make(kc.Attributes)
+		attrs.Set(constants.AttrbTrustIDGroups, []string{"/grp1", "/grp3"})
+		var kcUserRep = kc.UserRepresentation{
+			Username:   &username,
+			Attributes: &attrs,
+		}
+		var groups = []api.TrustIDGroupRepresentation{{Name: &grp1}, {Name: &grp2, ExpiresAt: &expiresAt, Justification: &justification}}
+
+		mockKeycloakClient.EXPECT().GetUser(accessToken, realmName, userID).Return(kcUserRep, nil).Times(1)
+		mockUsersDetailsDBModule.EXPECT().UpdateTrustIDGroupsExpiries(ctx, realmName, userID, []dto.DBTrustIDGroupExpiry{
+			{RealmID: realmName, UserID: userID, GroupName: grp2, ExpiresAt: expiresAt},
+		}).Return(nil).Times(1)
+		mockKeycloakClient.EXPECT().UpdateUser(accessToken, realmName, userID, gomock.Any()).DoAndReturn(
+			func(_, _, _ string, user kc.UserRepresentation) error {
+				var assignments = keycloakb.GetTrustIDGroupAssignments(user)
+				assert.Equal(t, []keycloakb.TrustIDGroupAssignment{
+					{Group: grp1},
+					{Group: grp2, ExpiresAt: &expiresAt, Justification: &justification, GrantedBy: &operator},
+				}, assignments)
+				return nil
+			}).Times(1)
+		mockEventDBModule.EXPECT().ReportEvent(ctx, "API_TRUSTID_GROUP_GRANT", "back-office", database.CtEventRealmName, realmName, database.CtEventUserID, userID, database.CtEventAdditionalInfo, gomock.Any()).Return(nil).Times(1)
+		mockEventDBModule.EXPECT().ReportEvent(ctx, "API_TRUSTID_GROUP_REMOVAL", "back-office", database.CtEventRealmName, realmName, database.CtEventUserID, userID, database.CtEventAdditionalInfo, gomock.Any()).Return(nil).Times(1)
+
+		err := managementComponent.SetTrustIDGroupsToUser(ctx, realmName, userID, groups)
+
+		assert.Nil(t, err)
+	})
+
 	t.Run("Try to set unknown group", func(t *testing.T) {
-		grpNames := []string{"grp1", "grp3"}
-		attrs := make(map[string][]string)
-		attrs["trustIDGroups"] = grpNames
+		err := managementComponent.SetTrustIDGroupsToUser(ctx, realmName, userID, []api.TrustIDGroupRepresentation{{Name: &grp1}, {Name: &grp3}})
 
-		var ctx = context.WithValue(context.Background(), cs.CtContextAccessToken, accessToken)
+		assert.NotNil(t, err)
+	})
 
-		err := managementComponent.SetTrustIDGroupsToUser(ctx, realmName, userID, grpNames)
+	t.Run("Try to set group which is not allowed in the realm", func(t *testing.T) {
+		var otherRealm = "other"
+		var otherRealmID = "8765-4321"
+		mockKeycloakClient.EXPECT().GetRealm(accessToken, otherRealm).Return(kc.RealmRepresentation{ID: &otherRealmID}, nil).Times(1)
+		mockConfigurationDBModule.EXPECT().GetTrustIDGroups(ctx, otherRealmID).Return([]string{grp3}, nil).Times(1)
+
+		err := managementComponent.SetTrustIDGroupsToUser(ctx, otherRealm, userID, []api.TrustIDGroupRepresentation{{Name: &grp1}})
+
+		assert.NotNil(t, err)
+	})
+
+	t.Run("Can't get groups allowed in the realm", func(t *testing.T) {
+		var otherRealm = "other"
+		mockKeycloakClient.EXPECT().GetRealm(accessToken, otherRealm).Return(kc.RealmRepresentation{}, fmt.Errorf("Unexpected error")).Times(1)
+
+		err := managementComponent.SetTrustIDGroupsToUser(ctx, otherRealm, userID, grpNames)
+
+		assert.NotNil(t, err)
+	})
+
+	t.Run("Try to set group without name", func(t *testing.T) {
+		err := managementComponent.SetTrustIDGroupsToUser(ctx, realmName, userID, []api.TrustIDGroupRepresentation{{}})
 
 		assert.NotNil(t, err)
 	})
 
+	t.Run("Try to set group with expiry date in the past", func(t *testing.T) {
+		var expiresAt = time.Now().Add(-time.Hour).Unix()
+
+		err := managementComponent.SetTrustIDGroupsToUser(ctx, realmName, userID, []api.TrustIDGroupRepresentation{{Name: &grp1, ExpiresAt: &expiresAt}})
+
+		assert.NotNil(t, err)
+	})
+
+	t.Run("Try to set group with a justification which does not fit in an attribute", func(t *testing.T) {
+		var expiresAt = time.Now().Add(time.Hour).Unix()
+		var justification = strings.Repeat("a", 255)
+
+		err := managementComponent.SetTrustIDGroupsToUser(ctx, realmName, userID, []api.TrustIDGroupRepresentation{{Name: &grp1, ExpiresAt: &expiresAt, Justification: &justification}})
+
+		assert.Equal(t, errorhandler.CreateBadRequestError(constants.MsgErrInvalidParam+"."+constants.Justification), err)
+	})
+
 	t.Run("Error while get user", func(t *testing.T) {
-		grpNames := []string{"grp1", "grp2"}
 		mockKeycloakClient.EXPECT().GetUser(accessToken, realmName, userID).Return(kc.UserRepresentation{}, fmt.Errorf("Unexpected error")).Times(1)
 
-		var ctx = context.WithValue(context.Background(), cs.CtContextAccessToken, accessToken)
+		err := managementComponent.SetTrustIDGroupsToUser(ctx, realmName, userID, grpNames)
+
+		assert.NotNil(t, err)
+	})
+
+	t.Run("Error while storing expiry dates", func(t *testing.T) {
+		mockKeycloakClient.EXPECT().GetUser(accessToken, realmName, userID).Return(kc.UserRepresentation{Username: &username}, nil).Times(1)
+		mockUsersDetailsDBModule.EXPECT().UpdateTrustIDGroupsExpiries(ctx, realmName, userID, nil).Return(fmt.Errorf("Unexpected error")).Times(1)
 
 		err := managementComponent.SetTrustIDGroupsToUser(ctx, realmName, userID, grpNames)
 
@@ -1956,7 +2373,6 @@ func TestSetTrustIDGroupsToUser(t *testing.T) {
 		var kcUserRep = kc.UserRepresentation{
 			Username: &username,
 		}
-		grpNames := []string{"grp1", "grp2"}
 		extGrpNames := []string{"/grp1", "/grp2"}
 		attrs := make(kc.Attributes)
 		attrs.Set(constants.AttrbTrustIDGroups, extGrpNames)
@@ -1965,10 +2381,9 @@ func TestSetTrustIDGroupsToUser(t *testing.T) {
 			Attributes: &attrs,
 		}
 		mockKeycloakClient.EXPECT().GetUser(accessToken, realmName, userID).Return(kcUserRep, nil).Times(1)
+		mockUsersDetailsDBModule.EXPECT().UpdateTrustIDGroupsExpiries(ctx, realmName, userID, nil).Return(nil).Times(1)
 		mockKeycloakClient.EXPECT().UpdateUser(accessToken, realmName, userID, kcUserRep2).Return(fmt.Errorf("Unexpected error")).Times(1)
 
-		var ctx = context.WithValue(context.Background(), cs.CtContextAccessToken, accessToken)
-
 		err := managementComponent.SetTrustIDGroupsToUser(ctx, realmName, userID, grpNames)
 
 		assert.NotNil(t, err)
@@ -1985,7 +2400,7 @@ func TestResetPassword(t *testing.T) {
 	var mockLogger = mock.NewLogger(mockCtrl)
 	var allowedTrustIDGroups = []string{"grp1", "grp2"}
 
-	var managementComponent = NewComponent(mockKeycloakClient, mockUsersDetailsDBModule, mockEventDBModule, mockConfigurationDBModule, allowedTrustIDGroups, mockLogger)
+	var managementComponent = NewComponent(mockKeycloakClient, mockUsersDetailsDBModule, mockEventDBModule, mockConfigurationDBModule, nil, allowedTrustIDGroups, nil, mockLogger)
 
 	var accessToken = "TOKEN=="
 	var realmName = "master"
@@ -2133,7 +2548,7 @@ func TestRecoveryCode(t *testing.T) {
 	var mockLogger = mock.NewLogger(mockCtrl)
 	var allowedTrustIDGroups = []string{"grp1", "grp2"}
 
-	var managementComponent = NewComponent(mockKeycloakClient, mockUsersDetailsDBModule, mockEventDBModule, mockConfigurationDBModule, allowedTrustIDGroups, mockLogger)
+	var managementComponent = NewComponent(mockKeycloakClient, mockUsersDetailsDBModule, mockEventDBModule, mockConfigurationDBModule, nil, allowedTrustIDGroups, nil, mockLogger)
 
 	var accessToken = "TOKEN=="
 	var realmName = "master"
@@ -2208,7 +2623,7 @@ func TestExecuteActionsEmail(t *testing.T) {
 	var mockLogger = log.NewNopLogger()
 	var allowedTrustIDGroups = []string{"grp1", "grp2"}
 
-	var managementComponent = NewComponent(mockKeycloakClient, mockUsersDetailsDBModule, mockEventDBModule, mockConfigurationDBModule, allowedTrustIDGroups, mockLogger)
+	var managementComponent = NewComponent(mockKeycloakClient, mockUsersDetailsDBModule, mockEventDBModule, mockConfigurationDBModule, nil, allowedTrustIDGroups, nil, mockLogger)
 
 	var accessToken = "TOKEN=="
 	var realmName = "master"
@@ -2271,7 +2686,7 @@ func TestSendNewEnrolmentCode(t *testing.T) {
 	var mockLogger = mock.NewLogger(mockCtrl)
 	var allowedTrustIDGroups = []string{"grp1", "grp2"}
 
-	var managementComponent = NewComponent(mockKeycloakClient, mockUsersDetailsDBModule, mockEventDBModule, mockConfigurationDBModule, allowedTrustIDGroups, mockLogger)
+	var managementComponent = NewComponent(mockKeycloakClient, mockUsersDetailsDBModule, mockEventDBModule, mockConfigurationDBModule, nil, allowedTrustIDGroups, nil, mockLogger)
 
 	var accessToken = "TOKEN=="
 	var realmName = "master"
@@ -2329,7 +2744,7 @@ func TestSendReminderEmail(t *testing.T) {
 	var mockLogger = log.NewNopLogger()
 	var allowedTrustIDGroups = []string{"grp1", "grp2"}
 
-	var managementComponent = NewComponent(mockKeycloakClient, mockUsersDetailsDBModule, mockEventDBModule, mockConfigurationDBModule, allowedTrustIDGroups, mockLogger)
+	var managementComponent = NewComponent(mockKeycloakClient, mockUsersDetailsDBModule, mockEventDBModule, mockConfigurationDBModule, nil, allowedTrustIDGroups, nil, mockLogger)
 
 	var accessToken = "TOKEN=="
 	var realmName = "master"
@@ -2376,7 +2791,7 @@ func TestResetSmsCounter(t *testing.T) {
 	var mockLogger = log.NewNopLogger()
 	var allowedTrustIDGroups = []string{"grp1", "grp2"}
 
-	var managementComponent = NewComponent(mockKeycloakClient, mockUsersDetailsDBModule, mockEventDBModule, mockConfigurationDBModule, allowedTrustIDGroups, mockLogger)
+	var managementComponent = NewComponent(mockKeycloakClient, mockUsersDetailsDBModule, mockEventDBModule, mockConfigurationDBModule, nil, allowedTrustIDGroups, nil, mockLogger)
 
 	var accessToken = "TOKEN=="
 	var realmName = "master"
@@ -2461,7 +2876,7 @@ func TestGetCredentialsForUser(t *testing.T) {
 	var mockLogger = log.NewNopLogger()
 	var allowedTrustIDGroups = []string{"grp1", "grp2"}
 
-	var managementComponent = NewComponent(mockKeycloakClient, mockUsersDetailsDBModule, mockEventDBModule, mockConfigurationDBModule, allowedTrustIDGroups, mockLogger)
+	var managementComponent = NewComponent(mockKeycloakClient, mockUsersDetailsDBModule, mockEventDBModule, mockConfigurationDBModule, nil, allowedTrustIDGroups, nil, mockLogger)
 	var accessToken = "TOKEN=="
 	var realmReq = "master"
 	var realmName = "otherRealm"
@@ -2490,7 +2905,7 @@ func TestDeleteCredentialsForUser(t *testing.T) {
 	var mockLogger = mock.NewLogger(mockCtrl)
 	var allowedTrustIDGroups = []string{"grp1", "grp2"}
 
-	var managementComponent = NewComponent(mockKeycloakClient, mockUsersDetailsDBModule, mockEventDBModule, mockConfigurationDBModule, allowedTrustIDGroups, mockLogger)
+	var managementComponent = NewComponent(mockKeycloakClient, mockUsersDetailsDBModule, mockEventDBModule, mockConfigurationDBModule, nil, allowedTrustIDGroups, nil, mockLogger)
 	var accessToken = "TOKEN=="
 	var realmReq = "master"
 	var realmName = "master"
@@ -2613,7 +3028,7 @@ func TestUnlockCredentialForUser(t *testing.T) {
 	var mockEventDBModule = mock.NewEventDBModule(mockCtrl)
 	var mockConfigurationDBModule = mock.NewConfigurationDBModule(mockCtrl)
 
-	var managementComponent = NewComponent(mockKeycloakClient, nil, mockEventDBModule, mockConfigurationDBModule, nil, log.NewNopLogger())
+	var managementComponent = NewComponent(mockKeycloakClient, nil, mockEventDBModule, mockConfigurationDBModule, nil, nil, nil, log.NewNopLogger())
 	var accessToken = "TOKEN=="
 	var realmName = "master"
 	var userID = "1245-7854-8963"
@@ -2671,7 +3086,7 @@ func TestClearUserLoginFailures(t *testing.T) {
 	var userID = "1245-7854-8963"
 	var allowedTrustIDGroups = []string{"grp1", "grp2"}
 	var ctx = context.WithValue(context.TODO(), cs.CtContextAccessToken, accessToken)
-	var component = NewComponent(mockKeycloakClient, mockUsersDetailsDBModule, mockEventDBModule, mockConfigurationDBModule, allowedTrustIDGroups, logger)
+	var component = NewComponent(mockKeycloakClient, mockUsersDetailsDBModule, mockEventDBModule, mockConfigurationDBModule, nil, allowedTrustIDGroups, nil, logger)
 
 	t.Run("Error occured", func(t *testing.T) {
 		var expectedError = errors.New("kc error")
@@ -2701,7 +3116,7 @@ func TestGetAttackDetectionStatus(t *testing.T) {
 	var userID = "1245-7854-8963"
 	var allowedTrustIDGroups = []string{"grp1", "grp2"}
 	var ctx = context.WithValue(context.TODO(), cs.CtContextAccessToken, accessToken)
-	var component = NewComponent(mockKeycloakClient, mockUsersDetailsDBModule, mockEventDBModule, mockConfigurationDBModule, allowedTrustIDGroups, logger)
+	var component = NewComponent(mockKeycloakClient, mockUsersDetailsDBModule, mockEventDBModule, mockConfigurationDBModule, nil, allowedTrustIDGroups, nil, logger)
 	var kcResult = map[string]interface{}{}
 
 	t.Run("Error occured", func(t *testing.T) {
@@ -2720,43 +3135,159 @@ func TestGetAttackDetectionStatus(t *testing.T) {
 	})
 }
 
-func TestGetRoles(t *testing.T) {
+func TestUserSessions(t *testing.T) {
 	var mockCtrl = gomock.NewController(t)
 	defer mockCtrl.Finish()
 	var mockKeycloakClient = mock.NewKeycloakClient(mockCtrl)
 	var mockUsersDetailsDBModule = mock.NewUsersDetailsDBModule(mockCtrl)
 	var mockEventDBModule = mock.NewEventDBModule(mockCtrl)
 	var mockConfigurationDBModule = mock.NewConfigurationDBModule(mockCtrl)
-	var mockLogger = log.NewNopLogger()
-	var allowedTrustIDGroups = []string{"grp1", "grp2"}
-
-	var managementComponent = NewComponent(mockKeycloakClient, mockUsersDetailsDBModule, mockEventDBModule, mockConfigurationDBModule, allowedTrustIDGroups, mockLogger)
+	var logger = log.NewNopLogger()
 
 	var accessToken = "TOKEN=="
-	var realmName = "master"
-
-	// Get roles with succces
-	{
-		var id = "1234-7454-4516"
-		var composite = false
-		var containerID = "containerId"
-		var description = "description role"
-		var clientRole = false
-		var name = "name"
+	var realm = "master"
+	var userID = "1245-7854-8963"
+	var sessionID = "7412-8523-9635"
+	var clientID = "backoffice"
+	var ctx = context.WithValue(context.TODO(), cs.CtContextAccessToken, accessToken)
+	var component = NewComponent(mockKeycloakClient, mockUsersDetailsDBModule, mockEventDBModule, mockConfigurationDBModule, nil, nil, nil, logger)
+	var expectedError = errors.New("kc error")
+	var sessionsKc = []kc.UserSessionRepresentation{{ID: &sessionID}}
 
-		var kcRoleRep = kc.RoleRepresentation{
-			ID:          &id,
-			Name:        &name,
-			ClientRole:  &clientRole,
-			Composite:   &composite,
-			ContainerID: &containerID,
-			Description: &description,
-		}
+	t.Run("Get sessions-Error occured", func(t *testing.T) {
+		mockKeycloakClient.EXPECT().GetSessionsOfUser(accessToken, realm, userID).Return(nil, expectedError)
+		var _, err = component.GetSessionsOfUser(ctx, realm, userID)
+		assert.Equal(t, expectedError, err)
+	})
+	t.Run("Get sessions-Success", func(t *testing.T) {
+		mockKeycloakClient.EXPECT().GetSessionsOfUser(accessToken, realm, userID).Return(sessionsKc, nil)
+		var res, err = component.GetSessionsOfUser(ctx, realm, userID)
+		assert.Nil(t, err)
+		assert.Len(t, res, 1)
+		assert.Equal(t, sessionID, *res[0].ID)
+	})
 
-		var kcRolesRep []kc.RoleRepresentation
-		kcRolesRep = append(kcRolesRep, kcRoleRep)
+	t.Run("Logout-Error occured", func(t *testing.T) {
+		mockKeycloakClient.EXPECT().LogoutUser(accessToken, realm, userID).Return(expectedError)
+		assert.Equal(t, expectedError, component.LogoutUser(ctx, realm, userID))
+	})
+	t.Run("Logout-Success", func(t *testing.T) {
+		mockKeycloakClient.EXPECT().LogoutUser(accessToken, realm, userID).Return(nil)
+		mockEventDBModule.EXPECT().ReportEvent(ctx, "API_USER_LOGOUT", "back-office", gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any())
+		assert.Nil(t, component.LogoutUser(ctx, realm, userID))
+	})
 
-		mockKeycloakClient.EXPECT().GetRoles(accessToken, realmName).Return(kcRolesRep, nil).Times(1)
+	t.Run("Delete session-Can't get sessions", func(t *testing.T) {
+		mockKeycloakClient.EXPECT().GetSessionsOfUser(accessToken, realm, userID).Return(nil, expectedError)
+		assert.Equal(t, expectedError, component.DeleteSessionOfUser(ctx, realm, userID, sessionID))
+	})
+	t.Run("Delete session-Session of another user", func(t *testing.T) {
+		mockKeycloakClient.EXPECT().GetSessionsOfUser(accessToken, realm, userID).Return(sessionsKc, nil)
+		var err = component.DeleteSessionOfUser(ctx, realm, userID, "other-session")
+		assert.IsType(t, errorhandler.Error{}, err)
+	})
+	t.Run("Delete session-Error occured", func(t *testing.T) {
+		mockKeycloakClient.EXPECT().GetSessionsOfUser(accessToken, realm, userID).Return(sessionsKc, nil)
+		mockKeycloakClient.EXPECT().DeleteSession(accessToken, realm, sessionID).Return(expectedError)
+		assert.Equal(t, expectedError, component.DeleteSessionOfUser(ctx, realm, userID, sessionID))
+	})
+	t.Run("Delete session-Success", func(t *testing.T) {
+		mockKeycloakClient.EXPECT().GetSessionsOfUser(accessToken, realm, userID).Return(sessionsKc, nil)
+		mockKeycloakClient.EXPECT().DeleteSession(accessToken, realm, sessionID).Return(nil)
+		mockEventDBModule.EXPECT().ReportEvent(ctx, "API_USER_SESSION_DELETION", "back-office", gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any())
+		assert.Nil(t, component.DeleteSessionOfUser(ctx, realm, userID, sessionID))
+	})
+
+	t.Run("Get offline sessions-Error occured", func(t *testing.T) {
+		mockKeycloakClient.EXPECT().GetOfflineSessionsOfUser(accessToken, realm, userID, clientID).Return(nil, expectedError)
+		var _, err = component.GetOfflineSessionsOfUser(ctx, realm, userID, clientID)
+		assert.Equal(t, expectedError, err)
+	})
+	t.Run("Get offline sessions-Success", func(t *testing.T) {
+		mockKeycloakClient.EXPECT().GetOfflineSessionsOfUser(accessToken, realm, userID, clientID).Return(sessionsKc, nil)
+		var res, err = component.GetOfflineSessionsOfUser(ctx, realm, userID, clientID)
+		assert.Nil(t, err)
+		assert.Len(t, res, 1)
+	})
+}
+
+func TestUserConsents(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+	var mockKeycloakClient = mock.NewKeycloakClient(mockCtrl)
+	var mockUsersDetailsDBModule = mock.NewUsersDetailsDBModule(mockCtrl)
+	var mockEventDBModule = mock.NewEventDBModule(mockCtrl)
+	var mockConfigurationDBModule = mock.NewConfigurationDBModule(mockCtrl)
+	var logger = log.NewNopLogger()
+
+	var accessToken = "TOKEN=="
+	var realm = "master"
+	var userID = "1245-7854-8963"
+	var clientID = "backoffice"
+	var ctx = context.WithValue(context.TODO(), cs.CtContextAccessToken, accessToken)
+	var component = NewComponent(mockKeycloakClient, mockUsersDetailsDBModule, mockEventDBModule, mockConfigurationDBModule, nil, nil, nil, logger)
+	var expectedError = errors.New("kc error")
+
+	t.Run("Get consents-Error occured", func(t *testing.T) {
+		mockKeycloakClient.EXPECT().GetConsentsOfUser(accessToken, realm, userID).Return(nil, expectedError)
+		var _, err = component.GetConsentsOfUser(ctx, realm, userID)
+		assert.Equal(t, expectedError, err)
+	})
+	t.Run("Get consents-Success", func(t *testing.T) {
+		mockKeycloakClient.EXPECT().GetConsentsOfUser(accessToken, realm, userID).Return([]kc.UserConsentRepresentation{{ClientID: &clientID}}, nil)
+		var res, err = component.GetConsentsOfUser(ctx, realm, userID)
+		assert.Nil(t, err)
+		assert.Equal(t, clientID, *res[0].ClientID)
+	})
+
+	t.Run("Revoke consent-Error occured", func(t *testing.T) {
+		mockKeycloakClient.EXPECT().RevokeConsentOfUser(accessToken, realm, userID, clientID).Return(expectedError)
+		assert.Equal(t, expectedError, component.RevokeConsentOfUser(ctx, realm, userID, clientID))
+	})
+	t.Run("Revoke consent-Success", func(t *testing.T) {
+		mockKeycloakClient.EXPECT().RevokeConsentOfUser(accessToken, realm, userID, clientID).Return(nil)
+		mockEventDBModule.EXPECT().ReportEvent(ctx, "API_USER_CONSENT_REVOCATION", "back-office", gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any())
+		assert.Nil(t, component.RevokeConsentOfUser(ctx, realm, userID, clientID))
+	})
+}
+
+func TestGetRoles(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+	var mockKeycloakClient = mock.NewKeycloakClient(mockCtrl)
+	var mockUsersDetailsDBModule = mock.NewUsersDetailsDBModule(mockCtrl)
+	var mockEventDBModule = mock.NewEventDBModule(mockCtrl)
+	var mockConfigurationDBModule = mock.NewConfigurationDBModule(mockCtrl)
+	var mockLogger = log.NewNopLogger()
+	var allowedTrustIDGroups = []string{"grp1", "grp2"}
+
+	var managementComponent = NewComponent(mockKeycloakClient, mockUsersDetailsDBModule, mockEventDBModule, mockConfigurationDBModule, nil, allowedTrustIDGroups, nil, mockLogger)
+
+	var accessToken = "TOKEN=="
+	var realmName = "master"
+
+	// Get roles with succces
+	{
+		var id = "1234-7454-4516"
+		var composite = false
+		var containerID = "containerId"
+		var description = "description role"
+		var clientRole = false
+		var name = "name"
+
+		var kcRoleRep = kc.RoleRepresentation{
+			ID:          &id,
+			Name:        &name,
+			ClientRole:  &clientRole,
+			Composite:   &composite,
+			ContainerID: &containerID,
+			Description: &description,
+		}
+
+		var kcRolesRep []kc.RoleRepresentation
+		kcRolesRep = append(kcRolesRep, kcRoleRep)
+
+		mockKeycloakClient.EXPECT().GetRoles(accessToken, realmName).Return(kcRolesRep, nil).Times(1)
 
 		var ctx = context.WithValue(context.Background(), cs.CtContextAccessToken, accessToken)
 
@@ -2794,7 +3325,7 @@ func TestGetRole(t *testing.T) {
 	var mockLogger = log.NewNopLogger()
 	var allowedTrustIDGroups = []string{"grp1", "grp2"}
 
-	var managementComponent = NewComponent(mockKeycloakClient, mockUsersDetailsDBModule, mockEventDBModule, mockConfigurationDBModule, allowedTrustIDGroups, mockLogger)
+	var managementComponent = NewComponent(mockKeycloakClient, mockUsersDetailsDBModule, mockEventDBModule, mockConfigurationDBModule, nil, allowedTrustIDGroups, nil, mockLogger)
 
 	var accessToken = "TOKEN=="
 	var realmName = "master"
@@ -2845,6 +3376,226 @@ func TestGetRole(t *testing.T) {
 	}
 }
 
+func TestCreateRole(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+	var mockKeycloakClient = mock.NewKeycloakClient(mockCtrl)
+	var mockEventDBModule = mock.NewEventDBModule(mockCtrl)
+
+	var managementComponent = NewComponent(mockKeycloakClient, nil, mockEventDBModule, nil, nil, nil, nil, log.NewNopLogger())
+
+	var accessToken = "TOKEN=="
+	var realmName = "master"
+	var name = "role-name"
+	var description = "description"
+	var clientRole = true
+	var locationURL = "http://location.url"
+	var ctx = context.WithValue(context.Background(), cs.CtContextAccessToken, accessToken)
+
+	// Only name and description are given to Keycloak
+	var role = api.RoleRepresentation{Name: &name, Description: &description, ClientRole: &clientRole}
+	var kcRole = kc.RoleRepresentation{Name: &name, Description: &description}
+
+	t.Run("Keycloak error", func(t *testing.T) {
+		mockKeycloakClient.EXPECT().CreateRole(accessToken, realmName, kcRole).Return("", errors.New("error"))
+		var _, err = managementComponent.CreateRole(ctx, realmName, role)
+		assert.NotNil(t, err)
+	})
+	t.Run("Success", func(t *testing.T) {
+		mockKeycloakClient.EXPECT().CreateRole(accessToken, realmName, kcRole).Return(locationURL, nil)
+		mockEventDBModule.EXPECT().ReportEvent(ctx, "API_ROLE_CREATION", "back-office", database.CtEventRealmName, realmName,
+			database.CtEventAdditionalInfo, database.CreateAdditionalInfo("role_ids", "", "role_names", name)).Return(nil)
+		var location, err = managementComponent.CreateRole(ctx, realmName, role)
+		assert.Nil(t, err)
+		assert.Equal(t, locationURL, location)
+	})
+}
+
+func TestUpdateRole(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+	var mockKeycloakClient = mock.NewKeycloakClient(mockCtrl)
+	var mockEventDBModule = mock.NewEventDBModule(mockCtrl)
+
+	var managementComponent = NewComponent(mockKeycloakClient, nil, mockEventDBModule, nil, nil, nil, nil, log.NewNopLogger())
+
+	var accessToken = "TOKEN=="
+	var realmName = "master"
+	var roleID = "1234-7454-4516"
+	var name = "role-name"
+	var description = "description"
+	var newDescription = "new description"
+	var ctx = context.WithValue(context.Background(), cs.CtContextAccessToken, accessToken)
+
+	var role = api.RoleRepresentation{Description: &newDescription}
+	var updatedRole = kc.RoleRepresentation{ID: &roleID, Name: &name, Description: &newDescription}
+
+	t.Run("Can't get role", func(t *testing.T) {
+		mockKeycloakClient.EXPECT().GetRole(accessToken, realmName, roleID).Return(kc.RoleRepresentation{}, errors.New("error"))
+		var err = managementComponent.UpdateRole(ctx, realmName, roleID, role)
+		assert.NotNil(t, err)
+	})
+	t.Run("Keycloak error", func(t *testing.T) {
+		mockKeycloakClient.EXPECT().GetRole(accessToken, realmName, roleID).Return(kc.RoleRepresentation{ID: &roleID, Name: &name, Description: &description}, nil)
+		mockKeycloakClient.EXPECT().UpdateRole(accessToken, realmName, roleID, updatedRole).Return(errors.New("error"))
+		var err = managementComponent.UpdateRole(ctx, realmName, roleID, role)
+		assert.NotNil(t, err)
+	})
+	t.Run("Success", func(t *testing.T) {
+		mockKeycloakClient.EXPECT().GetRole(accessToken, realmName, roleID).Return(kc.RoleRepresentation{ID: &roleID, Name: &name, Description: &description}, nil)
+		mockKeycloakClient.EXPECT().UpdateRole(accessToken, realmName, roleID, updatedRole).Return(nil)
+		mockEventDBModule.EXPECT().ReportEvent(ctx, "API_ROLE_UPDATE", "back-office", database.CtEventRealmName, realmName,
+			database.CtEventAdditionalInfo, database.CreateAdditionalInfo("role_ids", roleID, "role_names", name)).Return(nil)
+		var err = managementComponent.UpdateRole(ctx, realmName, roleID, role)
+		assert.Nil(t, err)
+	})
+}
+
+func TestDeleteRole(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+	var mockKeycloakClient = mock.NewKeycloakClient(mockCtrl)
+	var mockEventDBModule = mock.NewEventDBModule(mockCtrl)
+
+	var managementComponent = NewComponent(mockKeycloakClient, nil, mockEventDBModule, nil, nil, nil, nil, log.NewNopLogger())
+
+	var accessToken = "TOKEN=="
+	var realmName = "master"
+	var roleID = "1234-7454-4516"
+	var name = "role-name"
+	var clientRole = true
+	var realmRole = false
+	var ctx = context.WithValue(context.Background(), cs.CtContextAccessToken, accessToken)
+
+	t.Run("Can't get role", func(t *testing.T) {
+		mockKeycloakClient.EXPECT().GetRole(accessToken, realmName, roleID).Return(kc.RoleRepresentation{}, errors.New("error"))
+		var err = managementComponent.DeleteRole(ctx, realmName, roleID)
+		assert.NotNil(t, err)
+	})
+	t.Run("Client role", func(t *testing.T) {
+		mockKeycloakClient.EXPECT().GetRole(accessToken, realmName, roleID).Return(kc.RoleRepresentation{ID: &roleID, ClientRole: &clientRole}, nil)
+		var err = managementComponent.DeleteRole(ctx, realmName, roleID)
+		assert.NotNil(t, err)
+	})
+	t.Run("Keycloak error", func(t *testing.T) {
+		mockKeycloakClient.EXPECT().GetRole(accessToken, realmName, roleID).Return(kc.RoleRepresentation{ID: &roleID, Name: &name, ClientRole: &realmRole}, nil)
+		mockKeycloakClient.EXPECT().DeleteRole(accessToken, realmName, roleID).Return(errors.New("error"))
+		var err = managementComponent.DeleteRole(ctx, realmName, roleID)
+		assert.NotNil(t, err)
+	})
+	t.Run("Success", func(t *testing.T) {
+		mockKeycloakClient.EXPECT().GetRole(accessToken, realmName, roleID).Return(kc.RoleRepresentation{ID: &roleID, Name: &name, ClientRole: &realmRole}, nil)
+		mockKeycloakClient.EXPECT().DeleteRole(accessToken, realmName, roleID).Return(nil)
+		mockEventDBModule.EXPECT().ReportEvent(ctx, "API_ROLE_DELETION", "back-office", database.CtEventRealmName, realmName,
+			database.CtEventAdditionalInfo, database.CreateAdditionalInfo("role_ids", roleID, "role_names", name)).Return(nil)
+		var err = managementComponent.DeleteRole(ctx, realmName, roleID)
+		assert.Nil(t, err)
+	})
+}
+
+func TestRoleComposites(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+	var mockKeycloakClient = mock.NewKeycloakClient(mockCtrl)
+	var mockEventDBModule = mock.NewEventDBModule(mockCtrl)
+
+	var managementComponent = NewComponent(mockKeycloakClient, nil, mockEventDBModule, nil, nil, nil, nil, log.NewNopLogger())
+
+	var accessToken = "TOKEN=="
+	var realmName = "master"
+	var roleID = "1234-7454-4516"
+	var compositeID = "7894-7454-4516"
+	var compositeName = "composite"
+	var ctx = context.WithValue(context.Background(), cs.CtContextAccessToken, accessToken)
+
+	var composites = []api.RoleRepresentation{{ID: &compositeID, Name: &compositeName}}
+	var kcComposites = []kc.RoleRepresentation{{ID: &compositeID, Name: &compositeName}}
+	var additionalInfo = database.CreateAdditionalInfo("composite_role_id", roleID, "role_ids", compositeID, "role_names", compositeName)
+
+	t.Run("Get composites: Keycloak error", func(t *testing.T) {
+		mockKeycloakClient.EXPECT().GetRoleComposites(accessToken, realmName, roleID).Return(nil, errors.New("error"))
+		var _, err = managementComponent.GetRoleComposites(ctx, realmName, roleID)
+		assert.NotNil(t, err)
+	})
+	t.Run("Get composites", func(t *testing.T) {
+		mockKeycloakClient.EXPECT().GetRoleComposites(accessToken, realmName, roleID).Return(kcComposites, nil)
+		var res, err = managementComponent.GetRoleComposites(ctx, realmName, roleID)
+		assert.Nil(t, err)
+		assert.Len(t, res, 1)
+		assert.Equal(t, compositeID, *res[0].ID)
+	})
+	t.Run("Add composites: Keycloak error", func(t *testing.T) {
+		mockKeycloakClient.EXPECT().AddRoleComposites(accessToken, realmName, roleID, kcComposites).Return(errors.New("error"))
+		var err = managementComponent.AddRoleComposites(ctx, realmName, roleID, composites)
+		assert.NotNil(t, err)
+	})
+	t.Run("Add composites", func(t *testing.T) {
+		mockKeycloakClient.EXPECT().AddRoleComposites(accessToken, realmName, roleID, kcComposites).Return(nil)
+		mockEventDBModule.EXPECT().ReportEvent(ctx, "API_ROLE_COMPOSITES_ADDITION", "back-office", database.CtEventRealmName, realmName,
+			database.CtEventAdditionalInfo, additionalInfo).Return(nil)
+		var err = managementComponent.AddRoleComposites(ctx, realmName, roleID, composites)
+		assert.Nil(t, err)
+	})
+	t.Run("Delete composites: Keycloak error", func(t *testing.T) {
+		mockKeycloakClient.EXPECT().DeleteRoleComposites(accessToken, realmName, roleID, kcComposites).Return(errors.New("error"))
+		var err = managementComponent.DeleteRoleComposites(ctx, realmName, roleID, composites)
+		assert.NotNil(t, err)
+	})
+	t.Run("Delete composites", func(t *testing.T) {
+		mockKeycloakClient.EXPECT().DeleteRoleComposites(accessToken, realmName, roleID, kcComposites).Return(nil)
+		mockEventDBModule.EXPECT().ReportEvent(ctx, "API_ROLE_COMPOSITES_REMOVAL", "back-office", database.CtEventRealmName, realmName,
+			database.CtEventAdditionalInfo, additionalInfo).Return(nil)
+		var err = managementComponent.DeleteRoleComposites(ctx, realmName, roleID, composites)
+		assert.Nil(t, err)
+	})
+}
+
+func TestGetUsersWithRole(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+	var mockKeycloakClient = mock.NewKeycloakClient(mockCtrl)
+
+	var managementComponent = NewComponent(mockKeycloakClient, nil, nil, nil, nil, nil, nil, log.NewNopLogger())
+
+	var accessToken = "TOKEN=="
+	var realmName = "master"
+	var roleID = "1234-7454-4516"
+	var roleName = "role-name"
+	var clientID = "456-789-147"
+	var clientRole = true
+	var realmRole = false
+	var userID = "f467ed7c-0a1d-4eee-9bb8-669c6f89c0ee"
+	var users = []kc.UserRepresentation{{ID: &userID}}
+	var ctx = context.WithValue(context.Background(), cs.CtContextAccessToken, accessToken)
+
+	t.Run("Can't get role", func(t *testing.T) {
+		mockKeycloakClient.EXPECT().GetRole(accessToken, realmName, roleID).Return(kc.RoleRepresentation{}, errors.New("error"))
+		var _, err = managementComponent.GetUsersWithRole(ctx, realmName, roleID)
+		assert.NotNil(t, err)
+	})
+	t.Run("Keycloak error", func(t *testing.T) {
+		mockKeycloakClient.EXPECT().GetRole(accessToken, realmName, roleID).Return(kc.RoleRepresentation{Name: &roleName, ClientRole: &realmRole}, nil)
+		mockKeycloakClient.EXPECT().GetUsersWithRealmRole(accessToken, realmName, roleName).Return(nil, errors.New("error"))
+		var _, err = managementComponent.GetUsersWithRole(ctx, realmName, roleID)
+		assert.NotNil(t, err)
+	})
+	t.Run("Realm role", func(t *testing.T) {
+		mockKeycloakClient.EXPECT().GetRole(accessToken, realmName, roleID).Return(kc.RoleRepresentation{Name: &roleName, ClientRole: &realmRole}, nil)
+		mockKeycloakClient.EXPECT().GetUsersWithRealmRole(accessToken, realmName, roleName, "max", "10").Return(users, nil)
+		var res, err = managementComponent.GetUsersWithRole(ctx, realmName, roleID, "max", "10")
+		assert.Nil(t, err)
+		assert.Len(t, res, 1)
+		assert.Equal(t, userID, *res[0].ID)
+	})
+	t.Run("Client role", func(t *testing.T) {
+		mockKeycloakClient.EXPECT().GetRole(accessToken, realmName, roleID).Return(kc.RoleRepresentation{Name: &roleName, ClientRole: &clientRole, ContainerID: &clientID}, nil)
+		mockKeycloakClient.EXPECT().GetUsersWithClientRole(accessToken, realmName, clientID, roleName).Return(users, nil)
+		var res, err = managementComponent.GetUsersWithRole(ctx, realmName, roleID)
+		assert.Nil(t, err)
+		assert.Len(t, res, 1)
+	})
+}
+
 func TestGetGroups(t *testing.T) {
 	var mockCtrl = gomock.NewController(t)
 	defer mockCtrl.Finish()
@@ -2855,7 +3606,7 @@ func TestGetGroups(t *testing.T) {
 	var mockLogger = log.NewNopLogger()
 	var allowedTrustIDGroups = []string{"grp1", "grp2"}
 
-	var managementComponent = NewComponent(mockKeycloakClient, mockUsersDetailsDBModule, mockEventDBModule, mockConfigurationDBModule, allowedTrustIDGroups, mockLogger)
+	var managementComponent = NewComponent(mockKeycloakClient, mockUsersDetailsDBModule, mockEventDBModule, mockConfigurationDBModule, nil, allowedTrustIDGroups, nil, mockLogger)
 
 	var accessToken = "TOKEN=="
 	var realmName = "master"
@@ -2924,7 +3675,7 @@ func TestCreateGroup(t *testing.T) {
 	var mockLogger = mock.NewLogger(mockCtrl)
 	var allowedTrustIDGroups = []string{"grp1", "grp2"}
 
-	var managementComponent = NewComponent(mockKeycloakClient, mockUsersDetailsDBModule, mockEventDBModule, mockConfigurationDBModule, allowedTrustIDGroups, mockLogger)
+	var managementComponent = NewComponent(mockKeycloakClient, mockUsersDetailsDBModule, mockEventDBModule, mockConfigurationDBModule, nil, allowedTrustIDGroups, nil, mockLogger)
 
 	var accessToken = "TOKEN=="
 	var username = "username"
@@ -3012,10 +3763,11 @@ func TestDeleteGroup(t *testing.T) {
 	var mockUsersDetailsDBModule = mock.NewUsersDetailsDBModule(mockCtrl)
 	var mockEventDBModule = mock.NewEventDBModule(mockCtrl)
 	var mockConfigurationDBModule = mock.NewConfigurationDBModule(mockCtrl)
+	var mockTransaction = mock.NewTransaction(mockCtrl)
 	var mockLogger = mock.NewLogger(mockCtrl)
 	var allowedTrustIDGroups = []string{"grp1", "grp2"}
 
-	var managementComponent = NewComponent(mockKeycloakClient, mockUsersDetailsDBModule, mockEventDBModule, mockConfigurationDBModule, allowedTrustIDGroups, mockLogger)
+	var managementComponent = NewComponent(mockKeycloakClient, mockUsersDetailsDBModule, mockEventDBModule, mockConfigurationDBModule, nil, allowedTrustIDGroups, nil, mockLogger)
 
 	var accessToken = "TOKEN=="
 	var groupID = "41dbf4a8-32a9-4000-8c17-edc854c31231"
@@ -3028,6 +3780,10 @@ func TestDeleteGroup(t *testing.T) {
 		ID:   &groupID,
 		Name: &groupName,
 	}
+	var action = "MGMT_GetUsers"
+	var operators = "operators"
+	var rulesOnGroup = []configuration.Authorization{{RealmID: &targetRealmName, GroupName: &operators, Action: &action,
+		TargetRealmID: &targetRealmName, TargetGroupName: &groupName}}
 
 	// Delete group with success
 	{
@@ -3038,7 +3794,22 @@ func TestDeleteGroup(t *testing.T) {
 		ctx = context.WithValue(ctx, cs.CtContextRealm, realmName)
 		ctx = context.WithValue(ctx, cs.CtContextUsername, username)
 
-		mockConfigurationDBModule.EXPECT().DeleteAllAuthorizationsWithGroup(ctx, targetRealmName, groupName).Return(nil).Times(1)
+		mockConfigurationDBModule.EXPECT().GetAuthorizations(ctx, targetRealmName, groupName).Return([]configuration.Authorization{}, nil).Times(1)
+		mockConfigurationDBModule.EXPECT().GetAuthorizationsOnGroup(ctx, targetRealmName, groupName).Return(rulesOnGroup, nil).Times(1)
+		mockConfigurationDBModule.EXPECT().GetAuthorizations(ctx, targetRealmName, operators).Return(rulesOnGroup, nil).Times(1)
+		mockConfigurationDBModule.EXPECT().NewTransaction(ctx).Return(mockTransaction, nil).Times(1)
+		mockConfigurationDBModule.EXPECT().DeleteAllAuthorizationsWithGroup(ctx, mockTransaction, targetRealmName, groupName).Return(nil).Times(1)
+		mockConfigurationDBModule.EXPECT().CreateAuthorizationsVersion(ctx, mockTransaction, gomock.Any()).DoAndReturn(func(_ context.Context, _ sqltypes.Transaction, version dto.AuthorizationsVersion) error {
+			assert.Equal(t, groupName, version.GroupName)
+			return nil
+		}).Times(1)
+		mockConfigurationDBModule.EXPECT().CreateAuthorizationsVersion(ctx, mockTransaction, gomock.Any()).DoAndReturn(func(_ context.Context, _ sqltypes.Transaction, version dto.AuthorizationsVersion) error {
+			assert.Equal(t, operators, version.GroupName)
+			assert.Len(t, version.After, 0)
+			return nil
+		}).Times(1)
+		mockTransaction.EXPECT().Commit().Return(nil).Times(1)
+		mockTransaction.EXPECT().Close().Times(1)
 		mockEventDBModule.EXPECT().ReportEvent(ctx, "API_GROUP_DELETION", "back-office", gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).Times(1)
 
 		err := managementComponent.DeleteGroup(ctx, targetRealmName, groupID)
@@ -3055,7 +3826,13 @@ func TestDeleteGroup(t *testing.T) {
 		ctx = context.WithValue(ctx, cs.CtContextRealm, realmName)
 		ctx = context.WithValue(ctx, cs.CtContextUsername, username)
 
-		mockConfigurationDBModule.EXPECT().DeleteAllAuthorizationsWithGroup(ctx, targetRealmName, groupName).Return(nil).Times(1)
+		mockConfigurationDBModule.EXPECT().GetAuthorizations(ctx, targetRealmName, groupName).Return([]configuration.Authorization{}, nil).Times(1)
+		mockConfigurationDBModule.EXPECT().GetAuthorizationsOnGroup(ctx, targetRealmName, groupName).Return([]configuration.Authorization{}, nil).Times(1)
+		mockConfigurationDBModule.EXPECT().NewTransaction(ctx).Return(mockTransaction, nil).Times(1)
+		mockConfigurationDBModule.EXPECT().DeleteAllAuthorizationsWithGroup(ctx, mockTransaction, targetRealmName, groupName).Return(nil).Times(1)
+		mockConfigurationDBModule.EXPECT().CreateAuthorizationsVersion(ctx, mockTransaction, gomock.Any()).Return(nil).Times(1)
+		mockTransaction.EXPECT().Commit().Return(nil).Times(1)
+		mockTransaction.EXPECT().Close().Times(1)
 		mockEventDBModule.EXPECT().ReportEvent(ctx, "API_GROUP_DELETION", "back-office", database.CtEventRealmName, targetRealmName, database.CtEventGroupName, groupName).Return(errors.New("error")).Times(1)
 		m := map[string]interface{}{"event_name": "API_GROUP_DELETION", database.CtEventRealmName: targetRealmName, database.CtEventGroupName: groupName}
 		eventJSON, _ := json.Marshal(m)
@@ -3074,7 +3851,11 @@ func TestDeleteGroup(t *testing.T) {
 		ctx = context.WithValue(ctx, cs.CtContextRealm, realmName)
 		ctx = context.WithValue(ctx, cs.CtContextUsername, username)
 
-		mockConfigurationDBModule.EXPECT().DeleteAllAuthorizationsWithGroup(ctx, targetRealmName, groupName).Return(fmt.Errorf("Error")).Times(1)
+		mockConfigurationDBModule.EXPECT().GetAuthorizations(ctx, targetRealmName, groupName).Return([]configuration.Authorization{}, nil).Times(1)
+		mockConfigurationDBModule.EXPECT().GetAuthorizationsOnGroup(ctx, targetRealmName, groupName).Return([]configuration.Authorization{}, nil).Times(1)
+		mockConfigurationDBModule.EXPECT().NewTransaction(ctx).Return(mockTransaction, nil).Times(1)
+		mockConfigurationDBModule.EXPECT().DeleteAllAuthorizationsWithGroup(ctx, mockTransaction, targetRealmName, groupName).Return(fmt.Errorf("Error")).Times(1)
+		mockTransaction.EXPECT().Close().Times(1)
 		mockLogger.EXPECT().Warn(ctx, "err", "Error")
 
 		err := managementComponent.DeleteGroup(ctx, targetRealmName, groupID)
@@ -3100,6 +3881,243 @@ func TestDeleteGroup(t *testing.T) {
 	}
 }
 
+func TestGetGroup(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+	var mockKeycloakClient = mock.NewKeycloakClient(mockCtrl)
+	var mockLogger = mock.NewLogger(mockCtrl)
+
+	var managementComponent = NewComponent(mockKeycloakClient, nil, nil, nil, nil, nil, nil, mockLogger)
+
+	var accessToken = "TOKEN=="
+	var realmName = "master"
+	var groupID = "41dbf4a8-32a9-4000-8c17-edc854c31231"
+	var groupName = "groupName"
+	var subGroupName = "subGroupName"
+	var attributes = map[string][]string{"costCenter": {"1234"}}
+	var subGroups = []kc.GroupRepresentation{{Name: &subGroupName}}
+	var ctx = context.WithValue(context.Background(), cs.CtContextAccessToken, accessToken)
+
+	t.Run("Keycloak error", func(t *testing.T) {
+		mockKeycloakClient.EXPECT().GetGroup(accessToken, realmName, groupID).Return(kc.GroupRepresentation{}, errors.New("error"))
+		mockLogger.EXPECT().Warn(ctx, "err", "error")
+		var _, err = managementComponent.GetGroup(ctx, realmName, groupID)
+		assert.NotNil(t, err)
+	})
+	t.Run("Success", func(t *testing.T) {
+		mockKeycloakClient.EXPECT().GetGroup(accessToken, realmName, groupID).Return(kc.GroupRepresentation{ID: &groupID, Name: &groupName,
+			Attributes: &attributes, SubGroups: &subGroups}, nil)
+		var group, err = managementComponent.GetGroup(ctx, realmName, groupID)
+		assert.Nil(t, err)
+		assert.Equal(t, groupName, *group.Name)
+		assert.Equal(t, attributes, *group.Attributes)
+		assert.Equal(t, subGroupName, *(*group.SubGroups)[0].Name)
+	})
+}
+
+func TestUpdateGroup(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+	var mockKeycloakClient = mock.NewKeycloakClient(mockCtrl)
+	var mockEventDBModule = mock.NewEventDBModule(mockCtrl)
+	var mockConfigurationDBModule = mock.NewConfigurationDBModule(mockCtrl)
+	var mockTransaction = mock.NewTransaction(mockCtrl)
+	var mockLogger = mock.NewLogger(mockCtrl)
+
+	var managementComponent = NewComponent(mockKeycloakClient, nil, mockEventDBModule, mockConfigurationDBModule, nil, nil, nil, mockLogger)
+
+	var accessToken = "TOKEN=="
+	var realmName = "master"
+	var groupID = "41dbf4a8-32a9-4000-8c17-edc854c31231"
+	var groupName = "groupName"
+	var newGroupName = "newGroupName"
+	var attributes = map[string][]string{"costCenter": {"1234"}}
+	var group = kc.GroupRepresentation{ID: &groupID, Name: &groupName}
+	var renamedGroup = kc.GroupRepresentation{ID: &groupID, Name: &newGroupName}
+	var action = "MGMT_GetUsers"
+	var operators = "operators"
+	var rulesOnGroup = []configuration.Authorization{{RealmID: &realmName, GroupName: &operators, Action: &action,
+		TargetRealmID: &realmName, TargetGroupName: &groupName}}
+	var ctx = context.WithValue(context.Background(), cs.CtContextAccessToken, accessToken)
+	ctx = context.WithValue(ctx, cs.CtContextUsername, "username")
+
+	t.Run("Can't get group", func(t *testing.T) {
+		mockKeycloakClient.EXPECT().GetGroup(accessToken, realmName, groupID).Return(kc.GroupRepresentation{}, errors.New("error"))
+		mockLogger.EXPECT().Warn(ctx, "err", "error")
+		var err = managementComponent.UpdateGroup(ctx, realmName, groupID, api.GroupRepresentation{Name: &newGroupName})
+		assert.NotNil(t, err)
+	})
+	t.Run("Can't update group", func(t *testing.T) {
+		mockKeycloakClient.EXPECT().GetGroup(accessToken, realmName, groupID).Return(group, nil)
+		mockKeycloakClient.EXPECT().UpdateGroup(accessToken, realmName, groupID, renamedGroup).Return(errors.New("error"))
+		mockLogger.EXPECT().Warn(ctx, "err", "error")
+		var err = managementComponent.UpdateGroup(ctx, realmName, groupID, api.GroupRepresentation{Name: &newGroupName})
+		assert.NotNil(t, err)
+	})
+	t.Run("Update attributes only", func(t *testing.T) {
+		var updatedGroup = kc.GroupRepresentation{ID: &groupID, Name: &groupName, Attributes: &attributes}
+		mockKeycloakClient.EXPECT().GetGroup(accessToken, realmName, groupID).Return(group, nil)
+		mockKeycloakClient.EXPECT().UpdateGroup(accessToken, realmName, groupID, updatedGroup).Return(nil)
+		mockEventDBModule.EXPECT().ReportEvent(ctx, "API_GROUP_UPDATE", "back-office", database.CtEventRealmName, realmName, database.CtEventGroupID, groupID,
+			database.CtEventGroupName, groupName).Return(nil)
+		var err = managementComponent.UpdateGroup(ctx, realmName, groupID, api.GroupRepresentation{Attributes: &attributes})
+		assert.Nil(t, err)
+	})
+	t.Run("Rename group", func(t *testing.T) {
+		mockKeycloakClient.EXPECT().GetGroup(accessToken, realmName, groupID).Return(group, nil)
+		mockKeycloakClient.EXPECT().UpdateGroup(accessToken, realmName, groupID, renamedGroup).Return(nil)
+		mockConfigurationDBModule.EXPECT().GetAuthorizationsOnGroup(ctx, realmName, groupName).Return(rulesOnGroup, nil)
+		mockConfigurationDBModule.EXPECT().GetAuthorizations(ctx, realmName, operators).Return(rulesOnGroup, nil)
+		mockConfigurationDBModule.EXPECT().NewTransaction(ctx).Return(mockTransaction, nil)
+		mockConfigurationDBModule.EXPECT().RenameGroup(ctx, mockTransaction, realmName, groupName, newGroupName).Return(nil)
+		mockConfigurationDBModule.EXPECT().CreateAuthorizationsVersion(ctx, mockTransaction, gomock.Any()).DoAndReturn(func(_ context.Context, _ sqltypes.Transaction, version dto.AuthorizationsVersion) error {
+			assert.Equal(t, operators, version.GroupName)
+			assert.Contains(t, version.After[action][realmName], newGroupName)
+			assert.NotContains(t, version.After[action][realmName], groupName)
+			return nil
+		})
+		mockTransaction.EXPECT().Commit().Return(nil)
+		mockTransaction.EXPECT().Close()
+		mockEventDBModule.EXPECT().ReportEvent(ctx, "API_GROUP_UPDATE", "back-office", database.CtEventRealmName, realmName, database.CtEventGroupID, groupID,
+			database.CtEventGroupName, newGroupName).Return(nil)
+		var err = managementComponent.UpdateGroup(ctx, realmName, groupID, api.GroupRepresentation{Name: &newGroupName})
+		assert.Nil(t, err)
+	})
+	t.Run("Can't rename group in configuration: previous name is restored", func(t *testing.T) {
+		mockKeycloakClient.EXPECT().GetGroup(accessToken, realmName, groupID).Return(group, nil)
+		gomock.InOrder(
+			mockKeycloakClient.EXPECT().UpdateGroup(accessToken, realmName, groupID, renamedGroup).Return(nil),
+			mockConfigurationDBModule.EXPECT().GetAuthorizationsOnGroup(ctx, realmName, groupName).Return([]configuration.Authorization{}, nil),
+			mockConfigurationDBModule.EXPECT().NewTransaction(ctx).Return(mockTransaction, nil),
+			mockConfigurationDBModule.EXPECT().RenameGroup(ctx, mockTransaction, realmName, groupName, newGroupName).Return(errors.New("SQL error")),
+			mockTransaction.EXPECT().Close(),
+			mockKeycloakClient.EXPECT().UpdateGroup(accessToken, realmName, groupID, group).Return(nil),
+		)
+		mockLogger.EXPECT().Warn(ctx, "msg", gomock.Any(), "err", "SQL error", "realm", realmName, "group", groupName)
+		var err = managementComponent.UpdateGroup(ctx, realmName, groupID, api.GroupRepresentation{Name: &newGroupName})
+		assert.NotNil(t, err)
+	})
+	t.Run("Can't restore previous name", func(t *testing.T) {
+		mockKeycloakClient.EXPECT().GetGroup(accessToken, realmName, groupID).Return(group, nil)
+		mockKeycloakClient.EXPECT().UpdateGroup(accessToken, realmName, groupID, renamedGroup).Return(nil)
+		mockConfigurationDBModule.EXPECT().GetAuthorizationsOnGroup(ctx, realmName, groupName).Return(nil, errors.New("SQL error"))
+		mockKeycloakClient.EXPECT().UpdateGroup(accessToken, realmName, groupID, group).Return(errors.New("KC error"))
+		mockLogger.EXPECT().Warn(ctx, "msg", gomock.Any(), "err", "SQL error", "realm", realmName, "group", groupName)
+		mockLogger.EXPECT().Error(ctx, "msg", gomock.Any(), "err", "KC error", "realm", realmName, "group", groupName, "newName", newGroupName)
+		var err = managementComponent.UpdateGroup(ctx, realmName, groupID, api.GroupRepresentation{Name: &newGroupName})
+		assert.NotNil(t, err)
+	})
+}
+
+func TestCreateGroupChild(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+	var mockKeycloakClient = mock.NewKeycloakClient(mockCtrl)
+	var mockEventDBModule = mock.NewEventDBModule(mockCtrl)
+	var mockLogger = mock.NewLogger(mockCtrl)
+
+	var managementComponent = NewComponent(mockKeycloakClient, nil, mockEventDBModule, nil, nil, nil, nil, mockLogger)
+
+	var accessToken = "TOKEN=="
+	var realmName = "master"
+	var groupID = "41dbf4a8-32a9-4000-8c17-edc854c31231"
+	var childGroupID = "41dbf4a8-32a9-4000-8c17-edc854c31232"
+	var groupName = "child"
+	var location = "https://location.url/auth/admin/master/groups/" + childGroupID
+	var ctx = context.WithValue(context.Background(), cs.CtContextAccessToken, accessToken)
+
+	t.Run("Keycloak error", func(t *testing.T) {
+		mockKeycloakClient.EXPECT().CreateGroupChild(accessToken, realmName, groupID, kc.GroupRepresentation{Name: &groupName}).Return("", errors.New("error"))
+		mockLogger.EXPECT().Warn(ctx, "err", "error")
+		var _, err = managementComponent.CreateGroupChild(ctx, realmName, groupID, api.GroupRepresentation{Name: &groupName})
+		assert.NotNil(t, err)
+	})
+	t.Run("Success", func(t *testing.T) {
+		mockKeycloakClient.EXPECT().CreateGroupChild(accessToken, realmName, groupID, kc.GroupRepresentation{Name: &groupName}).Return(location, nil)
+		mockEventDBModule.EXPECT().ReportEvent(ctx, "API_GROUP_CREATION", "back-office", database.CtEventRealmName, realmName, database.CtEventGroupID, childGroupID,
+			database.CtEventGroupName, groupName).Return(nil)
+		var res, err = managementComponent.CreateGroupChild(ctx, realmName, groupID, api.GroupRepresentation{Name: &groupName})
+		assert.Nil(t, err)
+		assert.Equal(t, location, res)
+	})
+}
+
+func TestMoveGroup(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+	var mockKeycloakClient = mock.NewKeycloakClient(mockCtrl)
+	var mockEventDBModule = mock.NewEventDBModule(mockCtrl)
+	var mockLogger = mock.NewLogger(mockCtrl)
+
+	var managementComponent = NewComponent(mockKeycloakClient, nil, mockEventDBModule, nil, nil, nil, nil, mockLogger)
+
+	var accessToken = "TOKEN=="
+	var realmName = "master"
+	var groupID = "41dbf4a8-32a9-4000-8c17-edc854c31231"
+	var childGroupID = "41dbf4a8-32a9-4000-8c17-edc854c31232"
+	var childName = "child"
+	var attributes = map[string][]string{"costCenter": {"1234"}}
+	var child = kc.GroupRepresentation{ID: &childGroupID, Name: &childName, Attributes: &attributes}
+	var ctx = context.WithValue(context.Background(), cs.CtContextAccessToken, accessToken)
+
+	t.Run("Group moved into itself", func(t *testing.T) {
+		var err = managementComponent.MoveGroup(ctx, realmName, groupID, groupID)
+		assert.NotNil(t, err)
+	})
+	t.Run("Can't get moved group", func(t *testing.T) {
+		mockKeycloakClient.EXPECT().GetGroup(accessToken, realmName, childGroupID).Return(kc.GroupRepresentation{}, errors.New("error"))
+		mockLogger.EXPECT().Warn(ctx, "err", "error")
+		var err = managementComponent.MoveGroup(ctx, realmName, groupID, childGroupID)
+		assert.NotNil(t, err)
+	})
+	t.Run("Keycloak error", func(t *testing.T) {
+		mockKeycloakClient.EXPECT().GetGroup(accessToken, realmName, childGroupID).Return(child, nil)
+		mockKeycloakClient.EXPECT().CreateGroupChild(accessToken, realmName, groupID, gomock.Any()).Return("", errors.New("error"))
+		mockLogger.EXPECT().Warn(ctx, "err", "error")
+		var err = managementComponent.MoveGroup(ctx, realmName, groupID, childGroupID)
+		assert.NotNil(t, err)
+	})
+	t.Run("Success", func(t *testing.T) {
+		mockKeycloakClient.EXPECT().GetGroup(accessToken, realmName, childGroupID).Return(child, nil)
+		mockKeycloakClient.EXPECT().CreateGroupChild(accessToken, realmName, groupID, kc.GroupRepresentation{ID: &childGroupID, Name: &childName}).Return("", nil)
+		mockEventDBModule.EXPECT().ReportEvent(ctx, "API_GROUP_MOVE", "back-office", database.CtEventRealmName, realmName, database.CtEventGroupID, childGroupID,
+			database.CtEventGroupName, childName).Return(nil)
+		var err = managementComponent.MoveGroup(ctx, realmName, groupID, childGroupID)
+		assert.Nil(t, err)
+	})
+}
+
+func TestGetGroupMembers(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+	var mockKeycloakClient = mock.NewKeycloakClient(mockCtrl)
+	var mockLogger = mock.NewLogger(mockCtrl)
+
+	var managementComponent = NewComponent(mockKeycloakClient, nil, nil, nil, nil, nil, nil, mockLogger)
+
+	var accessToken = "TOKEN=="
+	var realmName = "master"
+	var groupID = "41dbf4a8-32a9-4000-8c17-edc854c31231"
+	var userID = "f467ed7c-0a1d-4eee-9bb8-669c6f89c0ee"
+	var username = "username"
+	var ctx = context.WithValue(context.Background(), cs.CtContextAccessToken, accessToken)
+
+	t.Run("Keycloak error", func(t *testing.T) {
+		mockKeycloakClient.EXPECT().GetGroupMembers(accessToken, realmName, groupID, "first", "0", "max", "10").Return(nil, errors.New("error"))
+		mockLogger.EXPECT().Warn(ctx, "err", "error")
+		var _, err = managementComponent.GetGroupMembers(ctx, realmName, groupID, "first", "0", "max", "10")
+		assert.NotNil(t, err)
+	})
+	t.Run("Success", func(t *testing.T) {
+		var members = []kc.UserRepresentation{{ID: &userID, Username: &username}}
+		mockKeycloakClient.EXPECT().GetGroupMembers(accessToken, realmName, groupID, "first", "0", "max", "10").Return(members, nil)
+		var res, err = managementComponent.GetGroupMembers(ctx, realmName, groupID, "first", "0", "max", "10")
+		assert.Nil(t, err)
+		assert.Len(t, res, 1)
+		assert.Equal(t, userID, *res[0].ID)
+	})
+}
+
 func TestGetAuthorizations(t *testing.T) {
 	var mockCtrl = gomock.NewController(t)
 	defer mockCtrl.Finish()
@@ -3110,7 +4128,7 @@ func TestGetAuthorizations(t *testing.T) {
 	var mockLogger = mock.NewLogger(mockCtrl)
 	var allowedTrustIDGroups = []string{"grp1", "grp2"}
 
-	var managementComponent = NewComponent(mockKeycloakClient, mockUsersDetailsDBModule, mockEventDBModule, mockConfigurationDBModule, allowedTrustIDGroups, mockLogger)
+	var managementComponent = NewComponent(mockKeycloakClient, mockUsersDetailsDBModule, mockEventDBModule, mockConfigurationDBModule, nil, allowedTrustIDGroups, nil, mockLogger)
 
 	var accessToken = "TOKEN=="
 	var realmName = "master"
@@ -3191,7 +4209,7 @@ func TestUpdateAuthorizations(t *testing.T) {
 	var mockLogger = mock.NewLogger(mockCtrl)
 	var allowedTrustIDGroups = []string{"grp1", "grp2"}
 
-	var managementComponent = NewComponent(mockKeycloakClient, mockUsersDetailsDBModule, mockEventDBModule, mockConfigurationDBModule, allowedTrustIDGroups, mockLogger)
+	var managementComponent = NewComponent(mockKeycloakClient, mockUsersDetailsDBModule, mockEventDBModule, mockConfigurationDBModule, nil, allowedTrustIDGroups, nil, mockLogger)
 
 	var accessToken = "TOKEN=="
 	var currentRealmName = "master"
@@ -3286,8 +4304,10 @@ func TestUpdateAuthorizations(t *testing.T) {
 		mockKeycloakClient.EXPECT().AssignClientRole(accessToken, targetRealmName, groupID, ID, gomock.Any()).Return(nil).Times(1)
 
 		mockConfigurationDBModule.EXPECT().NewTransaction(ctx).Return(mockTransaction, nil).Times(1)
-		mockConfigurationDBModule.EXPECT().DeleteAuthorizations(ctx, targetRealmName, groupName).Return(nil).Times(1)
-		mockConfigurationDBModule.EXPECT().CreateAuthorization(ctx, gomock.Any()).Return(nil).Times(1)
+		mockConfigurationDBModule.EXPECT().GetAuthorizations(ctx, targetRealmName, groupName).Return([]configuration.Authorization{}, nil).Times(1)
+		mockConfigurationDBModule.EXPECT().DeleteAuthorizations(ctx, mockTransaction, targetRealmName, groupName).Return(nil).Times(1)
+		mockConfigurationDBModule.EXPECT().CreateAuthorization(ctx, mockTransaction, gomock.Any()).Return(nil).Times(1)
+		mockConfigurationDBModule.EXPECT().CreateAuthorizationsVersion(ctx, mockTransaction, gomock.Any()).Return(nil).Times(1)
 		mockTransaction.EXPECT().Close().Times(1)
 		mockTransaction.EXPECT().Commit().Times(1)
 
@@ -3329,8 +4349,10 @@ func TestUpdateAuthorizations(t *testing.T) {
 		mockKeycloakClient.EXPECT().RemoveClientRole(accessToken, targetRealmName, groupID, ID, gomock.Any()).Return(nil).Times(1)
 
 		mockConfigurationDBModule.EXPECT().NewTransaction(ctx).Return(mockTransaction, nil).Times(1)
-		mockConfigurationDBModule.EXPECT().DeleteAuthorizations(ctx, targetRealmName, groupName).Return(nil).Times(1)
-		mockConfigurationDBModule.EXPECT().CreateAuthorization(ctx, gomock.Any()).Return(nil).Times(1)
+		mockConfigurationDBModule.EXPECT().GetAuthorizations(ctx, targetRealmName, groupName).Return([]configuration.Authorization{}, nil).Times(1)
+		mockConfigurationDBModule.EXPECT().DeleteAuthorizations(ctx, mockTransaction, targetRealmName, groupName).Return(nil).Times(1)
+		mockConfigurationDBModule.EXPECT().CreateAuthorization(ctx, mockTransaction, gomock.Any()).Return(nil).Times(1)
+		mockConfigurationDBModule.EXPECT().CreateAuthorizationsVersion(ctx, mockTransaction, gomock.Any()).Return(nil).Times(1)
 		mockTransaction.EXPECT().Close().Times(1)
 		mockTransaction.EXPECT().Commit().Times(1)
 
@@ -3442,7 +4464,7 @@ func TestUpdateAuthorizations(t *testing.T) {
 		mockKeycloakClient.EXPECT().AssignClientRole(accessToken, targetRealmName, groupID, ID, gomock.Any()).Return(nil).Times(1)
 		mockConfigurationDBModule.EXPECT().NewTransaction(ctx).Return(mockTransaction, nil).Times(1)
 		mockTransaction.EXPECT().Close().Times(1)
-		mockConfigurationDBModule.EXPECT().DeleteAuthorizations(ctx, targetRealmName, groupName).Return(fmt.Errorf("Unexpected error")).Times(1)
+		mockConfigurationDBModule.EXPECT().DeleteAuthorizations(ctx, mockTransaction, targetRealmName, groupName).Return(fmt.Errorf("Unexpected error")).Times(1)
 		mockLogger.EXPECT().Warn(ctx, "err", "Unexpected error").Times(1)
 		err = managementComponent.UpdateAuthorizations(ctx, targetRealmName, groupID, apiAuthorizations)
 		assert.NotNil(t, err)
@@ -3456,8 +4478,9 @@ func TestUpdateAuthorizations(t *testing.T) {
 		mockKeycloakClient.EXPECT().AssignClientRole(accessToken, targetRealmName, groupID, ID, gomock.Any()).Return(nil).Times(1)
 		mockConfigurationDBModule.EXPECT().NewTransaction(ctx).Return(mockTransaction, nil).Times(1)
 		mockTransaction.EXPECT().Close().Times(1)
-		mockConfigurationDBModule.EXPECT().DeleteAuthorizations(ctx, targetRealmName, groupName).Return(nil).Times(1)
-		mockConfigurationDBModule.EXPECT().CreateAuthorization(ctx, gomock.Any()).Return(fmt.Errorf("Unexpected error")).Times(1)
+		mockConfigurationDBModule.EXPECT().GetAuthorizations(ctx, targetRealmName, groupName).Return([]configuration.Authorization{}, nil).Times(1)
+		mockConfigurationDBModule.EXPECT().DeleteAuthorizations(ctx, mockTransaction, targetRealmName, groupName).Return(nil).Times(1)
+		mockConfigurationDBModule.EXPECT().CreateAuthorization(ctx, mockTransaction, gomock.Any()).Return(fmt.Errorf("Unexpected error")).Times(1)
 		mockLogger.EXPECT().Warn(ctx, "err", "Unexpected error").Times(1)
 		err = managementComponent.UpdateAuthorizations(ctx, targetRealmName, groupID, apiAuthorizations)
 		assert.NotNil(t, err)
@@ -3470,8 +4493,10 @@ func TestUpdateAuthorizations(t *testing.T) {
 		mockKeycloakClient.EXPECT().GetGroupClientRoles(accessToken, targetRealmName, groupID, ID).Return(rolesCurrent, nil).Times(1)
 		mockKeycloakClient.EXPECT().AssignClientRole(accessToken, targetRealmName, groupID, ID, gomock.Any()).Return(nil).Times(1)
 		mockConfigurationDBModule.EXPECT().NewTransaction(ctx).Return(mockTransaction, nil).Times(1)
-		mockConfigurationDBModule.EXPECT().DeleteAuthorizations(ctx, targetRealmName, groupName).Return(nil).Times(1)
-		mockConfigurationDBModule.EXPECT().CreateAuthorization(ctx, gomock.Any()).Return(nil).Times(1)
+		mockConfigurationDBModule.EXPECT().GetAuthorizations(ctx, targetRealmName, groupName).Return([]configuration.Authorization{}, nil).Times(1)
+		mockConfigurationDBModule.EXPECT().DeleteAuthorizations(ctx, mockTransaction, targetRealmName, groupName).Return(nil).Times(1)
+		mockConfigurationDBModule.EXPECT().CreateAuthorization(ctx, mockTransaction, gomock.Any()).Return(nil).Times(1)
+		mockConfigurationDBModule.EXPECT().CreateAuthorizationsVersion(ctx, mockTransaction, gomock.Any()).Return(nil).Times(1)
 		mockTransaction.EXPECT().Close().Times(1)
 		mockTransaction.EXPECT().Commit().Return(fmt.Errorf("Unexpected error")).Times(1)
 		mockLogger.EXPECT().Warn(ctx, "err", "Unexpected error").Times(1)
@@ -3486,8 +4511,10 @@ func TestUpdateAuthorizations(t *testing.T) {
 		mockKeycloakClient.EXPECT().GetGroupClientRoles(accessToken, targetRealmName, groupID, ID).Return(rolesCurrent, nil).Times(1)
 		mockKeycloakClient.EXPECT().AssignClientRole(accessToken, targetRealmName, groupID, ID, gomock.Any()).Return(nil).Times(1)
 		mockConfigurationDBModule.EXPECT().NewTransaction(ctx).Return(mockTransaction, nil).Times(1)
-		mockConfigurationDBModule.EXPECT().DeleteAuthorizations(ctx, targetRealmName, groupName).Return(nil).Times(1)
-		mockConfigurationDBModule.EXPECT().CreateAuthorization(ctx, gomock.Any()).Return(nil).Times(1)
+		mockConfigurationDBModule.EXPECT().GetAuthorizations(ctx, targetRealmName, groupName).Return([]configuration.Authorization{}, nil).Times(1)
+		mockConfigurationDBModule.EXPECT().DeleteAuthorizations(ctx, mockTransaction, targetRealmName, groupName).Return(nil).Times(1)
+		mockConfigurationDBModule.EXPECT().CreateAuthorization(ctx, mockTransaction, gomock.Any()).Return(nil).Times(1)
+		mockConfigurationDBModule.EXPECT().CreateAuthorizationsVersion(ctx, mockTransaction, gomock.Any()).Return(nil).Times(1)
 		mockTransaction.EXPECT().Close().Times(1)
 		mockTransaction.EXPECT().Commit().Times(1)
 
@@ -3549,116 +4576,516 @@ func TestUpdateAuthorizations(t *testing.T) {
 			Matrix: &matrix,
 		}
 
-		var ctx = context.WithValue(context.Background(), cs.CtContextAccessToken, accessToken)
-		ctx = context.WithValue(ctx, cs.CtContextRealm, realmName)
-		ctx = context.WithValue(ctx, cs.CtContextUsername, username)
+		var ctx = context.WithValue(context.Background(), cs.CtContextAccessToken, accessToken)
+		ctx = context.WithValue(ctx, cs.CtContextRealm, realmName)
+		ctx = context.WithValue(ctx, cs.CtContextUsername, username)
+
+		mockKeycloakClient.EXPECT().GetGroup(accessToken, targetRealmName, groupID).Return(group, nil).Times(1)
+		mockKeycloakClient.EXPECT().GetRealms(accessToken).Return(realms, nil).Times(1)
+		mockKeycloakClient.EXPECT().GetGroups(accessToken, targetRealmName).Return(groups, nil).Times(1)
+		mockLogger.EXPECT().Warn(ctx, "err", gomock.Any()).Times(1)
+		err := managementComponent.UpdateAuthorizations(ctx, targetRealmName, groupID, apiAuthorizations)
+		assert.NotNil(t, err)
+	})
+}
+
+func TestUpdateAuthorizationsWithAny(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+	var mockKeycloakClient = mock.NewKeycloakClient(mockCtrl)
+	var mockUsersDetailsDBModule = mock.NewUsersDetailsDBModule(mockCtrl)
+	var mockEventDBModule = mock.NewEventDBModule(mockCtrl)
+	var mockTransaction = mock.NewTransaction(mockCtrl)
+	var mockConfigurationDBModule = mock.NewConfigurationDBModule(mockCtrl)
+	var mockLogger = mock.NewLogger(mockCtrl)
+	var allowedTrustIDGroups = []string{"grp1", "grp2"}
+
+	var managementComponent = NewComponent(mockKeycloakClient, mockUsersDetailsDBModule, mockEventDBModule, mockConfigurationDBModule, nil, allowedTrustIDGroups, nil, mockLogger)
+
+	var accessToken = "TOKEN=="
+	var currentRealmName = "master"
+	var targetRealmName = "DEP"
+	var targetMasterRealmName = "master"
+	var groupID = "41dbf4a8-32a9-4000-8c17-edc854c31231"
+	var groupName = "groupName"
+	var username = "username"
+
+	var group = kc.GroupRepresentation{
+		ID:   &groupID,
+		Name: &groupName,
+	}
+	var groups = []kc.GroupRepresentation{}
+	var clients = []kc.ClientRepresentation{}
+
+	var ctx = context.WithValue(context.Background(), cs.CtContextAccessToken, accessToken)
+	ctx = context.WithValue(ctx, cs.CtContextRealm, currentRealmName)
+	ctx = context.WithValue(ctx, cs.CtContextUsername, username)
+
+	// Check * as target realm is forbidden for non master realm
+	{
+		var action = "action"
+		var matrix = map[string]map[string]map[string]struct{}{
+			action: {"*": {}},
+		}
+
+		var apiAuthorizations = api.AuthorizationsRepresentation{
+			Matrix: &matrix,
+		}
+
+		var realm = kc.RealmRepresentation{
+			ID:    &targetRealmName,
+			Realm: &targetRealmName,
+		}
+		var realms = []kc.RealmRepresentation{realm}
+
+		mockKeycloakClient.EXPECT().GetGroup(accessToken, targetRealmName, groupID).Return(group, nil).Times(1)
+		mockKeycloakClient.EXPECT().GetRealms(accessToken).Return(realms, nil).Times(1)
+		mockKeycloakClient.EXPECT().GetGroups(accessToken, targetRealmName).Return(groups, nil).Times(1)
+		mockLogger.EXPECT().Warn(ctx, "err", gomock.Any()).Times(1)
+
+		err := managementComponent.UpdateAuthorizations(ctx, targetRealmName, groupID, apiAuthorizations)
+
+		assert.NotNil(t, err)
+	}
+
+	// Check * as target realm is allowed for master realm
+	{
+		var action = "action"
+		var matrix = map[string]map[string]map[string]struct{}{
+			action: {"*": {}},
+		}
+
+		var apiAuthorizations = api.AuthorizationsRepresentation{
+			Matrix: &matrix,
+		}
+
+		var realm = kc.RealmRepresentation{
+			ID:    &targetMasterRealmName,
+			Realm: &targetMasterRealmName,
+		}
+		var realms = []kc.RealmRepresentation{realm}
+
+		var ctx = context.WithValue(context.Background(), cs.CtContextAccessToken, accessToken)
+		ctx = context.WithValue(ctx, cs.CtContextRealm, targetMasterRealmName)
+		ctx = context.WithValue(ctx, cs.CtContextUsername, username)
+
+		mockKeycloakClient.EXPECT().GetGroup(accessToken, targetMasterRealmName, groupID).Return(group, nil).Times(1)
+		mockKeycloakClient.EXPECT().GetRealms(accessToken).Return(realms, nil).Times(1)
+		mockKeycloakClient.EXPECT().GetGroups(accessToken, targetMasterRealmName).Return(groups, nil).Times(1)
+		mockKeycloakClient.EXPECT().GetClients(accessToken, targetMasterRealmName).Return(clients, nil).Times(1)
+
+		mockConfigurationDBModule.EXPECT().NewTransaction(ctx).Return(mockTransaction, nil).Times(1)
+		mockConfigurationDBModule.EXPECT().GetAuthorizations(ctx, targetMasterRealmName, groupName).Return([]configuration.Authorization{}, nil).Times(1)
+		mockConfigurationDBModule.EXPECT().DeleteAuthorizations(ctx, mockTransaction, targetMasterRealmName, groupName).Return(nil).Times(1)
+		mockConfigurationDBModule.EXPECT().CreateAuthorization(ctx, mockTransaction, gomock.Any()).Return(nil).Times(1)
+		mockConfigurationDBModule.EXPECT().CreateAuthorizationsVersion(ctx, mockTransaction, gomock.Any()).Return(nil).Times(1)
+		mockTransaction.EXPECT().Close().Times(1)
+		mockTransaction.EXPECT().Commit().Times(1)
+
+		mockEventDBModule.EXPECT().ReportEvent(ctx, "API_AUTHORIZATIONS_UPDATE", "back-office", gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).Times(1)
+
+		err := managementComponent.UpdateAuthorizations(ctx, targetMasterRealmName, groupID, apiAuthorizations)
+
+		assert.Nil(t, err)
+	}
+}
+
+func TestCheckAuthorization(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+	var mockKeycloakClient = mock.NewKeycloakClient(mockCtrl)
+	var mockConfigurationDBModule = mock.NewConfigurationDBModule(mockCtrl)
+
+	var managementComponent = NewComponent(mockKeycloakClient, nil, nil, mockConfigurationDBModule, nil, nil, nil, log.NewNopLogger())
+
+	var accessToken = "TOKEN=="
+	var realmName = "master"
+	var currentUserID = "41dbf4a8-32a9-4000-8c17-edc854c31231"
+	var otherUserID = "f467ed7c-0a1d-4eee-9bb8-669c6f89c0ee"
+	var action = MGMTGetUser.String()
+	var targetRealm = "DEP"
+	var targetGroup = "targetGroup"
+	var groupName = "operators"
+	var any = "*"
+	var ctx = context.WithValue(context.Background(), cs.CtContextAccessToken, accessToken)
+	ctx = context.WithValue(ctx, cs.CtContextRealm, realmName)
+	ctx = context.WithValue(ctx, cs.CtContextUserID, currentUserID)
+	ctx = context.WithValue(ctx, cs.CtContextGroups, []string{groupName})
+
+	t.Run("Missing target group for a group action", func(t *testing.T) {
+		var _, err = managementComponent.CheckAuthorization(ctx, realmName, "", action, targetRealm, "")
+		assert.NotNil(t, err)
+	})
+	t.Run("Unknown action", func(t *testing.T) {
+		var _, err = managementComponent.CheckAuthorization(ctx, realmName, "", "MGMT_Unknown", targetRealm, targetGroup)
+		assert.NotNil(t, err)
+		assert.Equal(t, http.StatusBadRequest, err.(errorhandler.Error).Status)
+	})
+	t.Run("Can't get groups of another user", func(t *testing.T) {
+		mockKeycloakClient.EXPECT().GetGroupsOfUser(accessToken, realmName, otherUserID).Return(nil, errors.New("error"))
+		var _, err = managementComponent.CheckAuthorization(ctx, realmName, otherUserID, action, targetRealm, targetGroup)
+		assert.NotNil(t, err)
+	})
+	t.Run("Can't get authorizations", func(t *testing.T) {
+		mockConfigurationDBModule.EXPECT().GetAuthorizations(ctx, realmName, groupName).Return(nil, errors.New("error"))
+		var _, err = managementComponent.CheckAuthorization(ctx, realmName, "", action, targetRealm, targetGroup)
+		assert.NotNil(t, err)
+	})
+	t.Run("Current user is denied", func(t *testing.T) {
+		mockConfigurationDBModule.EXPECT().GetAuthorizations(ctx, realmName, groupName).Return([]configuration.Authorization{}, nil)
+		var res, err = managementComponent.CheckAuthorization(ctx, realmName, "", action, "", targetGroup)
+		assert.Nil(t, err)
+		assert.Equal(t, currentUserID, *res.UserID)
+		assert.Equal(t, realmName, *res.TargetRealm)
+		assert.Equal(t, []string{groupName}, res.Groups)
+		assert.False(t, *res.Allowed)
+		assert.Len(t, res.MatchingRules, 0)
+		assert.Len(t, res.MissingRules, 1)
+		assert.Equal(t, groupName, *res.MissingRules[0].Group)
+		assert.Equal(t, targetGroup, *res.MissingRules[0].TargetGroup)
+	})
+	t.Run("Other user is allowed", func(t *testing.T) {
+		var authorizations = []configuration.Authorization{
+			{RealmID: &realmName, GroupName: &groupName, Action: &action, TargetRealmID: &any, TargetGroupName: &any},
+		}
+		mockKeycloakClient.EXPECT().GetGroupsOfUser(accessToken, realmName, otherUserID).Return([]kc.GroupRepresentation{{Name: &groupName}}, nil)
+		mockConfigurationDBModule.EXPECT().GetAuthorizations(ctx, realmName, groupName).Return(authorizations, nil)
+		var res, err = managementComponent.CheckAuthorization(ctx, realmName, otherUserID, action, targetRealm, targetGroup)
+		assert.Nil(t, err)
+		assert.Equal(t, otherUserID, *res.UserID)
+		assert.True(t, *res.Allowed)
+		assert.Len(t, res.MatchingRules, 1)
+		assert.Len(t, res.MissingRules, 0)
+	})
+}
+
+func TestAuthorizationsVersions(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+	var mockKeycloakClient = mock.NewKeycloakClient(mockCtrl)
+	var mockEventDBModule = mock.NewEventDBModule(mockCtrl)
+	var mockConfigurationDBModule = mock.NewConfigurationDBModule(mockCtrl)
+	var mockTransaction = mock.NewTransaction(mockCtrl)
+
+	var managementComponent = NewComponent(mockKeycloakClient, nil, mockEventDBModule, mockConfigurationDBModule, nil, nil, nil, log.NewNopLogger())
+
+	var accessToken = "TOKEN=="
+	var realmName = "DEP"
+	var groupID = "41dbf4a8-32a9-4000-8c17-edc854c31231"
+	var groupName = "operators"
+	var group = kc.GroupRepresentation{ID: &groupID, Name: &groupName}
+	var version1 = dto.AuthorizationsVersion{
+		RealmID: realmName, GroupName: groupName, Version: 1, Author: "admin", CreatedAt: 1600000000,
+		Before: map[string]map[string]map[string]struct{}{},
+		After:  map[string]map[string]map[string]struct{}{"MGMT_GetUsers": {realmName: {"*": {}}}},
+	}
+	var version2 = dto.AuthorizationsVersion{
+		RealmID: realmName, GroupName: groupName, Version: 2, Author: "other", CreatedAt: 1600000100,
+		Before: version1.After,
+		After:  map[string]map[string]map[string]struct{}{"MGMT_DeleteUser": {realmName: {"*": {}}}},
+	}
+	var kcError = errors.New("kc error")
+	var ctx = context.WithValue(context.Background(), cs.CtContextAccessToken, accessToken)
+	ctx = context.WithValue(ctx, cs.CtContextRealm, "master")
+	ctx = context.WithValue(ctx, cs.CtContextUsername, "admin")
+
+	t.Run("List versions: can't get group", func(t *testing.T) {
+		mockKeycloakClient.EXPECT().GetGroup(accessToken, realmName, groupID).Return(kc.GroupRepresentation{}, kcError)
+		var _, err = managementComponent.GetAuthorizationsVersions(ctx, realmName, groupID)
+		assert.Equal(t, kcError, err)
+	})
+	t.Run("List versions", func(t *testing.T) {
+		mockKeycloakClient.EXPECT().GetGroup(accessToken, realmName, groupID).Return(group, nil)
+		mockConfigurationDBModule.EXPECT().GetAuthorizationsVersions(ctx, realmName, groupName).Return([]dto.AuthorizationsVersion{version2, version1}, nil)
+		var res, err = managementComponent.GetAuthorizationsVersions(ctx, realmName, groupID)
+		assert.Nil(t, err)
+		assert.Len(t, res, 2)
+		assert.Equal(t, 2, *res[0].Version)
+		assert.Equal(t, "other", *res[0].Author)
+		assert.Equal(t, "MGMT_DeleteUser", *res[0].Added[0].Action)
+		assert.Equal(t, "MGMT_GetUsers", *res[0].Removed[0].Action)
+		assert.Equal(t, "admin", *res[1].Author)
+		assert.Len(t, res[1].Added, 1)
+		assert.Len(t, res[1].Removed, 0)
+	})
+	t.Run("Diff: unknown version", func(t *testing.T) {
+		var notFound = errors.New("not found")
+		mockKeycloakClient.EXPECT().GetGroup(accessToken, realmName, groupID).Return(group, nil)
+		mockConfigurationDBModule.EXPECT().GetAuthorizationsVersion(ctx, realmName, groupName, 1).Return(version1, nil)
+		mockConfigurationDBModule.EXPECT().GetAuthorizationsVersion(ctx, realmName, groupName, 3).Return(dto.AuthorizationsVersion{}, notFound)
+		var _, err = managementComponent.GetAuthorizationsVersionsDiff(ctx, realmName, groupID, 1, 3)
+		assert.Equal(t, notFound, err)
+	})
+	t.Run("Diff", func(t *testing.T) {
+		mockKeycloakClient.EXPECT().GetGroup(accessToken, realmName, groupID).Return(group, nil)
+		mockConfigurationDBModule.EXPECT().GetAuthorizationsVersion(ctx, realmName, groupName, 2).Return(version2, nil)
+		mockConfigurationDBModule.EXPECT().GetAuthorizationsVersion(ctx, realmName, groupName, 1).Return(version1, nil)
+		var res, err = managementComponent.GetAuthorizationsVersionsDiff(ctx, realmName, groupID, 2, 1)
+		assert.Nil(t, err)
+		assert.Equal(t, groupName, *res.Group)
+		assert.Equal(t, "MGMT_GetUsers", *res.Added[0].Action)
+		assert.Equal(t, "MGMT_DeleteUser", *res.Removed[0].Action)
+	})
+
+	var expectRollback = func() {
+		mockKeycloakClient.EXPECT().GetGroup(accessToken, realmName, groupID).Return(group, nil)
+		mockConfigurationDBModule.EXPECT().GetAuthorizationsVersion(ctx, realmName, groupName, 1).Return(version1, nil)
+		mockKeycloakClient.EXPECT().GetRealms(accessToken).Return([]kc.RealmRepresentation{{ID: &realmName}}, nil)
+		mockKeycloakClient.EXPECT().GetGroups(accessToken, realmName).Return([]kc.GroupRepresentation{group}, nil)
+		mockKeycloakClient.EXPECT().GetClients(accessToken, realmName).Return([]kc.ClientRepresentation{}, nil)
+		mockConfigurationDBModule.EXPECT().NewTransaction(ctx).Return(mockTransaction, nil)
+		mockConfigurationDBModule.EXPECT().GetAuthorizations(ctx, realmName, groupName).Return([]configuration.Authorization{}, nil)
+		mockConfigurationDBModule.EXPECT().DeleteAuthorizations(ctx, mockTransaction, realmName, groupName).Return(nil)
+		mockConfigurationDBModule.EXPECT().CreateAuthorization(ctx, mockTransaction, gomock.Any()).Return(nil)
+		mockTransaction.EXPECT().Close()
+	}
+	t.Run("Rollback: can't record the new version", func(t *testing.T) {
+		var dbError = errors.New("db error")
+		expectRollback()
+		mockConfigurationDBModule.EXPECT().CreateAuthorizationsVersion(ctx, mockTransaction, gomock.Any()).Return(dbError)
+		var err = managementComponent.RollbackAuthorizations(ctx, realmName, groupID, 1)
+		assert.Equal(t, dbError, err)
+	})
+	t.Run("Rollback", func(t *testing.T) {
+		expectRollback()
+		mockConfigurationDBModule.EXPECT().CreateAuthorizationsVersion(ctx, mockTransaction, gomock.Any()).DoAndReturn(func(_ context.Context, _ sqltypes.Transaction, version dto.AuthorizationsVersion) error {
+			assert.Equal(t, version1.After, version.After)
+			assert.Equal(t, "admin", version.Author)
+			return nil
+		})
+		mockTransaction.EXPECT().Commit().Return(nil)
+		mockEventDBModule.EXPECT().ReportEvent(ctx, "API_AUTHORIZATIONS_ROLLBACK", "back-office", database.CtEventRealmName, realmName,
+			database.CtEventGroupName, groupName, database.CtEventAdditionalInfo, gomock.Any()).Return(nil)
+		var err = managementComponent.RollbackAuthorizations(ctx, realmName, groupID, 1)
+		assert.Nil(t, err)
+	})
+}
+
+func TestAuthorizationTemplates(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+	var mockEventDBModule = mock.NewEventDBModule(mockCtrl)
+	var mockConfigurationDBModule = mock.NewConfigurationDBModule(mockCtrl)
+
+	var managementComponent = NewComponent(nil, nil, mockEventDBModule, mockConfigurationDBModule, nil, nil, nil, log.NewNopLogger())
 
-		mockKeycloakClient.EXPECT().GetGroup(accessToken, targetRealmName, groupID).Return(group, nil).Times(1)
-		mockKeycloakClient.EXPECT().GetRealms(accessToken).Return(realms, nil).Times(1)
-		mockKeycloakClient.EXPECT().GetGroups(accessToken, targetRealmName).Return(groups, nil).Times(1)
-		mockLogger.EXPECT().Warn(ctx, "err", gomock.Any()).Times(1)
-		err := managementComponent.UpdateAuthorizations(ctx, targetRealmName, groupID, apiAuthorizations)
-		assert.NotNil(t, err)
+	var templateName = "support"
+	var matrix = map[string]map[string]map[string]struct{}{"MGMT_GetUsers": {api.AuthorizationTemplateRealmPlaceholder: {"*": {}}}}
+	var template = dto.AuthorizationTemplate{Name: templateName, Matrix: matrix}
+	var dbError = errors.New("db error")
+	var ctx = context.WithValue(context.Background(), cs.CtContextRealm, "master")
+
+	t.Run("Get templates fails", func(t *testing.T) {
+		mockConfigurationDBModule.EXPECT().GetAuthorizationTemplates(ctx).Return(nil, dbError)
+		var _, err = managementComponent.GetAuthorizationTemplates(ctx)
+		assert.Equal(t, dbError, err)
+	})
+	t.Run("Get templates", func(t *testing.T) {
+		mockConfigurationDBModule.EXPECT().GetAuthorizationTemplates(ctx).Return([]dto.AuthorizationTemplate{template}, nil)
+		var res, err = managementComponent.GetAuthorizationTemplates(ctx)
+		assert.Nil(t, err)
+		assert.Len(t, res, 1)
+		assert.Equal(t, templateName, *res[0].Name)
+		assert.Equal(t, matrix, *res[0].Matrix)
+	})
+	t.Run("Get template fails", func(t *testing.T) {
+		mockConfigurationDBModule.EXPECT().GetAuthorizationTemplate(ctx, templateName).Return(dto.AuthorizationTemplate{}, dbError)
+		var _, err = managementComponent.GetAuthorizationTemplate(ctx, templateName)
+		assert.Equal(t, dbError, err)
+	})
+	t.Run("Get template", func(t *testing.T) {
+		mockConfigurationDBModule.EXPECT().GetAuthorizationTemplate(ctx, templateName).Return(template, nil)
+		var res, err = managementComponent.GetAuthorizationTemplate(ctx, templateName)
+		assert.Nil(t, err)
+		assert.Equal(t, templateName, *res.Name)
+	})
+	t.Run("Update template fails", func(t *testing.T) {
+		mockConfigurationDBModule.EXPECT().StoreOrUpdateAuthorizationTemplate(ctx, template).Return(dbError)
+		var err = managementComponent.UpdateAuthorizationTemplate(ctx, templateName, api.AuthorizationTemplateRepresentation{Matrix: &matrix})
+		assert.Equal(t, dbError, err)
+	})
+	t.Run("Update template", func(t *testing.T) {
+		mockConfigurationDBModule.EXPECT().StoreOrUpdateAuthorizationTemplate(ctx, template).Return(nil)
+		mockEventDBModule.EXPECT().ReportEvent(ctx, "API_AUTHORIZATION_TEMPLATE_UPDATE", "back-office", database.CtEventAdditionalInfo, gomock.Any()).Return(nil)
+		var err = managementComponent.UpdateAuthorizationTemplate(ctx, templateName, api.AuthorizationTemplateRepresentation{Matrix: &matrix})
+		assert.Nil(t, err)
+	})
+	t.Run("Delete template fails", func(t *testing.T) {
+		mockConfigurationDBModule.EXPECT().DeleteAuthorizationTemplate(ctx, templateName).Return(dbError)
+		var err = managementComponent.DeleteAuthorizationTemplate(ctx, templateName)
+		assert.Equal(t, dbError, err)
+	})
+	t.Run("Delete template", func(t *testing.T) {
+		mockConfigurationDBModule.EXPECT().DeleteAuthorizationTemplate(ctx, templateName).Return(nil)
+		mockEventDBModule.EXPECT().ReportEvent(ctx, "API_AUTHORIZATION_TEMPLATE_DELETION", "back-office", database.CtEventAdditionalInfo, gomock.Any()).Return(nil)
+		var err = managementComponent.DeleteAuthorizationTemplate(ctx, templateName)
+		assert.Nil(t, err)
 	})
 }
 
-func TestUpdateAuthorizationsWithAny(t *testing.T) {
+func TestApplyAuthorizationTemplate(t *testing.T) {
 	var mockCtrl = gomock.NewController(t)
 	defer mockCtrl.Finish()
 	var mockKeycloakClient = mock.NewKeycloakClient(mockCtrl)
-	var mockUsersDetailsDBModule = mock.NewUsersDetailsDBModule(mockCtrl)
 	var mockEventDBModule = mock.NewEventDBModule(mockCtrl)
-	var mockTransaction = mock.NewTransaction(mockCtrl)
 	var mockConfigurationDBModule = mock.NewConfigurationDBModule(mockCtrl)
-	var mockLogger = mock.NewLogger(mockCtrl)
-	var allowedTrustIDGroups = []string{"grp1", "grp2"}
+	var mockTransaction = mock.NewTransaction(mockCtrl)
 
-	var managementComponent = NewComponent(mockKeycloakClient, mockUsersDetailsDBModule, mockEventDBModule, mockConfigurationDBModule, allowedTrustIDGroups, mockLogger)
+	var managementComponent = NewComponent(mockKeycloakClient, nil, mockEventDBModule, mockConfigurationDBModule, nil, nil, nil, log.NewNopLogger())
 
 	var accessToken = "TOKEN=="
-	var currentRealmName = "master"
-	var targetRealmName = "DEP"
-	var targetMasterRealmName = "master"
+	var realmName = "DEP"
+	var otherRealm = "other"
 	var groupID = "41dbf4a8-32a9-4000-8c17-edc854c31231"
-	var groupName = "groupName"
-	var username = "username"
-
-	var group = kc.GroupRepresentation{
-		ID:   &groupID,
-		Name: &groupName,
+	var groupName = "operators"
+	var templateName = "support"
+	var action = "MGMT_GetUsers"
+	var group = kc.GroupRepresentation{ID: &groupID, Name: &groupName}
+	var template = dto.AuthorizationTemplate{
+		Name:   templateName,
+		Matrix: map[string]map[string]map[string]struct{}{action: {api.AuthorizationTemplateRealmPlaceholder: {"*": {}}}},
 	}
-	var groups = []kc.GroupRepresentation{}
-	var clients = []kc.ClientRepresentation{}
-
+	var kcError = errors.New("kc error")
 	var ctx = context.WithValue(context.Background(), cs.CtContextAccessToken, accessToken)
-	ctx = context.WithValue(ctx, cs.CtContextRealm, currentRealmName)
-	ctx = context.WithValue(ctx, cs.CtContextUsername, username)
-
-	// Check * as target realm is forbidden for non master realm
-	{
-		var action = "action"
-		var matrix = map[string]map[string]map[string]struct{}{
-			action: {"*": {}},
-		}
-
-		var apiAuthorizations = api.AuthorizationsRepresentation{
-			Matrix: &matrix,
-		}
-
-		var realm = kc.RealmRepresentation{
-			ID:    &targetRealmName,
-			Realm: &targetRealmName,
-		}
-		var realms = []kc.RealmRepresentation{realm}
-
-		mockKeycloakClient.EXPECT().GetGroup(accessToken, targetRealmName, groupID).Return(group, nil).Times(1)
-		mockKeycloakClient.EXPECT().GetRealms(accessToken).Return(realms, nil).Times(1)
-		mockKeycloakClient.EXPECT().GetGroups(accessToken, targetRealmName).Return(groups, nil).Times(1)
-		mockLogger.EXPECT().Warn(ctx, "err", gomock.Any()).Times(1)
-
-		err := managementComponent.UpdateAuthorizations(ctx, targetRealmName, groupID, apiAuthorizations)
+	ctx = context.WithValue(ctx, cs.CtContextRealm, "master")
+	ctx = context.WithValue(ctx, cs.CtContextUsername, "admin")
 
+	t.Run("Can't get group", func(t *testing.T) {
+		mockKeycloakClient.EXPECT().GetGroup(accessToken, realmName, groupID).Return(kc.GroupRepresentation{}, kcError)
+		var err = managementComponent.ApplyAuthorizationTemplate(ctx, realmName, groupID, templateName, "")
+		assert.Equal(t, kcError, err)
+	})
+	t.Run("Unknown template", func(t *testing.T) {
+		var notFound = errors.New("not found")
+		mockKeycloakClient.EXPECT().GetGroup(accessToken, realmName, groupID).Return(group, nil)
+		mockConfigurationDBModule.EXPECT().GetAuthorizationTemplate(ctx, templateName).Return(dto.AuthorizationTemplate{}, notFound)
+		var err = managementComponent.ApplyAuthorizationTemplate(ctx, realmName, groupID, templateName, "")
+		assert.Equal(t, notFound, err)
+	})
+	t.Run("Target realm does not exist", func(t *testing.T) {
+		mockKeycloakClient.EXPECT().GetGroup(accessToken, realmName, groupID).Return(group, nil)
+		mockConfigurationDBModule.EXPECT().GetAuthorizationTemplate(ctx, templateName).Return(template, nil)
+		mockKeycloakClient.EXPECT().GetRealms(accessToken).Return([]kc.RealmRepresentation{{ID: &realmName}}, nil)
+		mockKeycloakClient.EXPECT().GetGroups(accessToken, realmName).Return([]kc.GroupRepresentation{group}, nil)
+		var err = managementComponent.ApplyAuthorizationTemplate(ctx, realmName, groupID, templateName, otherRealm)
 		assert.NotNil(t, err)
-	}
-
-	// Check * as target realm is allowed for master realm
-	{
-		var action = "action"
-		var matrix = map[string]map[string]map[string]struct{}{
-			action: {"*": {}},
-		}
-
-		var apiAuthorizations = api.AuthorizationsRepresentation{
-			Matrix: &matrix,
-		}
-
-		var realm = kc.RealmRepresentation{
-			ID:    &targetMasterRealmName,
-			Realm: &targetMasterRealmName,
-		}
-		var realms = []kc.RealmRepresentation{realm}
+	})
+	t.Run("Placeholder replaced by the realm of the group", func(t *testing.T) {
+		mockKeycloakClient.EXPECT().GetGroup(accessToken, realmName, groupID).Return(group, nil)
+		mockConfigurationDBModule.EXPECT().GetAuthorizationTemplate(ctx, templateName).Return(template, nil)
+		mockKeycloakClient.EXPECT().GetRealms(accessToken).Return([]kc.RealmRepresentation{{ID: &realmName}}, nil)
+		mockKeycloakClient.EXPECT().GetGroups(accessToken, realmName).Return([]kc.GroupRepresentation{group}, nil)
+		mockKeycloakClient.EXPECT().GetClients(accessToken, realmName).Return([]kc.ClientRepresentation{}, nil)
+		mockConfigurationDBModule.EXPECT().NewTransaction(ctx).Return(mockTransaction, nil)
+		mockConfigurationDBModule.EXPECT().GetAuthorizations(ctx, realmName, groupName).Return([]configuration.Authorization{}, nil)
+		mockConfigurationDBModule.EXPECT().DeleteAuthorizations(ctx, mockTransaction, realmName, groupName).Return(nil)
+		mockConfigurationDBModule.EXPECT().CreateAuthorization(ctx, mockTransaction, gomock.Any()).DoAndReturn(func(_ context.Context, _ sqltypes.Transaction, authz configuration.Authorization) error {
+			assert.Equal(t, action, *authz.Action)
+			assert.Equal(t, realmName, *authz.TargetRealmID)
+			assert.Equal(t, "*", *authz.TargetGroupName)
+			return nil
+		})
+		mockConfigurationDBModule.EXPECT().CreateAuthorizationsVersion(ctx, mockTransaction, gomock.Any()).DoAndReturn(func(_ context.Context, _ sqltypes.Transaction, version dto.AuthorizationsVersion) error {
+			assert.Equal(t, "admin", version.Author)
+			assert.Len(t, version.Before, 0)
+			assert.Contains(t, version.After[action], realmName)
+			return nil
+		})
+		mockTransaction.EXPECT().Commit().Return(nil)
+		mockTransaction.EXPECT().Close()
+		mockEventDBModule.EXPECT().ReportEvent(ctx, "API_AUTHORIZATION_TEMPLATE_APPLICATION", "back-office", database.CtEventRealmName, realmName,
+			database.CtEventGroupName, groupName, database.CtEventAdditionalInfo, gomock.Any()).Return(nil)
+		var err = managementComponent.ApplyAuthorizationTemplate(ctx, realmName, groupID, templateName, "")
+		assert.Nil(t, err)
+	})
+}
 
-		var ctx = context.WithValue(context.Background(), cs.CtContextAccessToken, accessToken)
-		ctx = context.WithValue(ctx, cs.CtContextRealm, targetMasterRealmName)
-		ctx = context.WithValue(ctx, cs.CtContextUsername, username)
+func TestCopyAuthorizations(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+	var mockKeycloakClient = mock.NewKeycloakClient(mockCtrl)
+	var mockEventDBModule = mock.NewEventDBModule(mockCtrl)
+	var mockConfigurationDBModule = mock.NewConfigurationDBModule(mockCtrl)
+	var mockTransaction = mock.NewTransaction(mockCtrl)
 
-		mockKeycloakClient.EXPECT().GetGroup(accessToken, targetMasterRealmName, groupID).Return(group, nil).Times(1)
-		mockKeycloakClient.EXPECT().GetRealms(accessToken).Return(realms, nil).Times(1)
-		mockKeycloakClient.EXPECT().GetGroups(accessToken, targetMasterRealmName).Return(groups, nil).Times(1)
-		mockKeycloakClient.EXPECT().GetClients(accessToken, targetMasterRealmName).Return(clients, nil).Times(1)
+	var managementComponent = NewComponent(mockKeycloakClient, nil, mockEventDBModule, mockConfigurationDBModule, nil, nil, nil, log.NewNopLogger())
 
-		mockConfigurationDBModule.EXPECT().NewTransaction(ctx).Return(mockTransaction, nil).Times(1)
-		mockConfigurationDBModule.EXPECT().DeleteAuthorizations(ctx, targetMasterRealmName, groupName).Return(nil).Times(1)
-		mockConfigurationDBModule.EXPECT().CreateAuthorization(ctx, gomock.Any()).Return(nil).Times(1)
-		mockTransaction.EXPECT().Close().Times(1)
-		mockTransaction.EXPECT().Commit().Times(1)
+	var accessToken = "TOKEN=="
+	var sourceRealm = "source"
+	var targetRealm = "target"
+	var operators = "operators"
+	var auditors = "auditors"
+	var ghosts = "ghosts"
+	var operatorsID = "41dbf4a8-32a9-4000-8c17-edc854c31231"
+	var auditorsID = "f467ed7c-0a1d-4eee-9bb8-669c6f89c0ee"
+	var getUsers = "MGMT_GetUsers"
+	var deleteUser = "MGMT_DeleteUser"
+	var getActions = "MGMT_GetActions"
+	var any = "*"
+	var dbError = errors.New("db error")
+	var ctx = context.WithValue(context.Background(), cs.CtContextAccessToken, accessToken)
+	ctx = context.WithValue(ctx, cs.CtContextRealm, "master")
+	ctx = context.WithValue(ctx, cs.CtContextUsername, "admin")
 
-		mockEventDBModule.EXPECT().ReportEvent(ctx, "API_AUTHORIZATIONS_UPDATE", "back-office", gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).Times(1)
+	var sourceAuthorizations = []configuration.Authorization{
+		{RealmID: &sourceRealm, GroupName: &operators, Action: &getUsers, TargetRealmID: &sourceRealm, TargetGroupName: &any},
+		{RealmID: &sourceRealm, GroupName: &auditors, Action: &getActions},
+		{RealmID: &sourceRealm, GroupName: &ghosts, Action: &getUsers, TargetRealmID: &sourceRealm, TargetGroupName: &any},
+	}
+	var targetAuthorizations = []configuration.Authorization{
+		{RealmID: &targetRealm, GroupName: &operators, Action: &deleteUser, TargetRealmID: &targetRealm, TargetGroupName: &any},
+		{RealmID: &targetRealm, GroupName: &auditors, Action: &getActions},
+	}
+	var targetGroups = []kc.GroupRepresentation{{ID: &operatorsID, Name: &operators}, {ID: &auditorsID, Name: &auditors}}
 
-		err := managementComponent.UpdateAuthorizations(ctx, targetMasterRealmName, groupID, apiAuthorizations)
+	var expectDiff = func() {
+		mockConfigurationDBModule.EXPECT().GetRealmAuthorizations(ctx, sourceRealm).Return(sourceAuthorizations, nil)
+		mockConfigurationDBModule.EXPECT().GetRealmAuthorizations(ctx, targetRealm).Return(targetAuthorizations, nil)
+		mockKeycloakClient.EXPECT().GetGroups(accessToken, targetRealm).Return(targetGroups, nil).Times(2)
+		mockKeycloakClient.EXPECT().GetRealms(accessToken).Return([]kc.RealmRepresentation{{ID: &targetRealm}}, nil)
+	}
 
+	t.Run("Can't get authorizations of the source realm", func(t *testing.T) {
+		mockConfigurationDBModule.EXPECT().GetRealmAuthorizations(ctx, sourceRealm).Return(nil, dbError)
+		var _, err = managementComponent.CopyAuthorizations(ctx, sourceRealm, targetRealm, true)
+		assert.Equal(t, dbError, err)
+	})
+	t.Run("Dry run", func(t *testing.T) {
+		expectDiff()
+		var res, err = managementComponent.CopyAuthorizations(ctx, sourceRealm, targetRealm, true)
 		assert.Nil(t, err)
-	}
+		assert.True(t, *res.DryRun)
+		assert.Equal(t, []string{ghosts}, res.MissingGroups)
+		assert.Len(t, res.Groups, 1)
+		assert.Equal(t, operators, *res.Groups[0].Group)
+		assert.Len(t, res.Groups[0].Added, 1)
+		assert.Equal(t, getUsers, *res.Groups[0].Added[0].Action)
+		assert.Equal(t, targetRealm, *res.Groups[0].Added[0].TargetRealm)
+		assert.Len(t, res.Groups[0].Removed, 1)
+		assert.Equal(t, deleteUser, *res.Groups[0].Removed[0].Action)
+	})
+	t.Run("Copy", func(t *testing.T) {
+		expectDiff()
+		mockKeycloakClient.EXPECT().GetClients(accessToken, targetRealm).Return([]kc.ClientRepresentation{}, nil)
+		mockConfigurationDBModule.EXPECT().NewTransaction(ctx).Return(mockTransaction, nil)
+		mockConfigurationDBModule.EXPECT().GetAuthorizations(ctx, targetRealm, operators).Return([]configuration.Authorization{}, nil)
+		mockConfigurationDBModule.EXPECT().DeleteAuthorizations(ctx, mockTransaction, targetRealm, operators).Return(nil)
+		mockConfigurationDBModule.EXPECT().CreateAuthorization(ctx, mockTransaction, gomock.Any()).Return(nil)
+		mockConfigurationDBModule.EXPECT().CreateAuthorizationsVersion(ctx, mockTransaction, gomock.Any()).Return(nil)
+		mockTransaction.EXPECT().Commit().Return(nil)
+		mockTransaction.EXPECT().Close()
+		mockEventDBModule.EXPECT().ReportEvent(ctx, "API_AUTHORIZATIONS_COPY", "back-office", database.CtEventRealmName, targetRealm, database.CtEventAdditionalInfo, gomock.Any()).Return(nil)
+		var res, err = managementComponent.CopyAuthorizations(ctx, sourceRealm, targetRealm, false)
+		assert.Nil(t, err)
+		assert.False(t, *res.DryRun)
+		assert.Len(t, res.Groups, 1)
+	})
+	t.Run("Copy fails", func(t *testing.T) {
+		expectDiff()
+		mockKeycloakClient.EXPECT().GetClients(accessToken, targetRealm).Return(nil, errors.New("kc error"))
+		var _, err = managementComponent.CopyAuthorizations(ctx, sourceRealm, targetRealm, false)
+		assert.NotNil(t, err)
+	})
 }
 
 func TestGetClientRoles(t *testing.T) {
@@ -3671,7 +5098,7 @@ func TestGetClientRoles(t *testing.T) {
 	var mockLogger = log.NewNopLogger()
 	var allowedTrustIDGroups = []string{"grp1", "grp2"}
 
-	var managementComponent = NewComponent(mockKeycloakClient, mockUsersDetailsDBModule, mockEventDBModule, mockConfigurationDBModule, allowedTrustIDGroups, mockLogger)
+	var managementComponent = NewComponent(mockKeycloakClient, mockUsersDetailsDBModule, mockEventDBModule, mockConfigurationDBModule, nil, allowedTrustIDGroups, nil, mockLogger)
 
 	var accessToken = "TOKEN=="
 	var realmName = "master"
@@ -3736,7 +5163,7 @@ func TestCreateClientRole(t *testing.T) {
 	var mockLogger = log.NewNopLogger()
 	var allowedTrustIDGroups = []string{"grp1", "grp2"}
 
-	var managementComponent = NewComponent(mockKeycloakClient, mockUsersDetailsDBModule, mockEventDBModule, mockConfigurationDBModule, allowedTrustIDGroups, mockLogger)
+	var managementComponent = NewComponent(mockKeycloakClient, mockUsersDetailsDBModule, mockEventDBModule, mockConfigurationDBModule, nil, allowedTrustIDGroups, nil, mockLogger)
 
 	var accessToken = "TOKEN=="
 	var realmName = "master"
@@ -3775,6 +5202,9 @@ func TestCreateClientRole(t *testing.T) {
 			Description: &description,
 		}
 
+		mockEventDBModule.EXPECT().ReportEvent(ctx, "API_CLIENT_ROLE_CREATION", "back-office", database.CtEventRealmName, realmName,
+			database.CtEventAdditionalInfo, gomock.Any()).Return(nil).Times(1)
+
 		location, err := managementComponent.CreateClientRole(ctx, "master", clientID, roleRep)
 
 		assert.Nil(t, err)
@@ -3793,6 +5223,46 @@ func TestCreateClientRole(t *testing.T) {
 	}
 }
 
+func TestDeleteClientRole(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+	var mockKeycloakClient = mock.NewKeycloakClient(mockCtrl)
+	var mockEventDBModule = mock.NewEventDBModule(mockCtrl)
+
+	var managementComponent = NewComponent(mockKeycloakClient, nil, mockEventDBModule, nil, nil, nil, nil, log.NewNopLogger())
+
+	var accessToken = "TOKEN=="
+	var realmName = "master"
+	var clientID = "456-789-147"
+	var roleID = "1234-7454-4516"
+	var roleName = "role-name"
+	var clientRole = true
+	var realmRole = false
+	var ctx = context.WithValue(context.Background(), cs.CtContextAccessToken, accessToken)
+
+	var role = kc.RoleRepresentation{ID: &roleID, Name: &roleName, ClientRole: &clientRole, ContainerID: &clientID}
+
+	t.Run("Realm role", func(t *testing.T) {
+		mockKeycloakClient.EXPECT().GetRole(accessToken, realmName, roleID).Return(kc.RoleRepresentation{ID: &roleID, ClientRole: &realmRole}, nil)
+		var err = managementComponent.DeleteClientRole(ctx, realmName, clientID, roleID)
+		assert.NotNil(t, err)
+	})
+	t.Run("Keycloak error", func(t *testing.T) {
+		mockKeycloakClient.EXPECT().GetRole(accessToken, realmName, roleID).Return(role, nil)
+		mockKeycloakClient.EXPECT().DeleteRole(accessToken, realmName, roleID).Return(errors.New("error"))
+		var err = managementComponent.DeleteClientRole(ctx, realmName, clientID, roleID)
+		assert.NotNil(t, err)
+	})
+	t.Run("Success", func(t *testing.T) {
+		mockKeycloakClient.EXPECT().GetRole(accessToken, realmName, roleID).Return(role, nil)
+		mockKeycloakClient.EXPECT().DeleteRole(accessToken, realmName, roleID).Return(nil)
+		mockEventDBModule.EXPECT().ReportEvent(ctx, "API_CLIENT_ROLE_DELETION", "back-office", database.CtEventRealmName, realmName,
+			database.CtEventAdditionalInfo, database.CreateAdditionalInfo("client_id", clientID, "role_ids", roleID, "role_names", roleName)).Return(nil)
+		var err = managementComponent.DeleteClientRole(ctx, realmName, clientID, roleID)
+		assert.Nil(t, err)
+	})
+}
+
 func TestGetRealmCustomConfiguration(t *testing.T) {
 	var mockCtrl = gomock.NewController(t)
 	defer mockCtrl.Finish()
@@ -3803,7 +5273,7 @@ func TestGetRealmCustomConfiguration(t *testing.T) {
 	var mockLogger = log.NewNopLogger()
 	var allowedTrustIDGroups = []string{"grp1", "grp2"}
 
-	var managementComponent = NewComponent(mockKeycloakClient, mockUsersDetailsDBModule, mockEventDBModule, mockConfigurationDBModule, allowedTrustIDGroups, mockLogger)
+	var managementComponent = NewComponent(mockKeycloakClient, mockUsersDetailsDBModule, mockEventDBModule, mockConfigurationDBModule, nil, allowedTrustIDGroups, nil, mockLogger)
 
 	var accessToken = "TOKEN=="
 	var realmID = "master_id"
@@ -3837,7 +5307,7 @@ func TestGetRealmCustomConfiguration(t *testing.T) {
 		var ctx = context.WithValue(context.Background(), cs.CtContextAccessToken, accessToken)
 		mockConfigurationDBModule.EXPECT().GetConfiguration(ctx, realmID).Return(realmConfig, nil).Times(1)
 
-		configJSON, err := managementComponent.GetRealmCustomConfiguration(ctx, realmID)
+		configJSON, _, err := managementComponent.GetRealmCustomConfiguration(ctx, realmID)
 
 		assert.Nil(t, err)
 		assert.Equal(t, *configJSON.DefaultClientID, *realmConfig.DefaultClientID)
@@ -3865,7 +5335,7 @@ func TestGetRealmCustomConfiguration(t *testing.T) {
 		var ctx = context.WithValue(context.Background(), cs.CtContextAccessToken, accessToken)
 		mockConfigurationDBModule.EXPECT().GetConfiguration(ctx, realmID).Return(configuration.RealmConfiguration{}, errorhandler.Error{}).Times(1)
 
-		configJSON, err := managementComponent.GetRealmCustomConfiguration(ctx, realmID)
+		configJSON, _, err := managementComponent.GetRealmCustomConfiguration(ctx, realmID)
 
 		assert.Nil(t, err)
 		assert.Nil(t, configJSON.DefaultClientID)
@@ -3892,7 +5362,7 @@ func TestGetRealmCustomConfiguration(t *testing.T) {
 
 		var ctx = context.WithValue(context.Background(), cs.CtContextAccessToken, accessToken)
 
-		_, err := managementComponent.GetRealmCustomConfiguration(ctx, realmID)
+		_, _, err := managementComponent.GetRealmCustomConfiguration(ctx, realmID)
 
 		assert.NotNil(t, err)
 	}
@@ -3918,7 +5388,7 @@ func TestGetRealmCustomConfiguration(t *testing.T) {
 		var ctx = context.WithValue(context.Background(), cs.CtContextAccessToken, accessToken)
 		mockConfigurationDBModule.EXPECT().GetConfiguration(ctx, realmID).Return(configuration.RealmConfiguration{}, errors.New("error")).Times(1)
 
-		_, err := managementComponent.GetRealmCustomConfiguration(ctx, realmID)
+		_, _, err := managementComponent.GetRealmCustomConfiguration(ctx, realmID)
 
 		assert.NotNil(t, err)
 	}
@@ -3934,7 +5404,8 @@ func TestUpdateRealmCustomConfiguration(t *testing.T) {
 	var mockLogger = log.NewNopLogger()
 	var allowedTrustIDGroups = []string{"grp1", "grp2"}
 
-	var managementComponent = NewComponent(mockKeycloakClient, mockUsersDetailsDBModule, mockEventDBModule, mockConfigurationDBModule, allowedTrustIDGroups, mockLogger)
+	var mockTransaction = mock.NewTransaction(mockCtrl)
+	var managementComponent = NewComponent(mockKeycloakClient, mockUsersDetailsDBModule, mockEventDBModule, mockConfigurationDBModule, nil, allowedTrustIDGroups, nil, mockLogger)
 
 	var accessToken = "TOKEN=="
 	var realmID = "master_id"
@@ -3972,6 +5443,7 @@ func TestUpdateRealmCustomConfiguration(t *testing.T) {
 	}
 
 	var ctx = context.WithValue(context.Background(), cs.CtContextAccessToken, accessToken)
+	ctx = context.WithValue(ctx, cs.CtContextUsername, "admin")
 	var clientID = "clientID1"
 	var redirectURI = "https://www.cloudtrust.io/test"
 	var configInit = api.RealmCustomConfiguration{
@@ -3983,12 +5455,37 @@ func TestUpdateRealmCustomConfiguration(t *testing.T) {
 	{
 		mockKeycloakClient.EXPECT().GetRealm(accessToken, realmID).Return(kcRealmRep, nil).Times(1)
 		mockKeycloakClient.EXPECT().GetClients(accessToken, realmID).Return(clients, nil).Times(1)
-		mockConfigurationDBModule.EXPECT().StoreOrUpdateConfiguration(ctx, realmID, gomock.Any()).Return(nil).Times(1)
-		err := managementComponent.UpdateRealmCustomConfiguration(ctx, realmID, configInit)
+		mockConfigurationDBModule.EXPECT().NewTransaction(ctx).Return(mockTransaction, nil)
+		mockConfigurationDBModule.EXPECT().StoreOrUpdateConfiguration(ctx, mockTransaction, realmID, gomock.Any()).Return(nil).Times(1)
+		mockConfigurationDBModule.EXPECT().CreateRealmConfigurationVersion(ctx, mockTransaction, gomock.Any()).DoAndReturn(func(_ context.Context, _ sqltypes.Transaction, version dto.RealmConfigurationVersion) error {
+			assert.Equal(t, realmID, version.RealmID)
+			assert.Equal(t, keycloakb.RealmConfigurationTypeCustom, version.Type)
+			assert.Equal(t, "admin", version.Author)
+			assert.Contains(t, version.Configuration, `"default_client_id":"clientID1"`)
+			return nil
+		})
+		mockTransaction.EXPECT().Commit().Return(nil)
+		mockTransaction.EXPECT().Close()
+		mockEventDBModule.EXPECT().ReportEvent(ctx, "API_REALM_CUSTOM_CONFIGURATION_UPDATE", "back-office", database.CtEventRealmName, realmID).Return(nil)
+		err := managementComponent.UpdateRealmCustomConfiguration(ctx, realmID, configInit, "")
 
 		assert.Nil(t, err)
 	}
 
+	// Can't record the new version of the configuration
+	{
+		var dbError = errors.New("db error")
+		mockKeycloakClient.EXPECT().GetRealm(accessToken, realmID).Return(kcRealmRep, nil).Times(1)
+		mockKeycloakClient.EXPECT().GetClients(accessToken, realmID).Return(clients, nil).Times(1)
+		mockConfigurationDBModule.EXPECT().NewTransaction(ctx).Return(mockTransaction, nil)
+		mockConfigurationDBModule.EXPECT().StoreOrUpdateConfiguration(ctx, mockTransaction, realmID, gomock.Any()).Return(nil).Times(1)
+		mockConfigurationDBModule.EXPECT().CreateRealmConfigurationVersion(ctx, mockTransaction, gomock.Any()).Return(dbError)
+		mockTransaction.EXPECT().Close()
+		err := managementComponent.UpdateRealmCustomConfiguration(ctx, realmID, configInit, "")
+
+		assert.Equal(t, dbError, err)
+	}
+
 	// Update config with unknown client ID
 	{
 		mockKeycloakClient.EXPECT().GetRealm(accessToken, realmID).Return(kcRealmRep, nil).Times(1)
@@ -4000,7 +5497,7 @@ func TestUpdateRealmCustomConfiguration(t *testing.T) {
 			DefaultClientID:    &clientID,
 			DefaultRedirectURI: &redirectURI,
 		}
-		err := managementComponent.UpdateRealmCustomConfiguration(ctx, realmID, configInit)
+		err := managementComponent.UpdateRealmCustomConfiguration(ctx, realmID, configInit, "")
 
 		assert.NotNil(t, err)
 		assert.IsType(t, commonhttp.Error{}, err)
@@ -4019,7 +5516,7 @@ func TestUpdateRealmCustomConfiguration(t *testing.T) {
 			DefaultClientID:    &clientID,
 			DefaultRedirectURI: &redirectURI,
 		}
-		err := managementComponent.UpdateRealmCustomConfiguration(ctx, realmID, configInit)
+		err := managementComponent.UpdateRealmCustomConfiguration(ctx, realmID, configInit, "")
 
 		assert.NotNil(t, err)
 		assert.IsType(t, commonhttp.Error{}, err)
@@ -4038,7 +5535,7 @@ func TestUpdateRealmCustomConfiguration(t *testing.T) {
 			DefaultClientID:    &clientID,
 			DefaultRedirectURI: &redirectURI,
 		}
-		err := managementComponent.UpdateRealmCustomConfiguration(ctx, realmID, configInit)
+		err := managementComponent.UpdateRealmCustomConfiguration(ctx, realmID, configInit, "")
 
 		assert.NotNil(t, err)
 		assert.IsType(t, commonhttp.Error{}, err)
@@ -4050,7 +5547,7 @@ func TestUpdateRealmCustomConfiguration(t *testing.T) {
 	{
 		mockKeycloakClient.EXPECT().GetRealm(accessToken, realmID).Return(kcRealmRep, nil).Times(1)
 		mockKeycloakClient.EXPECT().GetClients(accessToken, realmID).Return([]kc.ClientRepresentation{}, errors.New("error")).Times(1)
-		err := managementComponent.UpdateRealmCustomConfiguration(ctx, realmID, configInit)
+		err := managementComponent.UpdateRealmCustomConfiguration(ctx, realmID, configInit, "")
 
 		assert.NotNil(t, err)
 	}
@@ -4058,7 +5555,7 @@ func TestUpdateRealmCustomConfiguration(t *testing.T) {
 	// error while calling GetRealm
 	{
 		mockKeycloakClient.EXPECT().GetRealm(accessToken, realmID).Return(kc.RealmRepresentation{}, errors.New("error")).Times(1)
-		err := managementComponent.UpdateRealmCustomConfiguration(ctx, realmID, configInit)
+		err := managementComponent.UpdateRealmCustomConfiguration(ctx, realmID, configInit, "")
 
 		assert.NotNil(t, err)
 	}
@@ -4083,25 +5580,63 @@ func TestGetRealmAdminConfiguration(t *testing.T) {
 	var apiAdminConfig = api.ConvertRealmAdminConfigurationFromDBStruct(dbAdminConfig)
 	var ctx = context.WithValue(context.TODO(), cs.CtContextAccessToken, accessToken)
 
-	var component = NewComponent(mockKeycloakClient, mockUsersDetailsDBModule, mockEventDBModule, mockConfigurationDBModule, allowedTrustIDGroups, logger)
+	var component = NewComponent(mockKeycloakClient, mockUsersDetailsDBModule, mockEventDBModule, mockConfigurationDBModule, nil, allowedTrustIDGroups, nil, logger)
 
 	t.Run("Request to Keycloak client fails", func(t *testing.T) {
 		mockKeycloakClient.EXPECT().GetRealm(accessToken, realmName).Return(kc.RealmRepresentation{}, expectedError)
-		var _, err = component.GetRealmAdminConfiguration(ctx, realmName)
+		var _, _, err = component.GetRealmAdminConfiguration(ctx, realmName)
 		assert.Equal(t, expectedError, err)
 	})
 	t.Run("Request to database fails", func(t *testing.T) {
 		mockKeycloakClient.EXPECT().GetRealm(accessToken, realmName).Return(kc.RealmRepresentation{ID: &realmID}, nil)
 		mockConfigurationDBModule.EXPECT().GetAdminConfiguration(ctx, gomock.Any()).Return(dbAdminConfig, expectedError)
-		var _, err = component.GetRealmAdminConfiguration(ctx, realmName)
+		var _, _, err = component.GetRealmAdminConfiguration(ctx, realmName)
+		assert.Equal(t, expectedError, err)
+	})
+	t.Run("Request to get trustID groups fails", func(t *testing.T) {
+		mockKeycloakClient.EXPECT().GetRealm(accessToken, realmName).Return(kc.RealmRepresentation{ID: &realmID}, nil)
+		mockConfigurationDBModule.EXPECT().GetAdminConfiguration(ctx, realmID).Return(dbAdminConfig, nil)
+		mockConfigurationDBModule.EXPECT().GetTrustIDGroups(ctx, realmID).Return(nil, expectedError)
+		var _, _, err = component.GetRealmAdminConfiguration(ctx, realmName)
 		assert.Equal(t, expectedError, err)
 	})
 	t.Run("Success", func(t *testing.T) {
 		mockKeycloakClient.EXPECT().GetRealm(accessToken, realmName).Return(kc.RealmRepresentation{ID: &realmID}, nil)
 		mockConfigurationDBModule.EXPECT().GetAdminConfiguration(ctx, realmID).Return(dbAdminConfig, nil)
-		var res, err = component.GetRealmAdminConfiguration(ctx, realmName)
+		mockConfigurationDBModule.EXPECT().GetTrustIDGroups(ctx, realmID).Return(nil, nil)
+		mockConfigurationDBModule.EXPECT().GetApprovalActions(ctx, realmID).Return(nil, nil)
+		var res, etag, err = component.GetRealmAdminConfiguration(ctx, realmName)
 		assert.Nil(t, err)
 		assert.Equal(t, apiAdminConfig, res)
+		var expectedETag, _ = keycloakb.ComputeETag(apiAdminConfig)
+		assert.Equal(t, expectedETag, etag)
+	})
+	t.Run("Request to get approval actions fails", func(t *testing.T) {
+		mockKeycloakClient.EXPECT().GetRealm(accessToken, realmName).Return(kc.RealmRepresentation{ID: &realmID}, nil)
+		mockConfigurationDBModule.EXPECT().GetAdminConfiguration(ctx, realmID).Return(dbAdminConfig, nil)
+		mockConfigurationDBModule.EXPECT().GetTrustIDGroups(ctx, realmID).Return(nil, nil)
+		mockConfigurationDBModule.EXPECT().GetApprovalActions(ctx, realmID).Return(nil, expectedError)
+		var _, _, err = component.GetRealmAdminConfiguration(ctx, realmName)
+		assert.Equal(t, expectedError, err)
+	})
+	t.Run("Success with approval actions", func(t *testing.T) {
+		mockKeycloakClient.EXPECT().GetRealm(accessToken, realmName).Return(kc.RealmRepresentation{ID: &realmID}, nil)
+		mockConfigurationDBModule.EXPECT().GetAdminConfiguration(ctx, realmID).Return(dbAdminConfig, nil)
+		mockConfigurationDBModule.EXPECT().GetTrustIDGroups(ctx, realmID).Return(nil, nil)
+		mockConfigurationDBModule.EXPECT().GetApprovalActions(ctx, realmID).Return([]string{"MGMT_DeleteUser"}, nil)
+		var res, _, err = component.GetRealmAdminConfiguration(ctx, realmName)
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"MGMT_DeleteUser"}, *res.ApprovalActions)
+	})
+	t.Run("Success with trustID groups configured for the realm", func(t *testing.T) {
+		mockKeycloakClient.EXPECT().GetRealm(accessToken, realmName).Return(kc.RealmRepresentation{ID: &realmID}, nil)
+		mockConfigurationDBModule.EXPECT().GetAdminConfiguration(ctx, realmID).Return(dbAdminConfig, sql.ErrNoRows)
+		mockConfigurationDBModule.EXPECT().GetTrustIDGroups(ctx, realmID).Return([]string{"grp3"}, nil)
+		mockConfigurationDBModule.EXPECT().GetApprovalActions(ctx, realmID).Return(nil, nil)
+		var res, _, err = component.GetRealmAdminConfiguration(ctx, realmName)
+		assert.Nil(t, err)
+		assert.Equal(t, "corporate", *res.Mode)
+		assert.Equal(t, []string{"grp3"}, *res.TrustIDGroups)
 	})
 }
 
@@ -4121,25 +5656,216 @@ func TestUpdateRealmAdminConfiguration(t *testing.T) {
 	var accessToken = "acce-ssto-ken"
 	var expectedError = errors.New("expectedError")
 	var ctx = context.WithValue(context.TODO(), cs.CtContextAccessToken, accessToken)
+	ctx = context.WithValue(ctx, cs.CtContextUsername, "admin")
 	var adminConfig api.RealmAdminConfiguration
+	var mockTransaction = mock.NewTransaction(mockCtrl)
 
-	var component = NewComponent(mockKeycloakClient, mockUsersDetailsDBModule, mockEventDBModule, mockConfigurationDBModule, allowedTrustIDGroups, logger)
+	var component = NewComponent(mockKeycloakClient, mockUsersDetailsDBModule, mockEventDBModule, mockConfigurationDBModule, nil, allowedTrustIDGroups, nil, logger)
+
+	var expectTransaction = func() {
+		mockConfigurationDBModule.EXPECT().NewTransaction(ctx).Return(mockTransaction, nil)
+		mockTransaction.EXPECT().Close()
+	}
+	var expectVersionAndCommit = func() {
+		mockConfigurationDBModule.EXPECT().CreateRealmConfigurationVersion(ctx, mockTransaction, gomock.Any()).Return(nil)
+		mockTransaction.EXPECT().Commit().Return(nil)
+		mockEventDBModule.EXPECT().ReportEvent(ctx, "API_REALM_ADMIN_CONFIGURATION_UPDATE", "back-office", database.CtEventRealmName, realmName).Return(nil)
+	}
 
 	t.Run("Request to Keycloak client fails", func(t *testing.T) {
 		mockKeycloakClient.EXPECT().GetRealm(accessToken, realmName).Return(kc.RealmRepresentation{}, expectedError)
-		var err = component.UpdateRealmAdminConfiguration(ctx, realmName, adminConfig)
+		var err = component.UpdateRealmAdminConfiguration(ctx, realmName, adminConfig, "")
 		assert.Equal(t, expectedError, err)
 	})
 	t.Run("Request to database fails", func(t *testing.T) {
 		mockKeycloakClient.EXPECT().GetRealm(accessToken, realmName).Return(kc.RealmRepresentation{ID: &realmID}, nil)
-		mockConfigurationDBModule.EXPECT().StoreOrUpdateAdminConfiguration(ctx, realmID, gomock.Any()).Return(expectedError)
-		var err = component.UpdateRealmAdminConfiguration(ctx, realmName, adminConfig)
+		expectTransaction()
+		mockConfigurationDBModule.EXPECT().StoreOrUpdateAdminConfiguration(ctx, mockTransaction, realmID, gomock.Any()).Return(expectedError)
+		var err = component.UpdateRealmAdminConfiguration(ctx, realmName, adminConfig, "")
+		assert.Equal(t, expectedError, err)
+	})
+	t.Run("Request to store trustID groups fails", func(t *testing.T) {
+		mockKeycloakClient.EXPECT().GetRealm(accessToken, realmName).Return(kc.RealmRepresentation{ID: &realmID}, nil)
+		expectTransaction()
+		mockConfigurationDBModule.EXPECT().StoreOrUpdateAdminConfiguration(ctx, mockTransaction, realmID, gomock.Any()).Return(nil)
+		mockConfigurationDBModule.EXPECT().StoreOrUpdateTrustIDGroups(ctx, mockTransaction, realmID, nil).Return(expectedError)
+		var err = component.UpdateRealmAdminConfiguration(ctx, realmName, adminConfig, "")
 		assert.Equal(t, expectedError, err)
 	})
 	t.Run("Success", func(t *testing.T) {
 		mockKeycloakClient.EXPECT().GetRealm(accessToken, realmName).Return(kc.RealmRepresentation{ID: &realmID}, nil)
-		mockConfigurationDBModule.EXPECT().StoreOrUpdateAdminConfiguration(ctx, realmID, gomock.Any()).Return(nil)
-		var err = component.UpdateRealmAdminConfiguration(ctx, realmName, adminConfig)
+		expectTransaction()
+		mockConfigurationDBModule.EXPECT().StoreOrUpdateAdminConfiguration(ctx, mockTransaction, realmID, gomock.Any()).Return(nil)
+		mockConfigurationDBModule.EXPECT().StoreOrUpdateTrustIDGroups(ctx, mockTransaction, realmID, nil).Return(nil)
+		mockConfigurationDBModule.EXPECT().StoreOrUpdateApprovalActions(ctx, mockTransaction, realmID, nil).Return(nil)
+		expectVersionAndCommit()
+		var err = component.UpdateRealmAdminConfiguration(ctx, realmName, adminConfig, "")
+		assert.Nil(t, err)
+	})
+	t.Run("Request to store approval actions fails", func(t *testing.T) {
+		var config = adminConfig
+		config.ApprovalActions = &[]string{"MGMT_DeleteUser"}
+		mockKeycloakClient.EXPECT().GetRealm(accessToken, realmName).Return(kc.RealmRepresentation{ID: &realmID}, nil)
+		expectTransaction()
+		mockConfigurationDBModule.EXPECT().StoreOrUpdateAdminConfiguration(ctx, mockTransaction, realmID, gomock.Any()).Return(nil)
+		mockConfigurationDBModule.EXPECT().StoreOrUpdateTrustIDGroups(ctx, mockTransaction, realmID, nil).Return(nil)
+		mockConfigurationDBModule.EXPECT().StoreOrUpdateApprovalActions(ctx, mockTransaction, realmID, []string{"MGMT_DeleteUser"}).Return(expectedError)
+		var err = component.UpdateRealmAdminConfiguration(ctx, realmName, config, "")
+		assert.Equal(t, expectedError, err)
+	})
+	t.Run("Can't record the new version of the configuration", func(t *testing.T) {
+		mockKeycloakClient.EXPECT().GetRealm(accessToken, realmName).Return(kc.RealmRepresentation{ID: &realmID}, nil)
+		expectTransaction()
+		mockConfigurationDBModule.EXPECT().StoreOrUpdateAdminConfiguration(ctx, mockTransaction, realmID, gomock.Any()).Return(nil)
+		mockConfigurationDBModule.EXPECT().StoreOrUpdateTrustIDGroups(ctx, mockTransaction, realmID, nil).Return(nil)
+		mockConfigurationDBModule.EXPECT().StoreOrUpdateApprovalActions(ctx, mockTransaction, realmID, nil).Return(nil)
+		mockConfigurationDBModule.EXPECT().CreateRealmConfigurationVersion(ctx, mockTransaction, gomock.Any()).Return(expectedError)
+		var err = component.UpdateRealmAdminConfiguration(ctx, realmName, adminConfig, "")
+		assert.Equal(t, expectedError, err)
+	})
+	t.Run("Configuration modified since it was read", func(t *testing.T) {
+		mockKeycloakClient.EXPECT().GetRealm(accessToken, realmName).Return(kc.RealmRepresentation{ID: &realmID}, nil)
+		mockConfigurationDBModule.EXPECT().GetAdminConfiguration(ctx, realmID).Return(configuration.RealmAdminConfiguration{}, sql.ErrNoRows)
+		mockConfigurationDBModule.EXPECT().GetTrustIDGroups(ctx, realmID).Return([]string{"grp3"}, nil)
+		mockConfigurationDBModule.EXPECT().GetApprovalActions(ctx, realmID).Return(nil, nil)
+		var err = component.UpdateRealmAdminConfiguration(ctx, realmName, adminConfig, `"outdated-etag"`)
+		assert.IsType(t, errorhandler.Error{}, err)
+		assert.Equal(t, http.StatusPreconditionFailed, err.(errorhandler.Error).Status)
+	})
+	t.Run("Success when the configuration did not change since it was read", func(t *testing.T) {
+		var currentConfig = api.CreateDefaultRealmAdminConfiguration()
+		var etag, _ = keycloakb.ComputeETag(currentConfig)
+		mockKeycloakClient.EXPECT().GetRealm(accessToken, realmName).Return(kc.RealmRepresentation{ID: &realmID}, nil)
+		mockConfigurationDBModule.EXPECT().GetAdminConfiguration(ctx, realmID).Return(configuration.RealmAdminConfiguration{}, sql.ErrNoRows)
+		mockConfigurationDBModule.EXPECT().GetTrustIDGroups(ctx, realmID).Return(nil, nil)
+		mockConfigurationDBModule.EXPECT().GetApprovalActions(ctx, realmID).Return(nil, nil)
+		expectTransaction()
+		mockConfigurationDBModule.EXPECT().StoreOrUpdateAdminConfiguration(ctx, mockTransaction, realmID, gomock.Any()).Return(nil)
+		mockConfigurationDBModule.EXPECT().StoreOrUpdateTrustIDGroups(ctx, mockTransaction, realmID, nil).Return(nil)
+		mockConfigurationDBModule.EXPECT().StoreOrUpdateApprovalActions(ctx, mockTransaction, realmID, nil).Return(nil)
+		expectVersionAndCommit()
+		var err = component.UpdateRealmAdminConfiguration(ctx, realmName, adminConfig, etag)
+		assert.Nil(t, err)
+	})
+	t.Run("Success with trustID groups", func(t *testing.T) {
+		var config = adminConfig
+		config.TrustIDGroups = &[]string{"grp3"}
+		mockKeycloakClient.EXPECT().GetRealm(accessToken, realmName).Return(kc.RealmRepresentation{ID: &realmID}, nil)
+		expectTransaction()
+		mockConfigurationDBModule.EXPECT().StoreOrUpdateAdminConfiguration(ctx, mockTransaction, realmID, gomock.Any()).Return(nil)
+		mockConfigurationDBModule.EXPECT().StoreOrUpdateTrustIDGroups(ctx, mockTransaction, realmID, []string{"grp3"}).Return(nil)
+		mockConfigurationDBModule.EXPECT().StoreOrUpdateApprovalActions(ctx, mockTransaction, realmID, nil).Return(nil)
+		expectVersionAndCommit()
+		var err = component.UpdateRealmAdminConfiguration(ctx, realmName, config, "")
+		assert.Nil(t, err)
+	})
+}
+
+func TestRealmConfigurationVersions(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+	var mockKeycloakClient = mock.NewKeycloakClient(mockCtrl)
+	var mockEventDBModule = mock.NewEventDBModule(mockCtrl)
+	var mockConfigurationDBModule = mock.NewConfigurationDBModule(mockCtrl)
+	var mockTransaction = mock.NewTransaction(mockCtrl)
+
+	var managementComponent = NewComponent(mockKeycloakClient, nil, mockEventDBModule, mockConfigurationDBModule, nil, nil, nil, log.NewNopLogger())
+
+	var accessToken = "TOKEN=="
+	var realmName = "DEP"
+	var realmID = "1234-5678"
+	var version1 = dto.RealmConfigurationVersion{
+		RealmID: realmID, Type: keycloakb.RealmConfigurationTypeCustom, Version: 1, Author: "admin", CreatedAt: 1600000000,
+		Configuration: `{"barcode_type":"CODE128","register_execute_actions":["ctpasswordset"],"show_profile_tab":true}`,
+	}
+	var version2 = dto.RealmConfigurationVersion{
+		RealmID: realmID, Type: keycloakb.RealmConfigurationTypeCustom, Version: 2, Author: "other", CreatedAt: 1600000100,
+		Configuration: `{"barcode_type":"CODE128","register_execute_actions":[],"show_profile_tab":true,"show_password_tab":false}`,
+	}
+	var kcError = errors.New("kc error")
+	var ctx = context.WithValue(context.Background(), cs.CtContextAccessToken, accessToken)
+	ctx = context.WithValue(ctx, cs.CtContextUsername, "admin")
+
+	t.Run("List versions: can't get realm", func(t *testing.T) {
+		mockKeycloakClient.EXPECT().GetRealm(accessToken, realmName).Return(kc.RealmRepresentation{}, kcError)
+		var _, err = managementComponent.GetRealmCustomConfigurationVersions(ctx, realmName)
+		assert.Equal(t, kcError, err)
+	})
+	t.Run("List versions", func(t *testing.T) {
+		mockKeycloakClient.EXPECT().GetRealm(accessToken, realmName).Return(kc.RealmRepresentation{ID: &realmID}, nil)
+		mockConfigurationDBModule.EXPECT().GetRealmConfigurationVersions(ctx, realmID, keycloakb.RealmConfigurationTypeAdmin).Return([]dto.RealmConfigurationVersion{version2, version1}, nil)
+		var res, err = managementComponent.GetRealmAdminConfigurationVersions(ctx, realmName)
+		assert.Nil(t, err)
+		assert.Len(t, res, 2)
+		assert.Equal(t, 2, *res[0].Version)
+		assert.Equal(t, "other", *res[0].Author)
+		assert.Equal(t, version1.Configuration, string(*res[1].Configuration))
+	})
+	t.Run("Diff: unknown version", func(t *testing.T) {
+		var notFound = errors.New("not found")
+		mockKeycloakClient.EXPECT().GetRealm(accessToken, realmName).Return(kc.RealmRepresentation{ID: &realmID}, nil)
+		mockConfigurationDBModule.EXPECT().GetRealmConfigurationVersion(ctx, realmID, keycloakb.RealmConfigurationTypeCustom, 1).Return(version1, nil)
+		mockConfigurationDBModule.EXPECT().GetRealmConfigurationVersion(ctx, realmID, keycloakb.RealmConfigurationTypeCustom, 3).Return(dto.RealmConfigurationVersion{}, notFound)
+		var _, err = managementComponent.GetRealmCustomConfigurationVersionsDiff(ctx, realmName, 1, 3)
+		assert.Equal(t, notFound, err)
+	})
+	t.Run("Diff", func(t *testing.T) {
+		mockKeycloakClient.EXPECT().GetRealm(accessToken, realmName).Return(kc.RealmRepresentation{ID: &realmID}, nil)
+		mockConfigurationDBModule.EXPECT().GetRealmConfigurationVersion(ctx, realmID, keycloakb.RealmConfigurationTypeCustom, 1).Return(version1, nil)
+		mockConfigurationDBModule.EXPECT().GetRealmConfigurationVersion(ctx, realmID, keycloakb.RealmConfigurationTypeCustom, 2).Return(version2, nil)
+		var res, err = managementComponent.GetRealmCustomConfigurationVersionsDiff(ctx, realmName, 1, 2)
+		assert.Nil(t, err)
+		assert.Len(t, res.Changes, 2)
+		assert.Equal(t, "register_execute_actions", *res.Changes[0].Field)
+		assert.Equal(t, `["ctpasswordset"]`, string(*res.Changes[0].OldValue))
+		assert.Equal(t, `[]`, string(*res.Changes[0].NewValue))
+		assert.Equal(t, "show_password_tab", *res.Changes[1].Field)
+		assert.Equal(t, `null`, string(*res.Changes[1].OldValue))
+		assert.Equal(t, `false`, string(*res.Changes[1].NewValue))
+	})
+
+	t.Run("Rollback: version is not valid anymore", func(t *testing.T) {
+		var invalid = version1
+		invalid.Configuration = `{"barcode_type":"UNKNOWN"}`
+		mockKeycloakClient.EXPECT().GetRealm(accessToken, realmName).Return(kc.RealmRepresentation{ID: &realmID}, nil)
+		mockConfigurationDBModule.EXPECT().GetRealmConfigurationVersion(ctx, realmID, keycloakb.RealmConfigurationTypeCustom, 1).Return(invalid, nil)
+		var err = managementComponent.RollbackRealmCustomConfiguration(ctx, realmName, 1)
+		assert.NotNil(t, err)
+	})
+	t.Run("Rollback custom configuration", func(t *testing.T) {
+		mockKeycloakClient.EXPECT().GetRealm(accessToken, realmName).Return(kc.RealmRepresentation{ID: &realmID}, nil)
+		mockConfigurationDBModule.EXPECT().GetRealmConfigurationVersion(ctx, realmID, keycloakb.RealmConfigurationTypeCustom, 1).Return(version1, nil)
+		mockKeycloakClient.EXPECT().GetClients(accessToken, realmName).Return([]kc.ClientRepresentation{}, nil)
+		mockConfigurationDBModule.EXPECT().NewTransaction(ctx).Return(mockTransaction, nil)
+		mockConfigurationDBModule.EXPECT().StoreOrUpdateConfiguration(ctx, mockTransaction, realmID, gomock.Any()).DoAndReturn(func(_ context.Context, _ sqltypes.Transaction, _ string, config configuration.RealmConfiguration) error {
+			assert.Equal(t, []string{"ctpasswordset"}, *config.RegisterExecuteActions)
+			return nil
+		})
+		mockConfigurationDBModule.EXPECT().CreateRealmConfigurationVersion(ctx, mockTransaction, gomock.Any()).Return(nil)
+		mockTransaction.EXPECT().Commit().Return(nil)
+		mockTransaction.EXPECT().Close()
+		mockEventDBModule.EXPECT().ReportEvent(ctx, "API_REALM_CUSTOM_CONFIGURATION_ROLLBACK", "back-office", database.CtEventRealmName, realmName,
+			database.CtEventAdditionalInfo, gomock.Any()).Return(nil)
+		var err = managementComponent.RollbackRealmCustomConfiguration(ctx, realmName, 1)
+		assert.Nil(t, err)
+	})
+	t.Run("Rollback admin configuration", func(t *testing.T) {
+		var adminVersion = dto.RealmConfigurationVersion{
+			RealmID: realmID, Type: keycloakb.RealmConfigurationTypeAdmin, Version: 4, Author: "admin", CreatedAt: 1600000000,
+			Configuration: `{"mode":"trustID","trustid-groups":["grp3"]}`,
+		}
+		mockKeycloakClient.EXPECT().GetRealm(accessToken, realmName).Return(kc.RealmRepresentation{ID: &realmID}, nil)
+		mockConfigurationDBModule.EXPECT().GetRealmConfigurationVersion(ctx, realmID, keycloakb.RealmConfigurationTypeAdmin, 4).Return(adminVersion, nil)
+		mockConfigurationDBModule.EXPECT().NewTransaction(ctx).Return(mockTransaction, nil)
+		mockConfigurationDBModule.EXPECT().StoreOrUpdateAdminConfiguration(ctx, mockTransaction, realmID, gomock.Any()).Return(nil)
+		mockConfigurationDBModule.EXPECT().StoreOrUpdateTrustIDGroups(ctx, mockTransaction, realmID, []string{"grp3"}).Return(nil)
+		mockConfigurationDBModule.EXPECT().StoreOrUpdateApprovalActions(ctx, mockTransaction, realmID, nil).Return(nil)
+		mockConfigurationDBModule.EXPECT().CreateRealmConfigurationVersion(ctx, mockTransaction, gomock.Any()).Return(nil)
+		mockTransaction.EXPECT().Commit().Return(nil)
+		mockTransaction.EXPECT().Close()
+		mockEventDBModule.EXPECT().ReportEvent(ctx, "API_REALM_ADMIN_CONFIGURATION_ROLLBACK", "back-office", database.CtEventRealmName, realmName,
+			database.CtEventAdditionalInfo, gomock.Any()).Return(nil)
+		var err = managementComponent.RollbackRealmAdminConfiguration(ctx, realmName, 4)
 		assert.Nil(t, err)
 	})
 }
@@ -4160,7 +5886,7 @@ func TestRealmBackOfficeConfiguration(t *testing.T) {
 	var mockLogger = log.NewNopLogger()
 	var allowedTrustIDGroups = []string{"grp1", "grp2"}
 
-	var component = NewComponent(mockKeycloakClient, mockUsersDetailsDBModule, mockEventDBModule, mockConfigurationDBModule, allowedTrustIDGroups, mockLogger)
+	var component = NewComponent(mockKeycloakClient, mockUsersDetailsDBModule, mockEventDBModule, mockConfigurationDBModule, nil, allowedTrustIDGroups, nil, mockLogger)
 
 	var realmID = "master_id"
 	var groupName = "the.group"
@@ -4261,7 +5987,7 @@ func TestLinkShadowUser(t *testing.T) {
 	var mockLogger = mock.NewLogger(mockCtrl)
 	var allowedTrustIDGroups = []string{"grp1", "grp2"}
 
-	var managementComponent = NewComponent(mockKeycloakClient, mockUsersDetailsDBModule, mockEventDBModule, mockConfigurationDBModule, allowedTrustIDGroups, mockLogger)
+	var managementComponent = NewComponent(mockKeycloakClient, mockUsersDetailsDBModule, mockEventDBModule, mockConfigurationDBModule, nil, allowedTrustIDGroups, nil, mockLogger)
 
 	var accessToken = "TOKEN=="
 	var username = "test"
@@ -4280,6 +6006,7 @@ func TestLinkShadowUser(t *testing.T) {
 		ctx = context.WithValue(ctx, cs.CtContextRealm, realmName)
 		ctx = context.WithValue(ctx, cs.CtContextUsername, username)
 
+		mockEventDBModule.EXPECT().ReportEvent(ctx, "API_FEDERATED_IDENTITY_LINK", "back-office", gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil)
 		err := managementComponent.LinkShadowUser(ctx, realmName, userID, provider, fedID)
 
 		assert.Nil(t, err)
@@ -4302,3 +6029,59 @@ func TestLinkShadowUser(t *testing.T) {
 		assert.NotNil(t, err)
 	})
 }
+
+func TestFederatedIdentities(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+	var mockKeycloakClient = mock.NewKeycloakClient(mockCtrl)
+	var mockUsersDetailsDBModule = mock.NewUsersDetailsDBModule(mockCtrl)
+	var mockEventDBModule = mock.NewEventDBModule(mockCtrl)
+	var mockConfigurationDBModule = mock.NewConfigurationDBModule(mockCtrl)
+
+	var managementComponent = NewComponent(mockKeycloakClient, mockUsersDetailsDBModule, mockEventDBModule, mockConfigurationDBModule, nil, nil, nil, log.NewNopLogger())
+
+	var accessToken = "TOKEN=="
+	var realmName = "master"
+	var userID = "41dbf4a8-32a9-4000-8c17-edc854c31231"
+	var provider = "provider"
+	var fedUserID = "fed-user-id"
+	var fedUsername = "fed-username"
+	var fedIDsKC = []kc.FederatedIdentityRepresentation{{IdentityProvider: &provider, UserID: &fedUserID, UserName: &fedUsername}}
+	var anyError = errors.New("error")
+	var ctx = context.WithValue(context.Background(), cs.CtContextAccessToken, accessToken)
+
+	t.Run("Get federated identities - error at KC client", func(t *testing.T) {
+		mockKeycloakClient.EXPECT().GetFederatedIdentities(accessToken, realmName, userID).Return(nil, anyError)
+		var _, err = managementComponent.GetFederatedIdentities(ctx, realmName, userID)
+		assert.Equal(t, anyError, err)
+	})
+	t.Run("Get federated identities successfully", func(t *testing.T) {
+		mockKeycloakClient.EXPECT().GetFederatedIdentities(accessToken, realmName, userID).Return(fedIDsKC, nil)
+		var res, err = managementComponent.GetFederatedIdentities(ctx, realmName, userID)
+		assert.Nil(t, err)
+		assert.Len(t, res, 1)
+		assert.Equal(t, provider, *res[0].IdentityProvider)
+		assert.Equal(t, fedUsername, *res[0].Username)
+	})
+
+	t.Run("Unlink shadow user - can't get federated identities", func(t *testing.T) {
+		mockKeycloakClient.EXPECT().GetFederatedIdentities(accessToken, realmName, userID).Return(nil, anyError)
+		assert.Equal(t, anyError, managementComponent.UnlinkShadowUser(ctx, realmName, userID, provider))
+	})
+	t.Run("Unlink shadow user - user not linked to provider", func(t *testing.T) {
+		mockKeycloakClient.EXPECT().GetFederatedIdentities(accessToken, realmName, userID).Return(fedIDsKC, nil)
+		var err = managementComponent.UnlinkShadowUser(ctx, realmName, userID, "other-provider")
+		assert.IsType(t, errorhandler.Error{}, err)
+	})
+	t.Run("Unlink shadow user - error at KC client", func(t *testing.T) {
+		mockKeycloakClient.EXPECT().GetFederatedIdentities(accessToken, realmName, userID).Return(fedIDsKC, nil)
+		mockKeycloakClient.EXPECT().UnlinkShadowUser(accessToken, realmName, userID, provider).Return(anyError)
+		assert.Equal(t, anyError, managementComponent.UnlinkShadowUser(ctx, realmName, userID, provider))
+	})
+	t.Run("Unlink shadow user successfully", func(t *testing.T) {
+		mockKeycloakClient.EXPECT().GetFederatedIdentities(accessToken, realmName, userID).Return(fedIDsKC, nil)
+		mockKeycloakClient.EXPECT().UnlinkShadowUser(accessToken, realmName, userID, provider).Return(nil)
+		mockEventDBModule.EXPECT().ReportEvent(ctx, "API_FEDERATED_IDENTITY_UNLINK", "back-office", gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil)
+		assert.Nil(t, managementComponent.UnlinkShadowUser(ctx, realmName, userID, provider))
+	})
+}
@@ -0,0 +1,365 @@
+package management
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	cs "github.com/cloudtrust/common-service"
+	"github.com/cloudtrust/common-service/database"
+	errorhandler "github.com/cloudtrust/common-service/errors"
+	api "github.com/cloudtrust/keycloak-bridge/api/management"
+	"github.com/cloudtrust/keycloak-bridge/internal/constants"
+	kc "github.com/cloudtrust/keycloak-client"
+)
+
+// A value shared by more users than this (a team mailbox, a switchboard number) does not identify a person
+const duplicateUsersMaxSharedValue = 10
+
+type duplicateCriterion struct {
+	name   string
+	weight float64
+	key    func(user api.UserRepresentation) string
+}
+
+// The weights of the criteria add up to 1: two users matching on all the criteria get a score of 1
+var duplicateCriteria = []duplicateCriterion{
+	{name: api.DuplicateCriterionEmail, weight: 0.4, key: duplicateEmailKey},
+	{name: api.DuplicateCriterionPhoneNumber, weight: 0.3, key: duplicatePhoneNumberKey},
+	{name: api.DuplicateCriterionIdentity, weight: 0.3, key: duplicateIdentityKey},
+}
+
+func duplicateEmailKey(user api.UserRepresentation) string {
+	if user.Email == nil {
+		return ""
+	}
+	return strings.ToLower(strings.TrimSpace(*user.Email))
+}
+
+func duplicatePhoneNumberKey(user api.UserRepresentation) string {
+	if user.PhoneNumber == nil {
+		return ""
+	}
+	return strings.Map(func(r rune) rune {
+		if unicode.IsDigit(r) {
+			return r
+		}
+		return -1
+	}, *user.PhoneNumber)
+}
+
+func duplicateIdentityKey(user api.UserRepresentation) string {
+	if user.FirstName == nil || user.LastName == nil || user.BirthDate == nil {
+		return ""
+	}
+	var normalize = func(value string) string {
+		return strings.ToLower(strings.Join(strings.Fields(value), " "))
+	}
+	var firstName, lastName, birthDate = normalize(*user.FirstName), normalize(*user.LastName), strings.TrimSpace(*user.BirthDate)
+	if firstName == "" || lastName == "" || birthDate == "" {
+		return ""
+	}
+	return firstName + "|" + lastName + "|" + birthDate
+}
+
+// GetDuplicateUsers lists the pairs of users of the given groups which are likely to be the same person, the most
+// similar first
+func (c *component) GetDuplicateUsers(ctx context.Context, realmName string, groupIDs []string) ([]api.DuplicateUsersRepresentation, error) {
+	var paramKV []string
+	for _, groupID := range groupIDs {
+		paramKV = append(paramKV, "groupId", groupID)
+	}
+
+	var users []api.UserRepresentation
+	for first := 0; first < searchUsersMaxScanned; first += searchUsersBatchSize {
+		var batchParamKV = append(paramKV, "first", strconv.Itoa(first), "max", strconv.Itoa(searchUsersBatchSize))
+		page, err := c.getUsersPage(ctx, realmName, batchParamKV)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, page.Users...)
+		if len(page.Users) < searchUsersBatchSize {
+			break
+		}
+	}
+
+	return findDuplicateUsers(users), nil
+}
+
+func findDuplicateUsers(users []api.UserRepresentation) []api.DuplicateUsersRepresentation {
+	type usersPair struct {
+		first, second int
+	}
+	var matches = make(map[usersPair][]string)
+	var scores = make(map[usersPair]float64)
+
+	for _, criterion := range duplicateCriteria {
+		var usersByKey = make(map[string][]int)
+		for i, user := range users {
+			if key := criterion.key(user); key != "" {
+				usersByKey[key] = append(usersByKey[key], i)
+			}
+		}
+		for _, indexes := range usersByKey {
+			if len(indexes) > duplicateUsersMaxSharedValue {
+				continue
+			}
+			for i := 0; i < len(indexes); i++ {
+				for j := i + 1; j < len(indexes); j++ {
+					var pair = usersPair{first: indexes[i], second: indexes[j]}
+					matches[pair] = append(matches[pair], criterion.name)
+					scores[pair] += criterion.weight
+				}
+			}
+		}
+	}
+
+	var pairs []usersPair
+	for pair := range matches {
+		pairs = append(pairs, pair)
+	}
+	sort.Slice(pairs, func(i, j int) bool {
+		if scores[pairs[i]] != scores[pairs[j]] {
+			return scores[pairs[i]] > scores[pairs[j]]
+		}
+		if pairs[i].first != pairs[j].first {
+			return pairs[i].first < pairs[j].first
+		}
+		return pairs[i].second < pairs[j].second
+	})
+
+	var res = []api.DuplicateUsersRepresentation{}
+	for _, pair := range pairs {
+		res = append(res, api.DuplicateUsersRepresentation{
+			User:      users[pair.first],
+			Duplicate: users[pair.second],
+			Score:     scores[pair],
+			MatchedOn: matches[pair],
+		})
+	}
+	return res
+}
+
+// MergeUsers keeps the given user, moves to it what is selected from the duplicate user then disables the duplicate.
+// Keycloak can't move passwords and OTP devices from a user to another: only the federated identities of the duplicate
+// are linked to the kept user, the other credentials are left on the disabled duplicate. The merge is not atomic: when a
+// step fails, a USER_MERGE_FAILED event records what has already been moved so that the merge can be completed
+func (c *component) MergeUsers(ctx context.Context, realmName string, userID string, merge api.UsersMergeRepresentation) error {
+	var accessToken = ctx.Value(cs.CtContextAccessToken).(string)
+	var duplicateUserID = *merge.DuplicateUserID
+
+	if duplicateUserID == userID {
+		return errorhandler.CreateBadRequestError(constants.MsgErrInvalidParam + "." + constants.DuplicateUserID)
+	}
+
+	userKc, err := c.keycloakClient.GetUser(accessToken, realmName, userID)
+	if err != nil {
+		c.logger.Warn(ctx, "err", err.Error())
+		return err
+	}
+	duplicateKc, err := c.keycloakClient.GetUser(accessToken, realmName, duplicateUserID)
+	if err != nil {
+		c.logger.Warn(ctx, "err", err.Error())
+		return err
+	}
+
+	var username = ""
+	if userKc.Username != nil {
+		username = *userKc.Username
+	}
+	var moved []string
+	var mergeFailed = func(step string, err error) error {
+		c.reportEvent(ctx, "USER_MERGE_FAILED", database.CtEventRealmName, realmName, database.CtEventUserID, userID, database.CtEventUsername, username,
+			database.CtEventAdditionalInfo, database.CreateAdditionalInfo("duplicate_user_id", duplicateUserID, "moved", strings.Join(moved, ","), "failed", step))
+		return err
+	}
+
+	if merge.Groups != nil && *merge.Groups {
+		if err = c.mergeGroups(ctx, realmName, userID, duplicateUserID); err != nil {
+			return mergeFailed("groups", err)
+		}
+		moved = append(moved, "groups")
+	}
+	if merge.Attributes != nil && *merge.Attributes && mergeAttributes(&userKc, duplicateKc) {
+		if err = c.keycloakClient.UpdateUser(accessToken, realmName, userID, userKc); err != nil {
+			c.logger.Warn(ctx, "err", err.Error())
+			return mergeFailed("attributes", err)
+		}
+		moved = append(moved, "attributes")
+	}
+	if merge.FederatedIdentities != nil && *merge.FederatedIdentities {
+		if err = c.mergeFederatedIdentities(ctx, realmName, userID, duplicateUserID); err != nil {
+			return mergeFailed("federatedIdentities", err)
+		}
+		moved = append(moved, "federatedIdentities")
+	}
+	if merge.Checks != nil && *merge.Checks {
+		if err = c.usersDBModule.MoveChecks(ctx, realmName, duplicateUserID, userID); err != nil {
+			c.logger.Warn(ctx, "msg", "Can't move the checks", "err", err.Error())
+			return mergeFailed("checks", err)
+		}
+		moved = append(moved, "checks")
+	}
+	if merge.Details != nil && *merge.Details {
+		if err = c.mergeUserDetails(ctx, realmName, userID, duplicateUserID); err != nil {
+			return mergeFailed("details", err)
+		}
+		moved = append(moved, "details")
+	}
+
+	var disabled = false
+	duplicateKc.Enabled = &disabled
+	if err = c.keycloakClient.UpdateUser(accessToken, realmName, duplicateUserID, duplicateKc); err != nil {
+		c.logger.Warn(ctx, "err", err.Error())
+		return mergeFailed("disable", err)
+	}
+
+	var additionalInfo = []string{"duplicate_user_id", duplicateUserID, "moved", strings.Join(moved, ",")}
+	if duplicateKc.Username != nil {
+		additionalInfo = append(additionalInfo, "duplicate_username", *duplicateKc.Username)
+	}
+	c.reportEvent(ctx, "USER_MERGED", database.CtEventRealmName, realmName, database.CtEventUserID, userID, database.CtEventUsername, username,
+		database.CtEventAdditionalInfo, database.CreateAdditionalInfo(additionalInfo...))
+
+	return nil
+}
+
+func (c *component) mergeGroups(ctx context.Context, realmName string, userID string, duplicateUserID string) error {
+	var accessToken = ctx.Value(cs.CtContextAccessToken).(string)
+
+	groupsKc, err := c.keycloakClient.GetGroupsOfUser(accessToken, realmName, userID)
+	if err != nil {
+		c.logger.Warn(ctx, "err", err.Error())
+		return err
+	}
+	duplicateGroupsKc, err := c.keycloakClient.GetGroupsOfUser(accessToken, realmName, duplicateUserID)
+	if err != nil {
+		c.logger.Warn(ctx, "err", err.Error())
+		return err
+	}
+
+	var groupIDs []string
+	for _, group := range groupsKc {
+		if group.ID != nil {
+			groupIDs = append(groupIDs, *group.ID)
+		}
+	}
+	for _, group := range duplicateGroupsKc {
+		if group.ID == nil || stringInSlice(*group.ID, groupIDs) {
+			continue
+		}
+		if err = c.keycloakClient.AddGroupToUser(accessToken, realmName, userID, *group.ID); err != nil {
+			c.logger.Warn(ctx, "err", err.Error())
+			return err
+		}
+	}
+	return nil
+}
+
+// Profile attributes which can be moved from a duplicate. The trustID groups and the accreditations are not merged: they
+// can only be granted through their dedicated flows
+var mergeableAttributes = []kc.AttributeKey{
+	constants.AttrbBirthDate,
+	constants.AttrbGender,
+	constants.AttrbLabel,
+	constants.AttrbLocale,
+	constants.AttrbPhoneNumber,
+}
+
+// mergeAttributes copies the profile attributes of the duplicate which the kept user does not have. Returns whether the
+// kept user has been updated
+func mergeAttributes(userKc *kc.UserRepresentation, duplicateKc kc.UserRepresentation) bool {
+	if duplicateKc.Attributes == nil {
+		return false
+	}
+	if userKc.Attributes == nil {
+		var attributes = make(kc.Attributes)
+		userKc.Attributes = &attributes
+	}
+
+	var updated = false
+	for _, key := range mergeableAttributes {
+		var values = (*duplicateKc.Attributes)[key]
+		if _, ok := (*userKc.Attributes)[key]; ok || len(values) == 0 {
+			continue
+		}
+		(*userKc.Attributes)[key] = values
+		if key == constants.AttrbPhoneNumber {
+			// The verification applies to the phone number of the duplicate
+			if verified, ok := (*duplicateKc.Attributes)[constants.AttrbPhoneNumberVerified]; ok {
+				(*userKc.Attributes)[constants.AttrbPhoneNumberVerified] = verified
+			}
+		}
+		updated = true
+	}
+	return updated
+}
+
+// mergeFederatedIdentities links the federated identities of the duplicate to the kept user. Each identity is linked to
+// the kept user before being unlinked from the duplicate: a failure never loses it
+func (c *component) mergeFederatedIdentities(ctx context.Context, realmName string, userID string, duplicateUserID string) error {
+	var accessToken = ctx.Value(cs.CtContextAccessToken).(string)
+
+	fedIDsKc, err := c.keycloakClient.GetFederatedIdentities(accessToken, realmName, duplicateUserID)
+	if err != nil {
+		c.logger.Warn(ctx, "msg", "Could not obtain list of federated identities", "err", err.Error())
+		return err
+	}
+
+	for _, fedIDKc := range fedIDsKc {
+		if fedIDKc.IdentityProvider == nil {
+			continue
+		}
+		var provider = *fedIDKc.IdentityProvider
+		if err = c.keycloakClient.LinkShadowUser(accessToken, realmName, userID, provider, fedIDKc); err != nil {
+			c.logger.Warn(ctx, "msg", "Could not link federated identity", "err", err.Error(), "provider", provider)
+			return err
+		}
+		if err = c.keycloakClient.UnlinkShadowUser(accessToken, realmName, duplicateUserID, provider); err != nil {
+			// The identity is linked to both users until it is unlinked from the disabled duplicate
+			c.logger.Warn(ctx, "msg", "Could not unlink federated identity", "err", err.Error(), "provider", provider, "duplicateUserID", duplicateUserID)
+			return err
+		}
+	}
+	return nil
+}
+
+// mergeUserDetails completes the details of the kept user with the details of the duplicate, then deletes the details
+// of the duplicate
+func (c *component) mergeUserDetails(ctx context.Context, realmName string, userID string, duplicateUserID string) error {
+	userDetails, err := c.usersDBModule.GetUserDetails(ctx, realmName, userID)
+	if err != nil {
+		c.logger.Warn(ctx, "msg", "Can't get user details from database", "err", err.Error())
+		return err
+	}
+	duplicateDetails, err := c.usersDBModule.GetUserDetails(ctx, realmName, duplicateUserID)
+	if err != nil {
+		c.logger.Warn(ctx, "msg", "Can't get user details from database", "err", err.Error())
+		return err
+	}
+
+	if userDetails.BirthLocation == nil {
+		userDetails.BirthLocation = duplicateDetails.BirthLocation
+	}
+	if userDetails.IDDocumentType == nil {
+		userDetails.IDDocumentType = duplicateDetails.IDDocumentType
+	}
+	if userDetails.IDDocumentNumber == nil {
+		userDetails.IDDocumentNumber = duplicateDetails.IDDocumentNumber
+	}
+	if userDetails.IDDocumentExpiration == nil {
+		userDetails.IDDocumentExpiration = duplicateDetails.IDDocumentExpiration
+	}
+
+	if err = c.usersDBModule.StoreOrUpdateUserDetails(ctx, realmName, userDetails); err != nil {
+		c.logger.Warn(ctx, "msg", "Can't store user details in database", "err", err.Error())
+		return err
+	}
+	if err = c.usersDBModule.DeleteUserDetails(ctx, realmName, duplicateUserID); err != nil {
+		c.logger.Warn(ctx, "msg", "Can't delete user details from database", "err", err.Error())
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,241 @@
+package management
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	cs "github.com/cloudtrust/common-service"
+	"github.com/cloudtrust/common-service/database"
+	"github.com/cloudtrust/common-service/log"
+	api "github.com/cloudtrust/keycloak-bridge/api/management"
+	"github.com/cloudtrust/keycloak-bridge/internal/constants"
+	"github.com/cloudtrust/keycloak-bridge/internal/dto"
+	"github.com/cloudtrust/keycloak-bridge/pkg/management/mock"
+	kc "github.com/cloudtrust/keycloak-client"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func createDuplicateCandidate(id, email, phoneNumber, firstName, lastName, birthDate string) api.UserRepresentation {
+	var optional = func(value string) *string {
+		if value == "" {
+			return nil
+		}
+		return &value
+	}
+	return api.UserRepresentation{
+		ID:          &id,
+		Email:       optional(email),
+		PhoneNumber: optional(phoneNumber),
+		FirstName:   optional(firstName),
+		LastName:    optional(lastName),
+		BirthDate:   optional(birthDate),
+	}
+}
+
+func TestFindDuplicateUsers(t *testing.T) {
+	t.Run("No duplicate", func(t *testing.T) {
+		var res = findDuplicateUsers([]api.UserRepresentation{
+			createDuplicateCandidate("1", "john@doe.ch", "+41791234567", "John", "Doe", "01.01.1980"),
+			createDuplicateCandidate("2", "jane@doe.ch", "+41797654321", "Jane", "Doe", "01.01.1980"),
+		})
+		assert.Len(t, res, 0)
+	})
+	t.Run("Duplicates sorted by score", func(t *testing.T) {
+		var res = findDuplicateUsers([]api.UserRepresentation{
+			createDuplicateCandidate("1", "John@Doe.ch ", "+41791234567", "John", "Doe", "01.01.1980"),
+			createDuplicateCandidate("2", "jane@doe.ch", "+41 79 123 45 67", "", "", ""),
+			createDuplicateCandidate("3", "john@doe.ch", "", "john ", "DOE", "01.01.1980"),
+		})
+		assert.Len(t, res, 2)
+		assert.Equal(t, "1", *res[0].User.ID)
+		assert.Equal(t, "3", *res[0].Duplicate.ID)
+		assert.InDelta(t, 0.7, res[0].Score, 0.001)
+		assert.Equal(t, []string{api.DuplicateCriterionEmail, api.DuplicateCriterionIdentity}, res[0].MatchedOn)
+		assert.Equal(t, "1", *res[1].User.ID)
+		assert.Equal(t, "2", *res[1].Duplicate.ID)
+		assert.Equal(t, []string{api.DuplicateCriterionPhoneNumber}, res[1].MatchedOn)
+	})
+	t.Run("Shared value does not identify a person", func(t *testing.T) {
+		var users []api.UserRepresentation
+		for _, id := range []string{"1", "2", "3", "4", "5", "6", "7", "8", "9", "10", "11"} {
+			users = append(users, createDuplicateCandidate(id, "team@company.ch", "", "", "", ""))
+		}
+		assert.Len(t, findDuplicateUsers(users), 0)
+	})
+}
+
+func TestGetDuplicateUsers(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+	var mockKeycloakClient = mock.NewKeycloakClient(mockCtrl)
+	var mockUsersDetailsDBModule = mock.NewUsersDetailsDBModule(mockCtrl)
+	var mockEventDBModule = mock.NewEventDBModule(mockCtrl)
+	var mockConfigurationDBModule = mock.NewConfigurationDBModule(mockCtrl)
+
+	var managementComponent = NewComponent(mockKeycloakClient, mockUsersDetailsDBModule, mockEventDBModule, mockConfigurationDBModule, nil, nil, nil, log.NewNopLogger())
+
+	var accessToken = "TOKEN=="
+	var realmName = "master"
+	var targetRealmName = "DEP"
+	var groupID = "41dbf4a8-32a9-4000-8c17-edc854c31231"
+	var ctx = context.WithValue(context.Background(), cs.CtContextAccessToken, accessToken)
+	ctx = context.WithValue(ctx, cs.CtContextRealm, realmName)
+
+	t.Run("Can't get users", func(t *testing.T) {
+		var anyError = errors.New("any error")
+		mockKeycloakClient.EXPECT().GetUsers(accessToken, realmName, targetRealmName, "groupId", groupID, "first", "0", "max", "100").
+			Return(kc.UsersPageRepresentation{}, anyError)
+
+		var _, err = managementComponent.GetDuplicateUsers(ctx, targetRealmName, []string{groupID})
+		assert.Equal(t, anyError, err)
+	})
+	t.Run("Success", func(t *testing.T) {
+		var id1, id2 = "id1", "id2"
+		var email = "john@doe.ch"
+		var count = 2
+		mockKeycloakClient.EXPECT().GetUsers(accessToken, realmName, targetRealmName, "groupId", groupID, "first", "0", "max", "100").
+			Return(kc.UsersPageRepresentation{Count: &count, Users: []kc.UserRepresentation{{ID: &id1, Email: &email}, {ID: &id2, Email: &email}}}, nil)
+
+		var res, err = managementComponent.GetDuplicateUsers(ctx, targetRealmName, []string{groupID})
+		assert.Nil(t, err)
+		assert.Len(t, res, 1)
+		assert.Equal(t, id1, *res[0].User.ID)
+		assert.Equal(t, id2, *res[0].Duplicate.ID)
+	})
+}
+
+func TestMergeUsers(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+	var mockKeycloakClient = mock.NewKeycloakClient(mockCtrl)
+	var mockUsersDetailsDBModule = mock.NewUsersDetailsDBModule(mockCtrl)
+	var mockEventDBModule = mock.NewEventDBModule(mockCtrl)
+	var mockConfigurationDBModule = mock.NewConfigurationDBModule(mockCtrl)
+
+	var managementComponent = NewComponent(mockKeycloakClient, mockUsersDetailsDBModule, mockEventDBModule, mockConfigurationDBModule, nil, nil, nil, log.NewNopLogger())
+
+	var accessToken = "TOKEN=="
+	var realmName = "DEP"
+	var userID = "f467ed7c-0a1d-4eee-9bb8-669c6f89c000"
+	var duplicateUserID = "a2c4e6f8-0a1d-4eee-9bb8-669c6f89c000"
+	var username = "username"
+	var groupID = "41dbf4a8-32a9-4000-8c17-edc854c31231"
+	var duplicateGroupID = "82b5a3c1-32a9-4000-8c17-edc854c31231"
+	var provider = "social"
+	var anyError = errors.New("any error")
+	var yes = true
+	var ctx = context.WithValue(context.Background(), cs.CtContextAccessToken, accessToken)
+
+	var createKcUser = func(id string, phoneNumber string) kc.UserRepresentation {
+		var attributes = make(kc.Attributes)
+		attributes.SetString(constants.AttrbPhoneNumber, phoneNumber)
+		var enabled = true
+		return kc.UserRepresentation{ID: &id, Username: &username, Enabled: &enabled, Attributes: &attributes}
+	}
+
+	t.Run("Duplicate is the kept user", func(t *testing.T) {
+		var err = managementComponent.MergeUsers(ctx, realmName, userID, api.UsersMergeRepresentation{DuplicateUserID: &userID})
+		assert.NotNil(t, err)
+	})
+	t.Run("Can't get duplicate user", func(t *testing.T) {
+		mockKeycloakClient.EXPECT().GetUser(accessToken, realmName, userID).Return(createKcUser(userID, "+41791234567"), nil)
+		mockKeycloakClient.EXPECT().GetUser(accessToken, realmName, duplicateUserID).Return(kc.UserRepresentation{}, anyError)
+
+		var err = managementComponent.MergeUsers(ctx, realmName, userID, api.UsersMergeRepresentation{DuplicateUserID: &duplicateUserID})
+		assert.Equal(t, anyError, err)
+	})
+	t.Run("Merge nothing but disable the duplicate", func(t *testing.T) {
+		var duplicateKc = createKcUser(duplicateUserID, "+41791234567")
+		mockKeycloakClient.EXPECT().GetUser(accessToken, realmName, userID).Return(createKcUser(userID, "+41791234567"), nil)
+		mockKeycloakClient.EXPECT().GetUser(accessToken, realmName, duplicateUserID).Return(duplicateKc, nil)
+		mockKeycloakClient.EXPECT().UpdateUser(accessToken, realmName, duplicateUserID, gomock.Any()).DoAndReturn(
+			func(_, _, _ string, user kc.UserRepresentation) error {
+				assert.False(t, *user.Enabled)
+				return nil
+			})
+		mockEventDBModule.EXPECT().ReportEvent(ctx, "USER_MERGED", "back-office", gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(),
+			gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil)
+
+		var err = managementComponent.MergeUsers(ctx, realmName, userID, api.UsersMergeRepresentation{DuplicateUserID: &duplicateUserID})
+		assert.Nil(t, err)
+	})
+	t.Run("Can't move checks", func(t *testing.T) {
+		mockKeycloakClient.EXPECT().GetUser(accessToken, realmName, userID).Return(createKcUser(userID, "+41791234567"), nil)
+		mockKeycloakClient.EXPECT().GetUser(accessToken, realmName, duplicateUserID).Return(createKcUser(duplicateUserID, "+41791234567"), nil)
+		mockKeycloakClient.EXPECT().GetGroupsOfUser(accessToken, realmName, userID).Return([]kc.GroupRepresentation{{ID: &groupID}}, nil)
+		mockKeycloakClient.EXPECT().GetGroupsOfUser(accessToken, realmName, duplicateUserID).Return([]kc.GroupRepresentation{{ID: &duplicateGroupID}}, nil)
+		mockKeycloakClient.EXPECT().AddGroupToUser(accessToken, realmName, userID, duplicateGroupID).Return(nil)
+		mockUsersDetailsDBModule.EXPECT().MoveChecks(ctx, realmName, duplicateUserID, userID).Return(anyError)
+		mockEventDBModule.EXPECT().ReportEvent(ctx, "USER_MERGE_FAILED", "back-office", database.CtEventRealmName, realmName, database.CtEventUserID, userID,
+			database.CtEventUsername, username, database.CtEventAdditionalInfo, gomock.Any()).DoAndReturn(
+			func(_ context.Context, _, _ string, values ...string) error {
+				var info map[string]string
+				assert.Nil(t, json.Unmarshal([]byte(values[7]), &info))
+				assert.Equal(t, "groups", info["moved"])
+				assert.Equal(t, "checks", info["failed"])
+				return nil
+			})
+
+		var err = managementComponent.MergeUsers(ctx, realmName, userID, api.UsersMergeRepresentation{DuplicateUserID: &duplicateUserID, Groups: &yes, Checks: &yes})
+		assert.Equal(t, anyError, err)
+	})
+	t.Run("Can't link federated identity", func(t *testing.T) {
+		var fedID = kc.FederatedIdentityRepresentation{IdentityProvider: &provider}
+		mockKeycloakClient.EXPECT().GetUser(accessToken, realmName, userID).Return(createKcUser(userID, "+41791234567"), nil)
+		mockKeycloakClient.EXPECT().GetUser(accessToken, realmName, duplicateUserID).Return(createKcUser(duplicateUserID, "+41791234567"), nil)
+		mockKeycloakClient.EXPECT().GetFederatedIdentities(accessToken, realmName, duplicateUserID).Return([]kc.FederatedIdentityRepresentation{fedID}, nil)
+		// The identity is not unlinked from the duplicate
+		mockKeycloakClient.EXPECT().LinkShadowUser(accessToken, realmName, userID, provider, fedID).Return(anyError)
+		mockEventDBModule.EXPECT().ReportEvent(ctx, "USER_MERGE_FAILED", "back-office", gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(),
+			gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil)
+
+		var err = managementComponent.MergeUsers(ctx, realmName, userID, api.UsersMergeRepresentation{DuplicateUserID: &duplicateUserID, FederatedIdentities: &yes})
+		assert.Equal(t, anyError, err)
+	})
+	t.Run("Merge everything", func(t *testing.T) {
+		var userKc = createKcUser(userID, "+41791234567")
+		var duplicateKc = createKcUser(duplicateUserID, "+41797654321")
+		duplicateKc.Attributes.SetString(constants.AttrbLocale, "fr")
+		duplicateKc.Attributes.SetString(constants.AttrbTrustIDGroups, "l1_support_agent")
+		duplicateKc.Attributes.SetString(constants.AttrbAccreditations, `{"type":"SHADOW","expiryDate":"01.01.2040"}`)
+		var federatedUserID, federatedUsername = "fed-id", "fed-name"
+		var fedID = kc.FederatedIdentityRepresentation{IdentityProvider: &provider, UserID: &federatedUserID, UserName: &federatedUsername}
+		var birthLocation, documentType, documentNumber = "Lausanne", "ID_CARD", "AB123456"
+
+		mockKeycloakClient.EXPECT().GetUser(accessToken, realmName, userID).Return(userKc, nil)
+		mockKeycloakClient.EXPECT().GetUser(accessToken, realmName, duplicateUserID).Return(duplicateKc, nil)
+		mockKeycloakClient.EXPECT().GetGroupsOfUser(accessToken, realmName, userID).Return([]kc.GroupRepresentation{{ID: &groupID}}, nil)
+		mockKeycloakClient.EXPECT().GetGroupsOfUser(accessToken, realmName, duplicateUserID).Return([]kc.GroupRepresentation{{ID: &groupID}, {ID: &duplicateGroupID}}, nil)
+		mockKeycloakClient.EXPECT().AddGroupToUser(accessToken, realmName, userID, duplicateGroupID).Return(nil)
+		mockKeycloakClient.EXPECT().UpdateUser(accessToken, realmName, userID, gomock.Any()).DoAndReturn(
+			func(_, _, _ string, user kc.UserRepresentation) error {
+				assert.Equal(t, "+41791234567", *user.GetAttributeString(constants.AttrbPhoneNumber))
+				assert.Equal(t, "fr", *user.GetAttributeString(constants.AttrbLocale))
+				assert.Nil(t, user.GetAttributeString(constants.AttrbTrustIDGroups))
+				assert.Nil(t, user.GetAttributeString(constants.AttrbAccreditations))
+				return nil
+			})
+		mockKeycloakClient.EXPECT().GetFederatedIdentities(accessToken, realmName, duplicateUserID).Return([]kc.FederatedIdentityRepresentation{fedID}, nil)
+		gomock.InOrder(
+			mockKeycloakClient.EXPECT().LinkShadowUser(accessToken, realmName, userID, provider, fedID).Return(nil),
+			mockKeycloakClient.EXPECT().UnlinkShadowUser(accessToken, realmName, duplicateUserID, provider).Return(nil),
+		)
+		mockUsersDetailsDBModule.EXPECT().MoveChecks(ctx, realmName, duplicateUserID, userID).Return(nil)
+		mockUsersDetailsDBModule.EXPECT().GetUserDetails(ctx, realmName, userID).Return(dto.DBUser{UserID: &userID, BirthLocation: &birthLocation}, nil)
+		mockUsersDetailsDBModule.EXPECT().GetUserDetails(ctx, realmName, duplicateUserID).
+			Return(dto.DBUser{UserID: &duplicateUserID, IDDocumentType: &documentType, IDDocumentNumber: &documentNumber}, nil)
+		mockUsersDetailsDBModule.EXPECT().StoreOrUpdateUserDetails(ctx, realmName, dto.DBUser{UserID: &userID, BirthLocation: &birthLocation,
+			IDDocumentType: &documentType, IDDocumentNumber: &documentNumber}).Return(nil)
+		mockUsersDetailsDBModule.EXPECT().DeleteUserDetails(ctx, realmName, duplicateUserID).Return(nil)
+		mockKeycloakClient.EXPECT().UpdateUser(accessToken, realmName, duplicateUserID, gomock.Any()).Return(nil)
+		mockEventDBModule.EXPECT().ReportEvent(ctx, "USER_MERGED", "back-office", gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(),
+			gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil)
+
+		var err = managementComponent.MergeUsers(ctx, realmName, userID, api.UsersMergeRepresentation{DuplicateUserID: &duplicateUserID,
+			Groups: &yes, Attributes: &yes, FederatedIdentities: &yes, Checks: &yes, Details: &yes})
+		assert.Nil(t, err)
+	})
+}
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"regexp"
+	"strconv"
 	"strings"
 
 	cs "github.com/cloudtrust/common-service"
@@ -26,10 +27,19 @@ type Endpoints struct {
 	DeleteUser                endpoint.Endpoint
 	GetUser                   endpoint.Endpoint
 	UpdateUser                endpoint.Endpoint
+	GetUserHistory            endpoint.Endpoint
 	LockUser                  endpoint.Endpoint
 	UnlockUser                endpoint.Endpoint
 	GetUsers                  endpoint.Endpoint
+	SearchUsers               endpoint.Endpoint
+	GetDuplicateUsers         endpoint.Endpoint
+	MergeUsers                endpoint.Endpoint
 	CreateUser                endpoint.Endpoint
+	ImportUsers               endpoint.Endpoint
+	GetUsersImportJob         endpoint.Endpoint
+	GetUsersImportResults     endpoint.Endpoint
+	StartBulkOperation        endpoint.Endpoint
+	GetBulkOperation          endpoint.Endpoint
 	GetRolesOfUser            endpoint.Endpoint
 	GetGroupsOfUser           endpoint.Endpoint
 	AddGroupToUser            endpoint.Endpoint
@@ -40,6 +50,7 @@ type Endpoints struct {
 	GetUserAccountStatus      endpoint.Endpoint
 	GetClientRoleForUser      endpoint.Endpoint
 	AddClientRoleToUser       endpoint.Endpoint
+	DeleteClientRoleFromUser  endpoint.Endpoint
 
 	ResetPassword                  endpoint.Endpoint
 	ExecuteActionsEmail            endpoint.Endpoint
@@ -52,19 +63,55 @@ type Endpoints struct {
 	ResetCredentialFailuresForUser endpoint.Endpoint
 	ClearUserLoginFailures         endpoint.Endpoint
 	GetAttackDetectionStatus       endpoint.Endpoint
-
-	GetRoles         endpoint.Endpoint
-	GetRole          endpoint.Endpoint
-	GetClientRoles   endpoint.Endpoint
-	CreateClientRole endpoint.Endpoint
+	GetSessionsOfUser              endpoint.Endpoint
+	LogoutUser                     endpoint.Endpoint
+	DeleteSessionOfUser            endpoint.Endpoint
+	GetOfflineSessionsOfUser       endpoint.Endpoint
+	GetConsentsOfUser              endpoint.Endpoint
+	RevokeConsentOfUser            endpoint.Endpoint
+
+	GetRoles             endpoint.Endpoint
+	GetRole              endpoint.Endpoint
+	CreateRole           endpoint.Endpoint
+	UpdateRole           endpoint.Endpoint
+	DeleteRole           endpoint.Endpoint
+	GetRoleComposites    endpoint.Endpoint
+	AddRoleComposites    endpoint.Endpoint
+	DeleteRoleComposites endpoint.Endpoint
+	GetUsersWithRole     endpoint.Endpoint
+	GetClientRoles       endpoint.Endpoint
+	CreateClientRole     endpoint.Endpoint
+	DeleteClientRole     endpoint.Endpoint
 
 	GetGroups            endpoint.Endpoint
 	CreateGroup          endpoint.Endpoint
 	DeleteGroup          endpoint.Endpoint
+	GetGroup             endpoint.Endpoint
+	UpdateGroup          endpoint.Endpoint
+	CreateGroupChild     endpoint.Endpoint
+	MoveGroup            endpoint.Endpoint
+	GetGroupMembers      endpoint.Endpoint
 	GetAuthorizations    endpoint.Endpoint
 	UpdateAuthorizations endpoint.Endpoint
+	CheckAuthorization   endpoint.Endpoint
 	GetActions           endpoint.Endpoint
 
+	GetAuthorizationTemplates   endpoint.Endpoint
+	GetAuthorizationTemplate    endpoint.Endpoint
+	UpdateAuthorizationTemplate endpoint.Endpoint
+	DeleteAuthorizationTemplate endpoint.Endpoint
+	ApplyAuthorizationTemplate  endpoint.Endpoint
+	CopyAuthorizations          endpoint.Endpoint
+
+	GetAuthorizationsVersions     endpoint.Endpoint
+	GetAuthorizationsVersionsDiff endpoint.Endpoint
+	RollbackAuthorizations        endpoint.Endpoint
+
+	GetPendingRequests    endpoint.Endpoint
+	GetPendingRequest     endpoint.Endpoint
+	ApprovePendingRequest endpoint.Endpoint
+	RejectPendingRequest  endpoint.Endpoint
+
 	GetRealmCustomConfiguration         endpoint.Endpoint
 	UpdateRealmCustomConfiguration      endpoint.Endpoint
 	GetRealmAdminConfiguration          endpoint.Endpoint
@@ -73,7 +120,16 @@ type Endpoints struct {
 	UpdateRealmBackOfficeConfiguration  endpoint.Endpoint
 	GetUserRealmBackOfficeConfiguration endpoint.Endpoint
 
-	LinkShadowUser endpoint.Endpoint
+	GetRealmCustomConfigurationVersions     endpoint.Endpoint
+	GetRealmCustomConfigurationVersionsDiff endpoint.Endpoint
+	RollbackRealmCustomConfiguration        endpoint.Endpoint
+	GetRealmAdminConfigurationVersions      endpoint.Endpoint
+	GetRealmAdminConfigurationVersionsDiff  endpoint.Endpoint
+	RollbackRealmAdminConfiguration         endpoint.Endpoint
+
+	LinkShadowUser         endpoint.Endpoint
+	GetFederatedIdentities endpoint.Endpoint
+	UnlinkShadowUser       endpoint.Endpoint
 }
 
 // MakeGetRealmsEndpoint makes the Realms endpoint to retrieve all available realms.
@@ -157,12 +213,126 @@ func MakeCreateUserEndpoint(component Component, logger keycloakb.Logger) cs.End
 	}
 }
 
+// Maximum number of users of an import file
+const maxUsersImport = 10000
+
+// MakeImportUsersEndpoint makes the endpoint to import users from a CSV or JSON lines file.
+// Groups given as query parameter are added to the groups of each user. In dry-run mode, the users are only validated.
+func MakeImportUsersEndpoint(component Component) cs.Endpoint {
+	return func(ctx context.Context, req interface{}) (interface{}, error) {
+		var m = req.(map[string]string)
+		var users []api.UserImportEntry
+		var err error
+
+		if m[prmQryFormat] == "csv" {
+			users, err = api.ParseUsersImportCSV(m[reqBody])
+		} else {
+			users, err = api.ParseUsersImportJSONLines(m[reqBody])
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(users) == 0 || len(users) > maxUsersImport {
+			return nil, errorhandler.CreateBadRequestError(msg.MsgErrInvalidLength + "." + msg.Body)
+		}
+
+		if groupIDs, ok := m[prmQryGroupIDs]; ok {
+			for i := range users {
+				users[i].User.Groups = mergeGroups(users[i].User.Groups, strings.Split(groupIDs, ","))
+			}
+		}
+
+		if m[prmQryDryRun] == "true" {
+			return component.CheckUsersImport(ctx, m[prmRealm], users)
+		}
+
+		var actions []api.RequiredAction
+		if value, ok := m[prmQryActions]; ok {
+			for _, action := range strings.Split(value, ",") {
+				actions = append(actions, api.RequiredAction(action))
+			}
+		}
+
+		return component.ImportUsers(ctx, m[prmRealm], users, actions)
+	}
+}
+
+func mergeGroups(groups *[]string, additionalGroups []string) *[]string {
+	var res []string
+	var known = make(map[string]bool)
+	if groups != nil {
+		res = append(res, *groups...)
+		for _, group := range *groups {
+			known[group] = true
+		}
+	}
+	for _, group := range additionalGroups {
+		if !known[group] {
+			res = append(res, group)
+			known[group] = true
+		}
+	}
+	return &res
+}
+
+// MakeGetUsersImportJobEndpoint creates an endpoint for GetUsersImportJob
+func MakeGetUsersImportJobEndpoint(component Component) cs.Endpoint {
+	return func(ctx context.Context, req interface{}) (interface{}, error) {
+		var m = req.(map[string]string)
+
+		return component.GetUsersImportJob(ctx, m[prmRealm], m[prmJobID])
+	}
+}
+
+// MakeGetUsersImportResultsEndpoint creates an endpoint for GetUsersImportResults. The results can be exported as CSV.
+func MakeGetUsersImportResultsEndpoint(component Component) cs.Endpoint {
+	return func(ctx context.Context, req interface{}) (interface{}, error) {
+		var m = req.(map[string]string)
+
+		rows, err := component.GetUsersImportResults(ctx, m[prmRealm], m[prmJobID])
+		if err != nil {
+			return nil, err
+		}
+		if m[prmQryFormat] == "csv" {
+			return CSVReply{Filename: "users-import-" + m[prmJobID] + ".csv", Records: rows.ToCSV()}, nil
+		}
+		return rows, nil
+	}
+}
+
+// MakeStartBulkOperationEndpoint makes the endpoint to apply an operation to a list of users
+func MakeStartBulkOperationEndpoint(component BulkComponent) cs.Endpoint {
+	return func(ctx context.Context, req interface{}) (interface{}, error) {
+		var m = req.(map[string]string)
+
+		var operation api.BulkOperationRepresentation
+		if err := json.Unmarshal([]byte(m[reqBody]), &operation); err != nil {
+			return nil, errorhandler.CreateBadRequestError(msg.MsgErrInvalidParam + "." + msg.Body)
+		}
+
+		if err := operation.Validate(); err != nil {
+			return nil, err
+		}
+
+		return component.StartBulkOperation(ctx, m[prmRealm], operation)
+	}
+}
+
+// MakeGetBulkOperationEndpoint creates an endpoint for GetBulkOperation
+func MakeGetBulkOperationEndpoint(component BulkComponent) cs.Endpoint {
+	return func(ctx context.Context, req interface{}) (interface{}, error) {
+		var m = req.(map[string]string)
+
+		return component.GetBulkOperation(ctx, m[prmRealm], m[prmJobID])
+	}
+}
+
 // MakeDeleteUserEndpoint creates an endpoint for DeleteUser
 func MakeDeleteUserEndpoint(component Component) cs.Endpoint {
 	return func(ctx context.Context, req interface{}) (interface{}, error) {
 		var m = req.(map[string]string)
 
-		return nil, component.DeleteUser(ctx, m[prmRealm], m[prmUserID])
+		return replyPendingApproval(nil, component.DeleteUser(ctx, m[prmRealm], m[prmUserID]))
 	}
 }
 
@@ -171,7 +341,11 @@ func MakeGetUserEndpoint(component Component) cs.Endpoint {
 	return func(ctx context.Context, req interface{}) (interface{}, error) {
 		var m = req.(map[string]string)
 
-		return component.GetUser(ctx, m[prmRealm], m[prmUserID])
+		user, etag, err := component.GetUser(ctx, m[prmRealm], m[prmUserID])
+		if err != nil {
+			return nil, err
+		}
+		return keycloakb.ETagReply{ETag: etag, Value: user}, nil
 	}
 }
 
@@ -190,7 +364,16 @@ func MakeUpdateUserEndpoint(component Component) cs.Endpoint {
 			return nil, err
 		}
 
-		return nil, component.UpdateUser(ctx, m[prmRealm], m[prmUserID], user)
+		return nil, component.UpdateUser(ctx, m[prmRealm], m[prmUserID], user, m[reqIfMatch])
+	}
+}
+
+// MakeGetUserHistoryEndpoint creates an endpoint for GetUserHistory
+func MakeGetUserHistoryEndpoint(component Component) cs.Endpoint {
+	return func(ctx context.Context, req interface{}) (interface{}, error) {
+		var m = req.(map[string]string)
+
+		return component.GetUserHistory(ctx, m[prmRealm], m[prmUserID])
 	}
 }
 
@@ -235,6 +418,78 @@ func MakeGetUsersEndpoint(component Component) cs.Endpoint {
 	}
 }
 
+// MakeSearchUsersEndpoint creates an endpoint for SearchUsers
+func MakeSearchUsersEndpoint(component Component) cs.Endpoint {
+	return func(ctx context.Context, req interface{}) (interface{}, error) {
+		var m = req.(map[string]string)
+
+		_, ok := m[prmQryGroupIDs]
+		if !ok {
+			return nil, errorhandler.CreateMissingParameterError(msg.GroupIDs)
+		}
+		groupIDs := strings.Split(m[prmQryGroupIDs], ",")
+
+		var criteria api.UsersSearchRepresentation
+		for key, value := range map[string]**string{
+			prmQryPhoneNumber:      &criteria.PhoneNumber,
+			prmQryTrustIDGroup:     &criteria.TrustIDGroup,
+			prmQryAccreditation:    &criteria.Accreditation,
+			prmQryLocale:           &criteria.Locale,
+			prmQryIDDocumentNumber: &criteria.IDDocumentNumber,
+		} {
+			if param := m[key]; param != "" {
+				*value = &param
+			}
+		}
+		for key, value := range map[string]**int{
+			prmQryFirst: &criteria.First,
+			prmQryMax:   &criteria.Max,
+		} {
+			if param := m[key]; param != "" {
+				number, err := strconv.Atoi(param)
+				if err != nil {
+					return nil, errorhandler.CreateBadRequestError(msg.MsgErrInvalidParam + "." + key)
+				}
+				*value = &number
+			}
+		}
+
+		return component.SearchUsers(ctx, m[prmRealm], groupIDs, criteria)
+	}
+}
+
+// MakeGetDuplicateUsersEndpoint creates an endpoint for GetDuplicateUsers
+func MakeGetDuplicateUsersEndpoint(component Component) cs.Endpoint {
+	return func(ctx context.Context, req interface{}) (interface{}, error) {
+		var m = req.(map[string]string)
+
+		_, ok := m[prmQryGroupIDs]
+		if !ok {
+			return nil, errorhandler.CreateMissingParameterError(msg.GroupIDs)
+		}
+		groupIDs := strings.Split(m[prmQryGroupIDs], ",")
+
+		return component.GetDuplicateUsers(ctx, m[prmRealm], groupIDs)
+	}
+}
+
+// MakeMergeUsersEndpoint creates an endpoint for MergeUsers
+func MakeMergeUsersEndpoint(component Component) cs.Endpoint {
+	return func(ctx context.Context, req interface{}) (interface{}, error) {
+		var m = req.(map[string]string)
+
+		var merge api.UsersMergeRepresentation
+		if err := json.Unmarshal([]byte(m[reqBody]), &merge); err != nil {
+			return nil, errorhandler.CreateBadRequestError(msg.MsgErrInvalidParam + "." + msg.Body)
+		}
+		if err := merge.Validate(); err != nil {
+			return nil, err
+		}
+
+		return replyPendingApproval(nil, component.MergeUsers(ctx, m[prmRealm], m[prmUserID], merge))
+	}
+}
+
 // MakeGetRolesOfUserEndpoint creates an endpoint for GetRolesOfUser
 func MakeGetRolesOfUserEndpoint(component Component) cs.Endpoint {
 	return func(ctx context.Context, req interface{}) (interface{}, error) {
@@ -294,13 +549,21 @@ func MakeSetTrustIDGroupsToUserEndpoint(component Component) cs.Endpoint {
 	return func(ctx context.Context, req interface{}) (interface{}, error) {
 		var m = req.(map[string]string)
 
-		var groupNames []string
+		var groups []api.TrustIDGroupRepresentation
 
-		if err := json.Unmarshal([]byte(m[reqBody]), &groupNames); err != nil {
-			return nil, errorhandler.CreateBadRequestError(msg.MsgErrInvalidParam + "." + msg.Body)
+		if err := json.Unmarshal([]byte(m[reqBody]), &groups); err != nil {
+			// The groups can still be given as a list of names: they are assigned without expiry date
+			var groupNames []string
+			if err := json.Unmarshal([]byte(m[reqBody]), &groupNames); err != nil {
+				return nil, errorhandler.CreateBadRequestError(msg.MsgErrInvalidParam + "." + msg.Body)
+			}
+			groups = make([]api.TrustIDGroupRepresentation, len(groupNames))
+			for i := range groupNames {
+				groups[i].Name = &groupNames[i]
+			}
 		}
 
-		return nil, component.SetTrustIDGroupsToUser(ctx, m[prmRealm], m[prmUserID], groupNames)
+		return replyPendingApproval(nil, component.SetTrustIDGroupsToUser(ctx, m[prmRealm], m[prmUserID], groups))
 	}
 }
 
@@ -344,6 +607,15 @@ func MakeAddClientRolesToUserEndpoint(component Component) cs.Endpoint {
 	}
 }
 
+// MakeDeleteClientRoleFromUserEndpoint creates an endpoint for DeleteClientRoleFromUser
+func MakeDeleteClientRoleFromUserEndpoint(component Component) cs.Endpoint {
+	return func(ctx context.Context, req interface{}) (interface{}, error) {
+		var m = req.(map[string]string)
+
+		return nil, component.DeleteClientRoleFromUser(ctx, m[prmRealm], m[prmUserID], m[prmClientID], m[prmRoleID])
+	}
+}
+
 // MakeResetPasswordEndpoint creates an endpoint for ResetPassword
 func MakeResetPasswordEndpoint(component Component) cs.Endpoint {
 	return func(ctx context.Context, req interface{}) (interface{}, error) {
@@ -364,7 +636,7 @@ func MakeResetPasswordEndpoint(component Component) cs.Endpoint {
 		if pwd != "" {
 			return pwd, err
 		}
-		return nil, err
+		return replyPendingApproval(nil, err)
 	}
 }
 
@@ -456,7 +728,7 @@ func MakeDeleteCredentialsForUserEndpoint(component Component) cs.Endpoint {
 	return func(ctx context.Context, req interface{}) (interface{}, error) {
 		var m = req.(map[string]string)
 
-		return nil, component.DeleteCredentialsForUser(ctx, m[prmRealm], m[prmUserID], m[prmCredentialID])
+		return replyPendingApproval(nil, component.DeleteCredentialsForUser(ctx, m[prmRealm], m[prmUserID], m[prmCredentialID]))
 	}
 }
 
@@ -487,94 +759,100 @@ func MakeGetAttackDetectionStatus(component Component) cs.Endpoint {
 	}
 }
 
-// MakeGetRolesEndpoint creates an endpoint for GetRoles
-func MakeGetRolesEndpoint(component Component) cs.Endpoint {
+// MakeGetSessionsOfUserEndpoint creates an endpoint for GetSessionsOfUser
+func MakeGetSessionsOfUserEndpoint(component Component) cs.Endpoint {
 	return func(ctx context.Context, req interface{}) (interface{}, error) {
 		var m = req.(map[string]string)
 
-		return component.GetRoles(ctx, m[prmRealm])
+		return component.GetSessionsOfUser(ctx, m[prmRealm], m[prmUserID])
 	}
 }
 
-// MakeGetRoleEndpoint creates an endpoint for GetRole
-func MakeGetRoleEndpoint(component Component) cs.Endpoint {
+// MakeLogoutUserEndpoint creates an endpoint for LogoutUser
+func MakeLogoutUserEndpoint(component Component) cs.Endpoint {
 	return func(ctx context.Context, req interface{}) (interface{}, error) {
 		var m = req.(map[string]string)
 
-		return component.GetRole(ctx, m[prmRealm], m[prmRoleID])
+		return nil, component.LogoutUser(ctx, m[prmRealm], m[prmUserID])
 	}
 }
 
-// MakeGetClientRolesEndpoint creates an endpoint for GetClientRoles
-func MakeGetClientRolesEndpoint(component Component) cs.Endpoint {
+// MakeDeleteSessionOfUserEndpoint creates an endpoint for DeleteSessionOfUser
+func MakeDeleteSessionOfUserEndpoint(component Component) cs.Endpoint {
 	return func(ctx context.Context, req interface{}) (interface{}, error) {
 		var m = req.(map[string]string)
 
-		return component.GetClientRoles(ctx, m[prmRealm], m[prmClientID])
+		return nil, component.DeleteSessionOfUser(ctx, m[prmRealm], m[prmUserID], m[prmSessionID])
 	}
 }
 
-// MakeCreateClientRoleEndpoint creates an endpoint for CreateClientRole
-func MakeCreateClientRoleEndpoint(component Component, logger keycloakb.Logger) cs.Endpoint {
+// MakeGetOfflineSessionsOfUserEndpoint creates an endpoint for GetOfflineSessionsOfUser
+func MakeGetOfflineSessionsOfUserEndpoint(component Component) cs.Endpoint {
 	return func(ctx context.Context, req interface{}) (interface{}, error) {
 		var m = req.(map[string]string)
-		var err error
 
-		var role api.RoleRepresentation
+		return component.GetOfflineSessionsOfUser(ctx, m[prmRealm], m[prmUserID], m[prmClientID])
+	}
+}
 
-		if err = json.Unmarshal([]byte(m[reqBody]), &role); err != nil {
-			return nil, errorhandler.CreateBadRequestError(msg.MsgErrInvalidParam + "." + msg.Body)
-		}
+// MakeGetConsentsOfUserEndpoint creates an endpoint for GetConsentsOfUser
+func MakeGetConsentsOfUserEndpoint(component Component) cs.Endpoint {
+	return func(ctx context.Context, req interface{}) (interface{}, error) {
+		var m = req.(map[string]string)
 
-		if err = role.Validate(); err != nil {
-			return nil, err
-		}
+		return component.GetConsentsOfUser(ctx, m[prmRealm], m[prmUserID])
+	}
+}
 
-		var keycloakLocation string
-		keycloakLocation, err = component.CreateClientRole(ctx, m[prmRealm], m[prmClientID], role)
+// MakeRevokeConsentOfUserEndpoint creates an endpoint for RevokeConsentOfUser
+func MakeRevokeConsentOfUserEndpoint(component Component) cs.Endpoint {
+	return func(ctx context.Context, req interface{}) (interface{}, error) {
+		var m = req.(map[string]string)
 
-		if err != nil {
-			return nil, err
-		}
+		return nil, component.RevokeConsentOfUser(ctx, m[prmRealm], m[prmUserID], m[prmClientID])
+	}
+}
 
-		url, err := convertLocationURL(keycloakLocation, m[reqScheme], m[reqHost])
-		if err != nil {
-			logger.Warn(ctx, "msg", "Invalid location", "location", keycloakLocation, "err", err.Error())
-		}
+// MakeGetRolesEndpoint creates an endpoint for GetRoles
+func MakeGetRolesEndpoint(component Component) cs.Endpoint {
+	return func(ctx context.Context, req interface{}) (interface{}, error) {
+		var m = req.(map[string]string)
 
-		return LocationHeader{
-			URL: url,
-		}, nil
+		return component.GetRoles(ctx, m[prmRealm])
 	}
 }
 
-// MakeGetGroupsEndpoint creates an endpoint for GetGroups
-func MakeGetGroupsEndpoint(component Component) cs.Endpoint {
+// MakeGetRoleEndpoint creates an endpoint for GetRole
+func MakeGetRoleEndpoint(component Component) cs.Endpoint {
 	return func(ctx context.Context, req interface{}) (interface{}, error) {
 		var m = req.(map[string]string)
 
-		return component.GetGroups(ctx, m[prmRealm])
+		return component.GetRole(ctx, m[prmRealm], m[prmRoleID])
 	}
 }
 
-// MakeCreateGroupEndpoint makes the endpoint to create a group.
-func MakeCreateGroupEndpoint(component Component, logger keycloakb.Logger) cs.Endpoint {
+// MakeCreateRoleEndpoint creates an endpoint for CreateRole
+func MakeCreateRoleEndpoint(component Component, logger keycloakb.Logger) cs.Endpoint {
 	return func(ctx context.Context, req interface{}) (interface{}, error) {
 		var m = req.(map[string]string)
 		var err error
 
-		var group api.GroupRepresentation
+		var role api.RoleRepresentation
 
-		if err = json.Unmarshal([]byte(m[reqBody]), &group); err != nil {
+		if err = json.Unmarshal([]byte(m[reqBody]), &role); err != nil {
 			return nil, errorhandler.CreateBadRequestError(msg.MsgErrInvalidParam + "." + msg.Body)
 		}
 
-		if err = group.Validate(); err != nil {
+		if err = role.Validate(); err != nil {
 			return nil, err
 		}
 
+		if role.Name == nil {
+			return nil, errorhandler.CreateMissingParameterError(msg.Name)
+		}
+
 		var keycloakLocation string
-		keycloakLocation, err = component.CreateGroup(ctx, m[prmRealm], group)
+		keycloakLocation, err = component.CreateRole(ctx, m[prmRealm], role)
 
 		if err != nil {
 			return nil, err
@@ -591,83 +869,540 @@ func MakeCreateGroupEndpoint(component Component, logger keycloakb.Logger) cs.En
 	}
 }
 
-// MakeDeleteGroupEndpoint creates an endpoint for DeleteGroup
-func MakeDeleteGroupEndpoint(component Component) cs.Endpoint {
+// MakeUpdateRoleEndpoint creates an endpoint for UpdateRole
+func MakeUpdateRoleEndpoint(component Component) cs.Endpoint {
 	return func(ctx context.Context, req interface{}) (interface{}, error) {
 		var m = req.(map[string]string)
+		var err error
+
+		var role api.RoleRepresentation
+
+		if err = json.Unmarshal([]byte(m[reqBody]), &role); err != nil {
+			return nil, errorhandler.CreateBadRequestError(msg.MsgErrInvalidParam + "." + msg.Body)
+		}
+
+		if err = role.Validate(); err != nil {
+			return nil, err
+		}
 
-		return nil, component.DeleteGroup(ctx, m[prmRealm], m[prmGroupID])
+		return nil, component.UpdateRole(ctx, m[prmRealm], m[prmRoleID], role)
 	}
 }
 
-// MakeGetAuthorizationsEndpoint creates an endpoint for GetAuthorizations
-func MakeGetAuthorizationsEndpoint(component Component) cs.Endpoint {
+// MakeDeleteRoleEndpoint creates an endpoint for DeleteRole
+func MakeDeleteRoleEndpoint(component Component) cs.Endpoint {
 	return func(ctx context.Context, req interface{}) (interface{}, error) {
 		var m = req.(map[string]string)
 
-		return component.GetAuthorizations(ctx, m[prmRealm], m[prmGroupID])
+		return nil, component.DeleteRole(ctx, m[prmRealm], m[prmRoleID])
 	}
 }
 
-// MakeUpdateAuthorizationsEndpoint creates an endpoint for UpdateAuthorizations
-func MakeUpdateAuthorizationsEndpoint(component Component) cs.Endpoint {
+// MakeGetRoleCompositesEndpoint creates an endpoint for GetRoleComposites
+func MakeGetRoleCompositesEndpoint(component Component) cs.Endpoint {
 	return func(ctx context.Context, req interface{}) (interface{}, error) {
 		var m = req.(map[string]string)
-		var err error
-
-		var authorizations api.AuthorizationsRepresentation
-
-		if err = json.Unmarshal([]byte(m[reqBody]), &authorizations); err != nil {
-			return nil, errorhandler.CreateBadRequestError(msg.MsgErrInvalidParam + "." + msg.Body)
-		}
-
-		return nil, component.UpdateAuthorizations(ctx, m[prmRealm], m[prmGroupID], authorizations)
-	}
-}
 
-// MakeGetActionsEndpoint creates an endpoint for GetActions
-func MakeGetActionsEndpoint(component Component) cs.Endpoint {
-	return func(ctx context.Context, _ interface{}) (interface{}, error) {
-		return component.GetActions(ctx)
+		return component.GetRoleComposites(ctx, m[prmRealm], m[prmRoleID])
 	}
 }
 
-// MakeGetRealmCustomConfigurationEndpoint creates an endpoint for GetRealmCustomConfiguration
-func MakeGetRealmCustomConfigurationEndpoint(component Component) cs.Endpoint {
+// MakeAddRoleCompositesEndpoint creates an endpoint for AddRoleComposites
+func MakeAddRoleCompositesEndpoint(component Component) cs.Endpoint {
 	return func(ctx context.Context, req interface{}) (interface{}, error) {
 		var m = req.(map[string]string)
 
-		return component.GetRealmCustomConfiguration(ctx, m[prmRealm])
+		var roles, err = getCompositeRolesFromBody(m[reqBody])
+		if err != nil {
+			return nil, err
+		}
+
+		return nil, component.AddRoleComposites(ctx, m[prmRealm], m[prmRoleID], roles)
 	}
 }
 
-// MakeUpdateRealmCustomConfigurationEndpoint creates an endpoint for UpdateRealmCustomConfiguration
-func MakeUpdateRealmCustomConfigurationEndpoint(component Component) cs.Endpoint {
+// MakeDeleteRoleCompositesEndpoint creates an endpoint for DeleteRoleComposites
+func MakeDeleteRoleCompositesEndpoint(component Component) cs.Endpoint {
 	return func(ctx context.Context, req interface{}) (interface{}, error) {
 		var m = req.(map[string]string)
-		var err error
 
-		configJSON := []byte(m[reqBody])
+		var roles, err = getCompositeRolesFromBody(m[reqBody])
+		if err != nil {
+			return nil, err
+		}
 
-		var customConfig api.RealmCustomConfiguration
+		return nil, component.DeleteRoleComposites(ctx, m[prmRealm], m[prmRoleID], roles)
+	}
+}
 
-		if err = json.Unmarshal(configJSON, &customConfig); err != nil {
-			return nil, errorhandler.CreateBadRequestError(msg.MsgErrInvalidParam + "." + msg.Body)
-		}
+func getCompositeRolesFromBody(body string) ([]api.RoleRepresentation, error) {
+	var roles []api.RoleRepresentation
 
-		if err = customConfig.Validate(); err != nil {
+	if err := json.Unmarshal([]byte(body), &roles); err != nil {
+		return nil, errorhandler.CreateBadRequestError(msg.MsgErrInvalidParam + "." + msg.Body)
+	}
+
+	for _, role := range roles {
+		if err := role.Validate(); err != nil {
 			return nil, err
 		}
-
-		return nil, component.UpdateRealmCustomConfiguration(ctx, m[prmRealm], customConfig)
+		if role.ID == nil {
+			return nil, errorhandler.CreateMissingParameterError(msg.RoleID)
+		}
 	}
+
+	return roles, nil
 }
 
-// MakeGetRealmAdminConfigurationEndpoint creates an endpoint for GetRealmAdminConfiguration
-func MakeGetRealmAdminConfigurationEndpoint(component Component) cs.Endpoint {
+// MakeGetUsersWithRoleEndpoint creates an endpoint for GetUsersWithRole
+func MakeGetUsersWithRoleEndpoint(component Component) cs.Endpoint {
 	return func(ctx context.Context, req interface{}) (interface{}, error) {
 		var m = req.(map[string]string)
-		return component.GetRealmAdminConfiguration(ctx, m[prmRealm])
+
+		var paramKV []string
+		for _, key := range []string{prmQryFirst, prmQryMax} {
+			if m[key] != "" {
+				paramKV = append(paramKV, key, m[key])
+			}
+		}
+
+		return component.GetUsersWithRole(ctx, m[prmRealm], m[prmRoleID], paramKV...)
+	}
+}
+
+// MakeGetClientRolesEndpoint creates an endpoint for GetClientRoles
+func MakeGetClientRolesEndpoint(component Component) cs.Endpoint {
+	return func(ctx context.Context, req interface{}) (interface{}, error) {
+		var m = req.(map[string]string)
+
+		return component.GetClientRoles(ctx, m[prmRealm], m[prmClientID])
+	}
+}
+
+// MakeCreateClientRoleEndpoint creates an endpoint for CreateClientRole
+func MakeCreateClientRoleEndpoint(component Component, logger keycloakb.Logger) cs.Endpoint {
+	return func(ctx context.Context, req interface{}) (interface{}, error) {
+		var m = req.(map[string]string)
+		var err error
+
+		var role api.RoleRepresentation
+
+		if err = json.Unmarshal([]byte(m[reqBody]), &role); err != nil {
+			return nil, errorhandler.CreateBadRequestError(msg.MsgErrInvalidParam + "." + msg.Body)
+		}
+
+		if err = role.Validate(); err != nil {
+			return nil, err
+		}
+
+		var keycloakLocation string
+		keycloakLocation, err = component.CreateClientRole(ctx, m[prmRealm], m[prmClientID], role)
+
+		if err != nil {
+			return nil, err
+		}
+
+		url, err := convertLocationURL(keycloakLocation, m[reqScheme], m[reqHost])
+		if err != nil {
+			logger.Warn(ctx, "msg", "Invalid location", "location", keycloakLocation, "err", err.Error())
+		}
+
+		return LocationHeader{
+			URL: url,
+		}, nil
+	}
+}
+
+// MakeDeleteClientRoleEndpoint creates an endpoint for DeleteClientRole
+func MakeDeleteClientRoleEndpoint(component Component) cs.Endpoint {
+	return func(ctx context.Context, req interface{}) (interface{}, error) {
+		var m = req.(map[string]string)
+
+		return nil, component.DeleteClientRole(ctx, m[prmRealm], m[prmClientID], m[prmRoleID])
+	}
+}
+
+// MakeGetGroupsEndpoint creates an endpoint for GetGroups
+func MakeGetGroupsEndpoint(component Component) cs.Endpoint {
+	return func(ctx context.Context, req interface{}) (interface{}, error) {
+		var m = req.(map[string]string)
+
+		return component.GetGroups(ctx, m[prmRealm])
+	}
+}
+
+// MakeCreateGroupEndpoint makes the endpoint to create a group.
+func MakeCreateGroupEndpoint(component Component, logger keycloakb.Logger) cs.Endpoint {
+	return func(ctx context.Context, req interface{}) (interface{}, error) {
+		var m = req.(map[string]string)
+		var err error
+
+		var group api.GroupRepresentation
+
+		if err = json.Unmarshal([]byte(m[reqBody]), &group); err != nil {
+			return nil, errorhandler.CreateBadRequestError(msg.MsgErrInvalidParam + "." + msg.Body)
+		}
+
+		if err = group.Validate(); err != nil {
+			return nil, err
+		}
+
+		var keycloakLocation string
+		keycloakLocation, err = component.CreateGroup(ctx, m[prmRealm], group)
+
+		if err != nil {
+			return nil, err
+		}
+
+		url, err := convertLocationURL(keycloakLocation, m[reqScheme], m[reqHost])
+		if err != nil {
+			logger.Warn(ctx, "msg", "Invalid location", "location", keycloakLocation, "err", err.Error())
+		}
+
+		return LocationHeader{
+			URL: url,
+		}, nil
+	}
+}
+
+// MakeDeleteGroupEndpoint creates an endpoint for DeleteGroup
+func MakeDeleteGroupEndpoint(component Component) cs.Endpoint {
+	return func(ctx context.Context, req interface{}) (interface{}, error) {
+		var m = req.(map[string]string)
+
+		return replyPendingApproval(nil, component.DeleteGroup(ctx, m[prmRealm], m[prmGroupID]))
+	}
+}
+
+// MakeGetGroupEndpoint creates an endpoint for GetGroup
+func MakeGetGroupEndpoint(component Component) cs.Endpoint {
+	return func(ctx context.Context, req interface{}) (interface{}, error) {
+		var m = req.(map[string]string)
+
+		return component.GetGroup(ctx, m[prmRealm], m[prmGroupID])
+	}
+}
+
+// MakeUpdateGroupEndpoint creates an endpoint for UpdateGroup
+func MakeUpdateGroupEndpoint(component Component) cs.Endpoint {
+	return func(ctx context.Context, req interface{}) (interface{}, error) {
+		var m = req.(map[string]string)
+		var err error
+
+		var group api.GroupRepresentation
+
+		if err = json.Unmarshal([]byte(m[reqBody]), &group); err != nil {
+			return nil, errorhandler.CreateBadRequestError(msg.MsgErrInvalidParam + "." + msg.Body)
+		}
+
+		if err = group.Validate(); err != nil {
+			return nil, err
+		}
+
+		return replyPendingApproval(nil, component.UpdateGroup(ctx, m[prmRealm], m[prmGroupID], group))
+	}
+}
+
+// MakeCreateGroupChildEndpoint makes the endpoint to create a subgroup.
+func MakeCreateGroupChildEndpoint(component Component, logger keycloakb.Logger) cs.Endpoint {
+	return func(ctx context.Context, req interface{}) (interface{}, error) {
+		var m = req.(map[string]string)
+		var err error
+
+		var group api.GroupRepresentation
+
+		if err = json.Unmarshal([]byte(m[reqBody]), &group); err != nil {
+			return nil, errorhandler.CreateBadRequestError(msg.MsgErrInvalidParam + "." + msg.Body)
+		}
+
+		if err = group.Validate(); err != nil {
+			return nil, err
+		}
+
+		if group.Name == nil {
+			return nil, errorhandler.CreateMissingParameterError(msg.Name)
+		}
+
+		var keycloakLocation string
+		keycloakLocation, err = component.CreateGroupChild(ctx, m[prmRealm], m[prmGroupID], group)
+
+		if err != nil {
+			return nil, err
+		}
+
+		url, err := convertLocationURL(keycloakLocation, m[reqScheme], m[reqHost])
+		if err != nil {
+			logger.Warn(ctx, "msg", "Invalid location", "location", keycloakLocation, "err", err.Error())
+		}
+
+		return LocationHeader{
+			URL: url,
+		}, nil
+	}
+}
+
+// MakeMoveGroupEndpoint creates an endpoint for MoveGroup
+func MakeMoveGroupEndpoint(component Component) cs.Endpoint {
+	return func(ctx context.Context, req interface{}) (interface{}, error) {
+		var m = req.(map[string]string)
+
+		return nil, component.MoveGroup(ctx, m[prmRealm], m[prmGroupID], m[prmChildGroupID])
+	}
+}
+
+// MakeGetGroupMembersEndpoint creates an endpoint for GetGroupMembers
+func MakeGetGroupMembersEndpoint(component Component) cs.Endpoint {
+	return func(ctx context.Context, req interface{}) (interface{}, error) {
+		var m = req.(map[string]string)
+
+		var paramKV []string
+		for _, key := range []string{prmQryFirst, prmQryMax} {
+			if m[key] != "" {
+				paramKV = append(paramKV, key, m[key])
+			}
+		}
+
+		return component.GetGroupMembers(ctx, m[prmRealm], m[prmGroupID], paramKV...)
+	}
+}
+
+// MakeGetAuthorizationsEndpoint creates an endpoint for GetAuthorizations
+func MakeGetAuthorizationsEndpoint(component Component) cs.Endpoint {
+	return func(ctx context.Context, req interface{}) (interface{}, error) {
+		var m = req.(map[string]string)
+
+		return component.GetAuthorizations(ctx, m[prmRealm], m[prmGroupID])
+	}
+}
+
+// MakeUpdateAuthorizationsEndpoint creates an endpoint for UpdateAuthorizations
+func MakeUpdateAuthorizationsEndpoint(component Component) cs.Endpoint {
+	return func(ctx context.Context, req interface{}) (interface{}, error) {
+		var m = req.(map[string]string)
+		var err error
+
+		var authorizations api.AuthorizationsRepresentation
+
+		if err = json.Unmarshal([]byte(m[reqBody]), &authorizations); err != nil {
+			return nil, errorhandler.CreateBadRequestError(msg.MsgErrInvalidParam + "." + msg.Body)
+		}
+
+		return replyPendingApproval(nil, component.UpdateAuthorizations(ctx, m[prmRealm], m[prmGroupID], authorizations))
+	}
+}
+
+// MakeGetAuthorizationsVersionsEndpoint creates an endpoint for GetAuthorizationsVersions
+func MakeGetAuthorizationsVersionsEndpoint(component Component) cs.Endpoint {
+	return func(ctx context.Context, req interface{}) (interface{}, error) {
+		var m = req.(map[string]string)
+
+		return component.GetAuthorizationsVersions(ctx, m[prmRealm], m[prmGroupID])
+	}
+}
+
+// MakeGetAuthorizationsVersionsDiffEndpoint creates an endpoint for GetAuthorizationsVersionsDiff
+func MakeGetAuthorizationsVersionsDiffEndpoint(component Component) cs.Endpoint {
+	return func(ctx context.Context, req interface{}) (interface{}, error) {
+		var m = req.(map[string]string)
+
+		fromVersion, err := strconv.Atoi(m[prmQryFrom])
+		if err != nil {
+			return nil, errorhandler.CreateMissingParameterError(msg.From)
+		}
+		toVersion, err := strconv.Atoi(m[prmQryTo])
+		if err != nil {
+			return nil, errorhandler.CreateMissingParameterError(msg.To)
+		}
+
+		return component.GetAuthorizationsVersionsDiff(ctx, m[prmRealm], m[prmGroupID], fromVersion, toVersion)
+	}
+}
+
+// MakeRollbackAuthorizationsEndpoint creates an endpoint for RollbackAuthorizations
+func MakeRollbackAuthorizationsEndpoint(component Component) cs.Endpoint {
+	return func(ctx context.Context, req interface{}) (interface{}, error) {
+		var m = req.(map[string]string)
+
+		version, err := strconv.Atoi(m[prmVersion])
+		if err != nil {
+			return nil, errorhandler.CreateBadRequestError(msg.MsgErrInvalidParam + "." + msg.Version)
+		}
+
+		return replyPendingApproval(nil, component.RollbackAuthorizations(ctx, m[prmRealm], m[prmGroupID], version))
+	}
+}
+
+// MakeGetPendingRequestsEndpoint creates an endpoint for GetPendingRequests
+func MakeGetPendingRequestsEndpoint(component Component) cs.Endpoint {
+	return func(ctx context.Context, req interface{}) (interface{}, error) {
+		var m = req.(map[string]string)
+
+		return component.GetPendingRequests(ctx, m[prmRealm])
+	}
+}
+
+// MakeGetPendingRequestEndpoint creates an endpoint for GetPendingRequest
+func MakeGetPendingRequestEndpoint(component Component) cs.Endpoint {
+	return func(ctx context.Context, req interface{}) (interface{}, error) {
+		var m = req.(map[string]string)
+
+		return component.GetPendingRequest(ctx, m[prmRealm], m[prmRequestID])
+	}
+}
+
+// MakeApprovePendingRequestEndpoint creates an endpoint for ApprovePendingRequest
+func MakeApprovePendingRequestEndpoint(component Component) cs.Endpoint {
+	return func(ctx context.Context, req interface{}) (interface{}, error) {
+		var m = req.(map[string]string)
+
+		pwd, err := component.ApprovePendingRequest(ctx, m[prmRealm], m[prmRequestID])
+		if pwd != "" {
+			return pwd, err
+		}
+		return nil, err
+	}
+}
+
+// MakeRejectPendingRequestEndpoint creates an endpoint for RejectPendingRequest
+func MakeRejectPendingRequestEndpoint(component Component) cs.Endpoint {
+	return func(ctx context.Context, req interface{}) (interface{}, error) {
+		var m = req.(map[string]string)
+
+		return nil, component.RejectPendingRequest(ctx, m[prmRealm], m[prmRequestID])
+	}
+}
+
+// MakeCheckAuthorizationEndpoint creates an endpoint for CheckAuthorization
+func MakeCheckAuthorizationEndpoint(component Component) cs.Endpoint {
+	return func(ctx context.Context, req interface{}) (interface{}, error) {
+		var m = req.(map[string]string)
+
+		if m[prmQryAction] == "" {
+			return nil, errorhandler.CreateMissingParameterError(msg.Action)
+		}
+
+		return component.CheckAuthorization(ctx, m[prmRealm], m[prmQryUser], m[prmQryAction], m[prmQryTargetRealm], m[prmQryTargetGroup])
+	}
+}
+
+// MakeGetAuthorizationTemplatesEndpoint creates an endpoint for GetAuthorizationTemplates
+func MakeGetAuthorizationTemplatesEndpoint(component Component) cs.Endpoint {
+	return func(ctx context.Context, _ interface{}) (interface{}, error) {
+		return component.GetAuthorizationTemplates(ctx)
+	}
+}
+
+// MakeGetAuthorizationTemplateEndpoint creates an endpoint for GetAuthorizationTemplate
+func MakeGetAuthorizationTemplateEndpoint(component Component) cs.Endpoint {
+	return func(ctx context.Context, req interface{}) (interface{}, error) {
+		var m = req.(map[string]string)
+
+		return component.GetAuthorizationTemplate(ctx, m[prmTemplateName])
+	}
+}
+
+// MakeUpdateAuthorizationTemplateEndpoint creates an endpoint for UpdateAuthorizationTemplate
+func MakeUpdateAuthorizationTemplateEndpoint(component Component) cs.Endpoint {
+	return func(ctx context.Context, req interface{}) (interface{}, error) {
+		var m = req.(map[string]string)
+		var err error
+
+		var template api.AuthorizationTemplateRepresentation
+
+		if err = json.Unmarshal([]byte(m[reqBody]), &template); err != nil {
+			return nil, errorhandler.CreateBadRequestError(msg.MsgErrInvalidParam + "." + msg.Body)
+		}
+
+		if err = template.Validate(); err != nil {
+			return nil, err
+		}
+
+		return nil, component.UpdateAuthorizationTemplate(ctx, m[prmTemplateName], template)
+	}
+}
+
+// MakeDeleteAuthorizationTemplateEndpoint creates an endpoint for DeleteAuthorizationTemplate
+func MakeDeleteAuthorizationTemplateEndpoint(component Component) cs.Endpoint {
+	return func(ctx context.Context, req interface{}) (interface{}, error) {
+		var m = req.(map[string]string)
+
+		return nil, component.DeleteAuthorizationTemplate(ctx, m[prmTemplateName])
+	}
+}
+
+// MakeApplyAuthorizationTemplateEndpoint creates an endpoint for ApplyAuthorizationTemplate
+func MakeApplyAuthorizationTemplateEndpoint(component Component) cs.Endpoint {
+	return func(ctx context.Context, req interface{}) (interface{}, error) {
+		var m = req.(map[string]string)
+
+		return replyPendingApproval(nil, component.ApplyAuthorizationTemplate(ctx, m[prmRealm], m[prmGroupID], m[prmTemplateName], m[prmQryTargetRealm]))
+	}
+}
+
+// MakeCopyAuthorizationsEndpoint creates an endpoint for CopyAuthorizations
+func MakeCopyAuthorizationsEndpoint(component Component) cs.Endpoint {
+	return func(ctx context.Context, req interface{}) (interface{}, error) {
+		var m = req.(map[string]string)
+
+		if m[prmQryTargetRealm] == "" {
+			return nil, errorhandler.CreateMissingParameterError(msg.TargetRealm)
+		}
+
+		return replyPendingApproval(component.CopyAuthorizations(ctx, m[prmRealm], m[prmQryTargetRealm], m[prmQryDryRun] == "true"))
+	}
+}
+
+// MakeGetActionsEndpoint creates an endpoint for GetActions
+func MakeGetActionsEndpoint(component Component) cs.Endpoint {
+	return func(ctx context.Context, _ interface{}) (interface{}, error) {
+		return component.GetActions(ctx)
+	}
+}
+
+// MakeGetRealmCustomConfigurationEndpoint creates an endpoint for GetRealmCustomConfiguration
+func MakeGetRealmCustomConfigurationEndpoint(component Component) cs.Endpoint {
+	return func(ctx context.Context, req interface{}) (interface{}, error) {
+		var m = req.(map[string]string)
+
+		config, etag, err := component.GetRealmCustomConfiguration(ctx, m[prmRealm])
+		if err != nil {
+			return nil, err
+		}
+		return keycloakb.ETagReply{ETag: etag, Value: config}, nil
+	}
+}
+
+// MakeUpdateRealmCustomConfigurationEndpoint creates an endpoint for UpdateRealmCustomConfiguration
+func MakeUpdateRealmCustomConfigurationEndpoint(component Component) cs.Endpoint {
+	return func(ctx context.Context, req interface{}) (interface{}, error) {
+		var m = req.(map[string]string)
+		var err error
+
+		configJSON := []byte(m[reqBody])
+
+		var customConfig api.RealmCustomConfiguration
+
+		if err = json.Unmarshal(configJSON, &customConfig); err != nil {
+			return nil, errorhandler.CreateBadRequestError(msg.MsgErrInvalidParam + "." + msg.Body)
+		}
+
+		if err = customConfig.Validate(); err != nil {
+			return nil, err
+		}
+
+		return nil, component.UpdateRealmCustomConfiguration(ctx, m[prmRealm], customConfig, m[reqIfMatch])
+	}
+}
+
+// MakeGetRealmAdminConfigurationEndpoint creates an endpoint for GetRealmAdminConfiguration
+func MakeGetRealmAdminConfigurationEndpoint(component Component) cs.Endpoint {
+	return func(ctx context.Context, req interface{}) (interface{}, error) {
+		var m = req.(map[string]string)
+		config, etag, err := component.GetRealmAdminConfiguration(ctx, m[prmRealm])
+		if err != nil {
+			return nil, err
+		}
+		return keycloakb.ETagReply{ETag: etag, Value: config}, nil
 	}
 }
 
@@ -689,7 +1424,89 @@ func MakeUpdateRealmAdminConfigurationEndpoint(component Component) cs.Endpoint
 			return nil, err
 		}
 
-		return nil, component.UpdateRealmAdminConfiguration(ctx, m[prmRealm], adminConfig)
+		return replyPendingApproval(nil, component.UpdateRealmAdminConfiguration(ctx, m[prmRealm], adminConfig, m[reqIfMatch]))
+	}
+}
+
+// MakeGetRealmCustomConfigurationVersionsEndpoint creates an endpoint for GetRealmCustomConfigurationVersions
+func MakeGetRealmCustomConfigurationVersionsEndpoint(component Component) cs.Endpoint {
+	return func(ctx context.Context, req interface{}) (interface{}, error) {
+		var m = req.(map[string]string)
+
+		return component.GetRealmCustomConfigurationVersions(ctx, m[prmRealm])
+	}
+}
+
+// MakeGetRealmCustomConfigurationVersionsDiffEndpoint creates an endpoint for GetRealmCustomConfigurationVersionsDiff
+func MakeGetRealmCustomConfigurationVersionsDiffEndpoint(component Component) cs.Endpoint {
+	return func(ctx context.Context, req interface{}) (interface{}, error) {
+		var m = req.(map[string]string)
+
+		fromVersion, err := strconv.Atoi(m[prmQryFrom])
+		if err != nil {
+			return nil, errorhandler.CreateMissingParameterError(msg.From)
+		}
+		toVersion, err := strconv.Atoi(m[prmQryTo])
+		if err != nil {
+			return nil, errorhandler.CreateMissingParameterError(msg.To)
+		}
+
+		return component.GetRealmCustomConfigurationVersionsDiff(ctx, m[prmRealm], fromVersion, toVersion)
+	}
+}
+
+// MakeRollbackRealmCustomConfigurationEndpoint creates an endpoint for RollbackRealmCustomConfiguration
+func MakeRollbackRealmCustomConfigurationEndpoint(component Component) cs.Endpoint {
+	return func(ctx context.Context, req interface{}) (interface{}, error) {
+		var m = req.(map[string]string)
+
+		version, err := strconv.Atoi(m[prmVersion])
+		if err != nil {
+			return nil, errorhandler.CreateBadRequestError(msg.MsgErrInvalidParam + "." + msg.Version)
+		}
+
+		return nil, component.RollbackRealmCustomConfiguration(ctx, m[prmRealm], version)
+	}
+}
+
+// MakeGetRealmAdminConfigurationVersionsEndpoint creates an endpoint for GetRealmAdminConfigurationVersions
+func MakeGetRealmAdminConfigurationVersionsEndpoint(component Component) cs.Endpoint {
+	return func(ctx context.Context, req interface{}) (interface{}, error) {
+		var m = req.(map[string]string)
+
+		return component.GetRealmAdminConfigurationVersions(ctx, m[prmRealm])
+	}
+}
+
+// MakeGetRealmAdminConfigurationVersionsDiffEndpoint creates an endpoint for GetRealmAdminConfigurationVersionsDiff
+func MakeGetRealmAdminConfigurationVersionsDiffEndpoint(component Component) cs.Endpoint {
+	return func(ctx context.Context, req interface{}) (interface{}, error) {
+		var m = req.(map[string]string)
+
+		fromVersion, err := strconv.Atoi(m[prmQryFrom])
+		if err != nil {
+			return nil, errorhandler.CreateMissingParameterError(msg.From)
+		}
+		toVersion, err := strconv.Atoi(m[prmQryTo])
+		if err != nil {
+			return nil, errorhandler.CreateMissingParameterError(msg.To)
+		}
+
+		return component.GetRealmAdminConfigurationVersionsDiff(ctx, m[prmRealm], fromVersion, toVersion)
+	}
+}
+
+// MakeRollbackRealmAdminConfigurationEndpoint creates an endpoint for RollbackRealmAdminConfiguration
+func MakeRollbackRealmAdminConfigurationEndpoint(component Component) cs.Endpoint {
+	return func(ctx context.Context, req interface{}) (interface{}, error) {
+		var m = req.(map[string]string)
+
+		version, err := strconv.Atoi(m[prmVersion])
+		if err != nil {
+			return nil, errorhandler.CreateBadRequestError(msg.MsgErrInvalidParam + "." + msg.Version)
+		}
+
+		return replyPendingApproval(nil, component.RollbackRealmAdminConfiguration(ctx, m[prmRealm], version))
 	}
 }
 
@@ -758,11 +1575,48 @@ func MakeLinkShadowUserEndpoint(component Component) cs.Endpoint {
 	}
 }
 
+// MakeGetFederatedIdentitiesEndpoint makes the endpoint to get the federated identities of a user.
+func MakeGetFederatedIdentitiesEndpoint(component Component) cs.Endpoint {
+	return func(ctx context.Context, req interface{}) (interface{}, error) {
+		var m = req.(map[string]string)
+
+		return component.GetFederatedIdentities(ctx, m[prmRealm], m[prmUserID])
+	}
+}
+
+// MakeUnlinkShadowUserEndpoint makes the endpoint to remove the link between a user and a login provider.
+func MakeUnlinkShadowUserEndpoint(component Component) cs.Endpoint {
+	return func(ctx context.Context, req interface{}) (interface{}, error) {
+		var m = req.(map[string]string)
+
+		if err := api.ValidateIdentityProvider(m[prmProvider]); err != nil {
+			return nil, err
+		}
+
+		return nil, component.UnlinkShadowUser(ctx, m[prmRealm], m[prmUserID], m[prmProvider])
+	}
+}
+
 // LocationHeader type
 type LocationHeader struct {
 	URL string
 }
 
+// PendingApprovalReply is the reply of an action recorded as a pending request instead of being executed. It is
+// encoded as 202 Accepted with the pending request
+type PendingApprovalReply struct {
+	Request api.PendingRequestRepresentation
+}
+
+// replyPendingApproval converts the PendingApproval returned by the component into a reply: an action waiting for an
+// approval is not a failure for the endpoint middlewares (logging, metrics and tracing)
+func replyPendingApproval(rep interface{}, err error) (interface{}, error) {
+	if pending, ok := err.(PendingApproval); ok {
+		return PendingApprovalReply{Request: pending.Request}, nil
+	}
+	return rep, err
+}
+
 // ConvertLocationError type
 type ConvertLocationError struct {
 	Location string
@@ -9,6 +9,7 @@ import (
 
 	"github.com/cloudtrust/common-service/log"
 	api "github.com/cloudtrust/keycloak-bridge/api/management"
+	"github.com/cloudtrust/keycloak-bridge/internal/keycloakb"
 	"github.com/cloudtrust/keycloak-bridge/pkg/management/mock"
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
@@ -194,10 +195,26 @@ func TestDeleteUserEndpoint(t *testing.T) {
 	req[prmRealm] = realm
 	req[prmUserID] = userID
 
-	mockManagementComponent.EXPECT().DeleteUser(ctx, realm, userID).Return(nil).Times(1)
-	var res, err = e(ctx, req)
-	assert.Nil(t, err)
-	assert.Nil(t, res)
+	t.Run("Deleted", func(t *testing.T) {
+		mockManagementComponent.EXPECT().DeleteUser(ctx, realm, userID).Return(nil).Times(1)
+		var res, err = e(ctx, req)
+		assert.Nil(t, err)
+		assert.Nil(t, res)
+	})
+	t.Run("Waiting for an approval", func(t *testing.T) {
+		var requestID = "0123456789abcdef0123456789abcdef"
+		var request = api.PendingRequestRepresentation{ID: &requestID}
+		mockManagementComponent.EXPECT().DeleteUser(ctx, realm, userID).Return(PendingApproval{Request: request}).Times(1)
+		var res, err = e(ctx, req)
+		assert.Nil(t, err)
+		assert.Equal(t, PendingApprovalReply{Request: request}, res)
+	})
+	t.Run("Error", func(t *testing.T) {
+		mockManagementComponent.EXPECT().DeleteUser(ctx, realm, userID).Return(errors.New("error")).Times(1)
+		var res, err = e(ctx, req)
+		assert.NotNil(t, err)
+		assert.Nil(t, res)
+	})
 }
 
 func TestGetUserEndpoint(t *testing.T) {
@@ -215,7 +232,37 @@ func TestGetUserEndpoint(t *testing.T) {
 	req[prmRealm] = realm
 	req[prmUserID] = userID
 
-	mockManagementComponent.EXPECT().GetUser(ctx, realm, userID).Return(api.UserRepresentation{}, nil).Times(1)
+	t.Run("No error", func(t *testing.T) {
+		mockManagementComponent.EXPECT().GetUser(ctx, realm, userID).Return(api.UserRepresentation{}, `"etag"`, nil).Times(1)
+		var res, err = e(ctx, req)
+		assert.Nil(t, err)
+		assert.Equal(t, keycloakb.ETagReply{ETag: `"etag"`, Value: api.UserRepresentation{}}, res)
+	})
+	t.Run("Error", func(t *testing.T) {
+		var expectedErr = errors.New("component error")
+		mockManagementComponent.EXPECT().GetUser(ctx, realm, userID).Return(api.UserRepresentation{}, "", expectedErr).Times(1)
+		var res, err = e(ctx, req)
+		assert.Equal(t, expectedErr, err)
+		assert.Nil(t, res)
+	})
+}
+
+func TestGetUserHistoryEndpoint(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	var mockManagementComponent = mock.NewManagementComponent(mockCtrl)
+
+	var e = MakeGetUserHistoryEndpoint(mockManagementComponent)
+
+	var realm = "master"
+	var userID = "1234-452-4578"
+	var ctx = context.Background()
+	var req = make(map[string]string)
+	req[prmRealm] = realm
+	req[prmUserID] = userID
+
+	mockManagementComponent.EXPECT().GetUserHistory(ctx, realm, userID).Return([]api.UserChangeRepresentation{}, nil).Times(1)
 	var res, err = e(ctx, req)
 	assert.Nil(t, err)
 	assert.NotNil(t, res)
@@ -239,8 +286,9 @@ func TestUpdateUserEndpoint(t *testing.T) {
 		req[prmUserID] = userID
 		userJSON, _ := json.Marshal(api.UserRepresentation{})
 		req[reqBody] = string(userJSON)
+		req[reqIfMatch] = `"etag"`
 
-		mockManagementComponent.EXPECT().UpdateUser(ctx, realm, userID, gomock.Any()).Return(nil).Times(1)
+		mockManagementComponent.EXPECT().UpdateUser(ctx, realm, userID, gomock.Any(), `"etag"`).Return(nil).Times(1)
 		var res, err = e(ctx, req)
 		assert.Nil(t, err)
 		assert.Nil(t, res)
@@ -367,6 +415,86 @@ func TestGetUsersEndpoint(t *testing.T) {
 	}
 }
 
+func TestSearchUsersEndpoint(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	var mockManagementComponent = mock.NewManagementComponent(mockCtrl)
+
+	var e = MakeSearchUsersEndpoint(mockManagementComponent)
+
+	var realm = "master"
+	var groupID = "123-784dsf-sdf567"
+	var ctx = context.Background()
+
+	t.Run("Missing mandatory parameter group", func(t *testing.T) {
+		var req = map[string]string{prmRealm: realm}
+		var _, err = e(ctx, req)
+		assert.NotNil(t, err)
+	})
+	t.Run("With criteria", func(t *testing.T) {
+		var phoneNumber = "+41791234567"
+		var documentNumber = "AB 123456"
+		var first, max = 10, 5
+		var req = map[string]string{
+			prmRealm:               realm,
+			prmQryGroupIDs:         groupID,
+			prmQryPhoneNumber:      phoneNumber,
+			prmQryIDDocumentNumber: documentNumber,
+			prmQryLocale:           "",
+			prmQryFirst:            "10",
+			prmQryMax:              "5",
+		}
+		var criteria = api.UsersSearchRepresentation{PhoneNumber: &phoneNumber, IDDocumentNumber: &documentNumber, First: &first, Max: &max}
+		mockManagementComponent.EXPECT().SearchUsers(ctx, realm, []string{groupID}, criteria).Return(api.UsersPageRepresentation{}, nil)
+		var _, err = e(ctx, req)
+		assert.Nil(t, err)
+	})
+}
+
+func TestDuplicateUsersEndpoints(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	var mockManagementComponent = mock.NewManagementComponent(mockCtrl)
+
+	var realm = "master"
+	var userID = "f467ed7c-0a1d-4eee-9bb8-669c6f89c000"
+	var duplicateUserID = "a2c4e6f8-0a1d-4eee-9bb8-669c6f89c000"
+	var groupID = "123-784dsf-sdf567"
+	var ctx = context.Background()
+
+	t.Run("GetDuplicateUsers: missing mandatory parameter group", func(t *testing.T) {
+		var _, err = MakeGetDuplicateUsersEndpoint(mockManagementComponent)(ctx, map[string]string{prmRealm: realm})
+		assert.NotNil(t, err)
+	})
+	t.Run("GetDuplicateUsers", func(t *testing.T) {
+		var req = map[string]string{prmRealm: realm, prmQryGroupIDs: groupID}
+		mockManagementComponent.EXPECT().GetDuplicateUsers(ctx, realm, []string{groupID}).Return([]api.DuplicateUsersRepresentation{}, nil)
+		var res, err = MakeGetDuplicateUsersEndpoint(mockManagementComponent)(ctx, req)
+		assert.Nil(t, err)
+		assert.NotNil(t, res)
+	})
+
+	t.Run("MergeUsers: invalid body", func(t *testing.T) {
+		var req = map[string]string{prmRealm: realm, prmUserID: userID, reqBody: "{"}
+		var _, err = MakeMergeUsersEndpoint(mockManagementComponent)(ctx, req)
+		assert.NotNil(t, err)
+	})
+	t.Run("MergeUsers: missing duplicate user", func(t *testing.T) {
+		var req = map[string]string{prmRealm: realm, prmUserID: userID, reqBody: `{"groups":true}`}
+		var _, err = MakeMergeUsersEndpoint(mockManagementComponent)(ctx, req)
+		assert.NotNil(t, err)
+	})
+	t.Run("MergeUsers", func(t *testing.T) {
+		var yes = true
+		var req = map[string]string{prmRealm: realm, prmUserID: userID, reqBody: `{"duplicateUserId":"` + duplicateUserID + `","checks":true}`}
+		mockManagementComponent.EXPECT().MergeUsers(ctx, realm, userID, api.UsersMergeRepresentation{DuplicateUserID: &duplicateUserID, Checks: &yes}).Return(nil)
+		var _, err = MakeMergeUsersEndpoint(mockManagementComponent)(ctx, req)
+		assert.Nil(t, err)
+	})
+}
+
 func TestGetUserAccountStatusEndpoint(t *testing.T) {
 	var mockCtrl = gomock.NewController(t)
 	defer mockCtrl.Finish()
@@ -511,7 +639,8 @@ func TestGetTrustIDGroupsOfUserEndpoint(t *testing.T) {
 	var req = map[string]string{prmRealm: realm, prmUserID: userID}
 
 	t.Run("No error", func(t *testing.T) {
-		mockManagementComponent.EXPECT().GetTrustIDGroupsOfUser(ctx, realm, userID).Return([]string{"grp1", "grp2"}, nil).Times(1)
+		var grp1, grp2 = "grp1", "grp2"
+		mockManagementComponent.EXPECT().GetTrustIDGroupsOfUser(ctx, realm, userID).Return([]api.TrustIDGroupRepresentation{{Name: &grp1}, {Name: &grp2}}, nil).Times(1)
 		var res, err = e(ctx, req)
 		assert.Nil(t, err)
 		assert.Len(t, res, 2)
@@ -540,7 +669,8 @@ func TestSetTrustIDGroupsToUserEndpoint(t *testing.T) {
 		var req = make(map[string]string)
 		req[prmRealm] = realm
 		req[prmUserID] = userID
-		body := []string{"grp1", "grp2"}
+		var grp1, grp2 = "grp1", "grp2"
+		body := []api.TrustIDGroupRepresentation{{Name: &grp1}, {Name: &grp2}}
 		req[reqBody] = string("[\"grp1\", \"grp2\"]")
 
 		mockManagementComponent.EXPECT().SetTrustIDGroupsToUser(ctx, realm, userID, body).Return(nil).Times(1)
@@ -549,6 +679,25 @@ func TestSetTrustIDGroupsToUserEndpoint(t *testing.T) {
 		assert.Nil(t, res)
 	})
 
+	t.Run("Groups with expiry date", func(t *testing.T) {
+		var realm = "master"
+		var userID = "123-123-456"
+		var ctx = context.Background()
+		var req = make(map[string]string)
+		req[prmRealm] = realm
+		req[prmUserID] = userID
+		var grp1, grp2 = "grp1", "grp2"
+		var expiresAt = int64(1900000000)
+		var justification = "support ticket #42"
+		body := []api.TrustIDGroupRepresentation{{Name: &grp1, ExpiresAt: &expiresAt, Justification: &justification}, {Name: &grp2}}
+		req[reqBody] = `[{"name":"grp1","expiresAt":1900000000,"justification":"support ticket #42"},{"name":"grp2"}]`
+
+		mockManagementComponent.EXPECT().SetTrustIDGroupsToUser(ctx, realm, userID, body).Return(nil).Times(1)
+		var res, err = e(ctx, req)
+		assert.Nil(t, err)
+		assert.Nil(t, res)
+	})
+
 	t.Run("Bad input", func(t *testing.T) {
 		var realm = "master"
 		var userID = "123-123-456"
@@ -635,6 +784,27 @@ func TestAddClientRolesToUserEndpoint(t *testing.T) {
 	}
 }
 
+func TestDeleteClientRoleFromUserEndpoint(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	var mockManagementComponent = mock.NewManagementComponent(mockCtrl)
+
+	var e = MakeDeleteClientRoleFromUserEndpoint(mockManagementComponent)
+
+	var realm = "master"
+	var userID = "123-123-456"
+	var clientID = "456-789-741"
+	var roleID = "789-654-123"
+	var ctx = context.Background()
+	var req = map[string]string{prmRealm: realm, prmUserID: userID, prmClientID: clientID, prmRoleID: roleID}
+
+	mockManagementComponent.EXPECT().DeleteClientRoleFromUser(ctx, realm, userID, clientID, roleID).Return(nil).Times(1)
+	var res, err = e(ctx, req)
+	assert.Nil(t, err)
+	assert.Nil(t, res)
+}
+
 func TestResetPasswordEndpoint(t *testing.T) {
 	var mockCtrl = gomock.NewController(t)
 	defer mockCtrl.Finish()
@@ -959,6 +1129,51 @@ func TestBruteForceEndpoints(t *testing.T) {
 	})
 }
 
+func TestSessionsEndpoints(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	var mockManagementComponent = mock.NewManagementComponent(mockCtrl)
+
+	var realm = "master"
+	var userID = "123-456-789"
+	var sessionID = "987-654-321"
+	var clientID = "backoffice"
+	var ctx = context.Background()
+	var req = map[string]string{prmRealm: realm, prmUserID: userID, prmSessionID: sessionID, prmClientID: clientID}
+
+	t.Run("MakeGetSessionsOfUserEndpoint", func(t *testing.T) {
+		mockManagementComponent.EXPECT().GetSessionsOfUser(ctx, realm, userID).Return([]api.UserSessionRepresentation{}, nil).Times(1)
+		var _, err = MakeGetSessionsOfUserEndpoint(mockManagementComponent)(ctx, req)
+		assert.Nil(t, err)
+	})
+	t.Run("MakeLogoutUserEndpoint", func(t *testing.T) {
+		mockManagementComponent.EXPECT().LogoutUser(ctx, realm, userID).Return(nil).Times(1)
+		var _, err = MakeLogoutUserEndpoint(mockManagementComponent)(ctx, req)
+		assert.Nil(t, err)
+	})
+	t.Run("MakeDeleteSessionOfUserEndpoint", func(t *testing.T) {
+		mockManagementComponent.EXPECT().DeleteSessionOfUser(ctx, realm, userID, sessionID).Return(nil).Times(1)
+		var _, err = MakeDeleteSessionOfUserEndpoint(mockManagementComponent)(ctx, req)
+		assert.Nil(t, err)
+	})
+	t.Run("MakeGetOfflineSessionsOfUserEndpoint", func(t *testing.T) {
+		mockManagementComponent.EXPECT().GetOfflineSessionsOfUser(ctx, realm, userID, clientID).Return([]api.UserSessionRepresentation{}, nil).Times(1)
+		var _, err = MakeGetOfflineSessionsOfUserEndpoint(mockManagementComponent)(ctx, req)
+		assert.Nil(t, err)
+	})
+	t.Run("MakeGetConsentsOfUserEndpoint", func(t *testing.T) {
+		mockManagementComponent.EXPECT().GetConsentsOfUser(ctx, realm, userID).Return([]api.UserConsentRepresentation{}, nil).Times(1)
+		var _, err = MakeGetConsentsOfUserEndpoint(mockManagementComponent)(ctx, req)
+		assert.Nil(t, err)
+	})
+	t.Run("MakeRevokeConsentOfUserEndpoint", func(t *testing.T) {
+		mockManagementComponent.EXPECT().RevokeConsentOfUser(ctx, realm, userID, clientID).Return(nil).Times(1)
+		var _, err = MakeRevokeConsentOfUserEndpoint(mockManagementComponent)(ctx, req)
+		assert.Nil(t, err)
+	})
+}
+
 func TestGetRolesEndpoint(t *testing.T) {
 	var mockCtrl = gomock.NewController(t)
 	defer mockCtrl.Finish()
@@ -1005,6 +1220,156 @@ func TestGetRoleEndpoint(t *testing.T) {
 	}
 }
 
+func TestCreateRoleEndpoint(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	var mockManagementComponent = mock.NewManagementComponent(mockCtrl)
+
+	var e = MakeCreateRoleEndpoint(mockManagementComponent, log.NewNopLogger())
+	var ctx = context.Background()
+	var location = "https://location.url/auth/admin/master/roles/my-role"
+	var realm = "master"
+	var name = "my-role"
+	var role = api.RoleRepresentation{Name: &name}
+	var roleJSON, _ = json.Marshal(role)
+
+	t.Run("No error", func(t *testing.T) {
+		var req = map[string]string{reqScheme: "https", reqHost: "elca.ch", prmRealm: realm, reqBody: string(roleJSON)}
+
+		mockManagementComponent.EXPECT().CreateRole(ctx, realm, role).Return(location, nil).Times(1)
+		var res, err = e(ctx, req)
+		assert.Nil(t, err)
+		assert.Equal(t, "https://elca.ch/management/master/roles/my-role", res.(LocationHeader).URL)
+	})
+	t.Run("Cannot unmarshal", func(t *testing.T) {
+		var _, err = e(ctx, map[string]string{reqBody: "JSON"})
+		assert.NotNil(t, err)
+	})
+	t.Run("Missing name", func(t *testing.T) {
+		var _, err = e(ctx, map[string]string{prmRealm: realm, reqBody: "{}"})
+		assert.NotNil(t, err)
+	})
+	t.Run("Component error", func(t *testing.T) {
+		var req = map[string]string{reqScheme: "https", reqHost: "elca.ch", prmRealm: realm, reqBody: string(roleJSON)}
+
+		mockManagementComponent.EXPECT().CreateRole(ctx, realm, role).Return("", fmt.Errorf("Error")).Times(1)
+		var _, err = e(ctx, req)
+		assert.NotNil(t, err)
+	})
+}
+
+func TestUpdateRoleEndpoint(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	var mockManagementComponent = mock.NewManagementComponent(mockCtrl)
+
+	var e = MakeUpdateRoleEndpoint(mockManagementComponent)
+	var ctx = context.Background()
+	var realm = "master"
+	var roleID = "123456"
+	var description = "new description"
+	var role = api.RoleRepresentation{Description: &description}
+	var roleJSON, _ = json.Marshal(role)
+
+	t.Run("No error", func(t *testing.T) {
+		var req = map[string]string{prmRealm: realm, prmRoleID: roleID, reqBody: string(roleJSON)}
+
+		mockManagementComponent.EXPECT().UpdateRole(ctx, realm, roleID, role).Return(nil).Times(1)
+		var res, err = e(ctx, req)
+		assert.Nil(t, err)
+		assert.Nil(t, res)
+	})
+	t.Run("Cannot unmarshal", func(t *testing.T) {
+		var _, err = e(ctx, map[string]string{prmRealm: realm, prmRoleID: roleID, reqBody: "JSON"})
+		assert.NotNil(t, err)
+	})
+}
+
+func TestDeleteRoleEndpoint(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	var mockManagementComponent = mock.NewManagementComponent(mockCtrl)
+
+	var e = MakeDeleteRoleEndpoint(mockManagementComponent)
+	var ctx = context.Background()
+	var realm = "master"
+	var roleID = "123456"
+	var req = map[string]string{prmRealm: realm, prmRoleID: roleID}
+
+	mockManagementComponent.EXPECT().DeleteRole(ctx, realm, roleID).Return(nil).Times(1)
+	var res, err = e(ctx, req)
+	assert.Nil(t, err)
+	assert.Nil(t, res)
+}
+
+func TestRoleCompositesEndpoints(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	var mockManagementComponent = mock.NewManagementComponent(mockCtrl)
+
+	var ctx = context.Background()
+	var realm = "master"
+	var roleID = "123456"
+	var compositeID = "789012"
+	var roles = []api.RoleRepresentation{{ID: &compositeID}}
+	var rolesJSON, _ = json.Marshal(roles)
+
+	t.Run("Get composites", func(t *testing.T) {
+		var e = MakeGetRoleCompositesEndpoint(mockManagementComponent)
+		mockManagementComponent.EXPECT().GetRoleComposites(ctx, realm, roleID).Return(roles, nil).Times(1)
+		var res, err = e(ctx, map[string]string{prmRealm: realm, prmRoleID: roleID})
+		assert.Nil(t, err)
+		assert.Equal(t, roles, res)
+	})
+	t.Run("Add composites", func(t *testing.T) {
+		var e = MakeAddRoleCompositesEndpoint(mockManagementComponent)
+		mockManagementComponent.EXPECT().AddRoleComposites(ctx, realm, roleID, roles).Return(nil).Times(1)
+		var res, err = e(ctx, map[string]string{prmRealm: realm, prmRoleID: roleID, reqBody: string(rolesJSON)})
+		assert.Nil(t, err)
+		assert.Nil(t, res)
+	})
+	t.Run("Delete composites", func(t *testing.T) {
+		var e = MakeDeleteRoleCompositesEndpoint(mockManagementComponent)
+		mockManagementComponent.EXPECT().DeleteRoleComposites(ctx, realm, roleID, roles).Return(nil).Times(1)
+		var res, err = e(ctx, map[string]string{prmRealm: realm, prmRoleID: roleID, reqBody: string(rolesJSON)})
+		assert.Nil(t, err)
+		assert.Nil(t, res)
+	})
+	t.Run("Cannot unmarshal", func(t *testing.T) {
+		var e = MakeAddRoleCompositesEndpoint(mockManagementComponent)
+		var _, err = e(ctx, map[string]string{prmRealm: realm, prmRoleID: roleID, reqBody: "JSON"})
+		assert.NotNil(t, err)
+	})
+	t.Run("Missing role ID", func(t *testing.T) {
+		var e = MakeDeleteRoleCompositesEndpoint(mockManagementComponent)
+		var _, err = e(ctx, map[string]string{prmRealm: realm, prmRoleID: roleID, reqBody: "[{}]"})
+		assert.NotNil(t, err)
+	})
+}
+
+func TestGetUsersWithRoleEndpoint(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	var mockManagementComponent = mock.NewManagementComponent(mockCtrl)
+
+	var e = MakeGetUsersWithRoleEndpoint(mockManagementComponent)
+
+	var realm = "master"
+	var roleID = "123456"
+	var ctx = context.Background()
+	var req = map[string]string{prmRealm: realm, prmRoleID: roleID, prmQryMax: "10"}
+
+	mockManagementComponent.EXPECT().GetUsersWithRole(ctx, realm, roleID, "max", "10").Return([]api.UserRepresentation{}, nil).Times(1)
+	var res, err = e(ctx, req)
+	assert.Nil(t, err)
+	assert.Equal(t, []api.UserRepresentation{}, res)
+}
+
 func TestGetGroupsEndpoint(t *testing.T) {
 	var mockCtrl = gomock.NewController(t)
 	defer mockCtrl.Finish()
@@ -1103,51 +1468,442 @@ func TestDeleteGroupEndpoint(t *testing.T) {
 	assert.Nil(t, res)
 }
 
-func TestGetAuthorizationsEndpoint(t *testing.T) {
+func TestGetGroupEndpoint(t *testing.T) {
 	var mockCtrl = gomock.NewController(t)
 	defer mockCtrl.Finish()
 
 	var mockManagementComponent = mock.NewManagementComponent(mockCtrl)
 
-	var e = MakeGetAuthorizationsEndpoint(mockManagementComponent)
+	var e = MakeGetGroupEndpoint(mockManagementComponent)
 
-	// No error
-	{
-		var realm = "master"
-		var groupID = "123456"
-		var ctx = context.Background()
-		var req = make(map[string]string)
-		req[prmRealm] = realm
-		req[prmGroupID] = groupID
+	var realm = "master"
+	var groupID = "1234-452-4578"
+	var ctx = context.Background()
+	var req = make(map[string]string)
+	req[prmRealm] = realm
+	req[prmGroupID] = groupID
 
-		mockManagementComponent.EXPECT().GetAuthorizations(ctx, realm, groupID).Return(api.AuthorizationsRepresentation{}, nil).Times(1)
-		var res, err = e(ctx, req)
-		assert.Nil(t, err)
-		assert.NotNil(t, res)
-	}
+	mockManagementComponent.EXPECT().GetGroup(ctx, realm, groupID).Return(api.GroupRepresentation{ID: &groupID}, nil).Times(1)
+	var res, err = e(ctx, req)
+	assert.Nil(t, err)
+	assert.Equal(t, api.GroupRepresentation{ID: &groupID}, res)
 }
 
-func TestGetClientRolesEndpoint(t *testing.T) {
+func TestUpdateGroupEndpoint(t *testing.T) {
 	var mockCtrl = gomock.NewController(t)
 	defer mockCtrl.Finish()
 
 	var mockManagementComponent = mock.NewManagementComponent(mockCtrl)
 
-	var e = MakeGetClientRolesEndpoint(mockManagementComponent)
+	var e = MakeUpdateGroupEndpoint(mockManagementComponent)
 
-	// No error
-	{
-		var realm = "master"
-		var clientID = "123456"
-		var ctx = context.Background()
-		var req = make(map[string]string)
-		req[prmRealm] = realm
-		req[prmClientID] = clientID
+	var realm = "master"
+	var groupID = "1234-452-4578"
+	var name = "name"
+	var attributes = map[string][]string{"costCenter": {"1234"}}
+	var ctx = context.Background()
+	var req = map[string]string{prmRealm: realm, prmGroupID: groupID}
 
-		mockManagementComponent.EXPECT().GetClientRoles(ctx, realm, clientID).Return([]api.RoleRepresentation{}, nil).Times(1)
-		var res, err = e(ctx, req)
-		assert.Nil(t, err)
-		assert.NotNil(t, res)
+	t.Run("Invalid body", func(t *testing.T) {
+		req[reqBody] = "JSON"
+		_, err := e(ctx, req)
+		assert.NotNil(t, err)
+	})
+
+	t.Run("Invalid name", func(t *testing.T) {
+		var invalidName = ""
+		groupJSON, _ := json.Marshal(api.GroupRepresentation{Name: &invalidName})
+		req[reqBody] = string(groupJSON)
+		_, err := e(ctx, req)
+		assert.NotNil(t, err)
+	})
+
+	t.Run("Success", func(t *testing.T) {
+		var group = api.GroupRepresentation{Name: &name, Attributes: &attributes}
+		groupJSON, _ := json.Marshal(group)
+		req[reqBody] = string(groupJSON)
+		mockManagementComponent.EXPECT().UpdateGroup(ctx, realm, groupID, group).Return(nil).Times(1)
+		res, err := e(ctx, req)
+		assert.Nil(t, err)
+		assert.Nil(t, res)
+	})
+}
+
+func TestCreateGroupChildEndpoint(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	var mockManagementComponent = mock.NewManagementComponent(mockCtrl)
+
+	var e = MakeCreateGroupChildEndpoint(mockManagementComponent, log.NewNopLogger())
+
+	var realm = "master"
+	var groupID = "1234-452-4578"
+	var location = "https://location.url/auth/admin/master/groups/123456"
+	var name = "name"
+	var ctx = context.Background()
+	var req = map[string]string{reqScheme: "https", reqHost: "elca.ch", prmRealm: realm, prmGroupID: groupID}
+
+	t.Run("Missing name", func(t *testing.T) {
+		req[reqBody] = "{}"
+		_, err := e(ctx, req)
+		assert.NotNil(t, err)
+	})
+
+	t.Run("Keycloak client error", func(t *testing.T) {
+		groupJSON, _ := json.Marshal(api.GroupRepresentation{Name: &name})
+		req[reqBody] = string(groupJSON)
+		mockManagementComponent.EXPECT().CreateGroupChild(ctx, realm, groupID, gomock.Any()).Return("", fmt.Errorf("Error")).Times(1)
+		_, err := e(ctx, req)
+		assert.NotNil(t, err)
+	})
+
+	t.Run("Success", func(t *testing.T) {
+		groupJSON, _ := json.Marshal(api.GroupRepresentation{Name: &name})
+		req[reqBody] = string(groupJSON)
+		mockManagementComponent.EXPECT().CreateGroupChild(ctx, realm, groupID, api.GroupRepresentation{Name: &name}).Return(location, nil).Times(1)
+		res, err := e(ctx, req)
+		assert.Nil(t, err)
+		assert.Equal(t, "https://elca.ch/management/master/groups/123456", res.(LocationHeader).URL)
+	})
+}
+
+func TestMoveGroupEndpoint(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	var mockManagementComponent = mock.NewManagementComponent(mockCtrl)
+
+	var e = MakeMoveGroupEndpoint(mockManagementComponent)
+
+	var realm = "master"
+	var groupID = "1234-452-4578"
+	var childGroupID = "1234-452-4579"
+	var ctx = context.Background()
+	var req = map[string]string{prmRealm: realm, prmGroupID: groupID, prmChildGroupID: childGroupID}
+
+	mockManagementComponent.EXPECT().MoveGroup(ctx, realm, groupID, childGroupID).Return(nil).Times(1)
+	var res, err = e(ctx, req)
+	assert.Nil(t, err)
+	assert.Nil(t, res)
+}
+
+func TestGetGroupMembersEndpoint(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	var mockManagementComponent = mock.NewManagementComponent(mockCtrl)
+
+	var e = MakeGetGroupMembersEndpoint(mockManagementComponent)
+
+	var realm = "master"
+	var groupID = "1234-452-4578"
+	var ctx = context.Background()
+	var req = map[string]string{prmRealm: realm, prmGroupID: groupID, prmQryFirst: "20", prmQryMax: "10"}
+
+	mockManagementComponent.EXPECT().GetGroupMembers(ctx, realm, groupID, "first", "20", "max", "10").Return([]api.UserRepresentation{}, nil).Times(1)
+	var res, err = e(ctx, req)
+	assert.Nil(t, err)
+	assert.Equal(t, []api.UserRepresentation{}, res)
+}
+
+func TestGetAuthorizationsEndpoint(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	var mockManagementComponent = mock.NewManagementComponent(mockCtrl)
+
+	var e = MakeGetAuthorizationsEndpoint(mockManagementComponent)
+
+	// No error
+	{
+		var realm = "master"
+		var groupID = "123456"
+		var ctx = context.Background()
+		var req = make(map[string]string)
+		req[prmRealm] = realm
+		req[prmGroupID] = groupID
+
+		mockManagementComponent.EXPECT().GetAuthorizations(ctx, realm, groupID).Return(api.AuthorizationsRepresentation{}, nil).Times(1)
+		var res, err = e(ctx, req)
+		assert.Nil(t, err)
+		assert.NotNil(t, res)
+	}
+}
+
+func TestCheckAuthorizationEndpoint(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	var mockManagementComponent = mock.NewManagementComponent(mockCtrl)
+
+	var e = MakeCheckAuthorizationEndpoint(mockManagementComponent)
+
+	var realm = "master"
+	var userID = "1234-452-4578"
+	var action = "MGMT_GetUser"
+	var targetRealm = "DEP"
+	var targetGroup = "group"
+	var ctx = context.Background()
+
+	t.Run("Missing action", func(t *testing.T) {
+		var _, err = e(ctx, map[string]string{prmRealm: realm})
+		assert.NotNil(t, err)
+	})
+	t.Run("Success", func(t *testing.T) {
+		var req = map[string]string{prmRealm: realm, prmQryUser: userID, prmQryAction: action, prmQryTargetRealm: targetRealm, prmQryTargetGroup: targetGroup}
+		mockManagementComponent.EXPECT().CheckAuthorization(ctx, realm, userID, action, targetRealm, targetGroup).Return(api.AuthorizationCheckRepresentation{}, nil).Times(1)
+		var res, err = e(ctx, req)
+		assert.Nil(t, err)
+		assert.NotNil(t, res)
+	})
+}
+
+func TestAuthorizationTemplatesEndpoints(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	var mockManagementComponent = mock.NewManagementComponent(mockCtrl)
+
+	var templateName = "support"
+	var ctx = context.Background()
+
+	t.Run("Get templates", func(t *testing.T) {
+		mockManagementComponent.EXPECT().GetAuthorizationTemplates(ctx).Return([]api.AuthorizationTemplateRepresentation{}, nil).Times(1)
+		var res, err = MakeGetAuthorizationTemplatesEndpoint(mockManagementComponent)(ctx, nil)
+		assert.Nil(t, err)
+		assert.NotNil(t, res)
+	})
+	t.Run("Get template", func(t *testing.T) {
+		mockManagementComponent.EXPECT().GetAuthorizationTemplate(ctx, templateName).Return(api.AuthorizationTemplateRepresentation{}, nil).Times(1)
+		var res, err = MakeGetAuthorizationTemplateEndpoint(mockManagementComponent)(ctx, map[string]string{prmTemplateName: templateName})
+		assert.Nil(t, err)
+		assert.NotNil(t, res)
+	})
+	t.Run("Update template-Invalid JSON", func(t *testing.T) {
+		var _, err = MakeUpdateAuthorizationTemplateEndpoint(mockManagementComponent)(ctx, map[string]string{prmTemplateName: templateName, reqBody: "{"})
+		assert.NotNil(t, err)
+	})
+	t.Run("Update template-Missing matrix", func(t *testing.T) {
+		var _, err = MakeUpdateAuthorizationTemplateEndpoint(mockManagementComponent)(ctx, map[string]string{prmTemplateName: templateName, reqBody: "{}"})
+		assert.NotNil(t, err)
+	})
+	t.Run("Update template", func(t *testing.T) {
+		var req = map[string]string{prmTemplateName: templateName, reqBody: `{"matrix":{"MGMT_GetUsers":{"{realm}":{"*":{}}}}}`}
+		mockManagementComponent.EXPECT().UpdateAuthorizationTemplate(ctx, templateName, gomock.Any()).Return(nil).Times(1)
+		var res, err = MakeUpdateAuthorizationTemplateEndpoint(mockManagementComponent)(ctx, req)
+		assert.Nil(t, err)
+		assert.Nil(t, res)
+	})
+	t.Run("Delete template", func(t *testing.T) {
+		mockManagementComponent.EXPECT().DeleteAuthorizationTemplate(ctx, templateName).Return(nil).Times(1)
+		var res, err = MakeDeleteAuthorizationTemplateEndpoint(mockManagementComponent)(ctx, map[string]string{prmTemplateName: templateName})
+		assert.Nil(t, err)
+		assert.Nil(t, res)
+	})
+	t.Run("Apply template", func(t *testing.T) {
+		var req = map[string]string{prmRealm: "master", prmGroupID: "123456", prmTemplateName: templateName, prmQryTargetRealm: "DEP"}
+		mockManagementComponent.EXPECT().ApplyAuthorizationTemplate(ctx, "master", "123456", templateName, "DEP").Return(nil).Times(1)
+		var res, err = MakeApplyAuthorizationTemplateEndpoint(mockManagementComponent)(ctx, req)
+		assert.Nil(t, err)
+		assert.Nil(t, res)
+	})
+}
+
+func TestCopyAuthorizationsEndpoint(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	var mockManagementComponent = mock.NewManagementComponent(mockCtrl)
+
+	var e = MakeCopyAuthorizationsEndpoint(mockManagementComponent)
+
+	var sourceRealm = "master"
+	var targetRealm = "DEP"
+	var ctx = context.Background()
+
+	t.Run("Missing target realm", func(t *testing.T) {
+		var _, err = e(ctx, map[string]string{prmRealm: sourceRealm})
+		assert.NotNil(t, err)
+	})
+	t.Run("Dry run", func(t *testing.T) {
+		mockManagementComponent.EXPECT().CopyAuthorizations(ctx, sourceRealm, targetRealm, true).Return(api.AuthorizationsCopyRepresentation{}, nil).Times(1)
+		var res, err = e(ctx, map[string]string{prmRealm: sourceRealm, prmQryTargetRealm: targetRealm, prmQryDryRun: "true"})
+		assert.Nil(t, err)
+		assert.NotNil(t, res)
+	})
+	t.Run("Copy", func(t *testing.T) {
+		mockManagementComponent.EXPECT().CopyAuthorizations(ctx, sourceRealm, targetRealm, false).Return(api.AuthorizationsCopyRepresentation{}, nil).Times(1)
+		var _, err = e(ctx, map[string]string{prmRealm: sourceRealm, prmQryTargetRealm: targetRealm})
+		assert.Nil(t, err)
+	})
+}
+
+func TestAuthorizationsVersionsEndpoints(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	var mockManagementComponent = mock.NewManagementComponent(mockCtrl)
+
+	var realm = "master"
+	var groupID = "123456"
+	var ctx = context.Background()
+
+	t.Run("List versions", func(t *testing.T) {
+		mockManagementComponent.EXPECT().GetAuthorizationsVersions(ctx, realm, groupID).Return([]api.AuthorizationsVersionRepresentation{}, nil).Times(1)
+		var res, err = MakeGetAuthorizationsVersionsEndpoint(mockManagementComponent)(ctx, map[string]string{prmRealm: realm, prmGroupID: groupID})
+		assert.Nil(t, err)
+		assert.NotNil(t, res)
+	})
+	t.Run("Diff-Missing from", func(t *testing.T) {
+		var _, err = MakeGetAuthorizationsVersionsDiffEndpoint(mockManagementComponent)(ctx, map[string]string{prmRealm: realm, prmGroupID: groupID, prmQryTo: "2"})
+		assert.NotNil(t, err)
+	})
+	t.Run("Diff-Missing to", func(t *testing.T) {
+		var _, err = MakeGetAuthorizationsVersionsDiffEndpoint(mockManagementComponent)(ctx, map[string]string{prmRealm: realm, prmGroupID: groupID, prmQryFrom: "1"})
+		assert.NotNil(t, err)
+	})
+	t.Run("Diff", func(t *testing.T) {
+		mockManagementComponent.EXPECT().GetAuthorizationsVersionsDiff(ctx, realm, groupID, 1, 2).Return(api.AuthorizationsDiffRepresentation{}, nil).Times(1)
+		var res, err = MakeGetAuthorizationsVersionsDiffEndpoint(mockManagementComponent)(ctx, map[string]string{prmRealm: realm, prmGroupID: groupID, prmQryFrom: "1", prmQryTo: "2"})
+		assert.Nil(t, err)
+		assert.NotNil(t, res)
+	})
+	t.Run("Rollback-Invalid version", func(t *testing.T) {
+		var _, err = MakeRollbackAuthorizationsEndpoint(mockManagementComponent)(ctx, map[string]string{prmRealm: realm, prmGroupID: groupID})
+		assert.NotNil(t, err)
+	})
+	t.Run("Rollback", func(t *testing.T) {
+		mockManagementComponent.EXPECT().RollbackAuthorizations(ctx, realm, groupID, 3).Return(nil).Times(1)
+		var res, err = MakeRollbackAuthorizationsEndpoint(mockManagementComponent)(ctx, map[string]string{prmRealm: realm, prmGroupID: groupID, prmVersion: "3"})
+		assert.Nil(t, err)
+		assert.Nil(t, res)
+	})
+}
+
+func TestRealmConfigurationVersionsEndpoints(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	var mockManagementComponent = mock.NewManagementComponent(mockCtrl)
+
+	var realm = "master"
+	var ctx = context.Background()
+
+	t.Run("List versions of the custom configuration", func(t *testing.T) {
+		mockManagementComponent.EXPECT().GetRealmCustomConfigurationVersions(ctx, realm).Return([]api.RealmConfigurationVersionRepresentation{}, nil).Times(1)
+		var res, err = MakeGetRealmCustomConfigurationVersionsEndpoint(mockManagementComponent)(ctx, map[string]string{prmRealm: realm})
+		assert.Nil(t, err)
+		assert.NotNil(t, res)
+	})
+	t.Run("Diff of the custom configuration-Missing from", func(t *testing.T) {
+		var _, err = MakeGetRealmCustomConfigurationVersionsDiffEndpoint(mockManagementComponent)(ctx, map[string]string{prmRealm: realm, prmQryTo: "2"})
+		assert.NotNil(t, err)
+	})
+	t.Run("Diff of the custom configuration", func(t *testing.T) {
+		mockManagementComponent.EXPECT().GetRealmCustomConfigurationVersionsDiff(ctx, realm, 1, 2).Return(api.RealmConfigurationDiffRepresentation{}, nil).Times(1)
+		var res, err = MakeGetRealmCustomConfigurationVersionsDiffEndpoint(mockManagementComponent)(ctx, map[string]string{prmRealm: realm, prmQryFrom: "1", prmQryTo: "2"})
+		assert.Nil(t, err)
+		assert.NotNil(t, res)
+	})
+	t.Run("Rollback of the custom configuration", func(t *testing.T) {
+		mockManagementComponent.EXPECT().RollbackRealmCustomConfiguration(ctx, realm, 3).Return(nil).Times(1)
+		var res, err = MakeRollbackRealmCustomConfigurationEndpoint(mockManagementComponent)(ctx, map[string]string{prmRealm: realm, prmVersion: "3"})
+		assert.Nil(t, err)
+		assert.Nil(t, res)
+	})
+	t.Run("List versions of the admin configuration", func(t *testing.T) {
+		mockManagementComponent.EXPECT().GetRealmAdminConfigurationVersions(ctx, realm).Return([]api.RealmConfigurationVersionRepresentation{}, nil).Times(1)
+		var res, err = MakeGetRealmAdminConfigurationVersionsEndpoint(mockManagementComponent)(ctx, map[string]string{prmRealm: realm})
+		assert.Nil(t, err)
+		assert.NotNil(t, res)
+	})
+	t.Run("Diff of the admin configuration-Missing to", func(t *testing.T) {
+		var _, err = MakeGetRealmAdminConfigurationVersionsDiffEndpoint(mockManagementComponent)(ctx, map[string]string{prmRealm: realm, prmQryFrom: "1"})
+		assert.NotNil(t, err)
+	})
+	t.Run("Diff of the admin configuration", func(t *testing.T) {
+		mockManagementComponent.EXPECT().GetRealmAdminConfigurationVersionsDiff(ctx, realm, 1, 2).Return(api.RealmConfigurationDiffRepresentation{}, nil).Times(1)
+		var res, err = MakeGetRealmAdminConfigurationVersionsDiffEndpoint(mockManagementComponent)(ctx, map[string]string{prmRealm: realm, prmQryFrom: "1", prmQryTo: "2"})
+		assert.Nil(t, err)
+		assert.NotNil(t, res)
+	})
+	t.Run("Rollback of the admin configuration-Invalid version", func(t *testing.T) {
+		var _, err = MakeRollbackRealmAdminConfigurationEndpoint(mockManagementComponent)(ctx, map[string]string{prmRealm: realm})
+		assert.NotNil(t, err)
+	})
+	t.Run("Rollback of the admin configuration", func(t *testing.T) {
+		mockManagementComponent.EXPECT().RollbackRealmAdminConfiguration(ctx, realm, 3).Return(nil).Times(1)
+		var res, err = MakeRollbackRealmAdminConfigurationEndpoint(mockManagementComponent)(ctx, map[string]string{prmRealm: realm, prmVersion: "3"})
+		assert.Nil(t, err)
+		assert.Nil(t, res)
+	})
+}
+
+func TestPendingRequestsEndpoints(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	var mockManagementComponent = mock.NewManagementComponent(mockCtrl)
+
+	var realm = "master"
+	var requestID = "0123456789abcdef0123456789abcdef"
+	var req = map[string]string{prmRealm: realm, prmRequestID: requestID}
+	var ctx = context.Background()
+
+	t.Run("List pending requests", func(t *testing.T) {
+		mockManagementComponent.EXPECT().GetPendingRequests(ctx, realm).Return([]api.PendingRequestRepresentation{}, nil).Times(1)
+		var res, err = MakeGetPendingRequestsEndpoint(mockManagementComponent)(ctx, req)
+		assert.Nil(t, err)
+		assert.NotNil(t, res)
+	})
+	t.Run("Get pending request", func(t *testing.T) {
+		mockManagementComponent.EXPECT().GetPendingRequest(ctx, realm, requestID).Return(api.PendingRequestRepresentation{}, nil).Times(1)
+		var res, err = MakeGetPendingRequestEndpoint(mockManagementComponent)(ctx, req)
+		assert.Nil(t, err)
+		assert.NotNil(t, res)
+	})
+	t.Run("Approve", func(t *testing.T) {
+		mockManagementComponent.EXPECT().ApprovePendingRequest(ctx, realm, requestID).Return("", nil).Times(1)
+		var res, err = MakeApprovePendingRequestEndpoint(mockManagementComponent)(ctx, req)
+		assert.Nil(t, err)
+		assert.Nil(t, res)
+	})
+	t.Run("Approve password reset", func(t *testing.T) {
+		mockManagementComponent.EXPECT().ApprovePendingRequest(ctx, realm, requestID).Return("P@ssw0rd", nil).Times(1)
+		var res, err = MakeApprovePendingRequestEndpoint(mockManagementComponent)(ctx, req)
+		assert.Nil(t, err)
+		assert.Equal(t, "P@ssw0rd", res)
+	})
+	t.Run("Reject", func(t *testing.T) {
+		mockManagementComponent.EXPECT().RejectPendingRequest(ctx, realm, requestID).Return(nil).Times(1)
+		var res, err = MakeRejectPendingRequestEndpoint(mockManagementComponent)(ctx, req)
+		assert.Nil(t, err)
+		assert.Nil(t, res)
+	})
+}
+
+func TestGetClientRolesEndpoint(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	var mockManagementComponent = mock.NewManagementComponent(mockCtrl)
+
+	var e = MakeGetClientRolesEndpoint(mockManagementComponent)
+
+	// No error
+	{
+		var realm = "master"
+		var clientID = "123456"
+		var ctx = context.Background()
+		var req = make(map[string]string)
+		req[prmRealm] = realm
+		req[prmClientID] = clientID
+
+		mockManagementComponent.EXPECT().GetClientRoles(ctx, realm, clientID).Return([]api.RoleRepresentation{}, nil).Times(1)
+		var res, err = e(ctx, req)
+		assert.Nil(t, err)
+		assert.NotNil(t, res)
 	}
 }
 
@@ -1247,6 +2003,25 @@ func TestCreateClientRoleEndpoint(t *testing.T) {
 	}
 }
 
+func TestDeleteClientRoleEndpoint(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	var mockManagementComponent = mock.NewManagementComponent(mockCtrl)
+
+	var e = MakeDeleteClientRoleEndpoint(mockManagementComponent)
+	var ctx = context.Background()
+	var realm = "master"
+	var clientID = "123456"
+	var roleID = "789012"
+	var req = map[string]string{prmRealm: realm, prmClientID: clientID, prmRoleID: roleID}
+
+	mockManagementComponent.EXPECT().DeleteClientRole(ctx, realm, clientID, roleID).Return(nil).Times(1)
+	var res, err = e(ctx, req)
+	assert.Nil(t, err)
+	assert.Nil(t, res)
+}
+
 func TestConfigurationEndpoints(t *testing.T) {
 	var mockCtrl = gomock.NewController(t)
 	defer mockCtrl.Finish()
@@ -1262,10 +2037,10 @@ func TestConfigurationEndpoints(t *testing.T) {
 		var req = map[string]string{prmRealm: realmName, prmClientID: clientID}
 		var e = MakeGetRealmCustomConfigurationEndpoint(mockManagementComponent)
 
-		mockManagementComponent.EXPECT().GetRealmCustomConfiguration(ctx, realmName).Return(api.RealmCustomConfiguration{}, nil).Times(1)
+		mockManagementComponent.EXPECT().GetRealmCustomConfiguration(ctx, realmName).Return(api.RealmCustomConfiguration{}, `"etag"`, nil).Times(1)
 		var res, err = e(ctx, req)
 		assert.Nil(t, err)
-		assert.NotNil(t, res)
+		assert.Equal(t, `"etag"`, res.(keycloakb.ETagReply).ETag)
 	})
 
 	t.Run("MakeUpdateRealmCustomConfigurationEndpoint - No error", func(t *testing.T) {
@@ -1273,7 +2048,7 @@ func TestConfigurationEndpoints(t *testing.T) {
 		var req = map[string]string{prmRealm: realmName, prmClientID: clientID, reqBody: configJSON}
 		var e = MakeUpdateRealmCustomConfigurationEndpoint(mockManagementComponent)
 
-		mockManagementComponent.EXPECT().UpdateRealmCustomConfiguration(ctx, realmName, gomock.Any()).Return(nil).Times(1)
+		mockManagementComponent.EXPECT().UpdateRealmCustomConfiguration(ctx, realmName, gomock.Any(), "").Return(nil).Times(1)
 		var res, err = e(ctx, req)
 		assert.Nil(t, err)
 		assert.Nil(t, res)
@@ -1284,7 +2059,7 @@ func TestConfigurationEndpoints(t *testing.T) {
 		var req = map[string]string{prmRealm: realmName, prmClientID: clientID, reqBody: configJSON}
 		var e = MakeUpdateRealmCustomConfigurationEndpoint(mockManagementComponent)
 
-		mockManagementComponent.EXPECT().UpdateRealmCustomConfiguration(ctx, realmName, gomock.Any()).Return(nil).Times(0)
+		mockManagementComponent.EXPECT().UpdateRealmCustomConfiguration(ctx, realmName, gomock.Any(), "").Return(nil).Times(0)
 		var res, err = e(ctx, req)
 		assert.NotNil(t, err)
 		assert.Nil(t, res)
@@ -1331,10 +2106,10 @@ func TestGetRealmAdminConfigurationEndpoint(t *testing.T) {
 		var req = make(map[string]string)
 		req[prmRealm] = realmName
 
-		mockManagementComponent.EXPECT().GetRealmAdminConfiguration(ctx, realmName).Return(adminConfig, nil).Times(1)
+		mockManagementComponent.EXPECT().GetRealmAdminConfiguration(ctx, realmName).Return(adminConfig, `"etag"`, nil).Times(1)
 		var res, err = e(ctx, req)
 		assert.Nil(t, err)
-		assert.NotNil(t, res)
+		assert.Equal(t, keycloakb.ETagReply{ETag: `"etag"`, Value: adminConfig}, res)
 	})
 	t.Run("Request fails at component level", func(t *testing.T) {
 		var realmName = "master"
@@ -1343,7 +2118,7 @@ func TestGetRealmAdminConfigurationEndpoint(t *testing.T) {
 		var req = make(map[string]string)
 		req[prmRealm] = realmName
 
-		mockManagementComponent.EXPECT().GetRealmAdminConfiguration(ctx, realmName).Return(adminConfig, expectedError).Times(1)
+		mockManagementComponent.EXPECT().GetRealmAdminConfiguration(ctx, realmName).Return(adminConfig, "", expectedError).Times(1)
 		var _, err = e(ctx, req)
 		assert.Equal(t, expectedError, err)
 	})
@@ -1364,8 +2139,9 @@ func TestUpdateRealmAdminConfigurationEndpoint(t *testing.T) {
 		var req = make(map[string]string)
 		req[prmRealm] = realmName
 		req[reqBody] = configJSON
+		req[reqIfMatch] = `"etag"`
 
-		mockManagementComponent.EXPECT().UpdateRealmAdminConfiguration(ctx, realmName, gomock.Any()).Return(nil).Times(1)
+		mockManagementComponent.EXPECT().UpdateRealmAdminConfiguration(ctx, realmName, gomock.Any(), `"etag"`).Return(nil).Times(1)
 		var res, err = e(ctx, req)
 		assert.Nil(t, err)
 		assert.Nil(t, res)
@@ -1387,7 +2163,7 @@ func TestUpdateRealmAdminConfigurationEndpoint(t *testing.T) {
 		req[prmRealm] = realmName
 		req[reqBody] = configJSON
 
-		mockManagementComponent.EXPECT().UpdateRealmAdminConfiguration(ctx, realmName, gomock.Any()).Return(nil).Times(0)
+		mockManagementComponent.EXPECT().UpdateRealmAdminConfiguration(ctx, realmName, gomock.Any(), gomock.Any()).Return(nil).Times(0)
 		var res, err = e(ctx, req)
 		assert.NotNil(t, err)
 		assert.Nil(t, res)
@@ -1439,6 +2215,35 @@ func TestLinkShadowUserEndpoint(t *testing.T) {
 	})
 }
 
+func TestFederatedIdentitiesEndpoints(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	var mockManagementComponent = mock.NewManagementComponent(mockCtrl)
+
+	var realm = "master"
+	var ctx = context.Background()
+	var userID = "abcdefgh-1234-ijkl-5678-mnopqrstuvwx"
+	var provider = "provider"
+	var req = map[string]string{prmRealm: realm, prmUserID: userID, prmProvider: provider}
+
+	t.Run("Get federated identities", func(t *testing.T) {
+		mockManagementComponent.EXPECT().GetFederatedIdentities(ctx, realm, userID).Return([]api.FederatedIdentityRepresentation{}, nil).Times(1)
+		_, err := MakeGetFederatedIdentitiesEndpoint(mockManagementComponent)(ctx, req)
+		assert.Nil(t, err)
+	})
+
+	t.Run("Unlink shadow user - invalid provider", func(t *testing.T) {
+		_, err := MakeUnlinkShadowUserEndpoint(mockManagementComponent)(ctx, map[string]string{prmRealm: realm, prmUserID: userID})
+		assert.NotNil(t, err)
+	})
+	t.Run("Unlink shadow user successfully", func(t *testing.T) {
+		mockManagementComponent.EXPECT().UnlinkShadowUser(ctx, realm, userID, provider).Return(nil).Times(1)
+		_, err := MakeUnlinkShadowUserEndpoint(mockManagementComponent)(ctx, req)
+		assert.Nil(t, err)
+	})
+}
+
 func TestConvertLocationUrl(t *testing.T) {
 
 	res, err := convertLocationURL("http://localhost:8080/auth/realms/master/api/admin/realms/dep/users/1522-4245245-4542545/credentials", "https", "ct-bridge.services.com")
@@ -1454,3 +2259,111 @@ func TestConvertLocationUrl(t *testing.T) {
 	assert.Equal(t, ConvertLocationError{Location: "http://localhost:8080/toto"}, err)
 
 }
+
+func TestImportUsersEndpoint(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	var mockManagementComponent = mock.NewManagementComponent(mockCtrl)
+
+	var e = MakeImportUsersEndpoint(mockManagementComponent)
+
+	var realm = "master"
+	var ctx = context.Background()
+	var groupID = "f467ed7c-0a1d-4eee-9bb8-669c6f89c0ee"
+	var otherGroupID = "41dbf4a8-32a9-4000-8c17-edc854c31231"
+
+	t.Run("Invalid body", func(t *testing.T) {
+		var _, err = e(ctx, map[string]string{prmRealm: realm, reqBody: "not json"})
+		assert.NotNil(t, err)
+	})
+	t.Run("Empty file", func(t *testing.T) {
+		var _, err = e(ctx, map[string]string{prmRealm: realm, reqBody: "username\n", prmQryFormat: "csv"})
+		assert.NotNil(t, err)
+	})
+	t.Run("Dry run", func(t *testing.T) {
+		var req = map[string]string{prmRealm: realm, reqBody: "username,groups\nuser1," + groupID + "\n", prmQryFormat: "csv", prmQryGroupIDs: groupID + "," + otherGroupID, prmQryDryRun: "true"}
+		mockManagementComponent.EXPECT().CheckUsersImport(ctx, realm, gomock.Any()).DoAndReturn(
+			func(_ context.Context, _ string, users []api.UserImportEntry) (api.UserImportReportRepresentation, error) {
+				assert.Equal(t, []string{groupID, otherGroupID}, *users[0].User.Groups)
+				return api.UserImportReportRepresentation{Total: 1}, nil
+			}).Times(1)
+		var res, err = e(ctx, req)
+		assert.Nil(t, err)
+		assert.Equal(t, api.UserImportReportRepresentation{Total: 1}, res)
+	})
+	t.Run("Import", func(t *testing.T) {
+		var req = map[string]string{prmRealm: realm, reqBody: `{"username":"user1"}`, prmQryActions: "VERIFY_EMAIL,UPDATE_PASSWORD"}
+		var actions = []api.RequiredAction{"VERIFY_EMAIL", "UPDATE_PASSWORD"}
+		mockManagementComponent.EXPECT().ImportUsers(ctx, realm, gomock.Any(), actions).Return(api.UserImportJobRepresentation{ID: "job"}, nil).Times(1)
+		var res, err = e(ctx, req)
+		assert.Nil(t, err)
+		assert.Equal(t, api.UserImportJobRepresentation{ID: "job"}, res)
+	})
+}
+
+func TestGetUsersImportJobEndpoints(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	var mockManagementComponent = mock.NewManagementComponent(mockCtrl)
+
+	var realm = "master"
+	var jobID = "0123456789abcdef0123456789abcdef"
+	var ctx = context.Background()
+	var req = map[string]string{prmRealm: realm, prmJobID: jobID}
+
+	t.Run("Job", func(t *testing.T) {
+		mockManagementComponent.EXPECT().GetUsersImportJob(ctx, realm, jobID).Return(api.UserImportJobRepresentation{ID: jobID}, nil).Times(1)
+		var res, err = MakeGetUsersImportJobEndpoint(mockManagementComponent)(ctx, req)
+		assert.Nil(t, err)
+		assert.Equal(t, api.UserImportJobRepresentation{ID: jobID}, res)
+	})
+	t.Run("Results - error", func(t *testing.T) {
+		mockManagementComponent.EXPECT().GetUsersImportResults(ctx, realm, jobID).Return(nil, errors.New("error")).Times(1)
+		var _, err = MakeGetUsersImportResultsEndpoint(mockManagementComponent)(ctx, req)
+		assert.NotNil(t, err)
+	})
+	t.Run("Results - CSV", func(t *testing.T) {
+		var csvReq = map[string]string{prmRealm: realm, prmJobID: jobID, prmQryFormat: "csv"}
+		mockManagementComponent.EXPECT().GetUsersImportResults(ctx, realm, jobID).Return(api.UserImportRowsRepresentation{{Line: 2}}, nil).Times(1)
+		var res, err = MakeGetUsersImportResultsEndpoint(mockManagementComponent)(ctx, csvReq)
+		assert.Nil(t, err)
+		assert.Equal(t, "users-import-"+jobID+".csv", res.(CSVReply).Filename)
+		assert.Len(t, res.(CSVReply).Records, 2)
+	})
+}
+
+func TestBulkOperationEndpoints(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	var mockBulkComponent = mock.NewBulkComponent(mockCtrl)
+
+	var realm = "master"
+	var jobID = "0123456789abcdef0123456789abcdef"
+	var ctx = context.Background()
+
+	t.Run("Start - invalid body", func(t *testing.T) {
+		var _, err = MakeStartBulkOperationEndpoint(mockBulkComponent)(ctx, map[string]string{prmRealm: realm, reqBody: "not json"})
+		assert.NotNil(t, err)
+	})
+	t.Run("Start - group operation without group", func(t *testing.T) {
+		var body = `{"operation":"addGroup","userIds":["f467ed7c-0a1d-4eee-9bb8-669c6f89c0ee"]}`
+		var _, err = MakeStartBulkOperationEndpoint(mockBulkComponent)(ctx, map[string]string{prmRealm: realm, reqBody: body})
+		assert.NotNil(t, err)
+	})
+	t.Run("Start", func(t *testing.T) {
+		var body = `{"operation":"lock","userIds":["f467ed7c-0a1d-4eee-9bb8-669c6f89c0ee"]}`
+		mockBulkComponent.EXPECT().StartBulkOperation(ctx, realm, gomock.Any()).Return(api.BulkOperationJobRepresentation{ID: jobID}, nil).Times(1)
+		var res, err = MakeStartBulkOperationEndpoint(mockBulkComponent)(ctx, map[string]string{prmRealm: realm, reqBody: body})
+		assert.Nil(t, err)
+		assert.Equal(t, api.BulkOperationJobRepresentation{ID: jobID}, res)
+	})
+	t.Run("Get", func(t *testing.T) {
+		mockBulkComponent.EXPECT().GetBulkOperation(ctx, realm, jobID).Return(api.BulkOperationJobRepresentation{ID: jobID}, nil).Times(1)
+		var res, err = MakeGetBulkOperationEndpoint(mockBulkComponent)(ctx, map[string]string{prmRealm: realm, prmJobID: jobID})
+		assert.Nil(t, err)
+		assert.Equal(t, api.BulkOperationJobRepresentation{ID: jobID}, res)
+	})
+}
@@ -2,6 +2,9 @@ package management
 
 import (
 	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
 	"net/http"
 
 	commonhttp "github.com/cloudtrust/common-service/http"
@@ -21,14 +24,22 @@ const (
 	reqBody   = "body"
 	reqScheme = "scheme"
 	reqHost   = "host"
+	// reqIfMatch is not a parameter of the request but its If-Match header
+	reqIfMatch = "ifMatch"
 
 	prmRealm        = "realm"
 	prmUserID       = "userID"
 	prmClientID     = "clientID"
 	prmRoleID       = "roleID"
 	prmGroupID      = "groupID"
+	prmChildGroupID = "childGroupID"
 	prmCredentialID = "credentialID"
+	prmSessionID    = "sessionID"
 	prmProvider     = "provider"
+	prmJobID        = "jobID"
+	prmTemplateName = "templateName"
+	prmVersion      = "version"
+	prmRequestID    = "requestID"
 
 	prmQryEmail       = "email"
 	prmQryFirstName   = "firstName"
@@ -42,8 +53,29 @@ const (
 	prmQryFirst       = "first"
 	prmQryMax         = "max"
 	prmQryGroupName   = "groupName"
+	prmQryFormat      = "format"
+	prmQryDryRun      = "dryRun"
+	prmQryActions     = "actions"
+	prmQryAction      = "action"
+	prmQryTargetRealm = "targetRealm"
+	prmQryTargetGroup = "targetGroup"
+	prmQryUser        = "user"
+	prmQryFrom        = "from"
+	prmQryTo          = "to"
+
+	prmQryPhoneNumber      = "phoneNumber"
+	prmQryTrustIDGroup     = "trustIdGroup"
+	prmQryAccreditation    = "accreditation"
+	prmQryLocale           = "locale"
+	prmQryIDDocumentNumber = "idDocumentNumber"
 )
 
+// CSVReply is a reply encoded as a CSV attachment
+type CSVReply struct {
+	Filename string
+	Records  [][]string
+}
+
 // MakeManagementHandler make an HTTP handler for a Management endpoint.
 func MakeManagementHandler(e endpoint.Endpoint, logger log.Logger) *http_transport.Server {
 	return http_transport.NewServer(e,
@@ -61,8 +93,14 @@ func decodeManagementRequest(ctx context.Context, req *http.Request) (interface{
 		prmClientID:     api.RegExpClientID,
 		prmRoleID:       api.RegExpID,
 		prmGroupID:      api.RegExpID,
+		prmChildGroupID: api.RegExpID,
 		prmCredentialID: api.RegExpID,
-		prmProvider:     api.RegExpName,
+		prmSessionID:    api.RegExpID,
+		prmProvider:     api.RegExpIdentityProvider,
+		prmJobID:        api.RegExpJobID,
+		prmTemplateName: api.RegExpName,
+		prmVersion:      api.RegExpNumber,
+		prmRequestID:    api.RegExpPendingRequestID,
 	}
 
 	var queryParams = map[string]string{
@@ -78,9 +116,32 @@ func decodeManagementRequest(ctx context.Context, req *http.Request) (interface{
 		prmQryFirst:       api.RegExpNumber,
 		prmQryMax:         api.RegExpNumber,
 		prmQryGroupName:   api.RegExpName,
+		prmQryFormat:      api.RegExpImportFormat,
+		prmQryDryRun:      api.RegExpBool,
+		prmQryActions:     api.RegExpRequiredActions,
+		prmQryAction:      api.RegExpAction,
+		prmQryTargetRealm: api.RegExpRealmName,
+		prmQryTargetGroup: api.RegExpName,
+		prmQryUser:        api.RegExpID,
+		prmQryFrom:        api.RegExpNumber,
+		prmQryTo:          api.RegExpNumber,
+
+		prmQryPhoneNumber:      api.RegExpPhoneNumber,
+		prmQryTrustIDGroup:     api.RegExpName,
+		prmQryAccreditation:    api.RegExpAccreditationType,
+		prmQryLocale:           api.RegExpLocale,
+		prmQryIDDocumentNumber: api.RegExpIDDocumentNumber,
 	}
 
-	return commonhttp.DecodeRequest(ctx, req, pathParams, queryParams)
+	var request, err = commonhttp.DecodeRequest(ctx, req, pathParams, queryParams)
+	if err != nil {
+		return nil, err
+	}
+
+	// optimistic concurrency: the client gives the version of the resource it read before updating it
+	request.(map[string]string)[reqIfMatch] = req.Header.Get("If-Match")
+
+	return request, nil
 }
 
 // encodeManagementReply encodes the reply.
@@ -90,6 +151,19 @@ func encodeManagementReply(ctx context.Context, w http.ResponseWriter, rep inter
 		w.Header().Set("Location", r.URL)
 		w.WriteHeader(http.StatusCreated)
 		return nil
+	case keycloakb.ETagReply:
+		w.Header().Set("ETag", r.ETag)
+		return commonhttp.EncodeReply(ctx, w, r.Value)
+	case PendingApprovalReply:
+		// The action was not executed: it is waiting for the approval of a second operator
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(http.StatusAccepted)
+		return json.NewEncoder(w).Encode(r.Request)
+	case CSVReply:
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", r.Filename))
+		w.WriteHeader(http.StatusOK)
+		return csv.NewWriter(w).WriteAll(r.Records)
 	default:
 		return commonhttp.EncodeReply(ctx, w, rep)
 	}
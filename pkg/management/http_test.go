@@ -108,6 +108,22 @@ func TestHTTPManagementHandler(t *testing.T) {
 		assert.Equal(t, http.NoBody, res.Body)
 	}
 
+	// Post - 202 with the pending request of an action waiting for an approval
+	{
+		var requestID = "0123456789abcdef0123456789abcdef"
+		var pending = PendingApproval{Request: api.PendingRequestRepresentation{ID: &requestID}}
+		mockComponent.EXPECT().ResetPassword(gomock.Any(), "master", "f467ed7c-0a1d-4eee-9bb8-669c6f89c0ee", gomock.Any()).Return("", pending).Times(1)
+
+		var body = strings.NewReader("{}")
+		res, err := http.Post(ts.URL+"/realms/master/users/f467ed7c-0a1d-4eee-9bb8-669c6f89c0ee/reset-password", "application/json", body)
+
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusAccepted, res.StatusCode)
+
+		var request api.PendingRequestRepresentation
+		assert.Nil(t, json.NewDecoder(res.Body).Decode(&request))
+		assert.Equal(t, requestID, *request.ID)
+	}
 }
 
 func TestHTTPErrorHandler(t *testing.T) {
@@ -220,6 +236,63 @@ func TestHTTPErrorHandler(t *testing.T) {
 	}
 }
 
+func TestHTTPETagHandler(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+	var mockComponent = mock.NewManagementComponent(mockCtrl)
+	var mockLogger = log.NewNopLogger()
+
+	var getUserHandler = MakeManagementHandler(keycloakb.ToGoKitEndpoint(MakeGetUserEndpoint(mockComponent)), mockLogger)
+	var updateUserHandler = MakeManagementHandler(keycloakb.ToGoKitEndpoint(MakeUpdateUserEndpoint(mockComponent)), mockLogger)
+
+	r := mux.NewRouter()
+	r.Path("/realms/{realm}/users/{userID}").Methods("GET").Handler(getUserHandler)
+	r.Path("/realms/{realm}/users/{userID}").Methods("PUT").Handler(updateUserHandler)
+
+	ts := httptest.NewServer(r)
+	defer ts.Close()
+
+	var userID = "f467ed7c-0a1d-4eee-9bb8-669c6f89c0ee"
+	var etag = `"0123456789abcdef"`
+	var client = &http.Client{}
+
+	t.Run("GET gives the version of the user in the ETag header", func(t *testing.T) {
+		var username = "toto"
+		mockComponent.EXPECT().GetUser(gomock.Any(), "master", userID).Return(api.UserRepresentation{Username: &username}, etag, nil).Times(1)
+
+		res, err := http.Get(ts.URL + "/realms/master/users/" + userID)
+
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		assert.Equal(t, etag, res.Header.Get("ETag"))
+		var user api.UserRepresentation
+		assert.Nil(t, json.NewDecoder(res.Body).Decode(&user))
+		assert.Equal(t, username, *user.Username)
+	})
+
+	t.Run("PUT with an outdated If-Match header", func(t *testing.T) {
+		mockComponent.EXPECT().UpdateUser(gomock.Any(), "master", userID, gomock.Any(), etag).
+			Return(commonhttp.Error{Status: http.StatusPreconditionFailed, Message: "keycloak-bridge.outdatedVersion.ifMatch"}).Times(1)
+
+		req, _ := http.NewRequest("PUT", ts.URL+"/realms/master/users/"+userID, strings.NewReader(`{}`))
+		req.Header.Set("If-Match", etag)
+		res, err := client.Do(req)
+
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusPreconditionFailed, res.StatusCode)
+	})
+
+	t.Run("PUT without If-Match header", func(t *testing.T) {
+		mockComponent.EXPECT().UpdateUser(gomock.Any(), "master", userID, gomock.Any(), "").Return(nil).Times(1)
+
+		req, _ := http.NewRequest("PUT", ts.URL+"/realms/master/users/"+userID, strings.NewReader(`{}`))
+		res, err := client.Do(req)
+
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+	})
+}
+
 func TestHTTPXForwardHeaderHandler(t *testing.T) {
 	var mockCtrl = gomock.NewController(t)
 	defer mockCtrl.Finish()
@@ -0,0 +1,239 @@
+package management
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"regexp"
+	"sync"
+	"time"
+
+	cs "github.com/cloudtrust/common-service"
+	errorhandler "github.com/cloudtrust/common-service/errors"
+	api "github.com/cloudtrust/keycloak-bridge/api/management"
+	"github.com/cloudtrust/keycloak-bridge/internal/constants"
+	"golang.org/x/time/rate"
+)
+
+// Finished users import jobs are kept in memory during this delay so that their results can be downloaded
+const usersImportJobRetention = 24 * time.Hour
+
+var userIDInLocation = regexp.MustCompile(`[0-9a-fA-F]{8}\-[0-9a-fA-F]{4}\-[0-9a-fA-F]{4}\-[0-9a-fA-F]{4}\-[0-9a-fA-F]{12}`)
+
+type usersImportJob struct {
+	realmName string
+	creator   string
+	progress  api.UserImportJobRepresentation
+	rows      api.UserImportRowsRepresentation
+}
+
+type usersImportJobs struct {
+	mutex   sync.Mutex
+	jobs    map[string]*usersImportJob
+	limiter *rate.Limiter
+}
+
+// CheckUsersImport validates the users of an import file without creating them
+func (c *component) CheckUsersImport(ctx context.Context, realmName string, users []api.UserImportEntry) (api.UserImportReportRepresentation, error) {
+	var rows = checkUsersImport(users)
+
+	var res = api.UserImportReportRepresentation{Total: len(rows), Rows: rows}
+	for _, row := range rows {
+		if row.Status == api.UserImportStatusValid {
+			res.Valid++
+		} else {
+			res.Invalid++
+		}
+	}
+	return res, nil
+}
+
+// ImportUsers starts the asynchronous creation of the valid users of an import file. Users are created as with CreateUser and,
+// if actions are provided, an execute-actions email is sent to each created user. The import uses the access token of the caller:
+// very large imports should be split so that they complete before the token expires. Users are created at the rate of the bulk operations
+func (c *component) ImportUsers(ctx context.Context, realmName string, users []api.UserImportEntry, actions []api.RequiredAction) (api.UserImportJobRepresentation, error) {
+	var jobID, err = newRandomID()
+	if err != nil {
+		c.logger.Warn(ctx, "msg", "Can't generate users import job ID", "err", err.Error())
+		return api.UserImportJobRepresentation{}, err
+	}
+
+	var rows = checkUsersImport(users)
+	var job = &usersImportJob{
+		realmName: realmName,
+		creator:   ctx.Value(cs.CtContextUserID).(string),
+		progress: api.UserImportJobRepresentation{
+			ID:        jobID,
+			Status:    api.UserImportJobStatusRunning,
+			Total:     len(rows),
+			StartedAt: time.Now().Unix(),
+		},
+		rows: rows,
+	}
+
+	var registry = c.usersImportJobs
+	registry.mutex.Lock()
+	registry.purge(time.Now())
+	registry.jobs[jobID] = job
+	var res = job.progress
+	registry.mutex.Unlock()
+
+	go c.runUsersImport(detachContext(ctx), job, users, actions)
+
+	return res, nil
+}
+
+func (c *component) runUsersImport(ctx context.Context, job *usersImportJob, users []api.UserImportEntry, actions []api.RequiredAction) {
+	var registry = c.usersImportJobs
+
+	for i, entry := range users {
+		registry.mutex.Lock()
+		var row = job.rows[i]
+		registry.mutex.Unlock()
+
+		if row.Status == api.UserImportStatusValid {
+			// The limiter is shared with the bulk operations and must not depend on the request context
+			if err := registry.limiter.Wait(context.Background()); err != nil {
+				var errMsg = err.Error()
+				row.Status = api.UserImportStatusFailed
+				row.Error = &errMsg
+			} else {
+				row = c.importUser(ctx, job.realmName, entry, actions, row)
+			}
+		}
+
+		registry.mutex.Lock()
+		job.rows[i] = row
+		job.progress.Processed++
+		if row.Status == api.UserImportStatusCreated {
+			job.progress.Created++
+		} else {
+			job.progress.Failed++
+		}
+		registry.mutex.Unlock()
+	}
+
+	registry.mutex.Lock()
+	var finishedAt = time.Now().Unix()
+	job.progress.Status = api.UserImportJobStatusDone
+	job.progress.FinishedAt = &finishedAt
+	registry.mutex.Unlock()
+
+	c.logger.Info(ctx, "msg", "Users import done", "realm", job.realmName, "job", job.progress.ID, "created", job.progress.Created, "failed", job.progress.Failed)
+}
+
+func (c *component) importUser(ctx context.Context, realmName string, entry api.UserImportEntry, actions []api.RequiredAction, row api.UserImportRowRepresentation) api.UserImportRowRepresentation {
+	// CreateUser stores the user details in database and reports the API_ACCOUNT_CREATION event
+	locationURL, err := c.CreateUser(ctx, realmName, entry.User)
+	if err != nil {
+		var errMsg = err.Error()
+		row.Status = api.UserImportStatusFailed
+		row.Error = &errMsg
+		return row
+	}
+
+	var userID = userIDInLocation.FindString(locationURL)
+	row.Status = api.UserImportStatusCreated
+	row.UserID = &userID
+
+	if len(actions) > 0 {
+		// The user is created: a failure to send the email is only reported in the results
+		if err = c.ExecuteActionsEmail(ctx, realmName, userID, actions); err != nil {
+			var errMsg = "executeActionsEmail: " + err.Error()
+			row.Error = &errMsg
+		}
+	}
+	return row
+}
+
+// GetUsersImportJob gives the progress of a users import job. A job is only visible to the user who started it
+func (c *component) GetUsersImportJob(ctx context.Context, realmName string, jobID string) (api.UserImportJobRepresentation, error) {
+	var registry = c.usersImportJobs
+	registry.mutex.Lock()
+	defer registry.mutex.Unlock()
+
+	var job, ok = registry.jobs[jobID]
+	if !ok || job.realmName != realmName || job.creator != ctx.Value(cs.CtContextUserID).(string) {
+		return api.UserImportJobRepresentation{}, errorhandler.CreateNotFoundError("usersImportJob")
+	}
+	return job.progress, nil
+}
+
+// GetUsersImportResults gives the result of each row of a users import job. Rows not processed yet keep their validation status.
+// A job is only visible to the user who started it
+func (c *component) GetUsersImportResults(ctx context.Context, realmName string, jobID string) (api.UserImportRowsRepresentation, error) {
+	var registry = c.usersImportJobs
+	registry.mutex.Lock()
+	defer registry.mutex.Unlock()
+
+	var job, ok = registry.jobs[jobID]
+	if !ok || job.realmName != realmName || job.creator != ctx.Value(cs.CtContextUserID).(string) {
+		return nil, errorhandler.CreateNotFoundError("usersImportJob")
+	}
+	return append(api.UserImportRowsRepresentation{}, job.rows...), nil
+}
+
+// checkUsersImport validates each user of an import file. A user is valid if it passes UserRepresentation.Validate(),
+// has a username which is not used by another user of the file and belongs to at least one group
+func checkUsersImport(users []api.UserImportEntry) api.UserImportRowsRepresentation {
+	var res = make(api.UserImportRowsRepresentation, 0, len(users))
+	var usernames = make(map[string]bool)
+
+	for _, entry := range users {
+		var row = api.UserImportRowRepresentation{Line: entry.Line, Status: api.UserImportStatusValid}
+		var errMsg string
+
+		if entry.User.Username != nil {
+			row.Username = *entry.User.Username
+		}
+
+		if err := entry.User.Validate(); err != nil {
+			errMsg = err.Error()
+		} else if row.Username == "" {
+			errMsg = constants.MsgErrMissingParam + "." + constants.Username
+		} else if usernames[row.Username] {
+			errMsg = constants.MsgErrInvalidParam + "." + constants.Username + ".duplicate"
+		} else if entry.User.Groups == nil || len(*entry.User.Groups) == 0 {
+			errMsg = constants.MsgErrMissingParam + "." + constants.Groups
+		}
+		usernames[row.Username] = true
+
+		if errMsg != "" {
+			row.Status = api.UserImportStatusInvalid
+			row.Error = &errMsg
+		}
+		res = append(res, row)
+	}
+
+	return res
+}
+
+// purge removes the jobs finished for more than the retention delay. The mutex must be held by the caller
+func (r *usersImportJobs) purge(now time.Time) {
+	for jobID, job := range r.jobs {
+		if job.progress.FinishedAt != nil && now.Sub(time.Unix(*job.progress.FinishedAt, 0)) > usersImportJobRetention {
+			delete(r.jobs, jobID)
+		}
+	}
+}
+
+// detachContext returns a context for a job which outlives the request: it is not cancelled with the request but keeps
+// the access token and the identity of the caller, used to call Keycloak, to check authorizations and to report events
+func detachContext(ctx context.Context) context.Context {
+	var res = context.Background()
+	for _, key := range []interface{}{cs.CtContextAccessToken, cs.CtContextRealm, cs.CtContextUserID, cs.CtContextUsername,
+		cs.CtContextGroups, cs.CtContextCorrelationID} {
+		if value := ctx.Value(key); value != nil {
+			res = context.WithValue(res, key, value)
+		}
+	}
+	return res
+}
+
+func newRandomID() (string, error) {
+	var bytes = make([]byte, 16)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(bytes), nil
+}
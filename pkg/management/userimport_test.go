@@ -0,0 +1,149 @@
+package management
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	cs "github.com/cloudtrust/common-service"
+	"github.com/cloudtrust/common-service/log"
+	api "github.com/cloudtrust/keycloak-bridge/api/management"
+	"github.com/cloudtrust/keycloak-bridge/pkg/management/mock"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func createImportEntry(line int, username string, groups ...string) api.UserImportEntry {
+	var user = api.UserRepresentation{}
+	if username != "" {
+		user.Username = &username
+	}
+	if len(groups) > 0 {
+		user.Groups = &groups
+	}
+	return api.UserImportEntry{Line: line, User: user}
+}
+
+func TestCheckUsersImport(t *testing.T) {
+	var groupID = "41dbf4a8-32a9-4000-8c17-edc854c31231"
+	var invalidEmail = "not-an-email"
+	var invalidUser = createImportEntry(6, "user5", groupID)
+	invalidUser.User.Email = &invalidEmail
+
+	var rows = checkUsersImport([]api.UserImportEntry{
+		createImportEntry(2, "user1", groupID),
+		createImportEntry(3, "", groupID),
+		createImportEntry(4, "user1", groupID),
+		createImportEntry(5, "user4"),
+		invalidUser,
+	})
+
+	assert.Len(t, rows, 5)
+	assert.Equal(t, api.UserImportStatusValid, rows[0].Status)
+	assert.Nil(t, rows[0].Error)
+	for _, row := range rows[1:] {
+		assert.Equal(t, api.UserImportStatusInvalid, row.Status)
+		assert.NotNil(t, row.Error)
+	}
+	assert.Equal(t, 6, rows[4].Line)
+}
+
+func TestUsersImport(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+	var mockKeycloakClient = mock.NewKeycloakClient(mockCtrl)
+	var mockUsersDetailsDBModule = mock.NewUsersDetailsDBModule(mockCtrl)
+	var mockEventDBModule = mock.NewEventDBModule(mockCtrl)
+	var mockConfigurationDBModule = mock.NewConfigurationDBModule(mockCtrl)
+
+	var limiter, _ = NewBulkLimiter(1000)
+	var managementComponent = NewComponent(mockKeycloakClient, mockUsersDetailsDBModule, mockEventDBModule, mockConfigurationDBModule, nil, nil, limiter, log.NewNopLogger())
+
+	var accessToken = "TOKEN=="
+	var realmName = "master"
+	var targetRealmName = "DEP"
+	var groupID = "41dbf4a8-32a9-4000-8c17-edc854c31231"
+	var userID = "f467ed7c-0a1d-4eee-9bb8-669c6f89c000"
+	var ctx = context.WithValue(context.Background(), cs.CtContextAccessToken, accessToken)
+	ctx = context.WithValue(ctx, cs.CtContextRealm, realmName)
+	ctx = context.WithValue(ctx, cs.CtContextUserID, "creator-id")
+
+	var users = []api.UserImportEntry{
+		createImportEntry(1, "user1", groupID),
+		createImportEntry(2, "user2", groupID),
+		createImportEntry(3, ""),
+	}
+
+	t.Run("Dry run", func(t *testing.T) {
+		var report, err = managementComponent.CheckUsersImport(ctx, targetRealmName, users)
+		assert.Nil(t, err)
+		assert.Equal(t, 3, report.Total)
+		assert.Equal(t, 2, report.Valid)
+		assert.Equal(t, 1, report.Invalid)
+	})
+
+	t.Run("Unknown job", func(t *testing.T) {
+		var _, err = managementComponent.GetUsersImportJob(ctx, targetRealmName, "0123456789abcdef0123456789abcdef")
+		assert.NotNil(t, err)
+		_, err = managementComponent.GetUsersImportResults(ctx, targetRealmName, "0123456789abcdef0123456789abcdef")
+		assert.NotNil(t, err)
+	})
+
+	t.Run("Import", func(t *testing.T) {
+		var actions = []api.RequiredAction{"VERIFY_EMAIL"}
+		gomock.InOrder(
+			mockKeycloakClient.EXPECT().CreateUser(accessToken, realmName, targetRealmName, gomock.Any()).Return("http://toto.com/realms/"+userID, nil),
+			mockKeycloakClient.EXPECT().CreateUser(accessToken, realmName, targetRealmName, gomock.Any()).Return("", errors.New("conflict")),
+		)
+		mockEventDBModule.EXPECT().ReportEvent(gomock.Any(), "API_ACCOUNT_CREATION", "back-office", gomock.Any()).Return(nil)
+		mockEventDBModule.EXPECT().ReportEvent(gomock.Any(), "ACTION_EMAIL", "back-office", gomock.Any()).Return(nil)
+		mockKeycloakClient.EXPECT().ExecuteActionsEmail(accessToken, targetRealmName, userID, []string{"VERIFY_EMAIL"}).Return(nil)
+
+		var job, err = managementComponent.ImportUsers(ctx, targetRealmName, users, actions)
+		assert.Nil(t, err)
+		assert.Len(t, job.ID, 32)
+		assert.Equal(t, 3, job.Total)
+
+		for i := 0; i < 100 && job.Status != api.UserImportJobStatusDone; i++ {
+			time.Sleep(10 * time.Millisecond)
+			job, err = managementComponent.GetUsersImportJob(ctx, targetRealmName, job.ID)
+			assert.Nil(t, err)
+		}
+		assert.Equal(t, api.UserImportJobStatusDone, job.Status)
+		assert.Equal(t, 3, job.Processed)
+		assert.Equal(t, 1, job.Created)
+		assert.Equal(t, 2, job.Failed)
+
+		// Jobs are only visible in their realm, to the user who started them
+		_, err = managementComponent.GetUsersImportJob(ctx, realmName, job.ID)
+		assert.NotNil(t, err)
+		var otherCtx = context.WithValue(ctx, cs.CtContextUserID, "other-id")
+		_, err = managementComponent.GetUsersImportJob(otherCtx, targetRealmName, job.ID)
+		assert.NotNil(t, err)
+		_, err = managementComponent.GetUsersImportResults(otherCtx, targetRealmName, job.ID)
+		assert.NotNil(t, err)
+
+		rows, err := managementComponent.GetUsersImportResults(ctx, targetRealmName, job.ID)
+		assert.Nil(t, err)
+		assert.Equal(t, api.UserImportStatusCreated, rows[0].Status)
+		assert.Equal(t, userID, *rows[0].UserID)
+		assert.Equal(t, api.UserImportStatusFailed, rows[1].Status)
+		assert.Equal(t, api.UserImportStatusInvalid, rows[2].Status)
+	})
+}
+
+func TestPurgeUsersImportJobs(t *testing.T) {
+	var now = time.Now()
+	var old = now.Add(-25 * time.Hour).Unix()
+	var recent = now.Add(-time.Hour).Unix()
+	var registry = usersImportJobs{jobs: map[string]*usersImportJob{
+		"old":     {progress: api.UserImportJobRepresentation{FinishedAt: &old}},
+		"recent":  {progress: api.UserImportJobRepresentation{FinishedAt: &recent}},
+		"running": {},
+	}}
+
+	registry.purge(now)
+	assert.Len(t, registry.jobs, 2)
+	assert.Nil(t, registry.jobs["old"])
+}
@@ -0,0 +1,204 @@
+package management
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	cs "github.com/cloudtrust/common-service"
+	errorhandler "github.com/cloudtrust/common-service/errors"
+	"github.com/cloudtrust/common-service/validation"
+	api "github.com/cloudtrust/keycloak-bridge/api/management"
+	"github.com/cloudtrust/keycloak-bridge/internal/constants"
+	"github.com/cloudtrust/keycloak-bridge/internal/keycloakb"
+)
+
+const (
+	// Accreditations are stored as JSON in a single attribute and can't be searched by Keycloak: users are read by
+	// batches and filtered by the bridge. A search which would read more users is rejected
+	searchUsersBatchSize  = 100
+	searchUsersMaxScanned = 10000
+)
+
+// SearchUsers finds the users of the given groups matching all the given criteria. Attributes are searched by Keycloak,
+// the ID document number is searched in the users DB using its blind index
+func (c *component) SearchUsers(ctx context.Context, realmName string, groupIDs []string, criteria api.UsersSearchRepresentation) (api.UsersPageRepresentation, error) {
+	var users []api.UserRepresentation
+	var err error
+
+	if criteria.IDDocumentNumber != nil {
+		users, err = c.searchUsersByIDDocumentNumber(ctx, realmName, groupIDs, criteria)
+	} else if criteria.Accreditation != nil {
+		users, err = c.searchUsersByAccreditation(ctx, realmName, groupIDs, criteria)
+	} else {
+		var paramKV = searchUsersParamKV(groupIDs, criteria)
+		if criteria.First != nil {
+			paramKV = append(paramKV, "first", strconv.Itoa(*criteria.First))
+		}
+		if criteria.Max != nil {
+			paramKV = append(paramKV, "max", strconv.Itoa(*criteria.Max))
+		}
+		return c.getUsersPage(ctx, realmName, paramKV)
+	}
+
+	if err != nil {
+		return api.UsersPageRepresentation{}, err
+	}
+	return pageUsers(users, criteria.First, criteria.Max), nil
+}
+
+func (c *component) searchUsersByIDDocumentNumber(ctx context.Context, realmName string, groupIDs []string, criteria api.UsersSearchRepresentation) ([]api.UserRepresentation, error) {
+	var accessToken = ctx.Value(cs.CtContextAccessToken).(string)
+
+	userIDs, err := c.usersDBModule.FindUsersByIDDocumentNumber(ctx, realmName, *criteria.IDDocumentNumber)
+	if err != nil {
+		c.logger.Warn(ctx, "msg", "Can't find users by ID document number", "err", err.Error())
+		return nil, err
+	}
+
+	var res = []api.UserRepresentation{}
+	for _, userID := range userIDs {
+		userKc, err := c.keycloakClient.GetUser(accessToken, realmName, userID)
+		if err != nil {
+			c.logger.Warn(ctx, "err", err.Error())
+			return nil, err
+		}
+		keycloakb.ConvertLegacyAttribute(&userKc)
+		var user = api.ConvertToAPIUser(ctx, userKc, c.logger)
+		if !matchesUsersSearch(user, criteria) {
+			continue
+		}
+
+		groupsKc, err := c.keycloakClient.GetGroupsOfUser(accessToken, realmName, userID)
+		if err != nil {
+			c.logger.Warn(ctx, "err", err.Error())
+			return nil, err
+		}
+		for _, group := range groupsKc {
+			if group.ID != nil && validation.IsStringInSlice(groupIDs, *group.ID) {
+				res = append(res, user)
+				break
+			}
+		}
+	}
+	return res, nil
+}
+
+func (c *component) searchUsersByAccreditation(ctx context.Context, realmName string, groupIDs []string, criteria api.UsersSearchRepresentation) ([]api.UserRepresentation, error) {
+	var paramKV = searchUsersParamKV(groupIDs, criteria)
+
+	var res = []api.UserRepresentation{}
+	for first := 0; ; first += searchUsersBatchSize {
+		if first >= searchUsersMaxScanned {
+			return nil, c.tooBroadSearchError(ctx, realmName)
+		}
+		var batchParamKV = append(paramKV, "first", strconv.Itoa(first), "max", strconv.Itoa(searchUsersBatchSize))
+		page, err := c.getUsersPage(ctx, realmName, batchParamKV)
+		if err != nil {
+			return nil, err
+		}
+		if page.Count != nil && *page.Count > searchUsersMaxScanned {
+			return nil, c.tooBroadSearchError(ctx, realmName)
+		}
+		for _, user := range page.Users {
+			if hasAccreditation(user, *criteria.Accreditation) {
+				res = append(res, user)
+			}
+		}
+		if len(page.Users) < searchUsersBatchSize || (page.Count != nil && first+searchUsersBatchSize >= *page.Count) {
+			break
+		}
+	}
+	return res, nil
+}
+
+// tooBroadSearchError rejects a search by accreditation which would read more than searchUsersMaxScanned users: the
+// operator must restrict it with other criteria instead of getting an incomplete result
+func (c *component) tooBroadSearchError(ctx context.Context, realmName string) error {
+	c.logger.Warn(ctx, "msg", "Search by accreditation matches too many users", "realm", realmName, "max", searchUsersMaxScanned)
+	return errorhandler.CreateBadRequestError(constants.MsgErrTooManyUsers + "." + constants.Accreditation)
+}
+
+func (c *component) getUsersPage(ctx context.Context, realmName string, paramKV []string) (api.UsersPageRepresentation, error) {
+	var accessToken = ctx.Value(cs.CtContextAccessToken).(string)
+	var ctxRealm = ctx.Value(cs.CtContextRealm).(string)
+
+	usersKc, err := c.keycloakClient.GetUsers(accessToken, ctxRealm, realmName, paramKV...)
+	if err != nil {
+		c.logger.Warn(ctx, "err", err.Error())
+		return api.UsersPageRepresentation{}, err
+	}
+
+	for i := 0; i < len(usersKc.Users); i++ {
+		keycloakb.ConvertLegacyAttribute(&usersKc.Users[i])
+	}
+	return api.ConvertToAPIUsersPage(ctx, usersKc, c.logger), nil
+}
+
+// searchUsersParamKV builds the Keycloak query of the criteria searchable by Keycloak
+func searchUsersParamKV(groupIDs []string, criteria api.UsersSearchRepresentation) []string {
+	var paramKV []string
+	for _, groupID := range groupIDs {
+		paramKV = append(paramKV, "groupId", groupID)
+	}
+
+	var query []string
+	if criteria.PhoneNumber != nil {
+		query = append(query, string(constants.AttrbPhoneNumber)+":"+*criteria.PhoneNumber)
+	}
+	if criteria.TrustIDGroup != nil {
+		query = append(query, string(constants.AttrbTrustIDGroups)+":/"+*criteria.TrustIDGroup)
+	}
+	if criteria.Locale != nil {
+		query = append(query, string(constants.AttrbLocale)+":"+*criteria.Locale)
+	}
+	if len(query) > 0 {
+		paramKV = append(paramKV, "q", strings.Join(query, " "))
+	}
+	return paramKV
+}
+
+// matchesUsersSearch checks the criteria of a search on a user which has not been found by Keycloak
+func matchesUsersSearch(user api.UserRepresentation, criteria api.UsersSearchRepresentation) bool {
+	if criteria.PhoneNumber != nil && (user.PhoneNumber == nil || *user.PhoneNumber != *criteria.PhoneNumber) {
+		return false
+	}
+	if criteria.Locale != nil && (user.Locale == nil || *user.Locale != *criteria.Locale) {
+		return false
+	}
+	if criteria.TrustIDGroup != nil && (user.TrustIDGroups == nil || !validation.IsStringInSlice(*user.TrustIDGroups, "/"+*criteria.TrustIDGroup)) {
+		return false
+	}
+	return criteria.Accreditation == nil || hasAccreditation(user, *criteria.Accreditation)
+}
+
+func hasAccreditation(user api.UserRepresentation, accreditationType string) bool {
+	if user.Accreditations == nil {
+		return false
+	}
+	for _, accreditation := range *user.Accreditations {
+		if accreditation.Type != nil && *accreditation.Type == accreditationType {
+			return true
+		}
+	}
+	return false
+}
+
+// pageUsers applies the paging of a search to users filtered by the bridge
+func pageUsers(users []api.UserRepresentation, first *int, max *int) api.UsersPageRepresentation {
+	var count = len(users)
+	var start, end = 0, count
+	if first != nil {
+		start = *first
+		if start > count {
+			start = count
+		}
+	}
+	if max != nil && start+*max < count {
+		end = start + *max
+	}
+	return api.UsersPageRepresentation{
+		Count: &count,
+		Users: users[start:end],
+	}
+}
@@ -0,0 +1,183 @@
+package management
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	cs "github.com/cloudtrust/common-service"
+	errorhandler "github.com/cloudtrust/common-service/errors"
+	"github.com/cloudtrust/common-service/log"
+	api "github.com/cloudtrust/keycloak-bridge/api/management"
+	"github.com/cloudtrust/keycloak-bridge/internal/constants"
+	"github.com/cloudtrust/keycloak-bridge/pkg/management/mock"
+	kc "github.com/cloudtrust/keycloak-client"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func createSearchedUser(id string, phoneNumber string, accreditationType string) kc.UserRepresentation {
+	var attributes = make(kc.Attributes)
+	attributes.SetString(constants.AttrbPhoneNumber, phoneNumber)
+	if accreditationType != "" {
+		attributes.SetString(constants.AttrbAccreditations, `{"type":"`+accreditationType+`","expiryDate":"01.01.2040"}`)
+	}
+	return kc.UserRepresentation{ID: &id, Attributes: &attributes}
+}
+
+func TestSearchUsers(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+	var mockKeycloakClient = mock.NewKeycloakClient(mockCtrl)
+	var mockUsersDetailsDBModule = mock.NewUsersDetailsDBModule(mockCtrl)
+	var mockEventDBModule = mock.NewEventDBModule(mockCtrl)
+	var mockConfigurationDBModule = mock.NewConfigurationDBModule(mockCtrl)
+
+	var managementComponent = NewComponent(mockKeycloakClient, mockUsersDetailsDBModule, mockEventDBModule, mockConfigurationDBModule, nil, nil, nil, log.NewNopLogger())
+
+	var accessToken = "TOKEN=="
+	var realmName = "master"
+	var targetRealmName = "DEP"
+	var groupID = "41dbf4a8-32a9-4000-8c17-edc854c31231"
+	var otherGroupID = "82b5a3c1-32a9-4000-8c17-edc854c31231"
+	var userID = "f467ed7c-0a1d-4eee-9bb8-669c6f89c000"
+	var otherUserID = "a2c4e6f8-0a1d-4eee-9bb8-669c6f89c000"
+	var phoneNumber = "+41791234567"
+	var locale = "en"
+	var accreditation = "SHADOW"
+	var documentNumber = "AB123456"
+	var first = 0
+	var max = 10
+	var anyError = errors.New("any error")
+	var ctx = context.WithValue(context.Background(), cs.CtContextAccessToken, accessToken)
+	ctx = context.WithValue(ctx, cs.CtContextRealm, realmName)
+
+	t.Run("Search by attributes", func(t *testing.T) {
+		var count = 1
+		var criteria = api.UsersSearchRepresentation{PhoneNumber: &phoneNumber, Locale: &locale, First: &first, Max: &max}
+		mockKeycloakClient.EXPECT().GetUsers(accessToken, realmName, targetRealmName, "groupId", groupID, "q", "phoneNumber:+41791234567 locale:en", "first", "0", "max", "10").
+			Return(kc.UsersPageRepresentation{Count: &count, Users: []kc.UserRepresentation{createSearchedUser(userID, phoneNumber, "")}}, nil)
+
+		var res, err = managementComponent.SearchUsers(ctx, targetRealmName, []string{groupID}, criteria)
+		assert.Nil(t, err)
+		assert.Equal(t, 1, *res.Count)
+		assert.Equal(t, userID, *res.Users[0].ID)
+	})
+	t.Run("Search by attributes fails", func(t *testing.T) {
+		var criteria = api.UsersSearchRepresentation{PhoneNumber: &phoneNumber}
+		mockKeycloakClient.EXPECT().GetUsers(accessToken, realmName, targetRealmName, "groupId", groupID, "q", "phoneNumber:+41791234567").
+			Return(kc.UsersPageRepresentation{}, anyError)
+
+		var _, err = managementComponent.SearchUsers(ctx, targetRealmName, []string{groupID}, criteria)
+		assert.Equal(t, anyError, err)
+	})
+
+	t.Run("Search by accreditation", func(t *testing.T) {
+		var count = 2
+		var criteria = api.UsersSearchRepresentation{Accreditation: &accreditation}
+		mockKeycloakClient.EXPECT().GetUsers(accessToken, realmName, targetRealmName, "groupId", groupID, "first", "0", "max", "100").
+			Return(kc.UsersPageRepresentation{Count: &count, Users: []kc.UserRepresentation{
+				createSearchedUser(userID, phoneNumber, accreditation),
+				createSearchedUser(otherUserID, phoneNumber, "OTHER"),
+			}}, nil)
+
+		var res, err = managementComponent.SearchUsers(ctx, targetRealmName, []string{groupID}, criteria)
+		assert.Nil(t, err)
+		assert.Equal(t, 1, *res.Count)
+		assert.Equal(t, userID, *res.Users[0].ID)
+	})
+	t.Run("Search by accreditation matching too many users", func(t *testing.T) {
+		var count = searchUsersMaxScanned + 1
+		var criteria = api.UsersSearchRepresentation{Accreditation: &accreditation}
+		mockKeycloakClient.EXPECT().GetUsers(accessToken, realmName, targetRealmName, "groupId", groupID, "first", "0", "max", "100").
+			Return(kc.UsersPageRepresentation{Count: &count, Users: []kc.UserRepresentation{createSearchedUser(userID, phoneNumber, accreditation)}}, nil)
+
+		var _, err = managementComponent.SearchUsers(ctx, targetRealmName, []string{groupID}, criteria)
+		assert.Equal(t, errorhandler.CreateBadRequestError(constants.MsgErrTooManyUsers+"."+constants.Accreditation), err)
+	})
+	t.Run("Search by accreditation reaching the maximum number of scanned users", func(t *testing.T) {
+		var criteria = api.UsersSearchRepresentation{Accreditation: &accreditation}
+		var batch = make([]kc.UserRepresentation, searchUsersBatchSize)
+		for i := range batch {
+			batch[i] = createSearchedUser(userID, phoneNumber, "OTHER")
+		}
+		// The number of users is not given by Keycloak
+		mockKeycloakClient.EXPECT().GetUsers(accessToken, realmName, targetRealmName, "groupId", groupID, "first", gomock.Any(), "max", "100").
+			Return(kc.UsersPageRepresentation{Users: batch}, nil).Times(searchUsersMaxScanned / searchUsersBatchSize)
+
+		var _, err = managementComponent.SearchUsers(ctx, targetRealmName, []string{groupID}, criteria)
+		assert.Equal(t, errorhandler.CreateBadRequestError(constants.MsgErrTooManyUsers+"."+constants.Accreditation), err)
+	})
+	t.Run("Search by accreditation fails", func(t *testing.T) {
+		var criteria = api.UsersSearchRepresentation{Accreditation: &accreditation}
+		mockKeycloakClient.EXPECT().GetUsers(accessToken, realmName, targetRealmName, "groupId", groupID, "first", "0", "max", "100").
+			Return(kc.UsersPageRepresentation{}, anyError)
+
+		var _, err = managementComponent.SearchUsers(ctx, targetRealmName, []string{groupID}, criteria)
+		assert.Equal(t, anyError, err)
+	})
+
+	t.Run("Search by ID document number: DB fails", func(t *testing.T) {
+		var criteria = api.UsersSearchRepresentation{IDDocumentNumber: &documentNumber}
+		mockUsersDetailsDBModule.EXPECT().FindUsersByIDDocumentNumber(ctx, targetRealmName, documentNumber).Return(nil, anyError)
+
+		var _, err = managementComponent.SearchUsers(ctx, targetRealmName, []string{groupID}, criteria)
+		assert.Equal(t, anyError, err)
+	})
+	t.Run("Search by ID document number: Keycloak fails", func(t *testing.T) {
+		var criteria = api.UsersSearchRepresentation{IDDocumentNumber: &documentNumber}
+		mockUsersDetailsDBModule.EXPECT().FindUsersByIDDocumentNumber(ctx, targetRealmName, documentNumber).Return([]string{userID}, nil)
+		mockKeycloakClient.EXPECT().GetUser(accessToken, targetRealmName, userID).Return(kc.UserRepresentation{}, anyError)
+
+		var _, err = managementComponent.SearchUsers(ctx, targetRealmName, []string{groupID}, criteria)
+		assert.Equal(t, anyError, err)
+	})
+	t.Run("Search by ID document number", func(t *testing.T) {
+		var thirdUserID = "c3d5f7a9-0a1d-4eee-9bb8-669c6f89c000"
+		var otherPhoneNumber = "+41797654321"
+		var criteria = api.UsersSearchRepresentation{IDDocumentNumber: &documentNumber, PhoneNumber: &phoneNumber}
+		mockUsersDetailsDBModule.EXPECT().FindUsersByIDDocumentNumber(ctx, targetRealmName, documentNumber).Return([]string{userID, otherUserID, thirdUserID}, nil)
+		mockKeycloakClient.EXPECT().GetUser(accessToken, targetRealmName, userID).Return(createSearchedUser(userID, phoneNumber, ""), nil)
+		mockKeycloakClient.EXPECT().GetGroupsOfUser(accessToken, targetRealmName, userID).Return([]kc.GroupRepresentation{{ID: &otherGroupID}, {ID: &groupID}}, nil)
+		// Not in the searched groups
+		mockKeycloakClient.EXPECT().GetUser(accessToken, targetRealmName, otherUserID).Return(createSearchedUser(otherUserID, phoneNumber, ""), nil)
+		mockKeycloakClient.EXPECT().GetGroupsOfUser(accessToken, targetRealmName, otherUserID).Return([]kc.GroupRepresentation{{ID: &otherGroupID}}, nil)
+		// Not matching the other criteria
+		mockKeycloakClient.EXPECT().GetUser(accessToken, targetRealmName, thirdUserID).Return(createSearchedUser(thirdUserID, otherPhoneNumber, ""), nil)
+
+		var res, err = managementComponent.SearchUsers(ctx, targetRealmName, []string{groupID}, criteria)
+		assert.Nil(t, err)
+		assert.Equal(t, 1, *res.Count)
+		assert.Len(t, res.Users, 1)
+		assert.Equal(t, userID, *res.Users[0].ID)
+	})
+}
+
+func TestPageUsers(t *testing.T) {
+	var ids = []string{"1", "2", "3"}
+	var users []api.UserRepresentation
+	for i := range ids {
+		users = append(users, api.UserRepresentation{ID: &ids[i]})
+	}
+	var one, two, five = 1, 2, 5
+
+	t.Run("No paging", func(t *testing.T) {
+		var res = pageUsers(users, nil, nil)
+		assert.Equal(t, 3, *res.Count)
+		assert.Len(t, res.Users, 3)
+	})
+	t.Run("First and max", func(t *testing.T) {
+		var res = pageUsers(users, &one, &one)
+		assert.Equal(t, 3, *res.Count)
+		assert.Equal(t, []api.UserRepresentation{users[1]}, res.Users)
+	})
+	t.Run("Max after the last user", func(t *testing.T) {
+		var res = pageUsers(users, &two, &five)
+		assert.Equal(t, []api.UserRepresentation{users[2]}, res.Users)
+	})
+	t.Run("First after the last user", func(t *testing.T) {
+		var res = pageUsers(users, &five, &one)
+		assert.Equal(t, 3, *res.Count)
+		assert.Len(t, res.Users, 0)
+	})
+}
@@ -75,7 +75,7 @@ type componentBuilder struct {
 
 // NewComponentBuilder returns a builder for the management component.
 func NewComponentBuilder(keycloakURL string, keycloakClient KeycloakClient, tokenProvider toolbox.OidcTokenProvider, usersDBModule keycloakb.UsersDetailsDBModule,
-	configDBModule ConfigurationDBModule, eventsDBModule database.EventsDBModule, logger internal.Logger) ComponentBuilder {
+	configDBModule ConfigurationDBModule, eventsDBModule database.EventsDBModule, sagaModule keycloakb.SagaModule, logger internal.Logger) ComponentBuilder {
 	var component = &component{
 		keycloakURL:         keycloakURL,
 		realmConfigurations: make(map[string]RealmRegisterConfiguration),
@@ -84,6 +84,7 @@ func NewComponentBuilder(keycloakURL string, keycloakClient KeycloakClient, toke
 		usersDBModule:       usersDBModule,
 		configDBModule:      configDBModule,
 		eventsDBModule:      eventsDBModule,
+		sagaModule:          sagaModule,
 		logger:              logger,
 	}
 	return &componentBuilder{component: component}
@@ -112,6 +113,7 @@ type component struct {
 	usersDBModule       keycloakb.UsersDetailsDBModule
 	configDBModule      ConfigurationDBModule
 	eventsDBModule      database.EventsDBModule
+	sagaModule          keycloakb.SagaModule
 	logger              internal.Logger
 }
 
@@ -197,15 +199,28 @@ func (c *component) RegisterUser(ctx context.Context, targetRealmName, customerR
 	return username, nil
 }
 
+// userDetails returns the details of a registered user which are stored in the users database
+func userDetails(userID string, user apiregister.UserRepresentation) dto.DBUser {
+	return dto.DBUser{
+		UserID:               &userID,
+		BirthLocation:        user.BirthLocation,
+		IDDocumentType:       user.IDDocumentType,
+		IDDocumentNumber:     user.IDDocumentNumber,
+		IDDocumentExpiration: user.IDDocumentExpiration,
+	}
+}
+
 func (c *component) storeUser(ctx context.Context, accessToken string, targetRealmName, customerRealmName string, user apiregister.UserRepresentation, existingKcUser *kc.UserRepresentation, realmConf configuration.RealmConfiguration) (string, string, error) {
 	authToken, err := c.generateAuthToken()
 
 	var userID string
+	var saga keycloakb.SagaOperation
 	var kcUser = user.ConvertToKeycloak()
 	kcUser.SetAttributeString(constants.AttrbTrustIDAuthToken, authToken.ToJSON())
 
 	if existingKcUser == nil {
-		userID, err = c.createKeycloakUser(ctx, accessToken, targetRealmName, &kcUser)
+		// The user is deleted from Keycloak if its details can't be stored
+		userID, saga, err = c.createKeycloakUser(ctx, accessToken, targetRealmName, &kcUser)
 		if err != nil {
 			return "", "", err
 		}
@@ -216,25 +231,36 @@ func (c *component) storeUser(ctx context.Context, accessToken string, targetRea
 		kcUser.Username = existingKcUser.Username
 		kcUser.Groups = &realmConf.endUserGroupIDs
 
+		// The previous user is restored in Keycloak if its details can't be stored
+		var previousDetails dto.DBUser
+		previousDetails, err = c.usersDBModule.GetUserDetails(ctx, targetRealmName, userID)
+		if err != nil {
+			c.logger.Warn(ctx, "msg", "Can't get user details from database", "err", err.Error())
+			return "", "", err
+		}
+		var targetKcUser, targetDetails = kcUser, userDetails(userID, user)
+		saga, err = c.sagaModule.Begin(ctx, keycloakb.SagaOperation{Kind: keycloakb.SagaUpdateUser, Realm: targetRealmName, UserID: userID,
+			PreviousUser: existingKcUser, PreviousDetails: &previousDetails, TargetUser: &targetKcUser, TargetDetails: &targetDetails})
+		if err != nil {
+			return "", "", err
+		}
+
 		err = c.keycloakClient.UpdateUser(accessToken, targetRealmName, userID, kcUser)
 		if err != nil {
 			c.logger.Warn(ctx, "msg", "Failed to update user through Keycloak API", "err", err.Error())
+			_ = c.sagaModule.Complete(ctx, saga)
 			return "", "", err
 		}
 	}
 
 	// Store user in database
-	err = c.usersDBModule.StoreOrUpdateUserDetails(ctx, targetRealmName, dto.DBUser{
-		UserID:               &userID,
-		BirthLocation:        user.BirthLocation,
-		IDDocumentType:       user.IDDocumentType,
-		IDDocumentNumber:     user.IDDocumentNumber,
-		IDDocumentExpiration: user.IDDocumentExpiration,
-	})
+	err = c.usersDBModule.StoreOrUpdateUserDetails(ctx, targetRealmName, userDetails(userID, user))
 	if err != nil {
 		c.logger.Warn(ctx, "msg", "Can't store user details in database", "err", err.Error())
+		_ = c.sagaModule.Compensate(ctx, accessToken, saga)
 		return "", "", err
 	}
+	_ = c.sagaModule.Complete(ctx, saga)
 
 	// Send execute actions email
 	if err = c.sendExecuteActionsEmail(ctx, accessToken, targetRealmName, authToken, &kcUser, customerRealmName, userID, realmConf); err != nil {
@@ -244,9 +270,12 @@ func (c *component) storeUser(ctx context.Context, accessToken string, targetRea
 	return userID, *kcUser.Username, nil
 }
 
-func (c *component) createKeycloakUser(ctx context.Context, accessToken, targetRealmName string, kcUser *kc.UserRepresentation) (string, error) {
+// createKeycloakUser creates the user with a generated username. Each creation is recorded as a pending operation
+// before Keycloak is called, so that a user created just before the bridge stops is removed by the reconciler
+func (c *component) createKeycloakUser(ctx context.Context, accessToken, targetRealmName string, kcUser *kc.UserRepresentation) (string, keycloakb.SagaOperation, error) {
 	var chars = []rune("0123456789")
 	var userID string
+	var saga keycloakb.SagaOperation
 	var err error
 
 	for i := 0; i < 10; i++ {
@@ -255,6 +284,11 @@ func (c *component) createKeycloakUser(ctx context.Context, accessToken, targetR
 		kcUser.Username = &username
 		kcUser.Groups = &groups
 
+		saga, err = c.sagaModule.Begin(ctx, keycloakb.SagaOperation{Kind: keycloakb.SagaCreateUser, Realm: targetRealmName, Username: username})
+		if err != nil {
+			return "", saga, err
+		}
+
 		userID, err = c.keycloakClient.CreateUser(accessToken, targetRealmName, targetRealmName, *kcUser)
 
 		// Create success: just have to get the userID and exit this loop
@@ -264,6 +298,7 @@ func (c *component) createKeycloakUser(ctx context.Context, accessToken, targetR
 			break
 		}
 		userID = ""
+		_ = c.sagaModule.Complete(ctx, saga)
 		switch e := err.(type) {
 		case errorhandler.Error:
 			if e.Status == http.StatusConflict && e.Message == "keycloak.existing.username" {
@@ -272,13 +307,19 @@ func (c *component) createKeycloakUser(ctx context.Context, accessToken, targetR
 			}
 		}
 		c.logger.Warn(ctx, "msg", "Failed to create user through Keycloak API", "err", err.Error())
-		return "", err
+		return "", saga, err
 	}
 	if userID == "" {
 		c.logger.Warn(ctx, "msg", "Can't generate unused username after multiple attempts")
-		return "", errorhandler.CreateInternalServerError("username.generation")
+		return "", saga, errorhandler.CreateInternalServerError("username.generation")
+	}
+
+	saga, err = c.sagaModule.SetUserID(ctx, saga, userID)
+	if err != nil {
+		_ = c.sagaModule.Compensate(ctx, accessToken, saga)
+		return "", saga, err
 	}
-	return userID, nil
+	return userID, saga, nil
 }
 
 func (c *component) sendExecuteActionsEmail(ctx context.Context, accessToken string, targetRealmName string, authToken TrustIDAuthToken, kcUser *kc.UserRepresentation,
@@ -11,6 +11,8 @@ import (
 	errorhandler "github.com/cloudtrust/common-service/errors"
 	log "github.com/cloudtrust/common-service/log"
 	apiregister "github.com/cloudtrust/keycloak-bridge/api/register"
+	"github.com/cloudtrust/keycloak-bridge/internal/dto"
+	"github.com/cloudtrust/keycloak-bridge/internal/keycloakb"
 	"github.com/cloudtrust/keycloak-bridge/pkg/register/mock"
 	kc "github.com/cloudtrust/keycloak-client"
 	"github.com/golang/mock/gomock"
@@ -74,7 +76,7 @@ func TestGroupIDsResolution(t *testing.T) {
 
 	t.Run("ProvideToken fails", func(t *testing.T) {
 		mockTokenProvider.EXPECT().ProvideToken(gomock.Any()).Return(accessToken, anError)
-		var cb = NewComponentBuilder(anyString, nil, mockTokenProvider, nil, nil, nil, nil)
+		var cb = NewComponentBuilder(anyString, nil, mockTokenProvider, nil, nil, nil, nil, nil)
 		var err = cb.AddTargetRealm(targetRealmConf)
 		assert.Equal(t, anError, err)
 	})
@@ -82,20 +84,20 @@ func TestGroupIDsResolution(t *testing.T) {
 
 	t.Run("GetGroups fails", func(t *testing.T) {
 		mockKeycloakClient.EXPECT().GetGroups(accessToken, targetRealm).Return(nil, anError)
-		var cb = NewComponentBuilder(anyString, mockKeycloakClient, mockTokenProvider, nil, nil, nil, nil)
+		var cb = NewComponentBuilder(anyString, mockKeycloakClient, mockTokenProvider, nil, nil, nil, nil, nil)
 		var err = cb.AddTargetRealm(targetRealmConf)
 		assert.Equal(t, anError, err)
 	})
 	t.Run("Unknown groups", func(t *testing.T) {
 		mockKeycloakClient.EXPECT().GetGroups(accessToken, targetRealm).Return([]kc.GroupRepresentation{}, nil)
-		var cb = NewComponentBuilder(anyString, mockKeycloakClient, mockTokenProvider, nil, nil, nil, nil)
+		var cb = NewComponentBuilder(anyString, mockKeycloakClient, mockTokenProvider, nil, nil, nil, nil, nil)
 		var err = cb.AddTargetRealm(targetRealmConf)
 		assert.NotNil(t, err)
 	})
 	mockKeycloakClient.EXPECT().GetGroups(accessToken, targetRealm).Return(groups, nil).AnyTimes()
 
 	t.Run("Success", func(t *testing.T) {
-		var cb = NewComponentBuilder(anyString, mockKeycloakClient, mockTokenProvider, nil, nil, nil, nil)
+		var cb = NewComponentBuilder(anyString, mockKeycloakClient, mockTokenProvider, nil, nil, nil, nil, nil)
 		var err = cb.AddTargetRealm(targetRealmConf)
 		assert.Nil(t, err)
 		var res = cb.Build()
@@ -104,7 +106,7 @@ func TestGroupIDsResolution(t *testing.T) {
 	})
 }
 
-func createComponent(keycloakURL, targetRealm, ssePublicURL, enduserClientID string, enduserGroups []string, keycloakClient *mock.KeycloakClient, tokenProvider *mock.OidcTokenProvider, usersDB *mock.UsersDetailsDBModule, configDB *mock.ConfigurationDBModule, eventsDB *mock.EventsDBModule) Component {
+func createComponent(keycloakURL, targetRealm, ssePublicURL, enduserClientID string, enduserGroups []string, keycloakClient *mock.KeycloakClient, tokenProvider *mock.OidcTokenProvider, usersDB *mock.UsersDetailsDBModule, configDB *mock.ConfigurationDBModule, eventsDB *mock.EventsDBModule, sagaModule *mock.SagaModule) Component {
 	var accessToken = "the-access-token"
 	var group1ID = "end_user-group-id"
 	var group1Name = "end_user"
@@ -113,7 +115,7 @@ func createComponent(keycloakURL, targetRealm, ssePublicURL, enduserClientID str
 	tokenProvider.EXPECT().ProvideToken(gomock.Any()).Return(accessToken, nil)
 	keycloakClient.EXPECT().GetGroups(accessToken, targetRealm).Return(groups, nil)
 
-	var cb = NewComponentBuilder(keycloakURL, keycloakClient, tokenProvider, usersDB, configDB, eventsDB, log.NewNopLogger())
+	var cb = NewComponentBuilder(keycloakURL, keycloakClient, tokenProvider, usersDB, configDB, eventsDB, sagaModule, log.NewNopLogger())
 	_ = cb.AddTargetRealm(RealmRegisterConfiguration{
 		Realm:           targetRealm,
 		EndUserGroups:   enduserGroups,
@@ -132,6 +134,7 @@ func TestRegisterUser(t *testing.T) {
 	var mockConfigDB = mock.NewConfigurationDBModule(mockCtrl)
 	var mockUsersDB = mock.NewUsersDetailsDBModule(mockCtrl)
 	var mockEventsDB = mock.NewEventsDBModule(mockCtrl)
+	var mockSaga = mock.NewSagaModule(mockCtrl)
 
 	var ctx = context.TODO()
 	var targetRealm = "cloudtrust"
@@ -144,7 +147,8 @@ func TestRegisterUser(t *testing.T) {
 	var accessToken = "abcdef"
 	var empty = 0
 	var usersSearchResult = kc.UsersPageRepresentation{Count: &empty}
-	var component = createComponent(keycloakURL, targetRealm, ssePublicURL, enduserClientID, enduserGroups, mockKeycloakClient, mockTokenProvider, mockUsersDB, mockConfigDB, mockEventsDB)
+	var saga = keycloakb.SagaOperation{ID: "saga-id"}
+	var component = createComponent(keycloakURL, targetRealm, ssePublicURL, enduserClientID, enduserGroups, mockKeycloakClient, mockTokenProvider, mockUsersDB, mockConfigDB, mockEventsDB, mockSaga)
 
 	t.Run("Can't get realm configuration from DB", func(t *testing.T) {
 		var dbError = errors.New("db error")
@@ -177,9 +181,11 @@ func TestRegisterUser(t *testing.T) {
 		mockConfigDB.EXPECT().GetConfiguration(ctx, confRealm).Return(configuration.RealmConfiguration{}, nil)
 		mockTokenProvider.EXPECT().ProvideToken(ctx).Return(accessToken, nil)
 		mockKeycloakClient.EXPECT().GetUsers(accessToken, targetRealm, targetRealm, "email", *validUser.Email).Return(usersSearchResult, nil)
+		mockSaga.EXPECT().Begin(ctx, gomock.Any()).Return(saga, nil).Times(10)
 		mockKeycloakClient.EXPECT().CreateUser(accessToken, targetRealm, targetRealm, gomock.Any()).
 			Return("", errorhandler.Error{Status: http.StatusConflict, Message: "keycloak.existing.username"}).
 			Times(10)
+		mockSaga.EXPECT().Complete(ctx, saga).Return(nil).Times(10)
 
 		var _, err = component.RegisterUser(ctx, targetRealm, confRealm, validUser)
 		assert.NotNil(t, err)
@@ -190,7 +196,9 @@ func TestRegisterUser(t *testing.T) {
 		mockConfigDB.EXPECT().GetConfiguration(ctx, confRealm).Return(configuration.RealmConfiguration{}, nil)
 		mockTokenProvider.EXPECT().ProvideToken(ctx).Return(accessToken, nil)
 		mockKeycloakClient.EXPECT().GetUsers(accessToken, targetRealm, targetRealm, "email", *validUser.Email).Return(usersSearchResult, nil)
+		mockSaga.EXPECT().Begin(ctx, gomock.Any()).Return(saga, nil)
 		mockKeycloakClient.EXPECT().CreateUser(accessToken, targetRealm, targetRealm, gomock.Any()).Return("", keycloakError)
+		mockSaga.EXPECT().Complete(ctx, saga).Return(nil)
 
 		var _, err = component.RegisterUser(ctx, targetRealm, confRealm, validUser)
 		assert.Equal(t, keycloakError, err)
@@ -209,7 +217,14 @@ func TestRegisterUser(t *testing.T) {
 		mockConfigDB.EXPECT().GetConfiguration(ctx, confRealm).Return(configuration.RealmConfiguration{}, nil)
 		mockTokenProvider.EXPECT().ProvideToken(ctx).Return(accessToken, nil)
 		mockKeycloakClient.EXPECT().GetUsers(accessToken, targetRealm, targetRealm, "email", *validUser.Email).Return(userExistsSearch, nil)
+		mockUsersDB.EXPECT().GetUserDetails(ctx, targetRealm, userID).Return(dto.DBUser{UserID: &userID}, nil)
+		mockSaga.EXPECT().Begin(ctx, gomock.Any()).DoAndReturn(func(_ context.Context, op keycloakb.SagaOperation) (keycloakb.SagaOperation, error) {
+			assert.Equal(t, keycloakb.SagaUpdateUser, op.Kind)
+			assert.Equal(t, userID, *op.PreviousUser.ID)
+			return saga, nil
+		})
 		mockKeycloakClient.EXPECT().UpdateUser(accessToken, targetRealm, userID, gomock.Any()).Return(updateError)
+		mockSaga.EXPECT().Complete(ctx, saga).Return(nil)
 
 		var _, err = component.RegisterUser(ctx, targetRealm, confRealm, createValidUser())
 		assert.Equal(t, updateError, err)
@@ -222,21 +237,60 @@ func TestRegisterUser(t *testing.T) {
 		mockConfigDB.EXPECT().GetConfiguration(ctx, confRealm).Return(configuration.RealmConfiguration{}, nil)
 		mockTokenProvider.EXPECT().ProvideToken(ctx).Return(token, nil)
 		mockKeycloakClient.EXPECT().GetUsers(accessToken, targetRealm, targetRealm, "email", *validUser.Email).Return(usersSearchResult, nil)
+		mockSaga.EXPECT().Begin(ctx, gomock.Any()).DoAndReturn(func(_ context.Context, op keycloakb.SagaOperation) (keycloakb.SagaOperation, error) {
+			assert.Equal(t, keycloakb.SagaCreateUser, op.Kind)
+			assert.Equal(t, targetRealm, op.Realm)
+			assert.Len(t, op.Username, 8)
+			return saga, nil
+		})
 		mockKeycloakClient.EXPECT().CreateUser(token, targetRealm, targetRealm, gomock.Any()).Return(userID, nil)
+		mockSaga.EXPECT().SetUserID(ctx, saga, userID).Return(saga, nil)
 		mockUsersDB.EXPECT().StoreOrUpdateUserDetails(ctx, targetRealm, gomock.Any()).Return(insertError)
+		mockSaga.EXPECT().Compensate(ctx, token, saga).Return(nil)
 
 		var _, err = component.RegisterUser(ctx, targetRealm, confRealm, createValidUser())
 		assert.Equal(t, insertError, err)
 	})
 
+	t.Run("Can't record pending operation: user is not created", func(t *testing.T) {
+		var sagaError = errors.New("saga error")
+		var token = "abcdef"
+		mockConfigDB.EXPECT().GetConfiguration(ctx, confRealm).Return(configuration.RealmConfiguration{}, nil)
+		mockTokenProvider.EXPECT().ProvideToken(ctx).Return(token, nil)
+		mockKeycloakClient.EXPECT().GetUsers(accessToken, targetRealm, targetRealm, "email", *validUser.Email).Return(usersSearchResult, nil)
+		mockSaga.EXPECT().Begin(ctx, gomock.Any()).Return(keycloakb.SagaOperation{}, sagaError)
+
+		var _, err = component.RegisterUser(ctx, targetRealm, confRealm, createValidUser())
+		assert.Equal(t, sagaError, err)
+	})
+
+	t.Run("Can't record the ID of the created user", func(t *testing.T) {
+		var sagaError = errors.New("saga error")
+		var token = "abcdef"
+		var userID = "abc789def"
+		mockConfigDB.EXPECT().GetConfiguration(ctx, confRealm).Return(configuration.RealmConfiguration{}, nil)
+		mockTokenProvider.EXPECT().ProvideToken(ctx).Return(token, nil)
+		mockKeycloakClient.EXPECT().GetUsers(accessToken, targetRealm, targetRealm, "email", *validUser.Email).Return(usersSearchResult, nil)
+		mockSaga.EXPECT().Begin(ctx, gomock.Any()).Return(saga, nil)
+		mockKeycloakClient.EXPECT().CreateUser(token, targetRealm, targetRealm, gomock.Any()).Return(userID, nil)
+		mockSaga.EXPECT().SetUserID(ctx, saga, userID).Return(saga, sagaError)
+		mockSaga.EXPECT().Compensate(ctx, token, saga).Return(nil)
+
+		var _, err = component.RegisterUser(ctx, targetRealm, confRealm, createValidUser())
+		assert.Equal(t, sagaError, err)
+	})
+
 	t.Run("No required actions. RegisterUser is successful", func(t *testing.T) {
 		var token = "abcdef"
 		var userID = "abc789def"
 		mockConfigDB.EXPECT().GetConfiguration(ctx, confRealm).Return(configuration.RealmConfiguration{}, nil)
 		mockTokenProvider.EXPECT().ProvideToken(ctx).Return(token, nil)
 		mockKeycloakClient.EXPECT().GetUsers(accessToken, targetRealm, targetRealm, "email", *validUser.Email).Return(usersSearchResult, nil)
+		mockSaga.EXPECT().Begin(ctx, gomock.Any()).Return(saga, nil)
 		mockKeycloakClient.EXPECT().CreateUser(token, targetRealm, targetRealm, gomock.Any()).Return(userID, nil)
+		mockSaga.EXPECT().SetUserID(ctx, saga, userID).Return(saga, nil)
 		mockUsersDB.EXPECT().StoreOrUpdateUserDetails(ctx, targetRealm, gomock.Any()).Return(nil)
+		mockSaga.EXPECT().Complete(ctx, saga).Return(nil)
 		mockEventsDB.EXPECT().ReportEvent(gomock.Any(), "REGISTER_USER", "back-office", gomock.Any()).Return(nil)
 
 		var _, err = component.RegisterUser(ctx, targetRealm, confRealm, createValidUser())
@@ -249,8 +303,11 @@ func TestRegisterUser(t *testing.T) {
 		mockConfigDB.EXPECT().GetConfiguration(ctx, confRealm).Return(configuration.RealmConfiguration{}, nil)
 		mockTokenProvider.EXPECT().ProvideToken(ctx).Return(token, nil)
 		mockKeycloakClient.EXPECT().GetUsers(accessToken, targetRealm, targetRealm, "email", *validUser.Email).Return(usersSearchResult, nil)
+		mockSaga.EXPECT().Begin(ctx, gomock.Any()).Return(saga, nil)
 		mockKeycloakClient.EXPECT().CreateUser(token, targetRealm, targetRealm, gomock.Any()).Return(userID, nil)
+		mockSaga.EXPECT().SetUserID(ctx, saga, userID).Return(saga, nil)
 		mockUsersDB.EXPECT().StoreOrUpdateUserDetails(ctx, targetRealm, gomock.Any()).Return(nil)
+		mockSaga.EXPECT().Complete(ctx, saga).Return(nil)
 		mockEventsDB.EXPECT().ReportEvent(gomock.Any(), "REGISTER_USER", "back-office", gomock.Any()).Return(errors.New("report event error"))
 
 		var _, err = component.RegisterUser(ctx, targetRealm, confRealm, createValidUser())
@@ -264,13 +321,16 @@ func TestRegisterUser(t *testing.T) {
 		var successURL = "http://couldtrust.ch"
 		var enduserGroups = []string{"end_user"}
 		var realmConfiguration = configuration.RealmConfiguration{RegisterExecuteActions: &requiredActions, RedirectSuccessfulRegistrationURL: &successURL}
-		var component = createComponent("not\nvalid\nURL", targetRealm, "", "", enduserGroups, mockKeycloakClient, mockTokenProvider, mockUsersDB, mockConfigDB, mockEventsDB)
+		var component = createComponent("not\nvalid\nURL", targetRealm, "", "", enduserGroups, mockKeycloakClient, mockTokenProvider, mockUsersDB, mockConfigDB, mockEventsDB, mockSaga)
 
 		mockConfigDB.EXPECT().GetConfiguration(ctx, confRealm).Return(realmConfiguration, nil)
 		mockTokenProvider.EXPECT().ProvideToken(ctx).Return(token, nil)
 		mockKeycloakClient.EXPECT().GetUsers(accessToken, targetRealm, targetRealm, "email", *validUser.Email).Return(usersSearchResult, nil)
+		mockSaga.EXPECT().Begin(ctx, gomock.Any()).Return(saga, nil)
 		mockKeycloakClient.EXPECT().CreateUser(token, targetRealm, targetRealm, gomock.Any()).Return(userID, nil)
+		mockSaga.EXPECT().SetUserID(ctx, saga, userID).Return(saga, nil)
 		mockUsersDB.EXPECT().StoreOrUpdateUserDetails(ctx, targetRealm, gomock.Any()).Return(nil)
+		mockSaga.EXPECT().Complete(ctx, saga).Return(nil)
 
 		var _, err = component.RegisterUser(ctx, targetRealm, confRealm, createValidUser())
 		assert.NotNil(t, err)
@@ -286,8 +346,11 @@ func TestRegisterUser(t *testing.T) {
 		mockConfigDB.EXPECT().GetConfiguration(ctx, confRealm).Return(realmConfiguration, nil)
 		mockTokenProvider.EXPECT().ProvideToken(ctx).Return(token, nil)
 		mockKeycloakClient.EXPECT().GetUsers(accessToken, targetRealm, targetRealm, "email", *validUser.Email).Return(usersSearchResult, nil)
+		mockSaga.EXPECT().Begin(ctx, gomock.Any()).Return(saga, nil)
 		mockKeycloakClient.EXPECT().CreateUser(token, targetRealm, targetRealm, gomock.Any()).Return(userID, nil)
+		mockSaga.EXPECT().SetUserID(ctx, saga, userID).Return(saga, nil)
 		mockUsersDB.EXPECT().StoreOrUpdateUserDetails(ctx, targetRealm, gomock.Any()).Return(nil)
+		mockSaga.EXPECT().Complete(ctx, saga).Return(nil)
 		mockKeycloakClient.EXPECT().ExecuteActionsEmail(token, targetRealm, userID, requiredActions, "client_id", gomock.Any(), "redirect_uri", gomock.Any()).DoAndReturn(
 			func(_ string, _ string, _ string, _ []string, _ string, _ string, _ string, fullURL string) error {
 				expectedSubStringURL := "%2F" + targetRealm + "%2Fconfirmation%2F" + confRealm
@@ -325,7 +388,7 @@ func TestCheckExistingUser(t *testing.T) {
 	var one = 1
 	var foundUsers = kc.UsersPageRepresentation{Count: &one, Users: []kc.UserRepresentation{keycloakUser}}
 
-	var component = &component{keycloakURL, map[string]RealmRegisterConfiguration{}, mockKeycloakClient, mockTokenProvider, mockUsersDB, mockConfigDB, mockEventsDB, log.NewNopLogger()}
+	var component = &component{keycloakURL, map[string]RealmRegisterConfiguration{}, mockKeycloakClient, mockTokenProvider, mockUsersDB, mockConfigDB, mockEventsDB, nil, log.NewNopLogger()}
 
 	mockTokenProvider.EXPECT().ProvideToken(gomock.Any()).Return(accessToken, nil).AnyTimes()
 	mockKeycloakClient.EXPECT().GetGroups(gomock.Any(), targetRealm).Return([]kc.GroupRepresentation{}, nil)
@@ -390,7 +453,7 @@ func TestGetConfiguration(t *testing.T) {
 	var enduserGroups = []string{"end_user"}
 	var targetRealm = "cloudtrust"
 	var confRealm = "test"
-	var component = createComponent(keycloakURL, targetRealm, ssePublicURL, enduserClientID, enduserGroups, mockKeycloakClient, mockTokenProvider, mockUsersDB, mockConfigDB, mockEventsDB)
+	var component = createComponent(keycloakURL, targetRealm, ssePublicURL, enduserClientID, enduserGroups, mockKeycloakClient, mockTokenProvider, mockUsersDB, mockConfigDB, mockEventsDB, nil)
 
 	t.Run("Retrieve configuration successfully", func(t *testing.T) {
 		// Retrieve configuration successfully
@@ -0,0 +1,307 @@
+package statistics
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	cs "github.com/cloudtrust/common-service"
+	api "github.com/cloudtrust/keycloak-bridge/api/statistics"
+	"github.com/cloudtrust/keycloak-bridge/internal/constants"
+	"github.com/cloudtrust/keycloak-bridge/internal/keycloakb"
+	kc "github.com/cloudtrust/keycloak-client"
+	"github.com/pkg/errors"
+)
+
+// The accreditations of a realm are indexed in memory. The index is fully rebuilt from Keycloak when older than
+// accreditationsIndexMaxAge. Otherwise, only the users concerned by audit events which change accreditations since the
+// last refresh are reloaded, unless there are so many of them that a rebuild is cheaper.
+const (
+	accreditationsIndexMaxAge = 24 * time.Hour
+	// Events may be stored in the audit database a bit after they occurred
+	accreditationsIndexOverlap = time.Minute
+	// Maximum number of users reloaded one by one by a refresh
+	accreditationsIndexMaxReloadedUsers = 100
+)
+
+// Types of the audit events which may change the accreditations of a user: validations, updates of the identity which
+// revoke the accreditations and deletions
+var accreditationsEventTypes = []string{
+	keycloakb.CtEventTypeValidateUser,
+	keycloakb.CtEventTypeValidationStoreCheck,
+	keycloakb.CtEventTypeAccreditationsRevoked,
+	"VALIDATION_UPDATE_USER",
+	"UPDATE_ACCOUNT",
+	"API_ACCOUNT_DELETION",
+	"SELF_DELETE_ACCOUNT",
+}
+
+type accreditationsIndex struct {
+	mutex  sync.Mutex
+	realms map[string]*realmAccreditations
+}
+
+type realmAccreditations struct {
+	users       map[string]userAccreditations
+	builtAt     time.Time
+	refreshedAt time.Time
+}
+
+type userAccreditations struct {
+	username       string
+	accreditations []accreditation
+}
+
+type accreditation struct {
+	accredType string
+	expiryDate string
+	expiry     time.Time
+	revoked    bool
+}
+
+func newAccreditationsIndex() *accreditationsIndex {
+	return &accreditationsIndex{
+		realms: make(map[string]*realmAccreditations),
+	}
+}
+
+// GetStatisticsAccreditations counts the active, revoked and expired accreditations of a realm per type
+func (ec *component) GetStatisticsAccreditations(ctx context.Context, realmName string) (map[string]api.StatisticsAccreditationsRepresentation, error) {
+	var now = time.Now()
+	var res = make(map[string]api.StatisticsAccreditationsRepresentation)
+
+	var err = ec.visitAccreditations(ctx, realmName, func(_ string, _ userAccreditations, accred accreditation) {
+		var count = res[accred.accredType]
+		switch {
+		case accred.revoked:
+			count.Revoked++
+		case accred.isExpired(now):
+			count.Expired++
+		default:
+			count.Active++
+		}
+		res[accred.accredType] = count
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// GetExpiringAccreditations gives a page of the active accreditations of a realm which expire within the given number of days,
+// sorted by expiry date
+func (ec *component) GetExpiringAccreditations(ctx context.Context, realmName string, days int, accredType *string, first int, max int) (api.ExpiringAccreditationsPageRepresentation, error) {
+	var now = time.Now()
+	var limit = now.Add(time.Duration(days) * 24 * time.Hour)
+
+	type expiringAccreditation struct {
+		rep    api.ExpiringAccreditationRepresentation
+		expiry time.Time
+	}
+	var matches = []expiringAccreditation{}
+
+	var err = ec.visitAccreditations(ctx, realmName, func(userID string, user userAccreditations, accred accreditation) {
+		if accred.revoked || accred.isExpired(now) || !accred.expiry.Before(limit) {
+			return
+		}
+		if accredType != nil && *accredType != accred.accredType {
+			return
+		}
+		matches = append(matches, expiringAccreditation{
+			rep: api.ExpiringAccreditationRepresentation{
+				UserID:     userID,
+				Username:   user.username,
+				Type:       accred.accredType,
+				ExpiryDate: accred.expiryDate,
+			},
+			expiry: accred.expiry,
+		})
+	})
+	if err != nil {
+		return api.ExpiringAccreditationsPageRepresentation{}, err
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		if !matches[i].expiry.Equal(matches[j].expiry) {
+			return matches[i].expiry.Before(matches[j].expiry)
+		}
+		return matches[i].rep.Username < matches[j].rep.Username
+	})
+
+	var res = api.ExpiringAccreditationsPageRepresentation{
+		Accreditations: []api.ExpiringAccreditationRepresentation{},
+		Count:          len(matches),
+	}
+	if start, end := getPageBounds(len(matches), first, max); start < end {
+		for _, match := range matches[start:end] {
+			res.Accreditations = append(res.Accreditations, match.rep)
+		}
+	}
+
+	return res, nil
+}
+
+// visitAccreditations refreshes the accreditations index of the given realm then calls visitor for each indexed accreditation.
+// Keycloak is called without holding the lock of the index: the lock only protects the reading and the update of the index
+func (ec *component) visitAccreditations(ctx context.Context, realmName string, visitor func(string, userAccreditations, accreditation)) error {
+	var index = ec.accreditations
+
+	index.mutex.Lock()
+	var realm = index.realms[realmName]
+	var refreshedAt time.Time
+	if realm != nil && time.Since(realm.builtAt) <= accreditationsIndexMaxAge {
+		refreshedAt = realm.refreshedAt
+	}
+	index.mutex.Unlock()
+
+	var update, err = ec.loadAccreditations(ctx, realmName, refreshedAt)
+	if err != nil {
+		return err
+	}
+
+	index.mutex.Lock()
+	defer index.mutex.Unlock()
+
+	realm = update.applyTo(index.realms[realmName])
+	index.realms[realmName] = realm
+
+	for userID, user := range realm.users {
+		for _, accred := range user.accreditations {
+			visitor(userID, user, accred)
+		}
+	}
+	return nil
+}
+
+// accreditationsUpdate is either a rebuilt index or the users reloaded since the last refresh. A nil user has been deleted
+type accreditationsUpdate struct {
+	rebuilt  *realmAccreditations
+	users    map[string]*kc.UserRepresentation
+	loadedAt time.Time
+}
+
+// loadAccreditations loads the whole realm when refreshedAt is zero, otherwise the users concerned by events since refreshedAt
+func (ec *component) loadAccreditations(ctx context.Context, realmName string, refreshedAt time.Time) (accreditationsUpdate, error) {
+	var accessToken = ctx.Value(cs.CtContextAccessToken).(string)
+	var ctxRealm = ctx.Value(cs.CtContextRealm).(string)
+	var res = accreditationsUpdate{loadedAt: time.Now()}
+
+	var userIDs []string
+	if !refreshedAt.IsZero() {
+		var err error
+		userIDs, err = ec.db.GetUsersWithEventsSince(ctx, realmName, refreshedAt.Add(-accreditationsIndexOverlap), accreditationsEventTypes...)
+		if err != nil {
+			ec.logger.Warn(ctx, "msg", "Can't refresh accreditations index", "err", err.Error(), "realm", realmName)
+			return res, err
+		}
+	}
+
+	if refreshedAt.IsZero() || len(userIDs) > accreditationsIndexMaxReloadedUsers {
+		usersKc, err := ec.keycloakClient.GetUsers(accessToken, ctxRealm, realmName, PrmQryMax, "0")
+		if err != nil {
+			ec.logger.Warn(ctx, "msg", "Can't build accreditations index", "err", err.Error(), "realm", realmName)
+			return res, err
+		}
+		res.rebuilt = &realmAccreditations{
+			users:       make(map[string]userAccreditations),
+			builtAt:     res.loadedAt,
+			refreshedAt: res.loadedAt,
+		}
+		for _, user := range usersKc.Users {
+			res.rebuilt.setUser(user)
+		}
+		return res, nil
+	}
+
+	res.users = make(map[string]*kc.UserRepresentation)
+	for _, userID := range userIDs {
+		user, err := ec.keycloakClient.GetUser(accessToken, realmName, userID)
+		if err != nil {
+			if e, ok := errors.Cause(err).(kc.HTTPError); ok && e.HTTPStatus == http.StatusNotFound {
+				res.users[userID] = nil
+				continue
+			}
+			ec.logger.Warn(ctx, "msg", "Can't refresh accreditations index", "err", err.Error(), "realm", realmName, "user", userID)
+			return res, err
+		}
+		res.users[userID] = &user
+	}
+	return res, nil
+}
+
+// applyTo returns the index of the realm once updated. The index may have been rebuilt by a concurrent call: the reloaded
+// users are more recent than its content and are applied to it as well
+func (u accreditationsUpdate) applyTo(realm *realmAccreditations) *realmAccreditations {
+	if u.rebuilt != nil {
+		if realm == nil || realm.builtAt.Before(u.rebuilt.builtAt) {
+			return u.rebuilt
+		}
+		return realm
+	}
+	if realm == nil {
+		// The index has been dropped concurrently: it will be rebuilt by the next call
+		return &realmAccreditations{users: make(map[string]userAccreditations)}
+	}
+
+	for userID, user := range u.users {
+		if user == nil {
+			delete(realm.users, userID)
+		} else {
+			realm.setUser(*user)
+		}
+	}
+	if realm.refreshedAt.Before(u.loadedAt) {
+		realm.refreshedAt = u.loadedAt
+	}
+	return realm
+}
+
+// setUser updates the accreditations of a user. Users without accreditations are not kept in the index
+func (r *realmAccreditations) setUser(user kc.UserRepresentation) {
+	if user.ID == nil {
+		return
+	}
+
+	var accreds = parseAccreditations(user.GetAttribute(constants.AttrbAccreditations))
+	if len(accreds) == 0 {
+		delete(r.users, *user.ID)
+		return
+	}
+
+	var username string
+	if user.Username != nil {
+		username = *user.Username
+	}
+	r.users[*user.ID] = userAccreditations{username: username, accreditations: accreds}
+}
+
+// parseAccreditations ignores the accreditations which can't be parsed
+func parseAccreditations(values []string) []accreditation {
+	var res []accreditation
+	for _, value := range values {
+		var accred keycloakb.AccreditationRepresentation
+		if err := json.Unmarshal([]byte(value), &accred); err != nil || accred.Type == nil || accred.ExpiryDate == nil {
+			continue
+		}
+		var expiry, err = time.Parse(constants.SupportedDateLayouts[0], *accred.ExpiryDate)
+		if err != nil {
+			continue
+		}
+		res = append(res, accreditation{
+			accredType: *accred.Type,
+			expiryDate: *accred.ExpiryDate,
+			expiry:     expiry,
+			revoked:    accred.Revoked != nil && *accred.Revoked,
+		})
+	}
+	return res
+}
+
+// isExpired uses the same rule as keycloakb.IsDateInThePast: an accreditation expires at the beginning of its expiry date
+func (a accreditation) isExpired(now time.Time) bool {
+	return !now.Before(a.expiry)
+}
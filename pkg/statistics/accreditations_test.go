@@ -0,0 +1,142 @@
+package statistics
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	cs "github.com/cloudtrust/common-service"
+	"github.com/cloudtrust/common-service/log"
+	api "github.com/cloudtrust/keycloak-bridge/api/statistics"
+	"github.com/cloudtrust/keycloak-bridge/internal/constants"
+	"github.com/cloudtrust/keycloak-bridge/pkg/statistics/mock"
+	kc "github.com/cloudtrust/keycloak-client"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func createAccreditedUser(id string, accreds ...string) kc.UserRepresentation {
+	var username = "user-" + id
+	var user = kc.UserRepresentation{ID: &id, Username: &username}
+	if len(accreds) > 0 {
+		user.SetAttribute(constants.AttrbAccreditations, accreds)
+	}
+	return user
+}
+
+func accreditationJSON(accredType string, expiry time.Time, revoked bool) string {
+	var res = `{"type":"` + accredType + `","expiryDate":"` + expiry.Format(constants.SupportedDateLayouts[0]) + `"`
+	if revoked {
+		res += `,"revoked":true`
+	}
+	return res + "}"
+}
+
+func TestAccreditations(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	var mockDBModule = mock.NewEventsDBModule(mockCtrl)
+	var mockKcClient = mock.NewKcClient(mockCtrl)
+	var mockLogger = log.NewNopLogger()
+
+	var realm = "the_realm_name"
+	var accessToken = "TOKEN=="
+	var ctx = context.WithValue(context.Background(), cs.CtContextAccessToken, accessToken)
+	ctx = context.WithValue(ctx, cs.CtContextRealm, realm)
+
+	var now = time.Now()
+	var inAWeek = now.Add(7 * 24 * time.Hour)
+	var inAYear = now.Add(365 * 24 * time.Hour)
+	var lastYear = now.Add(-365 * 24 * time.Hour)
+	var users = kc.UsersPageRepresentation{Users: []kc.UserRepresentation{
+		createAccreditedUser("1", accreditationJSON("SHADOW", inAWeek, false), accreditationJSON("DEP", inAYear, false)),
+		createAccreditedUser("2", accreditationJSON("SHADOW", inAYear, true), accreditationJSON("DEP", lastYear, false)),
+		createAccreditedUser("3", "not a json"),
+		createAccreditedUser("4"),
+	}}
+
+	t.Run("Build fails", func(t *testing.T) {
+		var component = NewComponent(mockDBModule, mockDBModule, mockKcClient, nil, "", nil, nil, nil, mockLogger)
+		mockKcClient.EXPECT().GetUsers(accessToken, realm, realm, "max", "0").Return(kc.UsersPageRepresentation{}, errors.New("error"))
+		_, err := component.GetStatisticsAccreditations(ctx, realm)
+		assert.NotNil(t, err)
+	})
+	t.Run("Statistics then incremental refresh", func(t *testing.T) {
+		var component = NewComponent(mockDBModule, mockDBModule, mockKcClient, nil, "", nil, nil, nil, mockLogger)
+		mockKcClient.EXPECT().GetUsers(accessToken, realm, realm, "max", "0").Return(users, nil)
+		res, err := component.GetStatisticsAccreditations(ctx, realm)
+		assert.Nil(t, err)
+		assert.Equal(t, map[string]api.StatisticsAccreditationsRepresentation{
+			"SHADOW": {Active: 1, Revoked: 1},
+			"DEP":    {Active: 1, Expired: 1},
+		}, res)
+
+		// User 1 has been deleted, user 3 got an accreditation
+		mockDBModule.EXPECT().GetUsersWithEventsSince(ctx, realm, gomock.Any(), gomock.Any()).Return([]string{"1", "3"}, nil)
+		mockKcClient.EXPECT().GetUser(accessToken, realm, "1").Return(kc.UserRepresentation{}, kc.HTTPError{HTTPStatus: http.StatusNotFound})
+		mockKcClient.EXPECT().GetUser(accessToken, realm, "3").Return(createAccreditedUser("3", accreditationJSON("SHADOW", inAWeek, false)), nil)
+		res, err = component.GetStatisticsAccreditations(ctx, realm)
+		assert.Nil(t, err)
+		assert.Equal(t, map[string]api.StatisticsAccreditationsRepresentation{
+			"SHADOW": {Active: 1, Revoked: 1},
+			"DEP":    {Expired: 1},
+		}, res)
+
+		// Too many users changed: the index is rebuilt
+		var changedUserIDs []string
+		for i := 0; i <= accreditationsIndexMaxReloadedUsers; i++ {
+			changedUserIDs = append(changedUserIDs, strconv.Itoa(i))
+		}
+		mockDBModule.EXPECT().GetUsersWithEventsSince(ctx, realm, gomock.Any(), gomock.Any()).Return(changedUserIDs, nil)
+		mockKcClient.EXPECT().GetUsers(accessToken, realm, realm, "max", "0").Return(users, nil)
+		res, err = component.GetStatisticsAccreditations(ctx, realm)
+		assert.Nil(t, err)
+		assert.Equal(t, map[string]api.StatisticsAccreditationsRepresentation{
+			"SHADOW": {Active: 1, Revoked: 1},
+			"DEP":    {Active: 1, Expired: 1},
+		}, res)
+
+		// Refresh fails
+		mockDBModule.EXPECT().GetUsersWithEventsSince(ctx, realm, gomock.Any(), gomock.Any()).Return([]string{"2"}, nil)
+		mockKcClient.EXPECT().GetUser(accessToken, realm, "2").Return(kc.UserRepresentation{}, errors.New("error"))
+		_, err = component.GetStatisticsAccreditations(ctx, realm)
+		assert.NotNil(t, err)
+
+		mockDBModule.EXPECT().GetUsersWithEventsSince(ctx, realm, gomock.Any(), gomock.Any()).Return(nil, errors.New("error"))
+		_, err = component.GetStatisticsAccreditations(ctx, realm)
+		assert.NotNil(t, err)
+	})
+	t.Run("Expiring accreditations", func(t *testing.T) {
+		var component = NewComponent(mockDBModule, mockDBModule, mockKcClient, nil, "", nil, nil, nil, mockLogger)
+		mockKcClient.EXPECT().GetUsers(accessToken, realm, realm, "max", "0").Return(users, nil)
+		res, err := component.GetExpiringAccreditations(ctx, realm, 30, nil, 0, 10)
+		assert.Nil(t, err)
+		assert.Equal(t, 1, res.Count)
+		assert.Equal(t, "1", res.Accreditations[0].UserID)
+		assert.Equal(t, "SHADOW", res.Accreditations[0].Type)
+
+		var accredType = "DEP"
+		mockDBModule.EXPECT().GetUsersWithEventsSince(ctx, realm, gomock.Any(), gomock.Any()).Return([]string{}, nil)
+		res, err = component.GetExpiringAccreditations(ctx, realm, 400, &accredType, 0, 10)
+		assert.Nil(t, err)
+		assert.Equal(t, 1, res.Count)
+		assert.Equal(t, "DEP", res.Accreditations[0].Type)
+
+		mockDBModule.EXPECT().GetUsersWithEventsSince(ctx, realm, gomock.Any(), gomock.Any()).Return([]string{}, nil)
+		res, err = component.GetExpiringAccreditations(ctx, realm, 400, nil, 1, 10)
+		assert.Nil(t, err)
+		assert.Equal(t, 2, res.Count)
+		assert.Equal(t, []api.ExpiringAccreditationRepresentation{{UserID: "1", Username: "user-1", Type: "DEP", ExpiryDate: inAYear.Format(constants.SupportedDateLayouts[0])}}, res.Accreditations)
+	})
+}
+
+func TestParseAccreditations(t *testing.T) {
+	var res = parseAccreditations([]string{`{"type":"SHADOW","expiryDate":"31.12.2030"}`, `{"type":"SHADOW"}`, `{"type":"DEP","expiryDate":"2030-12-31"}`, `{`})
+	assert.Len(t, res, 1)
+	assert.Equal(t, "SHADOW", res[0].accredType)
+	assert.False(t, res[0].revoked)
+}
@@ -23,13 +23,26 @@ func newAction(as string, scope security.Scope) security.Action {
 
 // Actions used for authorization module
 var (
-	STGetActions                      = newAction("ST_GetActions", security.ScopeGlobal)
-	STGetStatistics                   = newAction("ST_GetStatistics", security.ScopeRealm)
-	STGetStatisticsUsers              = newAction("ST_GetStatisticsUsers", security.ScopeRealm)
-	STGetStatisticsAuthenticators     = newAction("ST_GetStatisticsAuthenticators", security.ScopeRealm)
-	STGetStatisticsAuthentications    = newAction("ST_GetStatisticsAuthentications", security.ScopeRealm)
-	STGetStatisticsAuthenticationsLog = newAction("ST_GetStatisticsAuthenticationsLog", security.ScopeRealm)
-	STGetMigrationReport              = newAction("ST_GetMigrationReport", security.ScopeRealm)
+	STGetActions                         = newAction("ST_GetActions", security.ScopeGlobal)
+	STGetStatistics                      = newAction("ST_GetStatistics", security.ScopeRealm)
+	STGetStatisticsUsers                 = newAction("ST_GetStatisticsUsers", security.ScopeRealm)
+	STGetStatisticsAuthenticators        = newAction("ST_GetStatisticsAuthenticators", security.ScopeRealm)
+	STGetStatisticsAuthentications       = newAction("ST_GetStatisticsAuthentications", security.ScopeRealm)
+	STGetStatisticsAuthenticationsLog    = newAction("ST_GetStatisticsAuthenticationsLog", security.ScopeRealm)
+	STGetMigrationReport                 = newAction("ST_GetMigrationReport", security.ScopeRealm)
+	STGetStatisticsFailures              = newAction("ST_GetStatisticsFailures", security.ScopeRealm)
+	STGetStatisticsFailedAuthentications = newAction("ST_GetStatisticsFailedAuthentications", security.ScopeRealm)
+	STGetStatisticsLockouts              = newAction("ST_GetStatisticsLockouts", security.ScopeRealm)
+	STGetStatisticsFailuresBreakdown     = newAction("ST_GetStatisticsFailuresBreakdown", security.ScopeRealm)
+	STGetStatisticsAuthenticationsRatio  = newAction("ST_GetStatisticsAuthenticationsRatio", security.ScopeRealm)
+	STGetStatisticsRegistrations         = newAction("ST_GetStatisticsRegistrations", security.ScopeRealm)
+	STGetMigrationReportSummary          = newAction("ST_GetMigrationReportSummary", security.ScopeRealm)
+	STGetMigrationReportUsers            = newAction("ST_GetMigrationReportUsers", security.ScopeRealm)
+	STGetInactiveUsers                   = newAction("ST_GetInactiveUsers", security.ScopeRealm)
+	STLockInactiveUsers                  = newAction("ST_LockInactiveUsers", security.ScopeRealm)
+	STGetStatisticsAccreditations        = newAction("ST_GetStatisticsAccreditations", security.ScopeRealm)
+	STGetExpiringAccreditations          = newAction("ST_GetExpiringAccreditations", security.ScopeRealm)
+	STGetStatisticsOverview              = newAction("ST_GetStatisticsOverview", security.ScopeRealm)
 )
 
 // Tracking middleware at component level.
@@ -128,3 +141,129 @@ func (c *authorizationComponentMW) GetMigrationReport(ctx context.Context, realm
 
 	return c.next.GetMigrationReport(ctx, realm)
 }
+
+func (c *authorizationComponentMW) GetStatisticsFailures(ctx context.Context, realm string) (api.StatisticsFailuresRepresentation, error) {
+	var action = STGetStatisticsFailures.String()
+
+	if err := c.authManager.CheckAuthorizationOnTargetRealm(ctx, action, realm); err != nil {
+		return api.StatisticsFailuresRepresentation{}, err
+	}
+
+	return c.next.GetStatisticsFailures(ctx, realm)
+}
+
+func (c *authorizationComponentMW) GetStatisticsFailedAuthentications(ctx context.Context, realm string, unit string, timeshift *string) ([][]int64, error) {
+	var action = STGetStatisticsFailedAuthentications.String()
+
+	if err := c.authManager.CheckAuthorizationOnTargetRealm(ctx, action, realm); err != nil {
+		return nil, err
+	}
+
+	return c.next.GetStatisticsFailedAuthentications(ctx, realm, unit, timeshift)
+}
+
+func (c *authorizationComponentMW) GetStatisticsLockouts(ctx context.Context, realm string, unit string, timeshift *string) ([][]int64, error) {
+	var action = STGetStatisticsLockouts.String()
+
+	if err := c.authManager.CheckAuthorizationOnTargetRealm(ctx, action, realm); err != nil {
+		return nil, err
+	}
+
+	return c.next.GetStatisticsLockouts(ctx, realm, unit, timeshift)
+}
+
+func (c *authorizationComponentMW) GetStatisticsFailuresBreakdown(ctx context.Context, realm string, unit string) (api.StatisticsFailuresBreakdownRepresentation, error) {
+	var action = STGetStatisticsFailuresBreakdown.String()
+
+	if err := c.authManager.CheckAuthorizationOnTargetRealm(ctx, action, realm); err != nil {
+		return api.StatisticsFailuresBreakdownRepresentation{}, err
+	}
+
+	return c.next.GetStatisticsFailuresBreakdown(ctx, realm, unit)
+}
+
+func (c *authorizationComponentMW) GetStatisticsAuthenticationsRatio(ctx context.Context, realm string, unit string, timeshift *string) ([]api.StatisticsRatioRepresentation, error) {
+	var action = STGetStatisticsAuthenticationsRatio.String()
+
+	if err := c.authManager.CheckAuthorizationOnTargetRealm(ctx, action, realm); err != nil {
+		return nil, err
+	}
+
+	return c.next.GetStatisticsAuthenticationsRatio(ctx, realm, unit, timeshift)
+}
+
+func (c *authorizationComponentMW) GetStatisticsRegistrations(ctx context.Context, realm string, unit string, timeshift *string) (api.StatisticsRegistrationsRepresentation, error) {
+	var action = STGetStatisticsRegistrations.String()
+
+	if err := c.authManager.CheckAuthorizationOnTargetRealm(ctx, action, realm); err != nil {
+		return api.StatisticsRegistrationsRepresentation{}, err
+	}
+
+	return c.next.GetStatisticsRegistrations(ctx, realm, unit, timeshift)
+}
+
+func (c *authorizationComponentMW) GetMigrationReportSummary(ctx context.Context, realm string, async bool, refresh bool) (api.MigrationReportSummaryRepresentation, error) {
+	var action = STGetMigrationReportSummary.String()
+
+	if err := c.authManager.CheckAuthorizationOnTargetRealm(ctx, action, realm); err != nil {
+		return api.MigrationReportSummaryRepresentation{}, err
+	}
+
+	return c.next.GetMigrationReportSummary(ctx, realm, async, refresh)
+}
+
+func (c *authorizationComponentMW) GetMigrationReportUsers(ctx context.Context, realm string, migrated *bool, first int, max int) (api.MigrationReportUsersPageRepresentation, error) {
+	var action = STGetMigrationReportUsers.String()
+
+	if err := c.authManager.CheckAuthorizationOnTargetRealm(ctx, action, realm); err != nil {
+		return api.MigrationReportUsersPageRepresentation{}, err
+	}
+
+	return c.next.GetMigrationReportUsers(ctx, realm, migrated, first, max)
+}
+
+func (c *authorizationComponentMW) GetInactiveUsers(ctx context.Context, realm string, days int, first int, max int) (api.InactiveUsersPageRepresentation, error) {
+	var action = STGetInactiveUsers.String()
+
+	if err := c.authManager.CheckAuthorizationOnTargetRealm(ctx, action, realm); err != nil {
+		return api.InactiveUsersPageRepresentation{}, err
+	}
+
+	return c.next.GetInactiveUsers(ctx, realm, days, first, max)
+}
+
+func (c *authorizationComponentMW) LockInactiveUsers(ctx context.Context, realm string, days int, userIDs []string) (api.InactiveUsersLockRepresentation, error) {
+	var action = STLockInactiveUsers.String()
+
+	if err := c.authManager.CheckAuthorizationOnTargetRealm(ctx, action, realm); err != nil {
+		return api.InactiveUsersLockRepresentation{}, err
+	}
+
+	return c.next.LockInactiveUsers(ctx, realm, days, userIDs)
+}
+
+func (c *authorizationComponentMW) GetStatisticsAccreditations(ctx context.Context, realm string) (map[string]api.StatisticsAccreditationsRepresentation, error) {
+	var action = STGetStatisticsAccreditations.String()
+
+	if err := c.authManager.CheckAuthorizationOnTargetRealm(ctx, action, realm); err != nil {
+		return nil, err
+	}
+
+	return c.next.GetStatisticsAccreditations(ctx, realm)
+}
+
+func (c *authorizationComponentMW) GetExpiringAccreditations(ctx context.Context, realm string, days int, accredType *string, first int, max int) (api.ExpiringAccreditationsPageRepresentation, error) {
+	var action = STGetExpiringAccreditations.String()
+
+	if err := c.authManager.CheckAuthorizationOnTargetRealm(ctx, action, realm); err != nil {
+		return api.ExpiringAccreditationsPageRepresentation{}, err
+	}
+
+	return c.next.GetExpiringAccreditations(ctx, realm, days, accredType, first, max)
+}
+
+func (c *authorizationComponentMW) GetStatisticsOverview(ctx context.Context, sortBy string, descending bool) (api.StatisticsOverviewRepresentation, error) {
+	// For this method, there is no target realm: the component only includes the realms
+	// on which the user is allowed to perform STGetStatisticsOverview
+	return c.next.GetStatisticsOverview(ctx, sortBy, descending)
+}
@@ -165,3 +165,130 @@ func TestGetStatisticsAuthenticatorsDeny(t *testing.T) {
 		assert.Equal(t, security.ForbiddenError{}, err)
 	})
 }
+
+func TestGetStatisticsFailuresAllowAndDeny(t *testing.T) {
+	var unit = "hours"
+	testAuthorization(t, WithAuthorization(), func(auth Component, mockComponent *mock.Component, ctx context.Context, mp map[string]string) {
+		mockComponent.EXPECT().GetStatisticsFailures(ctx, mp[PrmRealm]).Return(api.StatisticsFailuresRepresentation{}, nil).Times(1)
+		_, err := auth.GetStatisticsFailures(ctx, mp[PrmRealm])
+		assert.Nil(t, err)
+
+		mockComponent.EXPECT().GetStatisticsFailedAuthentications(ctx, mp[PrmRealm], unit, nil).Return([][]int64{}, nil).Times(1)
+		_, err = auth.GetStatisticsFailedAuthentications(ctx, mp[PrmRealm], unit, nil)
+		assert.Nil(t, err)
+
+		mockComponent.EXPECT().GetStatisticsLockouts(ctx, mp[PrmRealm], unit, nil).Return([][]int64{}, nil).Times(1)
+		_, err = auth.GetStatisticsLockouts(ctx, mp[PrmRealm], unit, nil)
+		assert.Nil(t, err)
+
+		mockComponent.EXPECT().GetStatisticsFailuresBreakdown(ctx, mp[PrmRealm], unit).Return(api.StatisticsFailuresBreakdownRepresentation{}, nil).Times(1)
+		_, err = auth.GetStatisticsFailuresBreakdown(ctx, mp[PrmRealm], unit)
+		assert.Nil(t, err)
+
+		mockComponent.EXPECT().GetStatisticsAuthenticationsRatio(ctx, mp[PrmRealm], unit, nil).Return([]api.StatisticsRatioRepresentation{}, nil).Times(1)
+		_, err = auth.GetStatisticsAuthenticationsRatio(ctx, mp[PrmRealm], unit, nil)
+		assert.Nil(t, err)
+	})
+	testAuthorization(t, WithoutAuthorization, func(auth Component, mockComponent *mock.Component, ctx context.Context, mp map[string]string) {
+		_, err := auth.GetStatisticsFailures(ctx, mp[PrmRealm])
+		assert.Equal(t, security.ForbiddenError{}, err)
+
+		_, err = auth.GetStatisticsFailedAuthentications(ctx, mp[PrmRealm], unit, nil)
+		assert.Equal(t, security.ForbiddenError{}, err)
+
+		_, err = auth.GetStatisticsLockouts(ctx, mp[PrmRealm], unit, nil)
+		assert.Equal(t, security.ForbiddenError{}, err)
+
+		_, err = auth.GetStatisticsFailuresBreakdown(ctx, mp[PrmRealm], unit)
+		assert.Equal(t, security.ForbiddenError{}, err)
+
+		_, err = auth.GetStatisticsAuthenticationsRatio(ctx, mp[PrmRealm], unit, nil)
+		assert.Equal(t, security.ForbiddenError{}, err)
+	})
+}
+
+func TestGetStatisticsRegistrationsAllowAndDeny(t *testing.T) {
+	var unit = "days"
+	testAuthorization(t, WithAuthorization(), func(auth Component, mockComponent *mock.Component, ctx context.Context, mp map[string]string) {
+		mockComponent.EXPECT().GetStatisticsRegistrations(ctx, mp[PrmRealm], unit, nil).Return(api.StatisticsRegistrationsRepresentation{}, nil).Times(1)
+		_, err := auth.GetStatisticsRegistrations(ctx, mp[PrmRealm], unit, nil)
+		assert.Nil(t, err)
+	})
+	testAuthorization(t, WithoutAuthorization, func(auth Component, mockComponent *mock.Component, ctx context.Context, mp map[string]string) {
+		_, err := auth.GetStatisticsRegistrations(ctx, mp[PrmRealm], unit, nil)
+		assert.Equal(t, security.ForbiddenError{}, err)
+	})
+}
+
+func TestGetMigrationReportAllowAndDeny(t *testing.T) {
+	testAuthorization(t, WithAuthorization(), func(auth Component, mockComponent *mock.Component, ctx context.Context, mp map[string]string) {
+		mockComponent.EXPECT().GetMigrationReport(ctx, mp[PrmRealm]).Return(map[string]bool{}, nil).Times(1)
+		_, err := auth.GetMigrationReport(ctx, mp[PrmRealm])
+		assert.Nil(t, err)
+
+		mockComponent.EXPECT().GetMigrationReportSummary(ctx, mp[PrmRealm], true, false).Return(api.MigrationReportSummaryRepresentation{}, nil).Times(1)
+		_, err = auth.GetMigrationReportSummary(ctx, mp[PrmRealm], true, false)
+		assert.Nil(t, err)
+
+		mockComponent.EXPECT().GetMigrationReportUsers(ctx, mp[PrmRealm], nil, 0, 10).Return(api.MigrationReportUsersPageRepresentation{}, nil).Times(1)
+		_, err = auth.GetMigrationReportUsers(ctx, mp[PrmRealm], nil, 0, 10)
+		assert.Nil(t, err)
+	})
+	testAuthorization(t, WithoutAuthorization, func(auth Component, mockComponent *mock.Component, ctx context.Context, mp map[string]string) {
+		_, err := auth.GetMigrationReport(ctx, mp[PrmRealm])
+		assert.Equal(t, security.ForbiddenError{}, err)
+
+		_, err = auth.GetMigrationReportSummary(ctx, mp[PrmRealm], true, false)
+		assert.Equal(t, security.ForbiddenError{}, err)
+
+		_, err = auth.GetMigrationReportUsers(ctx, mp[PrmRealm], nil, 0, 10)
+		assert.Equal(t, security.ForbiddenError{}, err)
+	})
+}
+
+func TestInactiveUsersAllowAndDeny(t *testing.T) {
+	testAuthorization(t, WithAuthorization(), func(auth Component, mockComponent *mock.Component, ctx context.Context, mp map[string]string) {
+		mockComponent.EXPECT().GetInactiveUsers(ctx, mp[PrmRealm], 30, 0, 10).Return(api.InactiveUsersPageRepresentation{}, nil).Times(1)
+		_, err := auth.GetInactiveUsers(ctx, mp[PrmRealm], 30, 0, 10)
+		assert.Nil(t, err)
+
+		mockComponent.EXPECT().LockInactiveUsers(ctx, mp[PrmRealm], 30, []string{"user-id"}).Return(api.InactiveUsersLockRepresentation{}, nil).Times(1)
+		_, err = auth.LockInactiveUsers(ctx, mp[PrmRealm], 30, []string{"user-id"})
+		assert.Nil(t, err)
+	})
+	testAuthorization(t, WithoutAuthorization, func(auth Component, mockComponent *mock.Component, ctx context.Context, mp map[string]string) {
+		_, err := auth.GetInactiveUsers(ctx, mp[PrmRealm], 30, 0, 10)
+		assert.Equal(t, security.ForbiddenError{}, err)
+
+		_, err = auth.LockInactiveUsers(ctx, mp[PrmRealm], 30, []string{"user-id"})
+		assert.Equal(t, security.ForbiddenError{}, err)
+	})
+}
+
+func TestAccreditationsAllowAndDeny(t *testing.T) {
+	testAuthorization(t, WithAuthorization(), func(auth Component, mockComponent *mock.Component, ctx context.Context, mp map[string]string) {
+		mockComponent.EXPECT().GetStatisticsAccreditations(ctx, mp[PrmRealm]).Return(nil, nil).Times(1)
+		_, err := auth.GetStatisticsAccreditations(ctx, mp[PrmRealm])
+		assert.Nil(t, err)
+
+		mockComponent.EXPECT().GetExpiringAccreditations(ctx, mp[PrmRealm], 30, nil, 0, 10).Return(api.ExpiringAccreditationsPageRepresentation{}, nil).Times(1)
+		_, err = auth.GetExpiringAccreditations(ctx, mp[PrmRealm], 30, nil, 0, 10)
+		assert.Nil(t, err)
+	})
+	testAuthorization(t, WithoutAuthorization, func(auth Component, mockComponent *mock.Component, ctx context.Context, mp map[string]string) {
+		_, err := auth.GetStatisticsAccreditations(ctx, mp[PrmRealm])
+		assert.Equal(t, security.ForbiddenError{}, err)
+
+		_, err = auth.GetExpiringAccreditations(ctx, mp[PrmRealm], 30, nil, 0, 10)
+		assert.Equal(t, security.ForbiddenError{}, err)
+	})
+}
+
+func TestGetStatisticsOverviewAuthorization(t *testing.T) {
+	// Realms are filtered by the component: the middleware always delegates
+	testAuthorization(t, WithoutAuthorization, func(auth Component, mockComponent *mock.Component, ctx context.Context, mp map[string]string) {
+		mockComponent.EXPECT().GetStatisticsOverview(ctx, "users", true).Return(api.StatisticsOverviewRepresentation{}, nil).Times(1)
+		_, err := auth.GetStatisticsOverview(ctx, "users", true)
+		assert.Nil(t, err)
+	})
+}
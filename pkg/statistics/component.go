@@ -3,6 +3,9 @@ package statistics
 import (
 	"context"
 	"regexp"
+	"sort"
+	"strconv"
+	"sync"
 	"time"
 
 	cs "github.com/cloudtrust/common-service"
@@ -23,26 +26,191 @@ type Component interface {
 	GetStatisticsAuthentications(context.Context, string, string, *string) ([][]int64, error)
 	GetStatisticsAuthenticationsLog(context.Context, string, string) ([]api.StatisticsConnectionRepresentation, error)
 	GetMigrationReport(context.Context, string) (map[string]bool, error)
+	GetStatisticsFailures(context.Context, string) (api.StatisticsFailuresRepresentation, error)
+	GetStatisticsFailedAuthentications(context.Context, string, string, *string) ([][]int64, error)
+	GetStatisticsLockouts(context.Context, string, string, *string) ([][]int64, error)
+	GetStatisticsFailuresBreakdown(context.Context, string, string) (api.StatisticsFailuresBreakdownRepresentation, error)
+	GetStatisticsAuthenticationsRatio(context.Context, string, string, *string) ([]api.StatisticsRatioRepresentation, error)
+	GetStatisticsRegistrations(context.Context, string, string, *string) (api.StatisticsRegistrationsRepresentation, error)
+	GetMigrationReportSummary(context.Context, string, bool, bool) (api.MigrationReportSummaryRepresentation, error)
+	GetMigrationReportUsers(context.Context, string, *bool, int, int) (api.MigrationReportUsersPageRepresentation, error)
+	GetInactiveUsers(context.Context, string, int, int, int) (api.InactiveUsersPageRepresentation, error)
+	LockInactiveUsers(context.Context, string, int, []string) (api.InactiveUsersLockRepresentation, error)
+	GetStatisticsAccreditations(context.Context, string) (map[string]api.StatisticsAccreditationsRepresentation, error)
+	GetExpiringAccreditations(context.Context, string, int, *string, int, int) (api.ExpiringAccreditationsPageRepresentation, error)
+	GetStatisticsOverview(context.Context, string, bool) (api.StatisticsOverviewRepresentation, error)
 }
 
+// Duration covered by the breakdowns, depending on the unit used by the graphs
+var unitDurations = map[string]string{
+	"hours":  "1 DAY",
+	"days":   "1 MONTH",
+	"months": "1 YEAR",
+}
+
+// Steps of the registration funnel, in the order they are expected to be reached by the users
+var registrationSteps = []string{
+	keycloakb.CtEventTypeRegisterUser,
+	keycloakb.CtEventTypeEmailConfirmed,
+	keycloakb.CtEventTypePasswordReset,
+	keycloakb.CtEventTypeValidateUser,
+	keycloakb.CtEventTypeValidationStoreCheck,
+}
+
+// A registration is considered as abandoned when the user is not validated and has not reached any step for this duration
+const registrationAbandonDelay = 7 * 24 * time.Hour
+
+// A computed migration report is served from the cache during this duration
+const migrationReportCacheTTL = time.Hour
+
+// A computed list of inactive users is served from the cache during this duration: paging through the list or exporting
+// it does not read all the users of the realm again
+const inactiveUsersCacheTTL = 10 * time.Minute
+
+// Users are read by pages of brief representations of this size when computing a report on all the users of a realm
+const briefUsersPageSize = 500
+
 // KeycloakClient interface
 type KeycloakClient interface {
 	GetUsers(accessToken string, reqRealmName, targetRealmName string, paramKV ...string) (kc.UsersPageRepresentation, error)
+	GetUser(accessToken string, realmName, userID string) (kc.UserRepresentation, error)
 	GetStatisticsUsers(accessToken string, realmName string) (kc.StatisticsUsersRepresentation, error)
 	GetStatisticsAuthenticators(accessToken string, realmName string) (map[string]int64, error)
+	GetGroups(accessToken string, realmName string) ([]kc.GroupRepresentation, error)
+	GetRealms(accessToken string) ([]kc.RealmRepresentation, error)
+}
+
+// TokenProvider provides the OIDC token of the technical user
+type TokenProvider interface {
+	ProvideToken(ctx context.Context) (string, error)
+}
+
+// RealmAuthorizer checks if the current user is allowed to perform an action on a realm. It is implemented by security.AuthorizationManager
+type RealmAuthorizer interface {
+	CheckAuthorizationOnTargetRealm(ctx context.Context, action string, targetRealm string) error
+}
+
+// ManagementComponent is the subset of the management component used to lock users
+type ManagementComponent interface {
+	LockUser(ctx context.Context, realmName, userID string) error
 }
 
 type component struct {
-	db             keycloakb.EventsDBModule
-	keycloakClient KeycloakClient
-	logger         log.Logger
+	db                  keycloakb.EventsDBModule
+	counter             keycloakb.EventsCounter
+	keycloakClient      KeycloakClient
+	tokenProvider       TokenProvider
+	tokenRealm          string
+	managementComponent ManagementComponent
+	authorizer          RealmAuthorizer
+	registerRealms      map[string]bool
+	migrationReports    *migrationReportCache
+	inactiveUsers       *inactiveUsersCache
+	accreditations      *accreditationsIndex
+	logger              log.Logger
+}
+
+// migrationReport is a computed migration report, kept in cache per realm
+type migrationReport struct {
+	users      []api.MigrationReportUserRepresentation
+	summary    api.MigrationReportSummaryRepresentation
+	computedAt time.Time
+}
+
+type migrationReportCache struct {
+	mutex   sync.Mutex
+	reports map[string]*migrationReport
+	pending map[string]bool
+}
+
+// get gives the report of a realm if it has not expired. The caller must hold the mutex
+func (c *migrationReportCache) get(realmName string) (*migrationReport, bool) {
+	var report, ok = c.reports[realmName]
+	if ok && time.Since(report.computedAt) > migrationReportCacheTTL {
+		delete(c.reports, realmName)
+		return nil, false
+	}
+	return report, ok
+}
+
+// inactiveUsersReport is a computed list of inactive users, kept in cache per realm and number of days
+type inactiveUsersReport struct {
+	users      []api.InactiveUserRepresentation
+	computedAt time.Time
+}
+
+type inactiveUsersKey struct {
+	realm string
+	days  int
+}
+
+type inactiveUsersCache struct {
+	mutex   sync.Mutex
+	reports map[inactiveUsersKey]*inactiveUsersReport
+}
+
+// get gives the list of inactive users of a realm if it has not expired
+func (c *inactiveUsersCache) get(realmName string, days int) (*inactiveUsersReport, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	var report, ok = c.reports[inactiveUsersKey{realm: realmName, days: days}]
+	if ok && time.Since(report.computedAt) > inactiveUsersCacheTTL {
+		return nil, false
+	}
+	return report, ok
+}
+
+// put stores the list of inactive users of a realm and removes the expired ones
+func (c *inactiveUsersCache) put(realmName string, days int, report *inactiveUsersReport) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	for key, cached := range c.reports {
+		if time.Since(cached.computedAt) > inactiveUsersCacheTTL {
+			delete(c.reports, key)
+		}
+	}
+	c.reports[inactiveUsersKey{realm: realmName, days: days}] = report
+}
+
+// invalidate removes the lists of inactive users of a realm
+func (c *inactiveUsersCache) invalidate(realmName string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	for key := range c.reports {
+		if key.realm == realmName {
+			delete(c.reports, key)
+		}
+	}
 }
 
-// NewComponent returns a component
-func NewComponent(db keycloakb.EventsDBModule, keycloakClient KeycloakClient, logger log.Logger) Component {
+// NewComponent returns a component. Events counts are read from counter, which is either the events DB module itself
+// or the statistics rollups. The token of the technical user (from tokenRealm) is used for background computations
+func NewComponent(db keycloakb.EventsDBModule, counter keycloakb.EventsCounter, keycloakClient KeycloakClient, tokenProvider TokenProvider, tokenRealm string, managementComponent ManagementComponent, authorizer RealmAuthorizer, registerRealms []string, logger log.Logger) Component {
+	var realms = make(map[string]bool)
+	for _, realm := range registerRealms {
+		realms[realm] = true
+	}
+
 	return &component{
-		db:             db,
-		keycloakClient: keycloakClient,
+		db:                  db,
+		counter:             counter,
+		keycloakClient:      keycloakClient,
+		tokenProvider:       tokenProvider,
+		tokenRealm:          tokenRealm,
+		managementComponent: managementComponent,
+		authorizer:          authorizer,
+		registerRealms:      realms,
+		migrationReports: &migrationReportCache{
+			reports: make(map[string]*migrationReport),
+			pending: make(map[string]bool),
+		},
+		inactiveUsers: &inactiveUsersCache{
+			reports: make(map[inactiveUsersKey]*inactiveUsersReport),
+		},
+		accreditations: newAccreditationsIndex(),
 		logger:         logger,
 	}
 }
@@ -72,19 +240,19 @@ func (ec *component) GetStatistics(ctx context.Context, realmName string) (api.S
 	res.LastConnection, err = ec.db.GetLastConnection(ctx, realmName)
 
 	if err == nil {
-		res.TotalConnections.LastTwelveHours, err = ec.db.GetTotalConnectionsCount(ctx, realmName, "12 HOUR")
+		res.TotalConnections.LastTwelveHours, err = ec.counter.GetTotalConnectionsCount(ctx, realmName, "12 HOUR")
 	}
 	if err == nil {
-		res.TotalConnections.LastDay, err = ec.db.GetTotalConnectionsCount(ctx, realmName, "1 DAY")
+		res.TotalConnections.LastDay, err = ec.counter.GetTotalConnectionsCount(ctx, realmName, "1 DAY")
 	}
 	if err == nil {
-		res.TotalConnections.LastWeek, err = ec.db.GetTotalConnectionsCount(ctx, realmName, "1 WEEK")
+		res.TotalConnections.LastWeek, err = ec.counter.GetTotalConnectionsCount(ctx, realmName, "1 WEEK")
 	}
 	if err == nil {
-		res.TotalConnections.LastMonth, err = ec.db.GetTotalConnectionsCount(ctx, realmName, "1 MONTH")
+		res.TotalConnections.LastMonth, err = ec.counter.GetTotalConnectionsCount(ctx, realmName, "1 MONTH")
 	}
 	if err == nil {
-		res.TotalConnections.LastYear, err = ec.db.GetTotalConnectionsCount(ctx, realmName, "1 YEAR")
+		res.TotalConnections.LastYear, err = ec.counter.GetTotalConnectionsCount(ctx, realmName, "1 YEAR")
 	}
 
 	return res, err
@@ -121,26 +289,19 @@ func (ec *component) GetStatisticsAuthenticators(ctx context.Context, realmName
 // GetStatisticsAuthentications gives statistics on number of authentications on a certain period
 func (ec *component) GetStatisticsAuthentications(ctx context.Context, realmName string, unit string, timeshift *string) ([][]int64, error) {
 	var res [][]int64
-	var err error
-	var location = time.UTC
-	var timeshiftValue = 0
-
-	if timeshift != nil {
-		timeshiftValue, err = keycloakb.ConvertMinutesShift(*timeshift)
-		if err != nil {
-			return nil, err
-		}
-		location = time.FixedZone("web client", timeshiftValue*60)
+	var location, timeshiftValue, err = convertTimeshift(timeshift)
+	if err != nil {
+		return nil, err
 	}
 
 	// query to get number of authentications
 	switch unit {
 	case "hours":
-		res, err = ec.db.GetTotalConnectionsHoursCount(ctx, realmName, location, timeshiftValue)
+		res, err = ec.counter.GetTotalConnectionsHoursCount(ctx, realmName, location, timeshiftValue)
 	case "days":
-		res, err = ec.db.GetTotalConnectionsDaysCount(ctx, realmName, location, timeshiftValue)
+		res, err = ec.counter.GetTotalConnectionsDaysCount(ctx, realmName, location, timeshiftValue)
 	case "months":
-		res, err = ec.db.GetTotalConnectionsMonthsCount(ctx, realmName, location, timeshiftValue)
+		res, err = ec.counter.GetTotalConnectionsMonthsCount(ctx, realmName, location, timeshiftValue)
 	default:
 		ec.logger.Warn(ctx, "err", "Invalid parameter value")
 		return nil, errorhandler.CreateInvalidQueryParameterError(msg.Unit)
@@ -172,6 +333,304 @@ func (ec *component) GetStatisticsAuthenticationsLog(ctx context.Context, realmN
 	return res, nil
 }
 
+// GetStatisticsFailures gives the number of failed authentications and lockouts on different time periods
+func (ec *component) GetStatisticsFailures(ctx context.Context, realmName string) (api.StatisticsFailuresRepresentation, error) {
+	var res api.StatisticsFailuresRepresentation
+	var err error
+
+	res.FailedAuthentications, err = ec.getEventsCounts(ctx, realmName, keycloakb.CtEventTypeLogonError)
+	if err == nil {
+		res.Lockouts, err = ec.getEventsCounts(ctx, realmName, keycloakb.CtEventTypeTemporarilyLocked)
+	}
+	if err != nil {
+		ec.logger.Warn(ctx, "err", err.Error())
+		return api.StatisticsFailuresRepresentation{}, err
+	}
+
+	return res, nil
+}
+
+// GetStatisticsFailedAuthentications gives statistics on number of failed authentications on a certain period
+func (ec *component) GetStatisticsFailedAuthentications(ctx context.Context, realmName string, unit string, timeshift *string) ([][]int64, error) {
+	return ec.getEventsGraph(ctx, realmName, keycloakb.CtEventTypeLogonError, unit, timeshift)
+}
+
+// GetStatisticsLockouts gives statistics on number of temporary lockouts on a certain period
+func (ec *component) GetStatisticsLockouts(ctx context.Context, realmName string, unit string, timeshift *string) ([][]int64, error) {
+	return ec.getEventsGraph(ctx, realmName, keycloakb.CtEventTypeTemporarilyLocked, unit, timeshift)
+}
+
+// GetStatisticsFailuresBreakdown gives the number of failed authentications grouped by error code and by client
+// The covered duration is the one of the graph with the same unit: last day for hours, last month for days, last year for months
+func (ec *component) GetStatisticsFailuresBreakdown(ctx context.Context, realmName string, unit string) (api.StatisticsFailuresBreakdownRepresentation, error) {
+	var durationLabel, ok = unitDurations[unit]
+	if !ok {
+		ec.logger.Warn(ctx, "err", "Invalid parameter value")
+		return api.StatisticsFailuresBreakdownRepresentation{}, errorhandler.CreateInvalidQueryParameterError(msg.Unit)
+	}
+
+	var res api.StatisticsFailuresBreakdownRepresentation
+	var err error
+
+	res.ByErrorCode, err = ec.db.GetFailuresCountByErrorCode(ctx, realmName, durationLabel)
+	if err == nil {
+		res.ByClientID, err = ec.db.GetFailuresCountByClientID(ctx, realmName, durationLabel)
+	}
+	if err != nil {
+		ec.logger.Warn(ctx, "err", err.Error())
+		return api.StatisticsFailuresBreakdownRepresentation{}, err
+	}
+
+	return res, nil
+}
+
+// GetStatisticsAuthenticationsRatio gives, for each period, the number of successful and failed authentications and the ratio of successes
+// Failures include both failed authentications and temporary lockouts
+func (ec *component) GetStatisticsAuthenticationsRatio(ctx context.Context, realmName string, unit string, timeshift *string) ([]api.StatisticsRatioRepresentation, error) {
+	var series = make([][][]int64, 0, 3)
+	for _, ctEventType := range []string{keycloakb.CtEventTypeLogonOK, keycloakb.CtEventTypeLogonError, keycloakb.CtEventTypeTemporarilyLocked} {
+		var stats, err = ec.getEventsGraph(ctx, realmName, ctEventType, unit, timeshift)
+		if err != nil {
+			return nil, err
+		}
+		series = append(series, stats)
+	}
+
+	var successes, logonErrors, lockouts = series[0], series[1], series[2]
+	var res = []api.StatisticsRatioRepresentation{}
+	for i, success := range successes {
+		var ratio = api.StatisticsRatioRepresentation{
+			Period:    success[0],
+			Successes: success[1],
+		}
+		if i < len(logonErrors) && logonErrors[i][0] == ratio.Period {
+			ratio.Failures += logonErrors[i][1]
+		}
+		if i < len(lockouts) && lockouts[i][0] == ratio.Period {
+			ratio.Failures += lockouts[i][1]
+		}
+		if total := ratio.Successes + ratio.Failures; total > 0 {
+			ratio.SuccessRatio = float64(ratio.Successes) / float64(total)
+		}
+		res = append(res, ratio)
+	}
+
+	return res, nil
+}
+
+func (ec *component) getEventsCounts(ctx context.Context, realmName string, ctEventType string) (api.StatisticsConnectionsRepresentation, error) {
+	var res api.StatisticsConnectionsRepresentation
+	var err error
+
+	res.LastTwelveHours, err = ec.counter.GetTotalEventsCount(ctx, realmName, ctEventType, "12 HOUR")
+	if err == nil {
+		res.LastDay, err = ec.counter.GetTotalEventsCount(ctx, realmName, ctEventType, "1 DAY")
+	}
+	if err == nil {
+		res.LastWeek, err = ec.counter.GetTotalEventsCount(ctx, realmName, ctEventType, "1 WEEK")
+	}
+	if err == nil {
+		res.LastMonth, err = ec.counter.GetTotalEventsCount(ctx, realmName, ctEventType, "1 MONTH")
+	}
+	if err == nil {
+		res.LastYear, err = ec.counter.GetTotalEventsCount(ctx, realmName, ctEventType, "1 YEAR")
+	}
+
+	return res, err
+}
+
+func (ec *component) getEventsGraph(ctx context.Context, realmName string, ctEventType string, unit string, timeshift *string) ([][]int64, error) {
+	var res [][]int64
+	var location, timeshiftValue, err = convertTimeshift(timeshift)
+	if err != nil {
+		return nil, err
+	}
+
+	switch unit {
+	case "hours":
+		res, err = ec.counter.GetTotalEventsHoursCount(ctx, realmName, ctEventType, location, timeshiftValue)
+	case "days":
+		res, err = ec.counter.GetTotalEventsDaysCount(ctx, realmName, ctEventType, location, timeshiftValue)
+	case "months":
+		res, err = ec.counter.GetTotalEventsMonthsCount(ctx, realmName, ctEventType, location, timeshiftValue)
+	default:
+		ec.logger.Warn(ctx, "err", "Invalid parameter value")
+		return nil, errorhandler.CreateInvalidQueryParameterError(msg.Unit)
+	}
+	if err != nil {
+		ec.logger.Warn(ctx, "err", err.Error())
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// GetStatisticsRegistrations gives the registration funnel of the users registered in a register realm on a certain period
+func (ec *component) GetStatisticsRegistrations(ctx context.Context, realmName string, unit string, timeshift *string) (api.StatisticsRegistrationsRepresentation, error) {
+	if !ec.registerRealms[realmName] {
+		ec.logger.Warn(ctx, "msg", "Realm is not a register realm", "realm", realmName)
+		return api.StatisticsRegistrationsRepresentation{}, errorhandler.CreateBadRequestError(msg.MsgErrInvalidParam + "." + msg.Realm)
+	}
+
+	var location, _, err = convertTimeshift(timeshift)
+	if err != nil {
+		return api.StatisticsRegistrationsRepresentation{}, err
+	}
+
+	var now = time.Now()
+	var periodStarts, end, periodLabels, errPeriods = getPeriods(now.In(location), unit)
+	if errPeriods != nil {
+		ec.logger.Warn(ctx, "err", "Invalid parameter value")
+		return api.StatisticsRegistrationsRepresentation{}, errPeriods
+	}
+
+	users, err := ec.db.GetRegistrationSteps(ctx, realmName, periodStarts[0], end)
+	if err != nil {
+		ec.logger.Warn(ctx, "err", err.Error())
+		return api.StatisticsRegistrationsRepresentation{}, err
+	}
+
+	var usersPerPeriod = make([][]keycloakb.UserRegistrationSteps, len(periodStarts))
+	var abandonLimit = now.Add(-registrationAbandonDelay).Unix()
+	var res = api.StatisticsRegistrationsRepresentation{}
+
+	for _, user := range users {
+		var registrationTime = user.Steps[keycloakb.CtEventTypeRegisterUser]
+		var idx = sort.Search(len(periodStarts), func(i int) bool {
+			return periodStarts[i].Unix() > registrationTime
+		}) - 1
+		if idx >= 0 {
+			usersPerPeriod[idx] = append(usersPerPeriod[idx], user)
+		}
+		if isRegistrationAbandoned(user, abandonLimit) {
+			res.Abandoned++
+		}
+	}
+
+	res.Steps = computeRegistrationFunnel(users)
+	res.Periods = []api.StatisticsRegistrationPeriodRepresentation{}
+	for i, periodUsers := range usersPerPeriod {
+		res.Periods = append(res.Periods, api.StatisticsRegistrationPeriodRepresentation{
+			Period: periodLabels[i],
+			Steps:  computeRegistrationFunnel(periodUsers),
+		})
+	}
+
+	return res, nil
+}
+
+// getPeriods returns the start time and the label of each period of the given unit, up to the end of the current one
+func getPeriods(nowLocalized time.Time, unit string) ([]time.Time, time.Time, []int64, error) {
+	var end time.Time
+	var start time.Time
+	var next func(time.Time) time.Time
+	var label func(time.Time) int64
+
+	switch unit {
+	case "hours":
+		end = keycloakb.NextHour(nowLocalized)
+		start = end.Add(-24 * time.Hour)
+		next = func(t time.Time) time.Time { return t.Add(time.Hour) }
+		label = func(t time.Time) int64 { return int64(t.In(nowLocalized.Location()).Hour()) }
+	case "days":
+		end = keycloakb.NextDay(nowLocalized)
+		start = end.In(nowLocalized.Location()).AddDate(0, -1, 0).UTC()
+		next = func(t time.Time) time.Time { return t.Add(24 * time.Hour) }
+		label = func(t time.Time) int64 { return int64(t.In(nowLocalized.Location()).Day()) }
+	case "months":
+		end = keycloakb.NextMonth(nowLocalized)
+		start = end.In(nowLocalized.Location()).AddDate(-1, 0, 0).UTC()
+		next = func(t time.Time) time.Time { return t.In(nowLocalized.Location()).AddDate(0, 1, 0).UTC() }
+		label = func(t time.Time) int64 { return int64(t.In(nowLocalized.Location()).Month()) }
+	default:
+		return nil, time.Time{}, nil, errorhandler.CreateInvalidQueryParameterError(msg.Unit)
+	}
+
+	var starts []time.Time
+	var labels []int64
+	for t := start; t.Before(end); t = next(t) {
+		starts = append(starts, t)
+		labels = append(labels, label(t))
+	}
+
+	return starts, end, labels, nil
+}
+
+func computeRegistrationFunnel(users []keycloakb.UserRegistrationSteps) []api.StatisticsRegistrationStepRepresentation {
+	var res []api.StatisticsRegistrationStepRepresentation
+	var registered = int64(len(users))
+
+	for stepIdx, step := range registrationSteps {
+		var count int64
+		var delays []int64
+		for _, user := range users {
+			var stepTime, ok = user.Steps[step]
+			if !ok {
+				continue
+			}
+			count++
+			// Delay since the latest previous step reached by the user
+			for i := stepIdx - 1; i >= 0; i-- {
+				if previousTime, ok := user.Steps[registrationSteps[i]]; ok {
+					delays = append(delays, stepTime-previousTime)
+					break
+				}
+			}
+		}
+
+		var stepStats = api.StatisticsRegistrationStepRepresentation{
+			Name:        step,
+			Count:       count,
+			MedianDelay: median(delays),
+		}
+		if registered > 0 {
+			stepStats.ConversionRate = float64(count) / float64(registered)
+		}
+		res = append(res, stepStats)
+	}
+
+	return res
+}
+
+func isRegistrationAbandoned(user keycloakb.UserRegistrationSteps, abandonLimit int64) bool {
+	var lastStep int64
+	for _, step := range registrationSteps {
+		if step == keycloakb.CtEventTypeValidateUser || step == keycloakb.CtEventTypeValidationStoreCheck {
+			if _, ok := user.Steps[step]; ok {
+				return false
+			}
+		}
+		if stepTime, ok := user.Steps[step]; ok && stepTime > lastStep {
+			lastStep = stepTime
+		}
+	}
+	return lastStep < abandonLimit
+}
+
+func median(values []int64) *int64 {
+	if len(values) == 0 {
+		return nil
+	}
+	sort.Slice(values, func(i, j int) bool { return values[i] < values[j] })
+
+	var res = values[len(values)/2]
+	if len(values)%2 == 0 {
+		res = (values[len(values)/2-1] + res) / 2
+	}
+	return &res
+}
+
+func convertTimeshift(timeshift *string) (*time.Location, int, error) {
+	if timeshift == nil {
+		return time.UTC, 0, nil
+	}
+	var timeshiftValue, err = keycloakb.ConvertMinutesShift(*timeshift)
+	if err != nil {
+		return nil, 0, err
+	}
+	return time.FixedZone("web client", timeshiftValue*60), timeshiftValue, nil
+}
+
 // Compute Migration Report
 func (ec *component) GetMigrationReport(ctx context.Context, realmName string) (map[string]bool, error) {
 	var accessToken = ctx.Value(cs.CtContextAccessToken).(string)
@@ -196,6 +655,347 @@ func (ec *component) GetMigrationReport(ctx context.Context, realmName string) (
 	return migratedUsers, nil
 }
 
+// GetMigrationReportSummary gives the number of migrated and not migrated users of a realm, globally and per group
+// A report computed less than migrationReportCacheTTL ago is returned unless a refresh is requested.
+// In async mode, the report is computed in background and the last computed report (if any) is returned with a pending status
+func (ec *component) GetMigrationReportSummary(ctx context.Context, realmName string, async bool, refresh bool) (api.MigrationReportSummaryRepresentation, error) {
+	var accessToken = ctx.Value(cs.CtContextAccessToken).(string)
+	var ctxRealm = ctx.Value(cs.CtContextRealm).(string)
+	var cache = ec.migrationReports
+
+	if !async {
+		if !refresh {
+			cache.mutex.Lock()
+			var report, ok = cache.get(realmName)
+			cache.mutex.Unlock()
+			if ok {
+				return report.getSummary(api.MigrationReportStatusDone), nil
+			}
+		}
+		var report, err = ec.computeMigrationReport(ctx, accessToken, ctxRealm, realmName)
+		if err != nil {
+			return api.MigrationReportSummaryRepresentation{}, err
+		}
+		return report.getSummary(api.MigrationReportStatusDone), nil
+	}
+
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	var report, ok = cache.get(realmName)
+	if ok && !refresh {
+		if cache.pending[realmName] {
+			return report.getSummary(api.MigrationReportStatusPending), nil
+		}
+		return report.getSummary(api.MigrationReportStatusDone), nil
+	}
+
+	if !cache.pending[realmName] {
+		cache.pending[realmName] = true
+		// The computation outlives the request: its context may be cancelled and the token of the caller may expire
+		var bgCtx = context.WithValue(context.Background(), cs.CtContextCorrelationID, ctx.Value(cs.CtContextCorrelationID))
+		go func() {
+			if serviceToken, err := ec.tokenProvider.ProvideToken(bgCtx); err != nil {
+				ec.logger.Warn(bgCtx, "msg", "Can't get OIDC token", "err", err.Error())
+			} else {
+				// Errors are logged by computeMigrationReport
+				_, _ = ec.computeMigrationReport(bgCtx, serviceToken, ec.tokenRealm, realmName)
+			}
+			cache.mutex.Lock()
+			delete(cache.pending, realmName)
+			cache.mutex.Unlock()
+		}()
+	}
+
+	if ok {
+		return report.getSummary(api.MigrationReportStatusPending), nil
+	}
+	return api.MigrationReportSummaryRepresentation{Status: api.MigrationReportStatusPending}, nil
+}
+
+// GetMigrationReportUsers gives a page of the migration report of a realm
+// When filtering on the migrated flag, the last computed report is used if it has not expired, otherwise the report is computed
+func (ec *component) GetMigrationReportUsers(ctx context.Context, realmName string, migrated *bool, first int, max int) (api.MigrationReportUsersPageRepresentation, error) {
+	var accessToken = ctx.Value(cs.CtContextAccessToken).(string)
+	var ctxRealm = ctx.Value(cs.CtContextRealm).(string)
+
+	if migrated == nil {
+		usersKc, err := ec.keycloakClient.GetUsers(accessToken, ctxRealm, realmName, PrmQryFirst, strconv.Itoa(first), PrmQryMax, strconv.Itoa(max))
+		if err != nil {
+			ec.logger.Warn(ctx, "err", err.Error())
+			return api.MigrationReportUsersPageRepresentation{}, err
+		}
+
+		var res = api.MigrationReportUsersPageRepresentation{Users: []api.MigrationReportUserRepresentation{}}
+		for _, user := range usersKc.Users {
+			res.Users = append(res.Users, api.MigrationReportUserRepresentation{Username: *user.Username, Migrated: isMigrated(user)})
+		}
+		if usersKc.Count != nil {
+			res.Count = *usersKc.Count
+		}
+		return res, nil
+	}
+
+	ec.migrationReports.mutex.Lock()
+	var report, ok = ec.migrationReports.get(realmName)
+	ec.migrationReports.mutex.Unlock()
+
+	if !ok {
+		var err error
+		if report, err = ec.computeMigrationReport(ctx, accessToken, ctxRealm, realmName); err != nil {
+			return api.MigrationReportUsersPageRepresentation{}, err
+		}
+	}
+
+	var filtered = []api.MigrationReportUserRepresentation{}
+	for _, user := range report.users {
+		if user.Migrated == *migrated {
+			filtered = append(filtered, user)
+		}
+	}
+
+	var computedAt = report.computedAt.Unix()
+	var res = api.MigrationReportUsersPageRepresentation{
+		Users:      []api.MigrationReportUserRepresentation{},
+		Count:      len(filtered),
+		ComputedAt: &computedAt,
+	}
+	if start, end := getPageBounds(len(filtered), first, max); start < end {
+		res.Users = filtered[start:end]
+	}
+
+	return res, nil
+}
+
+// computeMigrationReport reads the users of the realm by pages of brief representations. These have no attributes: the
+// migrated users are searched by Keycloak first, so a user migrated while the report is computed may be counted as not
+// migrated
+func (ec *component) computeMigrationReport(ctx context.Context, accessToken string, ctxRealm string, realmName string) (*migrationReport, error) {
+	var migratedUsers = make(map[string]bool)
+	var err = ec.forEachBriefUser(accessToken, ctxRealm, realmName, []string{"q", "migrated:true"}, func(user kc.UserRepresentation) {
+		if user.ID != nil {
+			migratedUsers[*user.ID] = true
+		}
+	})
+	if err != nil {
+		ec.logger.Warn(ctx, "msg", "Can't compute migration report", "err", err.Error(), "realm", realmName)
+		return nil, err
+	}
+
+	var report = &migrationReport{
+		users: []api.MigrationReportUserRepresentation{},
+		summary: api.MigrationReportSummaryRepresentation{
+			Groups: make(map[string]api.MigrationReportCountRepresentation),
+		},
+	}
+	err = ec.forEachBriefUser(accessToken, ctxRealm, realmName, nil, func(user kc.UserRepresentation) {
+		if user.ID == nil || user.Username == nil {
+			return
+		}
+		var migrated = migratedUsers[*user.ID]
+		report.users = append(report.users, api.MigrationReportUserRepresentation{Username: *user.Username, Migrated: migrated})
+		report.summary.Add(migrated)
+	})
+	if err != nil {
+		ec.logger.Warn(ctx, "msg", "Can't compute migration report", "err", err.Error(), "realm", realmName)
+		return nil, err
+	}
+
+	groupsKc, err := ec.keycloakClient.GetGroups(accessToken, realmName)
+	if err != nil {
+		ec.logger.Warn(ctx, "msg", "Can't compute migration report", "err", err.Error(), "realm", realmName)
+		return nil, err
+	}
+	for _, group := range groupsKc {
+		var count api.MigrationReportCountRepresentation
+		err = ec.forEachBriefUser(accessToken, ctxRealm, realmName, []string{"groupId", *group.ID}, func(user kc.UserRepresentation) {
+			if user.ID != nil {
+				count.Add(migratedUsers[*user.ID])
+			}
+		})
+		if err != nil {
+			ec.logger.Warn(ctx, "msg", "Can't compute migration report", "err", err.Error(), "realm", realmName, "group", *group.Name)
+			return nil, err
+		}
+		report.summary.Groups[*group.Name] = count
+	}
+
+	report.computedAt = time.Now()
+
+	ec.migrationReports.mutex.Lock()
+	ec.migrationReports.reports[realmName] = report
+	ec.migrationReports.mutex.Unlock()
+
+	return report, nil
+}
+
+// forEachBriefUser reads by pages the brief representations (without attributes) of the users matching the given query
+func (ec *component) forEachBriefUser(accessToken string, ctxRealm string, realmName string, paramKV []string, f func(kc.UserRepresentation)) error {
+	for first := 0; ; first += briefUsersPageSize {
+		var pageParamKV = append(append([]string{}, paramKV...), "briefRepresentation", "true",
+			PrmQryFirst, strconv.Itoa(first), PrmQryMax, strconv.Itoa(briefUsersPageSize))
+		usersKc, err := ec.keycloakClient.GetUsers(accessToken, ctxRealm, realmName, pageParamKV...)
+		if err != nil {
+			return err
+		}
+		for _, user := range usersKc.Users {
+			f(user)
+		}
+		if len(usersKc.Users) < briefUsersPageSize {
+			return nil
+		}
+	}
+}
+
+func (r *migrationReport) getSummary(status string) api.MigrationReportSummaryRepresentation {
+	var computedAt = r.computedAt.Unix()
+	var res = r.summary
+	res.Status = status
+	res.ComputedAt = &computedAt
+	return res
+}
+
+// GetInactiveUsers gives a page of the users who did not log in successfully since the given number of days,
+// or who never logged in and were created before this period. A list computed less than inactiveUsersCacheTTL ago is used
+func (ec *component) GetInactiveUsers(ctx context.Context, realmName string, days int, first int, max int) (api.InactiveUsersPageRepresentation, error) {
+	var report, ok = ec.inactiveUsers.get(realmName, days)
+	if !ok {
+		var inactiveUsers, err = ec.getInactiveUsers(ctx, realmName, days)
+		if err != nil {
+			return api.InactiveUsersPageRepresentation{}, err
+		}
+		report = &inactiveUsersReport{users: inactiveUsers, computedAt: time.Now()}
+		ec.inactiveUsers.put(realmName, days, report)
+	}
+
+	var computedAt = report.computedAt.Unix()
+	var res = api.InactiveUsersPageRepresentation{
+		Users:      []api.InactiveUserRepresentation{},
+		Count:      len(report.users),
+		ComputedAt: &computedAt,
+	}
+	if start, end := getPageBounds(len(report.users), first, max); start < end {
+		res.Users = report.users[start:end]
+	}
+
+	return res, nil
+}
+
+// LockInactiveUsers locks the given users, as reviewed by the operator in the list of inactive users. Users who logged in
+// since the review (or who are no longer inactive for the given number of days) and users already locked are skipped.
+// The inactive users are computed again instead of being read from the cache
+func (ec *component) LockInactiveUsers(ctx context.Context, realmName string, days int, userIDs []string) (api.InactiveUsersLockRepresentation, error) {
+	var inactiveUsers, err = ec.getInactiveUsers(ctx, realmName, days)
+	if err != nil {
+		return api.InactiveUsersLockRepresentation{}, err
+	}
+	// The cached lists would still show the locked users as enabled
+	defer ec.inactiveUsers.invalidate(realmName)
+	var enabledInactiveUsers = make(map[string]bool)
+	for _, user := range inactiveUsers {
+		if user.Enabled {
+			enabledInactiveUsers[user.ID] = true
+		}
+	}
+
+	var res = api.InactiveUsersLockRepresentation{
+		Locked:  []string{},
+		Skipped: []string{},
+		Failed:  []string{},
+	}
+	for _, userID := range userIDs {
+		if !enabledInactiveUsers[userID] {
+			res.Skipped = append(res.Skipped, userID)
+			continue
+		}
+		// A user given twice is only locked once
+		delete(enabledInactiveUsers, userID)
+		if err := ec.managementComponent.LockUser(ctx, realmName, userID); err != nil {
+			ec.logger.Warn(ctx, "msg", "Can't lock inactive user", "err", err.Error(), "realm", realmName, "user", userID)
+			res.Failed = append(res.Failed, userID)
+			continue
+		}
+		res.Locked = append(res.Locked, userID)
+	}
+
+	return res, nil
+}
+
+// getInactiveUsers returns the inactive users of a realm, sorted by last connection (users who never logged in first) then by username
+func (ec *component) getInactiveUsers(ctx context.Context, realmName string, days int) ([]api.InactiveUserRepresentation, error) {
+	var accessToken = ctx.Value(cs.CtContextAccessToken).(string)
+	var ctxRealm = ctx.Value(cs.CtContextRealm).(string)
+
+	// The brief representations of the users give their status and creation date
+	var usersKc []kc.UserRepresentation
+	var err = ec.forEachBriefUser(accessToken, ctxRealm, realmName, nil, func(user kc.UserRepresentation) {
+		usersKc = append(usersKc, user)
+	})
+	if err != nil {
+		ec.logger.Warn(ctx, "err", err.Error())
+		return nil, err
+	}
+
+	lastConnections, err := ec.db.GetLastConnectionPerUser(ctx, realmName)
+	if err != nil {
+		ec.logger.Warn(ctx, "err", err.Error())
+		return nil, err
+	}
+
+	var threshold = time.Now().Add(-time.Duration(days) * 24 * time.Hour).Unix()
+	var res = []api.InactiveUserRepresentation{}
+	for _, user := range usersKc {
+		if user.ID == nil || user.Username == nil {
+			continue
+		}
+		var inactiveUser = api.InactiveUserRepresentation{
+			ID:               *user.ID,
+			Username:         *user.Username,
+			Enabled:          user.Enabled != nil && *user.Enabled,
+			CreatedTimestamp: user.CreatedTimestamp,
+		}
+		if lastConnection, ok := lastConnections[*user.ID]; ok {
+			if lastConnection >= threshold {
+				continue
+			}
+			var value = lastConnection
+			inactiveUser.LastConnection = &value
+		} else if user.CreatedTimestamp != nil && *user.CreatedTimestamp/1000 >= threshold {
+			// Recently created users who did not log in yet are not considered as inactive
+			continue
+		}
+		res = append(res, inactiveUser)
+	}
+
+	sort.SliceStable(res, func(i, j int) bool {
+		var lastI, lastJ int64 = -1, -1
+		if res[i].LastConnection != nil {
+			lastI = *res[i].LastConnection
+		}
+		if res[j].LastConnection != nil {
+			lastJ = *res[j].LastConnection
+		}
+		if lastI != lastJ {
+			return lastI < lastJ
+		}
+		return res[i].Username < res[j].Username
+	})
+
+	return res, nil
+}
+
+// getPageBounds gives the bounds of the requested page in a list of the given size. A max of 0 means no limit
+func getPageBounds(size int, first int, max int) (int, int) {
+	if first >= size {
+		return size, size
+	}
+	var end = size
+	if max > 0 && first+max < end {
+		end = first + max
+	}
+	return first, end
+}
+
 func isMigrated(user kc.UserRepresentation) bool {
 	if user.Attributes == nil {
 		return false
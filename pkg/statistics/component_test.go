@@ -3,11 +3,14 @@ package statistics
 import (
 	"context"
 	"errors"
+	"strconv"
 	"testing"
+	"time"
 
 	cs "github.com/cloudtrust/common-service"
 	"github.com/cloudtrust/common-service/log"
 	api "github.com/cloudtrust/keycloak-bridge/api/statistics"
+	"github.com/cloudtrust/keycloak-bridge/internal/keycloakb"
 	"github.com/cloudtrust/keycloak-bridge/pkg/statistics/mock"
 	kc "github.com/cloudtrust/keycloak-client"
 	"github.com/golang/mock/gomock"
@@ -20,7 +23,7 @@ func executeTest(t *testing.T, tester func(mockDBModule *mock.EventsDBModule, co
 	var mockDBModule = mock.NewEventsDBModule(mockCtrl)
 	var mockKcClient = mock.NewKcClient(mockCtrl)
 	var mockLogger = log.NewNopLogger()
-	tester(mockDBModule, NewComponent(mockDBModule, mockKcClient, mockLogger))
+	tester(mockDBModule, NewComponent(mockDBModule, mockDBModule, mockKcClient, nil, "", nil, nil, []string{"register-realm"}, mockLogger))
 }
 
 func TestGetStatistics(t *testing.T) {
@@ -30,7 +33,7 @@ func TestGetStatistics(t *testing.T) {
 	var mockDBModule = mock.NewEventsDBModule(mockCtrl)
 	var mockKcClient = mock.NewKcClient(mockCtrl)
 	var mockLogger = log.NewNopLogger()
-	component := NewComponent(mockDBModule, mockKcClient, mockLogger)
+	component := NewComponent(mockDBModule, mockDBModule, mockKcClient, nil, "", nil, nil, nil, mockLogger)
 
 	var errDbModule = errors.New("Dummy error in db module")
 	var realm = "the_realm_name"
@@ -73,7 +76,7 @@ func TestGetStatisticsUsers(t *testing.T) {
 	var mockDBModule = mock.NewEventsDBModule(mockCtrl)
 	var mockKcClient = mock.NewKcClient(mockCtrl)
 	var mockLogger = log.NewNopLogger()
-	component := NewComponent(mockDBModule, mockKcClient, mockLogger)
+	component := NewComponent(mockDBModule, mockDBModule, mockKcClient, nil, "", nil, nil, nil, mockLogger)
 
 	var realm = "the_realm_name"
 	var accessToken = "TOKEN=="
@@ -113,7 +116,7 @@ func TestGetStatisticsAuthenticators(t *testing.T) {
 	var mockDBModule = mock.NewEventsDBModule(mockCtrl)
 	var mockKcClient = mock.NewKcClient(mockCtrl)
 	var mockLogger = log.NewNopLogger()
-	component := NewComponent(mockDBModule, mockKcClient, mockLogger)
+	component := NewComponent(mockDBModule, mockDBModule, mockKcClient, nil, "", nil, nil, nil, mockLogger)
 
 	var realm = "the_realm_name"
 	var accessToken = "TOKEN=="
@@ -147,7 +150,7 @@ func TestGetStatisticsAuthentications(t *testing.T) {
 	var mockDBModule = mock.NewEventsDBModule(mockCtrl)
 	var mockKcClient = mock.NewKcClient(mockCtrl)
 	var mockLogger = log.NewNopLogger()
-	component := NewComponent(mockDBModule, mockKcClient, mockLogger)
+	component := NewComponent(mockDBModule, mockDBModule, mockKcClient, nil, "", nil, nil, nil, mockLogger)
 
 	var timeshift = 0
 	var realm = "the_realm_name"
@@ -202,7 +205,7 @@ func TestGetStatisticsAuthenticationsLog(t *testing.T) {
 	var mockDBModule = mock.NewEventsDBModule(mockCtrl)
 	var mockKcClient = mock.NewKcClient(mockCtrl)
 	var mockLogger = log.NewNopLogger()
-	component := NewComponent(mockDBModule, mockKcClient, mockLogger)
+	component := NewComponent(mockDBModule, mockDBModule, mockKcClient, nil, "", nil, nil, nil, mockLogger)
 
 	var realm = "the_realm_name"
 	var accessToken = "TOKEN=="
@@ -241,7 +244,7 @@ func TestGetActions(t *testing.T) {
 	var mockDBModule = mock.NewEventsDBModule(mockCtrl)
 	var mockKcClient = mock.NewKcClient(mockCtrl)
 	var mockLogger = log.NewNopLogger()
-	component := NewComponent(mockDBModule, mockKcClient, mockLogger)
+	component := NewComponent(mockDBModule, mockDBModule, mockKcClient, nil, "", nil, nil, nil, mockLogger)
 
 	var realm = "the_realm_name"
 	var accessToken = "TOKEN=="
@@ -253,3 +256,518 @@ func TestGetActions(t *testing.T) {
 	assert.Nil(t, err)
 	assert.Equal(t, len(actions), len(res))
 }
+
+func TestGetStatisticsFailures(t *testing.T) {
+	var realm = "the_realm_name"
+	var ctx = context.TODO()
+	var errDbModule = errors.New("Dummy error in db module")
+
+	t.Run("db.GetTotalEventsCount fails", func(t *testing.T) {
+		executeTest(t, func(mockDBModule *mock.EventsDBModule, component Component) {
+			mockDBModule.EXPECT().GetTotalEventsCount(ctx, realm, "LOGON_ERROR", "12 HOUR").Return(int64(0), errDbModule)
+			_, err := component.GetStatisticsFailures(ctx, realm)
+			assert.Equal(t, errDbModule, err)
+		})
+	})
+	t.Run("success", func(t *testing.T) {
+		executeTest(t, func(mockDBModule *mock.EventsDBModule, component Component) {
+			for _, ctEventType := range []string{"LOGON_ERROR", "TEMPORARILY_LOCKED"} {
+				mockDBModule.EXPECT().GetTotalEventsCount(ctx, realm, ctEventType, "12 HOUR").Return(int64(12), nil)
+				mockDBModule.EXPECT().GetTotalEventsCount(ctx, realm, ctEventType, "1 DAY").Return(int64(1), nil)
+				mockDBModule.EXPECT().GetTotalEventsCount(ctx, realm, ctEventType, "1 WEEK").Return(int64(7), nil)
+				mockDBModule.EXPECT().GetTotalEventsCount(ctx, realm, ctEventType, "1 MONTH").Return(int64(30), nil)
+				mockDBModule.EXPECT().GetTotalEventsCount(ctx, realm, ctEventType, "1 YEAR").Return(int64(365), nil)
+			}
+			var expected = api.StatisticsConnectionsRepresentation{LastTwelveHours: 12, LastDay: 1, LastWeek: 7, LastMonth: 30, LastYear: 365}
+			res, err := component.GetStatisticsFailures(ctx, realm)
+			assert.Nil(t, err)
+			assert.Equal(t, expected, res.FailedAuthentications)
+			assert.Equal(t, expected, res.Lockouts)
+		})
+	})
+}
+
+func TestGetStatisticsFailedAuthenticationsAndLockouts(t *testing.T) {
+	var realm = "the_realm_name"
+	var ctx = context.TODO()
+	var timeshift = "+60"
+	var stats = [][]int64{{11, 3}, {12, 5}}
+
+	t.Run("invalid unit", func(t *testing.T) {
+		executeTest(t, func(mockDBModule *mock.EventsDBModule, component Component) {
+			_, err := component.GetStatisticsFailedAuthentications(ctx, realm, "weeks", nil)
+			assert.NotNil(t, err)
+		})
+	})
+	t.Run("invalid timeshift", func(t *testing.T) {
+		executeTest(t, func(mockDBModule *mock.EventsDBModule, component Component) {
+			var invalidTimeshift = "abc"
+			_, err := component.GetStatisticsLockouts(ctx, realm, "hours", &invalidTimeshift)
+			assert.NotNil(t, err)
+		})
+	})
+	t.Run("db fails", func(t *testing.T) {
+		executeTest(t, func(mockDBModule *mock.EventsDBModule, component Component) {
+			mockDBModule.EXPECT().GetTotalEventsMonthsCount(ctx, realm, "TEMPORARILY_LOCKED", gomock.Any(), 0).Return(nil, errors.New("error"))
+			_, err := component.GetStatisticsLockouts(ctx, realm, "months", nil)
+			assert.NotNil(t, err)
+		})
+	})
+	t.Run("failed authentications by hours", func(t *testing.T) {
+		executeTest(t, func(mockDBModule *mock.EventsDBModule, component Component) {
+			mockDBModule.EXPECT().GetTotalEventsHoursCount(ctx, realm, "LOGON_ERROR", gomock.Any(), 60).Return(stats, nil)
+			res, err := component.GetStatisticsFailedAuthentications(ctx, realm, "hours", &timeshift)
+			assert.Nil(t, err)
+			assert.Equal(t, stats, res)
+		})
+	})
+	t.Run("lockouts by days", func(t *testing.T) {
+		executeTest(t, func(mockDBModule *mock.EventsDBModule, component Component) {
+			mockDBModule.EXPECT().GetTotalEventsDaysCount(ctx, realm, "TEMPORARILY_LOCKED", gomock.Any(), 0).Return(stats, nil)
+			res, err := component.GetStatisticsLockouts(ctx, realm, "days", nil)
+			assert.Nil(t, err)
+			assert.Equal(t, stats, res)
+		})
+	})
+}
+
+func TestGetStatisticsFailuresBreakdown(t *testing.T) {
+	var realm = "the_realm_name"
+	var ctx = context.TODO()
+	var byErrorCode = map[string]int64{"invalid_user_credentials": 12, "user_temporarily_disabled": 2}
+	var byClientID = map[string]int64{"account": 10, "backoffice": 4}
+
+	t.Run("invalid unit", func(t *testing.T) {
+		executeTest(t, func(mockDBModule *mock.EventsDBModule, component Component) {
+			_, err := component.GetStatisticsFailuresBreakdown(ctx, realm, "weeks")
+			assert.NotNil(t, err)
+		})
+	})
+	t.Run("GetFailuresCountByErrorCode fails", func(t *testing.T) {
+		executeTest(t, func(mockDBModule *mock.EventsDBModule, component Component) {
+			mockDBModule.EXPECT().GetFailuresCountByErrorCode(ctx, realm, "1 DAY").Return(nil, errors.New("error"))
+			_, err := component.GetStatisticsFailuresBreakdown(ctx, realm, "hours")
+			assert.NotNil(t, err)
+		})
+	})
+	t.Run("GetFailuresCountByClientID fails", func(t *testing.T) {
+		executeTest(t, func(mockDBModule *mock.EventsDBModule, component Component) {
+			mockDBModule.EXPECT().GetFailuresCountByErrorCode(ctx, realm, "1 MONTH").Return(byErrorCode, nil)
+			mockDBModule.EXPECT().GetFailuresCountByClientID(ctx, realm, "1 MONTH").Return(nil, errors.New("error"))
+			_, err := component.GetStatisticsFailuresBreakdown(ctx, realm, "days")
+			assert.NotNil(t, err)
+		})
+	})
+	t.Run("success", func(t *testing.T) {
+		executeTest(t, func(mockDBModule *mock.EventsDBModule, component Component) {
+			mockDBModule.EXPECT().GetFailuresCountByErrorCode(ctx, realm, "1 YEAR").Return(byErrorCode, nil)
+			mockDBModule.EXPECT().GetFailuresCountByClientID(ctx, realm, "1 YEAR").Return(byClientID, nil)
+			res, err := component.GetStatisticsFailuresBreakdown(ctx, realm, "months")
+			assert.Nil(t, err)
+			assert.Equal(t, byErrorCode, res.ByErrorCode)
+			assert.Equal(t, byClientID, res.ByClientID)
+		})
+	})
+}
+
+func TestGetStatisticsAuthenticationsRatio(t *testing.T) {
+	var realm = "the_realm_name"
+	var ctx = context.TODO()
+
+	t.Run("db fails", func(t *testing.T) {
+		executeTest(t, func(mockDBModule *mock.EventsDBModule, component Component) {
+			mockDBModule.EXPECT().GetTotalEventsHoursCount(ctx, realm, "LOGON_OK", gomock.Any(), 0).Return([][]int64{{1, 2}}, nil)
+			mockDBModule.EXPECT().GetTotalEventsHoursCount(ctx, realm, "LOGON_ERROR", gomock.Any(), 0).Return(nil, errors.New("error"))
+			_, err := component.GetStatisticsAuthenticationsRatio(ctx, realm, "hours", nil)
+			assert.NotNil(t, err)
+		})
+	})
+	t.Run("success", func(t *testing.T) {
+		executeTest(t, func(mockDBModule *mock.EventsDBModule, component Component) {
+			mockDBModule.EXPECT().GetTotalEventsHoursCount(ctx, realm, "LOGON_OK", gomock.Any(), 0).Return([][]int64{{1, 6}, {2, 0}, {3, 0}}, nil)
+			mockDBModule.EXPECT().GetTotalEventsHoursCount(ctx, realm, "LOGON_ERROR", gomock.Any(), 0).Return([][]int64{{1, 1}, {2, 0}, {3, 3}}, nil)
+			mockDBModule.EXPECT().GetTotalEventsHoursCount(ctx, realm, "TEMPORARILY_LOCKED", gomock.Any(), 0).Return([][]int64{{1, 1}, {2, 0}, {3, 0}}, nil)
+			res, err := component.GetStatisticsAuthenticationsRatio(ctx, realm, "hours", nil)
+			assert.Nil(t, err)
+			assert.Equal(t, []api.StatisticsRatioRepresentation{
+				{Period: 1, Successes: 6, Failures: 2, SuccessRatio: 0.75},
+				{Period: 2, Successes: 0, Failures: 0, SuccessRatio: 0},
+				{Period: 3, Successes: 0, Failures: 3, SuccessRatio: 0},
+			}, res)
+		})
+	})
+}
+
+func TestGetStatisticsRegistrations(t *testing.T) {
+	var realm = "register-realm"
+	var ctx = context.TODO()
+	var now = time.Now().Unix()
+	var day = int64(24 * 3600)
+
+	t.Run("not a register realm", func(t *testing.T) {
+		executeTest(t, func(mockDBModule *mock.EventsDBModule, component Component) {
+			_, err := component.GetStatisticsRegistrations(ctx, "other-realm", "days", nil)
+			assert.NotNil(t, err)
+		})
+	})
+	t.Run("invalid unit", func(t *testing.T) {
+		executeTest(t, func(mockDBModule *mock.EventsDBModule, component Component) {
+			_, err := component.GetStatisticsRegistrations(ctx, realm, "weeks", nil)
+			assert.NotNil(t, err)
+		})
+	})
+	t.Run("db fails", func(t *testing.T) {
+		executeTest(t, func(mockDBModule *mock.EventsDBModule, component Component) {
+			mockDBModule.EXPECT().GetRegistrationSteps(ctx, realm, gomock.Any(), gomock.Any()).Return(nil, errors.New("error"))
+			_, err := component.GetStatisticsRegistrations(ctx, realm, "days", nil)
+			assert.NotNil(t, err)
+		})
+	})
+	t.Run("success", func(t *testing.T) {
+		executeTest(t, func(mockDBModule *mock.EventsDBModule, component Component) {
+			var users = []keycloakb.UserRegistrationSteps{
+				{UserID: "1", Steps: map[string]int64{"REGISTER_USER": now - 20*day, "EMAIL_CONFIRMED": now - 20*day + 60, "PASSWORD_RESET": now - 20*day + 120, "VALIDATE_USER": now - 10*day}},
+				{UserID: "2", Steps: map[string]int64{"REGISTER_USER": now - 15*day, "EMAIL_CONFIRMED": now - 15*day + 180}},
+				{UserID: "3", Steps: map[string]int64{"REGISTER_USER": now - 2, "EMAIL_CONFIRMED": now - 1}},
+				{UserID: "4", Steps: map[string]int64{"REGISTER_USER": now}},
+			}
+			mockDBModule.EXPECT().GetRegistrationSteps(ctx, realm, gomock.Any(), gomock.Any()).Return(users, nil)
+			res, err := component.GetStatisticsRegistrations(ctx, realm, "days", nil)
+			assert.Nil(t, err)
+			assert.Equal(t, int64(1), res.Abandoned)
+			assert.Len(t, res.Steps, 5)
+			assert.Equal(t, "REGISTER_USER", res.Steps[0].Name)
+			assert.Equal(t, int64(4), res.Steps[0].Count)
+			assert.Equal(t, 1.0, res.Steps[0].ConversionRate)
+			assert.Nil(t, res.Steps[0].MedianDelay)
+			assert.Equal(t, int64(3), res.Steps[1].Count)
+			assert.Equal(t, 0.75, res.Steps[1].ConversionRate)
+			assert.Equal(t, int64(60), *res.Steps[1].MedianDelay)
+			assert.Equal(t, int64(1), res.Steps[3].Count)
+			assert.Equal(t, int64(0), res.Steps[4].Count)
+
+			var registered int64
+			for _, period := range res.Periods {
+				registered += period.Steps[0].Count
+			}
+			assert.Equal(t, int64(4), registered)
+			assert.Equal(t, int64(time.Now().UTC().Day()), res.Periods[len(res.Periods)-1].Period)
+			assert.Equal(t, int64(2), res.Periods[len(res.Periods)-1].Steps[0].Count)
+		})
+	})
+}
+
+func TestGetPeriods(t *testing.T) {
+	var location = time.FixedZone("test", 60*60)
+	var now = time.Date(2020, time.March, 10, 15, 30, 0, 0, location)
+
+	t.Run("hours", func(t *testing.T) {
+		starts, end, labels, err := getPeriods(now, "hours")
+		assert.Nil(t, err)
+		assert.Len(t, starts, 24)
+		assert.Equal(t, time.Date(2020, time.March, 10, 16, 0, 0, 0, location).UTC(), end)
+		assert.Equal(t, int64(16), labels[0])
+		assert.Equal(t, int64(15), labels[23])
+	})
+	t.Run("days", func(t *testing.T) {
+		starts, _, labels, err := getPeriods(now, "days")
+		assert.Nil(t, err)
+		assert.Len(t, starts, 29)
+		assert.Equal(t, int64(11), labels[0])
+		assert.Equal(t, int64(10), labels[28])
+	})
+	t.Run("months", func(t *testing.T) {
+		starts, _, labels, err := getPeriods(now, "months")
+		assert.Nil(t, err)
+		assert.Len(t, starts, 12)
+		assert.Equal(t, int64(4), labels[0])
+		assert.Equal(t, int64(3), labels[11])
+	})
+	t.Run("invalid unit", func(t *testing.T) {
+		_, _, _, err := getPeriods(now, "weeks")
+		assert.NotNil(t, err)
+	})
+}
+
+func TestMedian(t *testing.T) {
+	assert.Nil(t, median(nil))
+	assert.Equal(t, int64(3), *median([]int64{5, 1, 3}))
+	assert.Equal(t, int64(4), *median([]int64{5, 1, 3, 8}))
+}
+
+func TestMigrationReport(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	var mockDBModule = mock.NewEventsDBModule(mockCtrl)
+	var mockKcClient = mock.NewKcClient(mockCtrl)
+	var mockTokenProvider = mock.NewTokenProvider(mockCtrl)
+	var mockLogger = log.NewNopLogger()
+
+	var realm = "the_realm_name"
+	var accessToken = "TOKEN=="
+	var technicalRealm = "technical"
+	var serviceToken = "SERVICE-TOKEN=="
+	var ctx = context.WithValue(context.Background(), cs.CtContextAccessToken, accessToken)
+	ctx = context.WithValue(ctx, cs.CtContextRealm, realm)
+
+	var migratedAttributes = kc.Attributes{"migrated": []string{"true"}}
+	var newUser = func(username string, migrated bool) kc.UserRepresentation {
+		var userID = "id-" + username
+		var user = kc.UserRepresentation{ID: &userID, Username: &username}
+		if migrated {
+			user.Attributes = &migratedAttributes
+		}
+		return user
+	}
+	var count = 3
+	var allUsers = kc.UsersPageRepresentation{Users: []kc.UserRepresentation{newUser("a", true), newUser("b", false), newUser("c", true)}, Count: &count}
+	var groupID = "group-id"
+	var groupName = "group"
+	var groups = []kc.GroupRepresentation{{ID: &groupID, Name: &groupName}}
+	// Users are read in their brief representation, without attributes: the migrated users are searched by Keycloak
+	var briefUsers = kc.UsersPageRepresentation{Users: []kc.UserRepresentation{newUser("a", false), newUser("b", false), newUser("c", false)}}
+	var migratedUsers = kc.UsersPageRepresentation{Users: []kc.UserRepresentation{newUser("a", false), newUser("c", false)}}
+	var groupUsers = kc.UsersPageRepresentation{Users: []kc.UserRepresentation{newUser("a", false), newUser("b", false)}}
+	var expectBriefUsers = func(token string, reqRealm string, times int) {
+		mockKcClient.EXPECT().GetUsers(token, reqRealm, realm, "q", "migrated:true", "briefRepresentation", "true", "first", "0", "max", "500").Return(migratedUsers, nil).Times(times)
+		mockKcClient.EXPECT().GetUsers(token, reqRealm, realm, "briefRepresentation", "true", "first", "0", "max", "500").Return(briefUsers, nil).Times(times)
+	}
+
+	t.Run("Summary - GetUsers fails", func(t *testing.T) {
+		var component = NewComponent(mockDBModule, mockDBModule, mockKcClient, nil, "", nil, nil, nil, mockLogger)
+		mockKcClient.EXPECT().GetUsers(accessToken, realm, realm, "q", "migrated:true", "briefRepresentation", "true", "first", "0", "max", "500").
+			Return(kc.UsersPageRepresentation{}, errors.New("error"))
+		_, err := component.GetMigrationReportSummary(ctx, realm, false, false)
+		assert.NotNil(t, err)
+	})
+	t.Run("Summary - users read by pages", func(t *testing.T) {
+		var component = NewComponent(mockDBModule, mockDBModule, mockKcClient, nil, "", nil, nil, nil, mockLogger)
+		var fullPage = kc.UsersPageRepresentation{Users: make([]kc.UserRepresentation, briefUsersPageSize)}
+		for i := range fullPage.Users {
+			fullPage.Users[i] = newUser("user"+strconv.Itoa(i), false)
+		}
+		mockKcClient.EXPECT().GetUsers(accessToken, realm, realm, "q", "migrated:true", "briefRepresentation", "true", "first", "0", "max", "500").Return(migratedUsers, nil)
+		mockKcClient.EXPECT().GetUsers(accessToken, realm, realm, "briefRepresentation", "true", "first", "0", "max", "500").Return(fullPage, nil)
+		mockKcClient.EXPECT().GetUsers(accessToken, realm, realm, "briefRepresentation", "true", "first", "500", "max", "500").Return(briefUsers, nil)
+		mockKcClient.EXPECT().GetGroups(accessToken, realm).Return([]kc.GroupRepresentation{}, nil)
+		res, err := component.GetMigrationReportSummary(ctx, realm, false, false)
+		assert.Nil(t, err)
+		assert.Equal(t, int64(2), res.Migrated)
+		assert.Equal(t, int64(briefUsersPageSize+1), res.NotMigrated)
+	})
+	t.Run("Summary - GetGroups fails", func(t *testing.T) {
+		var component = NewComponent(mockDBModule, mockDBModule, mockKcClient, nil, "", nil, nil, nil, mockLogger)
+		expectBriefUsers(accessToken, realm, 1)
+		mockKcClient.EXPECT().GetGroups(accessToken, realm).Return(nil, errors.New("error"))
+		_, err := component.GetMigrationReportSummary(ctx, realm, false, false)
+		assert.NotNil(t, err)
+	})
+	t.Run("Summary - success then filtered users from cache", func(t *testing.T) {
+		var component = NewComponent(mockDBModule, mockDBModule, mockKcClient, nil, "", nil, nil, nil, mockLogger)
+		expectBriefUsers(accessToken, realm, 1)
+		mockKcClient.EXPECT().GetGroups(accessToken, realm).Return(groups, nil)
+		mockKcClient.EXPECT().GetUsers(accessToken, realm, realm, "groupId", groupID, "briefRepresentation", "true", "first", "0", "max", "500").Return(groupUsers, nil)
+		res, err := component.GetMigrationReportSummary(ctx, realm, false, false)
+		assert.Nil(t, err)
+		assert.Equal(t, api.MigrationReportStatusDone, res.Status)
+		assert.NotNil(t, res.ComputedAt)
+		assert.Equal(t, int64(2), res.Migrated)
+		assert.Equal(t, int64(1), res.NotMigrated)
+		assert.Equal(t, api.MigrationReportCountRepresentation{Migrated: 1, NotMigrated: 1}, res.Groups[groupName])
+
+		var migrated = true
+		page, err := component.GetMigrationReportUsers(ctx, realm, &migrated, 1, 10)
+		assert.Nil(t, err)
+		assert.Equal(t, 2, page.Count)
+		assert.Equal(t, []api.MigrationReportUserRepresentation{{Username: "c", Migrated: true}}, page.Users)
+		assert.Equal(t, res.ComputedAt, page.ComputedAt)
+
+		// Async mode returns the cached report
+		res, err = component.GetMigrationReportSummary(ctx, realm, true, false)
+		assert.Nil(t, err)
+		assert.Equal(t, api.MigrationReportStatusDone, res.Status)
+		assert.Equal(t, int64(2), res.Migrated)
+
+		// Sync mode also returns the cached report without calling Keycloak
+		res, err = component.GetMigrationReportSummary(ctx, realm, false, false)
+		assert.Nil(t, err)
+		assert.Equal(t, int64(2), res.Migrated)
+	})
+	t.Run("Summary - expired report is computed again", func(t *testing.T) {
+		var statsComponent = NewComponent(mockDBModule, mockDBModule, mockKcClient, nil, "", nil, nil, nil, mockLogger)
+		var noGroups = []kc.GroupRepresentation{}
+		expectBriefUsers(accessToken, realm, 2)
+		mockKcClient.EXPECT().GetGroups(accessToken, realm).Return(noGroups, nil).Times(2)
+		_, err := statsComponent.GetMigrationReportSummary(ctx, realm, false, false)
+		assert.Nil(t, err)
+
+		var cache = statsComponent.(*component).migrationReports
+		cache.reports[realm].computedAt = time.Now().Add(-migrationReportCacheTTL - time.Minute)
+
+		res, err := statsComponent.GetMigrationReportSummary(ctx, realm, false, false)
+		assert.Nil(t, err)
+		assert.Equal(t, api.MigrationReportStatusDone, res.Status)
+	})
+	t.Run("Summary - async without cached report", func(t *testing.T) {
+		var component = NewComponent(mockDBModule, mockDBModule, mockKcClient, mockTokenProvider, technicalRealm, nil, nil, nil, mockLogger)
+		var done = make(chan struct{})
+		mockTokenProvider.EXPECT().ProvideToken(gomock.Any()).Return(serviceToken, nil)
+		mockKcClient.EXPECT().GetUsers(serviceToken, technicalRealm, realm, "q", "migrated:true", "briefRepresentation", "true", "first", "0", "max", "500").DoAndReturn(func(_, _, _ string, _ ...string) (kc.UsersPageRepresentation, error) {
+			defer close(done)
+			return kc.UsersPageRepresentation{}, errors.New("error")
+		})
+		res, err := component.GetMigrationReportSummary(ctx, realm, true, false)
+		assert.Nil(t, err)
+		assert.Equal(t, api.MigrationReportStatusPending, res.Status)
+		assert.Nil(t, res.ComputedAt)
+		<-done
+	})
+	t.Run("Summary - async, can't get a service token", func(t *testing.T) {
+		var component = NewComponent(mockDBModule, mockDBModule, mockKcClient, mockTokenProvider, technicalRealm, nil, nil, nil, mockLogger)
+		var done = make(chan struct{})
+		mockTokenProvider.EXPECT().ProvideToken(gomock.Any()).DoAndReturn(func(_ context.Context) (string, error) {
+			defer close(done)
+			return "", errors.New("error")
+		})
+		res, err := component.GetMigrationReportSummary(ctx, realm, true, false)
+		assert.Nil(t, err)
+		assert.Equal(t, api.MigrationReportStatusPending, res.Status)
+		<-done
+	})
+	t.Run("Users - no filter", func(t *testing.T) {
+		var component = NewComponent(mockDBModule, mockDBModule, mockKcClient, nil, "", nil, nil, nil, mockLogger)
+		mockKcClient.EXPECT().GetUsers(accessToken, realm, realm, "first", "10", "max", "20").Return(allUsers, nil)
+		page, err := component.GetMigrationReportUsers(ctx, realm, nil, 10, 20)
+		assert.Nil(t, err)
+		assert.Equal(t, 3, page.Count)
+		assert.Len(t, page.Users, 3)
+		assert.Nil(t, page.ComputedAt)
+	})
+	t.Run("Users - no filter, GetUsers fails", func(t *testing.T) {
+		var component = NewComponent(mockDBModule, mockDBModule, mockKcClient, nil, "", nil, nil, nil, mockLogger)
+		mockKcClient.EXPECT().GetUsers(accessToken, realm, realm, "first", "0", "max", "20").Return(kc.UsersPageRepresentation{}, errors.New("error"))
+		_, err := component.GetMigrationReportUsers(ctx, realm, nil, 0, 20)
+		assert.NotNil(t, err)
+	})
+	t.Run("Users - filter without cached report", func(t *testing.T) {
+		var component = NewComponent(mockDBModule, mockDBModule, mockKcClient, nil, "", nil, nil, nil, mockLogger)
+		var migrated = false
+		expectBriefUsers(accessToken, realm, 1)
+		mockKcClient.EXPECT().GetGroups(accessToken, realm).Return([]kc.GroupRepresentation{}, nil)
+		page, err := component.GetMigrationReportUsers(ctx, realm, &migrated, 5, 10)
+		assert.Nil(t, err)
+		assert.Equal(t, 1, page.Count)
+		assert.Len(t, page.Users, 0)
+	})
+}
+
+func TestInactiveUsers(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	var mockDBModule = mock.NewEventsDBModule(mockCtrl)
+	var mockKcClient = mock.NewKcClient(mockCtrl)
+	var mockManagement = mock.NewManagementComponent(mockCtrl)
+	var mockLogger = log.NewNopLogger()
+	var statsComponent = NewComponent(mockDBModule, mockDBModule, mockKcClient, nil, "", mockManagement, nil, nil, mockLogger)
+
+	var realm = "the_realm_name"
+	var accessToken = "TOKEN=="
+	var ctx = context.WithValue(context.Background(), cs.CtContextAccessToken, accessToken)
+	ctx = context.WithValue(ctx, cs.CtContextRealm, realm)
+
+	var now = time.Now()
+	var oldCreation = now.Add(-100*24*time.Hour).Unix() * 1000
+	var recentCreation = now.Add(-time.Hour).Unix() * 1000
+	var newUser = func(id string, enabled bool, createdTimestamp int64) kc.UserRepresentation {
+		var username = "user-" + id
+		return kc.UserRepresentation{ID: &id, Username: &username, Enabled: &enabled, CreatedTimestamp: &createdTimestamp}
+	}
+	var users = kc.UsersPageRepresentation{Users: []kc.UserRepresentation{
+		newUser("active", true, oldCreation),
+		newUser("inactive", true, oldCreation),
+		newUser("never", true, oldCreation),
+		newUser("new", true, recentCreation),
+		newUser("disabled", false, oldCreation),
+	}}
+	var inactiveTime = now.Add(-60 * 24 * time.Hour).Unix()
+	var lastConnections = map[string]int64{
+		"active":   now.Add(-time.Hour).Unix(),
+		"inactive": inactiveTime,
+		"disabled": inactiveTime - 10,
+	}
+
+	t.Run("GetUsers fails", func(t *testing.T) {
+		mockKcClient.EXPECT().GetUsers(accessToken, realm, realm, "briefRepresentation", "true", "first", "0", "max", "500").Return(kc.UsersPageRepresentation{}, errors.New("error"))
+		_, err := statsComponent.GetInactiveUsers(ctx, realm, 30, 0, 10)
+		assert.NotNil(t, err)
+	})
+	t.Run("GetLastConnectionPerUser fails", func(t *testing.T) {
+		mockKcClient.EXPECT().GetUsers(accessToken, realm, realm, "briefRepresentation", "true", "first", "0", "max", "500").Return(users, nil)
+		mockDBModule.EXPECT().GetLastConnectionPerUser(ctx, realm).Return(nil, errors.New("error"))
+		_, err := statsComponent.GetInactiveUsers(ctx, realm, 30, 0, 10)
+		assert.NotNil(t, err)
+	})
+	t.Run("Success", func(t *testing.T) {
+		mockKcClient.EXPECT().GetUsers(accessToken, realm, realm, "briefRepresentation", "true", "first", "0", "max", "500").Return(users, nil)
+		mockDBModule.EXPECT().GetLastConnectionPerUser(ctx, realm).Return(lastConnections, nil)
+		res, err := statsComponent.GetInactiveUsers(ctx, realm, 30, 0, 10)
+		assert.Nil(t, err)
+		assert.Equal(t, 3, res.Count)
+		assert.Len(t, res.Users, 3)
+		assert.Equal(t, "never", res.Users[0].ID)
+		assert.Nil(t, res.Users[0].LastConnection)
+		assert.Equal(t, "disabled", res.Users[1].ID)
+		assert.False(t, res.Users[1].Enabled)
+		assert.Equal(t, "inactive", res.Users[2].ID)
+		assert.Equal(t, inactiveTime, *res.Users[2].LastConnection)
+	})
+	t.Run("Paging from cache", func(t *testing.T) {
+		res, err := statsComponent.GetInactiveUsers(ctx, realm, 30, 2, 10)
+		assert.Nil(t, err)
+		assert.Equal(t, 3, res.Count)
+		assert.Len(t, res.Users, 1)
+		assert.Equal(t, "inactive", res.Users[0].ID)
+		assert.NotNil(t, res.ComputedAt)
+	})
+	t.Run("Expired list is computed again", func(t *testing.T) {
+		var cache = statsComponent.(*component).inactiveUsers
+		cache.reports[inactiveUsersKey{realm: realm, days: 30}].computedAt = now.Add(-inactiveUsersCacheTTL - time.Minute)
+		mockKcClient.EXPECT().GetUsers(accessToken, realm, realm, "briefRepresentation", "true", "first", "0", "max", "500").Return(users, nil)
+		mockDBModule.EXPECT().GetLastConnectionPerUser(ctx, realm).Return(lastConnections, nil)
+		res, err := statsComponent.GetInactiveUsers(ctx, realm, 30, 0, 10)
+		assert.Nil(t, err)
+		assert.Equal(t, 3, res.Count)
+	})
+	t.Run("Lock - GetUsers fails", func(t *testing.T) {
+		mockKcClient.EXPECT().GetUsers(accessToken, realm, realm, "briefRepresentation", "true", "first", "0", "max", "500").Return(kc.UsersPageRepresentation{}, errors.New("error"))
+		_, err := statsComponent.LockInactiveUsers(ctx, realm, 30, []string{"inactive"})
+		assert.NotNil(t, err)
+	})
+	t.Run("Lock - only the reviewed users still inactive and enabled are locked", func(t *testing.T) {
+		mockKcClient.EXPECT().GetUsers(accessToken, realm, realm, "briefRepresentation", "true", "first", "0", "max", "500").Return(users, nil)
+		mockDBModule.EXPECT().GetLastConnectionPerUser(ctx, realm).Return(lastConnections, nil)
+		mockManagement.EXPECT().LockUser(ctx, realm, "never").Return(nil)
+		mockManagement.EXPECT().LockUser(ctx, realm, "inactive").Return(errors.New("error"))
+		// "active" logged in since the review, "disabled" is already locked
+		res, err := statsComponent.LockInactiveUsers(ctx, realm, 30, []string{"never", "active", "inactive", "disabled", "unknown", "never"})
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"never"}, res.Locked)
+		assert.Equal(t, []string{"active", "disabled", "unknown", "never"}, res.Skipped)
+		assert.Equal(t, []string{"inactive"}, res.Failed)
+
+		// The cached list no longer shows the locked users as enabled
+		_, ok := statsComponent.(*component).inactiveUsers.get(realm, 30)
+		assert.False(t, ok)
+	})
+}
+
+func TestGetPageBounds(t *testing.T) {
+	var start, end = getPageBounds(5, 1, 2)
+	assert.Equal(t, []int{1, 3}, []int{start, end})
+	start, end = getPageBounds(5, 4, 0)
+	assert.Equal(t, []int{4, 5}, []int{start, end})
+	start, end = getPageBounds(5, 7, 2)
+	assert.Equal(t, start, end)
+}
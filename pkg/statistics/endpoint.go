@@ -2,24 +2,47 @@ package statistics
 
 import (
 	"context"
+	"encoding/json"
+	"strconv"
 
 	cs "github.com/cloudtrust/common-service"
 	errorhandler "github.com/cloudtrust/common-service/errors"
+	api "github.com/cloudtrust/keycloak-bridge/api/statistics"
 	msg "github.com/cloudtrust/keycloak-bridge/internal/constants"
 	"github.com/go-kit/kit/endpoint"
 )
 
 // Endpoints exposed for path /events
 type Endpoints struct {
-	GetActions                      endpoint.Endpoint
-	GetStatistics                   endpoint.Endpoint
-	GetStatisticsUsers              endpoint.Endpoint
-	GetStatisticsAuthenticators     endpoint.Endpoint
-	GetStatisticsAuthentications    endpoint.Endpoint
-	GetStatisticsAuthenticationsLog endpoint.Endpoint
-	GetMigrationReport              endpoint.Endpoint
+	GetActions                         endpoint.Endpoint
+	GetStatistics                      endpoint.Endpoint
+	GetStatisticsUsers                 endpoint.Endpoint
+	GetStatisticsAuthenticators        endpoint.Endpoint
+	GetStatisticsAuthentications       endpoint.Endpoint
+	GetStatisticsAuthenticationsLog    endpoint.Endpoint
+	GetMigrationReport                 endpoint.Endpoint
+	GetStatisticsFailures              endpoint.Endpoint
+	GetStatisticsFailedAuthentications endpoint.Endpoint
+	GetStatisticsLockouts              endpoint.Endpoint
+	GetStatisticsFailuresBreakdown     endpoint.Endpoint
+	GetStatisticsAuthenticationsRatio  endpoint.Endpoint
+	GetStatisticsRegistrations         endpoint.Endpoint
+	GetMigrationReportSummary          endpoint.Endpoint
+	GetMigrationReportUsers            endpoint.Endpoint
+	GetInactiveUsers                   endpoint.Endpoint
+	LockInactiveUsers                  endpoint.Endpoint
+	GetStatisticsAccreditations        endpoint.Endpoint
+	GetExpiringAccreditations          endpoint.Endpoint
+	GetStatisticsOverview              endpoint.Endpoint
 }
 
+// Default page size of the migration report
+const defaultMigrationReportMax = 100
+
+// Default page size of the inactive users report and of the expiring accreditations report
+const defaultInactiveUsersMax = 100
+const defaultExpiringAccreditationsMax = 100
+
 // MakeGetActionsEndpoint creates an endpoint for GetActions
 func MakeGetActionsEndpoint(ec Component) cs.Endpoint {
 	return func(ctx context.Context, _ interface{}) (interface{}, error) {
@@ -85,3 +108,235 @@ func MakeGetMigrationReportEndpoint(ec Component) cs.Endpoint {
 		return ec.GetMigrationReport(ctx, m[PrmRealm])
 	}
 }
+
+// MakeGetStatisticsFailuresEndpoint makes the statistic failed authentications summary endpoint.
+func MakeGetStatisticsFailuresEndpoint(ec Component) cs.Endpoint {
+	return func(ctx context.Context, req interface{}) (interface{}, error) {
+		var m = req.(map[string]string)
+		return ec.GetStatisticsFailures(ctx, m[PrmRealm])
+	}
+}
+
+// MakeGetStatisticsFailedAuthenticationsEndpoint makes the statistic failed authentications per period summary endpoint.
+func MakeGetStatisticsFailedAuthenticationsEndpoint(ec Component) cs.Endpoint {
+	return func(ctx context.Context, req interface{}) (interface{}, error) {
+		var m = req.(map[string]string)
+		if _, ok := m[PrmQryUnit]; !ok {
+			return nil, errorhandler.CreateMissingParameterError(msg.Unit)
+		}
+		return ec.GetStatisticsFailedAuthentications(ctx, m[PrmRealm], m[PrmQryUnit], getTimeshift(m))
+	}
+}
+
+// MakeGetStatisticsLockoutsEndpoint makes the statistic lockouts per period summary endpoint.
+func MakeGetStatisticsLockoutsEndpoint(ec Component) cs.Endpoint {
+	return func(ctx context.Context, req interface{}) (interface{}, error) {
+		var m = req.(map[string]string)
+		if _, ok := m[PrmQryUnit]; !ok {
+			return nil, errorhandler.CreateMissingParameterError(msg.Unit)
+		}
+		return ec.GetStatisticsLockouts(ctx, m[PrmRealm], m[PrmQryUnit], getTimeshift(m))
+	}
+}
+
+// MakeGetStatisticsFailuresBreakdownEndpoint makes the statistic failed authentications per error code and per client endpoint.
+func MakeGetStatisticsFailuresBreakdownEndpoint(ec Component) cs.Endpoint {
+	return func(ctx context.Context, req interface{}) (interface{}, error) {
+		var m = req.(map[string]string)
+		if _, ok := m[PrmQryUnit]; !ok {
+			return nil, errorhandler.CreateMissingParameterError(msg.Unit)
+		}
+		return ec.GetStatisticsFailuresBreakdown(ctx, m[PrmRealm], m[PrmQryUnit])
+	}
+}
+
+// MakeGetStatisticsAuthenticationsRatioEndpoint makes the statistic authentications success ratio per period endpoint.
+func MakeGetStatisticsAuthenticationsRatioEndpoint(ec Component) cs.Endpoint {
+	return func(ctx context.Context, req interface{}) (interface{}, error) {
+		var m = req.(map[string]string)
+		if _, ok := m[PrmQryUnit]; !ok {
+			return nil, errorhandler.CreateMissingParameterError(msg.Unit)
+		}
+		return ec.GetStatisticsAuthenticationsRatio(ctx, m[PrmRealm], m[PrmQryUnit], getTimeshift(m))
+	}
+}
+
+// MakeGetStatisticsRegistrationsEndpoint makes the statistic registration funnel endpoint.
+func MakeGetStatisticsRegistrationsEndpoint(ec Component) cs.Endpoint {
+	return func(ctx context.Context, req interface{}) (interface{}, error) {
+		var m = req.(map[string]string)
+		if _, ok := m[PrmQryUnit]; !ok {
+			return nil, errorhandler.CreateMissingParameterError(msg.Unit)
+		}
+		return ec.GetStatisticsRegistrations(ctx, m[PrmRealm], m[PrmQryUnit], getTimeshift(m))
+	}
+}
+
+// MakeGetMigrationReportSummaryEndpoint makes the migration report summary endpoint.
+func MakeGetMigrationReportSummaryEndpoint(ec Component) cs.Endpoint {
+	return func(ctx context.Context, req interface{}) (interface{}, error) {
+		var m = req.(map[string]string)
+		return ec.GetMigrationReportSummary(ctx, m[PrmRealm], m[PrmQryAsync] == "true", m[PrmQryRefresh] == "true")
+	}
+}
+
+// MakeGetMigrationReportUsersEndpoint makes the paginated migration report endpoint.
+func MakeGetMigrationReportUsersEndpoint(ec Component) cs.Endpoint {
+	return func(ctx context.Context, req interface{}) (interface{}, error) {
+		var m = req.(map[string]string)
+
+		first, max, err := getPagination(m, defaultMigrationReportMax)
+		if err != nil {
+			return nil, err
+		}
+
+		var migrated *bool
+		if value, ok := m[PrmQryMigrated]; ok {
+			var flag = value == "true"
+			migrated = &flag
+		}
+
+		return ec.GetMigrationReportUsers(ctx, m[PrmRealm], migrated, first, max)
+	}
+}
+
+// MakeGetInactiveUsersEndpoint makes the inactive users report endpoint. The report can be exported as CSV.
+func MakeGetInactiveUsersEndpoint(ec Component) cs.Endpoint {
+	return func(ctx context.Context, req interface{}) (interface{}, error) {
+		var m = req.(map[string]string)
+
+		days, first, max, err := getInactiveUsersParameters(m)
+		if err != nil {
+			return nil, err
+		}
+
+		if m[PrmQryFormat] == "csv" {
+			// The export contains the whole result set, not only the requested page
+			page, err := ec.GetInactiveUsers(ctx, m[PrmRealm], days, 0, 0)
+			if err != nil {
+				return nil, err
+			}
+			return CSVReply{Filename: "inactive-users-" + m[PrmRealm] + ".csv", Records: page.ToCSV()}, nil
+		}
+
+		return ec.GetInactiveUsers(ctx, m[PrmRealm], days, first, max)
+	}
+}
+
+// MakeLockInactiveUsersEndpoint makes the endpoint used to lock the inactive users reviewed by the operator.
+func MakeLockInactiveUsersEndpoint(ec Component) cs.Endpoint {
+	return func(ctx context.Context, req interface{}) (interface{}, error) {
+		var m = req.(map[string]string)
+
+		days, err := getDays(m)
+		if err != nil {
+			return nil, err
+		}
+
+		var request api.InactiveUsersLockRequest
+		if err = json.Unmarshal([]byte(m[reqBody]), &request); err != nil {
+			return nil, errorhandler.CreateBadRequestError(msg.MsgErrInvalidParam + "." + msg.Body)
+		}
+		if err = request.Validate(); err != nil {
+			return nil, err
+		}
+
+		return ec.LockInactiveUsers(ctx, m[PrmRealm], days, request.UserIDs)
+	}
+}
+
+// MakeGetStatisticsAccreditationsEndpoint makes the accreditations statistics endpoint.
+func MakeGetStatisticsAccreditationsEndpoint(ec Component) cs.Endpoint {
+	return func(ctx context.Context, req interface{}) (interface{}, error) {
+		var m = req.(map[string]string)
+		return ec.GetStatisticsAccreditations(ctx, m[PrmRealm])
+	}
+}
+
+// MakeGetExpiringAccreditationsEndpoint makes the expiring accreditations report endpoint.
+func MakeGetExpiringAccreditationsEndpoint(ec Component) cs.Endpoint {
+	return func(ctx context.Context, req interface{}) (interface{}, error) {
+		var m = req.(map[string]string)
+
+		days, err := getDays(m)
+		if err != nil {
+			return nil, err
+		}
+		first, max, err := getPagination(m, defaultExpiringAccreditationsMax)
+		if err != nil {
+			return nil, err
+		}
+
+		var accredType *string
+		if value, ok := m[PrmQryType]; ok {
+			accredType = &value
+		}
+
+		return ec.GetExpiringAccreditations(ctx, m[PrmRealm], days, accredType, first, max)
+	}
+}
+
+// MakeGetStatisticsOverviewEndpoint makes the endpoint giving an overview of the statistics of all the allowed realms. The overview can be exported as CSV.
+func MakeGetStatisticsOverviewEndpoint(ec Component) cs.Endpoint {
+	return func(ctx context.Context, req interface{}) (interface{}, error) {
+		var m = req.(map[string]string)
+
+		overview, err := ec.GetStatisticsOverview(ctx, m[PrmQrySort], m[PrmQryOrder] == "desc")
+		if err != nil {
+			return nil, err
+		}
+		if m[PrmQryFormat] == "csv" {
+			return CSVReply{Filename: "statistics-overview.csv", Records: overview.ToCSV()}, nil
+		}
+		return overview, nil
+	}
+}
+
+func getDays(m map[string]string) (int, error) {
+	value, ok := m[PrmQryDays]
+	if !ok {
+		return 0, errorhandler.CreateMissingParameterError(msg.Days)
+	}
+	days, err := strconv.Atoi(value)
+	if err != nil || days == 0 {
+		return 0, errorhandler.CreateInvalidQueryParameterError(msg.Days)
+	}
+	return days, nil
+}
+
+func getInactiveUsersParameters(m map[string]string) (int, int, int, error) {
+	days, err := getDays(m)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+
+	first, max, err := getPagination(m, defaultInactiveUsersMax)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	return days, first, max, nil
+}
+
+func getPagination(m map[string]string, defaultMax int) (int, int, error) {
+	var first, max = 0, defaultMax
+	if value, ok := m[PrmQryFirst]; ok {
+		var err error
+		if first, err = strconv.Atoi(value); err != nil {
+			return 0, 0, errorhandler.CreateInvalidQueryParameterError(msg.First)
+		}
+	}
+	if value, ok := m[PrmQryMax]; ok {
+		var err error
+		if max, err = strconv.Atoi(value); err != nil || max == 0 {
+			return 0, 0, errorhandler.CreateInvalidQueryParameterError(msg.Max)
+		}
+	}
+	return first, max, nil
+}
+
+func getTimeshift(m map[string]string) *string {
+	if timeshiftStr, ok := m[PrmQryTimeshift]; ok {
+		return &timeshiftStr
+	}
+	return nil
+}
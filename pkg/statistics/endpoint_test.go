@@ -2,8 +2,10 @@ package statistics
 
 import (
 	"context"
+	"errors"
 	"testing"
 
+	cs "github.com/cloudtrust/common-service"
 	api "github.com/cloudtrust/keycloak-bridge/api/statistics"
 	"github.com/cloudtrust/keycloak-bridge/pkg/statistics/mock"
 	"github.com/golang/mock/gomock"
@@ -117,3 +119,243 @@ func TestMakeGetStatisticsAuthenticationsLogEndpoint(t *testing.T) {
 	assert.Nil(t, err)
 	assert.NotNil(t, res)
 }
+
+func TestMakeGetStatisticsFailuresEndpoints(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	var mockComponent = mock.NewComponent(mockCtrl)
+
+	var ctx = context.Background()
+	var realm = "realm"
+	var timeshift = "-120"
+
+	t.Run("GetStatisticsFailures", func(t *testing.T) {
+		var req = map[string]string{PrmRealm: realm}
+		mockComponent.EXPECT().GetStatisticsFailures(ctx, realm).Return(api.StatisticsFailuresRepresentation{}, nil).Times(1)
+		var res, err = MakeGetStatisticsFailuresEndpoint(mockComponent)(ctx, req)
+		assert.Nil(t, err)
+		assert.NotNil(t, res)
+	})
+	t.Run("Missing unit", func(t *testing.T) {
+		var req = map[string]string{PrmRealm: realm}
+		for _, e := range []func(Component) cs.Endpoint{MakeGetStatisticsFailedAuthenticationsEndpoint, MakeGetStatisticsLockoutsEndpoint,
+			MakeGetStatisticsFailuresBreakdownEndpoint, MakeGetStatisticsAuthenticationsRatioEndpoint} {
+			var _, err = e(mockComponent)(ctx, req)
+			assert.NotNil(t, err)
+		}
+	})
+	t.Run("GetStatisticsFailedAuthentications", func(t *testing.T) {
+		var req = map[string]string{PrmRealm: realm, PrmQryUnit: "days", PrmQryTimeshift: timeshift}
+		mockComponent.EXPECT().GetStatisticsFailedAuthentications(ctx, realm, "days", &timeshift).Return([][]int64{}, nil).Times(1)
+		var res, err = MakeGetStatisticsFailedAuthenticationsEndpoint(mockComponent)(ctx, req)
+		assert.Nil(t, err)
+		assert.NotNil(t, res)
+	})
+	t.Run("GetStatisticsLockouts", func(t *testing.T) {
+		var req = map[string]string{PrmRealm: realm, PrmQryUnit: "hours"}
+		mockComponent.EXPECT().GetStatisticsLockouts(ctx, realm, "hours", nil).Return([][]int64{}, nil).Times(1)
+		var res, err = MakeGetStatisticsLockoutsEndpoint(mockComponent)(ctx, req)
+		assert.Nil(t, err)
+		assert.NotNil(t, res)
+	})
+	t.Run("GetStatisticsFailuresBreakdown", func(t *testing.T) {
+		var req = map[string]string{PrmRealm: realm, PrmQryUnit: "months"}
+		mockComponent.EXPECT().GetStatisticsFailuresBreakdown(ctx, realm, "months").Return(api.StatisticsFailuresBreakdownRepresentation{}, nil).Times(1)
+		var res, err = MakeGetStatisticsFailuresBreakdownEndpoint(mockComponent)(ctx, req)
+		assert.Nil(t, err)
+		assert.NotNil(t, res)
+	})
+	t.Run("GetStatisticsAuthenticationsRatio", func(t *testing.T) {
+		var req = map[string]string{PrmRealm: realm, PrmQryUnit: "hours"}
+		mockComponent.EXPECT().GetStatisticsAuthenticationsRatio(ctx, realm, "hours", nil).Return([]api.StatisticsRatioRepresentation{}, nil).Times(1)
+		var res, err = MakeGetStatisticsAuthenticationsRatioEndpoint(mockComponent)(ctx, req)
+		assert.Nil(t, err)
+		assert.NotNil(t, res)
+	})
+}
+
+func TestMakeGetStatisticsRegistrationsEndpoint(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	var mockComponent = mock.NewComponent(mockCtrl)
+
+	var e = MakeGetStatisticsRegistrationsEndpoint(mockComponent)
+
+	var ctx = context.Background()
+	var req = map[string]string{PrmRealm: "realm"}
+
+	t.Run("Missing unit", func(t *testing.T) {
+		var _, err = e(ctx, req)
+		assert.NotNil(t, err)
+	})
+	t.Run("Success", func(t *testing.T) {
+		req[PrmQryUnit] = "days"
+		mockComponent.EXPECT().GetStatisticsRegistrations(ctx, "realm", "days", nil).Return(api.StatisticsRegistrationsRepresentation{}, nil).Times(1)
+		var res, err = e(ctx, req)
+		assert.Nil(t, err)
+		assert.NotNil(t, res)
+	})
+}
+
+func TestMakeGetMigrationReportEndpoints(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	var mockComponent = mock.NewComponent(mockCtrl)
+
+	var ctx = context.Background()
+	var realm = "realm"
+
+	t.Run("Summary", func(t *testing.T) {
+		var req = map[string]string{PrmRealm: realm, PrmQryAsync: "true"}
+		mockComponent.EXPECT().GetMigrationReportSummary(ctx, realm, true, false).Return(api.MigrationReportSummaryRepresentation{}, nil).Times(1)
+		var res, err = MakeGetMigrationReportSummaryEndpoint(mockComponent)(ctx, req)
+		assert.Nil(t, err)
+		assert.NotNil(t, res)
+	})
+	t.Run("Users - default paging", func(t *testing.T) {
+		var req = map[string]string{PrmRealm: realm}
+		mockComponent.EXPECT().GetMigrationReportUsers(ctx, realm, nil, 0, 100).Return(api.MigrationReportUsersPageRepresentation{}, nil).Times(1)
+		var res, err = MakeGetMigrationReportUsersEndpoint(mockComponent)(ctx, req)
+		assert.Nil(t, err)
+		assert.NotNil(t, res)
+	})
+	t.Run("Users - filter and paging", func(t *testing.T) {
+		var req = map[string]string{PrmRealm: realm, PrmQryMigrated: "false", PrmQryFirst: "20", PrmQryMax: "10"}
+		var migrated = false
+		mockComponent.EXPECT().GetMigrationReportUsers(ctx, realm, &migrated, 20, 10).Return(api.MigrationReportUsersPageRepresentation{}, nil).Times(1)
+		var res, err = MakeGetMigrationReportUsersEndpoint(mockComponent)(ctx, req)
+		assert.Nil(t, err)
+		assert.NotNil(t, res)
+	})
+	t.Run("Users - invalid max", func(t *testing.T) {
+		var req = map[string]string{PrmRealm: realm, PrmQryMax: "0"}
+		var _, err = MakeGetMigrationReportUsersEndpoint(mockComponent)(ctx, req)
+		assert.NotNil(t, err)
+	})
+}
+
+func TestMakeInactiveUsersEndpoints(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	var mockComponent = mock.NewComponent(mockCtrl)
+
+	var ctx = context.Background()
+	var realm = "realm"
+
+	t.Run("Missing days", func(t *testing.T) {
+		var _, err = MakeGetInactiveUsersEndpoint(mockComponent)(ctx, map[string]string{PrmRealm: realm})
+		assert.NotNil(t, err)
+		_, err = MakeLockInactiveUsersEndpoint(mockComponent)(ctx, map[string]string{PrmRealm: realm})
+		assert.NotNil(t, err)
+	})
+	t.Run("Invalid days", func(t *testing.T) {
+		var _, err = MakeGetInactiveUsersEndpoint(mockComponent)(ctx, map[string]string{PrmRealm: realm, PrmQryDays: "0"})
+		assert.NotNil(t, err)
+	})
+	t.Run("Get - JSON", func(t *testing.T) {
+		var req = map[string]string{PrmRealm: realm, PrmQryDays: "90", PrmQryFirst: "10"}
+		mockComponent.EXPECT().GetInactiveUsers(ctx, realm, 90, 10, 100).Return(api.InactiveUsersPageRepresentation{Count: 3}, nil).Times(1)
+		var res, err = MakeGetInactiveUsersEndpoint(mockComponent)(ctx, req)
+		assert.Nil(t, err)
+		assert.Equal(t, api.InactiveUsersPageRepresentation{Count: 3}, res)
+	})
+	t.Run("Get - CSV", func(t *testing.T) {
+		var req = map[string]string{PrmRealm: realm, PrmQryDays: "90", PrmQryFirst: "10", PrmQryMax: "20", PrmQryFormat: "csv"}
+		mockComponent.EXPECT().GetInactiveUsers(ctx, realm, 90, 0, 0).Return(api.InactiveUsersPageRepresentation{}, nil).Times(1)
+		var res, err = MakeGetInactiveUsersEndpoint(mockComponent)(ctx, req)
+		assert.Nil(t, err)
+		assert.IsType(t, CSVReply{}, res)
+		assert.Equal(t, "inactive-users-realm.csv", res.(CSVReply).Filename)
+	})
+	t.Run("Get - component fails", func(t *testing.T) {
+		var req = map[string]string{PrmRealm: realm, PrmQryDays: "90"}
+		mockComponent.EXPECT().GetInactiveUsers(ctx, realm, 90, 0, 100).Return(api.InactiveUsersPageRepresentation{}, errors.New("error")).Times(1)
+		var _, err = MakeGetInactiveUsersEndpoint(mockComponent)(ctx, req)
+		assert.NotNil(t, err)
+	})
+	t.Run("Lock - invalid body", func(t *testing.T) {
+		var req = map[string]string{PrmRealm: realm, PrmQryDays: "90", reqBody: `{`}
+		var _, err = MakeLockInactiveUsersEndpoint(mockComponent)(ctx, req)
+		assert.NotNil(t, err)
+
+		req[reqBody] = `{"userIds":[]}`
+		_, err = MakeLockInactiveUsersEndpoint(mockComponent)(ctx, req)
+		assert.NotNil(t, err)
+	})
+	t.Run("Lock", func(t *testing.T) {
+		var userID = "f467ed7c-0a1d-4eee-9bb8-669c6f89c000"
+		var req = map[string]string{PrmRealm: realm, PrmQryDays: "90", reqBody: `{"userIds":["` + userID + `"]}`}
+		mockComponent.EXPECT().LockInactiveUsers(ctx, realm, 90, []string{userID}).Return(api.InactiveUsersLockRepresentation{}, nil).Times(1)
+		var _, err = MakeLockInactiveUsersEndpoint(mockComponent)(ctx, req)
+		assert.Nil(t, err)
+	})
+}
+
+func TestMakeAccreditationsEndpoints(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	var mockComponent = mock.NewComponent(mockCtrl)
+
+	var ctx = context.Background()
+	var realm = "realm"
+
+	t.Run("Statistics", func(t *testing.T) {
+		mockComponent.EXPECT().GetStatisticsAccreditations(ctx, realm).Return(map[string]api.StatisticsAccreditationsRepresentation{}, nil).Times(1)
+		var _, err = MakeGetStatisticsAccreditationsEndpoint(mockComponent)(ctx, map[string]string{PrmRealm: realm})
+		assert.Nil(t, err)
+	})
+	t.Run("Expiring - missing days", func(t *testing.T) {
+		var _, err = MakeGetExpiringAccreditationsEndpoint(mockComponent)(ctx, map[string]string{PrmRealm: realm})
+		assert.NotNil(t, err)
+	})
+	t.Run("Expiring - invalid max", func(t *testing.T) {
+		var _, err = MakeGetExpiringAccreditationsEndpoint(mockComponent)(ctx, map[string]string{PrmRealm: realm, PrmQryDays: "30", PrmQryMax: "0"})
+		assert.NotNil(t, err)
+	})
+	t.Run("Expiring", func(t *testing.T) {
+		var accredType = "SHADOW"
+		var req = map[string]string{PrmRealm: realm, PrmQryDays: "30", PrmQryType: accredType}
+		mockComponent.EXPECT().GetExpiringAccreditations(ctx, realm, 30, &accredType, 0, 100).Return(api.ExpiringAccreditationsPageRepresentation{}, nil).Times(1)
+		var _, err = MakeGetExpiringAccreditationsEndpoint(mockComponent)(ctx, req)
+		assert.Nil(t, err)
+	})
+}
+
+func TestMakeGetStatisticsOverviewEndpoint(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	var mockComponent = mock.NewComponent(mockCtrl)
+
+	var ctx = context.Background()
+	var overview = api.StatisticsOverviewRepresentation{
+		Realms:            []api.StatisticsRealmOverviewRepresentation{{Realm: "realm", Users: 10}},
+		UnavailableRealms: []string{"broken"},
+	}
+
+	t.Run("JSON", func(t *testing.T) {
+		mockComponent.EXPECT().GetStatisticsOverview(ctx, "users", true).Return(overview, nil).Times(1)
+		var res, err = MakeGetStatisticsOverviewEndpoint(mockComponent)(ctx, map[string]string{PrmQrySort: "users", PrmQryOrder: "desc"})
+		assert.Nil(t, err)
+		assert.Equal(t, overview, res)
+	})
+	t.Run("CSV", func(t *testing.T) {
+		mockComponent.EXPECT().GetStatisticsOverview(ctx, "", false).Return(overview, nil).Times(1)
+		var res, err = MakeGetStatisticsOverviewEndpoint(mockComponent)(ctx, map[string]string{PrmQryFormat: "csv"})
+		assert.Nil(t, err)
+		assert.IsType(t, CSVReply{}, res)
+		assert.Len(t, res.(CSVReply).Records, 3)
+		assert.Equal(t, "10", res.(CSVReply).Records[1][1])
+		assert.Equal(t, []string{"broken", "", "", "", "", "", "", "", "", "unavailable"}, res.(CSVReply).Records[2])
+	})
+	t.Run("Component fails", func(t *testing.T) {
+		mockComponent.EXPECT().GetStatisticsOverview(ctx, "", false).Return(api.StatisticsOverviewRepresentation{}, errors.New("error")).Times(1)
+		var _, err = MakeGetStatisticsOverviewEndpoint(mockComponent)(ctx, map[string]string{})
+		assert.NotNil(t, err)
+	})
+}
@@ -2,6 +2,8 @@ package statistics
 
 import (
 	"context"
+	"encoding/csv"
+	"fmt"
 	"net/http"
 
 	commonhttp "github.com/cloudtrust/common-service/http"
@@ -14,17 +16,33 @@ import (
 // Parameter names
 const (
 	PrmRealm = "realm"
+	reqBody  = "body"
 
-	PrmQryUnit = "unit"
-	PrmQryMax = "max"
+	PrmQryUnit      = "unit"
+	PrmQryMax       = "max"
 	PrmQryTimeshift = "timeshift"
+	PrmQryFirst     = "first"
+	PrmQryMigrated  = "migrated"
+	PrmQryAsync     = "async"
+	PrmQryRefresh   = "refresh"
+	PrmQryDays      = "days"
+	PrmQryFormat    = "format"
+	PrmQryType      = "type"
+	PrmQrySort      = "sort"
+	PrmQryOrder     = "order"
 )
 
+// CSVReply is a reply encoded as a CSV attachment
+type CSVReply struct {
+	Filename string
+	Records  [][]string
+}
+
 // MakeStatisticsHandler make an HTTP handler for a Statistics endpoint.
 func MakeStatisticsHandler(e endpoint.Endpoint, logger log.Logger) *http_transport.Server {
 	return http_transport.NewServer(e,
 		decodeEventsRequest,
-		commonhttp.EncodeReply,
+		encodeStatisticsReply,
 		http_transport.ServerErrorEncoder(commonhttp.ErrorHandler(logger)),
 	)
 }
@@ -39,7 +57,29 @@ func decodeEventsRequest(ctx context.Context, req *http.Request) (interface{}, e
 		PrmQryUnit:      stat_api.RegExpPeriod,
 		PrmQryMax:       stat_api.RegExpNumber,
 		PrmQryTimeshift: stat_api.RegExpTimeshift,
+		PrmQryFirst:     stat_api.RegExpNumber,
+		PrmQryMigrated:  stat_api.RegExpBool,
+		PrmQryAsync:     stat_api.RegExpBool,
+		PrmQryRefresh:   stat_api.RegExpBool,
+		PrmQryDays:      stat_api.RegExpNumber,
+		PrmQryFormat:    stat_api.RegExpFormat,
+		PrmQryType:      stat_api.RegExpAccredType,
+		PrmQrySort:      stat_api.RegExpOverviewSort,
+		PrmQryOrder:     stat_api.RegExpSortOrder,
 	}
 
 	return commonhttp.DecodeRequest(ctx, req, pathParams, queryParams)
 }
+
+// encodeStatisticsReply encodes the reply.
+func encodeStatisticsReply(ctx context.Context, w http.ResponseWriter, rep interface{}) error {
+	switch r := rep.(type) {
+	case CSVReply:
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", r.Filename))
+		w.WriteHeader(http.StatusOK)
+		return csv.NewWriter(w).WriteAll(r.Records)
+	default:
+		return commonhttp.EncodeReply(ctx, w, rep)
+	}
+}
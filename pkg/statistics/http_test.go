@@ -50,3 +50,35 @@ func TestHTTPStatisticsHandler(t *testing.T) {
 		assert.Equal(t, string(statsJSON), buf.String())
 	}
 }
+
+func TestHTTPStatisticsHandlerCSV(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+	var mockComponent = mock.NewComponent(mockCtrl)
+
+	var statisticsHandler = MakeStatisticsHandler(keycloakb.ToGoKitEndpoint(MakeGetInactiveUsersEndpoint(mockComponent)), log.NewNopLogger())
+
+	r := mux.NewRouter()
+	r.Handle("/statistics/realm/{realm}/inactive-users", statisticsHandler)
+
+	ts := httptest.NewServer(r)
+	defer ts.Close()
+
+	var lastConnection = int64(1577836800)
+	var page = api.InactiveUsersPageRepresentation{
+		Users: []api.InactiveUserRepresentation{{ID: "id", Username: "name", Enabled: true, LastConnection: &lastConnection}},
+		Count: 1,
+	}
+	mockComponent.EXPECT().GetInactiveUsers(gomock.Any(), "master", 90, 0, 100).Return(page, nil).Times(1)
+
+	res, err := http.Get(ts.URL + "/statistics/realm/master/inactive-users?days=90&format=csv")
+
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, "text/csv; charset=utf-8", res.Header.Get("Content-Type"))
+	assert.Equal(t, `attachment; filename="inactive-users-master.csv"`, res.Header.Get("Content-Disposition"))
+
+	buf := new(bytes.Buffer)
+	buf.ReadFrom(res.Body)
+	assert.Equal(t, "id,username,enabled,createdTimestamp,lastConnection\nid,name,true,,2020-01-01T00:00:00Z\n", buf.String())
+}
@@ -6,3 +6,4 @@ package statistics
 //go:generate mockgen -destination=./mock/keycloak_client.go -package=mock -mock_names=KeycloakClient=KeycloakClient github.com/cloudtrust/common-service/security KeycloakClient
 //go:generate mockgen -destination=./mock/dbmodule.go -package=mock -mock_names=EventsDBModule=EventsDBModule github.com/cloudtrust/keycloak-bridge/internal/keycloakb EventsDBModule
 //go:generate mockgen -destination=./mock/authentication_db_reader.go -package=mock -mock_names=AuthorizationDBReader=AuthorizationDBReader github.com/cloudtrust/common-service/security AuthorizationDBReader
+//go:generate mockgen -destination=./mock/management.go -package=mock -mock_names=ManagementComponent=ManagementComponent,RealmAuthorizer=RealmAuthorizer,TokenProvider=TokenProvider github.com/cloudtrust/keycloak-bridge/pkg/statistics ManagementComponent,RealmAuthorizer,TokenProvider
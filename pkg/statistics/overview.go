@@ -0,0 +1,120 @@
+package statistics
+
+import (
+	"context"
+	"sort"
+
+	cs "github.com/cloudtrust/common-service"
+	errorhandler "github.com/cloudtrust/common-service/errors"
+	api "github.com/cloudtrust/keycloak-bridge/api/statistics"
+	"github.com/cloudtrust/keycloak-bridge/internal/constants"
+	"github.com/cloudtrust/keycloak-bridge/internal/keycloakb"
+)
+
+// Duration covered by the active users and by the failed login rate of the overview
+const overviewDuration = "1 MONTH"
+
+type realmOverview = api.StatisticsRealmOverviewRepresentation
+
+// Comparison of the realms overviews, depending on the sort criterion
+var overviewLess = map[string]func(a, b realmOverview) bool{
+	"realm":           func(a, b realmOverview) bool { return a.Realm < b.Realm },
+	"users":           func(a, b realmOverview) bool { return a.Users < b.Users },
+	"activeUsers":     func(a, b realmOverview) bool { return a.ActiveUsers < b.ActiveUsers },
+	"loginsLastDay":   func(a, b realmOverview) bool { return a.LoginsLastDay < b.LoginsLastDay },
+	"loginsLastWeek":  func(a, b realmOverview) bool { return a.LoginsLastWeek < b.LoginsLastWeek },
+	"loginsLastMonth": func(a, b realmOverview) bool { return a.LoginsLastMonth < b.LoginsLastMonth },
+	"failedLoginRate": func(a, b realmOverview) bool { return a.FailedLoginRate < b.FailedLoginRate },
+}
+
+// GetStatisticsOverview summarises the statistics of all the realms on which the current user is allowed to get the overview
+// Realms are sorted according to sortBy (by realm name if empty)
+func (ec *component) GetStatisticsOverview(ctx context.Context, sortBy string, descending bool) (api.StatisticsOverviewRepresentation, error) {
+	var accessToken = ctx.Value(cs.CtContextAccessToken).(string)
+
+	if sortBy == "" {
+		sortBy = "realm"
+	}
+	var less, ok = overviewLess[sortBy]
+	if !ok {
+		return api.StatisticsOverviewRepresentation{}, errorhandler.CreateBadRequestError(constants.MsgErrInvalidParam + "." + constants.SortBy)
+	}
+
+	realms, err := ec.keycloakClient.GetRealms(accessToken)
+	if err != nil {
+		ec.logger.Warn(ctx, "msg", "Can't get realms", "err", err.Error())
+		return api.StatisticsOverviewRepresentation{}, err
+	}
+
+	var res = api.StatisticsOverviewRepresentation{
+		Realms:            []realmOverview{},
+		UnavailableRealms: []string{},
+	}
+	for _, realm := range realms {
+		if realm.Realm == nil {
+			continue
+		}
+		var realmName = *realm.Realm
+		if ec.authorizer.CheckAuthorizationOnTargetRealm(ctx, STGetStatisticsOverview.String(), realmName) != nil {
+			// Realms on which the user is not allowed are silently ignored
+			continue
+		}
+		overview, err := ec.getRealmOverview(ctx, accessToken, realmName)
+		if err != nil {
+			// A failing realm must not prevent the overview of the other ones
+			ec.logger.Warn(ctx, "msg", "Can't get realm overview", "err", err.Error(), "realm", realmName)
+			res.UnavailableRealms = append(res.UnavailableRealms, realmName)
+			continue
+		}
+		res.Realms = append(res.Realms, overview)
+	}
+
+	sort.SliceStable(res.Realms, func(i, j int) bool {
+		if descending {
+			return less(res.Realms[j], res.Realms[i])
+		}
+		return less(res.Realms[i], res.Realms[j])
+	})
+
+	return res, nil
+}
+
+func (ec *component) getRealmOverview(ctx context.Context, accessToken string, realmName string) (realmOverview, error) {
+	var res = realmOverview{Realm: realmName}
+
+	users, err := ec.keycloakClient.GetStatisticsUsers(accessToken, realmName)
+	if err != nil {
+		return res, err
+	}
+	res.Users = users.Total
+	res.DisabledUsers = users.Disabled
+
+	var failures, lockouts int64
+	res.ActiveUsers, err = ec.db.GetActiveUsersCount(ctx, realmName, overviewDuration)
+	if err == nil {
+		res.LoginsLastDay, err = ec.counter.GetTotalConnectionsCount(ctx, realmName, "1 DAY")
+	}
+	if err == nil {
+		res.LoginsLastWeek, err = ec.counter.GetTotalConnectionsCount(ctx, realmName, "1 WEEK")
+	}
+	if err == nil {
+		res.LoginsLastMonth, err = ec.counter.GetTotalConnectionsCount(ctx, realmName, overviewDuration)
+	}
+	if err == nil {
+		failures, err = ec.counter.GetTotalEventsCount(ctx, realmName, keycloakb.CtEventTypeLogonError, overviewDuration)
+	}
+	if err == nil {
+		lockouts, err = ec.counter.GetTotalEventsCount(ctx, realmName, keycloakb.CtEventTypeTemporarilyLocked, overviewDuration)
+	}
+	if err != nil {
+		return res, err
+	}
+
+	// Failures include both failed authentications and temporary lockouts, as in GetStatisticsAuthenticationsRatio
+	res.FailedLoginsLastMonth = failures + lockouts
+	if total := res.LoginsLastMonth + res.FailedLoginsLastMonth; total > 0 {
+		res.FailedLoginRate = float64(res.FailedLoginsLastMonth) / float64(total)
+	}
+
+	return res, nil
+}
@@ -0,0 +1,101 @@
+package statistics
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	cs "github.com/cloudtrust/common-service"
+	errorhandler "github.com/cloudtrust/common-service/errors"
+	"github.com/cloudtrust/common-service/log"
+	"github.com/cloudtrust/common-service/security"
+	"github.com/cloudtrust/keycloak-bridge/internal/keycloakb"
+	"github.com/cloudtrust/keycloak-bridge/pkg/statistics/mock"
+	kc "github.com/cloudtrust/keycloak-client"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetStatisticsOverview(t *testing.T) {
+	var mockCtrl = gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	var mockDBModule = mock.NewEventsDBModule(mockCtrl)
+	var mockKcClient = mock.NewKcClient(mockCtrl)
+	var mockAuthorizer = mock.NewRealmAuthorizer(mockCtrl)
+	var mockLogger = log.NewNopLogger()
+
+	var component = NewComponent(mockDBModule, mockDBModule, mockKcClient, nil, "", nil, mockAuthorizer, nil, mockLogger)
+
+	var accessToken = "TOKEN=="
+	var ctx = context.WithValue(context.Background(), cs.CtContextAccessToken, accessToken)
+	var action = STGetStatisticsOverview.String()
+	var realmA, realmB, realmC = "realm-a", "realm-b", "realm-c"
+	var realms = []kc.RealmRepresentation{{Realm: &realmA}, {Realm: &realmB}, {Realm: &realmC}}
+	var anError = errors.New("any error")
+
+	var expectRealm = func(realmName string, users, active, logins, failures int64) {
+		mockKcClient.EXPECT().GetStatisticsUsers(accessToken, realmName).Return(kc.StatisticsUsersRepresentation{Total: users}, nil)
+		mockDBModule.EXPECT().GetActiveUsersCount(ctx, realmName, "1 MONTH").Return(active, nil)
+		mockDBModule.EXPECT().GetTotalConnectionsCount(ctx, realmName, "1 DAY").Return(logins, nil)
+		mockDBModule.EXPECT().GetTotalConnectionsCount(ctx, realmName, "1 WEEK").Return(logins, nil)
+		mockDBModule.EXPECT().GetTotalConnectionsCount(ctx, realmName, "1 MONTH").Return(logins, nil)
+		mockDBModule.EXPECT().GetTotalEventsCount(ctx, realmName, keycloakb.CtEventTypeLogonError, "1 MONTH").Return(failures, nil)
+		mockDBModule.EXPECT().GetTotalEventsCount(ctx, realmName, keycloakb.CtEventTypeTemporarilyLocked, "1 MONTH").Return(int64(0), nil)
+	}
+
+	t.Run("Unknown sort criterion", func(t *testing.T) {
+		var _, err = component.GetStatisticsOverview(ctx, "unknown", false)
+		assert.NotNil(t, err)
+		assert.Equal(t, http.StatusBadRequest, err.(errorhandler.Error).Status)
+	})
+	t.Run("Can't get realms", func(t *testing.T) {
+		mockKcClient.EXPECT().GetRealms(accessToken).Return(nil, anError)
+		var _, err = component.GetStatisticsOverview(ctx, "", false)
+		assert.Equal(t, anError, err)
+	})
+	t.Run("Can't get users statistics", func(t *testing.T) {
+		mockKcClient.EXPECT().GetRealms(accessToken).Return(realms[:1], nil)
+		mockAuthorizer.EXPECT().CheckAuthorizationOnTargetRealm(ctx, action, realmA).Return(nil)
+		mockKcClient.EXPECT().GetStatisticsUsers(accessToken, realmA).Return(kc.StatisticsUsersRepresentation{}, anError)
+		var res, err = component.GetStatisticsOverview(ctx, "", false)
+		assert.Nil(t, err)
+		assert.Len(t, res.Realms, 0)
+		assert.Equal(t, []string{realmA}, res.UnavailableRealms)
+	})
+	t.Run("Failing realm is skipped", func(t *testing.T) {
+		mockKcClient.EXPECT().GetRealms(accessToken).Return(realms, nil)
+		mockAuthorizer.EXPECT().CheckAuthorizationOnTargetRealm(ctx, action, realmA).Return(nil)
+		mockAuthorizer.EXPECT().CheckAuthorizationOnTargetRealm(ctx, action, realmB).Return(nil)
+		mockAuthorizer.EXPECT().CheckAuthorizationOnTargetRealm(ctx, action, realmC).Return(security.ForbiddenError{})
+		mockKcClient.EXPECT().GetStatisticsUsers(accessToken, realmA).Return(kc.StatisticsUsersRepresentation{}, nil)
+		mockDBModule.EXPECT().GetActiveUsersCount(ctx, realmA, "1 MONTH").Return(int64(0), anError)
+		expectRealm(realmB, 5, 2, 3, 1)
+		var res, err = component.GetStatisticsOverview(ctx, "", false)
+		assert.Nil(t, err)
+		assert.Len(t, res.Realms, 1)
+		assert.Equal(t, realmB, res.Realms[0].Realm)
+		assert.Equal(t, []string{realmA}, res.UnavailableRealms)
+	})
+	t.Run("Only allowed realms, sorted", func(t *testing.T) {
+		mockKcClient.EXPECT().GetRealms(accessToken).Return(realms, nil)
+		mockAuthorizer.EXPECT().CheckAuthorizationOnTargetRealm(ctx, action, realmA).Return(nil)
+		mockAuthorizer.EXPECT().CheckAuthorizationOnTargetRealm(ctx, action, realmB).Return(security.ForbiddenError{})
+		mockAuthorizer.EXPECT().CheckAuthorizationOnTargetRealm(ctx, action, realmC).Return(nil)
+		expectRealm(realmA, 5, 2, 3, 1)
+		expectRealm(realmC, 50, 20, 30, 0)
+
+		var overview, err = component.GetStatisticsOverview(ctx, "users", true)
+		assert.Nil(t, err)
+		assert.Len(t, overview.UnavailableRealms, 0)
+		var res = overview.Realms
+		assert.Len(t, res, 2)
+		assert.Equal(t, realmC, res[0].Realm)
+		assert.Equal(t, realmA, res[1].Realm)
+		assert.Equal(t, int64(2), res[1].ActiveUsers)
+		assert.Equal(t, int64(1), res[1].FailedLoginsLastMonth)
+		assert.Equal(t, 0.25, res[1].FailedLoginRate)
+		assert.Equal(t, 0.0, res[0].FailedLoginRate)
+	})
+}